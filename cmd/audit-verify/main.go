@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/storage"
+)
+
+// 审计链校验命令
+// 从头校验 audit_logs 的哈希链，并与 S3 中的锚点比对；发现问题时以状态码 1 退出
+//
+// 用法:
+//
+//	audit-verify [-config config/config.yaml] [-skip-anchors]
+func main() {
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	skipAnchors := flag.Bool("skip-anchors", false, "不读取 S3 锚点，只校验链本身")
+	flag.Parse()
+
+	if err := config.Load(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(2)
+	}
+	if err := logger.Init(config.GlobalConfig.Logger); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		os.Exit(2)
+	}
+	defer logger.Sync()
+
+	if err := database.Init(config.GlobalConfig.Database); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化数据库失败: %v\n", err)
+		os.Exit(2)
+	}
+	defer database.Close()
+	if err := migrate.CheckSchema(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "表结构版本不兼容: %v\n", err)
+		os.Exit(2)
+	}
+
+	var anchors []audit.Anchor
+	if !*skipAnchors {
+		if err := storage.Init(config.GlobalConfig.AWS); err != nil {
+			fmt.Fprintf(os.Stderr, "初始化 S3 存储失败: %v\n", err)
+			os.Exit(2)
+		}
+		var err error
+		anchors, err = audit.LoadAnchors(config.GlobalConfig.Audit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取锚点失败: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	result, err := audit.Verify(context.Background(), anchors)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "校验失败: %v\n", err)
+		os.Exit(2)
+	}
+
+	out, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(out))
+
+	if !result.OK() {
+		fmt.Fprintf(os.Stderr, "发现 %d 处问题，审计记录可能被篡改\n", len(result.Problems))
+		os.Exit(1)
+	}
+	fmt.Printf("审计链完整：共 %d 条记录，%d 个锚点\n", result.Checked, len(anchors))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/pgnotify"
+	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// changeEvent 发布到消息队列的变更事件
+type changeEvent struct {
+	pgnotify.Change
+	// At 收到通知的时间
+	At time.Time `json:"at"`
+}
+
+// startChangeListener 启动数据库变更监听
+// 参数:
+//
+//	ctx: 上下文，取消时停止监听
+//	cfg: 变更通知配置
+//
+// 返回:
+//
+//	error: 安装触发器失败时返回错误
+func startChangeListener(ctx context.Context, cfg config.ChangeNotifyConfig) error {
+	channel := cfg.GetChannel()
+
+	if cfg.InstallTriggers {
+		// activity.Tracker 定期批量写入的 last_seen_at 不是资料变更，不触发通知
+		if err := pgnotify.InstallTrigger(database.DB, service.User{}.TableName(), channel, "last_seen_at"); err != nil {
+			return err
+		}
+	}
+
+	listener := pgnotify.NewListener(config.GlobalConfig.Database.GetDatabaseDSN())
+	listener.Handle(channel, func(ctx context.Context, payload string) {
+		handleChange(ctx, cfg, payload)
+	})
+	go listener.Run(ctx)
+	return nil
+}
+
+// handleChange 处理单条变更：清理缓存并发布变更事件
+func handleChange(ctx context.Context, cfg config.ChangeNotifyConfig, payload string) {
+	change, err := pgnotify.ParseChange(payload)
+	if err != nil {
+		logger.Warn("解析数据库变更通知失败", zap.String("payload", payload), zap.Error(err))
+		return
+	}
+	event := changeEvent{Change: change, At: time.Now()}
+
+	logger.Debug("收到数据库变更通知",
+		zap.String("op", event.Op),
+		zap.String("table", event.Table),
+		zap.Int64("id", event.ID),
+	)
+
+	if event.Table == (service.User{}).TableName() {
+		if err := cache.DefaultRefresher.Invalidate(ctx, service.UserCacheClass, strconv.FormatInt(event.ID, 10)); err != nil {
+			logger.Error("清理用户缓存失败", zap.Int64("id", event.ID), zap.Error(err))
+		}
+	}
+
+	if cfg.EventRoutingKey == "" || queue.MQClient == nil {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := queue.MQClient.Publish(cfg.EventRoutingKey+"."+event.Table, body); err != nil {
+		logger.Error("发布数据库变更事件失败", zap.String("table", event.Table), zap.Error(err))
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// TestHandleChangePublishesContractEvent 变更事件按契约发布（契约校验在测试结束时进行）
+func TestHandleChangePublishesContractEvent(t *testing.T) {
+	testutil.InitLogger()
+	recorder := testutil.RecordEvents(t)
+
+	cfg := config.ChangeNotifyConfig{EventRoutingKey: "db.changed"}
+	handleChange(context.Background(), cfg, `{"op":"INSERT","table":"orders","id":7}`)
+
+	published := recorder.Events()
+	if len(published) != 1 {
+		t.Fatalf("发布事件数 = %d, 期望 1", len(published))
+	}
+	if published[0].RoutingKey != "db.changed.orders" {
+		t.Errorf("路由键 = %s", published[0].RoutingKey)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/cronctl"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// triggerPollTimeout 等待手动触发请求的单次超时，决定关闭时的最长等待
+const triggerPollTimeout = 5 * time.Second
+
+// startControl 启动任务列表上报与手动触发处理
+// 参数:
+//
+//	ctx: 上下文，取消后停止
+//	s: 调度器
+//
+// 返回:
+//
+//	func(): 等待后台协程及手动触发的任务结束，应在取消 ctx 后调用
+func startControl(ctx context.Context, s *scheduler) func() {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		reportJobs(ctx, s)
+	}()
+	go func() {
+		defer wg.Done()
+		consumeTriggers(ctx)
+	}()
+	return wg.Wait
+}
+
+// runScheduled 调度到点时执行任务，运行时被禁用的任务跳过
+// 读取禁用状态失败时仍然执行，避免 Redis 抖动导致任务漏跑
+func runScheduled(jobName string) {
+	defer recoverJob(jobName)
+
+	disabled, err := cronctl.IsDisabled(context.Background(), jobName)
+	if err != nil {
+		logger.Warn("读取任务禁用状态失败，按启用处理", zap.String("任务", jobName), zap.Error(err))
+	}
+	if disabled {
+		logger.Info("任务已在运行时禁用，跳过本次执行", zap.String("任务", jobName))
+		return
+	}
+	executeJob(jobName)
+}
+
+// reportJobs 定期上报任务列表及下次执行时间，供管理接口查询
+func reportJobs(ctx context.Context, s *scheduler) {
+	ticker := time.NewTicker(cronctl.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := cronctl.Report(ctx, jobInfos(s)); err != nil && ctx.Err() == nil {
+			logger.Warn("上报任务列表失败", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobInfos 汇总配置中的全部任务，未调度的任务也列出以便手动触发
+func jobInfos(s *scheduler) []cronctl.JobInfo {
+	jobs := make([]cronctl.JobInfo, 0, len(config.GlobalConfig.Cron.Jobs))
+	for _, job := range config.GlobalConfig.Cron.Jobs {
+		info := cronctl.JobInfo{Name: job.Name, Spec: job.Spec}
+		if entry, ok := s.entry(job.Name); ok {
+			info.Scheduled = true
+			if !entry.Next.IsZero() {
+				info.NextRun = &entry.Next
+			}
+			if !entry.Prev.IsZero() {
+				info.PrevRun = &entry.Prev
+			}
+		}
+		jobs = append(jobs, info)
+	}
+	return jobs
+}
+
+// consumeTriggers 取出并执行手动触发请求，关闭时等待已触发的任务执行完成
+func consumeTriggers(ctx context.Context) {
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	for ctx.Err() == nil {
+		jobName, err := cronctl.NextTrigger(ctx, triggerPollTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("读取手动触发请求失败", zap.Error(err))
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if jobName == "" {
+			continue
+		}
+
+		logger.Info("收到手动触发请求", zap.String("任务", jobName))
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			executeJob(jobName)
+		}()
+	}
+}
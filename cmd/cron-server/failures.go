@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/cronctl"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/notify"
+	"go.uber.org/zap"
+)
+
+// autoDisableActor 自动禁用任务时审计日志中的操作人
+const autoDisableActor = "cron-server"
+
+// recoverJob 捕获任务调度、执行过程中任务之外的 panic（如读取禁用状态、记录结果），
+// 避免一个任务的 panic 使整个定时任务服务退出，应以 defer 调用
+func recoverJob(jobName string) {
+	if r := recover(); r != nil {
+		logger.Error("执行定时任务时发生 panic",
+			zap.String("任务", jobName),
+			zap.Any("error", r),
+			zap.Stack("stacktrace"),
+		)
+	}
+}
+
+// trackFailures 记录任务的连续失败次数，达到 cron.max_failures 时在运行时禁用任务并通知运维
+// 只在恰好达到上限时禁用和通知一次；运维排查后通过管理接口重新启用，连续失败次数随之清零
+// 参数:
+//
+//	ctx: 上下文
+//	jobName: 任务名称
+//	runErr: 本次执行的错误，nil 表示成功
+func trackFailures(ctx context.Context, jobName string, runErr error) {
+	failures, err := cronctl.RecordResult(ctx, jobName, runErr)
+	if err != nil {
+		logger.Warn("记录任务连续失败次数失败", zap.String("任务", jobName), zap.Error(err))
+		return
+	}
+	metrics.SetCronJobFailures(jobName, failures)
+
+	max := config.GlobalConfig.Cron.GetMaxFailures(jobName)
+	if runErr == nil || max <= 0 || failures != int64(max) {
+		return
+	}
+
+	if err := cronctl.SetDisabled(ctx, jobName, true, autoDisableActor); err != nil {
+		logger.Error("自动禁用任务失败", zap.String("任务", jobName), zap.Error(err))
+		return
+	}
+	logger.Warn("任务连续失败，已自动禁用",
+		zap.String("任务", jobName),
+		zap.Int64("连续失败次数", failures),
+	)
+
+	msg := notify.Message{
+		Event: "cron.job_disabled",
+		Title: fmt.Sprintf("定时任务 %s 已自动禁用", jobName),
+		Text: fmt.Sprintf("定时任务 %s 连续失败 %d 次，已停止定时调度。最近一次错误: %v\n排查后可通过 POST /api/v1/admin/jobs/%s/enable 重新启用。",
+			jobName, failures, runErr, jobName),
+		Data: map[string]interface{}{
+			"job":      jobName,
+			"failures": failures,
+			"error":    runErr.Error(),
+		},
+	}
+	if err := notify.Send(ctx, msg); err != nil {
+		logger.Error("发送任务禁用通知失败", zap.String("任务", jobName), zap.Error(err))
+	}
+}
@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/robfig/cron/v3"
+	"github.com/zhang/microservice/internal/audit"
 	"github.com/zhang/microservice/internal/cache"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/jobrun"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/notify"
+	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
+	"github.com/zhang/microservice/internal/security"
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -29,62 +42,98 @@ func main() {
 		os.Exit(1)
 	}
 	defer logger.Sync()
+	logger.WatchSignal()
+
+	// JWT 签名配置（release 模式下未配置密钥时配置校验已失败）
+	jwtConfig, err := middleware.LoadJWTConfig(config.GlobalConfig.JWT)
+	if err != nil {
+		logger.Fatal("加载 JWT 密钥失败", zap.Error(err))
+	}
+	middleware.SetJWTConfig(jwtConfig)
+	if !config.GlobalConfig.JWT.Asymmetric() && config.GlobalConfig.JWT.UsesDefaultSecret() {
+		logger.Warn("未配置 jwt.secret，使用默认占位密钥，仅限开发环境")
+	}
+
+	// 配置文件修改后热加载可安全重载的配置段（日志级别、限流、CORS、定时任务启用状态）
+	config.Watch(func(err error) {
+		logger.Warn("重新加载配置文件", zap.Error(err))
+	})
 
 	logger.Info("定时任务服务启动中...")
 
+	// 依赖尚未就绪时的等待与重试、外部依赖熔断（需在初始化数据库、Redis、消息队列和 S3 之前设置）
+	retry.Init(config.GlobalConfig.Retry)
+	resilience.Init(config.GlobalConfig.CircuitBreaker)
+	audit.Init(config.GlobalConfig.Audit)
+
 	// 初始化数据库
 	if err := database.Init(config.GlobalConfig.Database); err != nil {
 		logger.Fatal("初始化数据库失败", zap.Error(err))
 	}
 	defer database.Close()
 
+	// 表结构由 migrate 命令迁移，未迁移或版本不兼容时拒绝启动（部分部署）
+	if err := migrate.CheckSchema(context.Background()); err != nil {
+		logger.Fatal("表结构版本不兼容", zap.Error(err))
+	}
+
 	// 初始化 Redis
 	if err := cache.Init(config.GlobalConfig.Redis); err != nil {
 		logger.Fatal("初始化 Redis 失败", zap.Error(err))
 	}
 	defer cache.Close()
 
-	// 检查是否启用定时任务
-	if !config.GlobalConfig.Cron.Enable {
-		logger.Info("定时任务未启用")
-		return
+	// 初始化 S3 存储
+	if err := storage.Init(config.GlobalConfig.AWS); err != nil {
+		logger.Fatal("初始化 S3 存储失败", zap.Error(err))
 	}
 
-	// 创建定时任务调度器
-	c := cron.New(cron.WithSeconds())
+	// 数据库变更通知
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
 
-	// 注册定时任务
-	for _, job := range config.GlobalConfig.Cron.Jobs {
-		if !job.Enabled {
-			logger.Info("跳过未启用的任务", zap.String("任务", job.Name))
-			continue
+	notifyCfg := config.GlobalConfig.Database.Notify
+	if notifyCfg.Enable {
+		if notifyCfg.EventRoutingKey != "" {
+			if err := security.InitKeyProvider(config.GlobalConfig.Security); err != nil {
+				logger.Fatal("初始化密钥失败", zap.Error(err))
+			}
+			if err := queue.Init(config.GlobalConfig.RabbitMQ); err != nil {
+				logger.Fatal("初始化消息队列失败", zap.Error(err))
+			}
+			defer queue.Close()
 		}
-
-		// 复制变量避免闭包问题
-		jobName := job.Name
-		jobSpec := job.Spec
-
-		// 添加任务
-		_, err := c.AddFunc(jobSpec, func() {
-			executeJob(jobName)
-		})
-		if err != nil {
-			logger.Error("注册定时任务失败",
-				zap.String("任务", jobName),
-				zap.Error(err),
-			)
-			continue
+		if err := startChangeListener(bgCtx, notifyCfg); err != nil {
+			logger.Fatal("启动数据库变更监听失败", zap.Error(err))
 		}
+	}
 
-		logger.Info("注册定时任务成功",
-			zap.String("任务", jobName),
-			zap.String("表达式", jobSpec),
-		)
+	// 暴露 Prometheus 指标（任务执行结果、队列深度等）
+	if port := config.GlobalConfig.Metrics.CronPort; port > 0 {
+		go metrics.Serve(port, config.GlobalConfig.Metrics.Path)
 	}
 
-	// 启动调度器
-	c.Start()
-	logger.Info("定时任务服务启动成功")
+	// 推送关键指标（任务耗时等）到 CloudWatch / StatsD
+	waitPusher, err := metrics.StartPusher(bgCtx, config.GlobalConfig.Metrics.Export, config.GlobalConfig.AWS)
+	if err != nil {
+		logger.Fatal("初始化指标推送失败", zap.Error(err))
+	}
+
+	// 任务连续失败被自动禁用时通知运维
+	notify.Init(config.GlobalConfig.Notify)
+
+	// 检查是否启用定时任务，启用时同时接受管理接口的手动触发与运行时启停
+	var s *scheduler
+	waitControl := func() {}
+	if config.GlobalConfig.Cron.Enable {
+		s = startCron()
+		waitControl = startControl(bgCtx, s)
+	} else {
+		logger.Info("定时任务未启用")
+		if !notifyCfg.Enable {
+			return
+		}
+	}
 
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
@@ -94,23 +143,56 @@ func main() {
 	logger.Info("正在关闭定时任务服务...")
 
 	// 停止调度器
-	ctx := c.Stop()
-	<-ctx.Done()
+	if s != nil {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+	}
+
+	bgCancel()
+	waitControl()
+	waitPusher()
 
 	logger.Info("定时任务服务已关闭")
 }
 
+// startCron 注册并启动配置中启用的定时任务，配置热加载后按新的启用状态增删任务
+// 返回:
+//
+//	*scheduler: 已启动的调度器
+func startCron() *scheduler {
+	// 创建定时任务调度器
+	s := newScheduler()
+
+	// 注册定时任务
+	s.apply(config.GlobalConfig.Cron.Jobs)
+	config.OnReload(func(_, next *config.Config) {
+		s.apply(next.Cron.Jobs)
+	})
+
+	// 启动调度器
+	s.cron.Start()
+	logger.Info("定时任务服务启动成功")
+	return s
+}
+
+// jobLockTTL 任务锁的过期时间，执行期间由看门狗自动续期；进程异常退出后最多经过该时间锁被释放
+const jobLockTTL = time.Minute
+
 // executeJob 执行定时任务
-// 使用分布式锁确保任务不会重复执行
+// 使用分布式锁确保任务不会重复执行，执行时间超过锁的过期时间时自动续期。
+// 每次执行是独立的故障域：panic 只记为本次失败（不影响调度器和其他任务），超过 cron.job_timeout 取消任务上下文，
+// 结果计入指标、执行历史和连续失败次数，连续失败达到 cron.max_failures 时自动禁用任务（见 trackFailures）
 // 参数:
 //
 //	jobName: 任务名称
 func executeJob(jobName string) {
+	defer recoverJob(jobName)
+
 	ctx := context.Background()
 	lockKey := fmt.Sprintf("cron:lock:%s", jobName)
 
-	// 尝试获取分布式锁（5分钟过期）
-	locked, err := cache.Lock(ctx, lockKey, 5*time.Minute)
+	// 尝试获取分布式锁（看门狗自动续期）
+	lock, locked, err := cache.LockWithWatchdog(ctx, lockKey, jobLockTTL)
 	if err != nil {
 		logger.Error("获取任务锁失败",
 			zap.String("任务", jobName),
@@ -128,7 +210,7 @@ func executeJob(jobName string) {
 
 	// 确保释放锁
 	defer func() {
-		if err := cache.Unlock(ctx, lockKey); err != nil {
+		if err := lock.Release(ctx); err != nil {
 			logger.Error("释放任务锁失败",
 				zap.String("任务", jobName),
 				zap.Error(err),
@@ -139,46 +221,186 @@ func executeJob(jobName string) {
 	logger.Info("开始执行定时任务", zap.String("任务", jobName))
 	startTime := time.Now()
 
-	// 根据任务名称执行相应的任务
+	err = runWithTimeout(lock.Context(), jobName, config.GlobalConfig.Cron.GetJobTimeout(jobName))
+
+	finishTime := time.Now()
+	duration := finishTime.Sub(startTime)
+	metrics.ObserveCronJob(jobName, duration, err)
+	jobrun.Record(ctx, jobName, startTime, finishTime, err)
+	trackFailures(ctx, jobName, err)
+
+	if err != nil {
+		logger.Error("定时任务执行失败",
+			zap.String("任务", jobName),
+			zap.Duration("耗时", duration),
+			zap.Error(err),
+		)
+		return
+	}
+	logger.Info("定时任务执行完成",
+		zap.String("任务", jobName),
+		zap.Duration("耗时", duration),
+	)
+}
+
+// runWithTimeout 在超时上下文中执行任务
+// 任务通过上下文感知取消；执行时间超过超时时间的任务即使返回成功也记为失败
+// 参数:
+//
+//	ctx: 上下文，任务锁丢失时被取消
+//	jobName: 任务名称
+//	timeout: 超时时间，0 表示不限制
+//
+// 返回:
+//
+//	error: 任务错误
+func runWithTimeout(ctx context.Context, jobName string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return runJob(ctx, jobName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := runJob(ctx, jobName)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+		return fmt.Errorf("任务执行超过 %s: %w", timeout, err)
+	}
+	return err
+}
+
+// runJob 根据任务名称执行相应的任务，任务 panic 时记录堆栈并转换为错误
+// 参数:
+//
+//	ctx: 上下文，任务锁丢失或超时时被取消
+//	jobName: 任务名称
+//
+// 返回:
+//
+//	error: 任务错误
+func runJob(ctx context.Context, jobName string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("定时任务发生 panic",
+				zap.String("任务", jobName),
+				zap.Any("error", r),
+				zap.Stack("stacktrace"),
+			)
+			err = fmt.Errorf("任务 panic: %v", r)
+		}
+	}()
+
 	switch jobName {
 	case "clean_expired_data":
-		cleanExpiredData()
+		return cleanExpiredData()
 	case "daily_statistics":
-		dailyStatistics()
+		return dailyStatistics()
+	case "clean_claim_checks":
+		return cleanClaimChecks()
+	case "clean_archives":
+		return cleanArchives()
+	case "anchor_audit_chain":
+		return anchorAuditChain(ctx)
+	case "purge_deleted_users":
+		return purgeDeletedUsers(ctx)
 	case "health_check":
-		healthCheck()
+		return healthCheck()
 	default:
-		logger.Warn("未知的任务", zap.String("任务", jobName))
+		return fmt.Errorf("未知的任务: %s", jobName)
 	}
-
-	duration := time.Since(startTime)
-	logger.Info("定时任务执行完成",
-		zap.String("任务", jobName),
-		zap.Duration("耗时", duration),
-	)
 }
 
 // cleanExpiredData 清理过期数据任务
-func cleanExpiredData() {
+func cleanExpiredData() error {
 	logger.Info("执行清理过期数据任务")
 	// TODO: 实现具体的清理逻辑
 	// 例如：删除过期的缓存、日志、临时文件等
+	return nil
 }
 
 // dailyStatistics 每日统计任务
-func dailyStatistics() {
+func dailyStatistics() error {
 	logger.Info("执行每日统计任务")
 	// TODO: 实现具体的统计逻辑
 	// 例如：统计用户数、订单数、收入等
+	return nil
+}
+
+// cleanClaimChecks 清理过期的超大消息转存对象
+// 消费成功的对象会被立即删除，这里只清理消费失败遗留的对象
+func cleanClaimChecks() error {
+	cfg := config.GlobalConfig.RabbitMQ.ClaimCheck
+	if !cfg.Enable {
+		return nil
+	}
+	if storage.S3Storage == nil {
+		return storage.ErrUnavailable
+	}
+	// 配置校验已保证前缀非空、保留时间大于 0，这里再兜底，避免误删整个存储桶或仍在传递中的对象
+	if strings.Trim(cfg.GetPrefix(), "/") == "" || cfg.Expire <= 0 {
+		return fmt.Errorf("转存对象前缀或保留时间无效，跳过清理")
+	}
+
+	deleted, err := storage.S3Storage.DeleteExpired(cfg.GetPrefix(), time.Now().Add(-cfg.GetExpire()))
+	if err != nil {
+		return fmt.Errorf("清理转存对象失败: %w", err)
+	}
+
+	logger.Info("清理转存对象完成", zap.Int("数量", deleted))
+	return nil
+}
+
+// cleanArchives 清理过期的异步打包结果
+func cleanArchives() error {
+	if storage.S3Storage == nil {
+		return storage.ErrUnavailable
+	}
+	cfg := config.GlobalConfig.AWS.S3.Archive
+
+	deleted, err := storage.S3Storage.DeleteExpired(cfg.GetPrefix(), time.Now().Add(-cfg.GetExpire()))
+	if err != nil {
+		return fmt.Errorf("清理打包结果失败: %w", err)
+	}
+
+	logger.Info("清理打包结果完成", zap.Int("数量", deleted))
+	return nil
+}
+
+// anchorAuditChain 将审计链头写入 S3，用于发现历史被重写
+func anchorAuditChain(ctx context.Context) error {
+	if _, err := audit.WriteAnchor(ctx, config.GlobalConfig.Audit); err != nil {
+		return fmt.Errorf("写入审计链锚点失败: %w", err)
+	}
+	return nil
+}
+
+// purgeDeletedUsers 永久删除软删除超过 users.purge_after_days 天的用户
+func purgeDeletedUsers(ctx context.Context) error {
+	users := service.NewUserService(service.NewGormUserRepository(database.DB))
+	before := time.Now().Add(-config.GlobalConfig.Users.GetPurgeAfter())
+
+	purged, err := users.PurgeDeletedUsers(ctx, before)
+	if err != nil {
+		return fmt.Errorf("清理已删除用户失败: %w", err)
+	}
+
+	logger.Info("清理已删除用户完成", zap.Int64("数量", purged))
+	return nil
 }
 
-// healthCheck 健康检查任务
-func healthCheck() {
+// healthCheck 健康检查任务，任一依赖异常时返回错误
+func healthCheck() error {
 	logger.Debug("执行健康检查任务")
 
+	var errs []error
+
 	// 检查数据库
 	if err := database.HealthCheck(); err != nil {
 		logger.Error("数据库健康检查失败", zap.Error(err))
+		errs = append(errs, fmt.Errorf("数据库: %w", err))
 	} else {
 		logger.Debug("数据库健康检查通过")
 	}
@@ -186,7 +408,10 @@ func healthCheck() {
 	// 检查 Redis
 	if err := cache.HealthCheck(); err != nil {
 		logger.Error("Redis 健康检查失败", zap.Error(err))
+		errs = append(errs, fmt.Errorf("Redis: %w", err))
 	} else {
 		logger.Debug("Redis 健康检查通过")
 	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// scheduler 定时任务调度器，记录已注册的任务以便按启用状态增删
+type scheduler struct {
+	cron *cron.Cron
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID
+}
+
+// newScheduler 创建调度器（支持秒级表达式）
+func newScheduler() *scheduler {
+	return &scheduler{
+		cron:    cron.New(cron.WithSeconds()),
+		entries: make(map[string]cron.EntryID),
+	}
+}
+
+// apply 按任务配置的启用状态注册或移除任务，已注册的任务保持不变
+// 参数:
+//
+//	jobs: 任务配置
+func (s *scheduler) apply(jobs []config.JobConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range jobs {
+		id, registered := s.entries[job.Name]
+		switch {
+		case job.Enabled && !registered:
+			s.add(job)
+		case !job.Enabled && registered:
+			s.cron.Remove(id)
+			delete(s.entries, job.Name)
+			logger.Info("已停止调度定时任务", zap.String("任务", job.Name))
+		case !job.Enabled:
+			logger.Info("跳过未启用的任务", zap.String("任务", job.Name))
+		}
+	}
+}
+
+// add 注册任务，调用方需持有锁
+func (s *scheduler) add(job config.JobConfig) {
+	// 复制变量避免闭包问题
+	jobName := job.Name
+
+	// 添加任务（到点时跳过运行时被禁用的任务）
+	id, err := s.cron.AddFunc(job.Spec, func() {
+		runScheduled(jobName)
+	})
+	if err != nil {
+		logger.Error("注册定时任务失败",
+			zap.String("任务", jobName),
+			zap.Error(err),
+		)
+		return
+	}
+	s.entries[jobName] = id
+
+	logger.Info("注册定时任务成功",
+		zap.String("任务", jobName),
+		zap.String("表达式", job.Spec),
+	)
+}
+
+// entry 获取已注册任务的调度信息
+// 参数:
+//
+//	name: 任务名称
+//
+// 返回:
+//
+//	cron.Entry: 调度信息（上次/下次执行时间）
+//	bool: 任务是否已注册
+func (s *scheduler) entry(name string) (cron.Entry, bool) {
+	s.mu.Lock()
+	id, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return cron.Entry{}, false
+	}
+	return s.cron.Entry(id), true
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// withAltSvc 在响应中添加 Alt-Svc 头，告知客户端可以在指定 UDP 端口上使用 HTTP/3
+// 参数:
+//
+//	next: 原 handler
+//	port: HTTP/3 端口
+//	maxAge: 客户端缓存该信息的时长
+//
+// 返回:
+//
+//	http.Handler: 包装后的 handler
+func withAltSvc(next http.Handler, port int, maxAge time.Duration) http.Handler {
+	value := fmt.Sprintf(`h3=":%d"; ma=%d`, port, int(maxAge.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", value)
+		next.ServeHTTP(w, r)
+	})
+}
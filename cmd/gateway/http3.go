@@ -0,0 +1,64 @@
+//go:build http3
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// startHTTP3 在 UDP 端口上启动 HTTP/3 监听，与 TCP 监听共用同一个 handler
+// 参数:
+//
+//	cfg: HTTP/3 配置
+//	port: UDP 端口
+//	handler: 路由（含全部中间件）
+//
+// 返回:
+//
+//	func(context.Context) error: 关闭监听
+//	error: 证书或端口错误
+func startHTTP3(cfg config.HTTP3Config, port int, handler http.Handler) (func(context.Context) error, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("HTTP/3 需要配置 cert_file 与 key_file")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载 HTTP/3 证书失败: %w", err)
+	}
+
+	addr := fmt.Sprintf(":%d", port)
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("监听 UDP 端口失败: %w", err)
+	}
+
+	srv := &http3.Server{
+		Handler: handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS13,
+		}),
+	}
+	go func() {
+		logger.Info("HTTP/3 监听启动成功", zap.String("地址", addr))
+		if err := srv.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP/3 监听异常退出", zap.Error(err))
+		}
+	}()
+
+	return func(context.Context) error {
+		err := srv.Close()
+		conn.Close()
+		return err
+	}, nil
+}
@@ -0,0 +1,16 @@
+//go:build !http3
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// startHTTP3 默认构建不包含 quic-go，启用 HTTP/3 时返回错误
+func startHTTP3(cfg config.HTTP3Config, port int, handler http.Handler) (func(context.Context) error, error) {
+	return nil, errors.New("当前构建未包含 HTTP/3 支持，请使用 http3 构建标签编译（make build-http3）")
+}
@@ -9,14 +9,31 @@ import (
 	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/activity"
+	"github.com/zhang/microservice/internal/audit"
 	"github.com/zhang/microservice/internal/cache"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/failures"
+	"github.com/zhang/microservice/internal/flags"
 	"github.com/zhang/microservice/internal/handler"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
 	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/notify"
+	"github.com/zhang/microservice/internal/proxyproto"
 	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/quota"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
+	"github.com/zhang/microservice/internal/security"
+	"github.com/zhang/microservice/internal/settings"
+	"github.com/zhang/microservice/internal/slo"
 	"github.com/zhang/microservice/internal/storage"
+	"github.com/zhang/microservice/internal/timezone"
+	"github.com/zhang/microservice/internal/usersettings"
 	"go.uber.org/zap"
 )
 
@@ -33,37 +50,128 @@ func main() {
 		os.Exit(1)
 	}
 	defer logger.Sync()
+	logger.WatchSignal()
+
+	// JWT 签名配置（release 模式下未配置密钥时配置校验已失败）
+	jwtConfig, err := middleware.LoadJWTConfig(config.GlobalConfig.JWT)
+	if err != nil {
+		logger.Fatal("加载 JWT 密钥失败", zap.Error(err))
+	}
+	middleware.SetJWTConfig(jwtConfig)
+	if !config.GlobalConfig.JWT.Asymmetric() && config.GlobalConfig.JWT.UsesDefaultSecret() {
+		logger.Warn("未配置 jwt.secret，使用默认占位密钥，仅限开发环境")
+	}
+
+	// 配置文件修改后热加载可安全重载的配置段（日志级别、限流、CORS、定时任务启用状态）
+	config.Watch(func(err error) {
+		logger.Warn("重新加载配置文件", zap.Error(err))
+	})
 
 	logger.Info("网关服务启动中...")
 
+	// 依赖尚未就绪时的等待与重试、外部依赖熔断（需在初始化数据库、Redis、消息队列和 S3 之前设置）
+	retry.Init(config.GlobalConfig.Retry)
+	resilience.Init(config.GlobalConfig.CircuitBreaker)
+	audit.Init(config.GlobalConfig.Audit)
+
 	// 初始化数据库
 	if err := database.Init(config.GlobalConfig.Database); err != nil {
 		logger.Fatal("初始化数据库失败", zap.Error(err))
 	}
 	defer database.Close()
 
+	// 表结构由 migrate 命令迁移，未迁移或版本不兼容时拒绝启动（部分部署）
+	if err := migrate.CheckSchema(context.Background()); err != nil {
+		logger.Fatal("表结构版本不兼容", zap.Error(err))
+	}
+
 	// 初始化 Redis
 	if err := cache.Init(config.GlobalConfig.Redis); err != nil {
 		logger.Fatal("初始化 Redis 失败", zap.Error(err))
 	}
 	defer cache.Close()
 
+	// 初始化 S3 存储（消息队列的超大消息转存依赖它）
+	if err := storage.Init(config.GlobalConfig.AWS); err != nil {
+		logger.Fatal("初始化 S3 存储失败", zap.Error(err))
+	}
+
+	// 初始化密钥
+	if err := security.InitKeyProvider(config.GlobalConfig.Security); err != nil {
+		logger.Fatal("初始化密钥失败", zap.Error(err))
+	}
+	security.InitPasswordHasher(config.GlobalConfig.Security.Password)
+
 	// 初始化消息队列
 	if err := queue.Init(config.GlobalConfig.RabbitMQ); err != nil {
 		logger.Fatal("初始化消息队列失败", zap.Error(err))
 	}
 	defer queue.Close()
 
-	// 初始化 S3 存储
-	if err := storage.Init(config.GlobalConfig.AWS); err != nil {
-		logger.Fatal("初始化 S3 存储失败", zap.Error(err))
+	// 后台任务（运行时配置同步等），关闭时取消
+	bgCtx, bgCancel := context.WithCancel(context.Background())
+	defer bgCancel()
+
+	// 同步数据库中的运行时配置，配置文件热加载后基于新的文件配置重新计算
+	settings.Init(config.GlobalConfig.RuntimeSettings)
+	reconciler := newSettingsReconciler()
+	config.OnReload(func(_, _ *config.Config) {
+		reconciler.Reapply()
+	})
+	go reconciler.Run(bgCtx)
+
+	// 热点缓存预刷新（各模块在注册路由时注册缓存类别）
+	cache.InitRefresher(config.GlobalConfig.Redis.Refresh)
+	go cache.DefaultRefresher.Run(bgCtx)
+
+	// 跨实例事件（Redis 发布/订阅，见 cache.Publish / cache.Subscribe）
+	go cache.DefaultEventBus.Run(bgCtx)
+
+	// 配额提醒及通知渠道
+	notify.Init(config.GlobalConfig.Notify)
+	quota.Init(config.GlobalConfig.Quota)
+	flags.Init(config.GlobalConfig.Flags)
+
+	// 响应时间戳的默认时区
+	if err := timezone.Init(config.GlobalConfig.Timezone); err != nil {
+		logger.Fatal("初始化默认时区失败", zap.Error(err))
+	}
+
+	// 最近失败请求记录（failures 中间件）
+	failures.Init(config.GlobalConfig.Middleware.FailureCapture)
+
+	// 用户设置项（值的结构由配置声明）
+	if err := usersettings.Init(config.GlobalConfig.UserSettings); err != nil {
+		logger.Fatal("初始化用户设置失败", zap.Error(err))
+	}
+
+	// 用户活跃时间批量写入
+	activity.Init(config.GlobalConfig.Activity)
+	activityDone := make(chan struct{})
+	go func() {
+		activity.DefaultTracker.Run(bgCtx)
+		close(activityDone)
+	}()
+
+	// 依赖健康状态、SLO 指标，并按配置推送关键指标到 CloudWatch / StatsD
+	metrics.RegisterHealthCheck("database", database.HealthCheck)
+	metrics.RegisterHealthCheck("redis", cache.HealthCheck)
+	if err := slo.Init(config.GlobalConfig.SLO); err != nil {
+		logger.Fatal("初始化 SLO 统计失败", zap.Error(err))
+	}
+	waitPusher, err := metrics.StartPusher(bgCtx, config.GlobalConfig.Metrics.Export, config.GlobalConfig.AWS)
+	if err != nil {
+		logger.Fatal("初始化指标推送失败", zap.Error(err))
 	}
 
 	// 设置 Gin 模式
 	gin.SetMode(config.GlobalConfig.Server.Mode)
 
 	// 创建路由
-	router := setupRouter()
+	router, err := setupRouter()
+	if err != nil {
+		logger.Fatal("创建路由失败", zap.Error(err))
+	}
 
 	// 创建 HTTP 服务器
 	addr := fmt.Sprintf(":%d", config.GlobalConfig.Server.GatewayPort)
@@ -72,13 +180,28 @@ func main() {
 		Handler: router,
 	}
 
-	// 启动服务器
+	// 实验性 HTTP/3 监听，共用同一套路由与中间件，TCP 响应通过 Alt-Svc 告知客户端升级
+	stopHTTP3 := func(context.Context) error { return nil }
+	if h3 := config.GlobalConfig.Server.HTTP3; h3.Enable {
+		port := h3.GetPort(config.GlobalConfig.Server.GatewayPort)
+		stopHTTP3, err = startHTTP3(h3, port, router)
+		if err != nil {
+			logger.Fatal("启动 HTTP/3 监听失败", zap.Error(err))
+		}
+		srv.Handler = withAltSvc(router, port, h3.GetAltSvcMaxAge())
+	}
+
+	// 启动服务器（按配置解析负载均衡的 PROXY 协议头）
+	lis, err := proxyproto.Listen(addr, config.GlobalConfig.Server.Proxy)
+	if err != nil {
+		logger.Fatal("创建监听器失败", zap.Error(err))
+	}
 	go func() {
 		logger.Info("网关服务启动成功",
 			zap.String("地址", addr),
 			zap.String("模式", config.GlobalConfig.Server.Mode),
 		)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(lis); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("启动服务器失败", zap.Error(err))
 		}
 	}()
@@ -100,6 +223,16 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("服务器强制关闭", zap.Error(err))
 	}
+	if err := stopHTTP3(ctx); err != nil {
+		logger.Error("关闭 HTTP/3 监听失败", zap.Error(err))
+	}
+
+	closeTranscoding()
+
+	// 停止后台任务，等待剩余的活跃记录与指标写入
+	bgCancel()
+	<-activityDone
+	waitPusher()
 
 	logger.Info("服务器已关闭")
 }
@@ -108,29 +241,45 @@ func main() {
 // 返回:
 //
 //	*gin.Engine: Gin 路由引擎
-func setupRouter() *gin.Engine {
+//	error: 中间件链配置错误
+func setupRouter() (*gin.Engine, error) {
 	router := gin.New()
 
-	// 使用中间件
-	router.Use(middleware.Recovery())
-	router.Use(middleware.Logger())
-	router.Use(middleware.CORS(config.GlobalConfig.Middleware.CORS))
-	router.Use(middleware.RateLimit(config.GlobalConfig.Middleware.RateLimit))
+	// 真实客户端地址：只采信受信任代理的转发头，Forwarded 与 X-Forwarded-For 统一处理
+	if err := middleware.TrustProxies(router, config.GlobalConfig.Server.Proxy); err != nil {
+		return nil, err
+	}
+	router.Use(middleware.ClientIP(config.GlobalConfig.Server.Proxy))
+
+	// 按配置的顺序加载中间件
+	mwConfig := config.GlobalConfig.Middleware
+	if err := middleware.ValidateChains(mwConfig); err != nil {
+		return nil, err
+	}
+	globalChain, err := middleware.Chain(mwConfig, "global")
+	if err != nil {
+		return nil, err
+	}
+	apiChain, err := middleware.Chain(mwConfig, "api")
+	if err != nil {
+		return nil, err
+	}
+	router.Use(globalChain...)
 
 	// 健康检查
 	router.GET("/health", handler.HealthCheck())
 	router.GET("/health/detail", handler.DetailedHealthCheck())
 
-	// API 路由组
-	v1 := router.Group("/api/v1")
-	{
-		// 文件上传
-		v1.POST("/upload", handler.UploadFile())
-		v1.GET("/presigned-url", handler.GetPresignedURL())
-
-		// 消息队列
-		v1.POST("/message", handler.PublishMessage())
+	// Prometheus 指标
+	metricsPath := config.GlobalConfig.Metrics.Path
+	if metricsPath == "" {
+		metricsPath = "/metrics"
 	}
+	router.GET(metricsPath, gin.WrapH(metrics.Handler()))
+
+	// API 路由组，按功能开关挂载各模块（见 modules.go）
+	v1 := router.Group("/api/v1", apiChain...)
+	module.SetupRoutes(v1, module.Deps{Config: config.GlobalConfig})
 
-	return router
+	return router, nil
 }
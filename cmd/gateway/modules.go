@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+)
+
+// init 注册网关编译进来的功能模块
+// 是否挂载由配置文件 features 开关决定；删除对应行即可从二进制中移除该模块
+func init() {
+	module.RegisterRoutes("upload", handler.RegisterUploadRoutes)
+	module.RegisterRoutes("messaging", handler.RegisterMessageRoutes)
+	module.RegisterRoutes("admin", handler.RegisterAdminRoutes)
+	module.RegisterRoutes("activity", handler.RegisterActivityRoutes)
+	module.RegisterRoutes("me", handler.RegisterMeRoutes)
+	module.RegisterRoutes("auth", handler.RegisterAuthRoutes)
+	module.RegisterRoutes("users", handler.RegisterUserRoutes)
+	module.RegisterRoutes("transcoding", registerTranscodingRoutes)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/settings"
+)
+
+// newSettingsReconciler 创建运行时配置同步器
+// 配置项被删除时恢复为配置文件中的值（已声明的队列不会被删除）
+// 返回:
+//
+//	*settings.Reconciler: 配置同步器
+func newSettingsReconciler() *settings.Reconciler {
+	r := settings.NewReconciler(config.GlobalConfig.RuntimeSettings.GetReconcileInterval())
+
+	r.Watch(settings.KeyCORSAllowOrigins, func(value []byte) error {
+		cfg := config.GlobalConfig.Middleware.CORS
+		if value != nil {
+			if err := json.Unmarshal(value, &cfg.AllowOrigins); err != nil {
+				return err
+			}
+		}
+		middleware.UpdateCORSConfig(cfg)
+		return nil
+	})
+
+	r.Watch(settings.KeyRateLimit, func(value []byte) error {
+		cfg := config.GlobalConfig.Middleware.RateLimit
+		if value != nil {
+			var v settings.RateLimitValue
+			if err := json.Unmarshal(value, &v); err != nil {
+				return err
+			}
+			cfg.RequestsPerSecond = v.RequestsPerSecond
+			cfg.Burst = v.Burst
+		}
+		middleware.UpdateRateLimitConfig(cfg)
+		return nil
+	})
+
+	r.Watch(settings.KeyQueues, func(value []byte) error {
+		if value == nil {
+			return nil
+		}
+
+		var list []settings.QueueValue
+		if err := json.Unmarshal(value, &list); err != nil {
+			return err
+		}
+
+		queues := make([]config.QueueConfig, 0, len(list))
+		for _, q := range list {
+			queues = append(queues, config.QueueConfig{
+				Name:           q.Name,
+				RoutingKey:     q.RoutingKey,
+				Durable:        q.Durable,
+				ProcessTimeout: q.ProcessTimeout,
+			})
+		}
+		return queue.MQClient.DeclareQueues(queues)
+	})
+
+	return r
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/grpcclient"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/transcode"
+	pb "github.com/zhang/microservice/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// rpcConn 转码使用的 gRPC 连接，关闭服务时释放
+var rpcConn *grpc.ClientConn
+
+// registerTranscodingRoutes 注册 gRPC HTTP 转码路由
+// 路由由 proto 描述符生成，新增 RPC 后无需修改网关代码；
+// 请求需通过 JWT 认证，Authorization 头随调用转发，gRPC 服务端的 GRPCAuth 拦截器据此识别调用方并按方法校验角色
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func registerTranscodingRoutes(r *gin.RouterGroup, deps module.Deps) {
+	// 消息大小、keepalive、连接超时与 gRPC 服务端配置一致
+	conn, err := grpcclient.Dial(deps.Config.GRPC)
+	if err != nil {
+		logger.Error("创建 gRPC 连接失败，跳过 HTTP 转码", zap.String("target", deps.Config.GRPC.Target), zap.Error(err))
+		return
+	}
+	rpcConn = conn
+
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(pb.UserService_ServiceDesc.ServiceName))
+	if err != nil {
+		logger.Error("查找 proto 服务描述符失败", zap.Error(err))
+		return
+	}
+
+	t := transcode.New(conn, desc.(protoreflect.ServiceDescriptor))
+	t.Register(r.Group("/rpc", middleware.JWTAuth()))
+	logger.Info("gRPC HTTP 转码已启用", zap.Strings("methods", t.Methods()))
+}
+
+// closeTranscoding 关闭转码使用的 gRPC 连接
+func closeTranscoding() {
+	if rpcConn != nil {
+		rpcConn.Close()
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/pgnotify"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// userChangeOps 触发器操作类型到用户变更类型的映射
+var userChangeOps = map[string]string{
+	pgnotify.OpInsert: service.UserCreated,
+	pgnotify.OpUpdate: service.UserUpdated,
+	pgnotify.OpDelete: service.UserDeleted,
+}
+
+// startUserFeed 监听数据库变更通知并广播 users 表的变更（WatchUsers）
+// 触发器由定时任务服务安装（database.notify.install_triggers）
+// 参数:
+//
+//	ctx: 上下文，取消时停止监听
+//	cfg: 变更通知配置
+func startUserFeed(ctx context.Context, cfg config.ChangeNotifyConfig) {
+	listener := pgnotify.NewListener(config.GlobalConfig.Database.GetDatabaseDSN())
+	listener.Handle(cfg.GetChannel(), func(ctx context.Context, payload string) {
+		publishUserChange(service.DefaultUserFeed, payload)
+	})
+	go listener.Run(ctx)
+}
+
+// publishUserChange 把 users 表的变更通知转为用户变更广播，其他表的通知忽略
+func publishUserChange(feed *service.UserFeed, payload string) {
+	change, err := pgnotify.ParseChange(payload)
+	if err != nil {
+		logger.Warn("解析数据库变更通知失败", zap.String("payload", payload), zap.Error(err))
+		return
+	}
+	if change.Table != (service.User{}).TableName() {
+		return
+	}
+	feed.Publish(service.UserChange{Op: userChangeOps[change.Op], ID: change.ID, At: time.Now()})
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// TestPublishUserChange 只有 users 表的变更进入用户变更广播
+func TestPublishUserChange(t *testing.T) {
+	testutil.InitLogger()
+	feed := service.NewUserFeed(4)
+	changes, cancel := feed.Subscribe()
+	defer cancel()
+
+	publishUserChange(feed, `{"op":"INSERT","table":"orders","id":7}`)
+	publishUserChange(feed, `not json`)
+	publishUserChange(feed, `{"op":"DELETE","table":"users","id":3}`)
+
+	select {
+	case change := <-changes:
+		if change.Op != service.UserDeleted || change.ID != 3 {
+			t.Errorf("变更 = %+v, 期望删除用户 3", change)
+		}
+	default:
+		t.Fatal("users 表的变更应被广播")
+	}
+	if len(changes) != 0 {
+		t.Errorf("其他表的变更不应被广播，剩余 %d 条", len(changes))
+	}
+}
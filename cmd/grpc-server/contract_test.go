@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/testutil"
+	pb "github.com/zhang/microservice/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// TestUserContract 校验 REST JSON 与 gRPC proto 的用户字段映射
+// 用例由 proto 描述符生成：proto 中每个字段都必须在 REST JSON 中以相同名称、等价值出现，
+// REST JSON 中也不能出现 proto 未定义的字段
+func TestUserContract(t *testing.T) {
+	lastSeen := time.Date(2024, 6, 8, 9, 10, 11, 0, time.UTC)
+	sample := &service.User{
+		ID:            42,
+		Name:          "测试用户",
+		Email:         "test@example.com",
+		Phone:         "13800138000",
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt:     time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC),
+		EmailVerified: true,
+		LastSeenAt:    &lastSeen,
+		Timezone:      "Asia/Shanghai",
+		Version:       3,
+	}
+
+	rest := marshalToMap(t, func() ([]byte, error) { return json.Marshal(sample) })
+	grpc := marshalToMap(t, func() ([]byte, error) {
+		return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(toPBUser(sample))
+	})
+
+	fields := (&pb.User{}).ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := string(fd.Name())
+
+		t.Run(name, func(t *testing.T) {
+			restValue, ok := rest[name]
+			if !ok {
+				t.Fatalf("REST 响应缺少 proto 字段 %s", name)
+			}
+			assertEquivalent(t, fd, restValue, grpc[name])
+		})
+	}
+
+	for key := range rest {
+		if fields.ByName(protoreflect.Name(key)) == nil {
+			t.Errorf("REST 响应字段 %s 未在 proto 中定义", key)
+		}
+	}
+}
+
+// marshalToMap 序列化后再解析为 map，便于按字段比较
+func marshalToMap(t *testing.T, marshal func() ([]byte, error)) map[string]interface{} {
+	t.Helper()
+
+	data, err := marshal()
+	if err != nil {
+		t.Fatalf("序列化失败: %v", err)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("解析 JSON 失败: %v", err)
+	}
+	return m
+}
+
+// assertEquivalent 比较同一字段在两种协议下的值
+// 时间字段两端都是 RFC 3339，但时区偏移可能不同，按时间点比较；
+// 其余字段按字面值比较（protojson 把 int64 编码为字符串）
+func assertEquivalent(t *testing.T, fd protoreflect.FieldDescriptor, restValue, grpcValue interface{}) {
+	t.Helper()
+
+	if fd.Message() != nil && fd.Message().FullName() == "google.protobuf.Timestamp" {
+		restTime, err := time.Parse(time.RFC3339Nano, fmt.Sprint(restValue))
+		if err != nil {
+			t.Fatalf("解析 REST 时间失败: %v", err)
+		}
+		grpcTime, err := time.Parse(time.RFC3339Nano, fmt.Sprint(grpcValue))
+		if err != nil {
+			t.Fatalf("解析 gRPC 时间失败: %v", err)
+		}
+		if !restTime.Equal(grpcTime) {
+			t.Errorf("时间不一致: REST %v, gRPC %v", restTime, grpcTime)
+		}
+		return
+	}
+
+	if fmt.Sprint(restValue) != fmt.Sprint(grpcValue) {
+		t.Errorf("值不一致: REST %v, gRPC %v", restValue, grpcValue)
+	}
+}
+
+// contractServers 共用同一个用户服务的进程内 REST 和 gRPC 服务
+type contractServers struct {
+	router *gin.Engine
+	client pb.UserServiceClient
+	users  *service.UserService
+	tokens map[string]string
+}
+
+// newContractServers 启动进程内 REST（与 RegisterUserRoutes 相同的路由和权限）和 gRPC（GRPCAuth 与方法级角色检查）服务，
+// 两端共用内存用户仓库；审计记录写入内存 SQLite
+func newContractServers(t *testing.T) *contractServers {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&audit.Entry{}); err != nil {
+		t.Fatal(err)
+	}
+	prevDB := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = prevDB
+		sqlDB.Close()
+	})
+
+	clock := testutil.NewClock(time.Now())
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	users := service.NewUserService(service.NewMemoryUserRepository())
+
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{
+		Chains: map[string][]string{"global": {"recovery", "request_id"}},
+	}, func(r *gin.RouterGroup) {
+		admin := middleware.RequireRole("admin")
+		g := r.Group("/users", middleware.JWTAuth())
+		g.GET("", handler.ListUsers(users))
+		g.GET("/:id", handler.GetUser(users))
+		g.POST("", admin, handler.CreateUser(users))
+		g.PUT("/:id", admin, handler.UpdateUser(users))
+	})
+
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		pb.RegisterUserServiceServer(s, &server{userService: users})
+	}, grpc.ChainUnaryInterceptor(
+		middleware.GRPCAuth(config.GRPCAuthConfig{Required: true}),
+		middleware.GRPCRequireRole(userMethodRoles),
+	))
+
+	return &contractServers{
+		router: router,
+		client: pb.NewUserServiceClient(conn),
+		users:  users,
+		tokens: map[string]string{
+			"admin": minter.MustMint(t, 1, "admin", time.Hour),
+			"user":  minter.MustMint(t, 2, "user", time.Hour),
+		},
+	}
+}
+
+// rest 以指定角色发送 REST 请求，role 为空时不带令牌
+func (cs *contractServers) rest(t *testing.T, role, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, "/api/v1"+path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("Authorization", testutil.BearerHeader(cs.tokens[role]))
+	}
+	return testutil.Do(cs.router, req)
+}
+
+// ctx 以指定角色调用 gRPC 的上下文，role 为空时不带令牌
+func (cs *contractServers) ctx(role string) context.Context {
+	ctx := context.Background()
+	if role == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", testutil.BearerHeader(cs.tokens[role]))
+}
+
+// seed 直接通过服务创建用户，返回 ID
+func (cs *contractServers) seed(t *testing.T, n int) []int64 {
+	t.Helper()
+
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		user, err := cs.users.CreateUser(context.Background(), &service.User{
+			Name:  fmt.Sprintf("用户%02d", i),
+			Email: fmt.Sprintf("user%02d@example.com", i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// TestUserErrorContract 校验同一种失败在 REST 和 gRPC 中映射为对应的状态码：
+// REST 状态码与 gRPC 状态码经 errs.HTTPStatus 映射后一致，problem 响应的 code 为 gRPC 状态码名称，
+// 字段校验失败时两端列出的字段相同
+func TestUserErrorContract(t *testing.T) {
+	cs := newContractServers(t)
+	existing := cs.seed(t, 1)[0]
+	existingPath := "/users/" + strconv.FormatInt(existing, 10)
+
+	tests := []struct {
+		name   string
+		want   codes.Code
+		rest   func() *httptest.ResponseRecorder
+		grpc   func() error
+		fields []string
+	}{
+		{
+			name: "未认证",
+			want: codes.Unauthenticated,
+			rest: func() *httptest.ResponseRecorder { return cs.rest(t, "", http.MethodGet, existingPath, nil) },
+			grpc: func() error {
+				_, err := cs.client.GetUser(cs.ctx(""), &pb.GetUserRequest{Id: existing})
+				return err
+			},
+		},
+		{
+			name: "非管理员创建用户",
+			want: codes.PermissionDenied,
+			rest: func() *httptest.ResponseRecorder {
+				return cs.rest(t, "user", http.MethodPost, "/users", gin.H{"name": "新用户", "email": "new@example.com"})
+			},
+			grpc: func() error {
+				_, err := cs.client.CreateUser(cs.ctx("user"), &pb.CreateUserRequest{Name: "新用户", Email: "new@example.com"})
+				return err
+			},
+		},
+		{
+			name: "用户不存在",
+			want: codes.NotFound,
+			rest: func() *httptest.ResponseRecorder { return cs.rest(t, "user", http.MethodGet, "/users/999999", nil) },
+			grpc: func() error {
+				_, err := cs.client.GetUser(cs.ctx("user"), &pb.GetUserRequest{Id: 999999})
+				return err
+			},
+		},
+		{
+			name: "更新不存在的用户",
+			want: codes.NotFound,
+			rest: func() *httptest.ResponseRecorder {
+				return cs.rest(t, "admin", http.MethodPut, "/users/999999", gin.H{"name": "改名"})
+			},
+			grpc: func() error {
+				_, err := cs.client.UpdateUser(cs.ctx("admin"), &pb.UpdateUserRequest{
+					Id: 999999, Name: "改名", UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+				})
+				return err
+			},
+		},
+		{
+			name: "字段不合法",
+			want: codes.InvalidArgument,
+			rest: func() *httptest.ResponseRecorder {
+				return cs.rest(t, "admin", http.MethodPost, "/users", gin.H{"name": "新用户", "email": "not-an-email"})
+			},
+			grpc: func() error {
+				_, err := cs.client.CreateUser(cs.ctx("admin"), &pb.CreateUserRequest{Name: "新用户", Email: "not-an-email"})
+				return err
+			},
+			fields: []string{"email"},
+		},
+		{
+			name: "邮箱已存在",
+			want: codes.AlreadyExists,
+			rest: func() *httptest.ResponseRecorder {
+				return cs.rest(t, "admin", http.MethodPost, "/users", gin.H{"name": "重复", "email": "user00@example.com"})
+			},
+			grpc: func() error {
+				_, err := cs.client.CreateUser(cs.ctx("admin"), &pb.CreateUserRequest{Name: "重复", Email: "user00@example.com"})
+				return err
+			},
+		},
+		{
+			name: "版本冲突",
+			want: codes.Aborted,
+			rest: func() *httptest.ResponseRecorder {
+				return cs.rest(t, "admin", http.MethodPut, existingPath, gin.H{"name": "改名", "version": 99})
+			},
+			grpc: func() error {
+				_, err := cs.client.UpdateUser(cs.ctx("admin"), &pb.UpdateUserRequest{
+					Id: existing, Name: "改名", Version: 99, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+				})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.grpc())
+			if st.Code() != tt.want {
+				t.Fatalf("gRPC 状态码 = %s, 期望 %s", st.Code(), tt.want)
+			}
+
+			w := tt.rest()
+			if w.Code != errs.HTTPStatus(tt.want) {
+				t.Fatalf("REST 状态码 = %d, 期望 %d（%s）: %s", w.Code, errs.HTTPStatus(tt.want), tt.want, w.Body.String())
+			}
+
+			var body struct {
+				Code   string `json:"code"`
+				Fields []struct {
+					Field string `json:"field"`
+				} `json:"fields"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("解析 REST 响应失败: %v", err)
+			}
+			// 认证中间件的响应使用自己的错误码（如 AUTH_TOKEN_MISSING），只比较 problem 响应
+			if strings.HasPrefix(w.Header().Get("Content-Type"), errs.ProblemContentType) && body.Code != tt.want.String() {
+				t.Errorf("problem code = %s, 期望 %s", body.Code, tt.want)
+			}
+
+			if tt.fields == nil {
+				return
+			}
+			var restFields, grpcFields []string
+			for _, f := range body.Fields {
+				restFields = append(restFields, f.Field)
+			}
+			for _, d := range st.Details() {
+				if br, ok := d.(*errdetails.BadRequest); ok {
+					for _, v := range br.GetFieldViolations() {
+						grpcFields = append(grpcFields, v.GetField())
+					}
+				}
+			}
+			sort.Strings(restFields)
+			sort.Strings(grpcFields)
+			if fmt.Sprint(restFields) != fmt.Sprint(tt.fields) || fmt.Sprint(grpcFields) != fmt.Sprint(tt.fields) {
+				t.Errorf("字段错误: REST %v, gRPC %v, 期望 %v", restFields, grpcFields, tt.fields)
+			}
+		})
+	}
+}
+
+// listPage 两种协议下同一页的结果
+type listPage struct {
+	IDs        []int64
+	Total      int64
+	Page       int
+	PageSize   int
+	NextCursor string
+}
+
+// restList 通过 REST 查询一页用户
+func (cs *contractServers) restList(t *testing.T, query string) listPage {
+	t.Helper()
+
+	w := cs.rest(t, "user", http.MethodGet, "/users?"+query, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("REST 查询 %s: 状态码 %d: %s", query, w.Code, w.Body.String())
+	}
+	var body struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+		Total      int64  `json:"total"`
+		Page       int    `json:"page"`
+		PageSize   int    `json:"page_size"`
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	p := listPage{IDs: []int64{}, Total: body.Total, Page: body.Page, PageSize: body.PageSize, NextCursor: body.NextCursor}
+	for _, item := range body.Items {
+		p.IDs = append(p.IDs, item.ID)
+	}
+	return p
+}
+
+// grpcList 通过 gRPC 查询一页用户
+func (cs *contractServers) grpcList(t *testing.T, req *pb.ListUsersRequest) listPage {
+	t.Helper()
+
+	resp, err := cs.client.ListUsers(cs.ctx("user"), req)
+	if err != nil {
+		t.Fatalf("gRPC 查询 %v: %v", req, err)
+	}
+	p := listPage{IDs: []int64{}, Total: resp.Total, Page: int(resp.Page), PageSize: int(resp.PageSize), NextCursor: resp.NextCursor}
+	for _, u := range resp.Users {
+		p.IDs = append(p.IDs, u.Id)
+	}
+	return p
+}
+
+// TestUserPaginationContract 校验 REST 与 gRPC 的分页语义一致：
+// 偏移量分页的每一页、总数、页大小的默认值和上限、越界页、游标翻页的结果都相同，游标可跨协议使用
+func TestUserPaginationContract(t *testing.T) {
+	cs := newContractServers(t)
+	ids := cs.seed(t, 5)
+
+	for _, sortBy := range []string{"", "-id"} {
+		want := append([]int64(nil), ids...)
+		if sortBy == "-id" {
+			sort.Slice(want, func(i, j int) bool { return want[i] > want[j] })
+		}
+
+		t.Run("offset"+sortBy, func(t *testing.T) {
+			var seen []int64
+			for page := 1; page <= 4; page++ {
+				rest := cs.restList(t, fmt.Sprintf("page=%d&page_size=2&sort=%s", page, sortBy))
+				grpc := cs.grpcList(t, &pb.ListUsersRequest{Page: int32(page), PageSize: 2, Sort: sortBy})
+				if fmt.Sprintf("%+v", rest) != fmt.Sprintf("%+v", grpc) {
+					t.Fatalf("第 %d 页不一致:\nREST %+v\ngRPC %+v", page, rest, grpc)
+				}
+				if rest.Total != int64(len(ids)) {
+					t.Errorf("total = %d, 期望 %d", rest.Total, len(ids))
+				}
+				// 最后一页（及之后的越界页）没有下一页游标
+				if (rest.NextCursor == "") != (page >= 3) {
+					t.Errorf("第 %d 页 next_cursor = %q", page, rest.NextCursor)
+				}
+				seen = append(seen, rest.IDs...)
+			}
+			if fmt.Sprint(seen) != fmt.Sprint(want) {
+				t.Errorf("逐页结果 = %v, 期望 %v", seen, want)
+			}
+		})
+
+		t.Run("cursor"+sortBy, func(t *testing.T) {
+			// 从偏移量分页的第一页开始，REST 游标交给 gRPC、gRPC 游标交给 REST 交替翻页
+			first := cs.restList(t, "page=1&page_size=2&sort="+sortBy)
+			seen := append([]int64(nil), first.IDs...)
+			cursor := first.NextCursor
+			for i := 0; cursor != ""; i++ {
+				if i > len(ids) {
+					t.Fatal("游标翻页没有结束")
+				}
+				rest := cs.restList(t, "page_size=2&sort="+sortBy+"&cursor="+cursor)
+				grpc := cs.grpcList(t, &pb.ListUsersRequest{PageSize: 2, Sort: sortBy, Cursor: cursor})
+				if fmt.Sprintf("%+v", rest) != fmt.Sprintf("%+v", grpc) {
+					t.Fatalf("游标 %q 不一致:\nREST %+v\ngRPC %+v", cursor, rest, grpc)
+				}
+				// 按游标翻页不统计总数和页码
+				if rest.Total != 0 || rest.Page != 0 {
+					t.Errorf("游标翻页不应返回 total/page: %+v", rest)
+				}
+				seen = append(seen, rest.IDs...)
+				cursor = rest.NextCursor
+			}
+			if fmt.Sprint(seen) != fmt.Sprint(want) {
+				t.Errorf("游标翻页结果 = %v, 期望 %v", seen, want)
+			}
+		})
+	}
+
+	// 页大小的默认值与上限
+	for _, size := range []int{0, service.MaxPageSize + 1} {
+		rest := cs.restList(t, fmt.Sprintf("page_size=%d", size))
+		grpc := cs.grpcList(t, &pb.ListUsersRequest{PageSize: int32(size)})
+		_, normalized := service.NormalizePage(1, size)
+		if rest.PageSize != normalized || grpc.PageSize != normalized || rest.Page != 1 || grpc.Page != 1 {
+			t.Errorf("page_size=%d: REST %+v, gRPC %+v, 期望页大小 %d", size, rest, grpc, normalized)
+		}
+	}
+}
@@ -3,111 +3,34 @@ package main
 import (
 	"context"
 	"fmt"
-	"net"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/zhang/microservice/internal/audit"
 	"github.com/zhang/microservice/internal/cache"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/grpchealth"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/proxyproto"
+	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
+	"github.com/zhang/microservice/internal/security"
 	"github.com/zhang/microservice/internal/service"
-	pb "github.com/zhang/microservice/proto"
+	"github.com/zhang/microservice/internal/slo"
+	"github.com/zhang/microservice/internal/timezone"
+	"github.com/zhang/microservice/internal/usersettings"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
-// server gRPC 服务器
-type server struct {
-	pb.UnimplementedUserServiceServer
-	userService *service.UserService
-}
-
-// GetUser 获取用户
-func (s *server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
-	user, err := s.userService.GetUser(ctx, req.Id)
-	if err != nil {
-		return nil, err
-	}
-
-	if user == nil {
-		return &pb.GetUserResponse{}, nil
-	}
-
-	return &pb.GetUserResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			Phone:     user.Phone,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
-	}, nil
-}
-
-// CreateUser 创建用户
-func (s *server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
-	user := &service.User{
-		Name:  req.Name,
-		Email: req.Email,
-		Phone: req.Phone,
-	}
-
-	user, err := s.userService.CreateUser(ctx, user)
-	if err != nil {
-		return nil, err
-	}
-
-	return &pb.CreateUserResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			Phone:     user.Phone,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
-	}, nil
-}
-
-// UpdateUser 更新用户
-func (s *server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
-	user := &service.User{
-		ID:    req.Id,
-		Name:  req.Name,
-		Email: req.Email,
-		Phone: req.Phone,
-	}
-
-	user, err := s.userService.UpdateUser(ctx, user)
-	if err != nil {
-		return nil, err
-	}
-
-	return &pb.UpdateUserResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Name:      user.Name,
-			Email:     user.Email,
-			Phone:     user.Phone,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
-	}, nil
-}
-
-// DeleteUser 删除用户
-func (s *server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
-	err := s.userService.DeleteUser(ctx, req.Id)
-	if err != nil {
-		return &pb.DeleteUserResponse{Success: false}, err
-	}
-
-	return &pb.DeleteUserResponse{Success: true}, nil
-}
-
 func main() {
 	// 加载配置
 	if err := config.Load("config/config.yaml"); err != nil {
@@ -121,9 +44,30 @@ func main() {
 		os.Exit(1)
 	}
 	defer logger.Sync()
+	logger.WatchSignal()
+
+	// JWT 签名配置（release 模式下未配置密钥时配置校验已失败）
+	jwtConfig, err := middleware.LoadJWTConfig(config.GlobalConfig.JWT)
+	if err != nil {
+		logger.Fatal("加载 JWT 密钥失败", zap.Error(err))
+	}
+	middleware.SetJWTConfig(jwtConfig)
+	if !config.GlobalConfig.JWT.Asymmetric() && config.GlobalConfig.JWT.UsesDefaultSecret() {
+		logger.Warn("未配置 jwt.secret，使用默认占位密钥，仅限开发环境")
+	}
+
+	// 配置文件修改后热加载可安全重载的配置段（日志级别、限流、CORS、定时任务启用状态）
+	config.Watch(func(err error) {
+		logger.Warn("重新加载配置文件", zap.Error(err))
+	})
 
 	logger.Info("gRPC 服务启动中...")
 
+	// 依赖尚未就绪时的等待与重试、外部依赖熔断（需在初始化数据库、Redis、消息队列和 S3 之前设置）
+	retry.Init(config.GlobalConfig.Retry)
+	resilience.Init(config.GlobalConfig.CircuitBreaker)
+	audit.Init(config.GlobalConfig.Audit)
+
 	// 初始化数据库
 	if err := database.Init(config.GlobalConfig.Database); err != nil {
 		logger.Fatal("初始化数据库失败", zap.Error(err))
@@ -136,23 +80,105 @@ func main() {
 	}
 	defer cache.Close()
 
-	// 自动迁移数据库表
-	if err := database.DB.AutoMigrate(&service.User{}); err != nil {
-		logger.Fatal("数据库迁移失败", zap.Error(err))
+	// 用户缓存的 TTL 等策略与网关一致，预刷新由网关负责
+	cache.InitRefresher(config.GlobalConfig.Redis.Refresh)
+
+	// 表结构由 migrate 命令迁移，未迁移或版本不兼容时拒绝启动（部分部署）
+	if err := migrate.CheckSchema(context.Background()); err != nil {
+		logger.Fatal("表结构版本不兼容", zap.Error(err))
 	}
 
-	// 创建监听器
+	// 创建监听器（按配置解析负载均衡的 PROXY 协议头）
 	addr := fmt.Sprintf(":%d", config.GlobalConfig.Server.GRPCPort)
-	lis, err := net.Listen("tcp", addr)
+	lis, err := proxyproto.Listen(addr, config.GlobalConfig.Server.Proxy)
 	if err != nil {
 		logger.Fatal("创建监听器失败", zap.Error(err))
 	}
 
-	// 创建 gRPC 服务器
-	s := grpc.NewServer()
-	pb.RegisterUserServiceServer(s, &server{
-		userService: service.NewUserService(),
-	})
+	// 创建 gRPC 服务器：通用拦截器（请求 ID、访问日志、指标、panic 恢复）之后识别调用方（JWT / API 密钥 / 客户端证书），
+	// 再为已声明弃用的方法返回弃用 metadata 并按调用方统计，
+	// 服务专属拦截器（如方法级角色检查）由模块注册表按方法分发
+	opts, err := serverOptions(config.GlobalConfig.GRPC)
+	if err != nil {
+		logger.Fatal("加载 gRPC TLS 证书失败", zap.Error(err))
+	}
+	unary, stream, err := middleware.GRPCInterceptors(config.GlobalConfig.GRPC)
+	if err != nil {
+		logger.Fatal("构建 gRPC 拦截器链失败", zap.Error(err))
+	}
+	authCfg := config.GlobalConfig.GRPC.Auth
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(append(unary, middleware.GRPCAuth(authCfg), middleware.GRPCDeprecation(), module.UnaryInterceptor())...),
+		grpc.ChainStreamInterceptor(append(stream, middleware.GRPCStreamAuth(authCfg), middleware.GRPCStreamDeprecation(), module.StreamInterceptor())...),
+	)
+	s := grpc.NewServer(opts...)
+
+	// 用户时间戳的默认时区
+	if err := timezone.Init(config.GlobalConfig.Timezone); err != nil {
+		logger.Fatal("初始化默认时区失败", zap.Error(err))
+	}
+
+	// 用户设置项（值的结构由配置声明）
+	if err := usersettings.Init(config.GlobalConfig.UserSettings); err != nil {
+		logger.Fatal("初始化用户设置失败", zap.Error(err))
+	}
+
+	// 消息服务（PublishEvent）启用时初始化消息队列
+	if config.GlobalConfig.FeatureEnabled("messaging") {
+		if err := security.InitKeyProvider(config.GlobalConfig.Security); err != nil {
+			logger.Fatal("初始化密钥失败", zap.Error(err))
+		}
+		if err := queue.Init(config.GlobalConfig.RabbitMQ); err != nil {
+			logger.Fatal("初始化消息队列失败", zap.Error(err))
+		}
+		defer queue.Close()
+	}
+
+	// 注册已启用的服务（见各服务文件的 init）
+	services := module.SetupGRPC(s, module.Deps{Config: config.GlobalConfig})
+
+	// 用户变更推送（WatchUsers）
+	feedCtx, stopFeed := context.WithCancel(context.Background())
+	defer stopFeed()
+	if config.GlobalConfig.Database.Notify.Enable {
+		startUserFeed(feedCtx, config.GlobalConfig.Database.Notify)
+	}
+
+	// 跨实例事件（Redis 发布/订阅），服务注册的处理函数在此之后才会收到事件
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	defer stopEvents()
+	go cache.DefaultEventBus.Run(eventsCtx)
+
+	// grpc.health.v1 健康检查（Kubernetes gRPC 探针），按数据库和 Redis 状态报告
+	checker := grpchealth.New(config.GlobalConfig.GRPC.GetHealthInterval(),
+		grpchealth.Dependency{Name: "database", Check: database.HealthCheck},
+		grpchealth.Dependency{Name: "redis", Check: cache.HealthCheck},
+	)
+	checker.Register(s, services)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+	go checker.Run(healthCtx)
+
+	// 反射服务（grpcurl 等工具）
+	if config.GlobalConfig.GRPC.Reflection {
+		reflection.Register(s)
+	}
+
+	// 暴露 Prometheus 指标
+	if port := config.GlobalConfig.Metrics.GRPCPort; port > 0 {
+		go metrics.Serve(port, config.GlobalConfig.Metrics.Path)
+	}
+
+	// SLO 统计，并推送关键指标到 CloudWatch / StatsD
+	if err := slo.Init(config.GlobalConfig.SLO); err != nil {
+		logger.Fatal("初始化 SLO 统计失败", zap.Error(err))
+	}
+	pushCtx, stopPush := context.WithCancel(context.Background())
+	defer stopPush()
+	waitPusher, err := metrics.StartPusher(pushCtx, config.GlobalConfig.Metrics.Export, config.GlobalConfig.AWS)
+	if err != nil {
+		logger.Fatal("初始化指标推送失败", zap.Error(err))
+	}
 
 	// 启动服务器
 	go func() {
@@ -170,6 +196,14 @@ func main() {
 	<-quit
 
 	logger.Info("正在关闭 gRPC 服务器...")
+	stopHealth()
+	checker.Shutdown()
+	stopFeed()
+	service.DefaultUserFeed.Close()
 	s.GracefulStop()
+
+	stopPush()
+	waitPusher()
+
 	logger.Info("gRPC 服务器已关闭")
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/queue"
+	pb "github.com/zhang/microservice/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// init 注册消息服务模块
+func init() {
+	module.RegisterGRPC(module.GRPCService{
+		Name: "messaging",
+		Desc: &pb.MessageService_ServiceDesc,
+		// 发布需要登录（任意角色），与 HTTP POST /api/v1/message 的 JWTAuth 一致
+		UnaryInterceptors: []grpc.UnaryServerInterceptor{middleware.GRPCRequireRole(messageMethodRoles)},
+		New: func(deps module.Deps) interface{} {
+			return &messageServer{cfg: deps.Config.RabbitMQ}
+		},
+	})
+}
+
+// messageMethodRoles MessageService 各方法要求的角色
+var messageMethodRoles = middleware.MethodRoles{
+	"PublishEvent": {},
+}
+
+// messageServer 消息服务
+type messageServer struct {
+	pb.UnimplementedMessageServiceServer
+	cfg config.RabbitMQConfig
+}
+
+// eventEnvelope 发布到队列的事件信封（EventEnvelope 的 JSON 形式）
+type eventEnvelope struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// PublishEvent 发布事件到队列
+func (s *messageServer) PublishEvent(ctx context.Context, req *pb.PublishEventRequest) (*pb.PublishEventResponse, error) {
+	if req.Queue == "" {
+		return nil, status.Error(codes.InvalidArgument, "queue 不能为空")
+	}
+	if req.GetEvent().GetType() == "" {
+		return nil, status.Error(codes.InvalidArgument, "event.type 不能为空")
+	}
+	if !s.cfg.PublishAllowed(req.Queue) {
+		logger.FromContext(ctx).Warn("拒绝发布到未授权的队列", zap.String("queue", req.Queue))
+		return nil, status.Error(codes.PermissionDenied, "不允许发布到该队列")
+	}
+	if queue.MQClient == nil {
+		return nil, status.Error(codes.Unavailable, "消息队列未初始化")
+	}
+
+	body, id, err := encodeEvent(req.Event)
+	if err != nil {
+		return nil, err
+	}
+	if err := queue.PublishContext(ctx, req.Queue+".*", body); err != nil {
+		logger.FromContext(ctx).Error("发布事件失败",
+			zap.String("queue", req.Queue),
+			zap.String("type", req.Event.Type),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Unavailable, "发送消息失败")
+	}
+
+	logger.FromContext(ctx).Info("事件发布成功",
+		zap.String("queue", req.Queue),
+		zap.String("type", req.Event.Type),
+		zap.String("event_id", id),
+	)
+	return &pb.PublishEventResponse{Id: id}, nil
+}
+
+// encodeEvent 补全事件 ID 和发生时间并序列化为 JSON
+// 返回:
+//
+//	[]byte: 消息体
+//	string: 事件 ID
+//	error: 错误信息（gRPC status）
+func encodeEvent(event *pb.EventEnvelope) ([]byte, string, error) {
+	envelope := eventEnvelope{ID: event.Id, Type: event.Type, OccurredAt: time.Now()}
+	if envelope.ID == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", status.Error(codes.Internal, "生成事件 ID 失败")
+		}
+		envelope.ID = hex.EncodeToString(b)
+	}
+	if event.OccurredAt != nil {
+		if err := event.OccurredAt.CheckValid(); err != nil {
+			return nil, "", status.Error(codes.InvalidArgument, "event.occurred_at 无效")
+		}
+		envelope.OccurredAt = event.OccurredAt.AsTime()
+	}
+	if event.Data != nil {
+		data, err := protojson.Marshal(event.Data)
+		if err != nil {
+			return nil, "", status.Error(codes.InvalidArgument, "event.data 无效")
+		}
+		envelope.Data = data
+	}
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, "", status.Error(codes.Internal, "处理消息失败")
+	}
+	return body, envelope.ID, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/queue"
+	"github.com/zhang/microservice/internal/testutil"
+	pb "github.com/zhang/microservice/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// recordingBroker 记录发布的消息
+type recordingBroker struct {
+	queue.MessageBroker
+	routingKey string
+	body       []byte
+}
+
+func (b *recordingBroker) Publish(routingKey string, body []byte) error {
+	b.routingKey, b.body = routingKey, body
+	return nil
+}
+
+func TestPublishEvent(t *testing.T) {
+	testutil.InitLogger()
+	broker := &recordingBroker{}
+	queue.MQClient = broker
+	t.Cleanup(func() { queue.MQClient = nil })
+
+	s := &messageServer{cfg: config.RabbitMQConfig{PublishAllowlist: []string{"task_queue"}}}
+	data, _ := structpb.NewStruct(map[string]interface{}{"order_id": 42.0})
+	at := time.Date(2024, 6, 7, 8, 9, 10, 0, time.UTC)
+
+	resp, err := s.PublishEvent(context.Background(), &pb.PublishEventRequest{
+		Queue: "task_queue",
+		Event: &pb.EventEnvelope{Type: "order.created", OccurredAt: timestamppb.New(at), Data: data},
+	})
+	if err != nil {
+		t.Fatalf("发布失败: %v", err)
+	}
+	if len(resp.Id) != 32 {
+		t.Errorf("应生成事件 ID, 得到 %q", resp.Id)
+	}
+	if broker.routingKey != "task_queue.*" {
+		t.Errorf("路由键 = %q, 期望 task_queue.*", broker.routingKey)
+	}
+
+	var envelope eventEnvelope
+	if err := json.Unmarshal(broker.body, &envelope); err != nil {
+		t.Fatalf("消息体不是事件信封: %v", err)
+	}
+	if envelope.ID != resp.Id || envelope.Type != "order.created" || !envelope.OccurredAt.Equal(at) {
+		t.Errorf("信封 = %+v", envelope)
+	}
+	if string(envelope.Data) != `{"order_id":42}` {
+		t.Errorf("data = %s", envelope.Data)
+	}
+
+	cases := []struct {
+		name string
+		req  *pb.PublishEventRequest
+		code codes.Code
+	}{
+		{"缺少队列", &pb.PublishEventRequest{Event: &pb.EventEnvelope{Type: "x"}}, codes.InvalidArgument},
+		{"缺少类型", &pb.PublishEventRequest{Queue: "task_queue"}, codes.InvalidArgument},
+		{"不在白名单", &pb.PublishEventRequest{Queue: "email_queue", Event: &pb.EventEnvelope{Type: "x"}}, codes.PermissionDenied},
+	}
+	for _, tc := range cases {
+		if _, err := s.PublishEvent(context.Background(), tc.req); status.Code(err) != tc.code {
+			t.Errorf("%s: 期望 %v, 得到 %v", tc.name, tc.code, err)
+		}
+	}
+
+	resp, err = s.PublishEvent(context.Background(), &pb.PublishEventRequest{
+		Queue: "task_queue",
+		Event: &pb.EventEnvelope{Type: "order.created", Id: "evt-1"},
+	})
+	if err != nil || resp.Id != "evt-1" {
+		t.Errorf("应使用请求中的事件 ID, 得到 %v, %v", resp, err)
+	}
+}
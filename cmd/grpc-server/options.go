@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// seconds 将秒数转换为时长
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// serverOptions 根据配置生成 gRPC 服务器选项（TLS、消息大小、连接超时、keepalive 与连接寿命）
+// 参数:
+//
+//	cfg: gRPC 配置
+//
+// 返回:
+//
+//	[]grpc.ServerOption: 服务器选项
+//	error: 证书加载失败时返回错误
+func serverOptions(cfg config.GRPCConfig) ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+
+	if cfg.TLS.Enable {
+		tlsConfig, err := serverTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	if cfg.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize*1024*1024))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgSize*1024*1024))
+	}
+	if cfg.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(seconds(cfg.ConnectionTimeout)))
+	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+
+	return append(opts,
+		grpc.KeepaliveParams(keepaliveParams(cfg)),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             seconds(cfg.KeepaliveMinTime),
+			PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		}),
+	), nil
+}
+
+// serverTLSConfig 服务端 TLS 配置
+// 配置了客户端 CA 时校验客户端证书（mTLS），require_client_cert 为 false 时不提供证书的连接仍可使用 JWT / API 密钥认证
+func serverTLSConfig(cfg config.GRPCTLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("客户端 CA 文件 %s 中没有有效证书", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.RequireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// keepaliveParams 服务端 keepalive 参数
+// 未配置（0）的字段保留 gRPC 默认值：不限制连接寿命和空闲时间
+func keepaliveParams(cfg config.GRPCConfig) keepalive.ServerParameters {
+	return keepalive.ServerParameters{
+		MaxConnectionIdle:     seconds(cfg.MaxConnectionIdle),
+		MaxConnectionAge:      seconds(cfg.MaxConnectionAge),
+		MaxConnectionAgeGrace: seconds(cfg.MaxConnectionAgeGrace),
+		Time:                  seconds(cfg.KeepaliveTime),
+		Timeout:               seconds(cfg.KeepaliveTimeout),
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/fieldmask"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/redact"
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/validate"
+	pb "github.com/zhang/microservice/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// init 注册用户服务模块
+func init() {
+	module.RegisterGRPC(module.GRPCService{
+		Name: "users",
+		Desc: &pb.UserService_ServiceDesc,
+		// 调用方身份由全局 GRPCAuth 拦截器解析（也用于按字段可见性策略裁剪响应）；
+		// 与 HTTP 路由一致，查询需要登录，创建、更新、删除需要管理员角色；
+		// 用户设置需要登录，访问他人设置的权限在方法内检查；订阅变更需要登录，批量导入需要管理员角色
+		UnaryInterceptors:  []grpc.UnaryServerInterceptor{middleware.GRPCRequireRole(userMethodRoles)},
+		StreamInterceptors: []grpc.StreamServerInterceptor{middleware.GRPCStreamRequireRole(userMethodRoles)},
+		New: func(deps module.Deps) interface{} {
+			repo := service.NewGormUserRepository(database.DB)
+			s := &server{
+				userService: service.NewUserService(service.NewCachedUserRepository(repo, cache.DefaultRefresher)),
+				feedUsers:   service.NewUserService(repo),
+			}
+			// 用户变更由数据库变更通知驱动（见 main.go），未启用时 WatchUsers 不可用
+			if deps.Config.Database.Notify.Enable {
+				s.feed = service.DefaultUserFeed
+			}
+			return s
+		},
+	})
+}
+
+// userMethodRoles UserService 各方法要求的角色
+var userMethodRoles = middleware.MethodRoles{
+	"GetUser":            {},
+	"ListUsers":          {},
+	"CreateUser":         {"admin"},
+	"UpdateUser":         {"admin"},
+	"DeleteUser":         {"admin"},
+	"RestoreUser":        {"admin"},
+	"PurgeUser":          {"admin"},
+	"GetUserSettings":    {},
+	"UpdateUserSettings": {},
+	"WatchUsers":         {},
+	"BulkCreateUsers":    {"admin"},
+}
+
+// server gRPC 服务器
+type server struct {
+	pb.UnimplementedUserServiceServer
+	userService *service.UserService
+	// feed 用户变更广播，为 nil 时未启用数据库变更通知
+	feed *service.UserFeed
+	// feedUsers 读取变更后的用户，不经缓存（通知可能先于缓存删除到达）
+	feedUsers *service.UserService
+}
+
+// GetUser 获取用户，用户不存在时返回 NOT_FOUND（与 REST 的 404 一致）
+func (s *server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	user, err := s.userService.GetUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "用户不存在")
+	}
+
+	pbUser := toPBUser(user)
+
+	// 按 read_mask 裁剪返回字段，再隐藏调用方无权查看的字段
+	fieldmask.PruneMessage(pbUser, req.GetReadMask().GetPaths())
+	redact.UserPolicy.Message(viewerFromContext(ctx), user.ID, pbUser)
+
+	return &pb.GetUserResponse{User: pbUser}, nil
+}
+
+// CreateUser 创建用户
+// 字段不合法时返回 INVALID_ARGUMENT，BadRequest 详情中列出各字段的错误
+func (s *server) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := validate.User(createUserInput(req)); err != nil {
+		return nil, err
+	}
+
+	user := &service.User{
+		Name:     req.Name,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Timezone: req.Timezone,
+	}
+
+	user, err := s.userService.CreateUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	pbUser := toPBUser(user)
+	redact.UserPolicy.Message(viewerFromContext(ctx), user.ID, pbUser)
+
+	return &pb.CreateUserResponse{
+		User: pbUser,
+	}, nil
+}
+
+// updatableUserFields UpdateUser 的 update_mask 可以包含的字段，与列名一致
+var updatableUserFields = []string{"name", "email", "phone", "timezone"}
+
+// UpdateUser 更新用户
+// 只更新 update_mask 列出的字段（为空时更新全部可更新字段），其余字段保持不变；
+// version 不为 0 且与当前版本不一致时返回 ABORTED
+func (s *server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UpdateUserResponse, error) {
+	fields, err := updateMaskFields(req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
+	if err := validate.User(updateUserInput(req, fields)); err != nil {
+		return nil, err
+	}
+
+	user := &service.User{
+		ID:       req.Id,
+		Name:     req.Name,
+		Email:    req.Email,
+		Phone:    req.Phone,
+		Timezone: req.Timezone,
+		Version:  req.Version,
+	}
+
+	user, err = s.userService.UpdateUser(ctx, user, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "用户不存在")
+	}
+
+	pbUser := toPBUser(user)
+	redact.UserPolicy.Message(viewerFromContext(ctx), user.ID, pbUser)
+
+	return &pb.UpdateUserResponse{
+		User: pbUser,
+	}, nil
+}
+
+// updateMaskFields 校验 update_mask 并返回需要更新的列，为空时返回全部可更新字段
+func updateMaskFields(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return updatableUserFields, nil
+	}
+	fields := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !slices.Contains(updatableUserFields, path) {
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask 包含不可更新的字段 %q，可更新字段: %s",
+				path, strings.Join(updatableUserFields, ", "))
+		}
+		if !slices.Contains(fields, path) {
+			fields = append(fields, path)
+		}
+	}
+	return fields, nil
+}
+
+// createUserInput 创建用户时需要校验的字段（全部字段）
+func createUserInput(req *pb.CreateUserRequest) validate.UserInput {
+	return validate.UserInput{Name: &req.Name, Email: &req.Email, Phone: &req.Phone, Timezone: &req.Timezone}
+}
+
+// updateUserInput 更新用户时需要校验的字段（update_mask 列出的字段）
+func updateUserInput(req *pb.UpdateUserRequest, fields []string) validate.UserInput {
+	var in validate.UserInput
+	for _, field := range fields {
+		switch field {
+		case "name":
+			in.Name = &req.Name
+		case "email":
+			in.Email = &req.Email
+		case "phone":
+			in.Phone = &req.Phone
+		case "timezone":
+			in.Timezone = &req.Timezone
+		}
+	}
+	return in
+}
+
+// DeleteUser 软删除用户并吊销其已签发的令牌
+func (s *server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	err := s.userService.DeleteUser(ctx, req.Id)
+	if err != nil {
+		return &pb.DeleteUserResponse{Success: false}, err
+	}
+	if err := middleware.RevokeUserTokens(ctx, req.Id); err != nil {
+		logger.FromContext(ctx).Error("吊销用户令牌失败",
+			zap.Int64("user_id", req.Id),
+			zap.Error(err),
+		)
+	}
+
+	return &pb.DeleteUserResponse{Success: true}, nil
+}
+
+// RestoreUser 恢复已软删除的用户，用户不存在或未被删除时返回 NOT_FOUND
+func (s *server) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.RestoreUserResponse, error) {
+	user, err := s.userService.RestoreUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "用户不存在或未被删除")
+	}
+
+	pbUser := toPBUser(user)
+	redact.UserPolicy.Message(viewerFromContext(ctx), user.ID, pbUser)
+
+	return &pb.RestoreUserResponse{User: pbUser}, nil
+}
+
+// PurgeUser 永久删除用户，用户不存在时返回 NOT_FOUND
+func (s *server) PurgeUser(ctx context.Context, req *pb.PurgeUserRequest) (*pb.PurgeUserResponse, error) {
+	purged, err := s.userService.PurgeUser(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !purged {
+		return nil, status.Error(codes.NotFound, "用户不存在")
+	}
+
+	return &pb.PurgeUserResponse{Success: true}, nil
+}
+
+// ListUsers 分页获取用户列表，cursor 非空时按游标翻页
+func (s *server) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	page, pageSize := service.NormalizePage(int(req.Page), int(req.PageSize))
+
+	filter := service.UserFilter{
+		Name:       req.Name,
+		NamePrefix: req.NamePrefix,
+		Email:      req.Email,
+		Sort:       req.Sort,
+	}
+	if req.CreatedAfter != nil {
+		filter.CreatedAfter = req.CreatedAfter.AsTime()
+	}
+	if req.CreatedBefore != nil {
+		filter.CreatedBefore = req.CreatedBefore.AsTime()
+	}
+
+	resp := &pb.ListUsersResponse{PageSize: int32(pageSize)}
+	var users []*service.User
+	if req.Cursor != "" {
+		list, next, err := s.userService.ListUsersAfter(ctx, filter, req.Cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		users, resp.NextCursor = list, next
+	} else {
+		list, total, err := s.userService.ListUsers(ctx, filter, (page-1)*pageSize, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		users, resp.Total, resp.Page = list, total, int32(page)
+		if len(list) > 0 && int64((page-1)*pageSize+len(list)) < total {
+			resp.NextCursor = service.EncodeUserCursor(filter.Sort, list[len(list)-1])
+		}
+	}
+
+	viewer := viewerFromContext(ctx)
+	resp.Users = make([]*pb.User, 0, len(users))
+	for _, user := range users {
+		pbUser := toPBUser(user)
+		redact.UserPolicy.Message(viewer, user.ID, pbUser)
+		resp.Users = append(resp.Users, pbUser)
+	}
+	return resp, nil
+}
+
+// toPBUser 将用户模型转换为 proto 消息
+// 参数:
+//
+//	user: 用户模型
+//
+// 返回:
+//
+//	*pb.User: proto 用户消息
+func toPBUser(user *service.User) *pb.User {
+	pbUser := &pb.User{
+		Id:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Phone:         user.Phone,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+		EmailVerified: user.EmailVerified,
+		PhoneVerified: user.PhoneVerified,
+		Timezone:      user.Timezone,
+		Version:       user.Version,
+	}
+	if user.LastSeenAt != nil {
+		pbUser.LastSeenAt = timestamppb.New(*user.LastSeenAt)
+	}
+	return pbUser
+}
+
+// viewerFromContext 从 gRPC 上下文获取调用方，未认证时为匿名调用方
+func viewerFromContext(ctx context.Context) redact.Viewer {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return redact.Viewer{}
+	}
+	return redact.Viewer{UserID: claims.UserID, Role: claims.Role}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/redact"
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/validate"
+	pb "github.com/zhang/microservice/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// bulkCreateBatchSize 批量导入每次插入的用户数
+const bulkCreateBatchSize = 100
+
+// userEventTypes 用户变更类型到 proto 枚举的映射
+var userEventTypes = map[string]pb.UserEvent_Type{
+	service.UserCreated: pb.UserEvent_CREATED,
+	service.UserUpdated: pb.UserEvent_UPDATED,
+	service.UserDeleted: pb.UserEvent_DELETED,
+}
+
+// WatchUsers 订阅用户变更
+// 变更来自数据库触发器的通知，因此绕过本服务的写入也会推送；创建和更新事件附带变更后的用户（按调用方隐藏字段），
+// 处理过慢导致缓冲区积压时返回 RESOURCE_EXHAUSTED，服务关闭时返回 UNAVAILABLE，客户端需重新订阅
+func (s *server) WatchUsers(req *pb.WatchUsersRequest, stream pb.UserService_WatchUsersServer) error {
+	if s.feed == nil {
+		return status.Error(codes.FailedPrecondition, "未启用数据库变更通知（database.notify.enable）")
+	}
+
+	ctx := stream.Context()
+	ids := make(map[int64]bool, len(req.Ids))
+	for _, id := range req.Ids {
+		ids[id] = true
+	}
+	changes, cancel := s.feed.Subscribe()
+	defer cancel()
+
+	viewer := viewerFromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case change, ok := <-changes:
+			if !ok {
+				if s.feed.Closed() {
+					return status.Error(codes.Unavailable, "服务正在关闭，请重新订阅")
+				}
+				return status.Error(codes.ResourceExhausted, "处理变更过慢，订阅已取消，请重新订阅")
+			}
+			if len(ids) > 0 && !ids[change.ID] {
+				continue
+			}
+
+			event := &pb.UserEvent{
+				Type: userEventTypes[change.Op],
+				Id:   change.ID,
+				At:   timestamppb.New(change.At),
+			}
+			if change.Op != service.UserDeleted {
+				user, err := s.feedUsers.GetUser(ctx, change.ID)
+				if err != nil {
+					return err
+				}
+				if user == nil {
+					if change.Op == service.UserCreated {
+						// 推送前已被删除，删除事件随后到达
+						continue
+					}
+					// 软删除是对 deleted_at 的 UPDATE，按删除事件推送
+					event.Type = pb.UserEvent_DELETED
+					if err := stream.Send(event); err != nil {
+						return err
+					}
+					continue
+				}
+				event.User = toPBUser(user)
+				redact.UserPolicy.Message(viewer, user.ID, event.User)
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// BulkCreateUsers 批量导入用户
+// 请求按 bulkCreateBatchSize 分批插入（每批一条 INSERT）；某批失败时逐个插入以找出失败的用户，
+// 失败的用户记录在响应的 errors 中，不影响其他用户
+func (s *server) BulkCreateUsers(stream pb.UserService_BulkCreateUsersServer) error {
+	ctx := stream.Context()
+	resp := &pb.BulkCreateUsersResponse{}
+	batch := make([]*service.User, 0, bulkCreateBatchSize)
+	indexes := make([]int32, 0, bulkCreateBatchSize)
+
+	flush := func() error {
+		s.createBatch(ctx, batch, indexes, resp)
+		batch, indexes = batch[:0], indexes[:0]
+		return ctx.Err()
+	}
+
+	for index := int32(0); ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := validate.User(createUserInput(req)); err != nil {
+			resp.Errors = append(resp.Errors, &pb.BulkCreateUserError{
+				Index:   index,
+				Email:   req.Email,
+				Message: err.Error(),
+			})
+			continue
+		}
+		batch = append(batch, &service.User{
+			Name:     req.Name,
+			Email:    req.Email,
+			Phone:    req.Phone,
+			Timezone: req.Timezone,
+		})
+		indexes = append(indexes, index)
+		if len(batch) == bulkCreateBatchSize {
+			if err := flush(); err != nil {
+				return status.FromContextError(err).Err()
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	logger.Info("批量导入用户完成", zap.Int32("created", resp.Created), zap.Int("failed", len(resp.Errors)))
+	return stream.SendAndClose(resp)
+}
+
+// createBatch 插入一批用户，整批失败时逐个插入，结果累计到 resp
+func (s *server) createBatch(ctx context.Context, users []*service.User, indexes []int32, resp *pb.BulkCreateUsersResponse) {
+	if len(users) == 0 {
+		return
+	}
+	if err := s.userService.CreateUsers(ctx, users); err == nil {
+		resp.Created += int32(len(users))
+		return
+	}
+
+	for i, user := range users {
+		user.ID = 0
+		if _, err := s.userService.CreateUser(ctx, user); err != nil {
+			resp.Errors = append(resp.Errors, &pb.BulkCreateUserError{
+				Index:   indexes[i],
+				Email:   user.Email,
+				Message: err.Error(),
+			})
+			continue
+		}
+		resp.Created++
+	}
+}
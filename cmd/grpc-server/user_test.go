@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUpdateMaskFields(t *testing.T) {
+	fields, err := updateMaskFields(nil)
+	if err != nil || !reflect.DeepEqual(fields, updatableUserFields) {
+		t.Errorf("空 update_mask 应更新全部字段, 得到 %v, %v", fields, err)
+	}
+
+	fields, err = updateMaskFields([]string{"email", "name", "email"})
+	if err != nil || !reflect.DeepEqual(fields, []string{"email", "name"}) {
+		t.Errorf("字段 = %v, %v, 期望 [email name]", fields, err)
+	}
+
+	for _, path := range []string{"email_verified", "created_at", "role"} {
+		if _, err := updateMaskFields([]string{"name", path}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: 期望 InvalidArgument, 得到 %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/usersettings"
+	pb "github.com/zhang/microservice/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GetUserSettings 获取用户设置
+func (s *server) GetUserSettings(ctx context.Context, req *pb.GetUserSettingsRequest) (*pb.UserSettingsResponse, error) {
+	userID, err := settingsOwner(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := usersettings.Get(ctx, userID)
+	if err != nil {
+		logger.Error("查询用户设置失败", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "查询设置失败")
+	}
+	return toSettingsResponse(values)
+}
+
+// UpdateUserSettings 更新用户设置
+func (s *server) UpdateUserSettings(ctx context.Context, req *pb.UpdateUserSettingsRequest) (*pb.UserSettingsResponse, error) {
+	userID, err := settingsOwner(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetSettings().GetFields()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "settings 不能为空")
+	}
+
+	changes := make(map[string]json.RawMessage, len(req.Settings.Fields))
+	for key, value := range req.Settings.Fields {
+		raw, err := protojson.Marshal(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "设置项 %s 的值无效", key)
+		}
+		changes[key] = raw
+	}
+
+	values, err := usersettings.Update(ctx, userID, changes)
+	if errors.Is(err, usersettings.ErrUnknownKey) || errors.Is(err, usersettings.ErrInvalidValue) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		logger.Error("更新用户设置失败", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, status.Error(codes.Internal, "保存设置失败")
+	}
+	return toSettingsResponse(values)
+}
+
+// settingsOwner 确定要访问的用户：0 表示调用方本人，访问他人设置需要 admin 角色
+func settingsOwner(ctx context.Context, userID int64) (int64, error) {
+	claims, ok := middleware.ClaimsFromContext(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "未提供认证凭证")
+	}
+	if userID == 0 || userID == claims.UserID {
+		return claims.UserID, nil
+	}
+	if claims.Role != "admin" {
+		return 0, status.Error(codes.PermissionDenied, "权限不足")
+	}
+	return userID, nil
+}
+
+// toSettingsResponse 将设置值转换为 Struct
+func toSettingsResponse(values map[string]json.RawMessage) (*pb.UserSettingsResponse, error) {
+	fields := make(map[string]interface{}, len(values))
+	for key, raw := range values {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, status.Errorf(codes.Internal, "设置项 %s 的值无法解析", key)
+		}
+		fields[key] = v
+	}
+
+	settings, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.UserSettingsResponse{Settings: settings}, nil
+}
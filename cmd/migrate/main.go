@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/migrate"
+)
+
+// 表结构迁移命令
+// 执行 internal/migrate/migrations 中内嵌的 SQL 迁移，部署新版本服务前运行；出错时以状态码 2 退出
+//
+// 用法:
+//
+//	migrate [-config config/config.yaml] up [N]     执行全部（或 N 个）待执行的迁移
+//	migrate [-config config/config.yaml] down [N]   回滚 N 个迁移（默认 1）
+//	migrate [-config config/config.yaml] status     输出当前版本与待执行的迁移
+//	migrate [-config config/config.yaml] force V    迁移中途失败并手工修复后，把版本记录设为 V
+func main() {
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 || len(args) > 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := config.Load(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		os.Exit(2)
+	}
+	if err := logger.Init(config.GlobalConfig.Logger); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		os.Exit(2)
+	}
+	defer logger.Sync()
+
+	m, err := migrate.New(config.GlobalConfig.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "初始化迁移失败: %v\n", err)
+		os.Exit(2)
+	}
+
+	err = run(m, args[0], args[1:])
+	if closeErr := m.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s 失败: %v\n", args[0], err)
+		os.Exit(2)
+	}
+}
+
+// run 执行子命令
+func run(m *migrate.Migrator, cmd string, args []string) error {
+	switch cmd {
+	case "up":
+		steps, err := intArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if err := m.Up(steps); err != nil {
+			return err
+		}
+		return printStatus(m)
+	case "down":
+		steps, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		if err := m.Down(steps); err != nil {
+			return err
+		}
+		return printStatus(m)
+	case "status":
+		return printStatus(m)
+	case "force":
+		if len(args) != 1 {
+			return fmt.Errorf("需要指定版本")
+		}
+		version, err := intArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if err := m.Force(version); err != nil {
+			return err
+		}
+		return printStatus(m)
+	}
+	usage()
+	return fmt.Errorf("未知命令 %q", cmd)
+}
+
+// intArg 解析可选的整数参数
+func intArg(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("参数 %q 不是整数", args[0])
+	}
+	return n, nil
+}
+
+// printStatus 以 JSON 输出迁移状态
+func printStatus(m *migrate.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	out, _ := json.MarshalIndent(status, "", "  ")
+	fmt.Println(string(out))
+	return nil
+}
+
+// usage 输出用法
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法:
+  migrate [-config config/config.yaml] <命令>
+
+命令:
+  up [N]     执行全部（或 N 个）待执行的迁移
+  down [N]   回滚 N 个迁移（默认 1）
+  status     输出当前版本与待执行的迁移
+  force V    把版本记录设为 V 并清除 dirty 标记（-1 表示未迁移），不执行迁移`)
+}
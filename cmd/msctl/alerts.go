@@ -0,0 +1,376 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zhang/microservice/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// alertsHeader 生成文件的文件头
+const alertsHeader = "# 由 msctl gen alerts 根据服务配置生成，修改配置后重新生成，请勿手动编辑\n"
+
+// cronParser 与定时任务服务一致的表达式解析器（含秒字段）
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// AlertOptions 告警阈值
+type AlertOptions struct {
+	// ErrorRate 路由 5xx 比例阈值
+	ErrorRate float64
+	// QueueBacklog 队列积压消息数阈值
+	QueueBacklog int
+	// PoolUsage 连接池使用率阈值
+	PoolUsage float64
+}
+
+// RuleGroup Prometheus 告警规则组
+type RuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []AlertRule `yaml:"rules"`
+}
+
+// AlertRule Prometheus 告警规则
+type AlertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// runGenAlerts 执行 gen alerts 子命令
+func runGenAlerts(args []string) int {
+	fs := flag.NewFlagSet("gen alerts", flag.ExitOnError)
+	configPath := fs.String("config", "config/config.yaml", "配置文件路径，为空时只读取 MS_ 环境变量")
+	out := fs.String("out", "", "输出文件，为空时输出到标准输出")
+	opts := AlertOptions{}
+	fs.Float64Var(&opts.ErrorRate, "error-rate", 0.05, "路由 5xx 比例告警阈值")
+	fs.IntVar(&opts.QueueBacklog, "queue-backlog", 1000, "队列积压消息数告警阈值")
+	fs.Float64Var(&opts.PoolUsage, "pool-usage", 0.9, "数据库连接池使用率告警阈值")
+	fs.Parse(args)
+
+	if err := config.Load(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	cfg := config.GlobalConfig
+	if cfg.Cron.Enable && cfg.Metrics.CronPort == 0 {
+		fmt.Fprintln(os.Stderr, "警告: metrics.cron_port 为 0，Prometheus 无法抓取定时任务服务的指标，任务失败告警不会触发")
+	}
+
+	groups, err := BuildAlertRules(cfg, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成告警规则失败: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := WriteAlertRules(w, groups); err != nil {
+		fmt.Fprintf(os.Stderr, "写入告警规则失败: %v\n", err)
+		return 1
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "已生成 %s\n", *out)
+	}
+	return 0
+}
+
+// BuildAlertRules 根据配置中的路由目标（slo.objectives）、队列、定时任务和连接池生成告警规则
+// 参数:
+//
+//	cfg: 服务配置
+//	opts: 告警阈值
+//
+// 返回:
+//
+//	[]RuleGroup: 告警规则组（没有规则的组不输出）
+//	error: 定时任务表达式无效时返回错误
+func BuildAlertRules(cfg *config.Config, opts AlertOptions) ([]RuleGroup, error) {
+	cronRules, err := cronAlertRules(cfg.Cron)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []RuleGroup
+	for _, g := range []RuleGroup{
+		{Name: "microservice.requests", Rules: requestAlertRules(cfg.SLO, opts)},
+		{Name: "microservice.queues", Rules: queueAlertRules(cfg.RabbitMQ, opts)},
+		{Name: "microservice.cron", Rules: cronRules},
+		{Name: "microservice.pools", Rules: poolAlertRules(cfg, opts)},
+	} {
+		if len(g.Rules) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// WriteAlertRules 以 Prometheus 规则文件格式输出
+// 参数:
+//
+//	w: 输出
+//	groups: 告警规则组
+//
+// 返回:
+//
+//	error: 错误信息
+func WriteAlertRules(w io.Writer, groups []RuleGroup) error {
+	if _, err := io.WriteString(w, alertsHeader); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string][]RuleGroup{"groups": groups}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// requestAlertRules 全部路由的 5xx 比例，以及每个 SLO 目标的错误预算消耗速率（多窗口告警）
+func requestAlertRules(slo config.SLOConfig, opts AlertOptions) []AlertRule {
+	rules := []AlertRule{
+		{
+			Alert: "HTTPHighErrorRate",
+			Expr: fmt.Sprintf(`sum by (method, route) (rate(microservice_http_request_duration_seconds_count{status=~"5.."}[5m]))
+  / sum by (method, route) (rate(microservice_http_request_duration_seconds_count[5m])) > %s`, formatFloat(opts.ErrorRate)),
+			For:    "5m",
+			Labels: severity("warning"),
+			Annotations: map[string]string{
+				"summary":     "{{ $labels.method }} {{ $labels.route }} 服务端错误比例过高",
+				"description": fmt.Sprintf("最近 5 分钟 5xx 比例为 {{ $value | humanizePercentage }}，阈值 %s", formatPercent(opts.ErrorRate)),
+			},
+		},
+		{
+			Alert: "GRPCHighErrorRate",
+			Expr: fmt.Sprintf(`sum by (method) (rate(microservice_grpc_request_duration_seconds_count{code=~"Unknown|DeadlineExceeded|Unimplemented|Internal|Unavailable|DataLoss"}[5m]))
+  / sum by (method) (rate(microservice_grpc_request_duration_seconds_count[5m])) > %s`, formatFloat(opts.ErrorRate)),
+			For:    "5m",
+			Labels: severity("warning"),
+			Annotations: map[string]string{
+				"summary":     "gRPC 方法 {{ $labels.method }} 服务端错误比例过高",
+				"description": fmt.Sprintf("最近 5 分钟服务端错误比例为 {{ $value | humanizePercentage }}，阈值 %s", formatPercent(opts.ErrorRate)),
+			},
+		},
+	}
+
+	// 多窗口消耗速率：1 小时内耗尽 2% 预算（14.4 倍）立即处理，6 小时内耗尽 5% 预算（6 倍）尽快处理
+	for _, o := range slo.Objectives {
+		for _, sli := range []struct {
+			name    string
+			enabled bool
+		}{
+			{"availability", o.Availability > 0},
+			{"latency", o.LatencyTarget > 0},
+		} {
+			if !sli.enabled {
+				continue
+			}
+			rules = append(rules,
+				burnRateRule(o, sli.name, "SLOFastBurn", "5m", "1h", 14.4, "2m", "critical"),
+				burnRateRule(o, sli.name, "SLOSlowBurn", "1h", "6h", 6, "15m", "warning"),
+			)
+		}
+	}
+	return rules
+}
+
+// burnRateRule 单个 SLO 目标的消耗速率告警：短窗口和长窗口同时超过倍数才触发
+func burnRateRule(o config.SLOObjectiveConfig, sli, alert, short, long string, factor float64, forDur, level string) AlertRule {
+	selector := func(window string) string {
+		return fmt.Sprintf(`microservice_slo_burn_rate{slo=%q, sli=%q, window=%q}`, o.Name, sli, window)
+	}
+	labels := severity(level)
+	labels["slo"] = o.Name
+	labels["sli"] = sli
+	return AlertRule{
+		Alert:  alert,
+		Expr:   fmt.Sprintf("%s > %s\n  and on (slo, sli) %s > %s", selector(short), formatFloat(factor), selector(long), formatFloat(factor)),
+		For:    forDur,
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s（%s %s）错误预算消耗过快", o.Name, strings.ToUpper(o.Kind), o.Target),
+			"description": fmt.Sprintf("%s 的 %s 错误预算在 %s 和 %s 窗口内的消耗速率均超过 %s 倍", o.Name, sli, short, long, formatFloat(factor)),
+		},
+	}
+}
+
+// queueAlertRules 每个队列的积压告警；慢消费转存队列有消息即告警
+func queueAlertRules(mq config.RabbitMQConfig, opts AlertOptions) []AlertRule {
+	var rules []AlertRule
+	for _, q := range mq.Queues {
+		rules = append(rules, AlertRule{
+			Alert:  "QueueBacklog",
+			Expr:   fmt.Sprintf(`microservice_mq_queue_depth{queue=%q} > %d`, q.Name, opts.QueueBacklog),
+			For:    "10m",
+			Labels: withLabel(severity("warning"), "queue", q.Name),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("队列 %s 积压", q.Name),
+				"description": fmt.Sprintf("队列 %s 持续 10 分钟积压 {{ $value }} 条消息（阈值 %d），检查消费者是否存活或处理变慢", q.Name, opts.QueueBacklog),
+			},
+		}, AlertRule{
+			Alert:  "QueueConsumeErrors",
+			Expr:   fmt.Sprintf(`sum(rate(microservice_mq_consumed_total{queue=%q, result!="success"}[5m])) / sum(rate(microservice_mq_consumed_total{queue=%q}[5m])) > %s`, q.Name, q.Name, formatFloat(opts.ErrorRate)),
+			For:    "10m",
+			Labels: withLabel(severity("warning"), "queue", q.Name),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("队列 %s 消费失败比例过高", q.Name),
+				"description": fmt.Sprintf("最近 5 分钟处理失败或超时的消息比例为 {{ $value | humanizePercentage }}，阈值 %s", formatPercent(opts.ErrorRate)),
+			},
+		})
+	}
+
+	if park := mq.SlowConsumer.ParkQueue; park != "" {
+		rules = append(rules, AlertRule{
+			Alert:  "SlowConsumerParked",
+			Expr:   fmt.Sprintf(`microservice_mq_queue_depth{queue=%q} > 0`, park),
+			For:    "5m",
+			Labels: withLabel(severity("warning"), "queue", park),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("慢消费转存队列 %s 中有消息", park),
+				"description": "{{ $value }} 条消息因处理超时被转存，需要排查后重新投递",
+			},
+		})
+	}
+	if dlq := mq.DeadLetterQueue; dlq != "" {
+		rules = append(rules, AlertRule{
+			Alert:  "DeadLetterQueueNotEmpty",
+			Expr:   fmt.Sprintf(`microservice_mq_queue_depth{queue=%q} > 0`, dlq),
+			For:    "5m",
+			Labels: withLabel(severity("warning"), "queue", dlq),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("死信队列 %s 中有消息", dlq),
+				"description": "{{ $value }} 条消息因无法解码（解密失败、转存对象丢失等）被转存，需要排查后重新投递",
+			},
+		})
+	}
+	return rules
+}
+
+// cronAlertRules 每个启用的定时任务在一个执行周期内失败即告警，告警持续到下一次执行
+func cronAlertRules(c config.CronConfig) ([]AlertRule, error) {
+	if !c.Enable {
+		return nil, nil
+	}
+
+	var rules []AlertRule
+	for _, job := range c.Jobs {
+		if !job.Enabled {
+			continue
+		}
+		interval, err := jobInterval(job.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("任务 %s: %w", job.Name, err)
+		}
+		window := promDuration(max(interval, 15*time.Minute))
+		rules = append(rules, AlertRule{
+			Alert:  "CronJobFailed",
+			Expr:   fmt.Sprintf(`increase(microservice_cron_job_runs_total{job=%q, result="error"}[%s]) > 0`, job.Name, window),
+			Labels: withLabel(severity("warning"), "job", job.Name),
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("定时任务 %s 执行失败", job.Name),
+				"description": fmt.Sprintf("%s（%s）最近 %s 内执行失败，详情见 GET /api/v1/admin/jobs/runs", job.Name, job.Spec, window),
+			},
+		})
+	}
+	return rules, nil
+}
+
+// jobInterval 任务的执行间隔（取两次相邻执行的间隔）
+func jobInterval(spec string) (time.Duration, error) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	first := schedule.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	return schedule.Next(first).Sub(first), nil
+}
+
+// poolAlertRules 数据库连接池接近上限、Redis 连接池等待超时
+func poolAlertRules(cfg *config.Config, opts AlertOptions) []AlertRule {
+	var rules []AlertRule
+	if cfg.Database.MaxOpenConns > 0 {
+		db := cfg.Database.DBName
+		rules = append(rules, AlertRule{
+			Alert: "DBPoolExhausted",
+			Expr: fmt.Sprintf(`go_sql_in_use_connections{db_name=%q} / go_sql_max_open_connections{db_name=%q} > %s`,
+				db, db, formatFloat(opts.PoolUsage)),
+			For:    "5m",
+			Labels: severity("warning"),
+			Annotations: map[string]string{
+				"summary":     "数据库连接池接近上限",
+				"description": fmt.Sprintf("{{ $labels.instance }} 已使用 {{ $value | humanizePercentage }} 的连接（max_open_conns=%d）", cfg.Database.MaxOpenConns),
+			},
+		}, AlertRule{
+			Alert:  "DBPoolWaiting",
+			Expr:   fmt.Sprintf(`rate(go_sql_wait_duration_seconds_total{db_name=%q}[5m]) > 0.1`, db),
+			For:    "5m",
+			Labels: severity("warning"),
+			Annotations: map[string]string{
+				"summary":     "请求在等待数据库连接",
+				"description": "{{ $labels.instance }} 每秒累计等待连接 {{ $value | humanizeDuration }}，考虑调大 database.max_open_conns 或排查慢查询",
+			},
+		})
+	}
+
+	rules = append(rules, AlertRule{
+		Alert:  "RedisPoolTimeouts",
+		Expr:   `increase(microservice_redis_pool_timeouts_total[5m]) > 0`,
+		Labels: severity("warning"),
+		Annotations: map[string]string{
+			"summary":     "等待 Redis 连接超时",
+			"description": fmt.Sprintf("{{ $labels.instance }} 最近 5 分钟有 {{ $value }} 次获取连接超时（redis.pool_size=%d）", cfg.Redis.PoolSize),
+		},
+	})
+	return rules
+}
+
+// severity 告警级别标签
+func severity(level string) map[string]string {
+	return map[string]string{"severity": level}
+}
+
+// withLabel 追加标签
+func withLabel(labels map[string]string, name, value string) map[string]string {
+	labels[name] = value
+	return labels
+}
+
+// promDuration Prometheus 时长格式，取能整除的最大单位
+func promDuration(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return strconv.Itoa(int(d/(24*time.Hour))) + "d"
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	default:
+		return strconv.Itoa(int(d/time.Second)) + "s"
+	}
+}
+
+// formatFloat 输出阈值，不带多余的 0
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatPercent 输出百分比
+func formatPercent(f float64) string {
+	return formatFloat(f*100) + "%"
+}
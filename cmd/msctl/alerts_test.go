@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestBuildAlertRules(t *testing.T) {
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{DBName: "orders", MaxOpenConns: 50},
+		RabbitMQ: config.RabbitMQConfig{
+			Queues:          []config.QueueConfig{{Name: "task_queue"}},
+			SlowConsumer:    config.SlowConsumerConfig{ParkQueue: "slow_queue"},
+			DeadLetterQueue: "dead_letter_queue",
+		},
+		Cron: config.CronConfig{Enable: true, Jobs: []config.JobConfig{
+			{Name: "hourly", Spec: "0 30 * * * *", Enabled: true},
+			{Name: "disabled", Spec: "0 0 * * * *"},
+		}},
+		SLO: config.SLOConfig{Objectives: []config.SLOObjectiveConfig{
+			{Name: "get_user", Kind: "http", Target: "GET /api/v1/users/:id", Availability: 0.999},
+		}},
+	}
+
+	groups, err := BuildAlertRules(cfg, AlertOptions{ErrorRate: 0.05, QueueBacklog: 500, PoolUsage: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAlertRules(&buf, groups); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`slo="get_user", sli="availability", window="5m"`,
+		`microservice_mq_queue_depth{queue="task_queue"} > 500`,
+		`microservice_mq_queue_depth{queue="slow_queue"} > 0`,
+		`microservice_mq_queue_depth{queue="dead_letter_queue"} > 0`,
+		`microservice_cron_job_runs_total{job="hourly", result="error"}[1h]`,
+		`go_sql_max_open_connections{db_name="orders"} > 0.9`,
+		"RedisPoolTimeouts",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("规则中缺少 %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `sli="latency"`) {
+		t.Error("未配置延迟目标时不应生成延迟告警")
+	}
+	if strings.Contains(out, `job="disabled"`) {
+		t.Error("未启用的任务不应生成告警")
+	}
+}
+
+func TestJobInterval(t *testing.T) {
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"0 */5 * * * *", 5 * time.Minute},
+		{"0 30 * * * *", time.Hour},
+		{"0 0 1 * * *", 24 * time.Hour},
+		{"@every 90s", 90 * time.Second},
+	}
+	for _, tt := range tests {
+		got, err := jobInterval(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("jobInterval(%q) = %v, %v, 期望 %v", tt.spec, got, err, tt.want)
+		}
+	}
+	if _, err := jobInterval("0 0 * * *"); err == nil {
+		t.Error("缺少秒字段的表达式应返回错误")
+	}
+}
+
+func TestPromDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		15 * time.Minute: "15m",
+		time.Hour:        "1h",
+		90 * time.Minute: "90m",
+		48 * time.Hour:   "2d",
+		90 * time.Second: "90s",
+	} {
+		if got := promDuration(d); got != want {
+			t.Errorf("promDuration(%v) = %s, 期望 %s", d, got, want)
+		}
+	}
+}
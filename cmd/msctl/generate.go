@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templates 已解析的模板
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).ParseFS(templateFS, "templates/*.tmpl"))
+
+// output 模板与生成文件的对应关系
+type output struct {
+	template string
+	path     func(r *Resource) string
+}
+
+// outputs 生成的文件
+var outputs = []output{
+	{"service.go.tmpl", func(r *Resource) string { return filepath.Join("internal", "service", r.Snake+".go") }},
+	{"service_test.go.tmpl", func(r *Resource) string { return filepath.Join("internal", "service", r.Snake+"_test.go") }},
+	{"handler.go.tmpl", func(r *Resource) string { return filepath.Join("internal", "handler", r.Snake+".go") }},
+	{"grpc.go.tmpl", func(r *Resource) string { return filepath.Join("cmd", "grpc-server", r.Snake+".go") }},
+	{"proto.tmpl", func(r *Resource) string { return filepath.Join("proto", r.Snake+".proto") }},
+}
+
+// modulesFile 网关模块注册文件
+var modulesFile = filepath.Join("cmd", "gateway", "modules.go")
+
+// Render 渲染资源的全部文件
+// 参数:
+//
+//	r: 资源
+//
+// 返回:
+//
+//	map[string][]byte: 相对路径 -> 文件内容（Go 文件已格式化）
+//	error: 模板或格式化错误
+func Render(r *Resource) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(outputs))
+	for _, o := range outputs {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, o.template, r); err != nil {
+			return nil, fmt.Errorf("渲染 %s 失败: %w", o.template, err)
+		}
+
+		path := o.path(r)
+		content := buf.Bytes()
+		if strings.HasSuffix(path, ".go") {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return nil, fmt.Errorf("格式化 %s 失败: %w", path, err)
+			}
+			content = formatted
+		}
+		files[path] = content
+	}
+	return files, nil
+}
+
+// Generate 生成资源文件并在网关中注册路由模块
+// 参数:
+//
+//	dir: 项目根目录
+//	r: 资源
+//	force: 是否覆盖已存在的文件
+//
+// 返回:
+//
+//	[]string: 已写入的文件
+//	error: 错误信息
+func Generate(dir string, r *Resource, force bool) ([]string, error) {
+	files, err := Render(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先检查全部文件，避免只生成一部分
+	if !force {
+		for path := range files {
+			if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+				return nil, fmt.Errorf("%s 已存在（使用 --force 覆盖）", path)
+			}
+		}
+	}
+
+	var written []string
+	for _, o := range outputs {
+		path := o.path(r)
+		if err := os.WriteFile(filepath.Join(dir, path), files[path], 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	changed, err := registerRoutes(filepath.Join(dir, modulesFile), r)
+	if err != nil {
+		return written, err
+	}
+	if changed {
+		written = append(written, modulesFile)
+	}
+	return written, nil
+}
+
+// registerRoutes 在网关 modules.go 的 init 末尾添加路由模块注册，已注册时不修改
+func registerRoutes(path string, r *Resource) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	src := string(data)
+
+	line := fmt.Sprintf("\tmodule.RegisterRoutes(%q, handler.Register%sRoutes)\n", r.Module, r.Name)
+	if strings.Contains(src, line) {
+		return false, nil
+	}
+
+	start := strings.Index(src, "func init() {")
+	if start < 0 {
+		return false, fmt.Errorf("%s 中没有 init 函数", path)
+	}
+	end := strings.Index(src[start:], "\n}")
+	if end < 0 {
+		return false, fmt.Errorf("%s 的 init 函数格式无法识别", path)
+	}
+	end += start + 1
+
+	src = src[:end] + line + src[end:]
+	formatted, err := format.Source([]byte(src))
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, formatted, 0o644)
+}
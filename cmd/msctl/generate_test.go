@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewResource(t *testing.T) {
+	r, err := NewResource("OrderItem", "订单明细", "sku:string, qty:int,order_id:int,note:text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string]string{
+		"Var":       r.Var,
+		"Snake":     r.Snake,
+		"Plural":    r.Plural,
+		"PluralVar": r.PluralVar,
+		"Table":     r.Table,
+	}
+	want := map[string]string{
+		"Var":       "orderItem",
+		"Snake":     "order_item",
+		"Plural":    "OrderItems",
+		"PluralVar": "orderItems",
+		"Table":     "order_items",
+	}
+	for k, v := range want {
+		if checks[k] != v {
+			t.Errorf("%s = %q, 期望 %q", k, checks[k], v)
+		}
+	}
+
+	if len(r.Fields) != 4 {
+		t.Fatalf("字段数 = %d, 期望 4", len(r.Fields))
+	}
+	sku, orderID := r.Fields[0], r.Fields[2]
+	if sku.GoName != "SKU" || sku.PBName != "Sku" {
+		t.Errorf("sku: GoName=%s PBName=%s", sku.GoName, sku.PBName)
+	}
+	if orderID.GoName != "OrderID" || orderID.PBName != "OrderId" {
+		t.Errorf("order_id: GoName=%s PBName=%s", orderID.GoName, orderID.PBName)
+	}
+	if got := len(r.FilterFields()); got != 1 {
+		t.Errorf("过滤字段数 = %d, 期望 1（仅 string）", got)
+	}
+}
+
+func TestNewResourceErrors(t *testing.T) {
+	tests := []struct{ name, fields string }{
+		{"order", "sku:string"},
+		{"Order", ""},
+		{"Order", "sku"},
+		{"Order", "sku:uuid"},
+		{"Order", "Sku:string"},
+		{"Order", "id:int"},
+		{"Order", "sku:string,sku:text"},
+		{"User", "name:string"},
+	}
+	for _, tt := range tests {
+		if _, err := NewResource(tt.name, "", tt.fields); err == nil {
+			t.Errorf("NewResource(%q, %q) 期望错误", tt.name, tt.fields)
+		}
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	for in, want := range map[string]string{
+		"order": "orders", "category": "categories", "key": "keys", "box": "boxes", "address": "addresses",
+	} {
+		if got := pluralize(in); got != want {
+			t.Errorf("pluralize(%q) = %q, 期望 %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"internal/service", "internal/handler", "cmd/grpc-server", "cmd/gateway", "proto"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modules := `package main
+
+import (
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+)
+
+func init() {
+	module.RegisterRoutes("users", handler.RegisterUserRoutes)
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, modulesFile), []byte(modules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewResource("Order", "订单", "sku:string,qty:int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := Generate(dir, r, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != len(outputs)+1 {
+		t.Errorf("写入文件 = %v", written)
+	}
+
+	proto, _ := os.ReadFile(filepath.Join(dir, "proto", "order.proto"))
+	for _, want := range []string{"service OrderService", "int64 qty = 3;", "string sku = 3;", "repeated Order orders = 1;", "string updated_at = 5;"} {
+		if !strings.Contains(string(proto), want) {
+			t.Errorf("proto 缺少 %q", want)
+		}
+	}
+
+	registered, _ := os.ReadFile(filepath.Join(dir, modulesFile))
+	if !strings.Contains(string(registered), `module.RegisterRoutes("orders", handler.RegisterOrderRoutes)`) {
+		t.Errorf("未注册路由模块:\n%s", registered)
+	}
+
+	// 已存在时拒绝覆盖；--force 覆盖且不重复注册
+	if _, err := Generate(dir, r, false); err == nil {
+		t.Error("文件已存在时期望错误")
+	}
+	if _, err := Generate(dir, r, true); err != nil {
+		t.Fatal(err)
+	}
+	registered, _ = os.ReadFile(filepath.Join(dir, modulesFile))
+	if n := strings.Count(string(registered), "RegisterOrderRoutes"); n != 1 {
+		t.Errorf("路由模块注册 %d 次, 期望 1", n)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// 项目管理命令
+//   - gen: 按现有 User 资源的写法生成新的 CRUD 资源：模型与服务、proto、gRPC 服务、REST 处理器（含参数校验、缓存与审计）及测试
+//   - gen alerts: 根据配置中的路由目标、队列、定时任务和连接池生成 Prometheus 告警规则
+//   - reindex: 从数据库重建派生数据（缓存等），支持断点续跑
+//
+// 用法:
+//
+//	msctl gen resource Order --fields "sku:string,qty:int" [--label 订单] [--dir .] [--force]
+//	msctl gen alerts [--config config/config.yaml] [--out alerts.yml] [--error-rate 0.05] [--queue-backlog 1000] [--pool-usage 0.9]
+//	msctl reindex users [--config config/config.yaml] [--batch 500] [--restart]
+func main() {
+	switch {
+	case len(os.Args) >= 4 && os.Args[1] == "gen" && os.Args[2] == "resource":
+		runGen(os.Args[3], os.Args[4:])
+	case len(os.Args) >= 3 && os.Args[1] == "gen" && os.Args[2] == "alerts":
+		os.Exit(runGenAlerts(os.Args[3:]))
+	case len(os.Args) >= 3 && os.Args[1] == "reindex":
+		os.Exit(runReindex(os.Args[2], os.Args[3:]))
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// runGen 执行 gen resource 子命令
+func runGen(name string, args []string) {
+
+	fs := flag.NewFlagSet("gen resource", flag.ExitOnError)
+	fields := fs.String("fields", "", "字段列表，格式 名称:类型，逗号分隔；类型: "+supportedTypes())
+	label := fs.String("label", "", "资源的中文名称，用于注释与错误信息，默认使用资源名")
+	dir := fs.String("dir", ".", "项目根目录")
+	force := fs.Bool("force", false, "覆盖已存在的文件")
+	fs.Parse(args)
+
+	res, err := NewResource(name, *label, *fields)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "参数错误: %v\n", err)
+		os.Exit(2)
+	}
+
+	written, err := Generate(*dir, res, *force)
+	for _, path := range written {
+		fmt.Printf("已生成 %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "生成失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf(`
+后续步骤:
+  1. make proto                                  # 生成 proto/%[1]s.pb.go
+  2. 在 internal/migrate/migrations 的各驱动目录中添加创建 %[2]s 表的 up/down SQL 迁移，执行 make migrate
+  3. 在 config/config.yaml 的 features 中添加 %[3]s: true
+  4. 按业务需要调整字段校验规则与列表过滤条件
+`, res.Snake, res.Table, res.Module)
+}
+
+// usage 输出用法
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法:
+  msctl gen resource <资源名> --fields "sku:string,qty:int" [--label 订单] [--dir .] [--force]
+  msctl gen alerts [--config config/config.yaml] [--out alerts.yml] [--error-rate 0.05] [--queue-backlog 1000] [--pool-usage 0.9]
+  msctl reindex <目标> [--config config/config.yaml] [--batch 500] [--restart]
+
+字段类型: `+supportedTypes()+`
+重建目标: `+reindexTargetNames())
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// checkpointKeyPrefix 重建进度的 Redis 键前缀，值为哈希 {last_id, processed}
+const checkpointKeyPrefix = "reindex:checkpoint:"
+
+// reindexTargets 可重建的目标
+var reindexTargets = map[string]func(ctx context.Context, opts reindexOptions) error{
+	"users": reindexUsers,
+}
+
+// reindexOptions 重建参数
+type reindexOptions struct {
+	// Batch 每批读取的记录数
+	Batch int
+	// Restart 忽略已保存的进度，从头开始
+	Restart bool
+}
+
+// userSink 用户数据的派生存储，每批用户依次写入
+// 目前只有 Redis 资料缓存；新增派生数据（如搜索索引）时在 userSinks 中追加
+type userSink struct {
+	name  string
+	apply func(ctx context.Context, users []*service.User) error
+}
+
+// userSinks 重建用户时写入的派生存储
+var userSinks = []userSink{
+	{name: "用户缓存", apply: warmUserCache},
+}
+
+// reindexTargetNames 返回可重建的目标名称，逗号分隔
+func reindexTargetNames() string {
+	names := make([]string, 0, len(reindexTargets))
+	for name := range reindexTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// runReindex 执行 reindex 子命令
+// 参数:
+//
+//	target: 重建目标
+//	args: 命令行参数
+//
+// 返回:
+//
+//	int: 退出码，中断时为 130
+func runReindex(target string, args []string) int {
+	run, ok := reindexTargets[target]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的重建目标: %s（可选: %s）\n", target, reindexTargetNames())
+		return 2
+	}
+
+	fs := flag.NewFlagSet("reindex "+target, flag.ExitOnError)
+	configPath := fs.String("config", "config/config.yaml", "配置文件路径，为空时只读取 MS_ 环境变量")
+	batch := fs.Int("batch", 500, "每批读取的记录数")
+	restart := fs.Bool("restart", false, "忽略上次中断保存的进度，从头开始")
+	fs.Parse(args)
+	if *batch <= 0 {
+		fmt.Fprintln(os.Stderr, "参数错误: --batch 必须大于 0")
+		return 2
+	}
+
+	if err := config.Load(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败: %v\n", err)
+		return 1
+	}
+	if err := logger.Init(config.GlobalConfig.Logger); err != nil {
+		fmt.Fprintf(os.Stderr, "初始化日志失败: %v\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	if err := database.Init(config.GlobalConfig.Database); err != nil {
+		logger.Error("初始化数据库失败", zap.Error(err))
+		return 1
+	}
+	defer database.Close()
+	if err := migrate.CheckSchema(context.Background()); err != nil {
+		logger.Error("表结构版本不兼容", zap.Error(err))
+		return 1
+	}
+
+	if err := cache.Init(config.GlobalConfig.Redis); err != nil {
+		logger.Error("初始化 Redis 失败", zap.Error(err))
+		return 1
+	}
+	defer cache.Close()
+	cache.InitRefresher(config.GlobalConfig.Redis.Refresh)
+
+	// 中断时处理完当前批次后退出，进度保留，再次执行从断点继续
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, reindexOptions{Batch: *batch, Restart: *restart})
+	switch {
+	case errors.Is(err, context.Canceled):
+		logger.Warn("重建已中断，进度已保存，再次执行同一命令继续", zap.String("target", target))
+		return 130
+	case err != nil:
+		logger.Error("重建失败，进度已保存，再次执行同一命令继续", zap.String("target", target), zap.Error(err))
+		return 1
+	}
+	return 0
+}
+
+// reindexUsers 分批读取全部用户并写入派生存储
+func reindexUsers(ctx context.Context, opts reindexOptions) error {
+	users := service.NewUserService(service.NewGormUserRepository(database.DB))
+	cache.DefaultRefresher.Register(service.UserCacheClass, func(ctx context.Context, key string) (interface{}, error) {
+		id, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		user, err := users.GetUser(ctx, id)
+		if err != nil || user == nil {
+			return nil, err
+		}
+		return user, nil
+	})
+
+	cpKey := checkpointKeyPrefix + "users"
+	cp := checkpoint{}
+	if opts.Restart {
+		if err := cache.RedisClient.Del(ctx, cpKey).Err(); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if cp, err = loadCheckpoint(ctx, cpKey); err != nil {
+			return err
+		}
+		if cp.LastID > 0 {
+			logger.Info("从上次中断处继续", zap.Int64("last_id", cp.LastID), zap.Int64("processed", cp.Processed))
+		}
+	}
+
+	_, total, err := users.ListUsers(ctx, service.UserFilter{}, 0, 1)
+	if err != nil {
+		return err
+	}
+	logger.Info("开始重建用户数据", zap.Int64("total", total), zap.Int("batch", opts.Batch))
+
+	start := time.Now()
+	done := int64(0)
+	err = users.ScanUsers(ctx, cp.LastID, opts.Batch, func(batch []*service.User) error {
+		for _, sink := range userSinks {
+			if err := sink.apply(ctx, batch); err != nil {
+				return fmt.Errorf("写入%s失败: %w", sink.name, err)
+			}
+		}
+
+		cp.LastID = batch[len(batch)-1].ID
+		cp.Processed += int64(len(batch))
+		done += int64(len(batch))
+		// 请求已中断时仍需保存本批进度
+		if err := cp.save(context.Background(), cpKey); err != nil {
+			return fmt.Errorf("保存进度失败: %w", err)
+		}
+
+		elapsed := time.Since(start).Seconds()
+		logger.Info("重建进度",
+			zap.Int64("processed", cp.Processed),
+			zap.Int64("total", total),
+			zap.String("percent", progressPercent(cp.Processed, total)),
+			zap.Int64("last_id", cp.LastID),
+			zap.Float64("per_second", float64(done)/max(elapsed, 0.001)),
+		)
+		return ctx.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := cache.RedisClient.Del(context.Background(), cpKey).Err(); err != nil {
+		logger.Warn("删除重建进度失败", zap.String("key", cpKey), zap.Error(err))
+	}
+	logger.Info("用户数据重建完成",
+		zap.Int64("processed", cp.Processed),
+		zap.Duration("elapsed", time.Since(start)),
+	)
+	return nil
+}
+
+// warmUserCache 将一批用户写入资料缓存
+func warmUserCache(ctx context.Context, users []*service.User) error {
+	values := make(map[string]interface{}, len(users))
+	for _, u := range users {
+		values[strconv.FormatInt(u.ID, 10)] = u
+	}
+	return cache.DefaultRefresher.Warm(ctx, service.UserCacheClass, values)
+}
+
+// checkpoint 重建进度
+type checkpoint struct {
+	// LastID 最后一条已处理记录的 ID
+	LastID int64
+	// Processed 已处理的记录数
+	Processed int64
+}
+
+// loadCheckpoint 读取已保存的进度，不存在时返回零值
+func loadCheckpoint(ctx context.Context, key string) (checkpoint, error) {
+	fields, err := cache.RedisClient.HGetAll(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return checkpoint{}, err
+	}
+	return parseCheckpoint(fields)
+}
+
+// parseCheckpoint 解析 Redis 哈希中的进度
+func parseCheckpoint(fields map[string]string) (checkpoint, error) {
+	var cp checkpoint
+	if len(fields) == 0 {
+		return cp, nil
+	}
+	var err error
+	if cp.LastID, err = strconv.ParseInt(fields["last_id"], 10, 64); err != nil {
+		return checkpoint{}, fmt.Errorf("进度数据无效，可使用 --restart 从头开始: %w", err)
+	}
+	if cp.Processed, err = strconv.ParseInt(fields["processed"], 10, 64); err != nil {
+		return checkpoint{}, fmt.Errorf("进度数据无效，可使用 --restart 从头开始: %w", err)
+	}
+	return cp, nil
+}
+
+// save 保存进度
+func (cp checkpoint) save(ctx context.Context, key string) error {
+	return cache.RedisClient.HSet(ctx, key, "last_id", cp.LastID, "processed", cp.Processed).Err()
+}
+
+// progressPercent 格式化完成百分比，重建期间新增用户可能使已处理数超过开始时的总数
+func progressPercent(processed, total int64) string {
+	if total <= 0 {
+		return "100.0%"
+	}
+	return fmt.Sprintf("%.1f%%", min(float64(processed)*100/float64(total), 100))
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseCheckpoint(t *testing.T) {
+	cp, err := parseCheckpoint(nil)
+	if err != nil || cp != (checkpoint{}) {
+		t.Errorf("空进度 = %+v, %v", cp, err)
+	}
+
+	cp, err = parseCheckpoint(map[string]string{"last_id": "1200", "processed": "1000"})
+	if err != nil || cp.LastID != 1200 || cp.Processed != 1000 {
+		t.Errorf("进度 = %+v, %v", cp, err)
+	}
+
+	if _, err := parseCheckpoint(map[string]string{"last_id": "x"}); err == nil {
+		t.Error("无效进度应返回错误")
+	}
+}
+
+func TestProgressPercent(t *testing.T) {
+	tests := []struct {
+		processed, total int64
+		want             string
+	}{
+		{0, 0, "100.0%"},
+		{250, 1000, "25.0%"},
+		{1001, 1000, "100.0%"},
+	}
+	for _, tt := range tests {
+		if got := progressPercent(tt.processed, tt.total); got != tt.want {
+			t.Errorf("progressPercent(%d, %d) = %s, 期望 %s", tt.processed, tt.total, got, tt.want)
+		}
+	}
+}
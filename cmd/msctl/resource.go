@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fieldType 字段类型在各层的表示
+type fieldType struct {
+	// GoType 模型中的 Go 类型
+	GoType string
+	// Gorm 列定义
+	Gorm string
+	// Proto proto 字段类型
+	Proto string
+	// Create 创建请求的校验规则
+	Create string
+	// Update 更新请求的校验规则（字段可选）
+	Update string
+	// Filter 是否支持作为列表过滤条件（精确匹配）
+	Filter bool
+}
+
+// fieldTypes 支持的字段类型
+var fieldTypes = map[string]fieldType{
+	"string": {GoType: "string", Gorm: "type:varchar(255);not null", Proto: "string", Create: "required,max=255", Update: "omitempty,max=255", Filter: true},
+	"text":   {GoType: "string", Gorm: "type:text", Proto: "string"},
+	"int":    {GoType: "int64", Gorm: "not null;default:0", Proto: "int64"},
+	"float":  {GoType: "float64", Gorm: "not null;default:0", Proto: "double"},
+	"bool":   {GoType: "bool", Gorm: "not null;default:false", Proto: "bool"},
+}
+
+// supportedTypes 支持的字段类型列表
+func supportedTypes() string {
+	names := make([]string, 0, len(fieldTypes))
+	for name := range fieldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// initialisms 生成 Go 名称时全部大写的缩写
+var initialisms = map[string]bool{
+	"id": true, "url": true, "sku": true, "ip": true, "api": true, "http": true, "uuid": true,
+}
+
+// Field 资源字段
+type Field struct {
+	fieldType
+	// Snake 列名、JSON 与 proto 字段名，如 unit_price
+	Snake string
+	// GoName 模型字段名，如 UnitPrice、SKU
+	GoName string
+	// PBName protoc 生成的字段名，如 UnitPrice、Sku
+	PBName string
+}
+
+// Resource 待生成的资源
+type Resource struct {
+	// Name 资源名（大驼峰），如 Order
+	Name string
+	// Label 中文名称，如 订单
+	Label string
+	// Var 变量名（小驼峰），如 order
+	Var string
+	// Snake 文件名与缓存类别，如 order
+	Snake string
+	// Plural 复数形式（大驼峰），如 Orders
+	Plural string
+	// PluralVar 复数变量名，如 orders
+	PluralVar string
+	// Table 表名，也是 REST 路径与 proto 列表字段名，如 orders
+	Table string
+	// Module 功能开关名称，与表名相同
+	Module string
+	Fields []Field
+}
+
+var (
+	resourceNamePattern = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	fieldNamePattern    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// reservedFields 模型中固定生成的字段
+var reservedFields = map[string]bool{"id": true, "created_at": true, "updated_at": true}
+
+// NewResource 解析资源名与字段定义
+// 参数:
+//
+//	name: 资源名（大驼峰，如 Order、OrderItem）
+//	label: 中文名称，为空时使用资源名
+//	fields: 字段定义，如 "sku:string,qty:int"
+//
+// 返回:
+//
+//	*Resource: 资源
+//	error: 格式错误
+func NewResource(name, label, fields string) (*Resource, error) {
+	if !resourceNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("资源名必须为大驼峰: %q", name)
+	}
+	if name == "User" {
+		return nil, fmt.Errorf("资源 %s 已存在", name)
+	}
+	if label == "" {
+		label = name
+	}
+
+	snake := toSnake(name)
+	table := pluralize(snake)
+	res := &Resource{
+		Name:      name,
+		Label:     label,
+		Var:       lowerFirst(name),
+		Snake:     snake,
+		Plural:    toPBName(table),
+		PluralVar: lowerFirst(toPBName(table)),
+		Table:     table,
+		Module:    table,
+	}
+	if token.Lookup(res.Var).IsKeyword() {
+		return nil, fmt.Errorf("资源名 %s 与 Go 关键字冲突", name)
+	}
+
+	seen := make(map[string]bool)
+	for _, def := range strings.Split(fields, ",") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		fieldName, typeName, ok := strings.Cut(def, ":")
+		if !ok {
+			return nil, fmt.Errorf("字段定义应为 名称:类型: %q", def)
+		}
+		fieldName = strings.TrimSpace(fieldName)
+		ft, ok := fieldTypes[strings.TrimSpace(typeName)]
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 的类型 %q 不支持（可选: %s）", fieldName, typeName, supportedTypes())
+		}
+		if !fieldNamePattern.MatchString(fieldName) {
+			return nil, fmt.Errorf("字段名必须为小写下划线格式: %q", fieldName)
+		}
+		if reservedFields[fieldName] || seen[fieldName] {
+			return nil, fmt.Errorf("字段 %s 重复或为保留字段", fieldName)
+		}
+		seen[fieldName] = true
+
+		res.Fields = append(res.Fields, Field{
+			fieldType: ft,
+			Snake:     fieldName,
+			GoName:    toGoName(fieldName),
+			PBName:    toPBName(fieldName),
+		})
+	}
+	if len(res.Fields) == 0 {
+		return nil, fmt.Errorf("至少需要一个字段")
+	}
+	return res, nil
+}
+
+// FilterFields 可作为列表过滤条件的字段
+func (r *Resource) FilterFields() []Field {
+	var fields []Field
+	for _, f := range r.Fields {
+		if f.Filter {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// toSnake 大驼峰转下划线：OrderItem -> order_item
+func toSnake(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 连续大写（缩写）只在单词边界处分隔：APIKey -> api_key
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toGoName 下划线转 Go 字段名，常见缩写全部大写：order_id -> OrderID
+func toGoName(snake string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(snake, "_") {
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(upperFirst(part))
+	}
+	return b.String()
+}
+
+// toPBName 下划线转 protoc-gen-go 生成的字段名（不处理缩写）：order_id -> OrderId
+func toPBName(snake string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(snake, "_") {
+		b.WriteString(upperFirst(part))
+	}
+	return b.String()
+}
+
+// pluralize 英文复数（覆盖常见规则）
+func pluralize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
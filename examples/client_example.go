@@ -71,7 +71,7 @@ func detailedHealthCheck() {
 	fmt.Printf("响应: %s\n", string(body))
 }
 
-// sendMessage 发送消息到队列
+// sendMessage 发送消息到队列（需要登录，访问令牌取自环境变量 ACCESS_TOKEN）
 func sendMessage() {
 	message := map[string]interface{}{
 		"queue": "task",
@@ -89,11 +89,16 @@ func sendMessage() {
 		return
 	}
 
-	resp, err := http.Post(
-		baseURL+"/api/v1/message",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/api/v1/message", bytes.NewBuffer(jsonData))
+	if err != nil {
+		fmt.Printf("创建请求失败: %v\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 发送消息需要登录，令牌通过 POST /api/v1/auth/login 获取
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("ACCESS_TOKEN"))
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Printf("请求失败: %v\n", err)
 		return
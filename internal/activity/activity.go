@@ -0,0 +1,212 @@
+package activity
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// lastSeenKey 记录用户最近活跃时间的 Redis 有序集合（score 为 Unix 秒）
+const lastSeenKey = "activity:last_seen"
+
+// Entry 用户活跃记录
+type Entry struct {
+	UserID     int64     `json:"user_id"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Tracker 用户活跃时间收集器
+// 请求只写入内存，由 Run 定期批量刷新到 Redis 和数据库
+type Tracker struct {
+	mu      sync.Mutex
+	pending map[int64]time.Time
+
+	interval     time.Duration
+	onlineWindow time.Duration
+	retention    time.Duration
+	now          func() time.Time
+}
+
+// DefaultTracker 全局活跃时间收集器
+var DefaultTracker = NewTracker(config.ActivityConfig{})
+
+// NewTracker 创建活跃时间收集器
+// 参数:
+//
+//	cfg: 活跃度配置
+//
+// 返回:
+//
+//	*Tracker: 收集器实例
+func NewTracker(cfg config.ActivityConfig) *Tracker {
+	interval := cfg.GetFlushInterval()
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	window := cfg.GetOnlineWindow()
+	if window <= 0 {
+		window = 5 * time.Minute
+	}
+
+	retention := cfg.GetRetention()
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	return &Tracker{
+		pending:      make(map[int64]time.Time),
+		interval:     interval,
+		onlineWindow: window,
+		retention:    retention,
+		now:          time.Now,
+	}
+}
+
+// Init 按配置初始化全局收集器
+// 参数:
+//
+//	cfg: 活跃度配置
+func Init(cfg config.ActivityConfig) {
+	DefaultTracker = NewTracker(cfg)
+}
+
+// Touch 记录用户活跃，同一用户在一个刷新周期内只保留最新时间
+// 参数:
+//
+//	userID: 用户 ID
+func (t *Tracker) Touch(userID int64) {
+	now := t.now()
+
+	t.mu.Lock()
+	if now.After(t.pending[userID]) {
+		t.pending[userID] = now
+	}
+	t.mu.Unlock()
+}
+
+// drain 取出并清空待刷新的记录
+func (t *Tracker) drain() map[int64]time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.pending) == 0 {
+		return nil
+	}
+	batch := t.pending
+	t.pending = make(map[int64]time.Time, len(batch))
+	return batch
+}
+
+// Run 启动定期刷新，ctx 取消时执行最后一次刷新后返回
+// 参数:
+//
+//	ctx: 上下文
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			t.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			t.Flush(ctx)
+		}
+	}
+}
+
+// Flush 将内存中的活跃记录写入 Redis 和数据库
+// 数据库写入在单个事务中完成，失败时记录日志，不重试（下次请求会重新记录）
+// 参数:
+//
+//	ctx: 上下文
+func (t *Tracker) Flush(ctx context.Context) {
+	batch := t.drain()
+	if len(batch) == 0 {
+		return
+	}
+
+	if cache.RedisClient != nil {
+		members := make([]redis.Z, 0, len(batch))
+		for id, ts := range batch {
+			members = append(members, redis.Z{Score: float64(ts.Unix()), Member: strconv.FormatInt(id, 10)})
+		}
+		if err := cache.RedisClient.ZAdd(ctx, lastSeenKey, members...).Err(); err != nil {
+			logger.Error("写入用户活跃时间到 Redis 失败", zap.Int("count", len(batch)), zap.Error(err))
+		}
+
+		// 清理超过保留时间的记录，避免有序集合无限增长
+		before := strconv.FormatInt(t.now().Add(-t.retention).Unix(), 10)
+		if err := cache.RedisClient.ZRemRangeByScore(ctx, lastSeenKey, "-inf", "("+before).Err(); err != nil {
+			logger.Warn("清理过期活跃记录失败", zap.Error(err))
+		}
+	}
+
+	if database.DB != nil {
+		err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for id, ts := range batch {
+				if err := tx.Model(&service.User{}).Where("id = ?", id).
+					UpdateColumn("last_seen_at", ts).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error("更新用户最近活跃时间失败", zap.Int("count", len(batch)), zap.Error(err))
+			return
+		}
+	}
+
+	logger.Debug("用户活跃时间已刷新", zap.Int("count", len(batch)))
+}
+
+// Recent 查询在时间窗口内活跃过的用户，按活跃时间倒序
+// 参数:
+//
+//	ctx: 上下文
+//	window: 时间窗口，<=0 时使用在线判定窗口
+//	limit: 最多返回条数
+//
+// 返回:
+//
+//	[]Entry: 活跃用户列表
+//	error: 错误信息
+func (t *Tracker) Recent(ctx context.Context, window time.Duration, limit int64) ([]Entry, error) {
+	if window <= 0 {
+		window = t.onlineWindow
+	}
+	since := t.now().Add(-window).Unix()
+
+	results, err := cache.RedisClient.ZRevRangeByScoreWithScores(ctx, lastSeenKey, &redis.ZRangeBy{
+		Min:   strconv.FormatInt(since, 10),
+		Max:   "+inf",
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(results))
+	for _, z := range results {
+		id, err := strconv.ParseInt(z.Member, 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{UserID: id, LastSeenAt: time.Unix(int64(z.Score), 0)})
+	}
+	return entries, nil
+}
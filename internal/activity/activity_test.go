@@ -0,0 +1,32 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestTouchKeepsLatestPerUser(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewTracker(config.ActivityConfig{})
+	tracker.now = func() time.Time { return now }
+
+	tracker.Touch(1)
+	now = now.Add(time.Second)
+	tracker.Touch(2)
+	now = now.Add(time.Second)
+	tracker.Touch(1)
+
+	batch := tracker.drain()
+	if len(batch) != 2 {
+		t.Fatalf("待刷新用户数 = %d, 期望 2", len(batch))
+	}
+	if want := now; !batch[1].Equal(want) {
+		t.Errorf("用户 1 活跃时间 = %v, 期望 %v", batch[1], want)
+	}
+
+	if again := tracker.drain(); again != nil {
+		t.Errorf("drain 后应清空, 得到 %v", again)
+	}
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Anchor 链头锚点，定期写入 S3（开启 Object Lock 时不可删除/覆盖），
+// 用于发现整段历史被重写或尾部记录被删除
+type Anchor struct {
+	EntryID    int64     `json:"entry_id"`
+	Hash       string    `json:"hash"`
+	AnchoredAt time.Time `json:"anchored_at"`
+}
+
+// WriteAnchor 将当前链头写入 S3
+// 参数:
+//
+//	ctx: 上下文
+//	cfg: 审计配置
+//
+// 返回:
+//
+//	*Anchor: 写入的锚点，链为空时返回 nil
+//	error: 错误信息
+func WriteAnchor(ctx context.Context, cfg config.AuditConfig) (*Anchor, error) {
+	if storage.S3Storage == nil {
+		return nil, storage.ErrUnavailable
+	}
+
+	head, err := lastEntry(database.DB.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, nil
+	}
+
+	anchor := &Anchor{EntryID: head.ID, Hash: head.Hash, AnchoredAt: time.Now().UTC()}
+	body, err := json.Marshal(anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s%020d.json", cfg.GetAnchorPrefix(), head.ID)
+	var retainUntil time.Time
+	if days := cfg.AnchorRetentionDays; days > 0 {
+		retainUntil = anchor.AnchoredAt.AddDate(0, 0, days)
+	}
+	if err := storage.S3Storage.PutObjectLocked(key, body, "application/json", retainUntil); err != nil {
+		return nil, err
+	}
+
+	logger.Info("审计链锚点已写入", zap.Int64("entry_id", head.ID), zap.String("key", key))
+	return anchor, nil
+}
+
+// LoadAnchors 读取 S3 中的全部锚点
+// 参数:
+//
+//	cfg: 审计配置
+//
+// 返回:
+//
+//	[]Anchor: 锚点列表
+//	error: 错误信息
+func LoadAnchors(cfg config.AuditConfig) ([]Anchor, error) {
+	if storage.S3Storage == nil {
+		return nil, storage.ErrUnavailable
+	}
+
+	keys, err := storage.S3Storage.ListFiles(cfg.GetAnchorPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	anchors := make([]Anchor, 0, len(keys))
+	for _, key := range keys {
+		body, err := storage.S3Storage.Download(key)
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取锚点 %s 失败: %w", key, err)
+		}
+
+		var anchor Anchor
+		if err := json.Unmarshal(data, &anchor); err != nil {
+			return nil, fmt.Errorf("解析锚点 %s 失败: %w", key, err)
+		}
+		anchors = append(anchors, anchor)
+	}
+	return anchors, nil
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// chainLockKey 追加审计记录时使用的事务锁（database.XactLockID），保证链按顺序追加
+const chainLockKey = 7_410_001
+
+// Entry 审计记录
+// 每条记录保存上一条记录的哈希（PrevHash），任何记录被修改、删除或插入都会导致后续哈希校验失败
+type Entry struct {
+	ID       int64  `gorm:"primaryKey" json:"id"`
+	Actor    string `gorm:"type:varchar(100);index" json:"actor"`
+	Action   string `gorm:"type:varchar(100);index" json:"action"`
+	Resource string `gorm:"type:varchar(200)" json:"resource"`
+	Detail   string `gorm:"type:text" json:"detail"`
+	// ClientIP 发起操作的客户端地址，来自请求上下文（见 WithClientIP）
+	ClientIP string `gorm:"type:varchar(45)" json:"client_ip,omitempty"`
+	// RequestID 发起操作的请求 ID（见 ctxkeys.RequestID），用于关联请求日志
+	RequestID string    `gorm:"type:varchar(128);index" json:"request_id,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	PrevHash  string    `gorm:"type:char(64);not null" json:"prev_hash"`
+	Hash      string    `gorm:"type:char(64);uniqueIndex;not null" json:"hash"`
+}
+
+// TableName 指定表名
+func (Entry) TableName() string {
+	return "audit_logs"
+}
+
+// computeHash 计算记录哈希：sha256(上一条哈希 | 时间 | 操作人 | 动作 | 资源 | 详情 [| 客户端地址 [| 请求 ID]])
+// 客户端地址和请求 ID 都为空时不参与计算，请求 ID 为空时只追加客户端地址，保证新增这些字段之前的记录哈希不变
+func computeHash(e *Entry) string {
+	fields := []string{
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Resource,
+		e.Detail,
+	}
+	if e.ClientIP != "" || e.RequestID != "" {
+		fields = append(fields, e.ClientIP)
+	}
+	if e.RequestID != "" {
+		fields = append(fields, e.RequestID)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// clientIPKey 上下文中客户端地址的键
+type clientIPKey struct{}
+
+// WithClientIP 在上下文中记录客户端地址，之后的 Record 调用会将其写入审计记录
+// 参数:
+//
+//	ctx: 上下文
+//	ip: 客户端地址
+//
+// 返回:
+//
+//	context.Context: 新上下文
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// Record 追加一条审计记录，请求上下文中的客户端地址和请求 ID 一并写入；
+// 开启 audit.stream 时写入后再发布到消息队列（见 Init）
+// 参数:
+//
+//	ctx: 上下文
+//	actor: 操作人
+//	action: 动作（如 settings.update）
+//	resource: 资源标识（如 settings/rate_limit）
+//	detail: 详情，序列化为 JSON 保存，可为 nil
+//
+// 返回:
+//
+//	error: 错误信息
+func Record(ctx context.Context, actor, action, resource string, detail interface{}) error {
+	var detailJSON string
+	if detail != nil {
+		data, err := json.Marshal(detail)
+		if err != nil {
+			return fmt.Errorf("序列化审计详情失败: %w", err)
+		}
+		detailJSON = string(data)
+	}
+
+	clientIP, _ := ctx.Value(clientIPKey{}).(string)
+	entry := &Entry{
+		Actor:     actor,
+		Action:    action,
+		Resource:  resource,
+		Detail:    detailJSON,
+		ClientIP:  clientIP,
+		RequestID: ctxkeys.RequestID(ctx),
+		// 数据库时间精度为微秒，提前截断保证读回后哈希一致
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	err := database.Conn(ctx, database.DB).Transaction(func(tx *gorm.DB) error {
+		if err := database.XactLockID(tx, chainLockKey); err != nil {
+			return err
+		}
+
+		head, err := lastEntry(tx)
+		if err != nil {
+			return err
+		}
+		if head != nil {
+			entry.PrevHash = head.Hash
+		}
+		entry.Hash = computeHash(entry)
+
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("写入审计记录失败",
+			zap.String("actor", actor),
+			zap.String("action", action),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	stream(ctx, entry)
+	return nil
+}
+
+// lastEntry 获取链头（最新一条记录），无记录时返回 nil
+func lastEntry(db *gorm.DB) (*Entry, error) {
+	var head Entry
+	err := db.Order("id DESC").Limit(1).Take(&head).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &head, nil
+}
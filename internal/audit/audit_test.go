@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildChain 构造一条合法的审计链
+func buildChain(n int) []Entry {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]Entry, n)
+	prev := ""
+	for i := range entries {
+		e := &entries[i]
+		e.ID = int64(i + 1)
+		e.Actor = "admin"
+		e.Action = "settings.update"
+		e.Resource = "settings/rate_limit"
+		e.Detail = `{"rps":10}`
+		e.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		e.PrevHash = prev
+		e.Hash = computeHash(e)
+		prev = e.Hash
+	}
+	return entries
+}
+
+// verifyEntries 按给定顺序校验记录
+func verifyEntries(entries []Entry, anchors []Anchor) *Result {
+	v := newChainVerifier(anchors)
+	for i := range entries {
+		v.check(&entries[i])
+	}
+	return v.finish()
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func([]Entry) []Entry
+		anchors func([]Entry) []Anchor
+		wantID  int64
+		reason  string
+	}{
+		{
+			name:   "未篡改",
+			mutate: func(e []Entry) []Entry { return e },
+		},
+		{
+			name: "修改内容",
+			mutate: func(e []Entry) []Entry {
+				e[2].Detail = `{"rps":1000}`
+				return e
+			},
+			wantID: 3,
+			reason: "内容被修改",
+		},
+		{
+			name: "删除记录",
+			mutate: func(e []Entry) []Entry {
+				return append(e[:2], e[3:]...)
+			},
+			wantID: 4,
+			reason: "上一条记录哈希不匹配",
+		},
+		{
+			name: "重排记录",
+			mutate: func(e []Entry) []Entry {
+				e[1], e[2] = e[2], e[1]
+				return e
+			},
+			wantID: 3,
+			reason: "上一条记录哈希不匹配",
+		},
+		{
+			name: "整链重写后与锚点不一致",
+			mutate: func(e []Entry) []Entry {
+				// 修改后重新计算全部哈希，链本身仍然自洽
+				e[1].Actor = "attacker"
+				prev := ""
+				for i := range e {
+					e[i].PrevHash = prev
+					e[i].Hash = computeHash(&e[i])
+					prev = e[i].Hash
+				}
+				return e
+			},
+			anchors: func(e []Entry) []Anchor {
+				return []Anchor{{EntryID: e[3].ID, Hash: e[3].Hash}}
+			},
+			wantID: 4,
+			reason: "锚点哈希不一致",
+		},
+		{
+			name: "删除链尾的锚点记录",
+			mutate: func(e []Entry) []Entry {
+				return e[:4]
+			},
+			anchors: func(e []Entry) []Anchor {
+				return []Anchor{{EntryID: e[4].ID, Hash: e[4].Hash}}
+			},
+			wantID: 5,
+			reason: "锚点对应的记录不存在",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := buildChain(5)
+			var anchors []Anchor
+			if tt.anchors != nil {
+				// 锚点取自篡改前的链
+				anchors = tt.anchors(entries)
+			}
+
+			result := verifyEntries(tt.mutate(entries), anchors)
+
+			if tt.wantID == 0 {
+				if !result.OK() {
+					t.Fatalf("期望校验通过, 实际发现问题: %+v", result.Problems)
+				}
+				return
+			}
+			if result.OK() {
+				t.Fatal("期望发现篡改, 实际校验通过")
+			}
+			first := result.Problems[0]
+			if first.EntryID != tt.wantID || !strings.Contains(first.Reason, tt.reason) {
+				t.Errorf("第一个问题 = %+v, 期望记录 %d 且原因包含 %q", first, tt.wantID, tt.reason)
+			}
+		})
+	}
+}
+
+func TestComputeHashCompatible(t *testing.T) {
+	e := buildChain(1)[0]
+	old := e.Hash
+
+	// 没有客户端地址和请求 ID 的记录哈希不变
+	if computeHash(&e) != old {
+		t.Fatal("未设置新字段时哈希应保持不变")
+	}
+	// 客户端地址与请求 ID 互换时哈希不同
+	a, b := e, e
+	a.RequestID = "10.0.0.1"
+	b.ClientIP = "10.0.0.1"
+	if computeHash(&a) == computeHash(&b) || computeHash(&a) == old {
+		t.Error("请求 ID 应参与哈希计算且与客户端地址区分")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type user struct {
+		Name   string `json:"name"`
+		Email  string `json:"email"`
+		Secret string `json:"-"`
+	}
+	changes := Diff(user{Name: "a", Email: "a@example.com", Secret: "x"}, user{Name: "b", Email: "a@example.com", Secret: "y"})
+	if len(changes) != 1 || changes["name"].From != "a" || changes["name"].To != "b" {
+		t.Errorf("Diff = %+v, 期望只有 name 变化", changes)
+	}
+	if changes := Diff(map[string]int{"n": 1}, map[string]int{}); len(changes) != 1 || changes["n"].To != nil {
+		t.Errorf("删除的字段应记录为 to=null: %+v", changes)
+	}
+}
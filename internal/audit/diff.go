@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Change 字段变更前后的值
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Diff 比较变更前后的对象，返回有变化的字段（以 JSON 字段名为键），作为审计详情保存
+// 对象按 JSON 序列化后比较，json:"-" 的字段（如密码哈希）不会出现在结果中
+// 参数:
+//
+//	before: 变更前的对象
+//	after: 变更后的对象
+//
+// 返回:
+//
+//	map[string]Change: 有变化的字段，无变化或无法序列化时为空
+func Diff(before, after interface{}) map[string]Change {
+	from, to := toFields(before), toFields(after)
+	changes := make(map[string]Change)
+	for k, v := range to {
+		if old, ok := from[k]; !ok || !reflect.DeepEqual(old, v) {
+			changes[k] = Change{From: old, To: v}
+		}
+	}
+	for k, old := range from {
+		if _, ok := to[k]; !ok {
+			changes[k] = Change{From: old}
+		}
+	}
+	return changes
+}
+
+// toFields 把对象按 JSON 序列化为字段映射
+func toFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
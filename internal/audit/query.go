@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/zhang/microservice/internal/database"
+	"gorm.io/gorm"
+)
+
+// Filter 审计记录查询条件，零值的条件不参与过滤
+type Filter struct {
+	// Actor 操作人
+	Actor string
+	// Action 动作，以 .* 结尾时按前缀匹配（如 users.* 匹配 users.create、users.update）
+	Action string
+	// Resource 资源标识前缀（如 users/42）
+	Resource string
+	// RequestID 请求 ID
+	RequestID string
+	// Since、Until 记录时间范围，左闭右开
+	Since, Until time.Time
+	// BeforeID 只返回 ID 小于该值的记录，用于翻页（取上一页最后一条的 ID）
+	BeforeID int64
+	// Limit 返回的最多条数
+	Limit int
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符，配合 ESCAPE '!' 使用
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// Query 按条件查询审计记录，最新的在前
+// 参数:
+//
+//	ctx: 上下文
+//	f: 查询条件
+//
+// 返回:
+//
+//	[]Entry: 审计记录
+//	error: 错误信息
+func Query(ctx context.Context, f Filter) ([]Entry, error) {
+	db := database.Conn(ctx, database.DB).Model(&Entry{})
+	if f.Actor != "" {
+		db = db.Where("actor = ?", f.Actor)
+	}
+	if prefix, ok := strings.CutSuffix(f.Action, ".*"); ok {
+		db = db.Where("action LIKE ? ESCAPE '!'", likeEscaper.Replace(prefix)+".%")
+	} else if f.Action != "" {
+		db = db.Where("action = ?", f.Action)
+	}
+	if f.Resource != "" {
+		db = db.Where("resource LIKE ? ESCAPE '!'", likeEscaper.Replace(f.Resource)+"%")
+	}
+	if f.RequestID != "" {
+		db = db.Where("request_id = ?", f.RequestID)
+	}
+	if !f.Since.IsZero() {
+		db = db.Where("created_at >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		db = db.Where("created_at < ?", f.Until.UTC())
+	}
+	if f.BeforeID > 0 {
+		db = db.Where("id < ?", f.BeforeID)
+	}
+
+	var entries []Entry
+	err := db.Order("id DESC").Limit(f.Limit).Find(&entries).Error
+	return entries, err
+}
+
+// Get 按 ID 查询审计记录，不存在时返回 nil, nil
+// 参数:
+//
+//	ctx: 上下文
+//	id: 记录 ID
+//
+// 返回:
+//
+//	*Entry: 审计记录
+//	error: 错误信息
+func Get(ctx context.Context, id int64) (*Entry, error) {
+	var e Entry
+	err := database.Conn(ctx, database.DB).Take(&e, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/queue"
+	"go.uber.org/zap"
+)
+
+// streamCfg 由 Init 设置的审计记录发布配置
+var streamCfg = struct {
+	mu  sync.RWMutex
+	cfg config.AuditStreamConfig
+}{}
+
+// Init 设置审计记录的发布配置，未调用时只写入数据库
+// 参数:
+//
+//	cfg: 审计日志配置
+func Init(cfg config.AuditConfig) {
+	streamCfg.mu.Lock()
+	defer streamCfg.mu.Unlock()
+	streamCfg.cfg = cfg.Stream
+}
+
+// stream 开启 audit.stream 时在后台把审计记录发布到消息队列，不阻塞调用方，失败只记录日志
+func stream(ctx context.Context, e *Entry) {
+	streamCfg.mu.RLock()
+	cfg := streamCfg.cfg
+	streamCfg.mu.RUnlock()
+	if !cfg.Enable || queue.MQClient == nil {
+		return
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	// 请求结束后仍需发布，保留请求 ID 但不随请求取消
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := queue.PublishContext(ctx, cfg.GetRoutingKey(), body); err != nil {
+			logger.FromContext(ctx).Warn("发布审计记录失败",
+				zap.Int64("id", e.ID),
+				zap.String("action", e.Action),
+				zap.Error(err),
+			)
+		}
+	}()
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pipelineBatchSize 批量操作每次往返发送的命令或键数，避免单个管道或 MGET 过大阻塞 Redis
+const pipelineBatchSize = 500
+
+// PipelineBatched 以管道分批执行 n 条命令，每批 pipelineBatchSize 条，一批一次往返
+// 参数:
+//
+//	ctx: 上下文
+//	n: 命令条数
+//	fn: 把第 i 条命令加入管道
+//
+// 返回:
+//
+//	error: 第一个失败的批次的错误，之后的批次不再执行
+func PipelineBatched(ctx context.Context, n int, fn func(pipe redis.Pipeliner, i int)) error {
+	for start := 0; start < n; start += pipelineBatchSize {
+		end := min(start+pipelineBatchSize, n)
+		if _, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := start; i < end; i++ {
+				fn(pipe, i)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MGet 批量读取字符串键，每批以一条 MGET 读取
+// 参数:
+//
+//	ctx: 上下文
+//	keys: 键名列表
+//
+// 返回:
+//
+//	map[string]string: 存在的键及其值，不存在的键不包含在内
+//	error: 错误信息
+func MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	for start := 0; start < len(keys); start += pipelineBatchSize {
+		batch := keys[start:min(start+pipelineBatchSize, len(keys))]
+		result, err := RedisClient.MGet(ctx, batch...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range result {
+			if s, ok := v.(string); ok {
+				values[batch[i]] = s
+			}
+		}
+	}
+	return values, nil
+}
+
+// MSet 批量写入键值；expiration 为 0 时每批以一条 MSET 写入，否则以管道中的 SET 写入并设置过期时间
+// 参数:
+//
+//	ctx: 上下文
+//	values: 键名到值的映射
+//	expiration: 过期时间（0 表示永不过期）
+//
+// 返回:
+//
+//	error: 错误信息，部分批次可能已写入
+func MSet(ctx context.Context, values map[string]interface{}, expiration time.Duration) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	if expiration > 0 {
+		return PipelineBatched(ctx, len(keys), func(pipe redis.Pipeliner, i int) {
+			pipe.Set(ctx, keys[i], values[keys[i]], expiration)
+		})
+	}
+	for start := 0; start < len(keys); start += pipelineBatchSize {
+		batch := keys[start:min(start+pipelineBatchSize, len(keys))]
+		pairs := make([]interface{}, 0, 2*len(batch))
+		for _, key := range batch {
+			pairs = append(pairs, key, values[key])
+		}
+		if err := RedisClient.MSet(ctx, pairs...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteByPattern 删除匹配模式的全部键：以 SCAN 分批遍历（从不使用 KEYS），每批以一条 UNLINK 删除，
+// 值的内存由 Redis 在后台释放；遍历期间新写入的匹配键可能不会被删除
+// 参数:
+//
+//	ctx: 上下文
+//	pattern: 匹配模式（如 cache:user:*）
+//
+// 返回:
+//
+//	int64: 删除的键数
+//	error: 错误信息，出错前已删除的键不会恢复
+func DeleteByPattern(ctx context.Context, pattern string) (int64, error) {
+	var (
+		cursor  uint64
+		deleted int64
+	)
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, pattern, pipelineBatchSize).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			n, err := RedisClient.Unlink(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,65 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestBatchHelpers(t *testing.T) {
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	// 超过一批的键数，覆盖分批逻辑
+	const n = 1200
+	values := make(map[string]interface{}, n)
+	keys := make([]string, 0, n+1)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("batch:%d", i)
+		values[key] = i
+		keys = append(keys, key)
+	}
+	if err := cache.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("batch:7"); ttl != time.Minute {
+		t.Errorf("TTL = %v", ttl)
+	}
+	if err := cache.MSet(ctx, map[string]interface{}{"other:1": "x"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := cache.MGet(ctx, append(keys, "batch:missing")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != n || got["batch:1199"] != "1199" {
+		t.Fatalf("MGet 返回 %d 个键, batch:1199 = %q", len(got), got["batch:1199"])
+	}
+
+	cmds := make([]*redis.IntCmd, n)
+	if err := cache.PipelineBatched(ctx, n, func(pipe redis.Pipeliner, i int) {
+		cmds[i] = pipe.Incr(ctx, keys[i])
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if v := cmds[n-1].Val(); v != n {
+		t.Errorf("最后一条命令结果 = %d", v)
+	}
+
+	deleted, err := cache.DeleteByPattern(ctx, "batch:*")
+	if err != nil || deleted != n {
+		t.Fatalf("DeleteByPattern = %d, %v", deleted, err)
+	}
+	if !mr.Exists("other:1") {
+		t.Error("不匹配的键被删除")
+	}
+	if deleted, err := cache.DeleteByPattern(ctx, "batch:*"); err != nil || deleted != 0 {
+		t.Errorf("再次删除 = %d, %v", deleted, err)
+	}
+}
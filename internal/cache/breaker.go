@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/resilience"
+)
+
+// breakerHook Redis 熔断的 go-redis 钩子，熔断期间命令直接返回“依赖不可用”错误
+// 位于 degradeHook 之内，熔断拒绝同样使 Redis 处于降级状态
+type breakerHook struct {
+	breaker *resilience.Breaker
+}
+
+// DialHook 建连失败也计入
+func (h breakerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := next(ctx, network, addr)
+		done(err)
+		return conn, err
+	}
+}
+
+// ProcessHook 保护单条命令
+func (h breakerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		err = next(ctx, cmd)
+		done(err)
+		return err
+	}
+}
+
+// ProcessPipelineHook 保护整个管道
+func (h breakerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		done, err := h.breaker.Allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		err = next(ctx, cmds)
+		done(err)
+		return err
+	}
+}
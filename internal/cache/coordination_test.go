@@ -0,0 +1,221 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestSemaphore(t *testing.T) {
+	testutil.InitLogger()
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	first, ok, err := cache.AcquireSemaphore(ctx, "sem:jobs", 2, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("获取第 1 个槽位 = %v, %v", ok, err)
+	}
+	watched, ok, err := cache.AcquireSemaphoreWithWatchdog(ctx, "sem:jobs", 2, 300*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("获取第 2 个槽位 = %v, %v", ok, err)
+	}
+	// 看门狗续期，超过 ttl 后槽位仍被持有
+	time.Sleep(500 * time.Millisecond)
+	if _, ok, _ := cache.AcquireSemaphore(ctx, "sem:jobs", 2, time.Minute); ok {
+		t.Fatal("槽位已满时不应获取成功")
+	}
+	if err := cache.ExtendSemaphore(ctx, "sem:jobs", first, time.Minute); err != nil {
+		t.Errorf("续期 = %v", err)
+	}
+	if err := cache.ExtendSemaphore(ctx, "sem:jobs", "unknown", time.Minute); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("续期未持有的槽位 = %v", err)
+	}
+
+	// 释放后等待者获取到槽位
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		watched.Release(context.Background())
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := cache.WaitSemaphore(waitCtx, "sem:jobs", 2, time.Minute, 10*time.Millisecond); err != nil {
+		t.Fatalf("WaitSemaphore = %v", err)
+	}
+	if watched.Context().Err() == nil {
+		t.Error("释放后上下文应被取消")
+	}
+
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := cache.WaitSemaphore(shortCtx, "sem:jobs", 2, time.Minute, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("超时后 WaitSemaphore = %v", err)
+	}
+}
+
+func TestIncrWindow(t *testing.T) {
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	for i, want := range []int64{1, 3} {
+		n, ttl, err := cache.IncrWindow(ctx, "count:jobs", int64(i+1), time.Minute)
+		if err != nil || n != want || ttl <= 0 || ttl > time.Minute {
+			t.Fatalf("IncrWindow = %d, %v, %v", n, ttl, err)
+		}
+	}
+	if n, err := cache.WindowCount(ctx, "count:jobs"); err != nil || n != 3 {
+		t.Errorf("WindowCount = %d, %v", n, err)
+	}
+
+	// 窗口结束后重新计数
+	mr.FastForward(time.Minute)
+	if n, err := cache.WindowCount(ctx, "count:jobs"); err != nil || n != 0 {
+		t.Errorf("窗口结束后 WindowCount = %d, %v", n, err)
+	}
+	if n, _, err := cache.IncrWindow(ctx, "count:jobs", 1, time.Minute); err != nil || n != 1 {
+		t.Errorf("新窗口 IncrWindow = %d, %v", n, err)
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	// 每秒 10 个（间隔 100ms），最多排队 2 个
+	var waits []time.Duration
+	for i := 0; i < 3; i++ {
+		ok, wait, err := cache.LeakyBucket(ctx, "leaky:api", 10, 2)
+		if err != nil || !ok {
+			t.Fatalf("第 %d 个请求 = %v, %v", i+1, ok, err)
+		}
+		waits = append(waits, wait)
+	}
+	if waits[0] != 0 || waits[1] < 50*time.Millisecond || waits[2] < 150*time.Millisecond || waits[2] > 200*time.Millisecond {
+		t.Errorf("等待时间 = %v", waits)
+	}
+
+	ok, retry, err := cache.LeakyBucket(ctx, "leaky:api", 10, 2)
+	if err != nil || ok || retry <= 0 || retry > 100*time.Millisecond {
+		t.Errorf("漏桶已满 = %v, %v, %v", ok, retry, err)
+	}
+	if _, _, err := cache.LeakyBucket(ctx, "leaky:api", 0, 2); err == nil {
+		t.Error("速率为 0 时应返回错误")
+	}
+}
+
+func TestLock(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	token, ok, err := cache.Lock(ctx, "lock:job", 10*time.Second)
+	if err != nil || !ok {
+		t.Fatalf("获取锁 = %v, %v", ok, err)
+	}
+	if ttl := mr.TTL("lock:job"); ttl != 10*time.Second {
+		t.Errorf("锁的过期时间 = %v, 期望 10s", ttl)
+	}
+	if _, ok, _ := cache.Lock(ctx, "lock:job", 10*time.Second); ok {
+		t.Fatal("锁被持有时不应获取成功")
+	}
+
+	// 令牌不一致时不释放、不续期
+	if err := cache.Unlock(ctx, "lock:job", "other"); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("以错误令牌释放 = %v", err)
+	}
+	if err := cache.ExtendLock(ctx, "lock:job", "other", time.Minute); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("以错误令牌续期 = %v", err)
+	}
+	if got, _ := mr.Get("lock:job"); got != token {
+		t.Fatal("错误令牌释放了锁")
+	}
+
+	if err := cache.ExtendLock(ctx, "lock:job", token, time.Minute); err != nil {
+		t.Fatalf("续期 = %v", err)
+	}
+	if ttl := mr.TTL("lock:job"); ttl != time.Minute {
+		t.Errorf("续期后的过期时间 = %v, 期望 1m", ttl)
+	}
+
+	// 过期后其他持有者获取锁，原持有者既不能续期也不能释放
+	mr.FastForward(time.Minute)
+	if err := cache.ExtendLock(ctx, "lock:job", token, time.Minute); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("过期后续期 = %v", err)
+	}
+	next, ok, err := cache.Lock(ctx, "lock:job", 1500*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("过期后获取锁 = %v, %v", ok, err)
+	}
+	if ttl := mr.TTL("lock:job"); ttl != 1500*time.Millisecond {
+		t.Errorf("非整秒的过期时间 = %v, 期望 1.5s", ttl)
+	}
+	if err := cache.Unlock(ctx, "lock:job", token); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("过期后释放 = %v", err)
+	}
+	if err := cache.Unlock(ctx, "lock:job", next); err != nil {
+		t.Errorf("释放 = %v", err)
+	}
+	if mr.Exists("lock:job") {
+		t.Error("释放后锁仍存在")
+	}
+}
+
+func TestLockWithWatchdog(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+	ttl := 300 * time.Millisecond
+
+	lock, ok, err := cache.LockWithWatchdog(ctx, "lock:watched", ttl)
+	if err != nil || !ok {
+		t.Fatalf("获取锁 = %v, %v", ok, err)
+	}
+	// 每 ttl/3 续期一次，过期时间恢复为 ttl
+	mr.FastForward(250 * time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for mr.TTL("lock:watched") != ttl {
+		if time.Now().After(deadline) {
+			t.Fatalf("未续期, 过期时间 = %v", mr.TTL("lock:watched"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 锁被他人获取后取消上下文
+	mr.Set("lock:watched", "other")
+	select {
+	case <-lock.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("锁丢失后上下文未取消")
+	}
+	if err := lock.Release(ctx); !errors.Is(err, cache.ErrLockNotHeld) {
+		t.Errorf("释放丢失的锁 = %v", err)
+	}
+	if got, _ := mr.Get("lock:watched"); got != "other" {
+		t.Errorf("释放了他人的锁, 值 = %q", got)
+	}
+
+	// 释放后停止续期
+	lock, ok, err = cache.LockWithWatchdog(ctx, "lock:released", ttl)
+	if err != nil || !ok {
+		t.Fatalf("获取锁 = %v, %v", ok, err)
+	}
+	token, _ := mr.Get("lock:released")
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("释放 = %v", err)
+	}
+	if lock.Context().Err() == nil {
+		t.Error("释放后上下文未取消")
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("重复释放 = %v", err)
+	}
+	// 以原令牌写回不带过期时间的键，仍在续期时会被设置过期时间
+	mr.Set("lock:released", token)
+	time.Sleep(ttl)
+	if got := mr.TTL("lock:released"); got != 0 {
+		t.Errorf("释放后仍在续期, 过期时间 = %v", got)
+	}
+}
@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/resilience"
+	"go.uber.org/zap"
+)
+
+// 降级策略
+const (
+	// PolicyLocal 限流退化为各实例本地限流
+	PolicyLocal = "local"
+	// PolicyOpen 放行（跳过检查）
+	PolicyOpen = "open"
+	// PolicyClosed 拒绝请求
+	PolicyClosed = "closed"
+	// PolicyBypass 跳过缓存直接读数据库
+	PolicyBypass = "bypass"
+	// PolicyFail 返回错误
+	PolicyFail = "fail"
+)
+
+// ErrDegraded Redis 不可用且策略要求返回错误
+var ErrDegraded = errors.New("Redis 不可用，服务已降级")
+
+// degradation Redis 降级状态
+type degradation struct {
+	mu       sync.RWMutex
+	policies config.RedisDegradationConfig
+	lastErr  string
+
+	degraded  atomic.Bool
+	since     atomic.Int64
+	lastProbe atomic.Int64
+}
+
+// degrade 全局降级状态
+var degrade = &degradation{}
+
+// DegradationStatus 降级状态
+type DegradationStatus struct {
+	// Degraded 是否处于降级状态
+	Degraded bool
+	// Since 进入降级状态的时间
+	Since time.Time
+	// LastError 最近一次导致降级的错误
+	LastError string
+	// Policies 各功能的降级策略
+	Policies map[string]string
+}
+
+// SetDegradationPolicies 设置降级策略（Init 时调用，测试中也可直接设置）
+// 参数:
+//
+//	cfg: 降级策略配置
+func SetDegradationPolicies(cfg config.RedisDegradationConfig) {
+	degrade.mu.Lock()
+	defer degrade.mu.Unlock()
+	degrade.policies = cfg
+}
+
+// current 返回当前的降级策略
+func (d *degradation) current() *config.RedisDegradationConfig {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	p := d.policies
+	return &p
+}
+
+// Degraded 是否处于降级状态
+func Degraded() bool {
+	return degrade.degraded.Load()
+}
+
+// Available Redis 是否可以访问
+// 正常时返回 true；降级期间返回 false，但每隔 probe_interval 放行一次调用用于检测恢复，
+// 该调用的结果由命令钩子更新降级状态
+// 返回:
+//
+//	bool: 是否访问 Redis
+func Available() bool {
+	if RedisClient == nil {
+		return false
+	}
+	if !degrade.degraded.Load() {
+		return true
+	}
+
+	now := time.Now().UnixNano()
+	last := degrade.lastProbe.Load()
+	if now-last < int64(degrade.current().GetProbeInterval()) {
+		return false
+	}
+	return degrade.lastProbe.CompareAndSwap(last, now)
+}
+
+// RevocationPolicy 返回令牌吊销检查的降级策略（closed 或 open）
+func RevocationPolicy() string {
+	return degrade.current().GetRevocation()
+}
+
+// RateLimitPolicy 返回 redis 模式限流的降级策略（local、open 或 closed）
+func RateLimitPolicy() string {
+	return degrade.current().GetRateLimit()
+}
+
+// ProbeInterval 返回降级期间检测 Redis 恢复的间隔（拒绝请求时用作 Retry-After）
+func ProbeInterval() time.Duration {
+	return degrade.current().GetProbeInterval()
+}
+
+// CacheReadable 读缓存的调用方是否应访问 Redis
+// 返回:
+//
+//	bool: true 表示读写缓存；false 表示跳过缓存直接读数据库（降级或未配置 Redis）
+//	error: 降级且策略为 fail 时返回 ErrDegraded
+func CacheReadable() (bool, error) {
+	if Available() {
+		return true, nil
+	}
+	// 未配置 Redis 不算降级
+	if RedisClient != nil && degrade.current().GetCache() == PolicyFail {
+		return false, ErrDegraded
+	}
+	return false, nil
+}
+
+// Degradation 返回降级状态（用于健康检查）
+// 返回:
+//
+//	DegradationStatus: 降级状态
+func Degradation() DegradationStatus {
+	p := degrade.current()
+	status := DegradationStatus{
+		Degraded: degrade.degraded.Load(),
+		Policies: map[string]string{
+			"rate_limit": p.GetRateLimit(),
+			"revocation": p.GetRevocation(),
+			"cache":      p.GetCache(),
+		},
+	}
+	if status.Degraded {
+		status.Since = time.Unix(0, degrade.since.Load())
+		degrade.mu.RLock()
+		status.LastError = degrade.lastErr
+		degrade.mu.RUnlock()
+	}
+	return status
+}
+
+// observe 根据命令结果更新降级状态
+func (d *degradation) observe(err error) {
+	if !isConnectionError(err) {
+		if d.degraded.CompareAndSwap(true, false) {
+			logger.Info("Redis 已恢复，退出降级状态",
+				zap.Duration("持续时间", time.Since(time.Unix(0, d.since.Load()))),
+			)
+		}
+		return
+	}
+
+	d.mu.Lock()
+	d.lastErr = err.Error()
+	d.mu.Unlock()
+	if d.degraded.CompareAndSwap(false, true) {
+		now := time.Now().UnixNano()
+		d.since.Store(now)
+		d.lastProbe.Store(now)
+		p := d.current()
+		logger.Error("Redis 不可用，进入降级状态",
+			zap.Error(err),
+			zap.String("rate_limit", p.GetRateLimit()),
+			zap.String("revocation", p.GetRevocation()),
+			zap.String("cache", p.GetCache()),
+		)
+	}
+}
+
+// isConnectionError 是否为连接类错误（网络错误、超时、连接已关闭、熔断）
+// 键不存在、命令错误等 Redis 正常返回的结果以及调用方取消不算
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, redis.ErrClosed) || errors.Is(err, resilience.ErrOpen)
+}
+
+// degradeHook 根据每条命令的结果更新降级状态的 go-redis 钩子
+type degradeHook struct{}
+
+// DialHook 建连失败也计入
+func (degradeHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			degrade.observe(err)
+		}
+		return conn, err
+	}
+}
+
+// ProcessHook 记录单条命令的结果
+func (degradeHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		degrade.observe(err)
+		return err
+	}
+}
+
+// ProcessPipelineHook 记录管道的结果
+func (degradeHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		degrade.observe(err)
+		return err
+	}
+}
@@ -0,0 +1,96 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestDegradation(t *testing.T) {
+	testutil.InitLogger()
+
+	// 没有服务监听的端口，Init 的 Ping 失败后进入降级状态
+	err := cache.Init(config.RedisConfig{
+		Host:        "127.0.0.1",
+		Port:        1,
+		Degradation: config.RedisDegradationConfig{ProbeInterval: 1},
+	})
+	t.Cleanup(func() {
+		_ = cache.Close()
+		cache.RedisClient = nil
+		cache.SetDegradationPolicies(config.RedisDegradationConfig{})
+	})
+	if err == nil {
+		t.Fatal("连接不存在的 Redis 应返回错误")
+	}
+
+	status := cache.Degradation()
+	if !cache.Degraded() || !status.Degraded || status.Since.IsZero() || status.LastError == "" {
+		t.Fatalf("期望进入降级状态: %+v", status)
+	}
+	if status.Policies["rate_limit"] != cache.PolicyLocal || status.Policies["revocation"] != cache.PolicyClosed ||
+		status.Policies["cache"] != cache.PolicyBypass {
+		t.Errorf("默认策略 = %v", status.Policies)
+	}
+
+	// 刚进入降级状态时不访问 Redis，读缓存按 bypass 直接读数据库
+	if cache.Available() {
+		t.Error("降级期间 Available 应返回 false")
+	}
+	if ok, err := cache.CacheReadable(); ok || err != nil {
+		t.Errorf("bypass: CacheReadable = %v, %v", ok, err)
+	}
+
+	// 每个检测间隔只放行一次调用
+	time.Sleep(1100 * time.Millisecond)
+	if !cache.Available() {
+		t.Error("超过检测间隔后应放行一次调用")
+	}
+	if cache.Available() {
+		t.Error("同一间隔内只应放行一次")
+	}
+
+	// 检测调用仍然失败，保持降级
+	_ = cache.HealthCheck()
+	if !cache.Degraded() {
+		t.Error("检测失败后应保持降级状态")
+	}
+
+	cache.SetDegradationPolicies(config.RedisDegradationConfig{Cache: cache.PolicyFail, ProbeInterval: 60})
+	if _, err := cache.CacheReadable(); !errors.Is(err, cache.ErrDegraded) {
+		t.Errorf("fail: 期望 ErrDegraded, 实际 %v", err)
+	}
+
+	// 刷新器在降级期间直接调用加载函数
+	refresher := cache.NewRefresher(config.CacheRefreshConfig{})
+	refresher.Register("user", func(ctx context.Context, key string) (interface{}, error) {
+		return map[string]string{"id": key}, nil
+	})
+	var got map[string]string
+	if _, err := refresher.Fetch(context.Background(), "user", "1", &got); !errors.Is(err, cache.ErrDegraded) {
+		t.Errorf("fail: Fetch 期望 ErrDegraded, 实际 %v", err)
+	}
+	cache.SetDegradationPolicies(config.RedisDegradationConfig{ProbeInterval: 60})
+	found, err := refresher.Fetch(context.Background(), "user", "1", &got)
+	if err != nil || !found || got["id"] != "1" {
+		t.Errorf("bypass: Fetch = %v, %v, %v", found, got, err)
+	}
+
+	// Redis 可用后第一条成功的命令即退出降级状态
+	mr := miniredis.RunT(t)
+	port, _ := strconv.Atoi(mr.Port())
+	_ = cache.Close()
+	if err := cache.Init(config.RedisConfig{Host: mr.Host(), Port: port}); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Degraded() || !cache.Available() {
+		t.Error("Redis 恢复后应退出降级状态")
+	}
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localEntry 进程内缓存项
+type localEntry struct {
+	key      string
+	data     []byte
+	expireAt time.Time
+}
+
+// LocalCache 进程内 LRU 缓存，保存编码后的缓存值，超过容量时淘汰最久未使用的键，并发安全
+type LocalCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+// NewLocalCache 创建进程内缓存
+// 参数:
+//
+//	size: 最多缓存的键数
+//	ttl: 缓存时间
+//
+// 返回:
+//
+//	*LocalCache: 进程内缓存
+func NewLocalCache(size int, ttl time.Duration) *LocalCache {
+	return &LocalCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+// Get 读取缓存值，过期的键视为不存在并删除
+// 参数:
+//
+//	key: 键名
+//
+// 返回:
+//
+//	[]byte: 缓存值（调用方不得修改）
+//	bool: 键是否存在
+func (c *LocalCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*localEntry)
+	if !c.now().Before(e.expireAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e.data, true
+}
+
+// Set 写入缓存值
+// 参数:
+//
+//	key: 键名
+//	data: 缓存值（写入后调用方不得修改）
+func (c *LocalCache) Set(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireAt := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*localEntry)
+		e.data, e.expireAt = data, expireAt
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&localEntry{key: key, data: data, expireAt: expireAt})
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// Delete 删除缓存值
+// 参数:
+//
+//	key: 键名
+func (c *LocalCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// Len 返回当前缓存的键数（包括已过期但尚未清理的键）
+func (c *LocalCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// removeElement 删除链表节点及其索引，调用方持有锁
+func (c *LocalCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*localEntry).key)
+}
@@ -0,0 +1,113 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestLocalCache(t *testing.T) {
+	c := cache.NewLocalCache(2, 50*time.Millisecond)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a") // a 最近使用，写入 c 时淘汰 b
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("最久未使用的键未被淘汰")
+	}
+	if v, ok := c.Get("a"); !ok || string(v) != "1" {
+		t.Errorf("Get(a) = %q, %v", v, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d", c.Len())
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("删除后仍可读取")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := c.Get("c"); ok {
+		t.Error("过期后仍可读取")
+	}
+	if c.Len() != 0 {
+		t.Errorf("过期键未清理, Len = %d", c.Len())
+	}
+}
+
+func TestRefresherLocalTier(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go cache.DefaultEventBus.Run(ctx)
+	waitSubscribed(t, mr)
+
+	cfg := config.CacheRefreshConfig{Local: config.LocalCacheConfig{Enable: true, Size: 10, TTL: 60}}
+	loads := 0
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		loads++
+		return "v" + key, nil
+	}
+	// 两个刷新器模拟两个实例，共用同一个 Redis
+	a, b := cache.NewRefresher(cfg), cache.NewRefresher(cfg)
+	t.Cleanup(a.Close)
+	t.Cleanup(b.Close)
+	a.Register("local", loader)
+	b.Register("local", loader)
+
+	fetch := func(r *cache.Refresher) string {
+		t.Helper()
+		var v string
+		if found, err := r.Fetch(ctx, "local", "1", &v); err != nil || !found {
+			t.Fatalf("Fetch = %v, %v", found, err)
+		}
+		return v
+	}
+
+	localHits := metrics.CacheClassRequests.WithLabelValues("local", "local_hit")
+	fetch(a)
+	fetch(b)
+	commands := mr.CommandCount()
+	if v := fetch(a); v != "v1" {
+		t.Fatalf("Fetch = %q", v)
+	}
+	fetch(b)
+	if mr.CommandCount() != commands {
+		t.Error("本地命中不应访问 Redis")
+	}
+	if got := promtest.ToFloat64(localHits); got != 2 {
+		t.Errorf("本地命中 %v 次, 期望 2", got)
+	}
+	if loads != 1 {
+		t.Errorf("回源 %d 次, 期望 1", loads)
+	}
+
+	// a 的失效通过发布/订阅删除 b 的本地副本
+	if err := a.Invalidate(ctx, "local", "1"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		commands = mr.CommandCount()
+		fetch(b)
+		if mr.CommandCount() != commands {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("其他实例的本地缓存未失效")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if loads != 2 {
+		t.Errorf("失效后回源 %d 次, 期望 2", loads)
+	}
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// loginFailuresPrefix 登录失败次数的 Redis 键前缀
+	loginFailuresPrefix = "auth:login_failures:"
+	// loginLockedPrefix 账号锁定标记的 Redis 键前缀
+	loginLockedPrefix = "auth:login_locked:"
+)
+
+// loginFailureScript 记录一次登录失败
+// 失败次数在窗口内累加，首次失败时设置窗口过期；达到上限时设置锁定标记并清零计数。
+// 返回 {是否已锁定, 剩余可尝试次数}
+var loginFailureScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if count >= tonumber(ARGV[1]) then
+  redis.call('SET', KEYS[2], 1, 'PX', ARGV[3])
+  redis.call('DEL', KEYS[1])
+  return {1, 0}
+end
+return {0, tonumber(ARGV[1]) - count}
+`)
+
+// LoginLocked 查询账号是否处于锁定状态
+// 参数:
+//
+//	ctx: 上下文
+//	subject: 登录标识（如邮箱）
+//
+// 返回:
+//
+//	time.Duration: 剩余锁定时间，未锁定时为 0
+//	error: 错误信息
+func LoginLocked(ctx context.Context, subject string) (time.Duration, error) {
+	if RedisClient == nil {
+		return 0, errors.New("Redis 未初始化")
+	}
+	ttl, err := RedisClient.PTTL(ctx, loginLockedPrefix+subject).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// RecordLoginFailure 记录一次登录失败，窗口内失败达到上限时锁定账号
+// 参数:
+//
+//	ctx: 上下文
+//	subject: 登录标识（如邮箱）
+//	maxAttempts: 锁定前允许的失败次数
+//	window: 失败次数统计窗口
+//	lockFor: 锁定时长
+//
+// 返回:
+//
+//	bool: 本次失败后是否已锁定
+//	int: 剩余可尝试次数
+//	error: 错误信息
+func RecordLoginFailure(ctx context.Context, subject string, maxAttempts int, window, lockFor time.Duration) (bool, int, error) {
+	if RedisClient == nil {
+		return false, 0, errors.New("Redis 未初始化")
+	}
+	keys := []string{loginFailuresPrefix + subject, loginLockedPrefix + subject}
+	result, err := loginFailureScript.Run(ctx, RedisClient, keys, maxAttempts, window.Milliseconds(), lockFor.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return result[0] == 1, int(result[1]), nil
+}
+
+// ResetLoginFailures 登录成功后清零失败次数
+// 参数:
+//
+//	ctx: 上下文
+//	subject: 登录标识（如邮箱）
+//
+// 返回:
+//
+//	error: 错误信息
+func ResetLoginFailures(ctx context.Context, subject string) error {
+	if RedisClient == nil {
+		return errors.New("Redis 未初始化")
+	}
+	return RedisClient.Del(ctx, loginFailuresPrefix+subject).Err()
+}
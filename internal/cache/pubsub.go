@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// channelPrefix 事件频道的 Redis 键前缀，所有实例订阅 channelPrefix* 一个模式
+const channelPrefix = "events:"
+
+// 订阅中断后重新订阅的退避时间，以及空闲时检测连接的间隔
+const (
+	resubscribeMin    = time.Second
+	resubscribeMax    = 30 * time.Second
+	eventPingInterval = 30 * time.Second
+)
+
+// InstanceID 当前进程的标识（主机名:进程号），作为事件的 Source
+var InstanceID = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}()
+
+// Event 跨实例事件
+type Event struct {
+	// Channel 频道名称（不含 Redis 前缀）
+	Channel string `json:"-"`
+	// Source 发布者实例，处理器可据此忽略本实例发布的事件（见 FromSelf）
+	Source string `json:"source"`
+	// At 发布时间
+	At time.Time `json:"at"`
+	// Data JSON 负载
+	Data json.RawMessage `json:"data"`
+}
+
+// Decode 把负载解码到 v
+// 参数:
+//
+//	v: 解码目标（指针）
+//
+// 返回:
+//
+//	error: 解码失败时返回错误
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// FromSelf 是否为本实例发布的事件
+func (e *Event) FromSelf() bool {
+	return e.Source == InstanceID
+}
+
+// EventHandler 事件处理函数，在订阅循环中依次调用，应尽快返回（耗时操作另起 goroutine）
+type EventHandler func(ctx context.Context, e *Event)
+
+// eventHandler 已注册的处理函数
+type eventHandler struct {
+	fn EventHandler
+}
+
+// EventBus 基于 Redis 发布/订阅的轻量跨实例事件（缓存失效通知、WebSocket 广播等），不经过 RabbitMQ
+// 事件只投递给当时在线的实例，订阅中断期间发布的事件会丢失，需要可靠投递时应使用消息队列；
+// 未配置 Redis（单实例部署）时事件只分发给本实例的处理函数
+type EventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]*eventHandler
+}
+
+// DefaultEventBus 全局事件总线，Publish / Subscribe 使用它
+var DefaultEventBus = NewEventBus()
+
+// NewEventBus 创建事件总线
+// 返回:
+//
+//	*EventBus: 事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: make(map[string][]*eventHandler)}
+}
+
+// checkChannel 校验频道名称：非空且不含模式匹配字符
+func checkChannel(channel string) error {
+	if channel == "" || strings.ContainsAny(channel, "*?[]") {
+		return fmt.Errorf("无效的事件频道名称 %q", channel)
+	}
+	return nil
+}
+
+// Subscribe 注册频道的处理函数，Run 启动前后均可注册
+// 参数:
+//
+//	channel: 频道名称，如 settings.changed
+//	handler: 处理函数
+//
+// 返回:
+//
+//	func(): 取消注册
+func (b *EventBus) Subscribe(channel string, handler EventHandler) func() {
+	h := &eventHandler{fn: handler}
+	b.mu.Lock()
+	b.handlers[channel] = append(b.handlers[channel], h)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		handlers := b.handlers[channel]
+		for i, registered := range handlers {
+			if registered == h {
+				b.handlers[channel] = append(handlers[:i:i], handlers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish 以 JSON 编码 payload 并发布到频道，所有实例（包括本实例）注册的处理函数都会收到
+// 参数:
+//
+//	ctx: 上下文
+//	channel: 频道名称
+//	payload: 负载
+//
+// 返回:
+//
+//	error: 频道名称无效、编码或发布失败时返回错误
+func (b *EventBus) Publish(ctx context.Context, channel string, payload interface{}) error {
+	if err := checkChannel(channel); err != nil {
+		return err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	e := &Event{Channel: channel, Source: InstanceID, At: time.Now().UTC(), Data: data}
+
+	if RedisClient == nil {
+		b.dispatch(ctx, e)
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return RedisClient.Publish(ctx, channelPrefix+channel, body).Err()
+}
+
+// dispatch 把事件交给频道的处理函数，处理函数 panic 时记录日志并继续
+func (b *EventBus) dispatch(ctx context.Context, e *Event) {
+	b.mu.RLock()
+	handlers := b.handlers[e.Channel]
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Error("处理事件时发生 panic", zap.String("channel", e.Channel), zap.Any("panic", r))
+				}
+			}()
+			h.fn(ctx, e)
+		}()
+	}
+}
+
+// Run 订阅事件并分发给处理函数，直到 ctx 取消
+// 连接断开或订阅失败后按退避时间（1 秒起，最长 30 秒）重新订阅；未配置 Redis 时直接返回
+// 参数:
+//
+//	ctx: 上下文
+func (b *EventBus) Run(ctx context.Context) {
+	if RedisClient == nil {
+		logger.Info("未配置 Redis，事件只在本实例内分发")
+		return
+	}
+
+	backoff := resubscribeMin
+	for {
+		subscribed, err := b.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if subscribed {
+			backoff = resubscribeMin
+		}
+		logger.Warn("事件订阅中断，稍后重新订阅", zap.Duration("retry_in", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > resubscribeMax {
+			backoff = resubscribeMax
+		}
+	}
+}
+
+// receive 订阅所有事件频道并循环分发，返回是否订阅成功过以及中断的原因
+func (b *EventBus) receive(ctx context.Context) (bool, error) {
+	ps := RedisClient.PSubscribe(ctx, channelPrefix+"*")
+	defer ps.Close()
+	// 阻塞的读取不响应 ctx，取消时关闭订阅连接使其返回
+	stop := context.AfterFunc(ctx, func() { ps.Close() })
+	defer stop()
+
+	// 等待订阅确认
+	if _, err := ps.Receive(ctx); err != nil {
+		return false, err
+	}
+	logger.Info("已订阅跨实例事件", zap.String("pattern", channelPrefix+"*"))
+
+	for {
+		msg, err := ps.ReceiveTimeout(ctx, eventPingInterval)
+		if err != nil {
+			// 空闲超时时发送 PING，检测半开的连接
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+				if err := ps.Ping(ctx); err != nil {
+					return true, err
+				}
+				continue
+			}
+			return true, err
+		}
+
+		m, ok := msg.(*redis.Message)
+		if !ok {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			logger.Warn("忽略无法解析的事件", zap.String("channel", m.Channel), zap.Error(err))
+			continue
+		}
+		e.Channel = strings.TrimPrefix(m.Channel, channelPrefix)
+		b.dispatch(ctx, &e)
+	}
+}
+
+// Publish 通过全局事件总线发布事件
+// 参数:
+//
+//	ctx: 上下文
+//	channel: 频道名称
+//	payload: 负载，以 JSON 编码
+//
+// 返回:
+//
+//	error: 错误信息
+func Publish(ctx context.Context, channel string, payload interface{}) error {
+	return DefaultEventBus.Publish(ctx, channel, payload)
+}
+
+// Subscribe 在全局事件总线上注册处理函数
+// 参数:
+//
+//	channel: 频道名称
+//	handler: 处理函数
+//
+// 返回:
+//
+//	func(): 取消注册
+func Subscribe(channel string, handler EventHandler) func() {
+	return DefaultEventBus.Subscribe(channel, handler)
+}
@@ -0,0 +1,113 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+type invalidation struct {
+	Class string `json:"class"`
+	Key   string `json:"key"`
+}
+
+// waitSubscribed 等待订阅循环在 Redis 上建立模式订阅
+func waitSubscribed(t *testing.T, mr *miniredis.Miniredis) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for mr.PubSubNumPat() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("等待订阅超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := cache.NewEventBus()
+	received := make(chan *cache.Event, 4)
+	bus.Subscribe("cache.invalidate", func(ctx context.Context, e *cache.Event) {
+		panic("处理函数 panic 不影响其他处理函数")
+	})
+	bus.Subscribe("cache.invalidate", func(ctx context.Context, e *cache.Event) {
+		received <- e
+	})
+	unsubscribe := bus.Subscribe("other", func(ctx context.Context, e *cache.Event) {
+		t.Error("已取消注册的处理函数不应被调用")
+	})
+	unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		bus.Run(ctx)
+		close(done)
+	}()
+	waitSubscribed(t, mr)
+
+	publish := func(key string) {
+		t.Helper()
+		if err := bus.Publish(ctx, "cache.invalidate", invalidation{Class: "user", Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		if err := bus.Publish(ctx, "other", nil); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-received:
+			var payload invalidation
+			if err := e.Decode(&payload); err != nil || payload.Key != key || e.Channel != "cache.invalidate" || !e.FromSelf() {
+				t.Fatalf("事件 = %+v, 负载 = %+v, %v", e, payload, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("未收到事件")
+		}
+	}
+	publish("1")
+
+	// Redis 重启后订阅断开，自动重新订阅
+	mr.Restart()
+	waitSubscribed(t, mr)
+	publish("2")
+
+	if err := bus.Publish(ctx, "events:*", nil); err == nil {
+		t.Error("含模式字符的频道名称应返回错误")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("取消后 Run 未返回")
+	}
+}
+
+func TestEventBusWithoutRedis(t *testing.T) {
+	testutil.InitLogger()
+	prev := cache.RedisClient
+	cache.RedisClient = nil
+	t.Cleanup(func() { cache.RedisClient = prev })
+
+	bus := cache.NewEventBus()
+	var got []string
+	bus.Subscribe("ws.broadcast", func(ctx context.Context, e *cache.Event) {
+		var msg string
+		_ = e.Decode(&msg)
+		got = append(got, msg)
+	})
+	bus.Run(context.Background()) // 未配置 Redis 时立即返回
+	if err := bus.Publish(context.Background(), "ws.broadcast", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "hello" {
+		t.Errorf("本实例分发 = %v", got)
+	}
+}
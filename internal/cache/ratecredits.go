@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateCreditPrefix 限流突发额度的 Redis 键前缀，后接白名单客户端名称，值为剩余请求数
+const rateCreditPrefix = "ratelimit:credits:"
+
+// RateCredit 客户端的突发额度
+type RateCredit struct {
+	Client    string    `json:"client"`
+	Remaining int64     `json:"remaining"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// consumeCreditScript 额度大于 0 时扣减一次，返回扣减后的剩余额度，没有额度时返回 -1
+var consumeCreditScript = redis.NewScript(`
+local n = tonumber(redis.call('GET', KEYS[1]))
+if n == nil or n <= 0 then
+  return -1
+end
+return redis.call('DECR', KEYS[1])
+`)
+
+// GrantRateCredits 为客户端增加突发额度（超出限流后仍可通过的请求数），有效期从本次授予时重新计算
+// 参数:
+//
+//	ctx: 上下文
+//	client: 白名单客户端名称
+//	credits: 增加的请求数
+//	ttl: 有效期
+//
+// 返回:
+//
+//	int64: 增加后的剩余额度
+//	error: 错误信息
+func GrantRateCredits(ctx context.Context, client string, credits int64, ttl time.Duration) (int64, error) {
+	if RedisClient == nil {
+		return 0, errors.New("Redis 未初始化")
+	}
+
+	var total *redis.IntCmd
+	_, err := RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.IncrBy(ctx, rateCreditPrefix+client, credits)
+		pipe.Expire(ctx, rateCreditPrefix+client, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total.Val(), nil
+}
+
+// ConsumeRateCredit 扣减客户端的一次突发额度
+// 参数:
+//
+//	ctx: 上下文
+//	client: 白名单客户端名称
+//
+// 返回:
+//
+//	int64: 扣减后的剩余额度
+//	bool: 是否扣减成功（没有额度或已过期时为 false）
+//	error: 错误信息
+func ConsumeRateCredit(ctx context.Context, client string) (int64, bool, error) {
+	if RedisClient == nil {
+		return 0, false, errors.New("Redis 未初始化")
+	}
+
+	n, err := consumeCreditScript.Run(ctx, RedisClient, []string{rateCreditPrefix + client}).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if n < 0 {
+		return 0, false, nil
+	}
+	return n, true, nil
+}
+
+// ListRateCredits 列出全部未过期的突发额度
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	[]RateCredit: 突发额度列表
+//	error: 错误信息
+func ListRateCredits(ctx context.Context) ([]RateCredit, error) {
+	if RedisClient == nil {
+		return nil, errors.New("Redis 未初始化")
+	}
+
+	var keys []string
+	iter := RedisClient.Scan(ctx, 0, rateCreditPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	pipe := RedisClient.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	credits := make([]RateCredit, 0, len(keys))
+	now := time.Now()
+	for i, key := range keys {
+		remaining, err := gets[i].Int64()
+		if err != nil {
+			continue // 扫描后已过期
+		}
+		credits = append(credits, RateCredit{
+			Client:    strings.TrimPrefix(key, rateCreditPrefix),
+			Remaining: remaining,
+			ExpiresAt: now.Add(ttls[i].Val()),
+		})
+	}
+	return credits, nil
+}
+
+// RevokeRateCredits 收回客户端的全部突发额度
+// 参数:
+//
+//	ctx: 上下文
+//	client: 白名单客户端名称
+//
+// 返回:
+//
+//	error: 错误信息
+func RevokeRateCredits(ctx context.Context, client string) error {
+	if RedisClient == nil {
+		return errors.New("Redis 未初始化")
+	}
+	return RedisClient.Del(ctx, rateCreditPrefix+client).Err()
+}
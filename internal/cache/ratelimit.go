@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript GCRA（通用信元速率算法）限流脚本
+// 每个键只保存一个“理论到达时间”（TAT，微秒），时间取 Redis 服务器时间，各网关实例共享同一时钟；
+// 返回 {是否允许, 需等待的微秒数}
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call('GET', KEYS[1]))
+if tat == nil or tat < now then
+  tat = now
+end
+
+local newTat = tat + emission
+local allowAt = newTat - emission * burst
+if now < allowAt then
+  return {0, allowAt - now}
+end
+
+redis.call('SET', KEYS[1], newTat, 'PX', math.ceil((newTat - now) / 1000))
+return {1, 0}
+`)
+
+// AllowRate 按 GCRA 算法判断请求是否允许通过（所有实例共享限额）
+// 参数:
+//
+//	ctx: 上下文
+//	key: 限流键
+//	perSecond: 每秒允许的请求数
+//	burst: 突发容量
+//
+// 返回:
+//
+//	bool: 是否允许
+//	time.Duration: 不允许时需要等待的时间
+//	error: 错误信息
+func AllowRate(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	if RedisClient == nil {
+		return false, 0, errors.New("Redis 未初始化")
+	}
+	if perSecond <= 0 || burst <= 0 {
+		return false, 0, errors.New("限流速率和容量必须大于 0")
+	}
+
+	emission := int64(float64(time.Second/time.Microsecond) / perSecond)
+	res, err := gcraScript.Run(ctx, RedisClient, []string{key}, emission, burst).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
+
+// leakyBucketScript 漏桶排队脚本
+// 每个键保存桶中最后一个请求的流出时间（微秒，Redis 服务器时间）；请求按固定间隔流出，
+// 排队等待时间超过 capacity 个间隔时拒绝。返回 {是否允许, 需等待的微秒数}
+var leakyBucketScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local last = tonumber(redis.call('GET', KEYS[1]))
+local at = now
+if last ~= nil and last + interval > now then
+  at = last + interval
+end
+local wait = at - now
+if wait > interval * capacity then
+  return {0, wait - interval * capacity}
+end
+
+redis.call('SET', KEYS[1], at, 'PX', math.ceil((wait + interval) / 1000))
+return {1, wait}
+`)
+
+// LeakyBucket 漏桶整形（所有实例共享）：与 AllowRate 在突发容量内立即放行不同，
+// 通过的请求按 perSecond 的固定速率依次排队，调用方应等待返回的时间后再执行，用于平滑消费者对下游的调用
+// 参数:
+//
+//	ctx: 上下文
+//	key: 漏桶键
+//	perSecond: 每秒流出的请求数
+//	capacity: 最多排队的请求数，排满时拒绝
+//
+// 返回:
+//
+//	bool: 是否进入漏桶
+//	time.Duration: 允许时为执行前需等待的时间；拒绝时为至少需等待多久再重试
+//	error: 错误信息
+func LeakyBucket(ctx context.Context, key string, perSecond float64, capacity int) (bool, time.Duration, error) {
+	if RedisClient == nil {
+		return false, 0, errors.New("Redis 未初始化")
+	}
+	if perSecond <= 0 || capacity <= 0 {
+		return false, 0, errors.New("漏桶速率和容量必须大于 0")
+	}
+
+	interval := int64(float64(time.Second/time.Microsecond) / perSecond)
+	res, err := leakyBucketScript.Run(ctx, RedisClient, []string{key}, interval, capacity).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Microsecond, nil
+}
@@ -2,12 +2,18 @@ package cache
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
 	"go.uber.org/zap"
 )
 
@@ -32,11 +38,28 @@ func Init(cfg config.RedisConfig) error {
 		MinIdleConns: cfg.MinIdleConns,
 	})
 
-	// 测试连接
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// 统计读缓存命中率和连接池状态
+	RedisClient.AddHook(metrics.RedisHook{})
+	metrics.RegisterRedisPool(RedisClient)
 
-	if err := RedisClient.Ping(ctx).Err(); err != nil {
+	// 根据命令结果进入或退出降级状态
+	SetDegradationPolicies(cfg.Degradation)
+	RedisClient.AddHook(degradeHook{})
+
+	// Redis 持续不可用时熔断
+	if breaker := resilience.For("redis", isConnectionError); breaker != nil {
+		RedisClient.AddHook(breakerHook{breaker: breaker})
+	}
+
+	// 测试连接，Redis 尚未就绪时按 retry.startup_timeout 等待，认证失败等错误立即返回
+	policy := retry.Startup("Redis")
+	policy.Retryable = isConnectionError
+	err := retry.Do(context.Background(), policy, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		return RedisClient.Ping(ctx).Err()
+	})
+	if err != nil {
 		return fmt.Errorf("Redis 连接失败: %w", err)
 	}
 
@@ -157,7 +180,27 @@ func Decr(ctx context.Context, key string) (int64, error) {
 	return RedisClient.Decr(ctx, key).Result()
 }
 
+// ErrLockNotHeld 锁已过期或被其他持有者获取
+var ErrLockNotHeld = errors.New("锁未被当前持有者持有")
+
+// unlockScript 仅当锁的值与持有者令牌一致时删除
+var unlockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// extendScript 仅当锁的值与持有者令牌一致时重设过期时间
+var extendScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
 // Lock 获取分布式锁
+// 锁的值为随机令牌，释放和续期时校验令牌，避免误释放其他实例持有的锁
 // 参数:
 //
 //	ctx: 上下文
@@ -166,11 +209,22 @@ func Decr(ctx context.Context, key string) (int64, error) {
 //
 // 返回:
 //
+//	string: 持有者令牌，释放或续期时传入
 //	bool: 是否成功获取锁
 //	error: 错误信息
-func Lock(ctx context.Context, key string, expiration time.Duration) (bool, error) {
-	// 使用 SET NX EX 命令实现分布式锁
-	return RedisClient.SetNX(ctx, key, "locked", expiration).Result()
+func Lock(ctx context.Context, key string, expiration time.Duration) (string, bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, fmt.Errorf("生成锁令牌失败: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	// 使用 SET NX 实现分布式锁，整秒的过期时间以 EX 设置，否则以 PX 设置
+	ok, err := RedisClient.SetNX(ctx, key, token, expiration).Result()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return token, true, nil
 }
 
 // Unlock 释放分布式锁
@@ -178,12 +232,42 @@ func Lock(ctx context.Context, key string, expiration time.Duration) (bool, erro
 //
 //	ctx: 上下文
 //	key: 锁的键名
+//	token: Lock 返回的持有者令牌
 //
 // 返回:
 //
-//	error: 错误信息
-func Unlock(ctx context.Context, key string) error {
-	return RedisClient.Del(ctx, key).Err()
+//	error: 锁已过期或被其他持有者获取时返回 ErrLockNotHeld
+func Unlock(ctx context.Context, key, token string) error {
+	n, err := unlockScript.Run(ctx, RedisClient, []string{key}, token).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// ExtendLock 延长分布式锁的过期时间，用于执行时间较长的任务
+// 参数:
+//
+//	ctx: 上下文
+//	key: 锁的键名
+//	token: Lock 返回的持有者令牌
+//	expiration: 新的过期时间（从当前时间起算）
+//
+// 返回:
+//
+//	error: 锁已过期或被其他持有者获取时返回 ErrLockNotHeld
+func ExtendLock(ctx context.Context, key, token string, expiration time.Duration) error {
+	n, err := extendScript.Run(ctx, RedisClient, []string{key}, token, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
 }
 
 // HGet 获取哈希字段值
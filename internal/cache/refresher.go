@@ -0,0 +1,518 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// 刷新器使用的 Redis 键
+const (
+	// expiriesKey 被追踪键的过期时间（有序集合，member 为 class|key，score 为 Unix 秒）
+	expiriesKey = "cache:refresh:expiries"
+	// accessKey 被追踪键的最近访问时间（有序集合，score 为 Unix 秒）
+	accessKey = "cache:refresh:access"
+)
+
+// refreshScanBatch 预刷新每次查询即将过期键的数量
+const refreshScanBatch = 500
+
+// invalidateChannel 缓存失效事件的频道，通知其他实例删除进程内缓存
+const invalidateChannel = "cache.invalidate"
+
+// invalidateEvent 缓存失效事件的负载
+type invalidateEvent struct {
+	Class string `json:"class"`
+	Key   string `json:"key"`
+}
+
+// Loader 从数据源加载缓存值，返回 nil 表示数据不存在（停止追踪该键）
+type Loader func(ctx context.Context, key string) (interface{}, error)
+
+// cacheClass 一类缓存键的刷新策略
+type cacheClass struct {
+	loader       Loader
+	ttl          time.Duration
+	refreshAhead time.Duration
+	hotWindow    time.Duration
+}
+
+// Refresher 热点缓存预刷新器
+// 通过 Fetch 写入的键会记录过期时间，Run 定期扫描即将过期且近期被访问过的键并提前重新加载；
+// 多实例之间通过分布式锁保证同一个键只由一个实例刷新，同一实例内同一个键的并发未命中只回源一次；
+// 启用 redis.refresh.local 时在 Redis 之前增加一层进程内 LRU 缓存
+type Refresher struct {
+	mu       sync.RWMutex
+	classes  map[string]*cacheClass
+	cfg      config.CacheRefreshConfig
+	interval time.Duration
+	now      func() time.Time
+	loads    singleflight.Group
+	// local 进程内缓存，未启用时为 nil
+	local       *LocalCache
+	unsubscribe func()
+}
+
+// loadResult 合并加载的结果，写缓存失败时 data 和 err 同时非空
+type loadResult struct {
+	data []byte
+	err  error
+}
+
+// DefaultRefresher 全局缓存刷新器
+var DefaultRefresher = NewRefresher(config.CacheRefreshConfig{})
+
+// NewRefresher 创建缓存刷新器
+// 参数:
+//
+//	cfg: 刷新配置
+//
+// 返回:
+//
+//	*Refresher: 刷新器
+func NewRefresher(cfg config.CacheRefreshConfig) *Refresher {
+	interval := cfg.GetInterval()
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	r := &Refresher{
+		classes:  make(map[string]*cacheClass),
+		cfg:      cfg,
+		interval: interval,
+		now:      time.Now,
+	}
+	if cfg.Local.Enable {
+		r.local = NewLocalCache(cfg.Local.GetSize(), cfg.Local.GetTTL())
+		r.unsubscribe = DefaultEventBus.Subscribe(invalidateChannel, r.onInvalidate)
+	}
+	return r
+}
+
+// InitRefresher 按配置初始化全局缓存刷新器，并停止之前的刷新器接收失效事件
+// 参数:
+//
+//	cfg: 刷新配置
+func InitRefresher(cfg config.CacheRefreshConfig) {
+	DefaultRefresher.Close()
+	DefaultRefresher = NewRefresher(cfg)
+}
+
+// Close 停止接收缓存失效事件，不再使用的刷新器应调用
+func (r *Refresher) Close() {
+	if r.unsubscribe != nil {
+		r.unsubscribe()
+		r.unsubscribe = nil
+	}
+}
+
+// onInvalidate 处理其他实例（或本实例）发布的缓存失效事件，删除进程内缓存
+func (r *Refresher) onInvalidate(ctx context.Context, e *Event) {
+	var payload invalidateEvent
+	if err := e.Decode(&payload); err != nil {
+		logger.Warn("忽略无法解析的缓存失效事件", zap.Error(err))
+		return
+	}
+	r.local.Delete(payload.Class + "|" + payload.Key)
+}
+
+// Register 注册一类缓存键及其加载函数，刷新策略取自配置（未配置时 TTL 5 分钟、提前 30 秒刷新）
+// 参数:
+//
+//	class: 类别名称
+//	loader: 加载函数
+func (r *Refresher) Register(class string, loader Loader) {
+	cc := r.cfg.Classes[class]
+	c := &cacheClass{
+		loader:       loader,
+		ttl:          cc.GetTTL(),
+		refreshAhead: cc.GetRefreshAhead(),
+		hotWindow:    cc.GetHotWindow(),
+	}
+	if c.ttl <= 0 {
+		c.ttl = 5 * time.Minute
+	}
+	if c.refreshAhead <= 0 || c.refreshAhead >= c.ttl {
+		c.refreshAhead = c.ttl / 10
+	}
+
+	r.mu.Lock()
+	r.classes[class] = c
+	r.mu.Unlock()
+}
+
+// class 获取已注册的类别
+func (r *Refresher) class(name string) (*cacheClass, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.classes[name]
+	if !ok {
+		return nil, fmt.Errorf("未注册的缓存类别: %s", name)
+	}
+	return c, nil
+}
+
+// dataKey 返回缓存值的 Redis 键
+func dataKey(class, key string) string {
+	return "cache:" + class + ":" + key
+}
+
+// Fetch 读取缓存，未命中时加载并写入缓存，结果以 JSON 解码到 dest
+// 启用进程内缓存时先读本地，未命中再读 Redis，读到的值写入本地；
+// Redis 降级期间按 redis.degradation.cache 策略直接调用加载函数（bypass，同时跳过进程内缓存）或返回 ErrDegraded（fail）
+// 参数:
+//
+//	ctx: 上下文
+//	class: 类别名称
+//	key: 键
+//	dest: 解码目标（指针）
+//
+// 返回:
+//
+//	bool: 数据是否存在
+//	error: 错误信息
+func (r *Refresher) Fetch(ctx context.Context, class, key string, dest interface{}) (bool, error) {
+	c, err := r.class(class)
+	if err != nil {
+		return false, err
+	}
+
+	useRedis, err := CacheReadable()
+	if err != nil {
+		return false, err
+	}
+	member := class + "|" + key
+	if !useRedis {
+		metrics.CacheClassRequests.WithLabelValues(class, "bypass").Inc()
+		// 降级期间同样合并同一个键的并发加载，避免缓存不可用时回源压力成倍放大
+		v, _, _ := r.loads.Do(member, func() (interface{}, error) {
+			value, err := c.loader(ctx, key)
+			if err != nil || value == nil {
+				return loadResult{err: err}, nil
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return loadResult{err: err}, nil
+			}
+			return loadResult{data: data}, nil
+		})
+		res := v.(loadResult)
+		if res.data == nil {
+			return false, res.err
+		}
+		return true, json.Unmarshal(res.data, dest)
+	}
+
+	if r.local != nil {
+		// 本地命中不记录访问时间，热点键仍会在本地过期后访问 Redis 时被记录
+		if data, ok := r.local.Get(member); ok {
+			metrics.CacheClassRequests.WithLabelValues(class, "local_hit").Inc()
+			return true, json.Unmarshal(data, dest)
+		}
+	}
+
+	data, err := RedisClient.Get(ctx, dataKey(class, key)).Bytes()
+	if err == nil {
+		metrics.CacheClassRequests.WithLabelValues(class, "hit").Inc()
+		r.touch(ctx, member)
+		r.setLocal(member, data)
+		return true, json.Unmarshal(data, dest)
+	}
+	metrics.CacheClassRequests.WithLabelValues(class, "miss").Inc()
+	if !errors.Is(err, redis.Nil) {
+		logger.Warn("读取缓存失败，回源加载", zap.String("class", class), zap.String("key", key), zap.Error(err))
+	}
+
+	// 热点键过期时的并发请求合并为一次加载，其余请求等待并共享结果
+	v, _, shared := r.loads.Do(member, func() (interface{}, error) {
+		data, err := r.load(ctx, c, class, key)
+		return loadResult{data: data, err: err}, nil
+	})
+	if shared {
+		metrics.CacheClassRequests.WithLabelValues(class, "shared").Inc()
+	}
+	res := v.(loadResult)
+	data, err = res.data, res.err
+	if data == nil {
+		// 数据不存在或加载失败时不记录访问时间，避免查询不存在的键使访问集合无限增长
+		return false, err
+	}
+	if err != nil {
+		if degrade.current().GetCache() == PolicyFail {
+			return false, err
+		}
+		logger.Warn("写入缓存失败", zap.String("class", class), zap.String("key", key), zap.Error(err))
+	} else {
+		r.touch(ctx, member)
+		r.setLocal(member, data)
+	}
+	return true, json.Unmarshal(data, dest)
+}
+
+// touch 记录键的最近访问时间，供预刷新判断热点
+func (r *Refresher) touch(ctx context.Context, member string) {
+	RedisClient.ZAdd(ctx, accessKey, redis.Z{Score: float64(r.now().Unix()), Member: member})
+}
+
+// setLocal 写入进程内缓存（未启用时不做处理）
+func (r *Refresher) setLocal(member string, data []byte) {
+	if r.local != nil {
+		r.local.Set(member, data)
+	}
+}
+
+// Invalidate 删除缓存并停止追踪，数据变更后调用；未配置 Redis 时不做处理
+// 启用进程内缓存时同时删除本地副本，并发布失效事件通知其他实例删除各自的副本
+// 参数:
+//
+//	ctx: 上下文
+//	class: 类别名称
+//	key: 键
+//
+// 返回:
+//
+//	error: 错误信息
+func (r *Refresher) Invalidate(ctx context.Context, class, key string) error {
+	if RedisClient == nil {
+		return nil
+	}
+	member := class + "|" + key
+	pipe := RedisClient.TxPipeline()
+	pipe.Del(ctx, dataKey(class, key))
+	pipe.ZRem(ctx, expiriesKey, member)
+	pipe.ZRem(ctx, accessKey, member)
+	_, err := pipe.Exec(ctx)
+
+	if r.local != nil {
+		r.local.Delete(member)
+		if perr := Publish(ctx, invalidateChannel, invalidateEvent{Class: class, Key: key}); perr != nil {
+			logger.Warn("发布缓存失效事件失败", zap.String("class", class), zap.String("key", key), zap.Error(perr))
+		}
+	}
+	return err
+}
+
+// load 调用加载函数并写入缓存，写缓存失败时同时返回已加载的数据和错误
+func (r *Refresher) load(ctx context.Context, c *cacheClass, class, key string) ([]byte, error) {
+	value, err := c.loader(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	member := class + "|" + key
+	if value == nil {
+		RedisClient.ZRem(ctx, expiriesKey, member)
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := RedisClient.TxPipeline()
+	r.set(ctx, pipe, c, class, key, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// 数据已加载，由调用方决定写缓存失败时是否仍使用
+		return data, err
+	}
+	return data, nil
+}
+
+// set 在管道中写入缓存值并记录过期时间，TTL 加入随机抖动以错开同时写入的键的过期时间
+func (r *Refresher) set(ctx context.Context, pipe redis.Pipeliner, c *cacheClass, class, key string, data []byte) {
+	ttl := c.ttl
+	if jitter := int64(ttl / 10); jitter > 0 {
+		ttl += time.Duration(rand.Int63n(jitter))
+	}
+	expireAt := r.now().Add(ttl)
+
+	pipe.Set(ctx, dataKey(class, key), data, ttl)
+	pipe.ZAdd(ctx, expiriesKey, redis.Z{Score: float64(expireAt.Unix()), Member: class + "|" + key})
+}
+
+// Warm 批量写入缓存（缓存重建、预热时使用），以管道分批写入，不记录访问时间，未被访问的键不会被预刷新
+// 参数:
+//
+//	ctx: 上下文
+//	class: 类别名称
+//	values: 键到缓存值的映射，值以 JSON 保存
+//
+// 返回:
+//
+//	error: 错误信息
+func (r *Refresher) Warm(ctx context.Context, class string, values map[string]interface{}) error {
+	c, err := r.class(class)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	encoded := make([][]byte, 0, len(values))
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		encoded = append(encoded, data)
+	}
+	return PipelineBatched(ctx, len(keys), func(pipe redis.Pipeliner, i int) {
+		r.set(ctx, pipe, c, class, keys[i], encoded[i])
+	})
+}
+
+// Run 启动刷新循环，直到 ctx 取消
+// 参数:
+//
+//	ctx: 上下文
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.refreshDue(ctx)
+		}
+	}
+}
+
+// refreshDue 刷新即将过期的键
+func (r *Refresher) refreshDue(ctx context.Context) {
+	// 降级期间不预刷新，恢复由请求路径上的检测发现
+	if Degraded() {
+		return
+	}
+
+	r.mu.RLock()
+	var maxAhead time.Duration
+	for _, c := range r.classes {
+		if c.refreshAhead > maxAhead {
+			maxAhead = c.refreshAhead
+		}
+	}
+	r.mu.RUnlock()
+
+	// 按分数游标分页扫描：下一页从上一页最后的分数开始（含），并跳过该分数上仍留在集合中的成员，
+	// 已刷新或停止追踪的键移出了当前分数，不占用偏移量
+	now := r.now()
+	maxScore := strconv.FormatInt(now.Add(maxAhead).Unix(), 10)
+	minScore, offset := "-inf", int64(0)
+	refreshed := 0
+	for {
+		due, err := RedisClient.ZRangeByScoreWithScores(ctx, expiriesKey, &redis.ZRangeBy{
+			Min:    minScore,
+			Max:    maxScore,
+			Offset: offset,
+			Count:  refreshScanBatch,
+		}).Result()
+		if err != nil {
+			logger.Error("查询即将过期的缓存键失败", zap.Error(err))
+			break
+		}
+
+		last := 0.0
+		if len(due) > 0 {
+			last = due[len(due)-1].Score
+		}
+		kept := int64(0)
+		for _, z := range due {
+			done, remains := r.refreshMember(ctx, z, now)
+			if done {
+				refreshed++
+			}
+			if remains && z.Score == last {
+				kept++
+			}
+		}
+		if len(due) < refreshScanBatch || ctx.Err() != nil {
+			break
+		}
+
+		next := strconv.FormatFloat(last, 'f', -1, 64)
+		if next == minScore {
+			offset += kept
+		} else {
+			minScore, offset = next, kept
+		}
+	}
+
+	if refreshed > 0 {
+		logger.Debug("已预刷新缓存", zap.Int("count", refreshed))
+	}
+}
+
+// refreshMember 处理扫描到的一个键，返回是否已刷新，以及该键是否仍以原分数留在过期集合中
+func (r *Refresher) refreshMember(ctx context.Context, z redis.Z, now time.Time) (refreshed, remains bool) {
+	member := z.Member
+	class, key, ok := strings.Cut(member, "|")
+	if !ok {
+		RedisClient.ZRem(ctx, expiriesKey, member)
+		return false, false
+	}
+	c, err := r.class(class)
+	if err != nil {
+		// 其他实例注册的类别，由其负责刷新
+		return false, true
+	}
+
+	expireAt := time.Unix(int64(z.Score), 0)
+	if expireAt.Sub(now) > c.refreshAhead {
+		return false, true
+	}
+	if !r.isHot(ctx, c, member, now) {
+		RedisClient.ZRem(ctx, expiriesKey, member)
+		RedisClient.ZRem(ctx, accessKey, member)
+		return false, false
+	}
+
+	// 刷新成功后过期时间后移（数据不存在时移出集合），失败时保留原分数
+	if r.refreshOne(ctx, c, class, key) {
+		return true, false
+	}
+	return false, true
+}
+
+// isHot 判断键在热点窗口内是否被访问过
+func (r *Refresher) isHot(ctx context.Context, c *cacheClass, member string, now time.Time) bool {
+	if c.hotWindow <= 0 {
+		return true
+	}
+	score, err := RedisClient.ZScore(ctx, accessKey, member).Result()
+	if err != nil {
+		return false
+	}
+	return now.Sub(time.Unix(int64(score), 0)) <= c.hotWindow
+}
+
+// refreshOne 在分布式锁保护下刷新单个键
+func (r *Refresher) refreshOne(ctx context.Context, c *cacheClass, class, key string) bool {
+	lockKey := "cache:refresh:lock:" + class + ":" + key
+	token, locked, err := Lock(ctx, lockKey, c.refreshAhead)
+	if err != nil || !locked {
+		return false
+	}
+	defer Unlock(ctx, lockKey, token)
+
+	if _, err := r.load(ctx, c, class, key); err != nil {
+		logger.Warn("预刷新缓存失败", zap.String("class", class), zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return true
+}
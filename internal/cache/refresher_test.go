@@ -0,0 +1,102 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestRefresherFetchAccess(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	r := cache.NewRefresher(config.CacheRefreshConfig{})
+	r.Register("access", func(ctx context.Context, key string) (interface{}, error) {
+		loads.Add(1)
+		if key == "missing" {
+			return nil, nil
+		}
+		<-release
+		return "v" + key, nil
+	})
+
+	// 不存在的数据不记录访问时间
+	var v string
+	if found, err := r.Fetch(ctx, "access", "missing", &v); err != nil || found {
+		t.Fatalf("Fetch 不存在的键 = %v, %v", found, err)
+	}
+	if members, _ := mr.ZMembers("cache:refresh:access"); len(members) != 0 {
+		t.Errorf("不存在的键记录了访问时间: %v", members)
+	}
+
+	// 冷键的并发未命中只回源一次
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var v string
+			if found, err := r.Fetch(ctx, "access", "1", &v); err != nil || !found || v != "v1" {
+				t.Errorf("Fetch = %v, %q, %v", found, v, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := loads.Load(); got != 2 {
+		t.Errorf("回源 %d 次, 期望 2", got)
+	}
+	if members, _ := mr.ZMembers("cache:refresh:access"); len(members) != 1 || members[0] != "access|1" {
+		t.Errorf("访问集合 = %v", members)
+	}
+}
+
+func TestRefresherRefreshDuePaged(t *testing.T) {
+	testutil.InitLogger()
+	mr := testutil.UseMiniredis(t)
+
+	var loads atomic.Int32
+	r := cache.NewRefresher(config.CacheRefreshConfig{Interval: 1})
+	r.Register("page", func(ctx context.Context, key string) (interface{}, error) {
+		loads.Add(1)
+		return key, nil
+	})
+
+	// 排在前面的其他实例类别的键超过一页且分数相同，不应阻塞后续键的刷新
+	past := float64(time.Now().Add(-time.Minute).Unix())
+	for i := 0; i < 600; i++ {
+		mr.ZAdd("cache:refresh:expiries", past, "other|"+strconv.Itoa(i))
+	}
+	for i := 0; i < 700; i++ {
+		mr.ZAdd("cache:refresh:expiries", past+1, "page|"+strconv.Itoa(i))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for loads.Load() < 700 {
+		if time.Now().After(deadline) {
+			t.Fatalf("预刷新 %d 个键, 期望 700", loads.Load())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !mr.Exists("cache:page:699") {
+		t.Error("最后一页的键未写入缓存")
+	}
+	if members, _ := mr.ZMembers("cache:refresh:expiries"); len(members) != 1300 {
+		t.Errorf("过期集合有 %d 个成员, 期望 1300", len(members))
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// semaphoreScript 分布式信号量获取脚本
+// 有序集合保存持有者令牌，分值为获取时间（毫秒，Redis 服务器时间）；先回收超过 TTL 的槽位，
+// 未满时加入令牌。返回 1 表示获取成功
+var semaphoreScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - ttl)
+if redis.call('ZCARD', KEYS[1]) >= limit then
+  return 0
+end
+redis.call('ZADD', KEYS[1], now, ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ttl)
+return 1
+`)
+
+// extendSemaphoreScript 槽位仍被令牌持有时把获取时间更新为当前时间，并延长键的过期时间
+var extendSemaphoreScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+  return 0
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZADD', KEYS[1], 'XX', now, ARGV[1])
+if redis.call('PTTL', KEYS[1]) < tonumber(ARGV[2]) then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// AcquireSemaphore 获取分布式信号量的一个槽位（所有实例共享）
+// 参数:
+//
+//	ctx: 上下文
+//	key: 信号量键
+//	limit: 槽位数
+//	ttl: 槽位最长占用时间，持有者异常退出时到期回收
+//
+// 返回:
+//
+//	string: 槽位令牌，释放时使用
+//	bool: 是否获取成功（槽位已满时为 false）
+//	error: 错误信息
+func AcquireSemaphore(ctx context.Context, key string, limit int, ttl time.Duration) (string, bool, error) {
+	if RedisClient == nil {
+		return "", false, errors.New("Redis 未初始化")
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	token := hex.EncodeToString(b)
+
+	ok, err := semaphoreScript.Run(ctx, RedisClient, []string{key}, limit, ttl.Milliseconds(), token).Int()
+	if err != nil {
+		return "", false, err
+	}
+	return token, ok == 1, nil
+}
+
+// ReleaseSemaphore 释放信号量槽位
+// 参数:
+//
+//	ctx: 上下文
+//	key: 信号量键
+//	token: AcquireSemaphore 返回的令牌
+//
+// 返回:
+//
+//	error: 错误信息
+func ReleaseSemaphore(ctx context.Context, key, token string) error {
+	return RedisClient.ZRem(ctx, key, token).Err()
+}
+
+// ExtendSemaphore 延长槽位的占用时间，用于执行时间超过 ttl 的持有者
+// 参数:
+//
+//	ctx: 上下文
+//	key: 信号量键
+//	token: AcquireSemaphore 返回的令牌
+//	ttl: 新的占用时间（从当前时间起算）
+//
+// 返回:
+//
+//	error: 槽位已过期被回收时返回 ErrLockNotHeld
+func ExtendSemaphore(ctx context.Context, key, token string, ttl time.Duration) error {
+	n, err := extendSemaphoreScript.Run(ctx, RedisClient, []string{key}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// WaitSemaphore 等待并获取信号量槽位，槽位已满时每隔 poll 重试一次
+// 参数:
+//
+//	ctx: 上下文，取消时停止等待
+//	key: 信号量键
+//	limit: 槽位数
+//	ttl: 槽位最长占用时间
+//	poll: 重试间隔
+//
+// 返回:
+//
+//	string: 槽位令牌
+//	error: ctx 取消时返回 ctx.Err()，以及 Redis 错误
+func WaitSemaphore(ctx context.Context, key string, limit int, ttl, poll time.Duration) (string, error) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		token, ok, err := AcquireSemaphore(ctx, key, limit, ttl)
+		if err != nil || ok {
+			return token, err
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanBatchSize SCAN 每次迭代建议返回的键数量
+const scanBatchSize = 100
+
+// formatScore 把分数格式化为 ZRANGEBYSCORE 的边界，正负无穷表示不限
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// toArgs 把字符串转为 go-redis 的可变参数
+func toArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+// ZAdd 向有序集合添加成员，已存在的成员更新分数
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	members: 成员及分数
+//
+// 返回:
+//
+//	int64: 新增的成员数（不含更新分数的成员）
+//	error: 错误信息
+func ZAdd(ctx context.Context, key string, members ...redis.Z) (int64, error) {
+	return RedisClient.ZAdd(ctx, key, members...).Result()
+}
+
+// ZAddMany 在一个管道中向多个有序集合添加成员，用于批量写入
+// 参数:
+//
+//	ctx: 上下文
+//	entries: 键名到成员列表的映射
+//
+// 返回:
+//
+//	error: 错误信息
+func ZAddMany(ctx context.Context, entries map[string][]redis.Z) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	_, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, members := range entries {
+			pipe.ZAdd(ctx, key, members...)
+		}
+		return nil
+	})
+	return err
+}
+
+// ZIncrBy 增加有序集合成员的分数，成员不存在时以 0 为初始分数
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	member: 成员
+//	increment: 增量
+//
+// 返回:
+//
+//	float64: 增加后的分数
+//	error: 错误信息
+func ZIncrBy(ctx context.Context, key, member string, increment float64) (float64, error) {
+	return RedisClient.ZIncrBy(ctx, key, increment, member).Result()
+}
+
+// ZRangeByScore 按分数从低到高返回 [min, max] 区间内的成员及分数
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	min: 最小分数（math.Inf(-1) 表示不限）
+//	max: 最大分数（math.Inf(1) 表示不限）
+//	limit: 最多返回的成员数，0 表示不限
+//
+// 返回:
+//
+//	[]redis.Z: 成员及分数
+//	error: 错误信息
+func ZRangeByScore(ctx context.Context, key string, min, max float64, limit int64) ([]redis.Z, error) {
+	return RedisClient.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   formatScore(min),
+		Max:   formatScore(max),
+		Count: limit,
+	}).Result()
+}
+
+// ZTop 按分数从高到低返回前 n 个成员及分数（排行榜）
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	n: 返回的成员数
+//
+// 返回:
+//
+//	[]redis.Z: 成员及分数
+//	error: 错误信息
+func ZTop(ctx context.Context, key string, n int64) ([]redis.Z, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	return RedisClient.ZRevRangeWithScores(ctx, key, 0, n-1).Result()
+}
+
+// ZRem 从有序集合删除成员
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	members: 成员
+//
+// 返回:
+//
+//	int64: 实际删除的成员数
+//	error: 错误信息
+func ZRem(ctx context.Context, key string, members ...string) (int64, error) {
+	return RedisClient.ZRem(ctx, key, toArgs(members)...).Result()
+}
+
+// LPush 从列表头部插入元素
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	values: 元素，按顺序插入（最后一个位于头部）
+//
+// 返回:
+//
+//	int64: 插入后的列表长度
+//	error: 错误信息
+func LPush(ctx context.Context, key string, values ...string) (int64, error) {
+	return RedisClient.LPush(ctx, key, toArgs(values)...).Result()
+}
+
+// BRPop 从列表尾部阻塞弹出元素，与 LPush 配合实现先进先出队列
+// 参数:
+//
+//	ctx: 上下文，取消时停止等待
+//	timeout: 最长等待时间，0 表示一直等待
+//	keys: 键名，按顺序检查第一个非空的列表
+//
+// 返回:
+//
+//	string: 弹出元素所在的键
+//	string: 元素
+//	bool: 是否弹出了元素（超时返回 false）
+//	error: 错误信息
+func BRPop(ctx context.Context, timeout time.Duration, keys ...string) (string, string, bool, error) {
+	result, err := RedisClient.BRPop(ctx, timeout, keys...).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, err
+	}
+	return result[0], result[1], true, nil
+}
+
+// LLen 获取列表长度
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//
+// 返回:
+//
+//	int64: 列表长度，键不存在时为 0
+//	error: 错误信息
+func LLen(ctx context.Context, key string) (int64, error) {
+	return RedisClient.LLen(ctx, key).Result()
+}
+
+// SAdd 向集合添加成员
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	members: 成员
+//
+// 返回:
+//
+//	int64: 新增的成员数
+//	error: 错误信息
+func SAdd(ctx context.Context, key string, members ...string) (int64, error) {
+	return RedisClient.SAdd(ctx, key, toArgs(members)...).Result()
+}
+
+// SAddMany 在一个管道中向多个集合添加成员，用于批量写入
+// 参数:
+//
+//	ctx: 上下文
+//	entries: 键名到成员列表的映射
+//
+// 返回:
+//
+//	error: 错误信息
+func SAddMany(ctx context.Context, entries map[string][]string) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	_, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, members := range entries {
+			pipe.SAdd(ctx, key, toArgs(members)...)
+		}
+		return nil
+	})
+	return err
+}
+
+// SRem 从集合删除成员
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	members: 成员
+//
+// 返回:
+//
+//	int64: 实际删除的成员数
+//	error: 错误信息
+func SRem(ctx context.Context, key string, members ...string) (int64, error) {
+	return RedisClient.SRem(ctx, key, toArgs(members)...).Result()
+}
+
+// SMembers 获取集合的全部成员（无序）
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//
+// 返回:
+//
+//	[]string: 成员，键不存在时为空
+//	error: 错误信息
+func SMembers(ctx context.Context, key string) ([]string, error) {
+	return RedisClient.SMembers(ctx, key).Result()
+}
+
+// SIsMember 判断成员是否在集合中
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	member: 成员
+//
+// 返回:
+//
+//	bool: 是否存在
+//	error: 错误信息
+func SIsMember(ctx context.Context, key, member string) (bool, error) {
+	return RedisClient.SIsMember(ctx, key, member).Result()
+}
+
+// ScanKeys 以 SCAN 遍历匹配的键，不像 KEYS 那样阻塞 Redis
+// 遍历期间新增或删除的键可能被遗漏，同一个键也可能被返回多次，fn 应可重入
+// 参数:
+//
+//	ctx: 上下文
+//	pattern: 匹配模式（如 cache:user:*）
+//	fn: 对每个键调用，返回错误时停止遍历并返回该错误
+//
+// 返回:
+//
+//	error: 错误信息
+func ScanKeys(ctx context.Context, pattern string, fn func(key string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// ScanTTL 以 SCAN 遍历匹配的键及其剩余过期时间，每批键的 TTL 在一个管道中查询
+// 参数:
+//
+//	ctx: 上下文
+//	pattern: 匹配模式
+//	fn: 对每个键调用，ttl 为 -1 表示永不过期；遍历期间被删除的键不会回调；返回错误时停止遍历
+//
+// 返回:
+//
+//	error: 错误信息
+func ScanTTL(ctx context.Context, pattern string, fn func(key string, ttl time.Duration) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := RedisClient.Scan(ctx, cursor, pattern, scanBatchSize).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			cmds := make([]*redis.DurationCmd, len(keys))
+			if _, err := RedisClient.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i, key := range keys {
+					cmds[i] = pipe.PTTL(ctx, key)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+
+			for i, key := range keys {
+				ttl := cmds[i].Val()
+				if ttl == -2 {
+					// 已被删除
+					continue
+				}
+				if ttl < 0 {
+					ttl = -1
+				}
+				if err := fn(key, ttl); err != nil {
+					return err
+				}
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,165 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestSortedSet(t *testing.T) {
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	added, err := cache.ZAdd(ctx, "board", redis.Z{Score: 10, Member: "a"}, redis.Z{Score: 30, Member: "b"}, redis.Z{Score: 20, Member: "c"})
+	if err != nil || added != 3 {
+		t.Fatalf("ZAdd = %d, %v", added, err)
+	}
+	if score, err := cache.ZIncrBy(ctx, "board", "a", 25); err != nil || score != 35 {
+		t.Errorf("ZIncrBy = %v, %v", score, err)
+	}
+
+	top, err := cache.ZTop(ctx, "board", 2)
+	if err != nil || len(top) != 2 || top[0].Member != "a" || top[1].Member != "b" {
+		t.Errorf("ZTop = %v, %v", top, err)
+	}
+
+	due, err := cache.ZRangeByScore(ctx, "board", math.Inf(-1), 30, 0)
+	if err != nil || len(due) != 2 || due[0].Member != "c" || due[1].Score != 30 {
+		t.Errorf("ZRangeByScore = %v, %v", due, err)
+	}
+	if limited, _ := cache.ZRangeByScore(ctx, "board", 0, math.Inf(1), 1); len(limited) != 1 {
+		t.Errorf("limit 未生效: %v", limited)
+	}
+
+	if removed, err := cache.ZRem(ctx, "board", "c", "missing"); err != nil || removed != 1 {
+		t.Errorf("ZRem = %d, %v", removed, err)
+	}
+
+	err = cache.ZAddMany(ctx, map[string][]redis.Z{
+		"board:1": {{Score: 1, Member: "x"}},
+		"board:2": {{Score: 2, Member: "y"}, {Score: 3, Member: "z"}},
+	})
+	if err != nil {
+		t.Fatalf("ZAddMany 失败: %v", err)
+	}
+	if top, _ := cache.ZTop(ctx, "board:2", 10); len(top) != 2 {
+		t.Errorf("ZAddMany 后 board:2 = %v", top)
+	}
+}
+
+func TestList(t *testing.T) {
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	if n, err := cache.LPush(ctx, "jobs", "first", "second"); err != nil || n != 2 {
+		t.Fatalf("LPush = %d, %v", n, err)
+	}
+
+	// LPush + BRPop 先进先出
+	key, value, ok, err := cache.BRPop(ctx, time.Second, "empty", "jobs")
+	if err != nil || !ok || key != "jobs" || value != "first" {
+		t.Errorf("BRPop = %s, %s, %v, %v", key, value, ok, err)
+	}
+	if n, _ := cache.LLen(ctx, "jobs"); n != 1 {
+		t.Errorf("LLen = %d, 期望 1", n)
+	}
+
+	// 超时返回 false 而不是错误
+	if _, _, ok, err := cache.BRPop(ctx, 50*time.Millisecond, "empty"); ok || err != nil {
+		t.Errorf("超时 BRPop = %v, %v", ok, err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	if n, err := cache.SAdd(ctx, "tags", "go", "redis", "go"); err != nil || n != 2 {
+		t.Fatalf("SAdd = %d, %v", n, err)
+	}
+	if ok, _ := cache.SIsMember(ctx, "tags", "redis"); !ok {
+		t.Error("redis 应在集合中")
+	}
+	if n, _ := cache.SRem(ctx, "tags", "redis"); n != 1 {
+		t.Errorf("SRem = %d", n)
+	}
+	if members, err := cache.SMembers(ctx, "tags"); err != nil || len(members) != 1 || members[0] != "go" {
+		t.Errorf("SMembers = %v, %v", members, err)
+	}
+
+	if err := cache.SAddMany(ctx, map[string][]string{"s:1": {"a", "b"}, "s:2": {"c"}}); err != nil {
+		t.Fatalf("SAddMany 失败: %v", err)
+	}
+	if members, _ := cache.SMembers(ctx, "s:1"); len(members) != 2 {
+		t.Errorf("s:1 = %v", members)
+	}
+}
+
+func TestScan(t *testing.T) {
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	// 超过一批（100）以覆盖游标迭代
+	for i := 0; i < 250; i++ {
+		mr.Set(fmt.Sprintf("cache:user:%d", i), "v")
+	}
+	mr.Set("other", "v")
+	mr.SetTTL("cache:user:7", time.Minute)
+
+	var keys []string
+	if err := cache.ScanKeys(ctx, "cache:user:*", func(key string) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanKeys 失败: %v", err)
+	}
+	sort.Strings(keys)
+	keys = dedupe(keys)
+	if len(keys) != 250 {
+		t.Errorf("扫描到 %d 个键, 期望 250", len(keys))
+	}
+
+	ttls := map[string]time.Duration{}
+	if err := cache.ScanTTL(ctx, "cache:user:*", func(key string, ttl time.Duration) error {
+		ttls[key] = ttl
+		return nil
+	}); err != nil {
+		t.Fatalf("ScanTTL 失败: %v", err)
+	}
+	if ttls["cache:user:7"] != time.Minute {
+		t.Errorf("cache:user:7 TTL = %v, 期望 1m", ttls["cache:user:7"])
+	}
+	if ttls["cache:user:8"] != -1 {
+		t.Errorf("永不过期的键 TTL = %v, 期望 -1", ttls["cache:user:8"])
+	}
+
+	// 回调返回错误时停止遍历
+	stop := errors.New("stop")
+	calls := 0
+	err := cache.ScanKeys(ctx, "*", func(string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ScanKeys = %v, 回调 %d 次", err, calls)
+	}
+}
+
+// dedupe 去除已排序切片中的重复项（SCAN 可能重复返回同一个键）
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
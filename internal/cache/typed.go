@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/ugorji/go/codec"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// Codec 缓存值的序列化方式
+type Codec interface {
+	// Marshal 编码
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal 解码到 v（指针）
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// jsonCodec encoding/json 编码
+type jsonCodec struct{}
+
+// Marshal JSON 编码
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal JSON 解码
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// msgpackCodec MessagePack 编码，字段名取 codec 或 json 标签
+type msgpackCodec struct{}
+
+// msgpackHandle 写入时间等扩展类型，读取时字符串解码为 string
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.RawToString = true
+	return h
+}()
+
+// Marshal MessagePack 编码
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(v)
+	return data, err
+}
+
+// Unmarshal MessagePack 解码
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(v)
+}
+
+var (
+	// JSON 默认编码，缓存值可读，便于用 redis-cli 排查
+	JSON Codec = jsonCodec{}
+	// Msgpack MessagePack 编码，体积更小、编解码更快，适合较大或读取频繁的值
+	Msgpack Codec = msgpackCodec{}
+)
+
+// remembers 合并 Remember 对同一个键的并发加载（同一个键应始终使用相同的类型）
+var remembers singleflight.Group
+
+// GetAs 读取缓存并用 c 解码
+// 参数:
+//
+//	ctx: 上下文
+//	c: 编码方式
+//	key: 键名
+//
+// 返回:
+//
+//	T: 缓存值，不存在时为零值
+//	bool: 键是否存在
+//	error: 读取或解码失败时返回错误
+func GetAs[T any](ctx context.Context, c Codec, key string) (T, bool, error) {
+	var v T
+	data, err := RedisClient.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+	if err := c.Unmarshal(data, &v); err != nil {
+		return v, false, err
+	}
+	return v, true, nil
+}
+
+// SetAs 用 c 编码后写入缓存
+// 参数:
+//
+//	ctx: 上下文
+//	c: 编码方式
+//	key: 键名
+//	value: 值
+//	expiration: 过期时间（0 表示永不过期）
+//
+// 返回:
+//
+//	error: 编码或写入失败时返回错误
+func SetAs[T any](ctx context.Context, c Codec, key string, value T, expiration time.Duration) error {
+	data, err := c.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return RedisClient.Set(ctx, key, data, expiration).Err()
+}
+
+// GetJSON 读取 JSON 编码的缓存
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//
+// 返回:
+//
+//	T: 缓存值，不存在时为零值
+//	bool: 键是否存在
+//	error: 读取或解码失败时返回错误
+func GetJSON[T any](ctx context.Context, key string) (T, bool, error) {
+	return GetAs[T](ctx, JSON, key)
+}
+
+// SetJSON 以 JSON 编码写入缓存
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	value: 值
+//	expiration: 过期时间（0 表示永不过期）
+//
+// 返回:
+//
+//	error: 编码或写入失败时返回错误
+func SetJSON[T any](ctx context.Context, key string, value T, expiration time.Duration) error {
+	return SetAs(ctx, JSON, key, value, expiration)
+}
+
+// Remember 旁路缓存：读取 JSON 编码的缓存，不存在时调用 loader 加载并写入缓存
+// 同一实例内同一个键的并发未命中只调用一次 loader；缓存读写失败只记录日志，仍返回 loader 的结果；
+// Redis 降级期间按 redis.degradation.cache 策略直接调用 loader（bypass）或返回 ErrDegraded（fail）。
+// loader 返回的零值同样会被缓存，可用于缓存“不存在”以避免反复查询数据库
+// 参数:
+//
+//	ctx: 上下文
+//	key: 键名
+//	ttl: 缓存时间
+//	loader: 加载函数
+//
+// 返回:
+//
+//	T: 缓存值或加载结果
+//	error: loader 的错误或 ErrDegraded
+func Remember[T any](ctx context.Context, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	return RememberAs(ctx, JSON, key, ttl, loader)
+}
+
+// RememberAs 与 Remember 相同，使用 c 编码
+// 参数:
+//
+//	ctx: 上下文
+//	c: 编码方式
+//	key: 键名
+//	ttl: 缓存时间
+//	loader: 加载函数
+//
+// 返回:
+//
+//	T: 缓存值或加载结果
+//	error: loader 的错误或 ErrDegraded
+func RememberAs[T any](ctx context.Context, c Codec, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	useCache, err := CacheReadable()
+	if err != nil {
+		return zero, err
+	}
+	if !useCache {
+		return loader(ctx)
+	}
+
+	v, found, err := GetAs[T](ctx, c, key)
+	if found {
+		return v, nil
+	}
+	if err != nil {
+		logger.Warn("读取缓存失败，回源加载", zap.String("key", key), zap.Error(err))
+	}
+
+	loaded, err, _ := remembers.Do(key, func() (interface{}, error) {
+		v, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := SetAs(ctx, c, key, v, ttl); err != nil {
+			logger.Warn("写入缓存失败", zap.String("key", key), zap.Error(err))
+		}
+		return v, nil
+	})
+	if err != nil {
+		return zero, err
+	}
+	v, _ = loaded.(T)
+	return v, nil
+}
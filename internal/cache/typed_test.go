@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+type profile struct {
+	Name    string    `json:"name"`
+	Tags    []string  `json:"tags"`
+	Updated time.Time `json:"updated"`
+}
+
+func TestTypedCodecs(t *testing.T) {
+	mr := testutil.UseMiniredis(t)
+	ctx := context.Background()
+	want := profile{Name: "张三", Tags: []string{"a", "b"}, Updated: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)}
+
+	for name, c := range map[string]cache.Codec{"json": cache.JSON, "msgpack": cache.Msgpack} {
+		t.Run(name, func(t *testing.T) {
+			if _, found, err := cache.GetAs[profile](ctx, c, "missing"); found || err != nil {
+				t.Fatalf("不存在的键: found = %v, err = %v", found, err)
+			}
+			if err := cache.SetAs(ctx, c, "profile:"+name, want, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			got, found, err := cache.GetAs[profile](ctx, c, "profile:"+name)
+			if err != nil || !found || got.Name != want.Name || len(got.Tags) != 2 || !got.Updated.Equal(want.Updated) {
+				t.Fatalf("GetAs = %+v, %v, %v", got, found, err)
+			}
+			if ttl := mr.TTL("profile:" + name); ttl != time.Minute {
+				t.Errorf("TTL = %v", ttl)
+			}
+		})
+	}
+
+	// JSON 编码的值可直接读取；解码失败返回错误
+	if err := cache.SetJSON(ctx, "n", 42, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get("n"); v != "42" {
+		t.Errorf("Redis 中的值 = %q", v)
+	}
+	if _, _, err := cache.GetJSON[profile](ctx, "n"); err == nil {
+		t.Error("类型不匹配时应返回解码错误")
+	}
+}
+
+func TestRemember(t *testing.T) {
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	calls := 0
+	loader := func(ctx context.Context) ([]int, error) {
+		calls++
+		return []int{1, 2, 3}, nil
+	}
+	for i := 0; i < 3; i++ {
+		got, err := cache.Remember(ctx, "numbers", time.Minute, loader)
+		if err != nil || len(got) != 3 || got[2] != 3 {
+			t.Fatalf("Remember = %v, %v", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader 调用 %d 次, 期望 1", calls)
+	}
+
+	// 加载失败不写入缓存，下次重新加载
+	errLoad := errors.New("load failed")
+	if _, err := cache.Remember(ctx, "failing", time.Minute, func(ctx context.Context) (string, error) {
+		return "", errLoad
+	}); !errors.Is(err, errLoad) {
+		t.Fatalf("err = %v", err)
+	}
+	got, err := cache.Remember(ctx, "failing", time.Minute, func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	if err != nil || got != "ok" {
+		t.Fatalf("重新加载 = %q, %v", got, err)
+	}
+
+	// 未配置 Redis 时直接调用 loader
+	cache.RedisClient = nil
+	calls = 0
+	if _, err := cache.Remember(ctx, "numbers", time.Minute, loader); err != nil || calls != 1 {
+		t.Errorf("无 Redis: calls = %d, err = %v", calls, err)
+	}
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// WatchedLock 由看门狗自动续期的分布式锁（或信号量槽位）
+// 持有期间每 ttl/3 续期一次；锁丢失（过期后被他人获取）或释放时 Context 被取消
+type WatchedLock struct {
+	key    string
+	token  string
+	ttl    time.Duration
+	extend func(ctx context.Context, key, token string, ttl time.Duration) error
+	unlock func(ctx context.Context, key, token string) error
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// LockWithWatchdog 获取分布式锁，并在后台持续续期直到释放或 ctx 取消
+// 参数:
+//
+//	ctx: 上下文，取消时停止续期（锁随后按 ttl 自然过期）
+//	key: 锁的键名
+//	ttl: 单次续期的过期时间，持有者异常退出后最多经过 ttl 锁被自动释放
+//
+// 返回:
+//
+//	*WatchedLock: 锁，未获取到时为 nil
+//	bool: 是否成功获取锁
+//	error: 错误信息
+func LockWithWatchdog(ctx context.Context, key string, ttl time.Duration) (*WatchedLock, bool, error) {
+	token, ok, err := Lock(ctx, key, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return watch(ctx, key, token, ttl, ExtendLock, Unlock), true, nil
+}
+
+// AcquireSemaphoreWithWatchdog 获取信号量槽位，并在后台持续续期直到释放或 ctx 取消，
+// 用于执行时间不确定的任务（如限制所有副本同时运行的任务数）
+// 参数:
+//
+//	ctx: 上下文，取消时停止续期（槽位随后按 ttl 回收）
+//	key: 信号量键
+//	limit: 槽位数
+//	ttl: 单次续期的占用时间，持有者异常退出后最多经过 ttl 槽位被回收
+//
+// 返回:
+//
+//	*WatchedLock: 槽位，未获取到时为 nil
+//	bool: 是否获取成功
+//	error: 错误信息
+func AcquireSemaphoreWithWatchdog(ctx context.Context, key string, limit int, ttl time.Duration) (*WatchedLock, bool, error) {
+	token, ok, err := AcquireSemaphore(ctx, key, limit, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return watch(ctx, key, token, ttl, ExtendSemaphore, ReleaseSemaphore), true, nil
+}
+
+// watch 创建 WatchedLock 并启动续期
+func watch(ctx context.Context, key, token string, ttl time.Duration,
+	extend func(ctx context.Context, key, token string, ttl time.Duration) error,
+	unlock func(ctx context.Context, key, token string) error) *WatchedLock {
+	lockCtx, cancel := context.WithCancel(ctx)
+	l := &WatchedLock{
+		key:    key,
+		token:  token,
+		ttl:    ttl,
+		extend: extend,
+		unlock: unlock,
+		ctx:    lockCtx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go l.watch()
+	return l
+}
+
+// Context 返回锁的上下文，锁丢失或释放后被取消，长任务应据此及时停止
+func (l *WatchedLock) Context() context.Context {
+	return l.ctx
+}
+
+// watch 定期续期，锁已被他人持有时停止并取消上下文
+func (l *WatchedLock) watch() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			err := l.extend(l.ctx, l.key, l.token, l.ttl)
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrLockNotHeld):
+				logger.Error("分布式锁已丢失", zap.String("key", l.key))
+				l.cancel()
+				return
+			case l.ctx.Err() != nil:
+				return
+			default:
+				// 暂时性错误，下次继续尝试（锁在 ttl 内仍然有效）
+				logger.Warn("续期分布式锁失败", zap.String("key", l.key), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Release 停止续期并释放锁，可重复调用
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	error: 锁已丢失时返回 ErrLockNotHeld
+func (l *WatchedLock) Release(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		l.cancel()
+		<-l.done
+		err = l.unlock(ctx, l.key, l.token)
+	})
+	return err
+}
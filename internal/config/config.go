@@ -2,9 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/zhang/microservice/internal/jsonschema"
 )
 
 // Config 全局配置结构
@@ -18,6 +23,23 @@ type Config struct {
 	Cron       CronConfig       `mapstructure:"cron"`
 	Middleware MiddlewareConfig `mapstructure:"middleware"`
 	GRPC       GRPCConfig       `mapstructure:"grpc"`
+	Security   SecurityConfig   `mapstructure:"security"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Features   map[string]bool  `mapstructure:"features"`
+
+	RuntimeSettings RuntimeSettingsConfig `mapstructure:"runtime_settings"`
+	Activity        ActivityConfig        `mapstructure:"activity"`
+	Users           UsersConfig           `mapstructure:"users"`
+	Timezone        TimezoneConfig        `mapstructure:"timezone"`
+	UserSettings    UserSettingsConfig    `mapstructure:"user_settings"`
+	Quota           QuotaConfig           `mapstructure:"quota"`
+	Notify          NotifyConfig          `mapstructure:"notify"`
+	Flags           map[string]FlagConfig `mapstructure:"flags"`
+	Metrics         MetricsConfig         `mapstructure:"metrics"`
+	Audit           AuditConfig           `mapstructure:"audit"`
+	SLO             SLOConfig             `mapstructure:"slo"`
+	CircuitBreaker  CircuitBreakerConfig  `mapstructure:"circuit_breaker"`
+	Retry           RetryConfig           `mapstructure:"retry"`
 }
 
 // ServerConfig 服务器配置
@@ -26,10 +48,49 @@ type ServerConfig struct {
 	GRPCPort        int    `mapstructure:"grpc_port"`
 	Mode            string `mapstructure:"mode"`
 	ShutdownTimeout int    `mapstructure:"shutdown_timeout"`
+
+	HTTP3 HTTP3Config `mapstructure:"http3"`
+	Proxy ProxyConfig `mapstructure:"proxy"`
+}
+
+// ProxyConfig 负载均衡 / 反向代理配置，决定如何获取真实客户端地址
+type ProxyConfig struct {
+	// TrustedProxies 受信任的代理地址（CIDR 或 IP），只有来自这些地址的转发头（见 ClientIPHeader）
+	// 和 PROXY 协议头才会被采信；为空表示不信任任何代理
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// ProxyProtocol 是否在 TCP 监听（网关与 gRPC）上解析 PROXY 协议 v1/v2 头（AWS NLB、HAProxy send-proxy）
+	ProxyProtocol bool `mapstructure:"proxy_protocol"`
+	// HeaderTimeout 读取 PROXY 协议头的超时（毫秒）
+	HeaderTimeout int `mapstructure:"header_timeout"`
+	// ClientIPHeader 受信任代理传递客户端地址使用的请求头：x-forwarded-for（默认，X-Forwarded-For / X-Real-IP）
+	// 或 forwarded（RFC 7239 Forwarded）；只采信其中一种，另一种由客户端伪造时不会生效
+	ClientIPHeader string `mapstructure:"client_ip_header"`
+}
+
+// 代理传递客户端地址使用的请求头
+const (
+	ClientIPHeaderXForwardedFor = "x-forwarded-for"
+	ClientIPHeaderForwarded     = "forwarded"
+)
+
+// HTTP3Config 网关 HTTP/3（QUIC）监听配置（实验性，需使用 http3 构建标签编译）
+type HTTP3Config struct {
+	// Enable 是否启用 HTTP/3 监听
+	Enable bool `mapstructure:"enable"`
+	// Port UDP 端口，0 表示与网关端口相同
+	Port int `mapstructure:"port"`
+	// CertFile TLS 证书文件（QUIC 强制要求 TLS）
+	CertFile string `mapstructure:"cert_file"`
+	// KeyFile TLS 私钥文件
+	KeyFile string `mapstructure:"key_file"`
+	// AltSvcMaxAge Alt-Svc 响应头的有效期（秒），客户端在有效期内优先使用 HTTP/3
+	AltSvcMaxAge int `mapstructure:"alt_svc_max_age"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	// Driver 数据库驱动: postgres（默认）, mysql, sqlite（dbname 为数据库文件路径）
+	Driver          string `mapstructure:"driver"`
 	Host            string `mapstructure:"host"`
 	Port            int    `mapstructure:"port"`
 	User            string `mapstructure:"user"`
@@ -38,7 +99,37 @@ type DatabaseConfig struct {
 	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
 	MaxOpenConns    int    `mapstructure:"max_open_conns"`
 	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
-	LogMode         bool   `mapstructure:"log_mode"`
+	// LogMode 是否记录全部 SQL（关闭时只记录出错和慢查询）
+	LogMode bool `mapstructure:"log_mode"`
+	// SlowQueryThreshold 慢查询阈值（毫秒），超过时记录警告日志并计入 db_slow_queries_total，默认 200
+	SlowQueryThreshold int `mapstructure:"slow_query_threshold"`
+	// DSN 主库连接字符串，设置后忽略 host、port、user、password、dbname
+	DSN string `mapstructure:"dsn"`
+	// Replicas 只读副本的连接字符串，非事务的查询分发到健康的副本，为空时读写都走主库
+	Replicas []string `mapstructure:"replicas"`
+	// ReplicaCheckInterval 副本健康检查间隔（秒），默认 5
+	ReplicaCheckInterval int `mapstructure:"replica_check_interval"`
+
+	Notify    ChangeNotifyConfig `mapstructure:"notify"`
+	Migration MigrationConfig    `mapstructure:"migration"`
+}
+
+// MigrationConfig 表结构迁移（cmd/migrate）配置
+type MigrationConfig struct {
+	// LockTimeout 等待其他 migrate 完成迁移的最长时间（秒），默认 300
+	LockTimeout int `mapstructure:"lock_timeout"`
+}
+
+// ChangeNotifyConfig 数据库变更通知（LISTEN/NOTIFY）配置
+type ChangeNotifyConfig struct {
+	// Enable 是否监听变更（定时任务服务清理缓存、发布事件，gRPC 服务推送 WatchUsers）
+	Enable bool `mapstructure:"enable"`
+	// InstallTriggers 启动时是否自动安装触发器（需要建表权限）
+	InstallTriggers bool `mapstructure:"install_triggers"`
+	// Channel 通知频道
+	Channel string `mapstructure:"channel"`
+	// EventRoutingKey 变更事件发布到消息队列的路由键前缀，为空时不发布
+	EventRoutingKey string `mapstructure:"event_routing_key"`
 }
 
 // RedisConfig Redis 配置
@@ -49,10 +140,60 @@ type RedisConfig struct {
 	DB           int    `mapstructure:"db"`
 	PoolSize     int    `mapstructure:"pool_size"`
 	MinIdleConns int    `mapstructure:"min_idle_conns"`
+
+	Refresh     CacheRefreshConfig     `mapstructure:"refresh"`
+	Degradation RedisDegradationConfig `mapstructure:"degradation"`
+}
+
+// RedisDegradationConfig Redis 不可用时各功能的降级策略
+// 命令出现连接错误后进入降级状态，期间各功能不再访问 Redis，每隔 ProbeInterval 放行一次请求检测是否恢复
+type RedisDegradationConfig struct {
+	// RateLimit redis 模式限流的策略：local 退化为各实例本地限流（默认）、open 放行、closed 拒绝（429）
+	RateLimit string `mapstructure:"rate_limit"`
+	// Revocation 令牌吊销检查的策略：closed 拒绝请求（HTTP 503 / gRPC UNAVAILABLE，默认）、open 跳过检查
+	Revocation string `mapstructure:"revocation"`
+	// Cache 读缓存的策略：bypass 直接读数据库（默认）、fail 返回错误
+	Cache string `mapstructure:"cache"`
+	// ProbeInterval 降级期间检测 Redis 是否恢复的间隔（秒），默认 5
+	ProbeInterval int `mapstructure:"probe_interval"`
+}
+
+// CacheRefreshConfig 热点缓存预刷新配置
+type CacheRefreshConfig struct {
+	// Interval 扫描即将过期键的间隔（秒）
+	Interval int `mapstructure:"interval"`
+	// Classes 各类缓存键的刷新策略，键为类别名称（如 user）
+	Classes map[string]CacheClassConfig `mapstructure:"classes"`
+	// Local 进程内缓存层（位于 Redis 之前）
+	Local LocalCacheConfig `mapstructure:"local"`
+}
+
+// LocalCacheConfig 进程内 LRU 缓存层配置
+// 启用后 Fetch 先读本实例内存，未命中再读 Redis；Invalidate 通过 Redis 发布/订阅通知所有实例删除本地副本，
+// 其他更新（如预刷新、缓存重建）在本地 TTL 内可能读到旧值，TTL 应保持较短
+type LocalCacheConfig struct {
+	// Enable 是否启用
+	Enable bool `mapstructure:"enable"`
+	// Size 最多缓存的键数，超过时淘汰最久未使用的键，默认 10000
+	Size int `mapstructure:"size"`
+	// TTL 本地缓存时间（秒），默认 10
+	TTL int `mapstructure:"ttl"`
+}
+
+// CacheClassConfig 单类缓存键的刷新策略
+type CacheClassConfig struct {
+	// TTL 缓存时间（秒），实际过期时间会加上最多 10% 的随机抖动
+	TTL int `mapstructure:"ttl"`
+	// RefreshAhead 过期前多久开始刷新（秒）
+	RefreshAhead int `mapstructure:"refresh_ahead"`
+	// HotWindow 在该时间内被访问过的键才会刷新（秒），0 表示总是刷新
+	HotWindow int `mapstructure:"hot_window"`
 }
 
 // RabbitMQConfig RabbitMQ 配置
 type RabbitMQConfig struct {
+	// Driver 消息代理: rabbitmq（默认）, redis_streams
+	Driver   string         `mapstructure:"driver"`
 	Host     string         `mapstructure:"host"`
 	Port     int            `mapstructure:"port"`
 	User     string         `mapstructure:"user"`
@@ -60,6 +201,69 @@ type RabbitMQConfig struct {
 	Vhost    string         `mapstructure:"vhost"`
 	Exchange ExchangeConfig `mapstructure:"exchange"`
 	Queues   []QueueConfig  `mapstructure:"queues"`
+	// SlowConsumer 慢消费检测配置
+	SlowConsumer SlowConsumerConfig `mapstructure:"slow_consumer"`
+	// DeadLetterQueue 无法还原的消息（解密失败、转存对象丢失等）原样转存到的队列，为空时直接丢弃
+	DeadLetterQueue string `mapstructure:"dead_letter_queue"`
+	// Encryption 消息体加密配置
+	Encryption MessageEncryptionConfig `mapstructure:"encryption"`
+	// Compression 消息体压缩配置
+	Compression MessageCompressionConfig `mapstructure:"compression"`
+	// ClaimCheck 超大消息转存配置
+	ClaimCheck ClaimCheckConfig `mapstructure:"claim_check"`
+	// Streams Redis Streams 驱动配置
+	Streams RedisStreamsConfig `mapstructure:"streams"`
+	// PublishAllowlist 允许通过 HTTP / gRPC 接口发布的队列名称，为空表示不限制
+	PublishAllowlist []string `mapstructure:"publish_allowlist"`
+}
+
+// RedisStreamsConfig Redis Streams 驱动配置
+type RedisStreamsConfig struct {
+	// KeyPrefix stream 键名前缀
+	KeyPrefix string `mapstructure:"key_prefix"`
+	// Group 消费组名称
+	Group string `mapstructure:"group"`
+	// MaxLen 每个 stream 保留的最大消息数（近似裁剪）
+	MaxLen int64 `mapstructure:"max_len"`
+	// ClaimIdle 未确认消息空闲超过该时间（秒）后被重新认领
+	ClaimIdle int `mapstructure:"claim_idle"`
+}
+
+// ClaimCheckConfig 超大消息转存（claim check）配置
+type ClaimCheckConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Threshold 超过该大小（字节）的消息体存入 S3，只发布引用
+	Threshold int `mapstructure:"threshold"`
+	// Prefix S3 对象前缀，默认 claim-checks/；定时任务按该前缀清理过期对象，不能为空
+	Prefix string `mapstructure:"prefix"`
+	// Expire 未被成功消费的对象保留时间（小时），过期后由定时任务清理，开启时必须大于 0
+	Expire int `mapstructure:"expire"`
+}
+
+// MessageCompressionConfig 消息体压缩配置
+type MessageCompressionConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// Algorithm 压缩算法: gzip, zstd
+	Algorithm string `mapstructure:"algorithm"`
+	// Threshold 超过该大小（字节）的消息才压缩
+	Threshold int `mapstructure:"threshold"`
+}
+
+// MessageEncryptionConfig 消息体加密配置
+type MessageEncryptionConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// RoutingKeyPrefixes 需要加密的路由键前缀，如 user.、email.
+	RoutingKeyPrefixes []string `mapstructure:"routing_key_prefixes"`
+}
+
+// SlowConsumerConfig 慢消费检测配置
+type SlowConsumerConfig struct {
+	// ParkQueue 处理超时的消息转存到的队列，为空时超时消息直接丢弃
+	ParkQueue string `mapstructure:"park_queue"`
+	// AlertThreshold 告警窗口内超时次数达到该值时告警
+	AlertThreshold int `mapstructure:"alert_threshold"`
+	// AlertWindow 告警统计窗口（秒）
+	AlertWindow int `mapstructure:"alert_window"`
 }
 
 // ExchangeConfig 交换机配置
@@ -74,6 +278,14 @@ type QueueConfig struct {
 	Name       string `mapstructure:"name"`
 	RoutingKey string `mapstructure:"routing_key"`
 	Durable    bool   `mapstructure:"durable"`
+	// ProcessTimeout 单条消息处理超时时间（秒），0 表示不限制
+	ProcessTimeout int `mapstructure:"process_timeout"`
+	// MessageTTL 消息在队列中的最长等待时间（秒），超时未被消费的消息被丢弃，0 表示不限制
+	MessageTTL int `mapstructure:"message_ttl"`
+	// MaxLength 队列最多保留的消息数，0 表示不限制
+	MaxLength int `mapstructure:"max_length"`
+	// Overflow 队列达到 MaxLength 时的行为: drop-head（丢弃最老的消息，默认）, reject-publish（拒绝新消息）
+	Overflow string `mapstructure:"overflow"`
 }
 
 // AWSConfig AWS 配置
@@ -89,6 +301,49 @@ type S3Config struct {
 	Bucket          string `mapstructure:"bucket"`
 	UploadPrefix    string `mapstructure:"upload_prefix"`
 	PresignedExpire int    `mapstructure:"presigned_expire"`
+	// CAS 内容寻址存储：以内容 SHA-256 作为对象 Key，相同内容只保存一份，删除时按引用计数回收
+	CAS bool `mapstructure:"cas"`
+
+	// MaxFileSize 单个上传文件的大小上限（字节），0 表示不限制；请求体总大小由 middleware.body_limit 限制
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+	// AllowedTypes 允许上传的内容类型（按文件内容识别，支持 image/* 形式），为空时不限制
+	AllowedTypes []string `mapstructure:"allowed_types"`
+
+	Archive      ArchiveConfig      `mapstructure:"archive"`
+	UploadLimits UploadLimitsConfig `mapstructure:"upload_limits"`
+}
+
+// UploadLimitsConfig 上传并发数与带宽限制
+type UploadLimitsConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// MaxConcurrent 每个用户（未登录按客户端 IP）同时进行的上传数，所有网关实例共享，0 表示不限制
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// BytesPerSecond 单个上传读取请求体的速率（字节/秒），0 表示不限制
+	BytesPerSecond int64 `mapstructure:"bytes_per_second"`
+	// SlotTTL 并发槽位的最长占用时间（秒），网关异常退出未释放的槽位到期后回收
+	SlotTTL int `mapstructure:"slot_ttl"`
+	// Tiers 按角色覆盖限制（如 admin、vip），未配置的字段使用上面的默认值
+	Tiers map[string]UploadTierConfig `mapstructure:"tiers"`
+}
+
+// UploadTierConfig 角色的上传限制，0 表示使用默认值，负数表示不限制
+type UploadTierConfig struct {
+	MaxConcurrent  int   `mapstructure:"max_concurrent"`
+	BytesPerSecond int64 `mapstructure:"bytes_per_second"`
+}
+
+// ArchiveConfig 多文件打包下载配置
+type ArchiveConfig struct {
+	// MaxFiles 单次打包的最大文件数
+	MaxFiles int `mapstructure:"max_files"`
+	// MaxSize 单次打包的文件总大小上限（MB）
+	MaxSize int `mapstructure:"max_size"`
+	// StreamMaxSize 不超过该大小（MB）时直接流式写入响应，超过则异步打包到 S3
+	StreamMaxSize int `mapstructure:"stream_max_size"`
+	// Prefix 异步打包结果的 S3 对象前缀
+	Prefix string `mapstructure:"prefix"`
+	// Expire 异步打包结果的保留时间（小时），过期后由定时任务清理
+	Expire int `mapstructure:"expire"`
 }
 
 // LoggerConfig 日志配置
@@ -99,12 +354,312 @@ type LoggerConfig struct {
 	ErrorOutputPaths []string `mapstructure:"error_output_paths"`
 	EnableCaller     bool     `mapstructure:"enable_caller"`
 	EnableStacktrace bool     `mapstructure:"enable_stacktrace"`
+
+	// Rotation 日志文件的切割与保留策略，对 output_paths、error_output_paths 中的所有文件生效
+	Rotation LogRotationConfig `mapstructure:"rotation"`
+	// Files 按输出路径单独配置的切割策略，整体替换 Rotation
+	Files []LogFileConfig `mapstructure:"files"`
+}
+
+// LogRotationConfig 日志文件切割与保留配置
+type LogRotationConfig struct {
+	// MaxSize 单个文件的大小上限（MB），超过后切割，未配置时为 100
+	MaxSize int `mapstructure:"max_size"`
+	// MaxAge 切割出的旧文件保留天数，0 表示不按时间删除
+	MaxAge int `mapstructure:"max_age"`
+	// MaxBackups 保留的旧文件个数，0 表示不按个数删除
+	MaxBackups int `mapstructure:"max_backups"`
+	// Compress 是否用 gzip 压缩旧文件
+	Compress bool `mapstructure:"compress"`
+}
+
+// LogFileConfig 单个日志文件的切割策略
+type LogFileConfig struct {
+	// Path 输出路径，与 output_paths 或 error_output_paths 中的路径相同
+	Path              string `mapstructure:"path"`
+	LogRotationConfig `mapstructure:",squash"`
+}
+
+// SecurityConfig 安全配置
+type SecurityConfig struct {
+	// CurrentKeyVersion 当前用于加密的密钥版本
+	CurrentKeyVersion string `mapstructure:"current_key_version"`
+	// EncryptionKeys 密钥版本到32字节密钥的映射，轮换时保留旧版本用于解密
+	EncryptionKeys map[string]string `mapstructure:"encryption_keys"`
+	// RoleScopes 角色拥有的权限范围
+	RoleScopes map[string][]string `mapstructure:"role_scopes"`
+	// Password 密码哈希配置
+	Password PasswordConfig `mapstructure:"password"`
+	// Lockout 登录失败锁定配置
+	Lockout LockoutConfig `mapstructure:"lockout"`
+}
+
+// PasswordConfig 密码哈希配置
+type PasswordConfig struct {
+	// Algorithm 新密码使用的哈希算法：argon2id、bcrypt
+	Algorithm string `mapstructure:"algorithm"`
+	// BcryptCost bcrypt 计算成本
+	BcryptCost int `mapstructure:"bcrypt_cost"`
+}
+
+// LockoutConfig 登录失败锁定配置，失败次数记录在 Redis 中
+type LockoutConfig struct {
+	// MaxAttempts 窗口内允许的连续失败次数，达到后锁定账号
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Window 失败次数的统计窗口（分钟）
+	Window int `mapstructure:"window"`
+	// Duration 锁定时长（分钟）
+	Duration int `mapstructure:"duration"`
+}
+
+// DefaultJWTSecret 未配置 jwt.secret 时使用的占位密钥，仅供本地开发，release 模式下拒绝启动
+const DefaultJWTSecret = "your-secret-key-change-in-production"
+
+// JWTConfig JWT 签发与校验配置
+type JWTConfig struct {
+	// Secret 签名密钥，为空时使用 DefaultJWTSecret
+	Secret string `mapstructure:"secret"`
+	// Issuer 签发者，校验 token 时要求一致
+	Issuer string `mapstructure:"issuer"`
+	// AccessExpire 访问令牌有效期（分钟）
+	AccessExpire int `mapstructure:"access_expire"`
+	// RefreshExpire 刷新令牌有效期（小时）
+	RefreshExpire int `mapstructure:"refresh_expire"`
+	// Algorithm 签名算法：HS256、HS384、HS512（共享密钥），RS256、RS384、RS512、ES256、ES384、ES512（非对称密钥）
+	Algorithm string `mapstructure:"algorithm"`
+	// PrivateKeyFile RS/ES 算法签发令牌的 PEM 私钥，为空时只校验外部身份提供方签发的令牌（登录、刷新不可用）
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	// PublicKeyFile RS/ES 算法校验令牌的 PEM 公钥，为空时由私钥推导
+	PublicKeyFile string `mapstructure:"public_key_file"`
+	// JWKSURL 远程公钥集地址（如 Keycloak、Auth0），令牌头带 kid 时按 kid 查找公钥
+	JWKSURL string `mapstructure:"jwks_url"`
+	// JWKSRefresh 公钥集缓存时间（分钟），遇到未知的 kid 时提前刷新
+	JWKSRefresh int `mapstructure:"jwks_refresh"`
+}
+
+// RuntimeSettingsConfig 运行时配置（数据库中可由管理员调整的配置项）选项
+type RuntimeSettingsConfig struct {
+	// ReconcileInterval 各组件同步配置的间隔（秒）
+	ReconcileInterval int `mapstructure:"reconcile_interval"`
+	// CacheTTL 配置项在 Redis 中的缓存时间（秒）
+	CacheTTL int `mapstructure:"cache_ttl"`
+}
+
+// ActivityConfig 用户活跃度追踪配置
+type ActivityConfig struct {
+	// FlushInterval 内存中的活跃记录批量写入的间隔（秒）
+	FlushInterval int `mapstructure:"flush_interval"`
+	// OnlineWindow 判定为在线的时间窗口（秒）
+	OnlineWindow int `mapstructure:"online_window"`
+	// Retention Redis 中活跃记录的保留时间（小时）
+	Retention int `mapstructure:"retention"`
+}
+
+// UsersConfig 用户管理配置
+type UsersConfig struct {
+	// PurgeAfterDays 软删除的用户保留的天数，超过后由定时任务 purge_deleted_users 永久删除
+	PurgeAfterDays int `mapstructure:"purge_after_days"`
+}
+
+// TimezoneConfig 响应时间戳的时区配置
+// 时间统一以 UTC 存储，输出时按请求头 X-Timezone、用户偏好、Default 的顺序选择时区
+type TimezoneConfig struct {
+	// Default 默认时区（IANA 名称，如 Asia/Shanghai），默认 UTC
+	Default string `mapstructure:"default"`
+}
+
+// UserSettingsConfig 用户偏好设置配置
+type UserSettingsConfig struct {
+	// CacheTTL 用户设置在 Redis 中的缓存时间（秒），默认 300
+	CacheTTL int `mapstructure:"cache_ttl"`
+	// MaxValueSize 单个设置值 JSON 编码后的最大字节数，默认 4096
+	MaxValueSize int `mapstructure:"max_value_size"`
+	// Keys 允许的设置项，未声明的键一律拒绝
+	Keys []UserSettingKey `mapstructure:"keys"`
+}
+
+// UserSettingKey 用户设置项
+type UserSettingKey struct {
+	// Name 设置项名称（小写，如 theme）
+	Name string `mapstructure:"name"`
+	// Description 说明
+	Description string `mapstructure:"description"`
+	// Schema 值的结构，写入时校验
+	Schema jsonschema.Schema `mapstructure:"schema"`
+	// Default 用户未设置时返回的默认值，为空表示不返回
+	Default interface{} `mapstructure:"default"`
+}
+
+// QuotaConfig 配额配置
+type QuotaConfig struct {
+	// Limits 各配额的上限，键为配额名称（如 storage、api）
+	Limits map[string]QuotaLimitConfig `mapstructure:"limits"`
+	// Thresholds 触发提醒的使用百分比（如 80、95）
+	Thresholds []int `mapstructure:"thresholds"`
+	// AlertCooldown 同一配额同一阈值两次提醒的最小间隔（小时）
+	AlertCooldown int `mapstructure:"alert_cooldown"`
+}
+
+// QuotaLimitConfig 单项配额上限
+type QuotaLimitConfig struct {
+	// Limit 上限（storage 为字节数，api 为请求次数）
+	Limit int64 `mapstructure:"limit"`
+	// Window 统计周期（秒），0 表示累计不重置
+	Window int `mapstructure:"window"`
+}
+
+// NotifyConfig 通知配置
+type NotifyConfig struct {
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	Email   EmailConfig   `mapstructure:"email"`
+}
+
+// WebhookConfig Webhook 通知配置，URL 为空时不发送
+type WebhookConfig struct {
+	URL string `mapstructure:"url"`
+	// Timeout 请求超时时间（秒）
+	Timeout int `mapstructure:"timeout"`
+}
+
+// EmailConfig 邮件通知配置，Host 为空时不发送
+type EmailConfig struct {
+	Host     string   `mapstructure:"host"`
+	Port     int      `mapstructure:"port"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+}
+
+// FlagConfig 功能开关（按用户评估，与按模块启停的 features 不同）
+type FlagConfig struct {
+	// Enabled 总开关
+	Enabled bool `mapstructure:"enabled"`
+	// Roles 仅对这些角色开启，为空表示不限角色
+	Roles []string `mapstructure:"roles"`
+	// Percentage 按用户 ID 灰度的百分比（1-99），0 或 100 表示全量
+	Percentage int `mapstructure:"percentage"`
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	// Path 网关暴露指标的路径
+	Path string `mapstructure:"path"`
+	// GRPCPort gRPC 服务暴露指标的 HTTP 端口，0 表示不暴露
+	GRPCPort int `mapstructure:"grpc_port"`
+	// CronPort 定时任务服务暴露指标的 HTTP 端口，0 表示不暴露
+	CronPort int `mapstructure:"cron_port"`
+	// Export 推送关键指标到 CloudWatch / StatsD
+	Export MetricsExportConfig `mapstructure:"export"`
+}
+
+// MetricsExportConfig 指标推送配置
+type MetricsExportConfig struct {
+	// Driver 推送目标：空（不推送）、statsd、cloudwatch
+	Driver string `mapstructure:"driver"`
+	// Interval 推送间隔（秒）
+	Interval int `mapstructure:"interval"`
+	// Include 推送的指标名（不含 microservice_ 前缀），为空时推送默认的关键指标
+	Include []string `mapstructure:"include"`
+	// StatsD StatsD 配置
+	StatsD StatsDConfig `mapstructure:"statsd"`
+	// CloudWatch CloudWatch 配置（凭证与区域使用 aws 配置）
+	CloudWatch CloudWatchConfig `mapstructure:"cloudwatch"`
+}
+
+// StatsDConfig StatsD 推送配置
+type StatsDConfig struct {
+	// Address StatsD 地址 (host:port，UDP)
+	Address string `mapstructure:"address"`
+	// Prefix 指标名前缀
+	Prefix string `mapstructure:"prefix"`
+	// Tags 是否以 DogStatsD 标签格式发送标签，否则拼接到指标名中
+	Tags bool `mapstructure:"tags"`
+}
+
+// CloudWatchConfig CloudWatch 推送配置
+type CloudWatchConfig struct {
+	// Namespace 指标命名空间
+	Namespace string `mapstructure:"namespace"`
+	// Dimensions 附加到所有指标的维度（如 service、env）
+	Dimensions map[string]string `mapstructure:"dimensions"`
+}
+
+// CircuitBreakerConfig 外部依赖（数据库、Redis、消息队列、S3）的熔断配置
+// 连续失败达到阈值后熔断，open_timeout 内直接返回“依赖不可用”错误，之后放行少量探测请求，成功则恢复
+type CircuitBreakerConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// FailureThreshold 连续失败多少次后熔断，默认 5
+	FailureThreshold int `mapstructure:"failure_threshold"`
+	// OpenTimeout 熔断持续时间（秒），之后进入半开状态，默认 30
+	OpenTimeout int `mapstructure:"open_timeout"`
+	// HalfOpenRequests 半开状态下同时放行的探测请求数，全部成功后恢复，默认 1
+	HalfOpenRequests int `mapstructure:"half_open_requests"`
+}
+
+// RetryConfig 重试配置：启动时等待依赖就绪，以及出站调用的暂时性失败重试
+// 重试间隔从 initial_interval 开始按指数增长（带随机抖动），最长 max_interval
+type RetryConfig struct {
+	// StartupTimeout 启动时等待数据库、Redis、RabbitMQ 就绪的最长时间（秒），0 表示不等待（连接失败立即退出）
+	StartupTimeout int `mapstructure:"startup_timeout"`
+	// InitialInterval 首次重试前的等待时间（毫秒），默认 200
+	InitialInterval int `mapstructure:"initial_interval"`
+	// MaxInterval 重试间隔上限（秒），默认 10
+	MaxInterval int `mapstructure:"max_interval"`
+	// Attempts 暂时性失败（如消息发布时连接正在重建）的最多尝试次数（含首次），默认 3
+	Attempts int `mapstructure:"attempts"`
+}
+
+// SLOConfig 服务等级目标配置
+type SLOConfig struct {
+	// Window 错误预算统计周期（小时）
+	Window int `mapstructure:"window"`
+	// Objectives 各路由/RPC 的目标
+	Objectives []SLOObjectiveConfig `mapstructure:"objectives"`
+}
+
+// SLOObjectiveConfig 单个服务等级目标
+type SLOObjectiveConfig struct {
+	// Name 目标名称，用作指标标签
+	Name string `mapstructure:"name"`
+	// Kind 请求类型：http 或 grpc
+	Kind string `mapstructure:"kind"`
+	// Target HTTP 为 "方法 路由模板"（如 GET /api/v1/users/:id），gRPC 为完整方法名（如 /microservice.UserService/GetUser）
+	Target string `mapstructure:"target"`
+	// Availability 可用性目标（非服务端错误的请求比例，如 0.999），0 表示不统计
+	Availability float64 `mapstructure:"availability"`
+	// LatencyThreshold 延迟阈值（毫秒）
+	LatencyThreshold int `mapstructure:"latency_threshold"`
+	// LatencyTarget 低于延迟阈值的请求比例目标（如 0.99），0 表示不统计
+	LatencyTarget float64 `mapstructure:"latency_target"`
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	// AnchorPrefix 链头锚点在 S3 中的前缀
+	AnchorPrefix string `mapstructure:"anchor_prefix"`
+	// AnchorRetentionDays 锚点的 Object Lock 保留天数，0 表示不设置（桶需开启 Object Lock）
+	AnchorRetentionDays int `mapstructure:"anchor_retention_days"`
+
+	// Stream 审计记录写入数据库后同时发布到消息队列，供 SIEM 等外部系统订阅
+	Stream AuditStreamConfig `mapstructure:"stream"`
+}
+
+// AuditStreamConfig 审计记录发布配置
+type AuditStreamConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// RoutingKey 发布使用的路由键，未配置时为 audit.events
+	RoutingKey string `mapstructure:"routing_key"`
 }
 
 // CronConfig 定时任务配置
 type CronConfig struct {
 	Enable bool        `mapstructure:"enable"`
 	Jobs   []JobConfig `mapstructure:"jobs"`
+	// JobTimeout 任务默认超时时间（秒），0 表示不限制；超时后取消任务上下文并记为失败
+	JobTimeout int `mapstructure:"job_timeout"`
+	// MaxFailures 任务连续失败达到该次数后自动在运行时禁用并通知运维，0 表示不自动禁用
+	MaxFailures int `mapstructure:"max_failures"`
 }
 
 // JobConfig 任务配置
@@ -112,6 +667,10 @@ type JobConfig struct {
 	Name    string `mapstructure:"name"`
 	Spec    string `mapstructure:"spec"`
 	Enabled bool   `mapstructure:"enabled"`
+	// Timeout 超时时间（秒），0 表示使用 cron.job_timeout
+	Timeout int `mapstructure:"timeout"`
+	// MaxFailures 自动禁用前允许的连续失败次数，0 表示使用 cron.max_failures
+	MaxFailures int `mapstructure:"max_failures"`
 }
 
 // MiddlewareConfig 中间件配置
@@ -119,11 +678,29 @@ type MiddlewareConfig struct {
 	CORS       CORSConfig       `mapstructure:"cors"`
 	RateLimit  RateLimitConfig  `mapstructure:"rate_limit"`
 	RequestLog RequestLogConfig `mapstructure:"request_log"`
+	// FailureCapture 最近失败请求的记录（failures 中间件）
+	FailureCapture FailureCaptureConfig `mapstructure:"failure_capture"`
+	// Idempotency 按 Idempotency-Key 请求头重放响应（idempotency 中间件）
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	// Timeout 请求超时（timeout 中间件）
+	Timeout TimeoutConfig `mapstructure:"timeout"`
+	// SecurityHeaders 安全响应头（security_headers 中间件）
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	// CSRF 基于 Cookie 会话的 CSRF 防护（csrf 中间件）
+	CSRF CSRFConfig `mapstructure:"csrf"`
+	// Compression 响应压缩（compression 中间件）
+	Compression CompressionConfig `mapstructure:"compression"`
+	// BodyLimit 请求体大小限制（body_limit 中间件）
+	BodyLimit BodyLimitConfig `mapstructure:"body_limit"`
+	// Chains 各路由组的中间件及顺序，如 global: [recovery, request_id, logger]
+	Chains map[string][]string `mapstructure:"chains"`
 }
 
 // CORSConfig CORS 配置
 type CORSConfig struct {
-	Enable           bool     `mapstructure:"enable"`
+	Enable bool `mapstructure:"enable"`
+	// AllowOrigins 允许的来源：完整来源（https://app.example.com）、子域名通配（https://*.example.com）或 *
+	// allow_credentials 开启时，只有明确列出或通配匹配的来源才允许携带凭证，* 始终不带凭证
 	AllowOrigins     []string `mapstructure:"allow_origins"`
 	AllowMethods     []string `mapstructure:"allow_methods"`
 	AllowHeaders     []string `mapstructure:"allow_headers"`
@@ -132,18 +709,190 @@ type CORSConfig struct {
 	MaxAge           int      `mapstructure:"max_age"`
 }
 
+// ValidCORSOrigin 检查 CORS 来源配置项的格式
+// 参数:
+//
+//	origin: *、scheme://host[:port]，host 最左侧可以是 *. 通配子域名
+//
+// 返回:
+//
+//	bool: 格式是否正确
+func ValidCORSOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme != "http" && scheme != "https" {
+		return false
+	}
+	host = strings.TrimPrefix(host, "*.")
+	return host != "" && !strings.ContainsAny(host, "*/?#@")
+}
+
 // RateLimitConfig 限流配置
 type RateLimitConfig struct {
 	Enable            bool `mapstructure:"enable"`
 	RequestsPerSecond int  `mapstructure:"requests_per_second"`
 	Burst             int  `mapstructure:"burst"`
+	// Mode 限流模式：local（默认，各实例内存令牌桶）、redis（基于 Redis 的 GCRA，多实例共享限额）
+	Mode string `mapstructure:"mode"`
+	// Tiers 限流等级，键为等级名称，供白名单引用
+	Tiers map[string]RateLimitTier `mapstructure:"tiers"`
+	// Allowlist 受信任的客户端（合作方集成等），命中后按其等级限流，且同一客户端的所有请求共享限额
+	Allowlist []RateLimitClient `mapstructure:"allowlist"`
+}
+
+// RateLimitTier 限流等级
+type RateLimitTier struct {
+	// Exempt 不限流
+	Exempt            bool `mapstructure:"exempt"`
+	RequestsPerSecond int  `mapstructure:"requests_per_second"`
+	// Burst 突发请求数，未配置时等于 requests_per_second
+	Burst int `mapstructure:"burst"`
+}
+
+// RateLimitClient 限流白名单中的客户端，按 API Key、JWT 主体（用户 ID）或 IP 识别，任一项匹配即命中
+type RateLimitClient struct {
+	// Name 客户端名称，也是限流键和突发额度的归属
+	Name string `mapstructure:"name"`
+	// Tier 使用的限流等级
+	Tier string `mapstructure:"tier"`
+	// APIKeys 请求头 X-API-Key 的取值
+	APIKeys []string `mapstructure:"api_keys"`
+	// Subjects JWT 中的用户 ID
+	Subjects []int64 `mapstructure:"subjects"`
+	// IPs IP 地址或 CIDR 网段
+	IPs []string `mapstructure:"ips"`
 }
 
 // RequestLogConfig 请求日志配置
+// 访问日志由中间件链中的 logger 记录；开启后按采样比例在访问日志中附带脱敏后的请求体、响应体
 type RequestLogConfig struct {
 	Enable          bool `mapstructure:"enable"`
 	LogRequestBody  bool `mapstructure:"log_request_body"`
 	LogResponseBody bool `mapstructure:"log_response_body"`
+	// MaxBodySize 请求体、响应体各记录的最大字节数，默认 2048
+	MaxBodySize int `mapstructure:"max_body_size"`
+	// SampleRate 记录内容的请求比例（0~1），未配置时为 1（全部记录）
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// RedactFields 额外需要隐藏的 JSON 字段、表单字段和查询参数（不区分大小写，按包含匹配）
+	RedactFields []string `mapstructure:"redact_fields"`
+}
+
+// FailureCaptureConfig 失败请求记录配置
+// 每个网关实例在内存中保留最近 Size 个状态码 ≥500 的请求（脱敏后的请求/响应体），供管理接口查看
+type FailureCaptureConfig struct {
+	// Size 保留的请求数，默认 100
+	Size int `mapstructure:"size"`
+	// MaxBodySize 请求体、响应体各保留的最大字节数，默认 4096
+	MaxBodySize int `mapstructure:"max_body_size"`
+	// RedactFields 额外需要脱敏的 JSON 字段、表单字段和查询参数（不区分大小写），
+	// 默认已包含 password、token、secret 等常见敏感字段
+	RedactFields []string `mapstructure:"redact_fields"`
+}
+
+// IdempotencyConfig 幂等请求配置
+// POST/PUT 请求带 Idempotency-Key 头时，首次的响应保存在 Redis 中，窗口内相同调用方以相同键重试时直接重放
+type IdempotencyConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// TTL 响应保存时间（秒），默认 86400
+	TTL int `mapstructure:"ttl"`
+	// LockTTL 请求处理中占用键的最长时间（秒），网关异常退出后到期释放，默认 60
+	LockTTL int `mapstructure:"lock_ttl"`
+	// MaxBodySize 保存的响应体上限（字节），超过时不保存（重试会再次执行），默认 1048576
+	MaxBodySize int `mapstructure:"max_body_size"`
+}
+
+// TimeoutConfig 请求超时配置
+// 超时后取消请求上下文（数据库、Redis、S3、gRPC 调用随之取消），未开始写出响应时返回 504
+type TimeoutConfig struct {
+	// Default 默认超时时间（秒），0 表示不限制
+	Default int `mapstructure:"default"`
+	// Routes 按路由覆盖，先匹配的生效
+	Routes []RouteTimeoutConfig `mapstructure:"routes"`
+}
+
+// RouteTimeoutConfig 路由的超时时间
+type RouteTimeoutConfig struct {
+	// Method HTTP 方法，为空匹配所有方法
+	Method string `mapstructure:"method"`
+	// Path 路由模板，如 /api/v1/users/:id
+	Path string `mapstructure:"path"`
+	// Timeout 超时时间（秒），0 表示不限制
+	Timeout int `mapstructure:"timeout"`
+}
+
+// SecurityHeadersConfig 安全响应头配置
+// X-Content-Type-Options: nosniff 始终发送
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge Strict-Transport-Security 的 max-age（秒），0 表示不发送（浏览器只在 HTTPS 响应中采用）
+	HSTSMaxAge int `mapstructure:"hsts_max_age"`
+	// HSTSIncludeSubdomains HSTS 是否包含子域名
+	HSTSIncludeSubdomains bool `mapstructure:"hsts_include_subdomains"`
+	// HSTSPreload HSTS 是否带 preload（申请加入浏览器预加载列表时使用）
+	HSTSPreload bool `mapstructure:"hsts_preload"`
+	// FrameOptions X-Frame-Options：DENY（默认）或 SAMEORIGIN
+	FrameOptions string `mapstructure:"frame_options"`
+	// ReferrerPolicy Referrer-Policy，默认 strict-origin-when-cross-origin
+	ReferrerPolicy string `mapstructure:"referrer_policy"`
+	// ContentSecurityPolicy Content-Security-Policy，为空时不发送
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+	// CSPReportOnly 以 Content-Security-Policy-Report-Only 发送，只上报不拦截（上线新策略前观察用）
+	CSPReportOnly bool `mapstructure:"csp_report_only"`
+}
+
+// CSRFConfig 双重提交 Cookie 的 CSRF 防护配置
+// 网关在 Cookie 中下发随机令牌，前端读取后放入请求头，不安全方法（POST、PUT、PATCH、DELETE）的两者必须一致
+type CSRFConfig struct {
+	Enable bool `mapstructure:"enable"`
+	// CookieName 令牌 Cookie 名称，默认 csrf_token
+	CookieName string `mapstructure:"cookie_name"`
+	// HeaderName 提交令牌的请求头，默认 X-CSRF-Token
+	HeaderName string `mapstructure:"header_name"`
+	// CookieDomain 令牌 Cookie 的 Domain，为空时为当前域名
+	CookieDomain string `mapstructure:"cookie_domain"`
+	// CookiePath 令牌 Cookie 的 Path，默认 /
+	CookiePath string `mapstructure:"cookie_path"`
+	// Secure 令牌 Cookie 是否只通过 HTTPS 发送
+	Secure bool `mapstructure:"secure"`
+	// SameSite 令牌 Cookie 的 SameSite：lax（默认）、strict、none
+	SameSite string `mapstructure:"same_site"`
+	// MaxAge 令牌 Cookie 有效期（秒），默认 86400
+	MaxAge int `mapstructure:"max_age"`
+	// ExemptPaths 不校验的路径前缀（如第三方回调）
+	ExemptPaths []string `mapstructure:"exempt_paths"`
+}
+
+// CompressionConfig 响应压缩配置
+// 按请求的 Accept-Encoding 以 gzip 或 deflate 压缩响应，已设置 Content-Encoding 的响应不再压缩
+type CompressionConfig struct {
+	// Level 压缩级别，1（最快）到 9（压缩率最高），默认 6
+	Level int `mapstructure:"level"`
+	// MinSize 响应体达到该字节数才压缩，默认 1024
+	MinSize int `mapstructure:"min_size"`
+	// ContentTypes 压缩的内容类型，支持 text/* 形式，默认 application/json、application/problem+json、text/plain
+	ContentTypes []string `mapstructure:"content_types"`
+	// ExcludePaths 不压缩的路径前缀（如文件上传、打包下载）
+	ExcludePaths []string `mapstructure:"exclude_paths"`
+}
+
+// BodyLimitConfig 请求体大小限制配置
+// 声明的 Content-Length 超过上限时直接返回 413，未声明时读取超过上限后读取失败
+type BodyLimitConfig struct {
+	// MaxSize 默认上限（字节），0 表示不限制
+	MaxSize int64 `mapstructure:"max_size"`
+	// Routes 按路由覆盖，先匹配的生效
+	Routes []RouteBodyLimitConfig `mapstructure:"routes"`
+}
+
+// RouteBodyLimitConfig 路由的请求体大小上限
+type RouteBodyLimitConfig struct {
+	// Method HTTP 方法，为空匹配所有方法
+	Method string `mapstructure:"method"`
+	// Path 路由模板，如 /api/v1/upload
+	Path string `mapstructure:"path"`
+	// MaxSize 上限（字节），0 表示不限制
+	MaxSize int64 `mapstructure:"max_size"`
 }
 
 // GRPCConfig gRPC 配置
@@ -153,12 +902,88 @@ type GRPCConfig struct {
 	ConnectionTimeout int `mapstructure:"connection_timeout"`
 	KeepaliveTime     int `mapstructure:"keepalive_time"`
 	KeepaliveTimeout  int `mapstructure:"keepalive_timeout"`
+	// KeepaliveMinTime 允许客户端发送 keepalive ping 的最小间隔（秒），更频繁的客户端会被断开
+	KeepaliveMinTime int `mapstructure:"keepalive_min_time"`
+	// KeepalivePermitWithoutStream 是否允许客户端在没有进行中的请求时发送 ping
+	KeepalivePermitWithoutStream bool `mapstructure:"keepalive_permit_without_stream"`
+	// MaxConnectionIdle 连接空闲多久后关闭（秒），0 表示不关闭
+	MaxConnectionIdle int `mapstructure:"max_connection_idle"`
+	// MaxConnectionAge 连接最长存活时间（秒），到期后服务端发送 GOAWAY 让客户端重连（重新负载均衡），0 表示不限制
+	MaxConnectionAge int `mapstructure:"max_connection_age"`
+	// MaxConnectionAgeGrace 连接到期后等待进行中请求完成的时间（秒），0 表示一直等待
+	MaxConnectionAgeGrace int `mapstructure:"max_connection_age_grace"`
+	// MaxConcurrentStreams 单个连接的最大并发请求数，0 表示不限制
+	MaxConcurrentStreams uint32 `mapstructure:"max_concurrent_streams"`
+	// Target 网关访问 gRPC 服务的地址（HTTP 转码使用）
+	Target string `mapstructure:"target"`
+	// CallTimeout 客户端调用的默认超时时间（秒），只用于没有截止时间的上下文，0 表示不限制；
+	// 请求上下文已有截止时间（如 timeout 中间件）时沿用该截止时间
+	CallTimeout int `mapstructure:"call_timeout"`
+	// Interceptors 通用拦截器链（recovery、request_id、logger、metrics），按顺序执行，
+	// 未配置时为 request_id、logger、metrics、recovery；认证拦截器始终在其后
+	Interceptors []string `mapstructure:"interceptors"`
+	// Reflection 是否注册反射服务（grpcurl、BloomRPC 等工具据此获取服务定义）
+	Reflection bool `mapstructure:"reflection"`
+	// HealthInterval 健康检查服务检查数据库和 Redis 的间隔（秒），默认 10
+	HealthInterval int `mapstructure:"health_interval"`
+
+	TLS  GRPCTLSConfig  `mapstructure:"tls"`
+	Auth GRPCAuthConfig `mapstructure:"auth"`
+}
+
+// GRPCTLSConfig gRPC TLS 配置
+type GRPCTLSConfig struct {
+	// Enable 是否启用 TLS（网关转码连接同时改用 TLS）
+	Enable bool `mapstructure:"enable"`
+	// CertFile 服务端证书
+	CertFile string `mapstructure:"cert_file"`
+	// KeyFile 服务端私钥
+	KeyFile string `mapstructure:"key_file"`
+	// ClientCAFile 签发客户端证书的 CA，配置后校验客户端证书（mTLS），证书 CN 可在 auth.client_certs 中映射角色
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// RequireClientCert 是否要求所有连接提供客户端证书，否则只在提供时校验
+	RequireClientCert bool `mapstructure:"require_client_cert"`
+	// CAFile 网关校验服务端证书使用的 CA，为空时使用系统根证书
+	CAFile string `mapstructure:"ca_file"`
+}
+
+// GRPCAuthConfig gRPC 认证配置
+// 调用方身份依次取自 metadata authorization（Bearer JWT）、x-api-key、已校验的客户端证书
+type GRPCAuthConfig struct {
+	// Required 是否拒绝未认证的调用（PublicMethods 除外）；为 false 时匿名调用可访问未声明角色要求的方法
+	Required bool `mapstructure:"required"`
+	// PublicMethods 无需认证的完整方法名，如 /grpc.health.v1.Health/Check
+	PublicMethods []string `mapstructure:"public_methods"`
+	// APIKeys 服务间调用的静态密钥
+	APIKeys []GRPCAPIKey `mapstructure:"api_keys"`
+	// ClientCerts 客户端证书 CN 到角色的映射，未列出的证书不作为身份
+	ClientCerts []GRPCClientCert `mapstructure:"client_certs"`
+}
+
+// GRPCAPIKey gRPC 调用方密钥
+type GRPCAPIKey struct {
+	// Name 调用方名称，作为上下文中的用户名
+	Name string `mapstructure:"name"`
+	// Key 密钥，通过 metadata x-api-key 传递
+	Key string `mapstructure:"key"`
+	// Role 角色
+	Role string `mapstructure:"role"`
+}
+
+// GRPCClientCert 客户端证书身份
+type GRPCClientCert struct {
+	// CommonName 证书主题 CN，作为上下文中的用户名
+	CommonName string `mapstructure:"common_name"`
+	// Role 角色
+	Role string `mapstructure:"role"`
 }
 
 // 全局配置实例
 var GlobalConfig *Config
 
-// Load 加载配置文件
+// Load 加载配置
+// 配置文件中的每一项都可用 MS_ 前缀的环境变量覆盖（如 MS_DATABASE_HOST）；
+// configPath 为空或设置 MS_CONFIG_MODE=env 时不读取配置文件，全部配置来自环境变量
 // 参数:
 //
 //	configPath: 配置文件路径
@@ -167,30 +992,66 @@ var GlobalConfig *Config
 //
 //	error: 错误信息
 func Load(configPath string) error {
-	viper.SetConfigFile(configPath)
-	viper.SetConfigType("yaml")
+	bindEnv()
 
-	// 支持环境变量覆盖配置
-	viper.AutomaticEnv()
+	envOnly = envOnlyMode(configPath)
+	if !envOnly {
+		viper.SetConfigFile(configPath)
+		viper.SetConfigType("yaml")
 
-	// 读取配置文件
-	if err := viper.ReadInConfig(); err != nil {
-		return fmt.Errorf("读取配置文件失败: %w", err)
+		// 读取配置文件
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("读取配置文件失败: %w", err)
+		}
 	}
 
 	// 解析配置到结构体
-	if err := viper.Unmarshal(&GlobalConfig); err != nil {
-		return fmt.Errorf("解析配置文件失败: %w", err)
+	var cfg Config
+	if err := unmarshal(&cfg); err != nil {
+		return fmt.Errorf("解析配置失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("配置校验失败:\n%w", err)
 	}
+	GlobalConfig = &cfg
 
 	return nil
 }
 
-// GetDatabaseDSN 获取数据库连接字符串
+// FeatureEnabled 检查功能模块是否启用
+// 参数:
+//
+//	name: 模块名称
+//
+// 返回:
+//
+//	bool: 未在 features 中配置的模块视为未启用
+func (c *Config) FeatureEnabled(name string) bool {
+	return c.Features[name]
+}
+
+// GetDatabaseDSN 获取主库连接字符串
 // 返回:
 //
-//	string: PostgreSQL 连接字符串
+//	string: 按驱动格式化的连接字符串，配置了 dsn 时直接返回
 func (c *DatabaseConfig) GetDatabaseDSN() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	switch c.GetDriver() {
+	case "mysql":
+		// 时间以 UTC 读写，与 gorm 的 NowFunc 一致
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=true&loc=UTC",
+			c.User, c.Password, c.Host, c.Port, c.DBName,
+		)
+	case "sqlite":
+		// 事务以 BEGIN IMMEDIATE 开始，写事务之间串行执行，锁冲突时最多等待 5 秒
+		return fmt.Sprintf(
+			"file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate",
+			c.DBName,
+		)
+	}
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
 		c.Host, c.Port, c.User, c.Password, c.DBName,
@@ -224,18 +1085,1133 @@ func (c *DatabaseConfig) GetConnMaxLifetime() time.Duration {
 	return time.Duration(c.ConnMaxLifetime) * time.Minute
 }
 
-// GetShutdownTimeout 获取优雅关闭超时时间
+// GetProcessTimeout 获取单条消息处理超时时间
 // 返回:
 //
-//	time.Duration: 超时时间
-func (c *ServerConfig) GetShutdownTimeout() time.Duration {
-	return time.Duration(c.ShutdownTimeout) * time.Second
+//	time.Duration: 超时时间（0 表示不限制）
+func (c *QueueConfig) GetProcessTimeout() time.Duration {
+	return time.Duration(c.ProcessTimeout) * time.Second
 }
 
-// GetPresignedExpire 获取预签名 URL 过期时间
+// GetMessageTTL 获取消息在队列中的最长等待时间
 // 返回:
 //
-//	time.Duration: 过期时间
-func (c *S3Config) GetPresignedExpire() time.Duration {
-	return time.Duration(c.PresignedExpire) * time.Minute
+//	time.Duration: 等待时间（0 表示不限制）
+func (c *QueueConfig) GetMessageTTL() time.Duration {
+	return time.Duration(c.MessageTTL) * time.Second
+}
+
+// GetPrefix 获取转存对象前缀
+// 返回:
+//
+//	string: 对象前缀，默认 claim-checks/
+func (c *ClaimCheckConfig) GetPrefix() string {
+	if c.Prefix == "" {
+		return "claim-checks/"
+	}
+	return c.Prefix
+}
+
+// GetExpire 获取转存对象保留时间
+// 返回:
+//
+//	time.Duration: 保留时间
+func (c *ClaimCheckConfig) GetExpire() time.Duration {
+	return time.Duration(c.Expire) * time.Hour
+}
+
+// GetClaimIdle 获取未确认消息的认领空闲时间
+// 返回:
+//
+//	time.Duration: 空闲时间
+func (c *RedisStreamsConfig) GetClaimIdle() time.Duration {
+	return time.Duration(c.ClaimIdle) * time.Second
+}
+
+// GetAlertWindow 获取慢消费告警统计窗口
+// 返回:
+//
+//	time.Duration: 统计窗口
+func (c *SlowConsumerConfig) GetAlertWindow() time.Duration {
+	return time.Duration(c.AlertWindow) * time.Second
+}
+
+// GetReconcileInterval 获取运行时配置同步间隔
+// 返回:
+//
+//	time.Duration: 同步间隔
+func (c *RuntimeSettingsConfig) GetReconcileInterval() time.Duration {
+	return time.Duration(c.ReconcileInterval) * time.Second
+}
+
+// GetCacheTTL 获取运行时配置缓存时间
+// 返回:
+//
+//	time.Duration: 缓存时间
+func (c *RuntimeSettingsConfig) GetCacheTTL() time.Duration {
+	return time.Duration(c.CacheTTL) * time.Second
+}
+
+// GetFlushInterval 获取活跃记录刷新间隔
+// 返回:
+//
+//	time.Duration: 刷新间隔
+func (c *ActivityConfig) GetFlushInterval() time.Duration {
+	return time.Duration(c.FlushInterval) * time.Second
+}
+
+// GetOnlineWindow 获取在线判定窗口
+// 返回:
+//
+//	time.Duration: 在线判定窗口
+func (c *ActivityConfig) GetOnlineWindow() time.Duration {
+	return time.Duration(c.OnlineWindow) * time.Second
+}
+
+// GetRetention 获取活跃记录保留时间
+// 返回:
+//
+//	time.Duration: 保留时间
+func (c *ActivityConfig) GetRetention() time.Duration {
+	return time.Duration(c.Retention) * time.Hour
+}
+
+// GetDefault 获取默认时区名称
+// 返回:
+//
+//	string: IANA 时区名称，未配置时为 UTC
+func (c *TimezoneConfig) GetDefault() string {
+	if c.Default == "" {
+		return "UTC"
+	}
+	return c.Default
+}
+
+// GetCacheTTL 获取用户设置缓存时间
+// 返回:
+//
+//	time.Duration: 缓存时间
+func (c *UserSettingsConfig) GetCacheTTL() time.Duration {
+	if c.CacheTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(c.CacheTTL) * time.Second
+}
+
+// GetMaxValueSize 获取单个设置值的最大字节数
+// 返回:
+//
+//	int: 最大字节数
+func (c *UserSettingsConfig) GetMaxValueSize() int {
+	if c.MaxValueSize <= 0 {
+		return 4096
+	}
+	return c.MaxValueSize
+}
+
+// GetSize 获取保留的失败请求数
+// 返回:
+//
+//	int: 请求数
+func (c *FailureCaptureConfig) GetSize() int {
+	if c.Size <= 0 {
+		return 100
+	}
+	return c.Size
+}
+
+// GetMaxBodySize 获取请求体、响应体保留的最大字节数
+// 返回:
+//
+//	int: 最大字节数
+func (c *FailureCaptureConfig) GetMaxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return 4096
+	}
+	return c.MaxBodySize
+}
+
+// GetRateLimit 获取限流降级策略
+// 返回:
+//
+//	string: local、open 或 closed
+func (c *RedisDegradationConfig) GetRateLimit() string {
+	if c.RateLimit == "" {
+		return "local"
+	}
+	return c.RateLimit
+}
+
+// GetRevocation 获取令牌吊销检查降级策略
+// 返回:
+//
+//	string: closed 或 open
+func (c *RedisDegradationConfig) GetRevocation() string {
+	if c.Revocation == "" {
+		return "closed"
+	}
+	return c.Revocation
+}
+
+// GetCache 获取读缓存降级策略
+// 返回:
+//
+//	string: bypass 或 fail
+func (c *RedisDegradationConfig) GetCache() string {
+	if c.Cache == "" {
+		return "bypass"
+	}
+	return c.Cache
+}
+
+// GetProbeInterval 获取降级期间检测 Redis 恢复的间隔
+// 返回:
+//
+//	time.Duration: 检测间隔
+func (c *RedisDegradationConfig) GetProbeInterval() time.Duration {
+	if c.ProbeInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.ProbeInterval) * time.Second
+}
+
+// GetChannel 获取变更通知频道
+// 返回:
+//
+//	string: 频道名称，默认 table_changes
+func (c *ChangeNotifyConfig) GetChannel() string {
+	if c.Channel == "" {
+		return "table_changes"
+	}
+	return c.Channel
+}
+
+// GetLockTimeout 获取等待迁移锁的最长时间
+// 返回:
+//
+//	time.Duration: 等待时间
+func (c *MigrationConfig) GetLockTimeout() time.Duration {
+	if c.LockTimeout <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(c.LockTimeout) * time.Second
+}
+
+// GetHealthInterval 获取 gRPC 健康检查服务检查依赖的间隔
+// 返回:
+//
+//	time.Duration: 检查间隔
+func (c *GRPCConfig) GetHealthInterval() time.Duration {
+	if c.HealthInterval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.HealthInterval) * time.Second
+}
+
+// GetWindow 获取配额统计周期
+// 返回:
+//
+//	time.Duration: 统计周期，0 表示累计不重置
+func (c *QuotaLimitConfig) GetWindow() time.Duration {
+	return time.Duration(c.Window) * time.Second
+}
+
+// GetAlertCooldown 获取配额提醒间隔
+// 返回:
+//
+//	time.Duration: 提醒间隔
+func (c *QuotaConfig) GetAlertCooldown() time.Duration {
+	return time.Duration(c.AlertCooldown) * time.Hour
+}
+
+// GetTimeout 获取 Webhook 请求超时时间
+// 返回:
+//
+//	time.Duration: 超时时间
+func (c *WebhookConfig) GetTimeout() time.Duration {
+	return time.Duration(c.Timeout) * time.Second
+}
+
+// GetAddr 获取 SMTP 服务器地址
+// 返回:
+//
+//	string: SMTP 地址 (host:port)
+func (c *EmailConfig) GetAddr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// GetInterval 获取缓存刷新扫描间隔
+// 返回:
+//
+//	time.Duration: 扫描间隔
+func (c *CacheRefreshConfig) GetInterval() time.Duration {
+	return time.Duration(c.Interval) * time.Second
+}
+
+// GetTTL 获取缓存时间
+// 返回:
+//
+//	time.Duration: 缓存时间
+func (c *CacheClassConfig) GetTTL() time.Duration {
+	return time.Duration(c.TTL) * time.Second
+}
+
+// GetRefreshAhead 获取提前刷新时间
+// 返回:
+//
+//	time.Duration: 提前刷新时间
+func (c *CacheClassConfig) GetRefreshAhead() time.Duration {
+	return time.Duration(c.RefreshAhead) * time.Second
+}
+
+// GetHotWindow 获取热点判定窗口
+// 返回:
+//
+//	time.Duration: 热点判定窗口
+func (c *CacheClassConfig) GetHotWindow() time.Duration {
+	return time.Duration(c.HotWindow) * time.Second
+}
+
+// GetInterval 获取指标推送间隔
+// 返回:
+//
+//	time.Duration: 推送间隔，未配置时为 60 秒
+func (c *MetricsExportConfig) GetInterval() time.Duration {
+	if c.Interval <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.Interval) * time.Second
+}
+
+// GetWindow 获取错误预算统计周期
+// 返回:
+//
+//	time.Duration: 统计周期，未配置时为 30 天
+func (c *SLOConfig) GetWindow() time.Duration {
+	if c.Window <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.Window) * time.Hour
+}
+
+// GetLatencyThreshold 获取延迟阈值
+// 返回:
+//
+//	time.Duration: 延迟阈值
+func (c *SLOObjectiveConfig) GetLatencyThreshold() time.Duration {
+	return time.Duration(c.LatencyThreshold) * time.Millisecond
+}
+
+// GetAnchorPrefix 获取审计锚点前缀
+// 返回:
+//
+//	string: 以 / 结尾的前缀
+func (c *AuditConfig) GetAnchorPrefix() string {
+	prefix := c.AnchorPrefix
+	if prefix == "" {
+		prefix = "audit-anchors/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
+
+// GetShutdownTimeout 获取优雅关闭超时时间
+// 返回:
+//
+//	time.Duration: 超时时间
+func (c *ServerConfig) GetShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeout) * time.Second
+}
+
+// GetPort 获取 HTTP/3 监听端口
+// 参数:
+//
+//	gatewayPort: 网关端口，未单独配置时使用
+//
+// 返回:
+//
+//	int: UDP 端口
+func (c *HTTP3Config) GetPort(gatewayPort int) int {
+	if c.Port > 0 {
+		return c.Port
+	}
+	return gatewayPort
+}
+
+// GetAltSvcMaxAge 获取 Alt-Svc 有效期，默认 24 小时
+// 返回:
+//
+//	time.Duration: 有效期
+func (c *HTTP3Config) GetAltSvcMaxAge() time.Duration {
+	if c.AltSvcMaxAge <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.AltSvcMaxAge) * time.Second
+}
+
+// GetHeaderTimeout 获取读取 PROXY 协议头的超时，默认 5 秒
+// 返回:
+//
+//	time.Duration: 超时时间
+func (c *ProxyConfig) GetHeaderTimeout() time.Duration {
+	if c.HeaderTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.HeaderTimeout) * time.Millisecond
+}
+
+// GetClientIPHeader 获取代理传递客户端地址使用的请求头
+// 返回:
+//
+//	string: x-forwarded-for 或 forwarded，未配置时为 x-forwarded-for
+func (c *ProxyConfig) GetClientIPHeader() string {
+	if c.ClientIPHeader == "" {
+		return ClientIPHeaderXForwardedFor
+	}
+	return strings.ToLower(c.ClientIPHeader)
+}
+
+// GetPresignedExpire 获取预签名 URL 过期时间
+// 返回:
+//
+//	time.Duration: 过期时间
+func (c *S3Config) GetPresignedExpire() time.Duration {
+	return time.Duration(c.PresignedExpire) * time.Minute
+}
+
+// GetMaxFiles 获取单次打包的最大文件数
+// 返回:
+//
+//	int: 最大文件数，默认 1000
+func (c *ArchiveConfig) GetMaxFiles() int {
+	if c.MaxFiles <= 0 {
+		return 1000
+	}
+	return c.MaxFiles
+}
+
+// GetMaxSize 获取单次打包的文件总大小上限
+// 返回:
+//
+//	int64: 字节数，默认 2GB
+func (c *ArchiveConfig) GetMaxSize() int64 {
+	if c.MaxSize <= 0 {
+		return 2048 << 20
+	}
+	return int64(c.MaxSize) << 20
+}
+
+// GetStreamMaxSize 获取直接流式下载的大小上限
+// 返回:
+//
+//	int64: 字节数，默认 200MB
+func (c *ArchiveConfig) GetStreamMaxSize() int64 {
+	if c.StreamMaxSize <= 0 {
+		return 200 << 20
+	}
+	return int64(c.StreamMaxSize) << 20
+}
+
+// GetPrefix 获取异步打包结果的对象前缀
+// 返回:
+//
+//	string: 对象前缀，默认 archives/
+func (c *ArchiveConfig) GetPrefix() string {
+	if c.Prefix == "" {
+		return "archives/"
+	}
+	return c.Prefix
+}
+
+// GetExpire 获取异步打包结果的保留时间
+// 返回:
+//
+//	time.Duration: 保留时间，默认 24 小时
+func (c *ArchiveConfig) GetExpire() time.Duration {
+	if c.Expire <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.Expire) * time.Hour
+}
+
+// ForRole 获取角色的上传限制
+// 参数:
+//
+//	role: 用户角色，未登录为空
+//
+// 返回:
+//
+//	int: 最大并发上传数，小于等于 0 表示不限制
+//	int64: 每个上传的速率（字节/秒），小于等于 0 表示不限制
+func (c *UploadLimitsConfig) ForRole(role string) (int, int64) {
+	maxConcurrent, bytesPerSecond := c.MaxConcurrent, c.BytesPerSecond
+	if tier, ok := c.Tiers[role]; ok {
+		if tier.MaxConcurrent != 0 {
+			maxConcurrent = tier.MaxConcurrent
+		}
+		if tier.BytesPerSecond != 0 {
+			bytesPerSecond = tier.BytesPerSecond
+		}
+	}
+	return maxConcurrent, bytesPerSecond
+}
+
+// GetSlotTTL 获取并发槽位的最长占用时间
+// 返回:
+//
+//	time.Duration: 占用时间，默认 10 分钟
+func (c *UploadLimitsConfig) GetSlotTTL() time.Duration {
+	if c.SlotTTL <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(c.SlotTTL) * time.Second
+}
+
+// UsesDefaultSecret 是否未配置签名密钥（或仍为占位密钥）
+func (c *JWTConfig) UsesDefaultSecret() bool {
+	return c.Secret == "" || c.Secret == DefaultJWTSecret
+}
+
+// GetSecret 获取签名密钥
+// 返回:
+//
+//	[]byte: 签名密钥，未配置时为 DefaultJWTSecret
+func (c *JWTConfig) GetSecret() []byte {
+	if c.Secret == "" {
+		return []byte(DefaultJWTSecret)
+	}
+	return []byte(c.Secret)
+}
+
+// GetIssuer 获取签发者
+// 返回:
+//
+//	string: 签发者，默认 microservice
+func (c *JWTConfig) GetIssuer() string {
+	if c.Issuer == "" {
+		return "microservice"
+	}
+	return c.Issuer
+}
+
+// GetAccessExpire 获取访问令牌有效期
+// 返回:
+//
+//	time.Duration: 有效期，默认 24 小时
+func (c *JWTConfig) GetAccessExpire() time.Duration {
+	if c.AccessExpire <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.AccessExpire) * time.Minute
+}
+
+// GetRefreshExpire 获取刷新令牌有效期
+// 返回:
+//
+//	time.Duration: 有效期，默认 7 天
+func (c *JWTConfig) GetRefreshExpire() time.Duration {
+	if c.RefreshExpire <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return time.Duration(c.RefreshExpire) * time.Hour
+}
+
+// GetAlgorithm 获取签名算法
+// 返回:
+//
+//	string: 算法名称，默认 HS256
+func (c *JWTConfig) GetAlgorithm() string {
+	if c.Algorithm == "" {
+		return "HS256"
+	}
+	return c.Algorithm
+}
+
+// Asymmetric 是否使用非对称签名算法（RS*、ES*），此时不使用共享密钥
+// 返回:
+//
+//	bool: 是否为非对称算法
+func (c *JWTConfig) Asymmetric() bool {
+	alg := c.GetAlgorithm()
+	return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "ES")
+}
+
+// GetJWKSRefresh 获取公钥集缓存时间
+// 返回:
+//
+//	time.Duration: 缓存时间，默认 60 分钟
+func (c *JWTConfig) GetJWKSRefresh() time.Duration {
+	if c.JWKSRefresh <= 0 {
+		return time.Hour
+	}
+	return time.Duration(c.JWKSRefresh) * time.Minute
+}
+
+// GetAlgorithm 获取新密码使用的哈希算法
+// 返回:
+//
+//	string: 算法名称，默认 argon2id
+func (c *PasswordConfig) GetAlgorithm() string {
+	if c.Algorithm == "" {
+		return "argon2id"
+	}
+	return c.Algorithm
+}
+
+// GetBcryptCost 获取 bcrypt 计算成本
+// 返回:
+//
+//	int: 计算成本，默认 12
+func (c *PasswordConfig) GetBcryptCost() int {
+	if c.BcryptCost <= 0 {
+		return 12
+	}
+	return c.BcryptCost
+}
+
+// GetMaxAttempts 获取锁定前允许的连续失败次数
+// 返回:
+//
+//	int: 失败次数，默认 5
+func (c *LockoutConfig) GetMaxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return 5
+	}
+	return c.MaxAttempts
+}
+
+// GetWindow 获取失败次数的统计窗口
+// 返回:
+//
+//	time.Duration: 统计窗口，默认 15 分钟
+func (c *LockoutConfig) GetWindow() time.Duration {
+	if c.Window <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.Window) * time.Minute
+}
+
+// GetDuration 获取锁定时长
+// 返回:
+//
+//	time.Duration: 锁定时长，默认 15 分钟
+func (c *LockoutConfig) GetDuration() time.Duration {
+	if c.Duration <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.Duration) * time.Minute
+}
+
+// Prefixes 解析客户端的 IP 地址和 CIDR 网段，单个地址视为 /32（IPv6 为 /128）
+// 返回:
+//
+//	[]netip.Prefix: 网段列表
+//	error: 格式无效时返回错误
+func (c *RateLimitClient) Prefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(c.IPs))
+	for _, s := range c.IPs {
+		if strings.Contains(s, "/") {
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("无效的 CIDR %q", s)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 IP %q", s)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// job 按名称查找任务配置
+func (c *CronConfig) job(name string) *JobConfig {
+	for i := range c.Jobs {
+		if c.Jobs[i].Name == name {
+			return &c.Jobs[i]
+		}
+	}
+	return nil
+}
+
+// GetJobTimeout 获取任务的超时时间，任务未单独配置时使用 job_timeout
+// 参数:
+//
+//	name: 任务名称
+//
+// 返回:
+//
+//	time.Duration: 超时时间（0 表示不限制）
+func (c *CronConfig) GetJobTimeout(name string) time.Duration {
+	timeout := c.JobTimeout
+	if job := c.job(name); job != nil && job.Timeout > 0 {
+		timeout = job.Timeout
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// GetMaxFailures 获取任务自动禁用前允许的连续失败次数，任务未单独配置时使用 max_failures
+// 参数:
+//
+//	name: 任务名称
+//
+// 返回:
+//
+//	int: 连续失败次数（0 表示不自动禁用）
+func (c *CronConfig) GetMaxFailures(name string) int {
+	if job := c.job(name); job != nil && job.MaxFailures > 0 {
+		return job.MaxFailures
+	}
+	return c.MaxFailures
+}
+
+// PublishAllowed 队列是否允许通过 HTTP / gRPC 接口发布消息
+// 参数:
+//
+//	queue: 队列名称
+//
+// 返回:
+//
+//	bool: publish_allowlist 为空或包含该队列时为 true
+func (c *RabbitMQConfig) PublishAllowed(queue string) bool {
+	return len(c.PublishAllowlist) == 0 || slices.Contains(c.PublishAllowlist, queue)
+}
+
+// GetReplicaCheckInterval 获取只读副本健康检查间隔
+// 返回:
+//
+//	time.Duration: 检查间隔，未配置时为 5 秒
+func (c *DatabaseConfig) GetReplicaCheckInterval() time.Duration {
+	if c.ReplicaCheckInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.ReplicaCheckInterval) * time.Second
+}
+
+// GetDriver 获取数据库驱动
+// 返回:
+//
+//	string: postgres、mysql 或 sqlite，未配置时为 postgres
+func (c *DatabaseConfig) GetDriver() string {
+	if c.Driver == "" {
+		return "postgres"
+	}
+	return c.Driver
+}
+
+// GetSlowQueryThreshold 获取慢查询阈值
+// 返回:
+//
+//	time.Duration: 阈值
+func (c *DatabaseConfig) GetSlowQueryThreshold() time.Duration {
+	if c.SlowQueryThreshold <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(c.SlowQueryThreshold) * time.Millisecond
+}
+
+// GetPurgeAfter 获取软删除用户的保留时间
+// 返回:
+//
+//	time.Duration: 保留时间，未配置时为 30 天
+func (c *UsersConfig) GetPurgeAfter() time.Duration {
+	if c.PurgeAfterDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.PurgeAfterDays) * 24 * time.Hour
+}
+
+// GetSize 获取进程内缓存的最大键数
+// 返回:
+//
+//	int: 最大键数，未配置时为 10000
+func (c *LocalCacheConfig) GetSize() int {
+	if c.Size <= 0 {
+		return 10000
+	}
+	return c.Size
+}
+
+// GetTTL 获取进程内缓存时间
+// 返回:
+//
+//	time.Duration: 缓存时间，未配置时为 10 秒
+func (c *LocalCacheConfig) GetTTL() time.Duration {
+	if c.TTL <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.TTL) * time.Second
+}
+
+// GetTTL 获取幂等响应的保存时间
+// 返回:
+//
+//	time.Duration: 保存时间，未配置时为 24 小时
+func (c *IdempotencyConfig) GetTTL() time.Duration {
+	if c.TTL <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.TTL) * time.Second
+}
+
+// GetLockTTL 获取处理中的请求占用键的最长时间
+// 返回:
+//
+//	time.Duration: 占用时间，未配置时为 60 秒
+func (c *IdempotencyConfig) GetLockTTL() time.Duration {
+	if c.LockTTL <= 0 {
+		return time.Minute
+	}
+	return time.Duration(c.LockTTL) * time.Second
+}
+
+// GetMaxBodySize 获取保存的响应体上限
+// 返回:
+//
+//	int: 字节数，未配置时为 1 MiB
+func (c *IdempotencyConfig) GetMaxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return 1 << 20
+	}
+	return c.MaxBodySize
+}
+
+// GetTimeout 获取路由的超时时间，未单独配置的路由使用 default
+// 参数:
+//
+//	method: HTTP 方法
+//	path: 路由模板
+//
+// 返回:
+//
+//	time.Duration: 超时时间（0 表示不限制）
+func (c *TimeoutConfig) GetTimeout(method, path string) time.Duration {
+	timeout := c.Default
+	for _, route := range c.Routes {
+		if route.Path == path && (route.Method == "" || strings.EqualFold(route.Method, method)) {
+			timeout = route.Timeout
+			break
+		}
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// GetFailureThreshold 获取熔断前允许的连续失败次数
+// 返回:
+//
+//	int: 次数，未配置时为 5
+func (c *CircuitBreakerConfig) GetFailureThreshold() int {
+	if c.FailureThreshold <= 0 {
+		return 5
+	}
+	return c.FailureThreshold
+}
+
+// GetOpenTimeout 获取熔断持续时间
+// 返回:
+//
+//	time.Duration: 持续时间，未配置时为 30 秒
+func (c *CircuitBreakerConfig) GetOpenTimeout() time.Duration {
+	if c.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.OpenTimeout) * time.Second
+}
+
+// GetHalfOpenRequests 获取半开状态下的探测请求数
+// 返回:
+//
+//	int: 请求数，未配置时为 1
+func (c *CircuitBreakerConfig) GetHalfOpenRequests() int {
+	if c.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return c.HalfOpenRequests
+}
+
+// GetStartupTimeout 获取启动时等待依赖就绪的最长时间
+// 返回:
+//
+//	time.Duration: 等待时间，0 表示不等待
+func (c *RetryConfig) GetStartupTimeout() time.Duration {
+	return time.Duration(c.StartupTimeout) * time.Second
+}
+
+// GetInitialInterval 获取首次重试前的等待时间
+// 返回:
+//
+//	time.Duration: 等待时间，未配置时为 200 毫秒
+func (c *RetryConfig) GetInitialInterval() time.Duration {
+	if c.InitialInterval <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(c.InitialInterval) * time.Millisecond
+}
+
+// GetMaxInterval 获取重试间隔上限
+// 返回:
+//
+//	time.Duration: 间隔上限，未配置时为 10 秒
+func (c *RetryConfig) GetMaxInterval() time.Duration {
+	if c.MaxInterval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.MaxInterval) * time.Second
+}
+
+// GetAttempts 获取暂时性失败的最多尝试次数
+// 返回:
+//
+//	int: 尝试次数（含首次），未配置时为 3
+func (c *RetryConfig) GetAttempts() int {
+	if c.Attempts <= 0 {
+		return 3
+	}
+	return c.Attempts
+}
+
+// RotationFor 获取输出文件的切割策略
+// 参数:
+//
+//	path: 输出路径
+//
+// 返回:
+//
+//	LogRotationConfig: files 中配置了该路径时为其策略，否则为 rotation
+func (c *LoggerConfig) RotationFor(path string) LogRotationConfig {
+	for _, f := range c.Files {
+		if f.Path == path {
+			return f.LogRotationConfig
+		}
+	}
+	return c.Rotation
+}
+
+// GetMaxSize 获取单个日志文件的大小上限
+// 返回:
+//
+//	int64: 字节数，未配置时为 100MB
+func (c *LogRotationConfig) GetMaxSize() int64 {
+	if c.MaxSize <= 0 {
+		return 100 << 20
+	}
+	return int64(c.MaxSize) << 20
+}
+
+// GetMaxAge 获取旧日志文件的保留时长
+// 返回:
+//
+//	time.Duration: 保留时长，0 表示不按时间删除
+func (c *LogRotationConfig) GetMaxAge() time.Duration {
+	return time.Duration(c.MaxAge) * 24 * time.Hour
+}
+
+// GetRoutingKey 获取审计记录发布的路由键
+// 返回:
+//
+//	string: 路由键，未配置时为 audit.events
+func (c *AuditStreamConfig) GetRoutingKey() string {
+	if c.RoutingKey == "" {
+		return "audit.events"
+	}
+	return c.RoutingKey
+}
+
+// GetMaxBodySize 获取请求日志中请求体、响应体各记录的最大字节数
+// 返回:
+//
+//	int: 字节数，未配置时为 2048
+func (c *RequestLogConfig) GetMaxBodySize() int {
+	if c.MaxBodySize <= 0 {
+		return 2048
+	}
+	return c.MaxBodySize
+}
+
+// GetSampleRate 获取记录请求体、响应体的请求比例
+// 返回:
+//
+//	float64: 比例（0~1），未配置时为 1
+func (c *RequestLogConfig) GetSampleRate() float64 {
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		return 1
+	}
+	return c.SampleRate
+}
+
+// GetFrameOptions 获取 X-Frame-Options
+// 返回:
+//
+//	string: 配置值，未配置时为 DENY
+func (c *SecurityHeadersConfig) GetFrameOptions() string {
+	if c.FrameOptions == "" {
+		return "DENY"
+	}
+	return c.FrameOptions
+}
+
+// GetReferrerPolicy 获取 Referrer-Policy
+// 返回:
+//
+//	string: 配置值，未配置时为 strict-origin-when-cross-origin
+func (c *SecurityHeadersConfig) GetReferrerPolicy() string {
+	if c.ReferrerPolicy == "" {
+		return "strict-origin-when-cross-origin"
+	}
+	return c.ReferrerPolicy
+}
+
+// HSTS 获取 Strict-Transport-Security 的值
+// 返回:
+//
+//	string: 头的值，未配置 hsts_max_age 时为空
+func (c *SecurityHeadersConfig) HSTS() string {
+	if c.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", c.HSTSMaxAge)
+	if c.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if c.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
+
+// GetCookieName 获取 CSRF 令牌 Cookie 名称
+// 返回:
+//
+//	string: 名称，未配置时为 csrf_token
+func (c *CSRFConfig) GetCookieName() string {
+	if c.CookieName == "" {
+		return "csrf_token"
+	}
+	return c.CookieName
+}
+
+// GetHeaderName 获取提交 CSRF 令牌的请求头
+// 返回:
+//
+//	string: 请求头名称，未配置时为 X-CSRF-Token
+func (c *CSRFConfig) GetHeaderName() string {
+	if c.HeaderName == "" {
+		return "X-CSRF-Token"
+	}
+	return c.HeaderName
+}
+
+// GetCookiePath 获取 CSRF 令牌 Cookie 的 Path
+// 返回:
+//
+//	string: Path，未配置时为 /
+func (c *CSRFConfig) GetCookiePath() string {
+	if c.CookiePath == "" {
+		return "/"
+	}
+	return c.CookiePath
+}
+
+// GetSameSite 获取 CSRF 令牌 Cookie 的 SameSite
+// 返回:
+//
+//	http.SameSite: SameSite，未配置时为 Lax
+func (c *CSRFConfig) GetSameSite() http.SameSite {
+	switch c.SameSite {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// GetMaxAge 获取 CSRF 令牌 Cookie 有效期
+// 返回:
+//
+//	time.Duration: 有效期，未配置时为 24 小时
+func (c *CSRFConfig) GetMaxAge() time.Duration {
+	if c.MaxAge <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(c.MaxAge) * time.Second
+}
+
+// GetLevel 获取响应压缩级别
+// 返回:
+//
+//	int: 压缩级别，未配置时为 6
+func (c *CompressionConfig) GetLevel() int {
+	if c.Level <= 0 {
+		return 6
+	}
+	return c.Level
+}
+
+// GetMinSize 获取压缩的最小响应体大小
+// 返回:
+//
+//	int: 字节数，未配置时为 1024
+func (c *CompressionConfig) GetMinSize() int {
+	if c.MinSize <= 0 {
+		return 1024
+	}
+	return c.MinSize
+}
+
+// GetContentTypes 获取压缩的内容类型
+// 返回:
+//
+//	[]string: 内容类型，未配置时为 application/json、application/problem+json、text/plain
+func (c *CompressionConfig) GetContentTypes() []string {
+	if len(c.ContentTypes) == 0 {
+		return []string{"application/json", "application/problem+json", "text/plain"}
+	}
+	return c.ContentTypes
+}
+
+// GetMaxSize 获取路由的请求体大小上限
+// 参数:
+//
+//	method: HTTP 方法
+//	path: 路由模板（gin.Context.FullPath）
+//
+// 返回:
+//
+//	int64: 上限（字节），0 表示不限制
+func (c *BodyLimitConfig) GetMaxSize(method, path string) int64 {
+	for _, route := range c.Routes {
+		if route.Path == path && (route.Method == "" || strings.EqualFold(route.Method, method)) {
+			return route.MaxSize
+		}
+	}
+	return c.MaxSize
+}
+
+// TypeAllowed 检查上传文件的内容类型是否允许
+// 参数:
+//
+//	contentType: 内容类型，可带参数（如 text/plain; charset=utf-8）
+//
+// 返回:
+//
+//	bool: 未配置 allowed_types 或匹配其中一项时为 true
+func (c *S3Config) TypeAllowed(contentType string) bool {
+	if len(c.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, t := range c.AllowedTypes {
+		t = strings.ToLower(t)
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
 }
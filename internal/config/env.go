@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 环境变量前缀，配置项按层级用下划线连接并转为大写，如 database.host 对应 MS_DATABASE_HOST
+const EnvPrefix = "MS"
+
+// EnvConfigMode 配置来源环境变量，设为 env 时不读取配置文件，全部配置来自环境变量（适用于容器部署）
+const EnvConfigMode = EnvPrefix + "_CONFIG_MODE"
+
+// envOnly 当前是否为纯环境变量模式（没有可监听的配置文件）
+var envOnly bool
+
+// bindEnv 为配置结构体中的每个配置项绑定环境变量
+// viper 的 AutomaticEnv 只对配置文件中出现过的 Key 生效，纯环境变量模式下需要逐个绑定
+func bindEnv() {
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		_ = viper.BindEnv(key)
+	}
+}
+
+// configKeys 按 mapstructure 标签列出配置项，嵌套结构体展开为 a.b.c，
+// 切片和 map 作为一个整体（值为逗号分隔列表或 JSON）
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// unmarshal 解析配置到结构体，环境变量中的 JSON 字符串可用于 map 和结构体切片
+func unmarshal(dst interface{}) error {
+	return viper.Unmarshal(dst, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		jsonStringHook,
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)))
+}
+
+// jsonStringHook 将以 [ 或 { 开头的字符串按 JSON 解析后再解码到切片或 map，
+// 如 MS_CRON_JOBS='[{"name":"health_check","spec":"0 */5 * * * *","enabled":true}]'
+func jsonStringHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || (to.Kind() != reflect.Slice && to.Kind() != reflect.Map) {
+		return data, nil
+	}
+	s := strings.TrimSpace(data.(string))
+	if s == "" || (s[0] != '[' && s[0] != '{') {
+		return data, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("解析 JSON 配置值失败: %w", err)
+	}
+	return v, nil
+}
+
+// envOnlyMode 是否使用纯环境变量模式：未指定配置文件，或 MS_CONFIG_MODE=env
+func envOnlyMode(configPath string) bool {
+	return configPath == "" || strings.EqualFold(os.Getenv(EnvConfigMode), "env")
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadEnvOnly(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	t.Setenv("MS_SERVER_GATEWAY_PORT", "9090")
+	t.Setenv("MS_DATABASE_HOST", "db.internal")
+	t.Setenv("MS_DATABASE_PORT", "5432")
+	t.Setenv("MS_DATABASE_USER", "app")
+	t.Setenv("MS_DATABASE_DBNAME", "microservice")
+	t.Setenv("MS_REDIS_HOST", "redis.internal")
+	t.Setenv("MS_REDIS_PORT", "6379")
+	t.Setenv("MS_RABBITMQ_DRIVER", "redis_streams")
+	t.Setenv("MS_MIDDLEWARE_RATE_LIMIT_ENABLE", "true")
+	t.Setenv("MS_MIDDLEWARE_CORS_ALLOW_ORIGINS", "https://a.example.com,https://b.example.com")
+	t.Setenv("MS_SERVER_PROXY_TRUSTED_PROXIES", `["10.0.0.0/8"]`)
+	t.Setenv("MS_CRON_JOBS", `[{"name":"health_check","spec":"0 */5 * * * *","enabled":true}]`)
+	t.Setenv("MS_FEATURES", `{"upload":true,"users":false}`)
+
+	if err := Load(""); err != nil {
+		t.Fatal(err)
+	}
+	cfg := GlobalConfig
+
+	if cfg.Server.GatewayPort != 9090 || cfg.Database.Host != "db.internal" || !cfg.Middleware.RateLimit.Enable {
+		t.Errorf("标量配置未生效: port=%d host=%s", cfg.Server.GatewayPort, cfg.Database.Host)
+	}
+	if want := []string{"https://a.example.com", "https://b.example.com"}; !reflect.DeepEqual(cfg.Middleware.CORS.AllowOrigins, want) {
+		t.Errorf("AllowOrigins = %v, 期望 %v", cfg.Middleware.CORS.AllowOrigins, want)
+	}
+	if want := []string{"10.0.0.0/8"}; !reflect.DeepEqual(cfg.Server.Proxy.TrustedProxies, want) {
+		t.Errorf("TrustedProxies = %v, 期望 %v", cfg.Server.Proxy.TrustedProxies, want)
+	}
+	if len(cfg.Cron.Jobs) != 1 || cfg.Cron.Jobs[0].Name != "health_check" || !cfg.Cron.Jobs[0].Enabled {
+		t.Errorf("Cron.Jobs = %+v", cfg.Cron.Jobs)
+	}
+	if !cfg.FeatureEnabled("upload") || cfg.FeatureEnabled("users") {
+		t.Errorf("Features = %v", cfg.Features)
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadTestConfig)
+	t.Setenv("MS_LOGGER_LEVEL", "warn")
+	// 配置文件中没有的配置项同样可以通过环境变量设置
+	t.Setenv("MS_REDIS_HOST", "redis.internal")
+
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if GlobalConfig.Logger.Level != "warn" {
+		t.Errorf("Logger.Level = %s, 期望 warn", GlobalConfig.Logger.Level)
+	}
+	if GlobalConfig.Redis.Host != "redis.internal" {
+		t.Errorf("Redis.Host = %s, 期望 redis.internal", GlobalConfig.Redis.Host)
+	}
+	if GlobalConfig.Server.GatewayPort != 8080 {
+		t.Errorf("GatewayPort = %d, 期望 8080", GlobalConfig.Server.GatewayPort)
+	}
+
+	// MS_CONFIG_MODE=env 时忽略配置文件，缺少数据库配置无法通过校验
+	viper.Reset()
+	t.Setenv(EnvConfigMode, "env")
+	if err := Load(path); err == nil || !strings.Contains(err.Error(), "database.host") {
+		t.Errorf("纯环境变量模式读取了配置文件: err = %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// ErrRestartRequired 配置文件中有不支持热加载的修改，需要重启服务才能生效
+var ErrRestartRequired = errors.New("部分配置修改需要重启服务才能生效")
+
+// ReloadFunc 配置热加载回调
+// 参数:
+//
+//	old: 重新加载前的配置
+//	next: 重新加载后生效的配置（已是新的 GlobalConfig）
+type ReloadFunc func(old, next *Config)
+
+var (
+	reloadMu        sync.Mutex
+	reloadCallbacks []ReloadFunc
+)
+
+// OnReload 注册配置热加载回调，配置文件变化并重新加载后按注册顺序调用
+// 参数:
+//
+//	fn: 回调函数
+func OnReload(fn ReloadFunc) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadCallbacks = append(reloadCallbacks, fn)
+}
+
+// Watch 监听配置文件变化并自动热加载（需先调用 Load），纯环境变量模式下不做任何事
+// 参数:
+//
+//	onError: 重新加载失败或存在需要重启的修改时的回调（config 包不依赖日志，由调用方记录）
+func Watch(onError func(err error)) {
+	if envOnly {
+		return
+	}
+	viper.OnConfigChange(func(fsnotify.Event) {
+		if err := Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+	viper.WatchConfig()
+}
+
+// Reload 重新读取配置文件，只替换支持热加载的配置段：
+// 日志级别、限流、CORS、定时任务的启用状态。其余配置段保持不变，有修改时返回 ErrRestartRequired
+// 返回:
+//
+//	error: 读取或校验失败时配置保持不变；ErrRestartRequired 表示可热加载的部分已生效
+func Reload() error {
+	var next Config
+	if err := unmarshal(&next); err != nil {
+		return fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("配置校验失败，保持原配置:\n%w", err)
+	}
+
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := GlobalConfig
+	merged := *old
+	applyReloadable(&merged, &next)
+	GlobalConfig = &merged
+
+	for _, fn := range reloadCallbacks {
+		fn(old, &merged)
+	}
+
+	if !reflect.DeepEqual(merged, next) {
+		return ErrRestartRequired
+	}
+	return nil
+}
+
+// applyReloadable 将可热加载的配置段从 next 复制到 dst
+// 定时任务只更新已有任务的启用状态，新增、删除任务或修改表达式需要重启
+func applyReloadable(dst, next *Config) {
+	dst.Logger.Level = next.Logger.Level
+	dst.Middleware.RateLimit = next.Middleware.RateLimit
+	dst.Middleware.CORS = next.Middleware.CORS
+
+	enabled := make(map[string]bool, len(next.Cron.Jobs))
+	for _, job := range next.Cron.Jobs {
+		enabled[job.Name] = job.Enabled
+	}
+	jobs := make([]JobConfig, len(dst.Cron.Jobs))
+	for i, job := range dst.Cron.Jobs {
+		if v, ok := enabled[job.Name]; ok {
+			job.Enabled = v
+		}
+		jobs[i] = job
+	}
+	dst.Cron.Jobs = jobs
+}
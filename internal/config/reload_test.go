@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const reloadTestConfig = `
+server:
+  gateway_port: 8080
+database:
+  host: localhost
+  port: 5432
+  user: postgres
+  dbname: microservice
+redis:
+  host: localhost
+  port: 6379
+rabbitmq:
+  driver: redis_streams
+logger:
+  level: info
+middleware:
+  rate_limit:
+    enable: true
+    requests_per_second: 100
+    burst: 200
+cron:
+  enable: true
+  jobs:
+    - name: health_check
+      spec: "0 */5 * * * *"
+      enabled: true
+    - name: daily_statistics
+      spec: "0 0 1 * * *"
+      enabled: false
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, reloadTestConfig)
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var gotOld, gotNext *Config
+	OnReload(func(old, next *Config) {
+		calls++
+		gotOld, gotNext = old, next
+	})
+
+	// 只修改可热加载的配置段
+	updated := strings.NewReplacer(
+		"level: info", "level: debug",
+		"requests_per_second: 100", "requests_per_second: 10",
+		"enabled: false", "enabled: true",
+	).Replace(reloadTestConfig)
+	writeConfig(t, path, updated)
+	if err := readAndReload(); err != nil {
+		t.Fatalf("Reload = %v, 期望 nil", err)
+	}
+
+	if calls != 1 || gotNext != GlobalConfig {
+		t.Fatalf("回调次数 = %d, next 是否为 GlobalConfig = %v", calls, gotNext == GlobalConfig)
+	}
+	if gotOld.Logger.Level != "info" || GlobalConfig.Logger.Level != "debug" {
+		t.Errorf("日志级别 old=%s new=%s", gotOld.Logger.Level, GlobalConfig.Logger.Level)
+	}
+	if GlobalConfig.Middleware.RateLimit.RequestsPerSecond != 10 {
+		t.Errorf("限流 = %v, 期望 10", GlobalConfig.Middleware.RateLimit.RequestsPerSecond)
+	}
+	if !GlobalConfig.Cron.Jobs[1].Enabled || gotOld.Cron.Jobs[1].Enabled {
+		t.Error("任务启用状态未更新，或修改了旧配置")
+	}
+
+	// 修改不支持热加载的配置段：保持原值并提示重启
+	writeConfig(t, path, strings.Replace(updated, "gateway_port: 8080", "gateway_port: 9090", 1))
+	if err := readAndReload(); !errors.Is(err, ErrRestartRequired) {
+		t.Fatalf("Reload = %v, 期望 ErrRestartRequired", err)
+	}
+	if GlobalConfig.Server.GatewayPort != 8080 {
+		t.Errorf("网关端口 = %d, 期望保持 8080", GlobalConfig.Server.GatewayPort)
+	}
+}
+
+// readAndReload 模拟文件变更通知：viper 重新读取文件后调用 Reload
+func readAndReload() error {
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	return Reload()
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zhang/microservice/internal/jsonschema"
+)
+
+// cronParser 与定时任务服务一致的表达式解析器（含秒字段）
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// validator 收集校验错误，全部检查完后一次返回
+type validator struct {
+	errs []error
+}
+
+// check 条件不成立时记录错误
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+// port 校验端口范围
+func (v *validator) port(key string, port int) {
+	v.check(port > 0 && port <= 65535, key, "端口必须在 1-65535 之间，当前为 %d", port)
+}
+
+// notEmpty 校验必填字符串
+func (v *validator) notEmpty(key, value string) {
+	v.check(value != "", key, "不能为空")
+}
+
+// nonNegative 校验非负数
+func (v *validator) nonNegative(key string, value int) {
+	v.check(value >= 0, key, "不能为负数，当前为 %d", value)
+}
+
+// oneOf 校验枚举值
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.check(false, key, "无效的值 %q，可选 %v", value, allowed)
+}
+
+// rotation 校验日志文件切割配置
+func (v *validator) rotation(key string, r LogRotationConfig) {
+	v.nonNegative(key+".max_size", r.MaxSize)
+	v.nonNegative(key+".max_age", r.MaxAge)
+	v.nonNegative(key+".max_backups", r.MaxBackups)
+}
+
+// Validate 为未配置的项填充默认值（服务端口、连接池、超时），并校验配置
+// 依赖服务（数据库、Redis、RabbitMQ）的地址和端口必须显式配置，避免误连默认地址
+// 返回:
+//
+//	error: 全部校验错误合并为一个错误（errors.Join），nil 表示通过
+func (c *Config) Validate() error {
+	c.applyDefaults()
+
+	v := &validator{}
+
+	// 服务
+	v.port("server.gateway_port", c.Server.GatewayPort)
+	v.port("server.grpc_port", c.Server.GRPCPort)
+	v.check(c.Server.GatewayPort != c.Server.GRPCPort, "server.grpc_port", "不能与 gateway_port 相同")
+	v.oneOf("server.mode", c.Server.Mode, "", "debug", "release", "test")
+	v.nonNegative("server.shutdown_timeout", c.Server.ShutdownTimeout)
+	v.oneOf("server.proxy.client_ip_header", c.Server.Proxy.GetClientIPHeader(), ClientIPHeaderXForwardedFor, ClientIPHeaderForwarded)
+	if c.Server.HTTP3.Enable {
+		v.port("server.http3.port", c.Server.HTTP3.GetPort(c.Server.GatewayPort))
+		v.notEmpty("server.http3.cert_file", c.Server.HTTP3.CertFile)
+		v.notEmpty("server.http3.key_file", c.Server.HTTP3.KeyFile)
+	}
+
+	// 数据库，sqlite 只需要数据库文件路径，不支持只读副本和变更通知
+	v.oneOf("database.driver", c.Database.Driver, "", "postgres", "mysql", "sqlite")
+	if c.Database.DSN == "" {
+		if c.Database.Driver != "sqlite" {
+			v.notEmpty("database.host", c.Database.Host)
+			v.port("database.port", c.Database.Port)
+			v.notEmpty("database.user", c.Database.User)
+		}
+		v.notEmpty("database.dbname", c.Database.DBName)
+	}
+	v.check(c.Database.Driver != "sqlite" || len(c.Database.Replicas) == 0, "database.replicas", "sqlite 不支持只读副本")
+	v.check(c.Database.GetDriver() == "postgres" || !c.Database.Notify.Enable, "database.notify.enable", "仅 postgres 支持变更通知")
+	for i, dsn := range c.Database.Replicas {
+		v.notEmpty(fmt.Sprintf("database.replicas[%d]", i), dsn)
+	}
+	v.nonNegative("database.replica_check_interval", c.Database.ReplicaCheckInterval)
+	v.nonNegative("database.slow_query_threshold", c.Database.SlowQueryThreshold)
+	v.nonNegative("database.max_idle_conns", c.Database.MaxIdleConns)
+	v.nonNegative("database.max_open_conns", c.Database.MaxOpenConns)
+	v.check(c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "database.max_idle_conns", "不能大于 max_open_conns")
+	v.nonNegative("database.migration.lock_timeout", c.Database.Migration.LockTimeout)
+
+	// Redis
+	v.notEmpty("redis.host", c.Redis.Host)
+	v.port("redis.port", c.Redis.Port)
+	v.nonNegative("redis.db", c.Redis.DB)
+	v.nonNegative("redis.pool_size", c.Redis.PoolSize)
+	v.nonNegative("redis.min_idle_conns", c.Redis.MinIdleConns)
+
+	// 消息队列，redis_streams 驱动不需要 RabbitMQ
+	v.oneOf("rabbitmq.driver", c.RabbitMQ.Driver, "", "rabbitmq", "redis_streams")
+	if c.RabbitMQ.Driver == "" || c.RabbitMQ.Driver == "rabbitmq" {
+		v.notEmpty("rabbitmq.host", c.RabbitMQ.Host)
+		v.port("rabbitmq.port", c.RabbitMQ.Port)
+		v.notEmpty("rabbitmq.user", c.RabbitMQ.User)
+	}
+	for i, q := range c.RabbitMQ.Queues {
+		key := fmt.Sprintf("rabbitmq.queues[%d]", i)
+		v.nonNegative(key+".message_ttl", q.MessageTTL)
+		v.nonNegative(key+".max_length", q.MaxLength)
+		v.oneOf(key+".overflow", q.Overflow, "", "drop-head", "reject-publish")
+	}
+	// 超大消息转存：清理任务按前缀删除过期对象，前缀只有 / 时会删除整个存储桶中的对象
+	if cc := c.RabbitMQ.ClaimCheck; cc.Enable {
+		v.check(strings.Trim(cc.GetPrefix(), "/") != "", "rabbitmq.claim_check.prefix", "不能为空或只有 /")
+		v.check(cc.Expire > 0, "rabbitmq.claim_check.expire", "必须大于 0，当前为 %d", cc.Expire)
+	}
+
+	// 日志
+	v.oneOf("logger.level", c.Logger.Level, "debug", "info", "warn", "error")
+	v.oneOf("logger.format", c.Logger.Format, "", "json", "console")
+	v.rotation("logger.rotation", c.Logger.Rotation)
+	for i, f := range c.Logger.Files {
+		key := fmt.Sprintf("logger.files[%d]", i)
+		v.notEmpty(key+".path", f.Path)
+		v.rotation(key, f.LogRotationConfig)
+	}
+
+	// Redis 降级策略
+	d := c.Redis.Degradation
+	v.oneOf("redis.degradation.rate_limit", d.RateLimit, "", "local", "open", "closed")
+	v.oneOf("redis.degradation.revocation", d.Revocation, "", "closed", "open")
+	v.oneOf("redis.degradation.cache", d.Cache, "", "bypass", "fail")
+	v.nonNegative("redis.degradation.probe_interval", d.ProbeInterval)
+	v.nonNegative("redis.refresh.local.size", c.Redis.Refresh.Local.Size)
+	v.nonNegative("redis.refresh.local.ttl", c.Redis.Refresh.Local.TTL)
+
+	// CORS
+	for i, origin := range c.Middleware.CORS.AllowOrigins {
+		v.check(ValidCORSOrigin(origin), fmt.Sprintf("middleware.cors.allow_origins[%d]", i),
+			"%q 格式不正确，应为 *、https://app.example.com 或 https://*.example.com", origin)
+	}
+	v.nonNegative("middleware.cors.max_age", c.Middleware.CORS.MaxAge)
+
+	// 限流
+	rl := c.Middleware.RateLimit
+	for name, tier := range rl.Tiers {
+		key := "middleware.rate_limit.tiers." + name
+		v.check(tier.Exempt || tier.RequestsPerSecond > 0, key+".requests_per_second", "非 exempt 等级必须大于 0")
+		v.nonNegative(key+".burst", tier.Burst)
+	}
+	clients := make(map[string]bool, len(rl.Allowlist))
+	for i, client := range rl.Allowlist {
+		key := fmt.Sprintf("middleware.rate_limit.allowlist[%d]", i)
+		v.notEmpty(key+".name", client.Name)
+		v.check(!clients[client.Name], key+".name", "客户端名称 %q 重复", client.Name)
+		clients[client.Name] = true
+		_, ok := rl.Tiers[client.Tier]
+		v.check(ok, key+".tier", "tiers 中没有等级 %q", client.Tier)
+		v.check(len(client.APIKeys)+len(client.Subjects)+len(client.IPs) > 0, key, "至少配置 api_keys、subjects、ips 之一")
+		_, err := client.Prefixes()
+		v.check(err == nil, key+".ips", "%v", err)
+	}
+
+	// 失败请求记录
+	v.nonNegative("middleware.failure_capture.size", c.Middleware.FailureCapture.Size)
+	v.nonNegative("middleware.failure_capture.max_body_size", c.Middleware.FailureCapture.MaxBodySize)
+	v.nonNegative("middleware.request_log.max_body_size", c.Middleware.RequestLog.MaxBodySize)
+	v.check(c.Middleware.RequestLog.SampleRate >= 0 && c.Middleware.RequestLog.SampleRate <= 1,
+		"middleware.request_log.sample_rate", "应在 0 到 1 之间，当前为 %v", c.Middleware.RequestLog.SampleRate)
+	v.nonNegative("middleware.idempotency.ttl", c.Middleware.Idempotency.TTL)
+	v.nonNegative("middleware.idempotency.lock_ttl", c.Middleware.Idempotency.LockTTL)
+	v.nonNegative("middleware.idempotency.max_body_size", c.Middleware.Idempotency.MaxBodySize)
+	v.nonNegative("middleware.timeout.default", c.Middleware.Timeout.Default)
+	v.nonNegative("middleware.security_headers.hsts_max_age", c.Middleware.SecurityHeaders.HSTSMaxAge)
+	v.oneOf("middleware.security_headers.frame_options", c.Middleware.SecurityHeaders.FrameOptions, "", "DENY", "SAMEORIGIN")
+	v.oneOf("middleware.csrf.same_site", c.Middleware.CSRF.SameSite, "", "lax", "strict", "none")
+	v.check(c.Middleware.CSRF.SameSite != "none" || c.Middleware.CSRF.Secure,
+		"middleware.csrf.same_site", "为 none 时 secure 必须开启（浏览器会拒绝该 Cookie）")
+	v.nonNegative("middleware.csrf.max_age", c.Middleware.CSRF.MaxAge)
+	v.check(c.Middleware.Compression.Level >= 0 && c.Middleware.Compression.Level <= 9,
+		"middleware.compression.level", "应在 1 到 9 之间（0 表示默认），当前为 %d", c.Middleware.Compression.Level)
+	v.nonNegative("middleware.compression.min_size", c.Middleware.Compression.MinSize)
+	v.check(c.Middleware.BodyLimit.MaxSize >= 0, "middleware.body_limit.max_size", "不能为负数，当前为 %d", c.Middleware.BodyLimit.MaxSize)
+	for i, route := range c.Middleware.BodyLimit.Routes {
+		key := fmt.Sprintf("middleware.body_limit.routes[%d]", i)
+		v.notEmpty(key+".path", route.Path)
+		v.check(route.MaxSize >= 0, key+".max_size", "不能为负数，当前为 %d", route.MaxSize)
+	}
+	v.check(c.AWS.S3.MaxFileSize >= 0, "aws.s3.max_file_size", "不能为负数，当前为 %d", c.AWS.S3.MaxFileSize)
+	for i, route := range c.Middleware.Timeout.Routes {
+		key := fmt.Sprintf("middleware.timeout.routes[%d]", i)
+		v.notEmpty(key+".path", route.Path)
+		v.nonNegative(key+".timeout", route.Timeout)
+	}
+
+	// gRPC 拦截器
+	interceptors := make(map[string]bool, len(c.GRPC.Interceptors))
+	for i, name := range c.GRPC.Interceptors {
+		key := fmt.Sprintf("grpc.interceptors[%d]", i)
+		v.oneOf(key, name, "recovery", "request_id", "logger", "metrics")
+		v.check(!interceptors[name], key, "拦截器 %q 重复", name)
+		interceptors[name] = true
+	}
+	v.nonNegative("grpc.health_interval", c.GRPC.HealthInterval)
+	v.nonNegative("grpc.call_timeout", c.GRPC.CallTimeout)
+
+	// gRPC 认证
+	if c.GRPC.TLS.Enable {
+		v.notEmpty("grpc.tls.cert_file", c.GRPC.TLS.CertFile)
+		v.notEmpty("grpc.tls.key_file", c.GRPC.TLS.KeyFile)
+	}
+	v.check(!c.GRPC.TLS.RequireClientCert || c.GRPC.TLS.ClientCAFile != "", "grpc.tls.client_ca_file", "require_client_cert 需要配置客户端 CA")
+	v.check(len(c.GRPC.Auth.ClientCerts) == 0 || c.GRPC.TLS.ClientCAFile != "", "grpc.auth.client_certs", "需要配置 grpc.tls.client_ca_file")
+	apiKeys := make(map[string]bool, len(c.GRPC.Auth.APIKeys))
+	for i, k := range c.GRPC.Auth.APIKeys {
+		key := fmt.Sprintf("grpc.auth.api_keys[%d]", i)
+		v.notEmpty(key+".name", k.Name)
+		v.notEmpty(key+".key", k.Key)
+		v.notEmpty(key+".role", k.Role)
+		v.check(!apiKeys[k.Key], key+".key", "密钥重复")
+		apiKeys[k.Key] = true
+	}
+	for i, cert := range c.GRPC.Auth.ClientCerts {
+		key := fmt.Sprintf("grpc.auth.client_certs[%d]", i)
+		v.notEmpty(key+".common_name", cert.CommonName)
+		v.notEmpty(key+".role", cert.Role)
+	}
+
+	// 熔断
+	v.nonNegative("circuit_breaker.failure_threshold", c.CircuitBreaker.FailureThreshold)
+	v.nonNegative("circuit_breaker.open_timeout", c.CircuitBreaker.OpenTimeout)
+	v.nonNegative("circuit_breaker.half_open_requests", c.CircuitBreaker.HalfOpenRequests)
+
+	// 重试
+	v.nonNegative("retry.startup_timeout", c.Retry.StartupTimeout)
+	v.nonNegative("retry.initial_interval", c.Retry.InitialInterval)
+	v.nonNegative("retry.max_interval", c.Retry.MaxInterval)
+	v.nonNegative("retry.attempts", c.Retry.Attempts)
+
+	// 时区，Local 取决于部署机器，不允许使用
+	if _, err := time.LoadLocation(c.Timezone.GetDefault()); err != nil || c.Timezone.Default == "Local" {
+		v.check(false, "timezone.default", "无效的时区 %q", c.Timezone.Default)
+	}
+
+	// 用户
+	v.nonNegative("users.purge_after_days", c.Users.PurgeAfterDays)
+
+	// 用户设置
+	v.nonNegative("user_settings.cache_ttl", c.UserSettings.CacheTTL)
+	v.nonNegative("user_settings.max_value_size", c.UserSettings.MaxValueSize)
+	settingKeys := make(map[string]bool, len(c.UserSettings.Keys))
+	for i, k := range c.UserSettings.Keys {
+		key := fmt.Sprintf("user_settings.keys[%d]", i)
+		v.notEmpty(key+".name", k.Name)
+		v.check(!settingKeys[k.Name], key+".name", "设置项 %q 重复", k.Name)
+		settingKeys[k.Name] = true
+		if err := k.Schema.Check(); err != nil {
+			v.check(false, key+".schema", "%v", err)
+			continue
+		}
+		if k.Default != nil {
+			err := k.Schema.Validate(jsonschema.Normalize(k.Default))
+			v.check(err == nil, key+".default", "不符合 schema: %v", err)
+		}
+	}
+
+	// 定时任务
+	v.nonNegative("cron.job_timeout", c.Cron.JobTimeout)
+	v.nonNegative("cron.max_failures", c.Cron.MaxFailures)
+	names := make(map[string]bool, len(c.Cron.Jobs))
+	for i, job := range c.Cron.Jobs {
+		key := fmt.Sprintf("cron.jobs[%d]", i)
+		v.notEmpty(key+".name", job.Name)
+		v.check(!names[job.Name], key+".name", "任务名称 %q 重复", job.Name)
+		names[job.Name] = true
+		if _, err := cronParser.Parse(job.Spec); err != nil {
+			v.check(false, key+".spec", "无效的表达式 %q（需包含秒字段）: %v", job.Spec, err)
+		}
+		v.nonNegative(key+".timeout", job.Timeout)
+		v.nonNegative(key+".max_failures", job.MaxFailures)
+	}
+
+	// 安全
+	if c.Security.CurrentKeyVersion != "" {
+		_, ok := c.Security.EncryptionKeys[c.Security.CurrentKeyVersion]
+		v.check(ok, "security.current_key_version", "encryption_keys 中没有版本 %q", c.Security.CurrentKeyVersion)
+	}
+	v.oneOf("security.password.algorithm", c.Security.Password.Algorithm, "", "argon2id", "bcrypt")
+	if cost := c.Security.Password.BcryptCost; cost != 0 {
+		v.check(cost >= 4 && cost <= 31, "security.password.bcrypt_cost", "必须在 4-31 之间，当前为 %d", cost)
+	}
+
+	// JWT
+	v.oneOf("jwt.algorithm", c.JWT.Algorithm, "", "HS256", "HS384", "HS512",
+		"RS256", "RS384", "RS512", "ES256", "ES384", "ES512")
+	v.nonNegative("jwt.access_expire", c.JWT.AccessExpire)
+	v.nonNegative("jwt.refresh_expire", c.JWT.RefreshExpire)
+	v.nonNegative("jwt.jwks_refresh", c.JWT.JWKSRefresh)
+	if c.JWT.Asymmetric() {
+		v.check(c.JWT.PrivateKeyFile != "" || c.JWT.PublicKeyFile != "" || c.JWT.JWKSURL != "",
+			"jwt", "%s 算法需要配置 private_key_file、public_key_file 或 jwks_url", c.JWT.Algorithm)
+	} else if c.Server.Mode == "release" {
+		v.check(!c.JWT.UsesDefaultSecret(), "jwt.secret", "release 模式下必须配置签名密钥，不能使用默认值")
+	}
+
+	return errors.Join(v.errs...)
+}
+
+// applyDefaults 为未配置（零值）的项填充默认值
+func (c *Config) applyDefaults() {
+	setDefault(&c.Server.GatewayPort, 8080)
+	setDefault(&c.Server.GRPCPort, 50051)
+	setDefault(&c.Server.ShutdownTimeout, 30)
+
+	setDefault(&c.Database.MaxIdleConns, 10)
+	setDefault(&c.Database.MaxOpenConns, 100)
+	setDefault(&c.Database.ConnMaxLifetime, 60)
+
+	setDefault(&c.Redis.PoolSize, 10)
+
+	if c.RabbitMQ.Vhost == "" {
+		c.RabbitMQ.Vhost = "/"
+	}
+	if c.Logger.Level == "" {
+		c.Logger.Level = "info"
+	}
+}
+
+// setDefault 值为 0 时设置为默认值
+func setDefault(value *int, def int) {
+	if *value == 0 {
+		*value = def
+	}
+}
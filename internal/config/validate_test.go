@@ -0,0 +1,309 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/zhang/microservice/internal/jsonschema"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Database: DatabaseConfig{Host: "localhost", Port: 5432, User: "postgres", DBName: "microservice"},
+		Redis:    RedisConfig{Host: "localhost", Port: 6379},
+		RabbitMQ: RabbitMQConfig{Driver: "rabbitmq", Host: "localhost", Port: 5672, User: "guest"},
+		Cron: CronConfig{Jobs: []JobConfig{
+			{Name: "health_check", Spec: "0 */5 * * * *"},
+		}},
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	cfg := validConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.GatewayPort != 8080 || cfg.Server.GRPCPort != 50051 {
+		t.Errorf("端口默认值: gateway=%d grpc=%d", cfg.Server.GatewayPort, cfg.Server.GRPCPort)
+	}
+	if cfg.Database.MaxOpenConns != 100 || cfg.Redis.PoolSize != 10 || cfg.Server.ShutdownTimeout != 30 {
+		t.Errorf("连接池 / 超时默认值: %+v %+v", cfg.Database, cfg.Redis)
+	}
+	if cfg.Logger.Level != "info" || cfg.RabbitMQ.Vhost != "/" {
+		t.Errorf("日志级别 = %q, vhost = %q", cfg.Logger.Level, cfg.RabbitMQ.Vhost)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.GatewayPort = 70000
+	cfg.Server.Proxy.ClientIPHeader = "x-real-ip"
+	cfg.Database.Host = ""
+	cfg.Database.DBName = ""
+	cfg.Redis.Port = 0
+	cfg.Logger.Level = "verbose"
+	cfg.Timezone.Default = "Mars/Olympus"
+	cfg.Redis.Degradation.Revocation = "bypass"
+	cfg.RabbitMQ.Queues = []QueueConfig{{Name: "email_queue", MaxLength: 100, Overflow: "drop-tail"}}
+	cfg.Cron.Jobs = append(cfg.Cron.Jobs,
+		JobConfig{Name: "daily", Spec: "0 1 * * *"},
+		JobConfig{Name: "health_check", Spec: "@hourly", MaxFailures: -1},
+	)
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("期望校验失败")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"server.gateway_port", "server.proxy.client_ip_header", "database.host", "database.dbname", "redis.port",
+		"logger.level", "timezone.default", "cron.jobs[1].spec", "cron.jobs[2].name",
+		"redis.degradation.revocation", "rabbitmq.queues[0].overflow", "cron.jobs[2].max_failures",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("错误信息缺少 %s:\n%s", want, msg)
+		}
+	}
+	if n := strings.Count(msg, "\n") + 1; n != 12 {
+		t.Errorf("错误数 = %d, 期望 12:\n%s", n, msg)
+	}
+}
+
+func TestValidateRedisStreamsWithoutRabbitMQ(t *testing.T) {
+	cfg := validConfig()
+	cfg.RabbitMQ = RabbitMQConfig{Driver: "redis_streams"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.RabbitMQ.Driver = "kafka"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "rabbitmq.driver") {
+		t.Errorf("err = %v, 期望 rabbitmq.driver 错误", err)
+	}
+}
+
+func TestValidateDatabaseDriver(t *testing.T) {
+	cfg := validConfig()
+	cfg.Database = DatabaseConfig{Driver: "sqlite", DBName: "data/microservice.db"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Database.Replicas = []string{"file:replica.db"}
+	cfg.Database.Notify.Enable = true
+	err := cfg.Validate()
+	for _, want := range []string{"database.replicas", "database.notify.enable"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("err = %v, 期望 %s 错误", err, want)
+		}
+	}
+
+	cfg = validConfig()
+	cfg.Database.Driver = "oracle"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "database.driver") {
+		t.Errorf("err = %v, 期望 database.driver 错误", err)
+	}
+}
+
+func TestGetDatabaseDSN(t *testing.T) {
+	base := DatabaseConfig{Host: "db", Port: 3306, User: "app", Password: "secret", DBName: "microservice"}
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"", "host=db port=3306 user=app password=secret dbname=microservice sslmode=disable"},
+		{"mysql", "app:secret@tcp(db:3306)/microservice?charset=utf8mb4&parseTime=true&loc=UTC"},
+		{"sqlite", "file:microservice?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"},
+	}
+	for _, tt := range tests {
+		cfg := base
+		cfg.Driver = tt.driver
+		if got := cfg.GetDatabaseDSN(); got != tt.want {
+			t.Errorf("driver %q: DSN = %q, 期望 %q", tt.driver, got, tt.want)
+		}
+	}
+
+	base.DSN = "root@unix(/run/mysqld.sock)/app"
+	base.Driver = "mysql"
+	if got := base.GetDatabaseDSN(); got != base.DSN {
+		t.Errorf("配置 dsn 时 DSN = %q", got)
+	}
+}
+
+func TestValidateJWTSecretInRelease(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Mode = "release"
+	for _, secret := range []string{"", DefaultJWTSecret} {
+		cfg.JWT.Secret = secret
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwt.secret") {
+			t.Errorf("secret = %q: err = %v, 期望 jwt.secret 错误", secret, err)
+		}
+	}
+
+	cfg.JWT.Secret = "a-real-production-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	// 开发模式允许使用默认密钥
+	cfg.Server.Mode = "debug"
+	cfg.JWT.Secret = ""
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.JWT.Algorithm = "PS256"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwt.algorithm") {
+		t.Errorf("err = %v, 期望 jwt.algorithm 错误", err)
+	}
+}
+
+func TestValidateJWTAsymmetric(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Mode = "release"
+	cfg.JWT.Algorithm = "RS256"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "jwks_url") {
+		t.Errorf("err = %v, 期望缺少密钥的错误", err)
+	}
+
+	// 非对称算法不使用共享密钥，release 模式下也不要求配置 secret
+	cfg.JWT.JWKSURL = "https://idp.example.com/realms/main/protocol/openid-connect/certs"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateRateLimitAllowlist(t *testing.T) {
+	cfg := validConfig()
+	cfg.Middleware.RateLimit = RateLimitConfig{
+		Tiers: map[string]RateLimitTier{
+			"partner":  {RequestsPerSecond: 100},
+			"internal": {Exempt: true},
+			"broken":   {Burst: 10},
+		},
+		Allowlist: []RateLimitClient{
+			{Name: "acme", Tier: "partner", APIKeys: []string{"k"}, IPs: []string{"203.0.113.0/24", "10.0.0.1"}},
+			{Name: "acme", Tier: "internal", Subjects: []int64{1}},
+			{Name: "ghost", Tier: "gold", IPs: []string{"10.0.0.300"}},
+			{Name: "empty", Tier: "partner"},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("期望校验失败")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"tiers.broken.requests_per_second", "allowlist[1].name", "allowlist[2].tier", "allowlist[2].ips", "allowlist[3]:",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("错误信息缺少 %s:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "allowlist[0]") {
+		t.Errorf("有效的客户端不应报错:\n%s", msg)
+	}
+}
+
+func TestValidateGRPCAuth(t *testing.T) {
+	cfg := validConfig()
+	cfg.GRPC.Interceptors = []string{"recovery", "tracing", "recovery"}
+	cfg.GRPC.TLS = GRPCTLSConfig{Enable: true, RequireClientCert: true}
+	cfg.GRPC.Auth = GRPCAuthConfig{
+		APIKeys: []GRPCAPIKey{
+			{Name: "billing", Key: "k1", Role: "admin"},
+			{Name: "report", Key: "k1"},
+		},
+		ClientCerts: []GRPCClientCert{{CommonName: "worker", Role: "user"}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("期望校验失败")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"grpc.tls.cert_file", "grpc.tls.key_file", "grpc.tls.client_ca_file", "grpc.auth.client_certs:",
+		"api_keys[1].key", "api_keys[1].role", "interceptors[1]", "interceptors[2]",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("错误信息缺少 %s:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "api_keys[0]") {
+		t.Errorf("有效的密钥不应报错:\n%s", msg)
+	}
+}
+
+func TestValidateUserSettings(t *testing.T) {
+	cfg := validConfig()
+	cfg.UserSettings.Keys = []UserSettingKey{
+		{Name: "theme", Schema: jsonschema.Schema{Type: "string", Enum: []interface{}{"light", "dark"}}, Default: "light"},
+		{Name: "theme", Schema: jsonschema.Schema{Type: "string"}},
+		{Name: "page_size", Schema: jsonschema.Schema{Type: "integer"}, Default: "20"},
+		{Name: "birthday", Schema: jsonschema.Schema{Type: "date"}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("期望校验失败")
+	}
+	msg := err.Error()
+	for _, want := range []string{"keys[1].name", "keys[2].default", "keys[3].schema"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("错误信息缺少 %s:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "keys[0]") {
+		t.Errorf("有效的设置项不应报错:\n%s", msg)
+	}
+}
+
+func TestDefaultConfigFileIsValid(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	if err := Load("../../config/config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidCORSOrigin(t *testing.T) {
+	tests := map[string]bool{
+		"*":                          true,
+		"https://app.example.com":    true,
+		"http://localhost:3000":      true,
+		"https://*.example.com":      true,
+		"https://*.example.com:8443": true,
+		"app.example.com":            false,
+		"ftp://example.com":          false,
+		"https://a.*.example.com":    false,
+		"https://example.com/path":   false,
+		"https://":                   false,
+	}
+	for origin, want := range tests {
+		if got := ValidCORSOrigin(origin); got != want {
+			t.Errorf("ValidCORSOrigin(%q) = %v, 期望 %v", origin, got, want)
+		}
+	}
+}
+
+func TestValidateClaimCheck(t *testing.T) {
+	cfg := validConfig()
+	cfg.RabbitMQ.ClaimCheck = ClaimCheckConfig{Enable: true, Prefix: "/", Expire: 0}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "rabbitmq.claim_check.prefix") || !strings.Contains(err.Error(), "rabbitmq.claim_check.expire") {
+		t.Fatalf("前缀只有 / 且保留时间为 0 时应校验失败: %v", err)
+	}
+
+	// 未配置前缀时使用默认值
+	cfg.RabbitMQ.ClaimCheck = ClaimCheckConfig{Enable: true, Expire: 72}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.RabbitMQ.ClaimCheck.GetPrefix(); got != "claim-checks/" {
+		t.Errorf("默认前缀 = %q", got)
+	}
+}
@@ -0,0 +1,263 @@
+package cronctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// 网关与定时任务服务通过 Redis 协作：
+// 定时任务服务定期上报任务列表（含下次执行时间），网关读取列表、写入运行时禁用标记、投递手动触发请求
+const (
+	// jobsKey 任务列表（hash：任务名 -> JobInfo JSON），由定时任务服务上报，过期表示服务不在线
+	jobsKey = "cron:jobs"
+	// disabledKey 运行时禁用的任务（set），调度到点时跳过
+	disabledKey = "cron:disabled"
+	// triggerKey 手动触发队列（list），由任一定时任务服务实例取出执行
+	triggerKey = "cron:triggers"
+	// failuresKey 任务连续失败次数（hash：任务名 -> 次数），执行成功或重新启用时清零
+	failuresKey = "cron:failures"
+)
+
+// ReportInterval 定时任务服务上报任务列表的间隔，列表在 3 个间隔内未更新即视为服务不在线
+const ReportInterval = 30 * time.Second
+
+var (
+	// ErrUnknownJob 任务不存在（或定时任务服务不在线）
+	ErrUnknownJob = errors.New("任务不存在")
+)
+
+// JobInfo 任务状态
+type JobInfo struct {
+	Name string `json:"name"`
+	Spec string `json:"spec"`
+	// Scheduled 是否按表达式调度（配置文件中未启用的任务只能手动触发）
+	Scheduled bool `json:"scheduled"`
+	// Disabled 是否在运行时被禁用
+	Disabled bool `json:"disabled"`
+	// ConsecutiveFailures 连续失败次数，达到 cron.max_failures 时任务被自动禁用
+	ConsecutiveFailures int64      `json:"consecutive_failures"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+	PrevRun             *time.Time `json:"prev_run,omitempty"`
+	// Host 上报任务列表的定时任务服务主机名
+	Host       string    `json:"host"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// hostname 当前主机名，启动时获取一次
+var hostname, _ = os.Hostname()
+
+// Report 上报任务列表（定时任务服务调用）
+// 参数:
+//
+//	ctx: 上下文
+//	jobs: 任务列表，Disabled 字段忽略
+//
+// 返回:
+//
+//	error: 错误信息
+func Report(ctx context.Context, jobs []JobInfo) error {
+	now := time.Now()
+	values := make([]interface{}, 0, len(jobs)*2)
+	for _, job := range jobs {
+		job.Host = hostname
+		job.ReportedAt = now
+		data, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		values = append(values, job.Name, data)
+	}
+
+	pipe := cache.RedisClient.TxPipeline()
+	pipe.Del(ctx, jobsKey)
+	if len(values) > 0 {
+		pipe.HSet(ctx, jobsKey, values...)
+		pipe.Expire(ctx, jobsKey, 3*ReportInterval)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// List 列出任务及其状态，按名称排序
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	[]JobInfo: 任务列表，定时任务服务不在线时为空
+//	error: 错误信息
+func List(ctx context.Context) ([]JobInfo, error) {
+	raw, err := cache.HGetAll(ctx, jobsKey)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := cache.RedisClient.SMembers(ctx, disabledKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	off := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		off[name] = true
+	}
+	failures, err := cache.HGetAll(ctx, failuresKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]JobInfo, 0, len(raw))
+	for name, data := range raw {
+		var job JobInfo
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			logger.Warn("解析任务状态失败", zap.String("任务", name), zap.Error(err))
+			continue
+		}
+		job.Disabled = off[name]
+		job.ConsecutiveFailures, _ = strconv.ParseInt(failures[name], 10, 64)
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs, nil
+}
+
+// exists 任务是否在定时任务服务上报的列表中
+func exists(ctx context.Context, name string) error {
+	ok, err := cache.RedisClient.HExists(ctx, jobsKey, name).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUnknownJob
+	}
+	return nil
+}
+
+// SetDisabled 在运行时启用或禁用任务的定时调度，无需重启定时任务服务；重新启用时清零连续失败次数
+// 参数:
+//
+//	ctx: 上下文
+//	name: 任务名称
+//	disabled: 是否禁用
+//	actor: 操作人
+//
+// 返回:
+//
+//	error: 任务不存在时返回 ErrUnknownJob
+func SetDisabled(ctx context.Context, name string, disabled bool, actor string) error {
+	if err := exists(ctx, name); err != nil {
+		return err
+	}
+
+	action := "jobs.enable"
+	cmd := cache.RedisClient.SRem
+	if disabled {
+		action = "jobs.disable"
+		cmd = cache.RedisClient.SAdd
+	}
+	if err := cmd(ctx, disabledKey, name).Err(); err != nil {
+		return fmt.Errorf("保存任务状态失败: %w", err)
+	}
+	if !disabled {
+		if err := cache.RedisClient.HDel(ctx, failuresKey, name).Err(); err != nil {
+			return fmt.Errorf("清零连续失败次数失败: %w", err)
+		}
+	}
+
+	logger.Info("任务调度状态已更新",
+		zap.String("任务", name),
+		zap.Bool("禁用", disabled),
+		zap.String("操作人", actor),
+	)
+	_ = audit.Record(ctx, actor, action, "jobs/"+name, nil)
+	return nil
+}
+
+// IsDisabled 任务是否在运行时被禁用（定时任务服务在调度到点时调用）
+// 参数:
+//
+//	ctx: 上下文
+//	name: 任务名称
+//
+// 返回:
+//
+//	bool: 是否禁用
+//	error: 错误信息
+func IsDisabled(ctx context.Context, name string) (bool, error) {
+	return cache.RedisClient.SIsMember(ctx, disabledKey, name).Result()
+}
+
+// RecordResult 记录一次执行结果（定时任务服务调用），成功时清零连续失败次数，失败时加一
+// 多个定时任务服务实例通过任务锁互斥执行，计数保存在 Redis 中由各实例共享
+// 参数:
+//
+//	ctx: 上下文
+//	name: 任务名称
+//	runErr: 任务错误，nil 表示成功
+//
+// 返回:
+//
+//	int64: 记录后的连续失败次数
+//	error: 错误信息
+func RecordResult(ctx context.Context, name string, runErr error) (int64, error) {
+	if runErr == nil {
+		return 0, cache.RedisClient.HDel(ctx, failuresKey, name).Err()
+	}
+	return cache.RedisClient.HIncrBy(ctx, failuresKey, name, 1).Result()
+}
+
+// Trigger 投递一次手动触发，由任一定时任务服务实例取出执行
+// 手动触发不受运行时禁用影响，仍通过任务锁避免与正在进行的执行重叠
+// 参数:
+//
+//	ctx: 上下文
+//	name: 任务名称
+//	actor: 操作人
+//
+// 返回:
+//
+//	error: 任务不存在时返回 ErrUnknownJob
+func Trigger(ctx context.Context, name, actor string) error {
+	if err := exists(ctx, name); err != nil {
+		return err
+	}
+	if err := cache.RedisClient.RPush(ctx, triggerKey, name).Err(); err != nil {
+		return fmt.Errorf("投递触发请求失败: %w", err)
+	}
+
+	logger.Info("任务已手动触发", zap.String("任务", name), zap.String("操作人", actor))
+	_ = audit.Record(ctx, actor, "jobs.trigger", "jobs/"+name, nil)
+	return nil
+}
+
+// NextTrigger 等待下一个手动触发请求（定时任务服务调用）
+// 参数:
+//
+//	ctx: 上下文
+//	timeout: 最长等待时间
+//
+// 返回:
+//
+//	string: 任务名称，超时返回空字符串
+//	error: 错误信息
+func NextTrigger(ctx context.Context, timeout time.Duration) (string, error) {
+	result, err := cache.RedisClient.BLPop(ctx, timeout, triggerKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return result[1], nil
+}
@@ -0,0 +1,51 @@
+package cronctl_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zhang/microservice/internal/cronctl"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestRecordResult(t *testing.T) {
+	testutil.InitLogger()
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	if err := cronctl.Report(ctx, []cronctl.JobInfo{{Name: "health_check", Spec: "0 */5 * * * *"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("数据库不可用")
+	for want := int64(1); want <= 3; want++ {
+		got, err := cronctl.RecordResult(ctx, "health_check", failed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("连续失败次数 = %d, 期望 %d", got, want)
+		}
+	}
+
+	jobs, err := cronctl.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].ConsecutiveFailures != 3 {
+		t.Errorf("任务列表 = %+v", jobs)
+	}
+
+	// 成功后清零
+	if got, err := cronctl.RecordResult(ctx, "health_check", nil); err != nil || got != 0 {
+		t.Errorf("成功后连续失败次数 = %d, err = %v", got, err)
+	}
+	jobs, err = cronctl.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs[0].ConsecutiveFailures != 0 {
+		t.Errorf("成功后任务列表 = %+v", jobs)
+	}
+}
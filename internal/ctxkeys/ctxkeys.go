@@ -0,0 +1,109 @@
+package ctxkeys
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// key 上下文键类型，未导出以避免与其他包的键冲突
+type key int
+
+const (
+	requestIDKey key = iota
+	identityKey
+)
+
+// Identity 已认证的调用方
+type Identity struct {
+	// UserID 用户 ID，API Key、客户端证书等非用户调用方为 0
+	UserID int64
+	// Username 用户名（或 API Key 名称、证书 CN）
+	Username string
+	// Role 角色
+	Role string
+}
+
+// value 读取上下文中的值
+// Gin 上下文的值保存在 c.Request 的上下文中（见 SetRequestID、SetIdentity），
+// 处理器把 c.Request.Context() 传给下层时同样可以读取
+func value(ctx context.Context, k key) interface{} {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return nil
+		}
+		ctx = c.Request.Context()
+	}
+	return ctx.Value(k)
+}
+
+// WithRequestID 返回带有请求 ID 的上下文
+// 参数:
+//
+//	ctx: 上下文
+//	id: 请求 ID
+//
+// 返回:
+//
+//	context.Context: 新上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID 获取请求 ID
+// 参数:
+//
+//	ctx: 上下文（*gin.Context、请求上下文或 gRPC 上下文）
+//
+// 返回:
+//
+//	string: 请求 ID，未设置时为空
+func RequestID(ctx context.Context) string {
+	id, _ := value(ctx, requestIDKey).(string)
+	return id
+}
+
+// SetRequestID 设置 Gin 请求的请求 ID
+// 参数:
+//
+//	c: Gin 上下文
+//	id: 请求 ID
+func SetRequestID(c *gin.Context, id string) {
+	c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+}
+
+// WithIdentity 返回带有调用方身份的上下文
+// 参数:
+//
+//	ctx: 上下文
+//	identity: 调用方身份
+//
+// 返回:
+//
+//	context.Context: 新上下文
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// IdentityFrom 获取调用方身份
+// 参数:
+//
+//	ctx: 上下文（*gin.Context、请求上下文或 gRPC 上下文）
+//
+// 返回:
+//
+//	Identity: 调用方身份
+//	bool: 是否已认证
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	identity, ok := value(ctx, identityKey).(Identity)
+	return identity, ok
+}
+
+// SetIdentity 设置 Gin 请求的调用方身份
+// 参数:
+//
+//	c: Gin 上下文
+//	identity: 调用方身份
+func SetIdentity(c *gin.Context, identity Identity) {
+	c.Request = c.Request.WithContext(WithIdentity(c.Request.Context(), identity))
+}
@@ -0,0 +1,79 @@
+package ctxkeys
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestContextValues(t *testing.T) {
+	ctx := context.Background()
+	if RequestID(ctx) != "" {
+		t.Error("未设置时请求 ID 应为空")
+	}
+	if _, ok := IdentityFrom(ctx); ok {
+		t.Error("未设置时不应有调用方身份")
+	}
+
+	ctx = WithIdentity(WithRequestID(ctx, "req-1"), Identity{UserID: 7, Username: "alice", Role: "admin"})
+	if RequestID(ctx) != "req-1" {
+		t.Errorf("请求 ID = %q", RequestID(ctx))
+	}
+	if identity, ok := IdentityFrom(ctx); !ok || identity.UserID != 7 || identity.Role != "admin" {
+		t.Errorf("调用方身份 = %+v, %v", identity, ok)
+	}
+}
+
+func TestGinContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if RequestID(c) != "" {
+		t.Error("没有请求时请求 ID 应为空")
+	}
+
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	SetRequestID(c, "req-2")
+	SetIdentity(c, Identity{UserID: 3, Username: "bob", Role: "user"})
+
+	// 值同时可从 *gin.Context 和 c.Request.Context() 读取
+	for name, ctx := range map[string]context.Context{"gin": c, "request": c.Request.Context()} {
+		if RequestID(ctx) != "req-2" {
+			t.Errorf("%s: 请求 ID = %q", name, RequestID(ctx))
+		}
+		if identity, ok := IdentityFrom(ctx); !ok || identity.Username != "bob" {
+			t.Errorf("%s: 调用方身份 = %+v, %v", name, identity, ok)
+		}
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	seen := map[string]bool{}
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id := NewRequestID()
+		if len(id) != 36 || id[14] != '7' || !strings.ContainsRune("89ab", rune(id[19])) {
+			t.Fatalf("不是 UUIDv7: %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("请求 ID 重复: %q", id)
+		}
+		seen[id] = true
+		// 时间戳部分不递减
+		if id[:13] < prev {
+			t.Fatalf("请求 ID 不按时间有序: %q < %q", id, prev)
+		}
+		prev = id[:13]
+		if !ValidRequestID(id) {
+			t.Fatalf("生成的请求 ID 应可沿用: %q", id)
+		}
+	}
+
+	for _, id := range []string{"", "a b", "id\nforged", strings.Repeat("a", 129)} {
+		if ValidRequestID(id) {
+			t.Errorf("不应沿用请求 ID %q", id)
+		}
+	}
+}
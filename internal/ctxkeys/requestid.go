@@ -0,0 +1,66 @@
+package ctxkeys
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// 传递请求 ID 的 HTTP 头，以及 gRPC metadata 键和消息头（RabbitMQ headers、Redis Streams headers 字段）名
+const (
+	RequestIDHeader   = "X-Request-ID"
+	RequestIDMetadata = "x-request-id"
+)
+
+// maxRequestIDLength 接受的外部请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+// NewRequestID 生成请求 ID（UUIDv7：前 48 位为毫秒时间戳，其余为随机数）
+// 按时间有序，便于在日志中按生成顺序排序；不同实例同一毫秒内生成的 ID 也不会冲突
+// 返回:
+//
+//	string: 请求 ID，如 0190163d-8694-739b-aea5-966c26f8ad91
+func NewRequestID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	_, _ = rand.Read(b[6:])
+	b[6] = 0x70 | b[6]&0x0f // 版本 7
+	b[8] = 0x80 | b[8]&0x3f // RFC 9562 变体
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// ValidRequestID 外部传入的请求 ID 是否可以沿用
+// 只接受不超过 128 个字符的字母、数字和 - _ . :，避免日志注入和超长的值
+// 参数:
+//
+//	id: 请求 ID
+//
+// 返回:
+//
+//	bool: 是否可以沿用
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
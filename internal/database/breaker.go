@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zhang/microservice/internal/resilience"
+	"gorm.io/gorm"
+)
+
+// breakerDoneKey 记录熔断器回调的实例键
+const breakerDoneKey = "resilience:done"
+
+// MySQL 服务端不可用的错误码
+const (
+	mysqlTooManyConnections = 1040
+	mysqlServerShutdown     = 1053
+)
+
+// registerBreaker 注册 GORM 回调，数据库熔断时直接返回“依赖不可用”错误而不再发起查询
+// 参数:
+//
+//	db: GORM 实例
+//	breaker: 熔断器，为 nil 时不注册
+//
+// 返回:
+//
+//	error: 错误信息
+func registerBreaker(db *gorm.DB, breaker *resilience.Breaker) error {
+	if breaker == nil {
+		return nil
+	}
+
+	before := func(tx *gorm.DB) {
+		done, err := breaker.Allow()
+		if err != nil {
+			// 已有错误时 GORM 跳过后续的查询回调
+			_ = tx.AddError(err)
+			return
+		}
+		tx.InstanceSet(breakerDoneKey, done)
+	}
+	after := func(tx *gorm.DB) {
+		v, ok := tx.InstanceGet(breakerDoneKey)
+		if !ok {
+			return
+		}
+		if done, ok := v.(func(error)); ok {
+			done(tx.Error)
+		}
+	}
+
+	cb := db.Callback()
+	registrations := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, r := range registrations {
+		if err := r.before("resilience:before_"+r.operation, before); err != nil {
+			return err
+		}
+		if err := r.after("resilience:after_"+r.operation, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isUnavailable 是否为数据库不可用类错误（计入熔断）
+// 连接失败、超时，以及 PostgreSQL 连接异常（08）、资源不足（53）、管理员关闭（57P）
+// 和 MySQL 连接数已满、正在关闭；记录不存在、约束冲突等查询结果不算
+func isUnavailable(err error) bool {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	if resilience.IsConnectionError(err) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") ||
+			strings.HasPrefix(pgErr.Code, "57P")
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == mysqlTooManyConnections || myErr.Number == mysqlServerShutdown
+	}
+	return false
+}
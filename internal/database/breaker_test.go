@@ -0,0 +1,56 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/resilience"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestBreakerCallbacks(t *testing.T) {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+		logger.Sugar = logger.Logger.Sugar()
+	}
+
+	// 端口 1 上没有数据库，查询立即因连接失败返回
+	db, err := gorm.Open(postgres.Open("postgres://postgres@127.0.0.1:1/test?connect_timeout=1"), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               gormlogger.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker := resilience.NewBreaker(resilience.Settings{
+		Name:             "database",
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		IsFailure:        isUnavailable,
+	})
+	if err := registerBreaker(db, breaker); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for i := 0; i < 2; i++ {
+		err := db.Raw("SELECT 1").Scan(&n).Error
+		if err == nil || errors.Is(err, resilience.ErrOpen) {
+			t.Fatalf("第 %d 次查询应返回连接错误, err = %v", i+1, err)
+		}
+	}
+	if s := breaker.State(); s != resilience.StateOpen {
+		t.Fatalf("连续连接失败后应熔断, 状态 = %s", s)
+	}
+	if err := db.Raw("SELECT 1").Scan(&n).Error; !errors.Is(err, resilience.ErrOpen) {
+		t.Fatalf("熔断期间应直接返回 ErrOpen, err = %v", err)
+	}
+	if isUnavailable(gorm.ErrRecordNotFound) {
+		t.Error("记录不存在不应计入熔断")
+	}
+}
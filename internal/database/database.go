@@ -7,10 +7,11 @@ import (
 
 	"github.com/zhang/microservice/internal/config"
 	zapLogger "github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
 	"go.uber.org/zap"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 // DB 全局数据库实例
@@ -27,22 +28,33 @@ var DB *gorm.DB
 func Init(cfg config.DatabaseConfig) error {
 	var err error
 
-	// 配置 GORM
-	gormConfig := &gorm.Config{}
-
-	// 配置日志
-	if cfg.LogMode {
-		gormConfig.Logger = logger.Default.LogMode(logger.Info)
-	} else {
-		gormConfig.Logger = logger.Default.LogMode(logger.Silent)
+	// 配置 GORM，时间统一以 UTC 写入，输出时再按请求时区转换；
+	// 连接由下面的 Ping 检查，只读副本不可用时由健康检查排除而不是导致启动失败；
+	// 各驱动的唯一约束、外键错误统一转换为 gorm.ErrDuplicatedKey / gorm.ErrForeignKeyViolated
+	gormConfig := &gorm.Config{
+		NowFunc:              func() time.Time { return time.Now().UTC() },
+		DisableAutomaticPing: true,
+		TranslateError:       true,
+		Logger:               newGormLogger(cfg),
 	}
 
 	// 连接数据库
-	DB, err = gorm.Open(postgres.Open(cfg.GetDatabaseDSN()), gormConfig)
+	driver := cfg.GetDriver()
+	DB, err = gorm.Open(dialector(driver, cfg.GetDatabaseDSN()), gormConfig)
 	if err != nil {
 		return fmt.Errorf("连接数据库失败: %w", err)
 	}
 
+	// 统计查询次数、耗时和慢查询
+	if err := metrics.RegisterGormCallbacks(DB, cfg.GetSlowQueryThreshold()); err != nil {
+		return fmt.Errorf("注册数据库指标失败: %w", err)
+	}
+
+	// 数据库持续不可用时熔断，直接返回“依赖不可用”错误
+	if err := registerBreaker(DB, resilience.For("database", isUnavailable)); err != nil {
+		return fmt.Errorf("注册数据库熔断器失败: %w", err)
+	}
+
 	// 获取底层的 sql.DB
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -53,16 +65,33 @@ func Init(cfg config.DatabaseConfig) error {
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	sqlDB.SetConnMaxLifetime(cfg.GetConnMaxLifetime())
-
-	// 测试连接
-	if err := sqlDB.Ping(); err != nil {
+	metrics.RegisterDBStats(sqlDB, cfg.DBName)
+
+	// 测试连接，数据库尚未就绪（连接失败、正在启动）时按 retry.startup_timeout 等待，认证失败等错误立即返回
+	policy := retry.Startup("数据库")
+	policy.Retryable = isUnavailable
+	err = retry.Do(context.Background(), policy, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		return sqlDB.PingContext(ctx)
+	})
+	if err != nil {
 		return fmt.Errorf("数据库连接测试失败: %w", err)
 	}
 
+	// 只读副本（读写分离）
+	if len(cfg.Replicas) > 0 {
+		if err := useReplicas(sqlDB, cfg); err != nil {
+			return err
+		}
+	}
+
 	zapLogger.Info("数据库连接成功",
+		zap.String("driver", driver),
 		zap.String("host", cfg.Host),
 		zap.Int("port", cfg.Port),
 		zap.String("database", cfg.DBName),
+		zap.Int("replicas", len(cfg.Replicas)),
 	)
 
 	return nil
@@ -73,6 +102,7 @@ func Init(cfg config.DatabaseConfig) error {
 //
 //	error: 错误信息
 func Close() error {
+	closeReplicas()
 	if DB != nil {
 		sqlDB, err := DB.DB()
 		if err != nil {
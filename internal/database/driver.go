@@ -0,0 +1,70 @@
+package database
+
+import (
+	"database/sql"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 数据库驱动（database.driver）
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlDriverName 返回驱动在 database/sql 中注册的名称
+// 参数:
+//
+//	driver: 数据库驱动
+//
+// 返回:
+//
+//	string: database/sql 驱动名称
+func sqlDriverName(driver string) string {
+	switch driver {
+	case DriverMySQL:
+		return "mysql"
+	case DriverSQLite:
+		return "sqlite3"
+	}
+	return "pgx"
+}
+
+// dialector 按驱动创建 gorm 方言
+// 参数:
+//
+//	driver: 数据库驱动
+//	dsn: 连接字符串
+//
+// 返回:
+//
+//	gorm.Dialector: gorm 方言
+func dialector(driver, dsn string) gorm.Dialector {
+	switch driver {
+	case DriverMySQL:
+		return mysql.Open(dsn)
+	case DriverSQLite:
+		return sqlite.Open(dsn)
+	}
+	return postgres.Open(dsn)
+}
+
+// connDialector 按驱动在已打开的连接池上创建 gorm 方言（只读副本使用）
+// 参数:
+//
+//	driver: 数据库驱动
+//	conn: 连接池
+//
+// 返回:
+//
+//	gorm.Dialector: gorm 方言
+func connDialector(driver string, conn *sql.DB) gorm.Dialector {
+	if driver == DriverMySQL {
+		return mysql.New(mysql.Config{Conn: conn})
+	}
+	return postgres.New(postgres.Config{Conn: conn})
+}
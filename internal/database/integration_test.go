@@ -0,0 +1,296 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/files"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/migrate"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 各驱动的集成测试：执行迁移、初始化连接后读写各模块的表，最后回滚全部迁移
+// sqlite 始终运行；设置 TEST_POSTGRES_DSN / TEST_MYSQL_DSN 后同时测试对应数据库（其中的表会被删除，请使用专用的测试库），如
+//
+//	TEST_MYSQL_DSN='root:secret@tcp(127.0.0.1:3306)/microservice_test?parseTime=true&loc=UTC' go test ./internal/database/
+func TestDrivers(t *testing.T) {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+		logger.Sugar = logger.Logger.Sugar()
+	}
+
+	t.Run(database.DriverSQLite, func(t *testing.T) {
+		testDriver(t, config.DatabaseConfig{
+			Driver: database.DriverSQLite,
+			DBName: filepath.Join(t.TempDir(), "microservice.db"),
+		})
+	})
+	for driver, env := range map[string]string{
+		database.DriverPostgres: "TEST_POSTGRES_DSN",
+		database.DriverMySQL:    "TEST_MYSQL_DSN",
+	} {
+		t.Run(driver, func(t *testing.T) {
+			dsn := os.Getenv(env)
+			if dsn == "" {
+				t.Skipf("未设置 %s", env)
+			}
+			testDriver(t, config.DatabaseConfig{Driver: driver, DSN: dsn, MaxIdleConns: 2, MaxOpenConns: 10})
+		})
+	}
+}
+
+func testDriver(t *testing.T, cfg config.DatabaseConfig) {
+	ctx := context.Background()
+
+	m, err := migrate.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		defer m.Close()
+		if err := m.Down(int(migrate.Version)); err != nil {
+			t.Errorf("回滚迁移失败: %v", err)
+		}
+		if status, err := m.Status(); err != nil || status.Version != 0 {
+			t.Errorf("回滚后状态 = %+v, %v", status, err)
+		}
+	})
+	if err := m.Up(0); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	if err := database.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := migrate.CheckSchema(ctx); err != nil {
+		t.Fatalf("表结构检查失败: %v", err)
+	}
+
+	t.Run("users", func(t *testing.T) {
+		repo := service.NewGormUserRepository(database.DB)
+		user := &service.User{Name: "张三", Email: "zhangsan@example.com"}
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		// 唯一约束冲突在各驱动下都转换为 Conflict
+		err := repo.Create(ctx, &service.User{Name: "李四", Email: user.Email})
+		if !errors.Is(errs.FromDB(err), errs.ErrConflict) {
+			t.Fatalf("重复邮箱: err = %v, 期望 Conflict", err)
+		}
+
+		got, err := repo.Get(ctx, user.ID)
+		if err != nil || got.Email != user.Email || got.Role != "user" || got.Version != 1 || !got.CreatedAt.Equal(user.CreatedAt) {
+			t.Fatalf("Get = %+v, %v", got, err)
+		}
+		list, total, err := repo.List(ctx, service.UserFilter{Name: "张"}, 0, 10)
+		if err != nil || total != 1 || len(list) != 1 {
+			t.Fatalf("List = %d 条 / 共 %d, %v", len(list), total, err)
+		}
+
+		// 乐观锁：版本从 1 开始，每次更新加一，过期的版本返回冲突
+		users := service.NewUserService(repo)
+		updated, err := users.UpdateUser(ctx, &service.User{ID: user.ID, Name: "张三丰", Version: 1}, "name")
+		if err != nil || updated.Version != 2 {
+			t.Fatalf("UpdateUser = %+v, %v", updated, err)
+		}
+		if _, err := users.UpdateUser(ctx, &service.User{ID: user.ID, Name: "王五", Version: 1}, "name"); !errors.Is(err, errs.ErrVersionConflict) {
+			t.Fatalf("过期版本: err = %v", err)
+		}
+		updated.Phone = "13800138000"
+		if updated, err = users.UpdateUser(ctx, updated); err != nil || updated.Version != 3 {
+			t.Fatalf("全部列 UpdateUser = %+v, %v", updated, err)
+		}
+		if got, err := repo.Get(ctx, user.ID); err != nil || got.Version != 3 || got.Name != "张三丰" {
+			t.Fatalf("更新后 Get = %+v, %v", got, err)
+		}
+
+		// 软删除后查询不到，恢复后重新可见，到期后被永久删除
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.Get(ctx, user.ID); err != nil || got != nil {
+			t.Fatalf("软删除后 Get = %+v, %v", got, err)
+		}
+		if ok, err := repo.Restore(ctx, user.ID); err != nil || !ok {
+			t.Fatalf("Restore = %v, %v", ok, err)
+		}
+		if got, err := repo.Get(ctx, user.ID); err != nil || got == nil {
+			t.Fatalf("恢复后 Get = %+v, %v", got, err)
+		}
+		if err := repo.Delete(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := repo.PurgeDeleted(ctx, time.Now().Add(-time.Hour), 10); err != nil || n != 0 {
+			t.Fatalf("未到期 PurgeDeleted = %d, %v", n, err)
+		}
+		if n, err := repo.PurgeDeleted(ctx, time.Now().Add(time.Minute), 10); err != nil || n != 1 {
+			t.Fatalf("PurgeDeleted = %d, %v", n, err)
+		}
+		if ok, err := repo.Restore(ctx, user.ID); err != nil || ok {
+			t.Fatalf("永久删除后 Restore = %v, %v", ok, err)
+		}
+	})
+
+	t.Run("user cursor", func(t *testing.T) {
+		repo := service.NewGormUserRepository(database.DB)
+		// 后两个用户的创建时间相同，翻页依赖 id 区分先后
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		users := []*service.User{
+			{Name: "a_1", Email: "c1@example.com", CreatedAt: base.Add(2 * time.Hour)},
+			{Name: "a2", Email: "c2@example.com", CreatedAt: base},
+			{Name: "ab3", Email: "c3@example.com", CreatedAt: base},
+		}
+		if err := repo.CreateBatch(ctx, users); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			for _, user := range users {
+				repo.Purge(ctx, user.ID)
+			}
+		}()
+
+		filter := service.UserFilter{Sort: service.UserSortCreatedAtDesc}
+		var names []string
+		var after *service.UserCursor
+		for {
+			page, err := repo.ListAfter(ctx, filter, after, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			names = append(names, page[0].Name)
+			if after, err = service.DecodeUserCursor(service.EncodeUserCursor(filter.Sort, page[0])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := strings.Join(names, " "); got != "a_1 ab3 a2" {
+			t.Fatalf("按创建时间降序翻页 = %q", got)
+		}
+
+		// 前缀中的 _ 按字面匹配；创建时间范围为左闭右开
+		filter = service.UserFilter{NamePrefix: "a_", CreatedAfter: base, CreatedBefore: base.Add(3 * time.Hour)}
+		if list, total, err := repo.List(ctx, filter, 0, 10); err != nil || total != 1 || list[0].Name != "a_1" {
+			t.Fatalf("前缀 List = %+v / 共 %d, %v", list, total, err)
+		}
+		filter = service.UserFilter{CreatedBefore: base.Add(2 * time.Hour), Sort: service.UserSortIDDesc}
+		if list, total, err := repo.List(ctx, filter, 0, 10); err != nil || total != 2 || list[0].Name != "ab3" {
+			t.Fatalf("时间范围 List = %+v / 共 %d, %v", list, total, err)
+		}
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := audit.Record(ctx, "admin", "settings.update", "settings/rate_limit", map[string]int{"n": i}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 请求上下文中的请求 ID 和客户端地址一并写入
+		reqCtx := audit.WithClientIP(ctxkeys.WithRequestID(ctx, "req-audit"), "203.0.113.7")
+		if err := audit.Record(reqCtx, "admin", "users.role", "users/42", map[string]string{"role": "admin"}); err != nil {
+			t.Fatal(err)
+		}
+		// 读回的记录哈希一致（时间精度、时区在各驱动下保持不变）
+		result, err := audit.Verify(ctx, nil)
+		if err != nil || !result.OK() || result.Checked != 3 {
+			t.Fatalf("Verify = %+v, %v", result, err)
+		}
+
+		entries, err := audit.Query(ctx, audit.Filter{Action: "users.*", Resource: "users/", Limit: 10})
+		if err != nil || len(entries) != 1 || entries[0].RequestID != "req-audit" || entries[0].ClientIP != "203.0.113.7" {
+			t.Fatalf("Query = %+v, %v", entries, err)
+		}
+		if entries, err := audit.Query(ctx, audit.Filter{Actor: "admin", BeforeID: entries[0].ID, Limit: 10}); err != nil || len(entries) != 2 {
+			t.Fatalf("Query before_id = %d 条, %v", len(entries), err)
+		}
+	})
+
+	t.Run("context tx", func(t *testing.T) {
+		repo := service.NewGormUserRepository(database.DB)
+		errAbort := errors.New("abort")
+
+		// 任一步骤失败时用户和审计记录一起回滚
+		err := database.RunInTx(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &service.User{Name: "王五", Email: "wangwu@example.com"}); err != nil {
+				return err
+			}
+			if err := audit.Record(ctx, "admin", "users.create", "users/wangwu", nil); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("RunInTx = %v", err)
+		}
+		if got, err := repo.GetByEmail(ctx, "wangwu@example.com"); err != nil || got != nil {
+			t.Fatalf("回滚后仍能查到用户: %+v, %v", got, err)
+		}
+
+		// 嵌套事务出错只回滚自身，外层提交
+		err = database.RunInTx(ctx, func(ctx context.Context) error {
+			if err := repo.Create(ctx, &service.User{Name: "赵六", Email: "zhaoliu@example.com"}); err != nil {
+				return err
+			}
+			inner := database.RunInTx(ctx, func(ctx context.Context) error {
+				if err := repo.Create(ctx, &service.User{Name: "孙七", Email: "sunqi@example.com"}); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			if !errors.Is(inner, errAbort) {
+				t.Errorf("嵌套 RunInTx = %v", inner)
+			}
+			return audit.Record(ctx, "admin", "users.create", "users/zhaoliu", nil)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.GetByEmail(ctx, "zhaoliu@example.com"); err != nil || got == nil {
+			t.Fatalf("外层事务未提交: %+v, %v", got, err)
+		}
+		if got, err := repo.GetByEmail(ctx, "sunqi@example.com"); err != nil || got != nil {
+			t.Fatalf("嵌套事务未回滚: %+v, %v", got, err)
+		}
+		if result, err := audit.Verify(ctx, nil); err != nil || !result.OK() || result.Checked != 4 {
+			t.Fatalf("Verify = %+v, %v", result, err)
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		obj := &files.Object{Key: "uploads/2024/a.txt", OwnerID: 1, Size: 3, CreatedAt: time.Now()}
+		if err := database.DB.Create(obj).Error; err != nil {
+			t.Fatal(err)
+		}
+		found, err := files.Lookup(ctx, []string{obj.Key, "uploads/missing.txt"})
+		if err != nil || len(found) != 1 || found[obj.Key].Size != 3 {
+			t.Fatalf("Lookup = %+v, %v", found, err)
+		}
+	})
+
+	t.Run("xact lock", func(t *testing.T) {
+		err := database.Transaction(func(tx *gorm.DB) error {
+			if err := database.XactLock(tx, "uploads/2024/a.txt"); err != nil {
+				return err
+			}
+			return database.XactLockID(tx, 42)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
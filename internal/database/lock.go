@@ -0,0 +1,58 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// XactLock 在事务内按名称加锁，事务提交或回滚时释放，用于串行化同一资源的读改写
+// postgres 使用 advisory lock；mysql 锁定 xact_locks 表中的对应行；
+// sqlite 的事务以 BEGIN IMMEDIATE 开始，写事务本身已串行执行，不再额外加锁
+// 参数:
+//
+//	tx: 事务
+//	name: 锁名称
+//
+// 返回:
+//
+//	error: 错误信息
+func XactLock(tx *gorm.DB, name string) error {
+	switch tx.Dialector.Name() {
+	case DriverPostgres:
+		return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", name).Error
+	case DriverMySQL:
+		return lockRow(tx, name)
+	}
+	return nil
+}
+
+// XactLockID 在事务内按数字 ID 加锁，见 XactLock
+// postgres 直接以 ID 作为 advisory lock 的键（与此前硬编码的 pg_advisory_xact_lock 为同一把锁）
+// 参数:
+//
+//	tx: 事务
+//	id: 锁 ID
+//
+// 返回:
+//
+//	error: 错误信息
+func XactLockID(tx *gorm.DB, id int64) error {
+	switch tx.Dialector.Name() {
+	case DriverPostgres:
+		return tx.Exec("SELECT pg_advisory_xact_lock(?)", id).Error
+	case DriverMySQL:
+		return lockRow(tx, "id:"+strconv.FormatInt(id, 10))
+	}
+	return nil
+}
+
+// lockRow 插入或锁定 xact_locks 中名称哈希对应的行，行锁持有到事务结束
+// ON DUPLICATE KEY UPDATE 对已存在的行直接加排他锁（INSERT IGNORE 只加共享锁，并发时会死锁）
+func lockRow(tx *gorm.DB, name string) error {
+	sum := sha256.Sum256([]byte(name))
+	return tx.Exec("INSERT INTO xact_locks (name) VALUES (?) ON DUPLICATE KEY UPDATE name = name",
+		hex.EncodeToString(sum[:])).Error
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	zapLogger "github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gormLogger 把 gorm 日志写入 zap（带请求 ID）
+// 出错的 SQL 记录 Error，耗时超过慢查询阈值的记录 Warn，log_mode 开启时其余 SQL 记录 Info；
+// 日志中的 SQL 不含参数值（可能包含密码哈希、邮箱等敏感信息）
+type gormLogger struct {
+	level         logger.LogLevel
+	slowThreshold time.Duration
+}
+
+// newGormLogger 按数据库配置创建 gorm 日志
+// 参数:
+//
+//	cfg: 数据库配置
+//
+// 返回:
+//
+//	logger.Interface: gorm 日志
+func newGormLogger(cfg config.DatabaseConfig) logger.Interface {
+	level := logger.Warn
+	if cfg.LogMode {
+		level = logger.Info
+	}
+	return &gormLogger{level: level, slowThreshold: cfg.GetSlowQueryThreshold()}
+}
+
+// LogMode 返回指定级别的副本（db.Debug() 等使用）
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	c := *l
+	c.level = level
+	return &c
+}
+
+// Info 记录 gorm 的提示信息
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		zapLogger.FromContext(ctx).Info(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn 记录 gorm 的警告信息
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		zapLogger.FromContext(ctx).Warn(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error 记录 gorm 的错误信息
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		zapLogger.FromContext(ctx).Error(fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace 记录一条 SQL 的执行情况
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !expectedError(err):
+		sql, rows := fc()
+		zapLogger.FromContext(ctx).Error("SQL 执行失败", zap.Error(err),
+			zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("耗时", elapsed))
+	case elapsed >= l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		zapLogger.FromContext(ctx).Warn("慢查询",
+			zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("耗时", elapsed),
+			zap.Duration("阈值", l.slowThreshold))
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		zapLogger.FromContext(ctx).Info("执行 SQL",
+			zap.String("sql", sql), zap.Int64("rows", rows), zap.Duration("耗时", elapsed))
+	}
+}
+
+// ParamsFilter 去掉 SQL 的参数值，日志中保留占位符（实现 gorm.ParamsFilter）
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// expectedError 业务中正常出现的错误（由调用方转换为 NotFound、Conflict 等），不记录日志
+func expectedError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, gorm.ErrDuplicatedKey) ||
+		errors.Is(err, gorm.ErrForeignKeyViolated) ||
+		errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	zapLogger "github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type account struct {
+	ID    int64
+	Email string
+}
+
+// openLogged 打开内存 SQLite，日志写入 observer
+func openLogged(t *testing.T, l *gormLogger) (*gorm.DB, *observer.ObservedLogs) {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	prev := zapLogger.Logger
+	zapLogger.Logger = zap.New(core)
+	t.Cleanup(func() { zapLogger.Logger = prev })
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: l, TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := metrics.RegisterGormCallbacks(db, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE accounts (id integer PRIMARY KEY, email text UNIQUE)").Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, logs
+}
+
+func TestGormLoggerErrors(t *testing.T) {
+	db, logs := openLogged(t, &gormLogger{level: logger.Warn, slowThreshold: time.Hour})
+	ctx := ctxkeys.WithRequestID(context.Background(), "req-1")
+
+	// 正常查询、记录不存在、唯一约束冲突都不记录
+	db.WithContext(ctx).Create(&account{ID: 1, Email: "a@example.com"})
+	db.WithContext(ctx).First(&account{}, 2)
+	db.WithContext(ctx).Create(&account{ID: 3, Email: "a@example.com"})
+	if n := logs.Len(); n != 0 {
+		t.Fatalf("记录了 %d 条日志: %v", n, logs.All())
+	}
+
+	before := promtest.ToFloat64(metrics.DBQueries.WithLabelValues("raw", "", "error"))
+	db.WithContext(ctx).Exec("UPDATE missing SET email = ?", "secret@example.com")
+	if got := promtest.ToFloat64(metrics.DBQueries.WithLabelValues("raw", "", "error")) - before; got != 1 {
+		t.Errorf("db_queries_total{result=error} 增加 %v", got)
+	}
+
+	entries := logs.FilterMessage("SQL 执行失败").All()
+	if len(entries) != 1 {
+		t.Fatalf("出错日志 %d 条: %v", len(entries), logs.All())
+	}
+	fields := entries[0].ContextMap()
+	sql, _ := fields["sql"].(string)
+	if !strings.Contains(sql, "UPDATE missing") || strings.Contains(sql, "secret@example.com") {
+		t.Errorf("sql = %q，期望不含参数值", sql)
+	}
+	if fields["request_id"] != "req-1" {
+		t.Errorf("request_id = %v", fields["request_id"])
+	}
+}
+
+func TestGormLoggerSlowQuery(t *testing.T) {
+	db, logs := openLogged(t, &gormLogger{level: logger.Warn, slowThreshold: time.Hour})
+
+	db.Create(&account{ID: 1, Email: "a@example.com"})
+	if logs.Len() != 0 {
+		t.Fatalf("未超过阈值时不应记录: %v", logs.All())
+	}
+
+	// 阈值为 0 时任何查询都是慢查询
+	db.Logger = &gormLogger{level: logger.Warn}
+	db.Find(&[]account{})
+	if n := logs.FilterMessage("慢查询").Len(); n != 1 {
+		t.Fatalf("慢查询日志 %d 条: %v", n, logs.All())
+	}
+
+	// log_mode 开启时记录全部 SQL
+	db.Logger = (&gormLogger{slowThreshold: time.Hour}).LogMode(logger.Info)
+	db.Find(&[]account{})
+	if n := logs.FilterMessage("执行 SQL").Len(); n != 1 {
+		t.Fatalf("SQL 日志 %d 条: %v", n, logs.All())
+	}
+}
+
+func TestGormCallbacksCountSlowQueries(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := metrics.RegisterGormCallbacks(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec("CREATE TABLE accounts (id integer PRIMARY KEY, email text)")
+
+	slow := metrics.DBSlowQueries.WithLabelValues("query", "accounts")
+	ok := metrics.DBQueries.WithLabelValues("query", "accounts", "ok")
+	slowBefore, okBefore := promtest.ToFloat64(slow), promtest.ToFloat64(ok)
+
+	db.First(&account{}, 1) // 记录不存在计为 ok
+	db.Find(&[]account{})
+
+	if got := promtest.ToFloat64(ok) - okBefore; got != 2 {
+		t.Errorf("db_queries_total{result=ok} 增加 %v, 期望 2", got)
+	}
+	if got := promtest.ToFloat64(slow) - slowBefore; got != 2 {
+		t.Errorf("db_slow_queries_total 增加 %v, 期望 2", got)
+	}
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	zapLogger "github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replica 只读副本连接
+type replica struct {
+	// name 日志中的副本名称（DSN 含密码，不记录）
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaPolicy 读请求分发策略：在健康的副本间轮询，全部不可用时回退到主库
+type replicaPolicy struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// Resolve 选择执行读请求的连接（实现 dbresolver.Policy）
+func (p *replicaPolicy) Resolve([]gorm.ConnPool) gorm.ConnPool {
+	n := uint64(len(p.replicas))
+	start := p.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := p.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return p.primary
+}
+
+// check 检查全部副本，状态变化时记录日志
+func (p *replicaPolicy) check(ctx context.Context, timeout time.Duration) {
+	for _, r := range p.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		if healthy {
+			zapLogger.Info("只读副本恢复，重新分发读请求", zap.String("副本", r.name))
+		} else {
+			zapLogger.Warn("只读副本不可用，暂停分发读请求", zap.String("副本", r.name), zap.Error(err))
+		}
+	}
+}
+
+// run 定期检查副本健康状态，ctx 取消后返回
+func (p *replicaPolicy) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.check(ctx, interval)
+		}
+	}
+}
+
+var (
+	// replicas 已启用的读写分离策略，未配置副本时为 nil
+	replicas *replicaPolicy
+	// stopReplicaCheck 停止副本健康检查
+	stopReplicaCheck context.CancelFunc
+)
+
+// useReplicas 为 DB 注册读写分离：非事务的查询分发到健康的副本，写入、事务和 FOR UPDATE 查询走主库
+// 启动时不可用的副本不会导致启动失败，恢复后自动加入分发
+// 参数:
+//
+//	primary: 主库连接池
+//	cfg: 数据库配置
+//
+// 返回:
+//
+//	error: 错误信息
+func useReplicas(primary *sql.DB, cfg config.DatabaseConfig) error {
+	driver := cfg.GetDriver()
+	policy := &replicaPolicy{primary: primary}
+	dialectors := make([]gorm.Dialector, 0, len(cfg.Replicas))
+	for i, dsn := range cfg.Replicas {
+		db, err := sql.Open(sqlDriverName(driver), dsn)
+		if err != nil {
+			policy.close()
+			return fmt.Errorf("打开只读副本 %d 失败: %w", i+1, err)
+		}
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+		db.SetConnMaxLifetime(cfg.GetConnMaxLifetime())
+
+		r := &replica{name: fmt.Sprintf("replica-%d", i+1), db: db}
+		metrics.RegisterDBStats(db, cfg.DBName+"/"+r.name)
+		policy.replicas = append(policy.replicas, r)
+		dialectors = append(dialectors, connDialector(driver, db))
+	}
+
+	// 先检查一次，避免启动后第一个周期内把读请求发到不可用的副本
+	interval := cfg.GetReplicaCheckInterval()
+	policy.check(context.Background(), interval)
+
+	if err := DB.Use(dbresolver.Register(dbresolver.Config{Replicas: dialectors, Policy: policy})); err != nil {
+		policy.close()
+		return fmt.Errorf("注册读写分离失败: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go policy.run(ctx, interval)
+	replicas, stopReplicaCheck = policy, cancel
+
+	zapLogger.Info("已启用读写分离", zap.Int("副本数", len(policy.replicas)))
+	return nil
+}
+
+// closeReplicas 停止副本健康检查并关闭副本连接
+func closeReplicas() {
+	if stopReplicaCheck != nil {
+		stopReplicaCheck()
+	}
+	if replicas != nil {
+		replicas.close()
+	}
+	replicas, stopReplicaCheck = nil, nil
+}
+
+// close 关闭副本连接
+func (p *replicaPolicy) close() {
+	for _, r := range p.replicas {
+		if err := r.db.Close(); err != nil {
+			zapLogger.Warn("关闭只读副本失败", zap.String("副本", r.name), zap.Error(err))
+		}
+	}
+}
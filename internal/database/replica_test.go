@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestReplicaPolicy(t *testing.T) {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+		logger.Sugar = logger.Logger.Sugar()
+	}
+
+	// 端口 1 上没有数据库，Ping 立即失败
+	db1, err := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/test?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db1.Close() })
+	db2, _ := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/other?connect_timeout=1")
+	t.Cleanup(func() { db2.Close() })
+	primary, _ := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/primary?connect_timeout=1")
+	t.Cleanup(func() { primary.Close() })
+
+	a := &replica{name: "replica-1", db: db1}
+	b := &replica{name: "replica-2", db: db2}
+	p := &replicaPolicy{primary: primary, replicas: []*replica{a, b}}
+
+	// 启动时检查失败的副本不参与分发，全部不可用时回退到主库
+	p.check(context.Background(), time.Second)
+	if a.healthy.Load() || b.healthy.Load() {
+		t.Fatal("无法连接的副本应标记为不可用")
+	}
+	if got := p.Resolve(nil); got != gorm.ConnPool(primary) {
+		t.Errorf("全部副本不可用时应回退到主库, 得到 %v", got)
+	}
+
+	// 只有一个副本健康时读请求全部发往该副本
+	b.healthy.Store(true)
+	for i := 0; i < 3; i++ {
+		if got := p.Resolve(nil); got != gorm.ConnPool(db2) {
+			t.Errorf("应选择健康的副本, 得到 %v", got)
+		}
+	}
+
+	// 多个副本健康时轮询
+	a.healthy.Store(true)
+	seen := map[gorm.ConnPool]int{}
+	for i := 0; i < 4; i++ {
+		seen[p.Resolve(nil)]++
+	}
+	if seen[db1] != 2 || seen[db2] != 2 {
+		t.Errorf("应在健康副本间轮询, 得到 %v", seen)
+	}
+}
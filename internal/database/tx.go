@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey 上下文中事务的键
+type txKey struct{}
+
+// WithTx 把事务放入上下文，之后通过 Conn 获取连接的仓库和服务都在该事务中读写
+// 参数:
+//
+//	ctx: 上下文
+//	tx: 事务（gorm Transaction 回调中的 *gorm.DB）
+//
+// 返回:
+//
+//	context.Context: 带事务的上下文
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFrom 获取上下文中的事务
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	*gorm.DB: 事务，不在事务中时为 nil
+func TxFrom(ctx context.Context) *gorm.DB {
+	tx, _ := ctx.Value(txKey{}).(*gorm.DB)
+	return tx
+}
+
+// Conn 获取本次调用使用的数据库连接
+// 上下文中有事务（RunInTx 内）时返回该事务，否则返回 db；返回值已绑定 ctx
+// 参数:
+//
+//	ctx: 上下文
+//	db: 不在事务中时使用的连接（通常为 database.DB）
+//
+// 返回:
+//
+//	*gorm.DB: 数据库连接
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx := TxFrom(ctx); tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// RunInTx 在一个事务中执行 fn，fn 返回错误或 panic 时回滚
+// fn 收到的上下文带有事务，其中调用的仓库和服务（通过 Conn 获取连接）都加入该事务，
+// 因此处理器可以把多个服务调用组合为一个原子操作；
+// 已在事务中时嵌套执行（使用保存点），fn 出错只回滚自身的修改
+// 参数:
+//
+//	ctx: 上下文
+//	fn: 事务处理函数
+//
+// 返回:
+//
+//	error: fn 返回的错误或提交失败的错误
+func RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Conn(ctx, DB).Transaction(func(tx *gorm.DB) error {
+		return fn(WithTx(ctx, tx))
+	})
+}
@@ -0,0 +1,244 @@
+package deprecation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// 调用统计保存在 Redis 中，网关和 gRPC 服务的各实例共享
+const (
+	// noticesKey 被调用过的弃用声明（hash：接口 -> Notice JSON），网关的报告据此包含 gRPC 服务中声明的方法
+	noticesKey = "deprecation:notices"
+	// callsKeyPrefix 调用次数（hash：调用方 -> 次数），键为前缀 + 接口
+	callsKeyPrefix = "deprecation:calls:"
+	// lastSeenKeyPrefix 最近调用时间（hash：调用方 -> Unix 秒），键为前缀 + 接口
+	lastSeenKeyPrefix = "deprecation:last_seen:"
+)
+
+// Anonymous 未认证调用方的统计名称
+const Anonymous = "anonymous"
+
+// Notice 接口弃用声明
+type Notice struct {
+	// Endpoint HTTP 路由（方法 + 路由模板，如 GET /api/v1/users/:id）或 gRPC 完整方法名（如 /microservice.UserService/GetUser）
+	Endpoint string `json:"endpoint"`
+	// Since 弃用日期，写入 Deprecation 头
+	Since time.Time `json:"since"`
+	// Sunset 计划下线时间，写入 Sunset 头，零值表示尚未确定
+	Sunset time.Time `json:"sunset"`
+	// Replacement 替代接口
+	Replacement string `json:"replacement,omitempty"`
+	// Link 迁移说明文档，写入 Link 头（rel="deprecation"）
+	Link string `json:"link,omitempty"`
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Notice)
+)
+
+// Register 声明接口已弃用，同一接口重复声明时 panic
+// 通常在处理器所在文件中声明为包级变量，路由和方法本身无需修改
+// 参数:
+//
+//	n: 弃用声明
+//
+// 返回:
+//
+//	Notice: 注册的声明
+func Register(n Notice) Notice {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if n.Endpoint == "" || n.Since.IsZero() {
+		panic("弃用声明缺少 Endpoint 或 Since")
+	}
+	if _, exists := registry[n.Endpoint]; exists {
+		panic(fmt.Sprintf("接口 %s 重复声明弃用", n.Endpoint))
+	}
+	registry[n.Endpoint] = n
+	return n
+}
+
+// HTTPEndpoint 返回 HTTP 路由的接口名称
+// 参数:
+//
+//	method: 请求方法
+//	route: 路由模板（c.FullPath()）
+//
+// 返回:
+//
+//	string: 接口名称，如 GET /api/v1/users/:id
+func HTTPEndpoint(method, route string) string {
+	return method + " " + route
+}
+
+// Lookup 查找接口的弃用声明
+// 参数:
+//
+//	endpoint: 接口名称
+//
+// 返回:
+//
+//	Notice: 弃用声明
+//	bool: 接口是否已弃用
+func Lookup(endpoint string) (Notice, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	n, ok := registry[endpoint]
+	return n, ok
+}
+
+// Notices 返回全部弃用声明，按下线时间排序（未确定的排在最后）
+func Notices() []Notice {
+	mu.RLock()
+	notices := make([]Notice, 0, len(registry))
+	for _, n := range registry {
+		notices = append(notices, n)
+	}
+	mu.RUnlock()
+
+	sortNotices(notices)
+	return notices
+}
+
+// sortNotices 按下线时间排序，未确定的排在最后
+func sortNotices(notices []Notice) {
+	sort.Slice(notices, func(i, j int) bool {
+		a, b := notices[i], notices[j]
+		if a.Sunset.IsZero() != b.Sunset.IsZero() {
+			return b.Sunset.IsZero()
+		}
+		if !a.Sunset.Equal(b.Sunset) {
+			return a.Sunset.Before(b.Sunset)
+		}
+		return a.Endpoint < b.Endpoint
+	})
+}
+
+// Headers 返回告知调用方弃用信息的响应头（gRPC 以同名 metadata 返回）
+// Deprecation 为 @Unix 秒（RFC 9745），Sunset 为 HTTP 日期（RFC 8594）
+// 返回:
+//
+//	map[string]string: 头名称到值的映射
+func (n Notice) Headers() map[string]string {
+	headers := map[string]string{
+		"Deprecation": "@" + strconv.FormatInt(n.Since.Unix(), 10),
+	}
+	if !n.Sunset.IsZero() {
+		headers["Sunset"] = n.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if n.Link != "" {
+		headers["Link"] = fmt.Sprintf("<%s>; rel=\"deprecation\"", n.Link)
+	}
+	return headers
+}
+
+// Record 记录一次对弃用接口的调用：计入指标，并在 Redis 中按调用方累计次数和最近调用时间
+// 调用方首次调用时记录警告日志；Redis 不可用时只记录指标
+// 参数:
+//
+//	ctx: 上下文
+//	endpoint: 接口名称
+//	client: 调用方（用户名、API Key 名称或证书 CN），未认证时为 Anonymous
+func Record(ctx context.Context, endpoint, client string) {
+	metrics.ObserveDeprecatedCall(endpoint, client)
+	if cache.RedisClient == nil {
+		return
+	}
+
+	notice, _ := Lookup(endpoint)
+	data, _ := json.Marshal(notice)
+
+	pipe := cache.RedisClient.TxPipeline()
+	calls := pipe.HIncrBy(ctx, callsKeyPrefix+endpoint, client, 1)
+	pipe.HSet(ctx, lastSeenKeyPrefix+endpoint, client, time.Now().Unix())
+	pipe.HSet(ctx, noticesKey, endpoint, data)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warn("记录弃用接口调用失败", zap.String("接口", endpoint), zap.String("调用方", client), zap.Error(err))
+		return
+	}
+	if calls.Val() == 1 {
+		logger.Warn("调用方开始调用已弃用的接口", zap.String("接口", endpoint), zap.String("调用方", client))
+	}
+}
+
+// ClientUsage 调用方对弃用接口的调用情况
+type ClientUsage struct {
+	Client   string    `json:"client"`
+	Calls    int64     `json:"calls"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Usage 弃用接口及其调用情况
+type Usage struct {
+	Notice
+	// Calls 全部调用方的调用次数之和
+	Calls int64 `json:"calls"`
+	// Clients 各调用方的调用情况，最近调用的在前
+	Clients []ClientUsage `json:"clients"`
+}
+
+// Report 汇总全部弃用接口的调用情况，供管理接口判断接口能否按期下线
+// 包含本进程声明的接口，以及其他服务（如 gRPC 服务）中声明且被调用过的接口
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	[]Usage: 各弃用接口的调用情况，按下线时间排序
+//	error: 错误信息
+func Report(ctx context.Context) ([]Usage, error) {
+	stored, err := cache.HGetAll(ctx, noticesKey)
+	if err != nil {
+		return nil, err
+	}
+	notices := Notices()
+	for endpoint, raw := range stored {
+		if _, ok := Lookup(endpoint); ok {
+			continue
+		}
+		var n Notice
+		if err := json.Unmarshal([]byte(raw), &n); err == nil {
+			notices = append(notices, n)
+		}
+	}
+	sortNotices(notices)
+
+	report := make([]Usage, 0, len(notices))
+	for _, n := range notices {
+		calls, err := cache.HGetAll(ctx, callsKeyPrefix+n.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		lastSeen, err := cache.HGetAll(ctx, lastSeenKeyPrefix+n.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		usage := Usage{Notice: n, Clients: make([]ClientUsage, 0, len(calls))}
+		for client, raw := range calls {
+			count, _ := strconv.ParseInt(raw, 10, 64)
+			seen, _ := strconv.ParseInt(lastSeen[client], 10, 64)
+			usage.Calls += count
+			usage.Clients = append(usage.Clients, ClientUsage{Client: client, Calls: count, LastSeen: time.Unix(seen, 0)})
+		}
+		sort.Slice(usage.Clients, func(i, j int) bool {
+			return usage.Clients[i].LastSeen.After(usage.Clients[j].LastSeen)
+		})
+		report = append(report, usage)
+	}
+	return report, nil
+}
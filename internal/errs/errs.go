@@ -0,0 +1,294 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Domain gRPC ErrorInfo 详情中的错误域
+const Domain = "microservice"
+
+// Kind 错误类别，决定 gRPC 状态码、HTTP 状态码和 problem 响应的 type
+type Kind string
+
+// 错误类别
+const (
+	KindInternal         Kind = "internal"
+	KindNotFound         Kind = "not_found"
+	KindConflict         Kind = "conflict"
+	KindVersionConflict  Kind = "version_conflict" // 乐观锁冲突：资源已被其他请求修改，需重新读取后再提交
+	KindValidation       Kind = "validation"
+	KindUnauthenticated  Kind = "unauthenticated"
+	KindPermissionDenied Kind = "permission_denied"
+	KindUnavailable      Kind = "unavailable"
+)
+
+// kindInfo 类别对应的 gRPC 状态码和默认说明（HTTP 状态码由 gRPC 状态码经 HTTPStatus 映射）
+var kindInfo = map[Kind]struct {
+	code    codes.Code
+	message string
+}{
+	KindInternal:         {codes.Internal, "服务器内部错误"},
+	KindNotFound:         {codes.NotFound, "资源不存在"},
+	KindConflict:         {codes.AlreadyExists, "资源冲突"},
+	KindVersionConflict:  {codes.Aborted, "资源已被修改"},
+	KindValidation:       {codes.InvalidArgument, "请求参数错误"},
+	KindUnauthenticated:  {codes.Unauthenticated, "未认证"},
+	KindPermissionDenied: {codes.PermissionDenied, "权限不足"},
+	KindUnavailable:      {codes.Unavailable, "服务暂时不可用"},
+}
+
+// 各类别的哨兵错误，用 errors.Is 判断类别（如 errors.Is(err, errs.ErrNotFound)）
+var (
+	ErrInternal         = &Error{Kind: KindInternal}
+	ErrNotFound         = &Error{Kind: KindNotFound}
+	ErrConflict         = &Error{Kind: KindConflict}
+	ErrVersionConflict  = &Error{Kind: KindVersionConflict}
+	ErrValidation       = &Error{Kind: KindValidation}
+	ErrUnauthenticated  = &Error{Kind: KindUnauthenticated}
+	ErrPermissionDenied = &Error{Kind: KindPermissionDenied}
+	ErrUnavailable      = &Error{Kind: KindUnavailable}
+)
+
+// Error 带类别的错误
+// Message 返回给调用方；Err 为原因（如数据库错误），只记录日志，不返回给调用方
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+// New 创建错误
+// 参数:
+//
+//	kind: 类别
+//	format: 返回给调用方的说明
+//	args: 格式化参数
+//
+// 返回:
+//
+//	*Error: 错误
+func New(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound 资源不存在
+func NotFound(format string, args ...interface{}) *Error {
+	return New(KindNotFound, format, args...)
+}
+
+// Conflict 资源冲突（如唯一字段重复）
+func Conflict(format string, args ...interface{}) *Error {
+	return New(KindConflict, format, args...)
+}
+
+// Invalid 请求参数错误，有字段级错误时使用 validate.Error
+func Invalid(format string, args ...interface{}) *Error {
+	return New(KindValidation, format, args...)
+}
+
+// Internal 内部错误，调用方只会看到通用说明
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Err: err}
+}
+
+// Error 实现 error 接口，包含原因，用于日志
+func (e *Error) Error() string {
+	msg := e.message()
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap 返回原因
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is 同类别的 *Error 视为相同，使 errors.Is(err, ErrNotFound) 成立
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind
+}
+
+// message 返回给调用方的说明，未设置时为类别的默认说明
+func (e *Error) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return kindInfo[e.Kind].message
+}
+
+// GRPCStatus 转换为 gRPC 状态，附带 ErrorInfo 详情（reason 为大写的类别）
+// 实现该方法后 gRPC 方法可直接返回 *Error
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(kindInfo[e.Kind].code, e.message())
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: strings.ToUpper(string(e.Kind)),
+		Domain: Domain,
+	}); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// HTTPStatus 对应的 HTTP 状态码
+func (e *Error) HTTPStatus() int {
+	return HTTPStatus(kindInfo[e.Kind].code)
+}
+
+// HTTPStatus 将 gRPC 状态码映射为 HTTP 状态码（与 grpc-gateway 的映射一致）
+// 参数:
+//
+//	code: gRPC 状态码
+//
+// 返回:
+//
+//	int: HTTP 状态码
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// KindFromReason 从 ErrorInfo 的 reason 解析类别
+// 参数:
+//
+//	info: gRPC ErrorInfo 详情
+//
+// 返回:
+//
+//	Kind: 类别
+//	bool: 是否为本服务定义的类别
+func KindFromReason(info *errdetails.ErrorInfo) (Kind, bool) {
+	if info.GetDomain() != Domain {
+		return "", false
+	}
+	kind := Kind(strings.ToLower(info.GetReason()))
+	_, ok := kindInfo[kind]
+	return kind, ok
+}
+
+// PostgreSQL 错误码
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgStringTooLong       = "22001"
+)
+
+// FromDB 把数据库错误转换为带类别的错误，避免 gorm / PostgreSQL 的原始错误返回给调用方
+// 记录不存在为 NotFound，唯一约束冲突为 Conflict，违反外键、非空、检查约束或超长为 Validation，
+// 上下文取消或超时原样返回，其余为 Internal
+// 参数:
+//
+//	err: 数据库错误
+//
+// 返回:
+//
+//	error: 转换后的错误，err 为 nil 时返回 nil
+func FromDB(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Kind: KindNotFound, Err: err}
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &Error{Kind: KindConflict, Message: "记录已存在", Err: err}
+	}
+	if errors.Is(err, gorm.ErrForeignKeyViolated) {
+		return &Error{Kind: KindValidation, Message: "关联的记录不存在或仍被引用", Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return &Error{Kind: KindConflict, Message: "记录已存在", Err: err}
+		case pgForeignKeyViolation:
+			return &Error{Kind: KindValidation, Message: "关联的记录不存在或仍被引用", Err: err}
+		case pgNotNullViolation, pgCheckViolation:
+			return &Error{Kind: KindValidation, Message: "字段取值无效", Err: err}
+		case pgStringTooLong:
+			return &Error{Kind: KindValidation, Message: "字段超出长度限制", Err: err}
+		}
+	}
+	return Internal(err)
+}
+
+// Status 把错误转换为返回给调用方的 gRPC 状态
+// 实现了 GRPCStatus 的错误（*Error、*validate.Error、status 错误）使用其自身的状态，
+// 上下文取消或超时为 Canceled / DeadlineExceeded，其余未分类的错误为 Internal，原始信息不返回给调用方
+// 参数:
+//
+//	err: 错误
+//
+// 返回:
+//
+//	*status.Status: gRPC 状态，err 为 nil 时为 OK
+func Status(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, "请求已取消")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, "请求超时")
+	}
+	return Internal(err).GRPCStatus()
+}
+
+// ServerError 是否为服务端错误（需要记录错误日志，调用方只看到通用说明）
+// 参数:
+//
+//	code: gRPC 状态码
+//
+// 返回:
+//
+//	bool: Unknown、Internal、DataLoss 时为 true
+func ServerError(code codes.Code) bool {
+	return code == codes.Unknown || code == codes.Internal || code == codes.DataLoss
+}
@@ -0,0 +1,152 @@
+package errs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/testutil"
+	"github.com/zhang/microservice/internal/validate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+func TestFromDB(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+		code codes.Code
+	}{
+		{"记录不存在", gorm.ErrRecordNotFound, errs.ErrNotFound, codes.NotFound},
+		{"唯一约束", fmt.Errorf("创建: %w", &pgconn.PgError{Code: "23505", Detail: "Key (email)=(a@b.c) already exists."}), errs.ErrConflict, codes.AlreadyExists},
+		{"gorm 翻译后的重复键", gorm.ErrDuplicatedKey, errs.ErrConflict, codes.AlreadyExists},
+		{"外键", &pgconn.PgError{Code: "23503"}, errs.ErrValidation, codes.InvalidArgument},
+		{"gorm 翻译后的外键", gorm.ErrForeignKeyViolated, errs.ErrValidation, codes.InvalidArgument},
+		{"超长", &pgconn.PgError{Code: "22001"}, errs.ErrValidation, codes.InvalidArgument},
+		{"连接失败", errors.New("dial tcp 10.0.0.5:5432: connection refused"), errs.ErrInternal, codes.Internal},
+	}
+	for _, tt := range tests {
+		err := errs.FromDB(tt.err)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v 不是 %v", tt.name, err, tt.want)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: 应保留原始错误", tt.name)
+		}
+		st := errs.Status(err)
+		if st.Code() != tt.code {
+			t.Errorf("%s: 状态码 = %s, 期望 %s", tt.name, st.Code(), tt.code)
+		}
+		// 数据库的原始信息不返回给调用方
+		if strings.Contains(st.Message(), "10.0.0.5") || strings.Contains(st.Message(), "a@b.c") {
+			t.Errorf("%s: 状态信息泄露原始错误: %q", tt.name, st.Message())
+		}
+	}
+
+	if errs.FromDB(nil) != nil {
+		t.Error("nil 应返回 nil")
+	}
+	if err := errs.FromDB(context.Canceled); err != context.Canceled {
+		t.Errorf("上下文取消应原样返回, 实际 %v", err)
+	}
+	if code := errs.Status(context.DeadlineExceeded).Code(); code != codes.DeadlineExceeded {
+		t.Errorf("超时: 状态码 = %s", code)
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	st := errs.Status(errs.NotFound("用户 %d 不存在", 7))
+	if st.Code() != codes.NotFound || st.Message() != "用户 7 不存在" {
+		t.Errorf("状态 = %s %q", st.Code(), st.Message())
+	}
+	var info *errdetails.ErrorInfo
+	for _, d := range st.Details() {
+		if i, ok := d.(*errdetails.ErrorInfo); ok {
+			info = i
+		}
+	}
+	if kind, ok := errs.KindFromReason(info); !ok || kind != errs.KindNotFound {
+		t.Errorf("ErrorInfo = %v", info)
+	}
+
+	// 已经是 gRPC 状态的错误保持不变
+	if code := errs.Status(status.Error(codes.ResourceExhausted, "限流")).Code(); code != codes.ResourceExhausted {
+		t.Errorf("status 错误: 状态码 = %s", code)
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	p := errs.NewProblem(errs.Status(errs.Conflict("邮箱已被使用")))
+	if p.Status != http.StatusConflict || p.Type != "urn:microservice:problem:conflict" ||
+		p.Title != "资源冲突" || p.Detail != "邮箱已被使用" || p.Error != p.Detail || p.Code != "AlreadyExists" {
+		t.Errorf("problem = %+v", p)
+	}
+
+	// 乐观锁冲突与唯一约束冲突同为 409，type 不同
+	versionErr := errs.New(errs.KindVersionConflict, "用户已被修改")
+	if errors.Is(versionErr, errs.ErrConflict) || !errors.Is(versionErr, errs.ErrVersionConflict) {
+		t.Errorf("errors.Is 类别判断错误: %v", versionErr)
+	}
+	p = errs.NewProblem(errs.Status(versionErr))
+	if p.Status != http.StatusConflict || p.Type != "urn:microservice:problem:version_conflict" || p.Code != "Aborted" {
+		t.Errorf("problem = %+v", p)
+	}
+
+	// 字段级错误
+	name := ""
+	p = errs.NewProblem(errs.Status(validate.User(validate.UserInput{Name: &name})))
+	if p.Status != http.StatusBadRequest || p.Type != "urn:microservice:problem:validation" ||
+		len(p.Fields) != 1 || p.Fields[0].Field != "name" {
+		t.Errorf("problem = %+v", p)
+	}
+
+	// 没有 ErrorInfo 时按状态码推断
+	p = errs.NewProblem(status.New(codes.ResourceExhausted, "请求过于频繁"))
+	if p.Status != http.StatusTooManyRequests || p.Type != "urn:microservice:problem:resource_exhausted" || p.Title != "Too Many Requests" {
+		t.Errorf("problem = %+v", p)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := testutil.NewGinEngine(t, config.MiddlewareConfig{}, func(g *gin.RouterGroup) {
+		g.GET("/users/:id", func(c *gin.Context) {
+			if c.Param("id") == "1" {
+				errs.Write(c, errs.FromDB(gorm.ErrRecordNotFound))
+				return
+			}
+			errs.Write(c, errors.New("pq: password authentication failed"))
+		})
+	})
+
+	w := testutil.Do(r, httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("状态码 = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, errs.ProblemContentType) {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var p errs.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Instance != "/api/v1/users/1" || p.Type != "urn:microservice:problem:not_found" || p.Error == "" {
+		t.Errorf("problem = %+v", p)
+	}
+
+	w = testutil.Do(r, httptest.NewRequest(http.MethodGet, "/api/v1/users/2", nil))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "password") {
+		t.Errorf("内部错误: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,137 @@
+package errs
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// ProblemContentType RFC 7807 错误响应的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix problem type 的 URI 前缀，后接错误类别（如 urn:microservice:problem:not_found）
+const problemTypePrefix = "urn:" + Domain + ":problem:"
+
+// Problem RFC 7807 风格的错误响应
+// 在标准字段之外保留 error、code 字段，兼容按旧格式解析错误的客户端
+type Problem struct {
+	// Type 错误类型 URI，按错误类别区分
+	Type string `json:"type"`
+	// Title 错误类型的简短说明，同一类型不变
+	Title string `json:"title"`
+	// Status HTTP 状态码
+	Status int `json:"status"`
+	// Detail 本次错误的说明
+	Detail string `json:"detail,omitempty"`
+	// Instance 出错的请求路径
+	Instance string `json:"instance,omitempty"`
+	// RequestID 请求 ID，便于按日志排查
+	RequestID string `json:"request_id,omitempty"`
+	// Code gRPC 状态码名称（如 NOT_FOUND 对应 NotFound）
+	Code string `json:"code"`
+	// Error 与 Detail 相同，兼容旧的 {"error": "..."} 格式
+	Error string `json:"error"`
+	// Fields 字段级错误（google.rpc.BadRequest），格式与 validate.FieldViolation 一致
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Field 字段级错误
+type Field struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewProblem 根据 gRPC 状态生成错误响应
+// 错误类别优先取 ErrorInfo 详情，没有时按 gRPC 状态码推断
+// 参数:
+//
+//	st: gRPC 状态
+//
+// 返回:
+//
+//	*Problem: 错误响应（Instance、RequestID 由调用方填写）
+func NewProblem(st *status.Status) *Problem {
+	kind := Kind(toSnake(st.Code().String()))
+	p := &Problem{
+		Status: HTTPStatus(st.Code()),
+		Detail: st.Message(),
+		Code:   st.Code().String(),
+		Error:  st.Message(),
+	}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if k, ok := KindFromReason(d); ok {
+				kind = k
+			}
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				p.Fields = append(p.Fields, Field{Field: v.GetField(), Message: v.GetDescription()})
+			}
+		}
+	}
+	if len(p.Fields) > 0 {
+		kind = KindValidation
+	}
+
+	p.Type = problemTypePrefix + string(kind)
+	if info, ok := kindInfo[kind]; ok {
+		p.Title = info.message
+	} else {
+		p.Title = http.StatusText(p.Status)
+	}
+	return p
+}
+
+// Write 把错误写为 application/problem+json 响应
+// 服务端错误记录错误日志（含原始错误），响应中只包含通用说明
+// 参数:
+//
+//	c: Gin 上下文
+//	err: 错误
+func Write(c *gin.Context, err error) {
+	st := Status(err)
+	requestID := ctxkeys.RequestID(c)
+	if ServerError(st.Code()) {
+		logger.FromContext(c).Error("请求处理失败",
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err),
+		)
+	}
+
+	p := NewProblem(st)
+	p.Instance = c.Request.URL.Path
+	p.RequestID = requestID
+	WriteProblem(c, p)
+}
+
+// WriteProblem 写出错误响应并终止后续处理器
+// 参数:
+//
+//	c: Gin 上下文
+//	p: 错误响应
+func WriteProblem(c *gin.Context, p *Problem) {
+	c.Header("Content-Type", ProblemContentType+"; charset=utf-8")
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// toSnake 把 gRPC 状态码名称（如 NotFound）转为类别名称（not_found）
+func toSnake(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
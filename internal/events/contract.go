@@ -0,0 +1,239 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/queue"
+)
+
+// FieldType 事件字段的 JSON 类型
+type FieldType string
+
+const (
+	// String 字符串
+	String FieldType = "string"
+	// Number 数字
+	Number FieldType = "number"
+	// Bool 布尔值
+	Bool FieldType = "boolean"
+	// Object 对象
+	Object FieldType = "object"
+	// Array 数组
+	Array FieldType = "array"
+	// Time RFC 3339 格式的时间字符串
+	Time FieldType = "time"
+)
+
+// Field 事件字段
+type Field struct {
+	Type FieldType
+	// Optional 字段可以缺省或为 null
+	Optional bool
+}
+
+// Contract 服务间事件契约：发布方保证消息体的字段和类型，消费方据此解析
+// 不兼容的变更（删除字段、修改类型）必须递增 Version 并保留旧版本的样例
+type Contract struct {
+	// Name 事件名称，也是样例文件的目录名
+	Name string
+	// Version 契约版本
+	Version int
+	// RoutingKey 路由键模式（AMQP topic 规则）
+	RoutingKey string
+	// Fields 消息体（JSON 对象）的字段
+	Fields map[string]Field
+}
+
+var (
+	mu       sync.RWMutex
+	registry []Contract
+)
+
+// Register 注册事件契约，名称与版本重复时 panic
+// 参数:
+//
+//	c: 事件契约
+//
+// 返回:
+//
+//	Contract: 注册的契约，便于声明为包级变量
+func Register(c Contract) Contract {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, existing := range registry {
+		if existing.Name == c.Name && existing.Version == c.Version {
+			panic(fmt.Sprintf("事件契约 %s v%d 重复注册", c.Name, c.Version))
+		}
+	}
+	registry = append(registry, c)
+	return c
+}
+
+// Contracts 返回全部已注册的契约（含旧版本），按名称和版本排序
+func Contracts() []Contract {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	contracts := append([]Contract(nil), registry...)
+	sort.Slice(contracts, func(i, j int) bool {
+		if contracts[i].Name != contracts[j].Name {
+			return contracts[i].Name < contracts[j].Name
+		}
+		return contracts[i].Version < contracts[j].Version
+	})
+	return contracts
+}
+
+// Lookup 按路由键查找当前（最高）版本的契约
+// 参数:
+//
+//	routingKey: 发布消息的路由键
+//
+// 返回:
+//
+//	Contract: 事件契约
+//	bool: 是否找到
+func Lookup(routingKey string) (Contract, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var (
+		found Contract
+		ok    bool
+	)
+	for _, c := range registry {
+		if queue.MatchTopic(c.RoutingKey, routingKey) && (!ok || c.Version > found.Version) {
+			found, ok = c, true
+		}
+	}
+	return found, ok
+}
+
+// Validate 校验消息体是否符合契约：必填字段齐全、类型一致，且没有未声明的字段
+// 参数:
+//
+//	payload: 消息体
+//
+// 返回:
+//
+//	error: 全部不符合项合并为一个错误，nil 表示通过
+func (c Contract) Validate(payload []byte) error {
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return fmt.Errorf("%s v%d: 消息体不是 JSON 对象: %w", c.Name, c.Version, err)
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(c.Fields) {
+		field := c.Fields[name]
+		value, present := body[name]
+		if !present || value == nil {
+			if !field.Optional {
+				errs = append(errs, fmt.Errorf("%s v%d: 缺少字段 %s", c.Name, c.Version, name))
+			}
+			continue
+		}
+		if !matchesType(field.Type, value) {
+			errs = append(errs, fmt.Errorf("%s v%d: 字段 %s 应为 %s，实际为 %s", c.Name, c.Version, name, field.Type, TypeOf(value)))
+		}
+	}
+	for _, name := range sortedKeys(body) {
+		if _, ok := c.Fields[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s v%d: 字段 %s 未在契约中声明", c.Name, c.Version, name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shape 提取 JSON 对象各字段的类型（null 字段不计入）
+// 参数:
+//
+//	payload: JSON 对象
+//
+// 返回:
+//
+//	map[string]FieldType: 字段名到类型
+//	error: 不是 JSON 对象时返回错误
+func Shape(payload []byte) (map[string]FieldType, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, err
+	}
+	shape := make(map[string]FieldType, len(body))
+	for name, value := range body {
+		if value != nil {
+			shape[name] = TypeOf(value)
+		}
+	}
+	return shape, nil
+}
+
+// Compatible 检查新结构对旧结构是否向后兼容：旧结构的字段必须保留且类型不变，允许新增字段
+// 参数:
+//
+//	previous: 旧结构（如样例文件）
+//	current: 新结构（如实际发布的消息）
+//
+// 返回:
+//
+//	error: 全部不兼容项合并为一个错误，nil 表示兼容
+func Compatible(previous, current map[string]FieldType) error {
+	var errs []error
+	for _, name := range sortedKeys(previous) {
+		typ, ok := current[name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("删除了字段 %s", name))
+		case !sameType(previous[name], typ):
+			errs = append(errs, fmt.Errorf("字段 %s 的类型由 %s 变为 %s", name, previous[name], typ))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// TypeOf 返回 JSON 值的类型，RFC 3339 格式的字符串视为 Time
+func TypeOf(value interface{}) FieldType {
+	switch v := value.(type) {
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return Time
+		}
+		return String
+	case float64, json.Number:
+		return Number
+	case bool:
+		return Bool
+	case []interface{}:
+		return Array
+	case map[string]interface{}:
+		return Object
+	default:
+		return FieldType(fmt.Sprintf("%T", value))
+	}
+}
+
+// matchesType 值是否符合字段类型，时间格式的字符串也可以作为 String
+func matchesType(typ FieldType, value interface{}) bool {
+	return sameType(typ, TypeOf(value))
+}
+
+// sameType 类型是否一致：String 字段的值恰好是时间格式时不算类型变化
+func sameType(expected, actual FieldType) bool {
+	return expected == actual || (expected == String && actual == Time)
+}
+
+// sortedKeys 返回排序后的键，使错误信息顺序稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRegisteredContractsHaveFixtures 每个契约版本都必须有样例，且样例符合契约
+// 修改契约时删除字段或修改类型会使旧样例校验失败，需要注册新版本
+func TestRegisteredContractsHaveFixtures(t *testing.T) {
+	for _, c := range Contracts() {
+		path := filepath.Join("testdata", c.Name, fmt.Sprintf("v%d.json", c.Version))
+		fixture, err := os.ReadFile(path)
+		if err != nil {
+			t.Errorf("%s v%d 缺少样例: %v", c.Name, c.Version, err)
+			continue
+		}
+		if err := c.Validate(fixture); err != nil {
+			t.Errorf("样例 %s 不符合契约:\n%v", path, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	c := Contract{Name: "order.paid", Version: 1, Fields: map[string]Field{
+		"id":     {Type: Number},
+		"status": {Type: String},
+		"paid":   {Type: Time},
+		"note":   {Type: String, Optional: true},
+	}}
+
+	tests := []struct {
+		name    string
+		payload string
+		errs    []string
+	}{
+		{"完整", `{"id":1,"status":"paid","paid":"2024-01-01T00:00:00Z","note":"x"}`, nil},
+		{"可选字段为 null", `{"id":1,"status":"paid","paid":"2024-01-01T00:00:00Z","note":null}`, nil},
+		{"缺少字段", `{"id":1,"paid":"2024-01-01T00:00:00Z"}`, []string{"缺少字段 status"}},
+		{"类型错误", `{"id":"1","status":"paid","paid":"yesterday"}`, []string{"字段 id 应为 number", "字段 paid 应为 time"}},
+		{"未声明字段", `{"id":1,"status":"paid","paid":"2024-01-01T00:00:00Z","amount":3}`, []string{"字段 amount 未在契约中声明"}},
+		{"不是对象", `[1]`, []string{"不是 JSON 对象"}},
+	}
+	for _, tt := range tests {
+		err := c.Validate([]byte(tt.payload))
+		if len(tt.errs) == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: 期望校验失败", tt.name)
+			continue
+		}
+		for _, want := range tt.errs {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: 错误信息缺少 %q:\n%v", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	previous := map[string]FieldType{"id": Number, "name": String, "at": Time}
+
+	if err := Compatible(previous, map[string]FieldType{"id": Number, "name": String, "at": Time, "extra": Bool}); err != nil {
+		t.Errorf("新增字段应兼容: %v", err)
+	}
+	if err := Compatible(previous, map[string]FieldType{"id": Number, "name": Time, "at": Time}); err != nil {
+		t.Errorf("字符串字段的值为时间格式应兼容: %v", err)
+	}
+
+	err := Compatible(previous, map[string]FieldType{"id": String, "at": Time})
+	if err == nil || !strings.Contains(err.Error(), "删除了字段 name") || !strings.Contains(err.Error(), "字段 id 的类型由 number 变为 string") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLookupLatestVersion(t *testing.T) {
+	Register(Contract{Name: "test.lookup", Version: 1, RoutingKey: "test.lookup.#"})
+	Register(Contract{Name: "test.lookup", Version: 2, RoutingKey: "test.lookup.#"})
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		kept := registry[:0]
+		for _, c := range registry {
+			if c.Name != "test.lookup" {
+				kept = append(kept, c)
+			}
+		}
+		registry = kept
+	})
+
+	c, ok := Lookup("test.lookup.a.b")
+	if !ok || c.Version != 2 {
+		t.Errorf("Lookup = %+v, %v, 期望 v2", c, ok)
+	}
+	if _, ok := Lookup("other.event"); ok {
+		t.Error("未注册的路由键不应找到契约")
+	}
+
+	c, ok = Lookup("db.changed.users")
+	if !ok || c.Name != DBChanged.Name {
+		t.Errorf("Lookup(db.changed.users) = %+v, %v", c, ok)
+	}
+}
@@ -0,0 +1,31 @@
+package events
+
+// 已发布的事件契约
+// 新增事件或修改字段时同步更新 testdata/<名称>/v<版本>.json 样例，
+// 删除字段或修改类型属于不兼容变更，需要注册新版本并保留旧版本的契约和样例
+
+// DBChanged 数据库变更事件，定时任务服务收到 LISTEN/NOTIFY 后发布，路由键为 <notify.event_routing_key>.<表名>
+var DBChanged = Register(Contract{
+	Name:       "db.changed",
+	Version:    1,
+	RoutingKey: "db.changed.*",
+	Fields: map[string]Field{
+		"op":    {Type: String},
+		"table": {Type: String},
+		"id":    {Type: Number},
+		"at":    {Type: Time},
+	},
+})
+
+// UserSettingsChanged 用户设置变更事件，changed 为新的取值，reset 为恢复默认值的设置项
+var UserSettingsChanged = Register(Contract{
+	Name:       "user.settings.changed",
+	Version:    1,
+	RoutingKey: "user.settings.changed",
+	Fields: map[string]Field{
+		"user_id": {Type: Number},
+		"changed": {Type: Object},
+		"reset":   {Type: Array},
+		"at":      {Type: Time},
+	},
+})
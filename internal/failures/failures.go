@@ -0,0 +1,137 @@
+package failures
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/redact"
+)
+
+// Redacted 脱敏后替换的值
+const Redacted = redact.Redacted
+
+// Entry 一次失败请求的记录
+type Entry struct {
+	Time      time.Time   `json:"time"`
+	RequestID string      `json:"request_id"`
+	Method    string      `json:"method"`
+	Path      string      `json:"path"`
+	Query     string      `json:"query,omitempty"`
+	Status    int         `json:"status"`
+	Latency   string      `json:"latency"`
+	ClientIP  string      `json:"client_ip"`
+	UserID    int64       `json:"user_id,omitempty"`
+	Headers   http.Header `json:"headers"`
+	// RequestBody 脱敏后的请求体，二进制内容（文件上传等）只记录类型
+	RequestBody string `json:"request_body,omitempty"`
+	// ResponseBody 脱敏后的响应体
+	ResponseBody string `json:"response_body,omitempty"`
+	// Truncated 请求体或响应体超过 max_body_size 被截断
+	Truncated bool `json:"truncated,omitempty"`
+	// Errors 处理器通过 c.Error 附加的错误
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Recorder 固定容量的环形缓冲区，写满后覆盖最早的记录
+type Recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+	total   int64
+
+	maxBody int
+	payload *redact.Payload
+}
+
+// Default 全局失败请求记录器
+var Default = NewRecorder(config.FailureCaptureConfig{})
+
+// NewRecorder 创建失败请求记录器
+// 参数:
+//
+//	cfg: 失败请求记录配置
+//
+// 返回:
+//
+//	*Recorder: 记录器
+func NewRecorder(cfg config.FailureCaptureConfig) *Recorder {
+	return &Recorder{
+		entries: make([]Entry, cfg.GetSize()),
+		maxBody: cfg.GetMaxBodySize(),
+		payload: redact.NewPayload(cfg.RedactFields, false),
+	}
+}
+
+// Init 按配置重建全局记录器
+// 参数:
+//
+//	cfg: 失败请求记录配置
+func Init(cfg config.FailureCaptureConfig) {
+	Default = NewRecorder(cfg)
+}
+
+// MaxBodySize 请求体、响应体各保留的最大字节数
+func (r *Recorder) MaxBodySize() int {
+	return r.maxBody
+}
+
+// Add 记录一次失败请求
+// 参数:
+//
+//	e: 请求记录（Headers、Query、请求体和响应体在此脱敏，调用方传入原始内容）
+//	reqType: 请求的 Content-Type
+//	respType: 响应的 Content-Type
+func (r *Recorder) Add(e Entry, reqType, respType string) {
+	e.Headers = r.payload.Headers(e.Headers)
+	e.Query = r.payload.Query(e.Query)
+	e.RequestBody = r.payload.Body(e.RequestBody, reqType)
+	e.ResponseBody = r.payload.Body(e.ResponseBody, respType)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.total++
+}
+
+// List 返回记录，最新的在前
+// 参数:
+//
+//	limit: 最多返回条数，0 表示全部
+//
+// 返回:
+//
+//	[]Entry: 记录列表
+//	int64: 启动以来记录的失败请求总数（含已被覆盖的）
+func (r *Recorder) List(limit int) ([]Entry, int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.entries)
+	}
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	list := make([]Entry, 0, n)
+	for i := 1; i <= n; i++ {
+		list = append(list, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return list, r.total
+}
+
+// Clear 清空记录
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = make([]Entry, len(r.entries))
+	r.next = 0
+	r.full = false
+}
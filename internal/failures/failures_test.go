@@ -0,0 +1,68 @@
+package failures
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestRecorderRing(t *testing.T) {
+	r := NewRecorder(config.FailureCaptureConfig{Size: 3})
+	for _, id := range []string{"a", "b", "c", "d"} {
+		r.Add(Entry{RequestID: id}, "", "")
+	}
+
+	list, total := r.List(0)
+	ids := make([]string, 0, len(list))
+	for _, e := range list {
+		ids = append(ids, e.RequestID)
+	}
+	if strings.Join(ids, ",") != "d,c,b" || total != 4 {
+		t.Errorf("List() = %v, total %d, 期望 d,c,b 和 4", ids, total)
+	}
+	if list, _ := r.List(1); len(list) != 1 || list[0].RequestID != "d" {
+		t.Errorf("List(1) = %+v", list)
+	}
+
+	r.Clear()
+	if list, _ := r.List(0); len(list) != 0 {
+		t.Errorf("Clear 后仍有 %d 条记录", len(list))
+	}
+}
+
+func TestRedact(t *testing.T) {
+	r := NewRecorder(config.FailureCaptureConfig{RedactFields: []string{"SSN"}})
+	r.Add(Entry{
+		Headers:      http.Header{"Authorization": {"Bearer x"}, "X-Request-Id": {"r1"}},
+		Query:        "page=1&access_token=abc",
+		RequestBody:  `{"name":"张三","password":"p","profile":{"ssn":"123"},"items":[{"client_secret":"s","id":9007199254740993}]}`,
+		ResponseBody: "<html>upstream error</html>",
+	}, "application/json; charset=utf-8", "text/html")
+	r.Add(Entry{RequestBody: "user=a&password=p", ResponseBody: "\x89PNG"}, "application/x-www-form-urlencoded", "image/png")
+	r.Add(Entry{RequestBody: `{"password":"p`}, "application/json", "")
+
+	list, _ := r.List(0)
+	truncated, form, first := list[0], list[1], list[2]
+
+	if first.Headers.Get("Authorization") != Redacted || first.Headers.Get("X-Request-Id") != "r1" {
+		t.Errorf("请求头 = %v", first.Headers)
+	}
+	if first.Query != "access_token=%5BREDACTED%5D&page=1" {
+		t.Errorf("查询参数 = %s", first.Query)
+	}
+	expected := `{"items":[{"client_secret":"[REDACTED]","id":9007199254740993}],"name":"张三","password":"[REDACTED]","profile":{"ssn":"[REDACTED]"}}`
+	if first.RequestBody != expected {
+		t.Errorf("请求体 = %s\n期望 %s", first.RequestBody, expected)
+	}
+	if first.ResponseBody != "<html>upstream error</html>" {
+		t.Errorf("文本响应体应原样保留: %s", first.ResponseBody)
+	}
+	if form.RequestBody != "password=%5BREDACTED%5D&user=a" || strings.Contains(form.ResponseBody, "PNG") {
+		t.Errorf("表单 = %s, 二进制 = %s", form.RequestBody, form.ResponseBody)
+	}
+	if strings.Contains(truncated.RequestBody, "password") {
+		t.Errorf("无法解析的 JSON 应整体隐藏: %s", truncated.RequestBody)
+	}
+}
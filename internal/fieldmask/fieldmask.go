@@ -0,0 +1,153 @@
+package fieldmask
+
+import (
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// envelopeKeys 响应信封中承载资源的字段
+// 存在这些字段时只裁剪其中的资源，分页等元信息原样保留
+var envelopeKeys = []string{"data", "items"}
+
+// tree 字段路径树，如 id,profile.name -> {id: {}, profile: {name: {}}}
+type tree map[string]tree
+
+// Parse 解析 ?fields= 参数
+// 参数:
+//
+//	raw: 逗号分隔的字段列表，支持用 . 表示嵌套字段
+//
+// 返回:
+//
+//	[]string: 去除空白后的字段路径
+func Parse(raw string) []string {
+	var paths []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// buildTree 根据字段路径构建路径树
+func buildTree(paths []string) tree {
+	root := tree{}
+	for _, p := range paths {
+		node := root
+		for _, part := range strings.Split(p, ".") {
+			child, ok := node[part]
+			if !ok {
+				child = tree{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+// PruneJSON 裁剪 JSON 响应，只保留指定字段
+// 顶层为数组时逐个裁剪元素；顶层为对象且包含信封字段（data/items）时裁剪信封内的资源，
+// 否则裁剪顶层对象本身
+// 参数:
+//
+//	body: JSON 响应体
+//	paths: 字段路径
+//
+// 返回:
+//
+//	[]byte: 裁剪后的 JSON
+//	error: 解析失败时返回错误
+func PruneJSON(body []byte, paths []string) ([]byte, error) {
+	if len(paths) == 0 {
+		return body, nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	t := buildTree(paths)
+	if obj, ok := v.(map[string]interface{}); ok {
+		enveloped := false
+		for _, key := range envelopeKeys {
+			if inner, exists := obj[key]; exists {
+				obj[key] = pruneValue(inner, t)
+				enveloped = true
+			}
+		}
+		if !enveloped {
+			v = pruneValue(obj, t)
+		}
+	} else {
+		v = pruneValue(v, t)
+	}
+
+	return json.Marshal(v)
+}
+
+// pruneValue 按路径树裁剪 JSON 值
+func pruneValue(v interface{}, t tree) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, field := range val {
+			sub, ok := t[key]
+			if !ok {
+				delete(val, key)
+				continue
+			}
+			if len(sub) > 0 {
+				val[key] = pruneValue(field, sub)
+			}
+		}
+		return val
+	case []interface{}:
+		for i := range val {
+			val[i] = pruneValue(val[i], t)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// PruneMessage 按 FieldMask 路径裁剪 protobuf 消息，未列出的字段被清空
+// 参数:
+//
+//	m: protobuf 消息
+//	paths: FieldMask 路径（proto 字段名）
+func PruneMessage(m proto.Message, paths []string) {
+	if m == nil || len(paths) == 0 {
+		return
+	}
+	pruneMessage(m.ProtoReflect(), buildTree(paths))
+}
+
+// pruneMessage 按路径树裁剪 protobuf 消息
+func pruneMessage(m protoreflect.Message, t tree) {
+	var clear []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := t[string(fd.Name())]
+		switch {
+		case !ok:
+			clear = append(clear, fd)
+		case len(sub) > 0 && fd.Message() != nil && fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				pruneMessage(list.Get(i).Message(), sub)
+			}
+		case len(sub) > 0 && fd.Message() != nil && !fd.IsMap():
+			pruneMessage(v.Message(), sub)
+		}
+		return true
+	})
+
+	for _, fd := range clear {
+		m.Clear(fd)
+	}
+}
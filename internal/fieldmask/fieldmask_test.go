@@ -0,0 +1,39 @@
+package fieldmask
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	paths := Parse(" id, name ,,email")
+	if len(paths) != 3 || paths[0] != "id" || paths[1] != "name" || paths[2] != "email" {
+		t.Errorf("解析结果不符合预期: %v", paths)
+	}
+}
+
+func TestPruneJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		paths    []string
+		expected string
+	}{
+		{"顶层对象", `{"id":1,"name":"a","email":"e"}`, []string{"id", "name"}, `{"id":1,"name":"a"}`},
+		{"顶层数组", `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`, []string{"id"}, `[{"id":1},{"id":2}]`},
+		{"分页信封", `{"items":[{"id":1,"name":"a"}],"total":1}`, []string{"name"}, `{"items":[{"name":"a"}],"total":1}`},
+		{"嵌套字段", `{"data":{"id":1,"profile":{"city":"x","zip":"y"}}}`, []string{"profile.city"}, `{"data":{"profile":{"city":"x"}}}`},
+		{"未指定字段", `{"id":1}`, nil, `{"id":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := PruneJSON([]byte(tt.body), tt.paths)
+			if err != nil {
+				t.Fatalf("裁剪失败: %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("期望 %s, 实际 %s", tt.expected, result)
+			}
+		})
+	}
+}
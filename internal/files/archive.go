@@ -0,0 +1,157 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/zhang/microservice/internal/storage"
+)
+
+// 打包格式
+const (
+	// FormatZip zip 格式（默认）
+	FormatZip = "zip"
+	// FormatTar 未压缩的 tar 格式
+	FormatTar = "tar"
+)
+
+// ErrSizeMismatch 对象实际大小与上传记录不一致，打包中止，避免超出大小限制或写出损坏的 tar
+var ErrSizeMismatch = errors.New("对象大小与记录不一致")
+
+// ContentType 返回打包格式对应的 Content-Type
+// 参数:
+//
+//	format: 打包格式
+//
+// 返回:
+//
+//	string: Content-Type
+func ContentType(format string) string {
+	if format == FormatTar {
+		return "application/x-tar"
+	}
+	return "application/zip"
+}
+
+// Extension 返回打包格式对应的文件扩展名
+// 参数:
+//
+//	format: 打包格式
+//
+// 返回:
+//
+//	string: 扩展名（含点）
+func Extension(format string) string {
+	if format == FormatTar {
+		return ".tar"
+	}
+	return ".zip"
+}
+
+// WriteArchive 从对象存储逐个读取文件并打包写入 w，不在内存中缓存完整文件
+// 参数:
+//
+//	ctx: 上下文，取消后在下一个文件开始前中止
+//	w: 输出（HTTP 响应或临时文件）
+//	format: 打包格式
+//	objects: 文件记录，Size 用于 tar 头和大小校验
+//	store: 对象存储
+//
+// 返回:
+//
+//	error: 错误信息，已写出的部分不可回退
+func WriteArchive(ctx context.Context, w io.Writer, format string, objects []Object, store storage.ObjectStore) error {
+	names := entryNames(objects)
+
+	if format == FormatTar {
+		tw := tar.NewWriter(w)
+		for i, obj := range objects {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			header := &tar.Header{
+				Typeflag: tar.TypeReg,
+				Name:     names[i],
+				Mode:     0o644,
+				Size:     obj.Size,
+				ModTime:  obj.CreatedAt,
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			if err := copyObject(tw, store, obj); err != nil {
+				return err
+			}
+		}
+		return tw.Close()
+	}
+
+	zw := zip.NewWriter(w)
+	for i, obj := range objects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     names[i],
+			Method:   zip.Deflate,
+			Modified: obj.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		if err := copyObject(fw, store, obj); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// copyObject 下载对象并写入 w，写入字节数必须与记录的大小一致
+func copyObject(w io.Writer, store storage.ObjectStore, obj Object) error {
+	body, err := store.Download(obj.Key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if _, err := io.CopyN(w, body, obj.Size); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%w: %s", ErrSizeMismatch, obj.Key)
+		}
+		return fmt.Errorf("读取对象 %s 失败: %w", obj.Key, err)
+	}
+	if n, _ := body.Read(make([]byte, 1)); n > 0 {
+		return fmt.Errorf("%w: %s", ErrSizeMismatch, obj.Key)
+	}
+	return nil
+}
+
+// entryNames 生成包内文件名：优先使用原始文件名，否则取 Key 的最后一段，重名时追加序号，如 a.txt、a (1).txt
+func entryNames(objects []Object) []string {
+	names := make([]string, len(objects))
+	used := make(map[string]bool, len(objects))
+	for i, obj := range objects {
+		base := path.Base(obj.Key)
+		if obj.Filename != "" {
+			base = path.Base(strings.ReplaceAll(obj.Filename, `\`, "/"))
+		}
+		if base == "." || base == "/" {
+			base = "file"
+		}
+		name := base
+		ext := path.Ext(base)
+		stem := strings.TrimSuffix(base, ext)
+		for n := 1; used[name]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", stem, n, ext)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
@@ -0,0 +1,182 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/storage"
+)
+
+// memStore 内存对象存储，只实现 Download
+type memStore struct {
+	storage.ObjectStore
+	objects map[string]string
+}
+
+func (m *memStore) Download(key string) (io.ReadCloser, error) {
+	body, ok := m.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(strings.NewReader(body)), nil
+}
+
+func newTestObjects() ([]Object, *memStore) {
+	store := &memStore{objects: map[string]string{
+		"uploads/a_1.txt":  "hello",
+		"uploads/b/c.txt":  "world!",
+		"other/b/c.txt":    "again",
+		"uploads/empty.md": "",
+	}}
+	now := time.Now()
+	var objects []Object
+	for _, key := range []string{"uploads/a_1.txt", "uploads/b/c.txt", "other/b/c.txt", "uploads/empty.md"} {
+		objects = append(objects, Object{Key: key, Size: int64(len(store.objects[key])), CreatedAt: now})
+	}
+	return objects, store
+}
+
+func TestWriteArchiveZip(t *testing.T) {
+	objects, store := newTestObjects()
+
+	var buf bytes.Buffer
+	if err := WriteArchive(context.Background(), &buf, FormatZip, objects, store); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a_1.txt": "hello", "c.txt": "world!", "c (1).txt": "again", "empty.md": ""}
+	if len(zr.File) != len(want) {
+		t.Fatalf("文件数 = %d, 期望 %d", len(zr.File), len(want))
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(data) != want[f.Name] {
+			t.Errorf("%s = %q, 期望 %q", f.Name, data, want[f.Name])
+		}
+	}
+}
+
+func TestWriteArchiveTar(t *testing.T) {
+	objects, store := newTestObjects()
+
+	var buf bytes.Buffer
+	if err := WriteArchive(context.Background(), &buf, FormatTar, objects, store); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&buf)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		if int64(len(data)) != h.Size {
+			t.Errorf("%s 大小 = %d, 期望 %d", h.Name, len(data), h.Size)
+		}
+		names = append(names, h.Name)
+	}
+	if got := strings.Join(names, ","); got != "a_1.txt,c.txt,c (1).txt,empty.md" {
+		t.Errorf("文件名 = %s", got)
+	}
+}
+
+func TestWriteArchiveSizeMismatch(t *testing.T) {
+	for _, size := range []int64{3, 10} {
+		objects, store := newTestObjects()
+		objects[0].Size = size
+
+		err := WriteArchive(context.Background(), io.Discard, FormatTar, objects[:1], store)
+		if !errors.Is(err, ErrSizeMismatch) {
+			t.Errorf("记录大小 %d: err = %v, 期望 ErrSizeMismatch", size, err)
+		}
+	}
+}
+
+func TestWriteArchiveCanceled(t *testing.T) {
+	objects, store := newTestObjects()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := WriteArchive(ctx, io.Discard, FormatZip, objects, store); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, 期望 context.Canceled", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	found := map[string]Object{
+		"mine":      {Key: "mine", OwnerID: 1, Size: 10},
+		"theirs":    {Key: "theirs", OwnerID: 2, Size: 20},
+		"anonymous": {Key: "anonymous", OwnerID: 0, Size: 30},
+		"shared":    {Key: "shared", OwnerID: 2, SHA256: "abc", RefCount: 2},
+		"released":  {Key: "released", OwnerID: 1, SHA256: "def", RefCount: 1},
+	}
+	// 用户 1 重复上传了 shared，released 的引用已释放
+	held := map[string]bool{"shared": true}
+
+	tests := []struct {
+		name    string
+		keys    []string
+		userID  int64
+		admin   bool
+		allowed bool
+	}{
+		{"本人文件", []string{"mine"}, 1, false, true},
+		{"他人文件", []string{"mine", "theirs"}, 1, false, false},
+		{"匿名上传", []string{"anonymous"}, 1, false, false},
+		{"无记录", []string{"missing"}, 1, false, false},
+		{"持有引用", []string{"mine", "shared"}, 1, false, true},
+		{"引用已释放", []string{"released"}, 1, false, false},
+		{"管理员", []string{"mine", "theirs", "anonymous"}, 9, true, true},
+		{"管理员无记录", []string{"missing"}, 9, true, false},
+	}
+	for _, tt := range tests {
+		objects, err := Authorize(found, held, tt.keys, tt.userID, tt.admin)
+		if tt.allowed {
+			if err != nil || len(objects) != len(tt.keys) {
+				t.Errorf("%s: err = %v", tt.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s: err = %v, 期望 ErrForbidden", tt.name, err)
+		}
+	}
+
+	objects, _ := Authorize(found, nil, []string{"mine", "theirs", "anonymous"}, 0, true)
+	if TotalSize(objects) != 60 {
+		t.Errorf("TotalSize = %d, 期望 60", TotalSize(objects))
+	}
+}
+
+func TestEntryNamesPreferFilename(t *testing.T) {
+	objects := []Object{
+		{Key: casKey("uploads/", "aaa"), Filename: "report.pdf"},
+		{Key: casKey("uploads/", "bbb"), Filename: `C:\docs\report.pdf`},
+		{Key: casKey("uploads/", "ccc")},
+	}
+	got := strings.Join(entryNames(objects), ",")
+	if got != "report.pdf,report (1).pdf,ccc" {
+		t.Errorf("entryNames = %s", got)
+	}
+}
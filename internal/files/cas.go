@@ -0,0 +1,229 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Ref 内容寻址存储的引用：每次上传一条，记录上传用户
+// 同一内容被多个用户上传时共用一个对象，各自持有引用，可以打包和删除
+type Ref struct {
+	ID        int64     `gorm:"primaryKey" json:"id"`
+	Key       string    `gorm:"type:varchar(1024);not null;index:idx_file_refs_key_owner,priority:1" json:"key"`
+	OwnerID   int64     `gorm:"not null;index:idx_file_refs_key_owner,priority:2" json:"owner_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Ref) TableName() string {
+	return "file_refs"
+}
+
+// casKey 内容寻址存储的对象 Key：前缀 + cas/ + 内容 SHA-256
+func casKey(prefix, sum string) string {
+	return prefix + "cas/" + sum
+}
+
+// lockKey 在事务内对 Key 加锁，串行化同一内容的上传和删除，
+// 避免删除对象的同时另一个请求刚确认对象存在
+func lockKey(tx *gorm.DB, key string) error {
+	return database.XactLock(tx, key)
+}
+
+// StoreCAS 以内容寻址方式保存上传文件，内容已存在时只增加引用计数，不重复上传
+// 内容先写入临时文件并同时计算哈希，不在内存中保留整个文件；
+// 匿名上传无法释放引用，不持有引用也不增加引用计数（新内容的引用计数为 0），对象随最后一个登录用户的引用释放而删除
+// 参数:
+//
+//	ctx: 上下文
+//	store: 对象存储
+//	prefix: 对象前缀
+//	filename: 原始文件名
+//	content: 文件内容
+//	contentType: 文件类型
+//	ownerID: 上传用户，匿名上传为 0
+//
+// 返回:
+//
+//	*Object: 文件记录（重复上传时为已有对象）
+//	bool: 是否为重复内容
+//	error: 错误信息
+func StoreCAS(ctx context.Context, store storage.ObjectStore, prefix, filename string, content io.Reader, contentType string, ownerID int64) (*Object, bool, error) {
+	tmp, err := os.CreateTemp("", "cas-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), content)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	key := casKey(prefix, sum)
+
+	var (
+		obj       Object
+		duplicate bool
+	)
+	err = database.Conn(ctx, database.DB).Transaction(func(tx *gorm.DB) error {
+		if err := lockKey(tx, key); err != nil {
+			return err
+		}
+
+		err := tx.Where(map[string]interface{}{"key": key}).First(&obj).Error
+		switch {
+		case err == nil:
+			duplicate = true
+			if ownerID == 0 {
+				return nil
+			}
+			obj.RefCount++
+			if err := tx.Model(&obj).Update("ref_count", obj.RefCount).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			if err := store.PutObjectStream(key, tmp, size, contentType); err != nil {
+				return err
+			}
+			obj = Object{
+				Key:         key,
+				OwnerID:     ownerID,
+				Size:        size,
+				ContentType: contentType,
+				Filename:    filename,
+				SHA256:      sum,
+				RefCount:    1,
+			}
+			if err := tx.Create(&obj).Error; err != nil {
+				return err
+			}
+			if ownerID == 0 {
+				// 引用计数列有默认值 1，创建时无法写入 0
+				obj.RefCount = 0
+				return tx.Model(&obj).Update("ref_count", 0).Error
+			}
+		default:
+			return err
+		}
+
+		return tx.Create(&Ref{Key: key, OwnerID: ownerID}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &obj, duplicate, nil
+}
+
+// Release 释放当前用户对文件的引用，引用归零时删除对象和记录
+// 内容寻址存储的文件需持有引用；其他文件只有上传者本人可以删除
+// 参数:
+//
+//	ctx: 上下文
+//	store: 对象存储
+//	key: 对象 Key
+//	userID: 当前用户
+//
+// 返回:
+//
+//	*Object: 释放前的文件记录
+//	bool: 对象是否已被删除
+//	error: 文件不存在或无权删除时返回 ErrForbidden
+func Release(ctx context.Context, store storage.ObjectStore, key string, userID int64) (*Object, bool, error) {
+	if userID == 0 {
+		return nil, false, ErrForbidden
+	}
+
+	var (
+		obj     Object
+		removed bool
+	)
+	err := database.Conn(ctx, database.DB).Transaction(func(tx *gorm.DB) error {
+		if err := lockKey(tx, key); err != nil {
+			return err
+		}
+
+		if err := tx.Where(map[string]interface{}{"key": key}).First(&obj).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrForbidden
+			}
+			return err
+		}
+
+		if obj.SHA256 != "" {
+			var ref Ref
+			err := tx.Where(map[string]interface{}{"key": key, "owner_id": userID}).Order("id").First(&ref).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrForbidden
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Delete(&ref).Error; err != nil {
+				return err
+			}
+		} else if obj.OwnerID != userID {
+			return ErrForbidden
+		}
+
+		if obj.RefCount > 1 {
+			return tx.Model(&obj).Update("ref_count", obj.RefCount-1).Error
+		}
+
+		// 最后一个引用：先删记录，对象删除失败时回滚，记录保留
+		removed = true
+		if err := tx.Delete(&obj).Error; err != nil {
+			return err
+		}
+		return store.Delete(key)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &obj, removed, nil
+}
+
+// Held 查询当前用户持有引用的文件（内容寻址存储中他人先上传、自己重复上传的文件）
+// 参数:
+//
+//	ctx: 上下文
+//	keys: 对象 Key 列表
+//	userID: 当前用户
+//
+// 返回:
+//
+//	map[string]bool: 持有引用的 Key
+//	error: 错误信息
+func Held(ctx context.Context, keys []string, userID int64) (map[string]bool, error) {
+	held := make(map[string]bool)
+	if userID == 0 {
+		return held, nil
+	}
+
+	var refs []string
+	err := database.DB.WithContext(ctx).Model(&Ref{}).
+		Where(map[string]interface{}{"key": keys, "owner_id": userID}).
+		Distinct().Pluck("key", &refs).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range refs {
+		held[key] = true
+	}
+	return held, nil
+}
@@ -0,0 +1,98 @@
+package files
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/zhang/microservice/internal/database"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func (m *memStore) PutObjectStream(key string, body io.ReadSeeker, size int64, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	m.objects[key] = string(data)
+	return nil
+}
+
+func (m *memStore) Delete(key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+// useSQLite 以内存 SQLite 替换 database.DB
+func useSQLite(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&Object{}, &Ref{}); err != nil {
+		t.Fatal(err)
+	}
+	prevDB := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = prevDB
+		sqlDB.Close()
+	})
+}
+
+func TestStoreCASAnonymousRefs(t *testing.T) {
+	useSQLite(t)
+	ctx := context.Background()
+	store := &memStore{objects: map[string]string{}}
+	upload := func(owner int64) (*Object, bool) {
+		t.Helper()
+		obj, duplicate, err := StoreCAS(ctx, store, "uploads/", "a.txt", strings.NewReader("hello"), "text/plain", owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj, duplicate
+	}
+	refs := func() int64 {
+		var n int64
+		database.DB.Model(&Ref{}).Count(&n)
+		return n
+	}
+
+	// 匿名上传新内容：保存对象，不持有引用
+	obj, duplicate := upload(0)
+	key := "uploads/cas/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if duplicate || obj.Key != key || obj.Size != 5 || obj.RefCount != 0 || store.objects[key] != "hello" {
+		t.Fatalf("匿名上传 = %+v, %v", obj, duplicate)
+	}
+	if refs() != 0 {
+		t.Error("匿名上传不应创建引用")
+	}
+
+	if obj, duplicate = upload(1); !duplicate || obj.RefCount != 1 {
+		t.Fatalf("登录用户重复上传 = %+v, %v", obj, duplicate)
+	}
+	// 匿名重复上传不增加引用计数
+	if obj, duplicate = upload(0); !duplicate || obj.RefCount != 1 || refs() != 1 {
+		t.Fatalf("匿名重复上传 = %+v, %v, 引用 %d", obj, duplicate, refs())
+	}
+
+	// 唯一的登录用户释放引用后删除对象
+	if _, removed, err := Release(ctx, store, key, 1); err != nil || !removed {
+		t.Fatalf("Release = %v, %v", removed, err)
+	}
+	if _, ok := store.objects[key]; ok {
+		t.Error("引用归零后对象未删除")
+	}
+	if found, _ := Lookup(ctx, []string{key}); len(found) != 0 {
+		t.Error("引用归零后文件记录未删除")
+	}
+}
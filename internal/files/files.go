@@ -0,0 +1,124 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zhang/microservice/internal/database"
+)
+
+// ErrForbidden 文件不存在或无权访问（不区分两者，避免泄露其他用户的文件是否存在）
+var ErrForbidden = errors.New("文件不存在或无权访问")
+
+// Object 文件记录，上传时保存，用于打包下载时的权限检查和大小限制
+type Object struct {
+	Key string `gorm:"primaryKey;type:varchar(1024)" json:"key"`
+	// OwnerID 上传用户，0 表示匿名上传（仅管理员可访问）
+	OwnerID     int64  `gorm:"not null;index" json:"owner_id"`
+	Size        int64  `gorm:"not null" json:"size"`
+	ContentType string `gorm:"type:varchar(255)" json:"content_type"`
+	// Filename 原始文件名（内容寻址存储的 Key 不含文件名，打包时使用）
+	Filename string `gorm:"type:varchar(255)" json:"filename,omitempty"`
+	// SHA256 内容哈希，只有内容寻址存储的对象有值
+	SHA256 string `gorm:"type:char(64);index" json:"sha256,omitempty"`
+	// RefCount 引用计数，内容寻址存储中登录用户的每次上传递增（匿名上传不计入），释放到零时删除对象
+	RefCount  int       `gorm:"not null;default:1" json:"ref_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (Object) TableName() string {
+	return "file_objects"
+}
+
+// Record 保存上传文件记录
+// 参数:
+//
+//	ctx: 上下文
+//	key: 对象 Key
+//	ownerID: 上传用户，匿名上传为 0
+//	size: 文件大小
+//	contentType: 文件类型
+//
+// 返回:
+//
+//	error: 错误信息
+func Record(ctx context.Context, key string, ownerID, size int64, contentType string) error {
+	obj := &Object{
+		Key:         key,
+		OwnerID:     ownerID,
+		Size:        size,
+		ContentType: contentType,
+		RefCount:    1,
+	}
+	return database.DB.WithContext(ctx).Create(obj).Error
+}
+
+// Lookup 批量查询文件记录
+// 参数:
+//
+//	ctx: 上下文
+//	keys: 对象 Key 列表
+//
+// 返回:
+//
+//	map[string]Object: Key 到记录的映射，没有记录的 Key 不在其中
+//	error: 错误信息
+func Lookup(ctx context.Context, keys []string) (map[string]Object, error) {
+	var objects []Object
+	// key 在 MySQL 中是保留字，使用 map 条件由 gorm 按方言为列名加引号
+	if err := database.DB.WithContext(ctx).Where(map[string]interface{}{"key": keys}).Find(&objects).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[string]Object, len(objects))
+	for _, obj := range objects {
+		found[obj.Key] = obj
+	}
+	return found, nil
+}
+
+// TotalSize 文件总大小
+// 参数:
+//
+//	objects: 文件记录
+//
+// 返回:
+//
+//	int64: 字节数
+func TotalSize(objects []Object) int64 {
+	var total int64
+	for _, obj := range objects {
+		total += obj.Size
+	}
+	return total
+}
+
+// Authorize 逐个检查文件访问权限：管理员可访问全部文件，
+// 其他用户只能访问自己上传的文件（内容寻址存储中包括持有引用的文件）
+// 参数:
+//
+//	found: Lookup 查询到的文件记录
+//	held: Held 查询到的当前用户持有引用的文件
+//	keys: 请求的对象 Key，按此顺序返回
+//	userID: 当前用户
+//	admin: 是否管理员
+//
+// 返回:
+//
+//	[]Object: 文件记录
+//	error: 任一文件没有记录或无权访问时返回 ErrForbidden
+func Authorize(found map[string]Object, held map[string]bool, keys []string, userID int64, admin bool) ([]Object, error) {
+	objects := make([]Object, 0, len(keys))
+	for _, key := range keys {
+		obj, ok := found[key]
+		// 内容寻址存储的对象以引用为准，上传者释放引用后不再可访问
+		owned := userID != 0 && (held[key] || (obj.SHA256 == "" && obj.OwnerID == userID))
+		if !ok || (!admin && !owned) {
+			return nil, fmt.Errorf("%w: %s", ErrForbidden, key)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
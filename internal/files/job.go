@@ -0,0 +1,172 @@
+package files
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/storage"
+	"go.uber.org/zap"
+)
+
+// 异步打包任务状态
+const (
+	// JobRunning 打包中
+	JobRunning = "running"
+	// JobDone 已完成，结果可下载
+	JobDone = "done"
+	// JobFailed 打包失败
+	JobFailed = "failed"
+)
+
+// jobKeyPrefix 任务状态（JSON）的 Redis Key 前缀，与打包结果保留相同时间
+const jobKeyPrefix = "archive:job:"
+
+// jobTimeout 单个异步打包任务的最长执行时间
+const jobTimeout = 30 * time.Minute
+
+// ErrJobNotFound 任务不存在或已过期
+var ErrJobNotFound = errors.New("打包任务不存在或已过期")
+
+// Job 异步打包任务
+type Job struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// UserID 发起打包的用户，只有本人和管理员可以查询
+	UserID int64  `json:"user_id"`
+	Format string `json:"format"`
+	Files  int    `json:"files"`
+	Size   int64  `json:"size"`
+	// Key 打包结果的对象 Key，完成后有效
+	Key        string     `json:"key,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// StartJob 创建异步打包任务，在后台打包并上传到对象存储
+// 网关重启时进行中的任务会中断，状态保持 running 直到过期，客户端需重新发起
+// 参数:
+//
+//	ctx: 上下文（只用于保存任务状态，打包本身不受请求结束影响）
+//	userID: 发起用户
+//	format: 打包格式
+//	name: 打包文件名（不含扩展名）
+//	objects: 已通过权限检查的文件记录
+//	cfg: 打包配置
+//
+// 返回:
+//
+//	*Job: 任务
+//	error: 错误信息
+func StartJob(ctx context.Context, userID int64, format, name string, objects []Object, cfg config.ArchiveConfig) (*Job, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+
+	job := &Job{
+		ID:        id,
+		Status:    JobRunning,
+		UserID:    userID,
+		Format:    format,
+		Files:     len(objects),
+		Size:      TotalSize(objects),
+		Key:       cfg.GetPrefix() + id + "/" + name + Extension(format),
+		CreatedAt: time.Now(),
+	}
+	if err := saveJob(ctx, job, cfg.GetExpire()); err != nil {
+		return nil, err
+	}
+
+	go runJob(job, objects, cfg.GetExpire())
+	return job, nil
+}
+
+// GetJob 查询异步打包任务
+// 参数:
+//
+//	ctx: 上下文
+//	id: 任务 ID
+//
+// 返回:
+//
+//	*Job: 任务
+//	error: 不存在或已过期时返回 ErrJobNotFound
+func GetJob(ctx context.Context, id string) (*Job, error) {
+	job, found, err := cache.GetJSON[*Job](ctx, jobKeyPrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// runJob 打包到临时文件后上传，更新任务状态
+func runJob(job *Job, objects []Object, expire time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+
+	err := buildAndUpload(ctx, job, objects)
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		job.Key = ""
+		logger.Error("异步打包失败", zap.String("任务", job.ID), zap.Error(err))
+	} else {
+		job.Status = JobDone
+		logger.Info("异步打包完成",
+			zap.String("任务", job.ID),
+			zap.Int("文件数", job.Files),
+			zap.Int64("大小", job.Size),
+		)
+	}
+
+	// 任务超时后 ctx 已取消，状态仍需保存
+	if err := saveJob(context.Background(), job, expire); err != nil {
+		logger.Error("保存打包任务状态失败", zap.String("任务", job.ID), zap.Error(err))
+	}
+}
+
+// buildAndUpload 打包到临时文件并上传到对象存储
+func buildAndUpload(ctx context.Context, job *Job, objects []Object) error {
+	if storage.S3Storage == nil {
+		return storage.ErrUnavailable
+	}
+
+	tmp, err := os.CreateTemp("", "archive-*"+Extension(job.Format))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := WriteArchive(ctx, tmp, job.Format, objects, storage.S3Storage); err != nil {
+		return err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return storage.S3Storage.PutObjectStream(job.Key, tmp, size, ContentType(job.Format))
+}
+
+// saveJob 保存任务状态
+func saveJob(ctx context.Context, job *Job, expire time.Duration) error {
+	return cache.SetJSON(ctx, jobKeyPrefix+job.ID, job, expire)
+}
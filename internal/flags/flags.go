@@ -0,0 +1,103 @@
+package flags
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// Subject 功能开关评估对象
+type Subject struct {
+	UserID int64
+	Role   string
+}
+
+// Engine 功能开关评估器
+type Engine struct {
+	flags map[string]config.FlagConfig
+}
+
+// DefaultEngine 全局功能开关评估器
+var DefaultEngine = NewEngine(nil)
+
+// NewEngine 创建功能开关评估器
+// 参数:
+//
+//	flags: 功能开关配置
+//
+// 返回:
+//
+//	*Engine: 评估器
+func NewEngine(flags map[string]config.FlagConfig) *Engine {
+	if flags == nil {
+		flags = make(map[string]config.FlagConfig)
+	}
+	return &Engine{flags: flags}
+}
+
+// Init 按配置初始化全局评估器
+// 参数:
+//
+//	flags: 功能开关配置
+func Init(flags map[string]config.FlagConfig) {
+	DefaultEngine = NewEngine(flags)
+}
+
+// Enabled 评估单个功能开关
+// 参数:
+//
+//	name: 开关名称
+//	subject: 评估对象
+//
+// 返回:
+//
+//	bool: 是否开启（未配置的开关为 false）
+func (e *Engine) Enabled(name string, subject Subject) bool {
+	flag, ok := e.flags[name]
+	if !ok || !flag.Enabled {
+		return false
+	}
+
+	if len(flag.Roles) > 0 && !contains(flag.Roles, subject.Role) {
+		return false
+	}
+
+	if flag.Percentage > 0 && flag.Percentage < 100 {
+		return bucket(name, subject.UserID) < flag.Percentage
+	}
+	return true
+}
+
+// Evaluate 评估所有功能开关
+// 参数:
+//
+//	subject: 评估对象
+//
+// 返回:
+//
+//	map[string]bool: 开关名称到结果的映射
+func (e *Engine) Evaluate(subject Subject) map[string]bool {
+	result := make(map[string]bool, len(e.flags))
+	for name := range e.flags {
+		result[name] = e.Enabled(name, subject)
+	}
+	return result
+}
+
+// bucket 将用户稳定地映射到 0-99 的灰度桶，不同开关使用不同的分桶
+func bucket(name string, userID int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + strconv.FormatInt(userID, 10)))
+	return int(h.Sum32() % 100)
+}
+
+// contains 检查切片是否包含指定值
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package flags
+
+import (
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestEnabled(t *testing.T) {
+	engine := NewEngine(map[string]config.FlagConfig{
+		"on":      {Enabled: true},
+		"off":     {Enabled: false},
+		"admins":  {Enabled: true, Roles: []string{"admin"}},
+		"rollout": {Enabled: true, Percentage: 50},
+		"full":    {Enabled: true, Percentage: 100},
+	})
+
+	user := Subject{UserID: 1, Role: "user"}
+	admin := Subject{UserID: 2, Role: "admin"}
+
+	if !engine.Enabled("on", user) {
+		t.Error("on 应对所有用户开启")
+	}
+	if engine.Enabled("off", user) {
+		t.Error("off 不应开启")
+	}
+	if engine.Enabled("missing", user) {
+		t.Error("未配置的开关不应开启")
+	}
+	if engine.Enabled("admins", user) || !engine.Enabled("admins", admin) {
+		t.Error("admins 应只对管理员开启")
+	}
+	if !engine.Enabled("full", user) {
+		t.Error("100% 灰度应对所有用户开启")
+	}
+
+	// 灰度结果对同一用户稳定，且大致符合比例
+	enabled := 0
+	for id := int64(1); id <= 1000; id++ {
+		s := Subject{UserID: id}
+		first := engine.Enabled("rollout", s)
+		if engine.Enabled("rollout", s) != first {
+			t.Fatalf("用户 %d 灰度结果不稳定", id)
+		}
+		if first {
+			enabled++
+		}
+	}
+	if enabled < 400 || enabled > 600 {
+		t.Errorf("50%% 灰度开启了 %d/1000 个用户", enabled)
+	}
+}
@@ -0,0 +1,174 @@
+package grpcclient
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+)
+
+// DialOptions 根据服务端的 gRPC 配置生成匹配的客户端选项（传输凭证、消息大小、keepalive、连接超时）
+// 客户端的发送上限对应服务端的接收上限，接收上限对应服务端的发送上限
+// 参数:
+//
+//	cfg: gRPC 配置
+//
+// 返回:
+//
+//	[]grpc.DialOption: 客户端选项
+//	error: CA 证书加载失败时返回错误
+func DialOptions(cfg config.GRPCConfig) ([]grpc.DialOption, error) {
+	creds, err := Credentials(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithKeepaliveParams(Keepalive(cfg)),
+		grpc.WithChainUnaryInterceptor(PropagateRequestID()),
+		grpc.WithChainStreamInterceptor(PropagateStreamRequestID()),
+	}
+	if cfg.CallTimeout > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(DefaultTimeout(time.Duration(cfg.CallTimeout)*time.Second)))
+	}
+
+	var callOpts []grpc.CallOption
+	if cfg.MaxRecvMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(cfg.MaxRecvMsgSize*1024*1024))
+	}
+	if cfg.MaxSendMsgSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(cfg.MaxSendMsgSize*1024*1024))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+
+	if cfg.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: time.Duration(cfg.ConnectionTimeout) * time.Second,
+		}))
+	}
+
+	return opts, nil
+}
+
+// DefaultTimeout 为没有截止时间的调用设置超时时间；上下文已有截止时间时（如来自网关请求）原样使用，
+// 截止时间随调用以 grpc-timeout 传给服务端，服务端的数据库、Redis 调用随之取消
+// 参数:
+//
+//	timeout: 默认超时时间
+//
+// 返回:
+//
+//	grpc.UnaryClientInterceptor: 客户端拦截器
+func DefaultTimeout(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// PropagateRequestID 把上下文中的请求 ID（见 ctxkeys.RequestID）以 metadata x-request-id 传给服务端，
+// 服务端的日志使用同一个请求 ID；调用方已在 metadata 中设置时不覆盖
+// 返回:
+//
+//	grpc.UnaryClientInterceptor: 客户端拦截器
+func PropagateRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// PropagateStreamRequestID 流式调用的请求 ID 传递，见 PropagateRequestID
+// 返回:
+//
+//	grpc.StreamClientInterceptor: 客户端拦截器
+func PropagateStreamRequestID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// outgoingRequestID 把请求 ID 加入发出的 metadata
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := ctxkeys.RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(ctxkeys.RequestIDMetadata)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, ctxkeys.RequestIDMetadata, id)
+}
+
+// Dial 按配置连接 cfg.Target（连接在首次调用时建立）
+// 参数:
+//
+//	cfg: gRPC 配置
+//	extra: 额外的客户端选项（如拦截器）
+//
+// 返回:
+//
+//	*grpc.ClientConn: 客户端连接
+//	error: 错误信息
+func Dial(cfg config.GRPCConfig, extra ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts, err := DialOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(cfg.Target, append(opts, extra...)...)
+}
+
+// Keepalive 客户端 keepalive 参数
+// ping 间隔不小于服务端允许的最小间隔，否则服务端会以 too_many_pings 断开连接
+// 参数:
+//
+//	cfg: gRPC 配置
+//
+// 返回:
+//
+//	keepalive.ClientParameters: keepalive 参数
+func Keepalive(cfg config.GRPCConfig) keepalive.ClientParameters {
+	interval := cfg.KeepaliveTime
+	if interval < cfg.KeepaliveMinTime {
+		interval = cfg.KeepaliveMinTime
+	}
+	return keepalive.ClientParameters{
+		Time:                time.Duration(interval) * time.Second,
+		Timeout:             time.Duration(cfg.KeepaliveTimeout) * time.Second,
+		PermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+	}
+}
+
+// Credentials 客户端传输凭证，未启用 TLS 时使用明文连接
+// 参数:
+//
+//	cfg: gRPC TLS 配置
+//
+// 返回:
+//
+//	credentials.TransportCredentials: 传输凭证
+//	error: CA 证书加载失败时返回错误
+func Credentials(cfg config.GRPCTLSConfig) (credentials.TransportCredentials, error) {
+	if !cfg.Enable {
+		return insecure.NewCredentials(), nil
+	}
+	if cfg.CAFile == "" {
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), nil
+	}
+	return credentials.NewClientTLSFromFile(cfg.CAFile, "")
+}
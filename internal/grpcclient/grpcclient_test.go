@@ -0,0 +1,88 @@
+package grpcclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestKeepalive(t *testing.T) {
+	// ping 间隔小于服务端允许的最小间隔时取最小间隔
+	params := Keepalive(config.GRPCConfig{KeepaliveTime: 5, KeepaliveMinTime: 10, KeepaliveTimeout: 3})
+	if params.Time != 10*time.Second || params.Timeout != 3*time.Second {
+		t.Errorf("Keepalive() = %+v", params)
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	opts, err := DialOptions(config.GRPCConfig{MaxRecvMsgSize: 4, MaxSendMsgSize: 4, ConnectionTimeout: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 传输凭证、keepalive、请求 ID 传递（一元、流）、默认调用选项、连接参数
+	if len(opts) != 6 {
+		t.Errorf("选项数 = %d, 期望 6", len(opts))
+	}
+
+	if creds, err := Credentials(config.GRPCTLSConfig{Enable: true}); err != nil || creds.Info().SecurityProtocol != "tls" {
+		t.Errorf("系统根证书: %v", err)
+	}
+	if _, err := DialOptions(config.GRPCConfig{TLS: config.GRPCTLSConfig{Enable: true, CAFile: "missing.pem"}}); err == nil {
+		t.Error("CA 文件不存在时应返回错误")
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	interceptor := DefaultTimeout(time.Minute)
+	invoke := func(ctx context.Context) time.Duration {
+		var remaining time.Duration
+		_ = interceptor(ctx, "/svc/Method", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			if deadline, ok := ctx.Deadline(); ok {
+				remaining = time.Until(deadline)
+			}
+			return nil
+		})
+		return remaining
+	}
+
+	if d := invoke(context.Background()); d <= 59*time.Second || d > time.Minute {
+		t.Errorf("无截止时间时应使用默认超时, 剩余 %v", d)
+	}
+	// 已有截止时间（如来自网关请求）时沿用
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if d := invoke(ctx); d > 5*time.Second {
+		t.Errorf("应沿用上下文的截止时间, 剩余 %v", d)
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	interceptor := PropagateRequestID()
+	invoke := func(ctx context.Context) []string {
+		var ids []string
+		_ = interceptor(ctx, "/svc/Method", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			ids = md.Get("x-request-id")
+			return nil
+		})
+		return ids
+	}
+
+	if ids := invoke(context.Background()); len(ids) != 0 {
+		t.Errorf("没有请求 ID 时不应设置 metadata, 得到 %v", ids)
+	}
+	ctx := ctxkeys.WithRequestID(context.Background(), "req-1")
+	if ids := invoke(ctx); len(ids) != 1 || ids[0] != "req-1" {
+		t.Errorf("应传递上下文中的请求 ID, 得到 %v", ids)
+	}
+	// 调用方已设置时不覆盖
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "upstream")
+	if ids := invoke(ctx); len(ids) != 1 || ids[0] != "upstream" {
+		t.Errorf("不应覆盖已有的请求 ID, 得到 %v", ids)
+	}
+}
@@ -0,0 +1,110 @@
+package grpchealth
+
+import (
+	"context"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Dependency 服务依赖的外部组件
+type Dependency struct {
+	// Name 组件名称，同时作为健康检查的服务名（如 database、redis）
+	Name string
+	// Check 检查函数，返回 nil 表示正常
+	Check func() error
+}
+
+// Checker 定期检查依赖并更新 grpc.health.v1 服务的状态
+// 依赖全部正常时整体（空服务名）和各 gRPC 服务为 SERVING，否则为 NOT_SERVING，
+// 与 HTTP 的 /health/detail 判断一致；各依赖也以自身名称单独报告
+type Checker struct {
+	server   *health.Server
+	deps     []Dependency
+	services []string
+	interval time.Duration
+}
+
+// New 创建健康检查器，首次检查前所有服务均为 NOT_SERVING
+// 参数:
+//
+//	interval: 检查间隔
+//	deps: 依赖组件
+//
+// 返回:
+//
+//	*Checker: 健康检查器
+func New(interval time.Duration, deps ...Dependency) *Checker {
+	c := &Checker{
+		server:   health.NewServer(),
+		deps:     deps,
+		interval: interval,
+	}
+	c.server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Register 把健康检查服务注册到 gRPC 服务器
+// 参数:
+//
+//	s: gRPC 服务器
+//	services: 需要报告状态的 gRPC 服务全名（通常为 module.SetupGRPC 的返回值）
+func (c *Checker) Register(s *grpc.Server, services []string) {
+	c.services = services
+	healthpb.RegisterHealthServer(s, c.server)
+}
+
+// Update 检查一次依赖并更新状态
+// 返回:
+//
+//	bool: 依赖是否全部正常
+func (c *Checker) Update() bool {
+	healthy := true
+	for _, dep := range c.deps {
+		status := healthpb.HealthCheckResponse_SERVING
+		if err := dep.Check(); err != nil {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+			healthy = false
+			logger.Warn("gRPC 健康检查: 依赖不可用", zap.String("依赖", dep.Name), zap.Error(err))
+		}
+		c.server.SetServingStatus(dep.Name, status)
+	}
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if !healthy {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	c.server.SetServingStatus("", status)
+	for _, svc := range c.services {
+		c.server.SetServingStatus(svc, status)
+	}
+	return healthy
+}
+
+// Run 立即检查一次，之后每隔 interval 检查，直到 ctx 取消
+// 参数:
+//
+//	ctx: 上下文
+func (c *Checker) Run(ctx context.Context) {
+	c.Update()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Update()
+		}
+	}
+}
+
+// Shutdown 将所有服务置为 NOT_SERVING 且不再更新，关闭服务器前调用，使负载均衡停止分发新请求
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
@@ -0,0 +1,71 @@
+package grpchealth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/grpchealth"
+	"github.com/zhang/microservice/internal/testutil"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestChecker(t *testing.T) {
+	var redisErr error
+	checker := grpchealth.New(time.Minute,
+		grpchealth.Dependency{Name: "database", Check: func() error { return nil }},
+		grpchealth.Dependency{Name: "redis", Check: func() error { return redisErr }},
+	)
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		checker.Register(s, []string{"user.UserService"})
+	})
+	client := healthpb.NewHealthClient(conn)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q): %v", service, err)
+		}
+		return resp.Status
+	}
+
+	// 首次检查前不接收流量
+	if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("首次检查前: %s", got)
+	}
+
+	if !checker.Update() {
+		t.Error("依赖正常时 Update 应返回 true")
+	}
+	for _, svc := range []string{"", "user.UserService", "database", "redis"} {
+		if got := check(svc); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("%q: %s, 期望 SERVING", svc, got)
+		}
+	}
+
+	// Redis 不可用时整体不可用，数据库仍单独报告正常
+	redisErr = errors.New("connection refused")
+	checker.Update()
+	want := map[string]healthpb.HealthCheckResponse_ServingStatus{
+		"":                 healthpb.HealthCheckResponse_NOT_SERVING,
+		"user.UserService": healthpb.HealthCheckResponse_NOT_SERVING,
+		"redis":            healthpb.HealthCheckResponse_NOT_SERVING,
+		"database":         healthpb.HealthCheckResponse_SERVING,
+	}
+	for svc, status := range want {
+		if got := check(svc); got != status {
+			t.Errorf("%q: %s, 期望 %s", svc, got, status)
+		}
+	}
+
+	// 关闭后不再恢复
+	redisErr = nil
+	checker.Shutdown()
+	checker.Update()
+	if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("关闭后: %s", got)
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/activity"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"go.uber.org/zap"
+)
+
+// maxActiveUsers 单次最多返回的活跃用户数
+const maxActiveUsers = 1000
+
+// RegisterActivityRoutes 注册用户活跃度模块路由
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterActivityRoutes(r *gin.RouterGroup, deps module.Deps) {
+	r.GET("/users/online", middleware.JWTAuth(), middleware.RequireRole("admin"), ListActiveUsers())
+}
+
+// ListActiveUsers 查询在线/近期活跃用户处理器
+// 用途: 默认返回在线判定窗口内活跃的用户，可通过 ?window=3600（秒）查询更长时间内活跃的用户；仅管理员可用
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListActiveUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window, err := strconv.Atoi(c.DefaultQuery("window", "0"))
+		if err != nil || window < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "window 参数错误",
+			})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "limit 参数错误",
+			})
+			return
+		}
+		if limit > maxActiveUsers {
+			limit = maxActiveUsers
+		}
+
+		entries, err := activity.DefaultTracker.Recent(
+			c.Request.Context(),
+			time.Duration(window)*time.Second,
+			int64(limit),
+		)
+		if err != nil {
+			logger.FromContext(c).Error("查询活跃用户失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询活跃用户失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": entries,
+			"total": len(entries),
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// TestActiveUsersRequiresAdmin 校验在线用户列表仅管理员可查询
+func TestActiveUsersRequiresAdmin(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{Chains: map[string][]string{"global": {"recovery"}}}, func(r *gin.RouterGroup) {
+		handler.RegisterActivityRoutes(r, module.Deps{Config: &config.Config{}})
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"未登录", "", http.StatusUnauthorized},
+		{"普通用户", minter.MustMint(t, 2, "user", time.Hour), http.StatusForbidden},
+		{"管理员参数错误", minter.MustMint(t, 1, "admin", time.Hour), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/online?window=-1", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", testutil.BearerHeader(tt.token))
+		}
+		if code := testutil.Do(router, req).Code; code != tt.expected {
+			t.Errorf("%s: 状态码 = %d, 期望 %d", tt.name, code, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/files"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ArchiveRequest 多文件打包请求
+type ArchiveRequest struct {
+	Keys []string `json:"keys" binding:"required,min=1,dive,required"`
+	// Format 打包格式: zip（默认）、tar
+	Format string `json:"format" binding:"omitempty,oneof=zip tar"`
+	// Name 下载文件名（不含扩展名），默认 archive
+	Name string `json:"name" binding:"omitempty,max=100"`
+	// Async 强制异步打包到 S3，总大小超过直接下载上限时自动异步
+	Async bool `json:"async"`
+}
+
+// ArchiveJobResponse 异步打包任务响应
+type ArchiveJobResponse struct {
+	*files.Job
+	// URL 打包结果的预签名下载地址，任务完成后返回
+	URL string `json:"url,omitempty"`
+}
+
+// CreateArchive 多文件打包下载处理器
+// 用途: 逐个检查文件权限后打包下载；总大小不超过直接下载上限时流式写入响应，
+// 否则异步打包到 S3，返回 202 和任务 ID，通过 GET /files/archive/:id 查询下载地址
+// 参数:
+//
+//	cfg: 打包配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func CreateArchive(cfg config.ArchiveConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logger.FromContext(c)
+
+		var req ArchiveRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.Warn("解析请求失败", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+			})
+			return
+		}
+		keys := uniqueKeys(req.Keys)
+		if len(keys) > cfg.GetMaxFiles() {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("单次最多打包 %d 个文件", cfg.GetMaxFiles()),
+			})
+			return
+		}
+		format := req.Format
+		if format == "" {
+			format = files.FormatZip
+		}
+		name := archiveName(req.Name)
+
+		ctx := c.Request.Context()
+		found, err := files.Lookup(ctx, keys)
+		if err != nil {
+			log.Error("查询文件记录失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "打包失败",
+			})
+			return
+		}
+		userID, _ := middleware.GetUserID(c)
+		role, _ := middleware.GetUserRole(c)
+		held, err := files.Held(ctx, keys, userID)
+		if err != nil {
+			log.Error("查询文件引用失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "打包失败",
+			})
+			return
+		}
+		objects, err := files.Authorize(found, held, keys, userID, role == "admin")
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		size := files.TotalSize(objects)
+		if size > cfg.GetMaxSize() {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("文件总大小超过上限 %d MB", cfg.GetMaxSize()>>20),
+			})
+			return
+		}
+
+		actor, _ := middleware.GetUsername(c)
+		detail := gin.H{"files": len(objects), "size": size, "format": format}
+
+		if req.Async || size > cfg.GetStreamMaxSize() {
+			job, err := files.StartJob(ctx, userID, format, name, objects, cfg)
+			if err != nil {
+				log.Error("创建打包任务失败", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "打包失败",
+				})
+				return
+			}
+			_ = audit.Record(ctx, actor, "files.archive", "files/archive/"+job.ID, detail)
+			c.JSON(http.StatusAccepted, ArchiveJobResponse{Job: job})
+			return
+		}
+
+		_ = audit.Record(ctx, actor, "files.archive", "files/archive", detail)
+
+		c.Header("Content-Type", files.ContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s%s"`, name, files.Extension(format)))
+		c.Status(http.StatusOK)
+		// 打包输出有缓冲，先发出响应头，此后的超时不再改写为 504
+		c.Writer.WriteHeaderNow()
+		// 响应头已发出，出错时只能中止写入，客户端会收到不完整（无法解压）的文件；
+		// 因此写入不受请求超时（timeout 中间件）约束，客户端断开时写入失败同样会中止
+		if err := files.WriteArchive(context.WithoutCancel(ctx), c.Writer, format, objects, storage.S3Storage); err != nil {
+			log.Error("打包下载中断",
+				zap.Int("文件数", len(objects)),
+				zap.Error(err),
+			)
+			c.Abort()
+		}
+	}
+}
+
+// GetArchiveJob 异步打包任务查询处理器
+// 用途: 查询打包进度，完成后返回预签名下载地址；只有发起人和管理员可以查询
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetArchiveJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := files.GetJob(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, files.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Error("查询打包任务失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询打包任务失败",
+			})
+			return
+		}
+
+		userID, _ := middleware.GetUserID(c)
+		role, _ := middleware.GetUserRole(c)
+		if job.UserID != userID && role != "admin" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": files.ErrJobNotFound.Error(),
+			})
+			return
+		}
+
+		resp := ArchiveJobResponse{Job: job}
+		if job.Status == files.JobDone {
+			url, err := storage.S3Storage.GetPresignedURL(job.Key)
+			if err != nil {
+				logger.FromContext(c).Error("生成预签名 URL 失败",
+					zap.String("key", job.Key),
+					zap.Error(err),
+				)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "生成访问链接失败",
+				})
+				return
+			}
+			resp.URL = url
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// uniqueKeys 去除重复的 Key，保持原有顺序
+func uniqueKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// archiveName 清理下载文件名，去掉路径和引号，为空时使用 archive
+func archiveName(name string) string {
+	name = path.Base(strings.TrimSpace(strings.ReplaceAll(name, `\`, "/")))
+	name = strings.Map(func(r rune) rune {
+		if r == '"' || r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "archive"
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/files"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/storage"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// slowStore 每次下载前等待 delay 的对象存储
+type slowStore struct {
+	storage.ObjectStore
+	delay time.Duration
+}
+
+func (s slowStore) Download(key string) (io.ReadCloser, error) {
+	time.Sleep(s.delay)
+	return io.NopCloser(strings.NewReader("content of " + key)), nil
+}
+
+// TestArchiveStreamOutlivesTimeout 直接打包下载开始写出后不被请求超时截断
+func TestArchiveStreamOutlivesTimeout(t *testing.T) {
+	useFileStore(t, slowStore{delay: 400 * time.Millisecond})
+	ctx := context.Background()
+	keys := []string{"uploads/a.txt", "uploads/b.txt", "uploads/c.txt", "uploads/d.txt"}
+	for _, key := range keys {
+		if err := files.Record(ctx, key, 2, int64(len("content of "+key)), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := testutil.NewClock(time.Now())
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	// 默认超时 1 秒，未按路由豁免
+	cfg := config.MiddlewareConfig{
+		Chains:  map[string][]string{"global": {"recovery", "timeout"}},
+		Timeout: config.TimeoutConfig{Default: 1},
+	}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		handler.RegisterUploadRoutes(r, module.Deps{Config: &config.Config{}})
+	})
+
+	body := `{"keys":["uploads/a.txt","uploads/b.txt","uploads/c.txt","uploads/d.txt"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/files/archive", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", testutil.BearerHeader(minter.MustMint(t, 2, "user", time.Hour)))
+	w := testutil.Do(router, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", w.Code, w.Body.String())
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("打包文件不完整: %v", err)
+	}
+	if len(zr.File) != len(keys) {
+		t.Errorf("文件数 = %d, 期望 %d", len(zr.File), len(keys))
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// ListAuditLogs 审计记录列表处理器
+// 用途: 按 ID 倒序列出审计记录，支持按操作人（actor）、动作（action，以 .* 结尾时按前缀匹配）、
+// 资源前缀（resource）、请求 ID（request_id）、时间范围（since、until，RFC3339）过滤；
+// limit 默认 20、最大 100，翻页时以上一页响应的 next_before_id 作为 before_id
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListAuditLogs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		_, limit = service.NormalizePage(1, limit)
+
+		filter := audit.Filter{
+			Actor:     c.Query("actor"),
+			Action:    c.Query("action"),
+			Resource:  c.Query("resource"),
+			RequestID: c.Query("request_id"),
+			Limit:     limit,
+		}
+		var err error
+		if filter.Since, err = queryTime(c, "since"); err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if filter.Until, err = queryTime(c, "until"); err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if v := c.Query("before_id"); v != "" {
+			if filter.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil {
+				errs.Write(c, errs.Invalid("before_id 不是有效的记录 ID: %q", v))
+				return
+			}
+		}
+
+		entries, err := audit.Query(c.Request.Context(), filter)
+		if err != nil {
+			logger.FromContext(c).Error("查询审计记录失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询审计记录失败",
+			})
+			return
+		}
+
+		resp := gin.H{
+			"items": entries,
+		}
+		if len(entries) == limit {
+			resp["next_before_id"] = entries[len(entries)-1].ID
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// GetAuditLog 审计记录详情处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetAuditLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的记录 ID",
+			})
+			return
+		}
+
+		entry, err := audit.Get(c.Request.Context(), id)
+		if err != nil {
+			logger.FromContext(c).Error("查询审计记录失败",
+				zap.Int64("id", id),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询审计记录失败",
+			})
+			return
+		}
+		if entry == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "审计记录不存在",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
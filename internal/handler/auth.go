@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/security"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// LoginRequest 登录请求
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+// lockoutWarned 上次提示登录锁定检查失败的时间（Unix 秒），避免每个请求都打印日志
+var lockoutWarned atomic.Int64
+
+// RefreshRequest 刷新令牌请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	// RefreshToken 同时吊销的刷新令牌，可选
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RegisterAuthRoutes 注册认证模块路由
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterAuthRoutes(r *gin.RouterGroup, deps module.Deps) {
+	g := r.Group("/auth")
+	{
+		g.POST("/login", Login(newUserService(), deps.Config.Security.Lockout))
+		// 缓存的用户不含角色，刷新令牌时直接查询数据库
+		g.POST("/refresh", Refresh(service.NewUserService(service.NewGormUserRepository(database.DB))))
+		g.POST("/logout", middleware.JWTAuth(), Logout())
+		g.POST("/logout-all", middleware.JWTAuth(), LogoutAll())
+		g.POST("/users/:id/revoke", middleware.JWTAuth(), middleware.RequireRole("admin"), RevokeUserTokens())
+	}
+}
+
+// Login 登录处理器
+// 用途: 校验邮箱和密码，签发访问令牌和刷新令牌；同一邮箱在统计窗口内连续失败达到上限后锁定一段时间。
+// 邮箱不存在与密码错误返回相同的响应，避免探测已注册的邮箱
+// 参数:
+//
+//	users: 用户服务
+//	lockout: 登录失败锁定配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func Login(users *service.UserService, lockout config.LockoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请提供邮箱和密码",
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		subject := strings.ToLower(strings.TrimSpace(req.Email))
+
+		remaining, err := cache.LoginLocked(ctx, subject)
+		if err != nil {
+			warnLockout(err)
+		}
+		if remaining > 0 {
+			respondLocked(c, remaining)
+			return
+		}
+
+		user, err := users.GetUserByEmail(ctx, req.Email)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "登录失败",
+			})
+			return
+		}
+
+		ok := false
+		if user != nil && user.PasswordHash != "" {
+			ok, err = security.Passwords.Verify(user.PasswordHash, req.Password)
+			if err != nil {
+				logger.Error("校验密码失败", zap.Int64("user_id", user.ID), zap.Error(err))
+			}
+		} else {
+			// 用户不存在时同样计算一次哈希，使响应时间与密码错误一致
+			_, _ = security.Passwords.Hash(req.Password)
+		}
+
+		if !ok {
+			locked, _, err := cache.RecordLoginFailure(ctx, subject, lockout.GetMaxAttempts(), lockout.GetWindow(), lockout.GetDuration())
+			if err != nil {
+				warnLockout(err)
+			}
+			if locked {
+				logger.Warn("登录失败次数过多，锁定账号", zap.String("email", subject), zap.String("ip", c.ClientIP()))
+				_ = audit.Record(ctx, subject, "auth.lockout", "auth/login", gin.H{"ip": c.ClientIP()})
+				respondLocked(c, lockout.GetDuration())
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "邮箱或密码错误",
+				"code":  "AUTH_LOGIN_FAILED",
+			})
+			return
+		}
+
+		if err := cache.ResetLoginFailures(ctx, subject); err != nil {
+			warnLockout(err)
+		}
+
+		// 哈希算法或参数已调整时按当前配置重新哈希
+		if security.Passwords.NeedsRehash(user.PasswordHash) {
+			if hash, err := security.Passwords.Hash(req.Password); err == nil {
+				if err := users.SetPasswordHash(ctx, user.ID, hash); err != nil {
+					logger.Warn("重新哈希密码失败", zap.Int64("user_id", user.ID), zap.Error(err))
+				}
+			}
+		}
+
+		role := user.Role
+		if role == "" {
+			role = "user"
+		}
+		pair, err := middleware.GenerateTokenPair(user.ID, user.Email, role)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "登录失败",
+			})
+			return
+		}
+
+		_ = audit.Record(ctx, user.Email, "auth.login", "users/"+strconv.FormatInt(user.ID, 10), gin.H{"ip": c.ClientIP()})
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+// respondLocked 账号锁定响应，Retry-After 为剩余锁定秒数
+func respondLocked(c *gin.Context, remaining time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": "登录失败次数过多，账号已临时锁定，请稍后重试",
+		"code":  "AUTH_ACCOUNT_LOCKED",
+	})
+}
+
+// warnLockout Redis 不可用时不锁定账号，每分钟最多提示一次
+func warnLockout(err error) {
+	now := time.Now().Unix()
+	if last := lockoutWarned.Load(); now-last >= 60 && lockoutWarned.CompareAndSwap(last, now) {
+		logger.Warn("登录失败计数不可用，不锁定账号", zap.Error(err))
+	}
+}
+
+// Refresh 刷新令牌处理器
+// 用途: 用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效；
+// 已使用过的刷新令牌再次提交时吊销该用户的全部令牌。新令牌的角色取自数据库，已删除的用户不能刷新
+// 参数:
+//
+//	users: 用户服务，需直接查询数据库（经缓存的用户不含角色）
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func Refresh(users *service.UserService) gin.HandlerFunc {
+	load := func(ctx context.Context, userID int64) (string, string, error) {
+		user, err := users.GetUser(ctx, userID)
+		if errors.Is(err, errs.ErrNotFound) || (err == nil && user == nil) {
+			return "", "", middleware.ErrUserInactive
+		}
+		if err != nil {
+			return "", "", err
+		}
+		role := user.Role
+		if role == "" {
+			role = "user"
+		}
+		return user.Email, role, nil
+	}
+
+	return func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请提供 refresh_token",
+			})
+			return
+		}
+
+		pair, err := middleware.RefreshToken(c.Request.Context(), req.RefreshToken, load)
+		if err != nil {
+			status, code := tokenErrorStatus(err)
+			if status == http.StatusServiceUnavailable {
+				logger.FromContext(c).Error("刷新令牌失败",
+					zap.Error(err),
+				)
+			}
+			c.JSON(status, gin.H{
+				"error": "刷新令牌无效或已过期",
+				"code":  code,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+// tokenErrorStatus 令牌操作失败的状态码：令牌本身的问题返回 401，吊销存储（Redis）不可用返回 503
+func tokenErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, middleware.ErrRefreshTokenReused):
+		return http.StatusUnauthorized, "AUTH_REFRESH_TOKEN_REUSED"
+	case errors.Is(err, middleware.ErrTokenRevoked):
+		return http.StatusUnauthorized, "AUTH_TOKEN_REVOKED"
+	case errors.Is(err, middleware.ErrUserInactive):
+		return http.StatusUnauthorized, "AUTH_USER_INACTIVE"
+	case errors.Is(err, middleware.ErrTokenType), errors.Is(err, jwt.ErrTokenMalformed),
+		errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable),
+		errors.Is(err, jwt.ErrTokenInvalidClaims):
+		return http.StatusUnauthorized, "AUTH_TOKEN_INVALID"
+	default:
+		return http.StatusServiceUnavailable, "AUTH_UNAVAILABLE"
+	}
+}
+
+// Logout 退出登录处理器
+// 用途: 吊销当前请求的访问令牌，请求体中提供刷新令牌时一并吊销
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func Logout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LogoutRequest
+		_ = c.ShouldBindJSON(&req)
+
+		ctx := c.Request.Context()
+		tokens := []string{strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")}
+		if req.RefreshToken != "" {
+			tokens = append(tokens, req.RefreshToken)
+		}
+		for _, token := range tokens {
+			if err := middleware.RevokeToken(ctx, token); err != nil {
+				status, code := tokenErrorStatus(err)
+				if status == http.StatusServiceUnavailable {
+					logger.FromContext(c).Error("吊销令牌失败",
+						zap.Error(err),
+					)
+				}
+				c.JSON(status, gin.H{
+					"error": "退出登录失败",
+					"code":  code,
+				})
+				return
+			}
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// LogoutAll 退出所有设备处理器
+// 用途: 吊销当前用户已签发的全部令牌
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func LogoutAll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+		if err := middleware.RevokeUserTokens(c.Request.Context(), userID); err != nil {
+			logger.FromContext(c).Error("吊销用户令牌失败",
+				zap.Int64("user_id", userID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "退出登录失败",
+			})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RevokeUserTokens 吊销指定用户令牌处理器
+// 用途: 管理员在用户令牌泄露时使其全部令牌立即失效
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func RevokeUserTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		if err := middleware.RevokeUserTokens(c.Request.Context(), id); err != nil {
+			logger.FromContext(c).Error("吊销用户令牌失败",
+				zap.Int64("user_id", id),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "吊销令牌失败",
+			})
+			return
+		}
+
+		actor, _ := middleware.GetUsername(c)
+		_ = audit.Record(c.Request.Context(), actor, "auth.revoke", "users/"+strconv.FormatInt(id, 10), nil)
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/cronctl"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// ListCronJobs 定时任务列表处理器
+// 用途: 列出定时任务服务上报的任务、调度表达式、上次/下次执行时间及运行时禁用状态；
+// 定时任务服务不在线时列表为空
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListCronJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobs, err := cronctl.List(c.Request.Context())
+		if err != nil {
+			logger.FromContext(c).Error("查询定时任务失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询定时任务失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": jobs,
+		})
+	}
+}
+
+// TriggerCronJob 手动触发定时任务处理器
+// 用途: 立即执行一次任务（不受运行时禁用影响），执行结果见 /admin/jobs/runs
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func TriggerCronJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		actor, _ := middleware.GetUsername(c)
+
+		err := cronctl.Trigger(c.Request.Context(), name, actor)
+		if !respondCronJobError(c, name, err) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "任务已触发",
+		})
+	}
+}
+
+// EnableCronJob 启用定时任务调度处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func EnableCronJob() gin.HandlerFunc {
+	return setCronJobDisabled(false, "任务已启用")
+}
+
+// DisableCronJob 禁用定时任务调度处理器
+// 用途: 到点时跳过该任务，直到重新启用；无需重启定时任务服务
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func DisableCronJob() gin.HandlerFunc {
+	return setCronJobDisabled(true, "任务已禁用")
+}
+
+// setCronJobDisabled 更新任务的运行时禁用状态
+func setCronJobDisabled(disabled bool, message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		actor, _ := middleware.GetUsername(c)
+
+		err := cronctl.SetDisabled(c.Request.Context(), name, disabled, actor)
+		if !respondCronJobError(c, name, err) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": message,
+		})
+	}
+}
+
+// respondCronJobError 输出任务操作错误
+// 返回:
+//
+//	bool: 无错误时为 true，调用方继续输出成功响应
+func respondCronJobError(c *gin.Context, name string, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, cronctl.ErrUnknownJob) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "任务不存在或定时任务服务不在线",
+		})
+		return false
+	}
+
+	logger.FromContext(c).Error("操作定时任务失败",
+		zap.String("任务", name),
+		zap.Error(err),
+	)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "操作定时任务失败",
+	})
+	return false
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/deprecation"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ListDeprecations 弃用接口报告处理器
+// 用途: 列出已声明弃用的 HTTP 路由和 gRPC 方法、计划下线时间，以及各调用方的调用次数和最近调用时间，
+// 下线前据此确认哪些客户端仍未迁移
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListDeprecations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := deprecation.Report(c.Request.Context())
+		if err != nil {
+			logger.FromContext(c).Error("查询弃用接口调用统计失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询弃用接口失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": report,
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/failures"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// ListFailures 最近失败请求处理器
+// 用途: 列出本实例最近状态码 ≥500 的请求（最新的在前，请求体和响应体已脱敏），limit 为 0 或不传时返回全部
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListFailures() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		entries, total := failures.Default.List(limit)
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": entries,
+			"total": total,
+		})
+	}
+}
+
+// ClearFailures 清空失败请求记录处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ClearFailures() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		failures.Default.Clear()
+		actor, _ := middleware.GetUsername(c)
+		logger.Info("失败请求记录已清空", zap.String("操作人", actor))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "失败请求记录已清空",
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/zhang/microservice/internal/cache"
 	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/resilience"
 )
 
 // HealthResponse 健康检查响应
@@ -14,12 +15,18 @@ type HealthResponse struct {
 	Status    string                 `json:"status"`
 	Timestamp string                 `json:"timestamp"`
 	Services  map[string]ServiceInfo `json:"services,omitempty"`
+	// Degraded 是否有功能因依赖不可用而按降级策略运行（目前为 Redis，见 redis.degradation）
+	Degraded bool `json:"degraded"`
+	// CircuitBreakers 各外部依赖的熔断器状态（closed、half_open、open），未启用熔断时省略
+	CircuitBreakers map[string]string `json:"circuit_breakers,omitempty"`
 }
 
 // ServiceInfo 服务信息
 type ServiceInfo struct {
 	Status  string `json:"status"`
 	Message string `json:"message,omitempty"`
+	// Details 附加信息，如 Redis 的降级策略和进入降级状态的时间
+	Details map[string]string `json:"details,omitempty"`
 }
 
 // HealthCheck 健康检查处理器
@@ -61,23 +68,35 @@ func DetailedHealthCheck() gin.HandlerFunc {
 			}
 		}
 
-		// 检查 Redis
+		// 检查 Redis（Ping 成功也会使降级状态恢复）
+		var redisInfo ServiceInfo
 		if err := cache.HealthCheck(); err != nil {
-			services["redis"] = ServiceInfo{
+			redisInfo = ServiceInfo{
 				Status:  "error",
 				Message: err.Error(),
 			}
 			overallStatus = "degraded"
 		} else {
-			services["redis"] = ServiceInfo{
+			redisInfo = ServiceInfo{
 				Status: "ok",
 			}
 		}
+		degradation := cache.Degradation()
+		redisInfo.Details = degradation.Policies
+		if degradation.Degraded {
+			redisInfo.Details["degraded_since"] = degradation.Since.Format(time.RFC3339)
+			redisInfo.Details["last_error"] = degradation.LastError
+		}
+		services["redis"] = redisInfo
 
 		response := HealthResponse{
 			Status:    overallStatus,
 			Timestamp: time.Now().Format(time.RFC3339),
 			Services:  services,
+			Degraded:  degradation.Degraded,
+		}
+		if states := resilience.States(); len(states) > 0 {
+			response.CircuitBreakers = states
 		}
 
 		// 根据整体状态返回相应的 HTTP 状态码
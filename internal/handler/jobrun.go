@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/jobrun"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// ListJobRuns 定时任务执行记录处理器
+// 用途: 按开始时间倒序列出最近的执行记录，支持按任务名称（job）和状态（status）过滤，limit 默认 20、最大 100
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListJobRuns() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+		_, limit = service.NormalizePage(1, limit)
+
+		filter := jobrun.Filter{
+			Job:    c.Query("job"),
+			Status: c.Query("status"),
+		}
+		runs, err := jobrun.List(c.Request.Context(), filter, limit)
+		if err != nil {
+			logger.FromContext(c).Error("查询任务执行记录失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询执行记录失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": runs,
+		})
+	}
+}
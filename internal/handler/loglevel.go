@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// LogLevelRequest 调整日志级别请求
+type LogLevelRequest struct {
+	Level string `json:"level" binding:"required"`
+}
+
+// GetLogLevel 查询日志级别处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetLogLevel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"level": logger.Level(),
+		})
+	}
+}
+
+// UpdateLogLevel 调整日志级别处理器
+// 只影响处理该请求的实例，重启或配置文件中的级别变化后恢复为配置的级别
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func UpdateLogLevel() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req LogLevelRequest
+		if err := c.ShouldBindJSON(&req); err != nil || !logger.ValidLevel(req.Level) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "level 无效，可选 debug、info、warn、error",
+			})
+			return
+		}
+
+		from := logger.Level()
+		logger.SetLevel(req.Level)
+		logger.FromContext(c).Info("日志级别已调整",
+			zap.String("from", from),
+			zap.String("to", req.Level),
+		)
+
+		actor, _ := middleware.GetUsername(c)
+		_ = audit.Record(c.Request.Context(), actor, "logger.level", "loglevel", gin.H{"from": from, "to": req.Level})
+
+		c.JSON(http.StatusOK, gin.H{
+			"level": logger.Level(),
+		})
+	}
+}
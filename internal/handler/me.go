@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/flags"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/quota"
+	"github.com/zhang/microservice/internal/service"
+	"go.uber.org/zap"
+)
+
+// MeResponse 当前用户信息响应
+type MeResponse struct {
+	UserID   int64           `json:"user_id"`
+	Username string          `json:"username"`
+	Profile  *service.User   `json:"profile"`
+	Roles    []string        `json:"roles"`
+	Scopes   []string        `json:"scopes"`
+	Verified VerifiedStatus  `json:"verified"`
+	Quotas   []quota.Status  `json:"quotas"`
+	Features map[string]bool `json:"features"`
+}
+
+// VerifiedStatus 联系方式验证状态
+type VerifiedStatus struct {
+	Email bool `json:"email"`
+	Phone bool `json:"phone"`
+}
+
+// RegisterMeRoutes 注册当前用户模块路由
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterMeRoutes(r *gin.RouterGroup, deps module.Deps) {
+	roleScopes := deps.Config.Security.RoleScopes
+	r.GET("/me", middleware.JWTAuth(), GetMe(newUserService(), roleScopes))
+	r.GET("/me/settings", middleware.JWTAuth(), GetMySettings())
+	r.PATCH("/me/settings", middleware.JWTAuth(), UpdateMySettings())
+}
+
+// GetMe 当前用户信息处理器
+// 用途: 并发查询用户资料、配额使用情况和功能开关，一次返回客户端启动所需的信息
+// 参数:
+//
+//	users: 用户服务
+//	roleScopes: 角色到权限范围的映射
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetMe(users *service.UserService, roleScopes map[string][]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		userID, _ := middleware.GetUserID(c)
+		username, _ := middleware.GetUsername(c)
+		role, _ := middleware.GetUserRole(c)
+
+		resp := MeResponse{
+			UserID:   userID,
+			Username: username,
+			Roles:    []string{role},
+			Scopes:   roleScopes[role],
+		}
+		if resp.Scopes == nil {
+			resp.Scopes = []string{}
+		}
+
+		var (
+			wg                   sync.WaitGroup
+			profileErr, quotaErr error
+		)
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			resp.Profile, profileErr = users.GetUser(ctx, userID)
+		}()
+		go func() {
+			defer wg.Done()
+			resp.Quotas, quotaErr = quota.DefaultEngine.Status(ctx, quota.UserSubject(userID))
+		}()
+		go func() {
+			defer wg.Done()
+			resp.Features = flags.DefaultEngine.Evaluate(flags.Subject{UserID: userID, Role: role})
+		}()
+		wg.Wait()
+
+		if profileErr != nil || quotaErr != nil {
+			logger.FromContext(c).Error("查询当前用户信息失败",
+				zap.Int64("user_id", userID),
+				zap.NamedError("profile_error", profileErr),
+				zap.NamedError("quota_error", quotaErr),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询用户信息失败",
+			})
+			return
+		}
+
+		if resp.Profile != nil {
+			resp.Verified = VerifiedStatus{
+				Email: resp.Profile.EmailVerified,
+				Phone: resp.Profile.PhoneVerified,
+			}
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -2,10 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
 	"github.com/zhang/microservice/internal/queue"
 	"go.uber.org/zap"
 )
@@ -16,19 +21,31 @@ type MessageRequest struct {
 	Message interface{} `json:"message" binding:"required"`
 }
 
+// RegisterMessageRoutes 注册消息队列模块路由
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterMessageRoutes(r *gin.RouterGroup, deps module.Deps) {
+	r.POST("/message", middleware.JWTAuth(), middleware.Idempotency(deps.Config.Middleware.Idempotency), PublishMessage(deps.Config.RabbitMQ))
+}
+
 // PublishMessage 发布消息处理器
-// 用途: 发送消息到消息队列
+// 用途: 发送消息到消息队列（需要登录，与 gRPC MessageService.PublishEvent 一致），不在 publish_allowlist 中的队列返回 403
+// 参数:
+//
+//	cfg: 消息队列配置
+//
 // 返回:
 //
 //	gin.HandlerFunc: Gin 处理器函数
-func PublishMessage() gin.HandlerFunc {
+func PublishMessage(cfg config.RabbitMQConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID, _ := c.Get("request_id")
+		log := logger.FromContext(c)
 
 		var req MessageRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
-			logger.Error("解析请求失败",
-				zap.String("request_id", requestID.(string)),
+			log.Error("解析请求失败",
 				zap.Error(err),
 			)
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -37,11 +54,20 @@ func PublishMessage() gin.HandlerFunc {
 			return
 		}
 
+		if !cfg.PublishAllowed(req.Queue) {
+			log.Warn("拒绝发布到未授权的队列",
+				zap.String("queue", req.Queue),
+			)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "不允许发布到该队列",
+			})
+			return
+		}
+
 		// 将消息序列化为 JSON
 		messageBody, err := json.Marshal(req.Message)
 		if err != nil {
-			logger.Error("序列化消息失败",
-				zap.String("request_id", requestID.(string)),
+			log.Error("序列化消息失败",
 				zap.Error(err),
 			)
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -52,20 +78,23 @@ func PublishMessage() gin.HandlerFunc {
 
 		// 发布消息到队列
 		routingKey := req.Queue + ".*"
-		if err := queue.MQClient.Publish(routingKey, messageBody); err != nil {
-			logger.Error("发布消息失败",
-				zap.String("request_id", requestID.(string)),
+		if err := queue.PublishContext(c.Request.Context(), routingKey, messageBody); err != nil {
+			log.Error("发布消息失败",
 				zap.String("queue", req.Queue),
 				zap.Error(err),
 			)
+			// 消息代理熔断时返回 503，客户端可稍后重试
+			if errors.Is(err, errs.ErrUnavailable) {
+				errs.Write(c, err)
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "发送消息失败",
 			})
 			return
 		}
 
-		logger.Info("消息发布成功",
-			zap.String("request_id", requestID.(string)),
+		log.Info("消息发布成功",
 			zap.String("queue", req.Queue),
 		)
 
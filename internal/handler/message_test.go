@@ -0,0 +1,44 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// TestPublishMessageRequiresLogin 校验发布消息与 gRPC PublishEvent 一样需要登录
+func TestPublishMessageRequiresLogin(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	deps := module.Deps{Config: &config.Config{RabbitMQ: config.RabbitMQConfig{PublishAllowlist: []string{"task"}}}}
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{Chains: map[string][]string{"global": {"recovery"}}}, func(r *gin.RouterGroup) {
+		handler.RegisterMessageRoutes(r, deps)
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"未登录", "", http.StatusUnauthorized},
+		{"已登录，队列不在白名单中", minter.MustMint(t, 2, "user", time.Hour), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/message", strings.NewReader(`{"queue":"other","message":{"a":1}}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("Authorization", testutil.BearerHeader(tt.token))
+		}
+		if code := testutil.Do(router, req).Code; code != tt.expected {
+			t.Errorf("%s: 状态码 = %d, 期望 %d", tt.name, code, tt.expected)
+		}
+	}
+}
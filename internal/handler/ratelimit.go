@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// maxRateCreditTTL 突发额度的最长有效期
+const maxRateCreditTTL = 30 * 24 * time.Hour
+
+// GrantRateCreditsRequest 授予突发额度请求
+type GrantRateCreditsRequest struct {
+	// Credits 增加的请求数（超出限流后仍可通过的请求数）
+	Credits int64 `json:"credits" binding:"required,min=1"`
+	// TTL 有效期，Go duration 格式（如 48h），默认 24h，最长 30 天
+	TTL string `json:"ttl"`
+}
+
+// ListRateCredits 列出突发额度处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListRateCredits() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		credits, err := cache.ListRateCredits(c.Request.Context())
+		if err != nil {
+			logger.FromContext(c).Error("查询突发额度失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "查询突发额度失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": credits,
+		})
+	}
+}
+
+// GrantRateCredits 授予突发额度处理器
+// 用途: 合作方迁移等场景下临时放宽白名单客户端的限额，额度在有效期内用完即止，重复授予时累加并重新计算有效期
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GrantRateCredits() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client, ok := rateLimitClient(c)
+		if !ok {
+			return
+		}
+
+		var req GrantRateCreditsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "credits 必须为正整数",
+			})
+			return
+		}
+		ttl := 24 * time.Hour
+		if req.TTL != "" {
+			d, err := time.ParseDuration(req.TTL)
+			if err != nil || d <= 0 || d > maxRateCreditTTL {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "ttl 无效，应为不超过 720h 的时长（如 48h）",
+				})
+				return
+			}
+			ttl = d
+		}
+
+		total, err := cache.GrantRateCredits(c.Request.Context(), client, req.Credits, ttl)
+		if err != nil {
+			logger.FromContext(c).Error("授予突发额度失败",
+				zap.String("client", client),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "授予突发额度失败",
+			})
+			return
+		}
+
+		actor, _ := middleware.GetUsername(c)
+		_ = audit.Record(c.Request.Context(), actor, "ratelimit.credits.grant", "ratelimit/"+client,
+			gin.H{"credits": req.Credits, "ttl": ttl.String()})
+
+		c.JSON(http.StatusOK, cache.RateCredit{
+			Client:    client,
+			Remaining: total,
+			ExpiresAt: time.Now().Add(ttl),
+		})
+	}
+}
+
+// RevokeRateCredits 收回突发额度处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func RevokeRateCredits() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := c.Param("client")
+		if err := cache.RevokeRateCredits(c.Request.Context(), client); err != nil {
+			logger.FromContext(c).Error("收回突发额度失败",
+				zap.String("client", client),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "收回突发额度失败",
+			})
+			return
+		}
+
+		actor, _ := middleware.GetUsername(c)
+		_ = audit.Record(c.Request.Context(), actor, "ratelimit.credits.revoke", "ratelimit/"+client, nil)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// rateLimitClient 读取路径中的客户端名称，只能为当前限流白名单中的客户端
+func rateLimitClient(c *gin.Context) (string, bool) {
+	name := c.Param("client")
+	for _, client := range middleware.CurrentRateLimitConfig().Allowlist {
+		if client.Name == name {
+			return name, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "限流白名单中没有该客户端",
+	})
+	return "", false
+}
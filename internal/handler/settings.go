@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/settings"
+	"go.uber.org/zap"
+)
+
+// RegisterAdminRoutes 注册管理模块路由
+// 所有路由需要管理员角色
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterAdminRoutes(r *gin.RouterGroup, deps module.Deps) {
+	admin := r.Group("/admin", middleware.JWTAuth(), middleware.RequireRole("admin"))
+	{
+		admin.GET("/settings", ListSettings())
+		admin.GET("/settings/:key", GetSetting())
+		admin.PUT("/settings/:key", UpdateSetting())
+		admin.DELETE("/settings/:key", DeleteSetting())
+		admin.GET("/slo", GetSLOStatus())
+		admin.GET("/jobs", ListCronJobs())
+		admin.GET("/jobs/runs", ListJobRuns())
+		admin.POST("/jobs/:name/trigger", TriggerCronJob())
+		admin.POST("/jobs/:name/enable", EnableCronJob())
+		admin.POST("/jobs/:name/disable", DisableCronJob())
+		admin.GET("/ratelimit/credits", ListRateCredits())
+		admin.POST("/ratelimit/credits/:client", GrantRateCredits())
+		admin.DELETE("/ratelimit/credits/:client", RevokeRateCredits())
+		admin.GET("/failures", ListFailures())
+		admin.DELETE("/failures", ClearFailures())
+		admin.GET("/deprecations", ListDeprecations())
+		admin.GET("/audit", ListAuditLogs())
+		admin.GET("/audit/:id", GetAuditLog())
+		admin.GET("/loglevel", GetLogLevel())
+		admin.PUT("/loglevel", UpdateLogLevel())
+	}
+}
+
+// ListSettings 列出运行时配置处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListSettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := settings.List(c.Request.Context())
+		if err != nil {
+			logger.FromContext(c).Error("查询运行时配置失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询配置失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"keys":  settings.Keys(),
+			"items": list,
+		})
+	}
+}
+
+// GetSetting 获取运行时配置处理器
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetSetting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Param("key")
+
+		value, err := settings.Get(c.Request.Context(), key)
+		if errors.Is(err, settings.ErrUnknownKey) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "未知的配置项",
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Error("查询运行时配置失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询配置失败",
+			})
+			return
+		}
+		if value == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "配置项未设置",
+			})
+			return
+		}
+
+		c.Data(http.StatusOK, "application/json; charset=utf-8", value)
+	}
+}
+
+// UpdateSetting 更新运行时配置处理器
+// 请求体为配置项的 JSON 值
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func UpdateSetting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Param("key")
+
+		value, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "读取请求失败",
+			})
+			return
+		}
+
+		actor, _ := middleware.GetUsername(c)
+		err = settings.Set(c.Request.Context(), key, value, actor)
+		if errors.Is(err, settings.ErrUnknownKey) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "未知的配置项",
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Warn("更新运行时配置失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "配置已保存，将在下次同步时生效",
+		})
+	}
+}
+
+// DeleteSetting 删除运行时配置处理器
+// 删除后恢复为配置文件中的值
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func DeleteSetting() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Param("key")
+		actor, _ := middleware.GetUsername(c)
+
+		err := settings.Delete(c.Request.Context(), key, actor)
+		if errors.Is(err, settings.ErrUnknownKey) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "未知的配置项",
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Error("删除运行时配置失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "删除配置失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "配置已删除，将在下次同步时恢复默认值",
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/slo"
+)
+
+// GetSLOStatus SLO 预算状态处理器
+// 用途: 汇总各目标在统计周期内的达标比例、剩余错误预算和各窗口的预算消耗速率
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetSLOStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slo.DefaultTracker == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "SLO 统计未启用",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"timestamp":  time.Now().Format(time.RFC3339),
+			"objectives": slo.DefaultTracker.Status(),
+		})
+	}
+}
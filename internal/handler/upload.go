@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"errors"
+	"io"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/files"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/quota"
 	"github.com/zhang/microservice/internal/storage"
 	"go.uber.org/zap"
 )
@@ -20,20 +29,57 @@ type UploadResponse struct {
 	Key string `json:"key"`
 }
 
+// RegisterUploadRoutes 注册文件上传模块路由
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterUploadRoutes(r *gin.RouterGroup, deps module.Deps) {
+	// 未初始化对象存储（如以 noaws 构建标签编译）时不挂载
+	if storage.S3Storage == nil {
+		logger.Warn("对象存储不可用，跳过文件上传路由")
+		return
+	}
+
+	r.POST("/upload", middleware.OptionalJWTAuth(), middleware.Idempotency(deps.Config.Middleware.Idempotency),
+		middleware.UploadLimit(deps.Config.AWS.S3.UploadLimits), UploadFile(deps.Config.AWS.S3))
+	r.GET("/presigned-url", middleware.JWTAuth(), GetPresignedURL())
+	r.DELETE("/files", middleware.JWTAuth(), DeleteFile())
+
+	archive := r.Group("/files/archive", middleware.JWTAuth())
+	{
+		archive.POST("", CreateArchive(deps.Config.AWS.S3.Archive))
+		archive.GET("/:id", GetArchiveJob())
+	}
+}
+
 // UploadFile 文件上传处理器
-// 用途: 处理文件上传到 S3
+// 用途: 处理文件上传到 S3；开启内容寻址存储时相同内容只保存一份，返回已有对象
+// 参数:
+//
+//	cfg: S3 配置
+//
 // 返回:
 //
 //	gin.HandlerFunc: Gin 处理器函数
-func UploadFile() gin.HandlerFunc {
+func UploadFile(cfg config.S3Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID, _ := c.Get("request_id")
+		log := logger.FromContext(c)
 
 		// 获取上传的文件
 		file, err := c.FormFile("file")
 		if err != nil {
-			logger.Error("获取上传文件失败",
-				zap.String("request_id", requestID.(string)),
+			// 请求体超过 middleware.body_limit 的上限（未声明 Content-Length 时读取中途才能发现）
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				log.Warn("上传请求体过大", zap.Int64("limit", tooLarge.Limit))
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": "请求体过大",
+					"limit": tooLarge.Limit,
+				})
+				return
+			}
+			log.Error("获取上传文件失败",
 				zap.Error(err),
 			)
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -41,12 +87,18 @@ func UploadFile() gin.HandlerFunc {
 			})
 			return
 		}
+		if cfg.MaxFileSize > 0 && file.Size > cfg.MaxFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "文件过大",
+				"limit": cfg.MaxFileSize,
+			})
+			return
+		}
 
 		// 打开文件
 		src, err := file.Open()
 		if err != nil {
-			logger.Error("打开上传文件失败",
-				zap.String("request_id", requestID.(string)),
+			log.Error("打开上传文件失败",
 				zap.Error(err),
 			)
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -56,11 +108,67 @@ func UploadFile() gin.HandlerFunc {
 		}
 		defer src.Close()
 
+		// 按文件内容识别类型，声明的类型和识别出的类型都需在 allowed_types 中
+		detected, err := detectContentType(src)
+		if err != nil {
+			log.Error("读取上传文件失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "处理文件失败",
+			})
+			return
+		}
+		contentType := file.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = detected
+		}
+		if !cfg.TypeAllowed(contentType) || !cfg.TypeAllowed(detected) {
+			log.Warn("上传文件类型不允许",
+				zap.String("content_type", contentType),
+				zap.String("detected", detected),
+			)
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error": "不支持的文件类型",
+				"type":  detected,
+			})
+			return
+		}
+
+		userID, loggedIn := middleware.GetUserID(c)
+
+		// 内容寻址存储：保存对象、文件记录和引用
+		if cfg.CAS {
+			obj, duplicate, err := files.StoreCAS(c.Request.Context(), storage.S3Storage, cfg.UploadPrefix, file.Filename, src, contentType, userID)
+			if err != nil {
+				log.Error("上传文件到 S3 失败",
+					zap.Error(err),
+				)
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "上传文件失败",
+				})
+				return
+			}
+			if duplicate {
+				log.Info("重复内容，复用已有对象",
+					zap.String("key", obj.Key),
+					zap.Int("引用数", obj.RefCount),
+				)
+			}
+			chargeStorage(c, userID, loggedIn, file.Size)
+			recordUploadAudit(c, obj.Key, file.Filename, file.Size, duplicate)
+			// 响应不区分是否为重复内容，避免泄露其他用户是否上传过相同文件
+			c.JSON(http.StatusOK, UploadResponse{
+				URL: storage.S3Storage.ObjectURL(obj.Key),
+				Key: obj.Key,
+			})
+			return
+		}
+
 		// 上传到 S3
-		url, key, err := storage.S3Storage.Upload(file.Filename, src, file.Header.Get("Content-Type"))
+		url, key, err := storage.S3Storage.Upload(file.Filename, src, contentType)
 		if err != nil {
-			logger.Error("上传文件到 S3 失败",
-				zap.String("request_id", requestID.(string)),
+			log.Error("上传文件到 S3 失败",
 				zap.Error(err),
 			)
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -69,6 +177,16 @@ func UploadFile() gin.HandlerFunc {
 			return
 		}
 
+		// 保存文件记录，打包下载时据此检查权限（匿名上传的文件只有管理员可以打包）
+		if err := files.Record(c.Request.Context(), key, userID, file.Size, contentType); err != nil {
+			log.Warn("保存文件记录失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+		}
+
+		chargeStorage(c, userID, loggedIn, file.Size)
+		recordUploadAudit(c, key, file.Filename, file.Size, false)
 		c.JSON(http.StatusOK, UploadResponse{
 			URL: url,
 			Key: key,
@@ -76,14 +194,83 @@ func UploadFile() gin.HandlerFunc {
 	}
 }
 
+// recordUploadAudit 记录文件上传审计日志，未登录的上传以 anonymous 记录
+func recordUploadAudit(c *gin.Context, key, filename string, size int64, deduplicated bool) {
+	actor, ok := middleware.GetUsername(c)
+	if !ok {
+		actor = "anonymous"
+	}
+	_ = audit.Record(c.Request.Context(), actor, "files.upload", "files/"+key,
+		gin.H{"filename": filename, "size": size, "deduplicated": deduplicated})
+}
+
+// chargeStorage 已登录用户计入存储配额（重复内容同样计入，释放引用时扣回），失败只记录日志
+func chargeStorage(c *gin.Context, userID int64, loggedIn bool, size int64) {
+	if !loggedIn {
+		return
+	}
+	if err := quota.DefaultEngine.Add(c.Request.Context(), quota.UserSubject(userID), quota.Storage, size); err != nil {
+		logger.FromContext(c).Warn("记录存储配额失败",
+			zap.Error(err),
+		)
+	}
+}
+
+// DeleteFile 删除文件处理器
+// 用途: 释放当前用户对文件（?key=）的引用；内容寻址存储中其他用户仍持有引用时只减少引用计数，
+// 引用归零才删除对象。只能删除自己上传的文件
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func DeleteFile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Query("key")
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请提供文件 key",
+			})
+			return
+		}
+
+		userID, _ := middleware.GetUserID(c)
+		obj, removed, err := files.Release(c.Request.Context(), storage.S3Storage, key, userID)
+		if errors.Is(err, files.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Error("删除文件失败",
+				zap.String("key", key),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "删除文件失败",
+			})
+			return
+		}
+
+		chargeStorage(c, userID, true, -obj.Size)
+		actor, _ := middleware.GetUsername(c)
+		_ = audit.Record(c.Request.Context(), actor, "files.delete", "files/"+key, gin.H{"removed": removed})
+
+		c.JSON(http.StatusOK, gin.H{
+			"key":     key,
+			"removed": removed,
+		})
+	}
+}
+
 // GetPresignedURL 获取预签名 URL 处理器
-// 用途: 生成文件的临时访问 URL
+// 用途: 生成文件的临时访问 URL，权限与打包下载相同：管理员可访问全部文件，
+// 其他用户只能访问自己上传（内容寻址存储中持有引用）的文件
 // 返回:
 //
 //	gin.HandlerFunc: Gin 处理器函数
 func GetPresignedURL() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID, _ := c.Get("request_id")
+		log := logger.FromContext(c)
 		key := c.Query("key")
 
 		if key == "" {
@@ -93,11 +280,37 @@ func GetPresignedURL() gin.HandlerFunc {
 			return
 		}
 
+		ctx := c.Request.Context()
+		keys := []string{key}
+		found, err := files.Lookup(ctx, keys)
+		if err != nil {
+			log.Error("查询文件记录失败", zap.String("key", key), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "生成访问链接失败",
+			})
+			return
+		}
+		userID, _ := middleware.GetUserID(c)
+		role, _ := middleware.GetUserRole(c)
+		held, err := files.Held(ctx, keys, userID)
+		if err != nil {
+			log.Error("查询文件引用失败", zap.String("key", key), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "生成访问链接失败",
+			})
+			return
+		}
+		if _, err := files.Authorize(found, held, keys, userID, role == "admin"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": files.ErrForbidden.Error(),
+			})
+			return
+		}
+
 		// 生成预签名 URL
 		url, err := storage.S3Storage.GetPresignedURL(key)
 		if err != nil {
-			logger.Error("生成预签名 URL 失败",
-				zap.String("request_id", requestID.(string)),
+			log.Error("生成预签名 URL 失败",
 				zap.String("key", key),
 				zap.Error(err),
 			)
@@ -112,3 +325,16 @@ func GetPresignedURL() gin.HandlerFunc {
 		})
 	}
 }
+
+// detectContentType 按文件开头（最多 512 字节）识别内容类型，之后回到文件开头
+func detectContentType(src multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
@@ -0,0 +1,170 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/files"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/storage"
+	"github.com/zhang/microservice/internal/testutil"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// TestUploadRejectsBeforeStorage 校验在写入对象存储之前即被拒绝的上传
+func TestUploadRejectsBeforeStorage(t *testing.T) {
+	cfg := config.MiddlewareConfig{
+		Chains: map[string][]string{"global": {"recovery", "request_id", "body_limit"}},
+		BodyLimit: config.BodyLimitConfig{
+			MaxSize: 1024,
+			Routes:  []config.RouteBodyLimitConfig{{Method: "POST", Path: "/api/v1/upload", MaxSize: 4096}},
+		},
+	}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.POST("/upload", handler.UploadFile(config.S3Config{MaxFileSize: 1024, AllowedTypes: []string{"image/*"}}))
+	})
+
+	png := []byte("\x89PNG\r\n\x1a\n")
+	upload := func(content []byte, declared string, chunked bool) int {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="file"; filename="a.bin"`)
+		if declared != "" {
+			header.Set("Content-Type", declared)
+		}
+		part, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if chunked {
+			req.ContentLength = -1
+		}
+		return testutil.Do(router, req).Code
+	}
+
+	tests := []struct {
+		name     string
+		content  []byte
+		declared string
+		chunked  bool
+		expected int
+	}{
+		{"文件超过 max_file_size", append(png, bytes.Repeat([]byte{0}, 2048)...), "image/png", false, http.StatusRequestEntityTooLarge},
+		{"请求体超过路由上限", append(png, bytes.Repeat([]byte{0}, 8192)...), "image/png", false, http.StatusRequestEntityTooLarge},
+		{"分块请求体超过路由上限", append(png, bytes.Repeat([]byte{0}, 8192)...), "image/png", true, http.StatusRequestEntityTooLarge},
+		{"内容类型不允许", []byte("hello"), "text/plain", false, http.StatusUnsupportedMediaType},
+		{"声明类型与内容不符", []byte("<html><script>x</script></html>"), "image/png", false, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		if code := upload(tt.content, tt.declared, tt.chunked); code != tt.expected {
+			t.Errorf("%s: 状态码 = %d, 期望 %d", tt.name, code, tt.expected)
+		}
+	}
+
+	// 未上传文件
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upload", strings.NewReader(""))
+	if code := testutil.Do(router, req).Code; code != http.StatusBadRequest {
+		t.Errorf("未上传文件: 状态码 = %d", code)
+	}
+}
+
+// signingStore 只实现预签名的对象存储
+type signingStore struct {
+	storage.ObjectStore
+}
+
+func (signingStore) GetPresignedURL(key string) (string, error) {
+	return "https://signed.example.com/" + key, nil
+}
+
+// useFileStore 以内存 SQLite 替换 database.DB（含文件记录和审计表），以 store 替换对象存储
+func useFileStore(t *testing.T, store storage.ObjectStore) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&files.Object{}, &files.Ref{}, &audit.Entry{}); err != nil {
+		t.Fatal(err)
+	}
+	prevDB, prevStore := database.DB, storage.S3Storage
+	database.DB, storage.S3Storage = db, store
+	t.Cleanup(func() {
+		database.DB, storage.S3Storage = prevDB, prevStore
+		sqlDB.Close()
+	})
+}
+
+// TestPresignedURLAuthorization 校验预签名 URL 的登录和文件权限检查
+func TestPresignedURLAuthorization(t *testing.T) {
+	useFileStore(t, signingStore{})
+
+	ctx := context.Background()
+	if err := files.Record(ctx, "uploads/mine.txt", 2, 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := files.Record(ctx, "uploads/anonymous.txt", 0, 5, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{Chains: map[string][]string{"global": {"recovery"}}}, func(r *gin.RouterGroup) {
+		handler.RegisterUploadRoutes(r, module.Deps{Config: &config.Config{}})
+	})
+	adminToken := minter.MustMint(t, 1, "admin", time.Hour)
+	ownerToken := minter.MustMint(t, 2, "user", time.Hour)
+	otherToken := minter.MustMint(t, 3, "user", time.Hour)
+
+	tests := []struct {
+		name     string
+		key      string
+		token    string
+		expected int
+	}{
+		{"未登录", "uploads/mine.txt", "", http.StatusUnauthorized},
+		{"上传者", "uploads/mine.txt", ownerToken, http.StatusOK},
+		{"其他用户", "uploads/mine.txt", otherToken, http.StatusForbidden},
+		{"管理员", "uploads/mine.txt", adminToken, http.StatusOK},
+		{"匿名上传的文件", "uploads/anonymous.txt", ownerToken, http.StatusForbidden},
+		{"没有记录的文件", "uploads/missing.txt", adminToken, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/presigned-url?key="+tt.key, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", testutil.BearerHeader(tt.token))
+		}
+		w := testutil.Do(router, req)
+		if w.Code != tt.expected {
+			t.Errorf("%s: 状态码 = %d, 期望 %d", tt.name, w.Code, tt.expected)
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), "signed.example.com/"+tt.key) {
+			t.Errorf("%s: 响应 = %s", tt.name, w.Body.String())
+		}
+	}
+}
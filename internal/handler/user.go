@@ -0,0 +1,592 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/redact"
+	"github.com/zhang/microservice/internal/security"
+	"github.com/zhang/microservice/internal/service"
+	"github.com/zhang/microservice/internal/validate"
+	"go.uber.org/zap"
+)
+
+// CreateUserRequest 创建用户请求
+// 名称、邮箱、手机号、时区由 validate.User 校验，以返回字段级错误
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	// Password 登录密码，可选；未设置密码的用户不能登录
+	Password string `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	// Timezone 时区偏好（IANA 名称，如 Asia/Shanghai）
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// UpdateUserRequest 更新用户请求，未提供的字段保持不变
+type UpdateUserRequest struct {
+	Name  *string `json:"name"`
+	Email *string `json:"email"`
+	Phone *string `json:"phone"`
+	// Password 新的登录密码
+	Password *string `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	// Timezone 时区偏好，空字符串表示清除偏好
+	Timezone *string `json:"timezone,omitempty"`
+	// Version 读取到的用户版本，与当前版本不一致时返回 409（用户已被其他请求修改）；不提供时不检查
+	Version *int64 `json:"version,omitempty"`
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	// Role 新角色，必须为 security.role_scopes 中配置的角色
+	Role string `json:"role" binding:"required"`
+}
+
+// RegisterUserRoutes 注册用户模块路由
+// 查询需要登录，创建、更新、删除、恢复、修改角色需要管理员角色
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+func RegisterUserRoutes(r *gin.RouterGroup, deps module.Deps) {
+	users := newUserService()
+	middleware.SetTimezoneLookup(userTimezone)
+	admin := middleware.RequireRole("admin")
+
+	g := r.Group("/users", middleware.JWTAuth())
+	{
+		g.GET("", ListUsers(users))
+		g.GET("/:id", GetUser(users))
+		g.POST("", admin, CreateUser(users))
+		g.PUT("/:id", admin, UpdateUser(users))
+		g.DELETE("/:id", admin, DeleteUser(users))
+		g.POST("/:id/restore", admin, RestoreUser(users))
+		g.DELETE("/:id/purge", admin, PurgeUser(users))
+		g.PUT("/:id/role", admin, UpdateUserRole(users, deps.Config.Security.RoleScopes))
+	}
+}
+
+// ListUsers 用户列表处理器
+// 用途: 分页查询用户，支持 ?page=1&page_size=20&name=&email= 过滤，以及
+// name_prefix（名称前缀）、created_after / created_before（RFC3339 创建时间范围）、sort（id、-id、created_at、-created_at）；
+// cursor 非空时按游标翻页（忽略 page，响应不含 total、page），cursor 取上一页响应的 next_cursor
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func ListUsers(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.Query("page"))
+		pageSize, _ := strconv.Atoi(c.Query("page_size"))
+		page, pageSize = service.NormalizePage(page, pageSize)
+
+		filter := service.UserFilter{
+			Name:       c.Query("name"),
+			NamePrefix: c.Query("name_prefix"),
+			Email:      c.Query("email"),
+			Sort:       c.Query("sort"),
+		}
+		var err error
+		if filter.CreatedAfter, err = queryTime(c, "created_after"); err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if filter.CreatedBefore, err = queryTime(c, "created_before"); err != nil {
+			errs.Write(c, err)
+			return
+		}
+
+		var (
+			list  []*service.User
+			total int64
+			next  string
+		)
+		cursor := c.Query("cursor")
+		if cursor != "" {
+			list, next, err = users.ListUsersAfter(c.Request.Context(), filter, cursor, pageSize)
+		} else {
+			list, total, err = users.ListUsers(c.Request.Context(), filter, (page-1)*pageSize, pageSize)
+			// 偏移量分页也返回游标，客户端可从任意一页切换为游标翻页
+			if err == nil && len(list) > 0 && int64((page-1)*pageSize+len(list)) < total {
+				next = service.EncodeUserCursor(filter.Sort, list[len(list)-1])
+			}
+		}
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+
+		viewer := viewerOf(c)
+		items := make([]interface{}, 0, len(list))
+		for _, user := range list {
+			item, err := redact.UserPolicy.JSON(viewer, user.ID, user)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "查询用户列表失败",
+				})
+				return
+			}
+			items = append(items, item)
+		}
+
+		if cursor != "" {
+			c.JSON(http.StatusOK, gin.H{
+				"items":       items,
+				"page_size":   pageSize,
+				"next_cursor": next,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"items":       items,
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"next_cursor": next,
+		})
+	}
+}
+
+// queryTime 解析 RFC3339 格式的时间查询参数，未提供时返回零值
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errs.Invalid("%s 不是有效的 RFC3339 时间: %q", key, v)
+	}
+	return t, nil
+}
+
+// GetUser 获取用户处理器
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		user, err := users.GetUser(c.Request.Context(), id)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+
+		renderUser(c, http.StatusOK, user)
+	}
+}
+
+// CreateUser 创建用户处理器
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func CreateUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.FromContext(c).Warn("解析请求失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+			})
+			return
+		}
+
+		if !validUser(c, validate.UserInput{Name: &req.Name, Email: &req.Email, Phone: &req.Phone, Timezone: &req.Timezone}) {
+			return
+		}
+
+		user := &service.User{
+			Name:     req.Name,
+			Email:    req.Email,
+			Phone:    req.Phone,
+			Timezone: req.Timezone,
+		}
+		if req.Password != "" {
+			hash, err := security.Passwords.Hash(req.Password)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "创建用户失败",
+				})
+				return
+			}
+			user.PasswordHash = hash
+			// 审计记录不保存明文密码
+			req.Password = ""
+		}
+
+		user, err := users.CreateUser(c.Request.Context(), user)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+
+		recordUserAudit(c, "users.create", user.ID, req)
+		renderUser(c, http.StatusCreated, user)
+	}
+}
+
+// UpdateUser 更新用户处理器
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func UpdateUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		var req UpdateUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			logger.FromContext(c).Warn("解析请求失败",
+				zap.Error(err),
+			)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+			})
+			return
+		}
+		if !validUser(c, validate.UserInput{Name: req.Name, Email: req.Email, Phone: req.Phone, Timezone: req.Timezone}) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := users.GetUser(ctx, id)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		before := *user
+
+		if req.Name != nil {
+			user.Name = *req.Name
+		}
+		if req.Email != nil && *req.Email != user.Email {
+			user.Email = *req.Email
+			user.EmailVerified = false
+		}
+		if req.Phone != nil && *req.Phone != user.Phone {
+			user.Phone = *req.Phone
+			user.PhoneVerified = false
+		}
+		if req.Timezone != nil {
+			user.Timezone = *req.Timezone
+		}
+		if req.Version != nil {
+			user.Version = *req.Version
+		}
+
+		// 读取后用户可能已被修改，未提供 version 时以读取到的版本检查，避免覆盖并发的修改
+		user, err = users.UpdateUser(ctx, user)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		// 审计记录保存变更前后的字段，密码只记录已修改
+		detail := gin.H{"changes": audit.Diff(&before, user)}
+		if req.Password != nil {
+			if !updatePassword(c, users, id, *req.Password) {
+				return
+			}
+			detail["password_changed"] = true
+		}
+		recordUserAudit(c, "users.update", id, detail)
+
+		renderUser(c, http.StatusOK, user)
+	}
+}
+
+// UpdateUserRole 修改用户角色处理器
+// 用途: 修改用户角色并吊销其全部令牌，用户重新登录后按新角色授权；审计记录包含变更前后的角色
+// 参数:
+//
+//	users: 用户服务
+//	roleScopes: 角色权限范围（security.role_scopes），其中的角色为可选角色
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func UpdateUserRole(users *service.UserService, roleScopes map[string][]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		var req UpdateUserRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误",
+			})
+			return
+		}
+		if _, ok := roleScopes[req.Role]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "未知的角色",
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := users.GetUser(ctx, id)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+
+		if user.Role != req.Role {
+			if err := users.SetRole(ctx, id, req.Role); err != nil {
+				errs.Write(c, err)
+				return
+			}
+			// 已签发的令牌仍带有旧角色，吊销后用户需重新登录
+			if err := middleware.RevokeUserTokens(ctx, id); err != nil {
+				logger.FromContext(c).Error("吊销用户令牌失败",
+					zap.Int64("user_id", id),
+					zap.Error(err),
+				)
+			}
+			recordUserAudit(c, "users.role", id, gin.H{
+				"changes": audit.Diff(gin.H{"role": user.Role}, gin.H{"role": req.Role}),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"id":   id,
+			"role": req.Role,
+		})
+	}
+}
+
+// DeleteUser 删除用户处理器
+// 用途: 软删除用户并吊销其已签发的令牌，可通过 RestoreUser 恢复，超过 users.purge_after_days 天后由定时任务永久删除
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func DeleteUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := users.DeleteUser(ctx, id); err != nil {
+			errs.Write(c, err)
+			return
+		}
+		// 已签发的令牌在过期前仍可使用，吊销后已删除的用户无法继续访问
+		if err := middleware.RevokeUserTokens(ctx, id); err != nil {
+			logger.FromContext(c).Error("吊销用户令牌失败",
+				zap.Int64("user_id", id),
+				zap.Error(err),
+			)
+		}
+		recordUserAudit(c, "users.delete", id, nil)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// RestoreUser 恢复用户处理器
+// 用途: 恢复已软删除（尚未永久删除）的用户
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func RestoreUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		user, err := users.RestoreUser(c.Request.Context(), id)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if user == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在或未被删除",
+			})
+			return
+		}
+		recordUserAudit(c, "users.restore", id, nil)
+
+		renderUser(c, http.StatusOK, user)
+	}
+}
+
+// PurgeUser 永久删除用户处理器
+// 用途: 立即永久删除用户（包括已软删除的），不可恢复
+// 参数:
+//
+//	users: 用户服务
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func PurgeUser(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseUserID(c)
+		if !ok {
+			return
+		}
+
+		purged, err := users.PurgeUser(c.Request.Context(), id)
+		if err != nil {
+			errs.Write(c, err)
+			return
+		}
+		if !purged {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "用户不存在",
+			})
+			return
+		}
+		recordUserAudit(c, "users.purge", id, nil)
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// parseUserID 解析路径中的用户 ID，失败时直接返回 400
+func parseUserID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "用户 ID 错误",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// newUserService 创建用户服务，用户查询经 Redis 缓存（见 service.CachedUserRepository）
+func newUserService() *service.UserService {
+	return service.NewUserService(service.NewCachedUserRepository(service.NewGormUserRepository(database.DB), cache.DefaultRefresher))
+}
+
+// userTimezone 查询用户的时区偏好（经用户资料缓存）
+func userTimezone(ctx context.Context, userID int64) (string, error) {
+	var user service.User
+	if _, err := cache.DefaultRefresher.Fetch(ctx, service.UserCacheClass, strconv.FormatInt(userID, 10), &user); err != nil {
+		return "", err
+	}
+	return user.Timezone, nil
+}
+
+// validUser 校验用户输入，不合法时直接返回 400 及字段级错误
+func validUser(c *gin.Context, in validate.UserInput) bool {
+	err := validate.User(in)
+	if err == nil {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  "请求参数错误",
+		"fields": err.(*validate.Error).Violations,
+	})
+	return false
+}
+
+// updatePassword 哈希并保存新密码，失败时写入错误响应
+func updatePassword(c *gin.Context, users *service.UserService, id int64, password string) bool {
+	hash, err := security.Passwords.Hash(password)
+	if err == nil {
+		err = users.SetPasswordHash(c.Request.Context(), id, hash)
+	}
+	if err != nil {
+		errs.Write(c, err)
+		return false
+	}
+	return true
+}
+
+// recordUserAudit 记录用户变更审计日志（写入失败只记录日志，不影响请求结果）
+func recordUserAudit(c *gin.Context, action string, id int64, detail interface{}) {
+	actor, _ := middleware.GetUsername(c)
+	_ = audit.Record(c.Request.Context(), actor, action, "users/"+strconv.FormatInt(id, 10), detail)
+}
+
+// viewerOf 返回当前请求方，用于字段可见性策略
+func viewerOf(c *gin.Context) redact.Viewer {
+	userID, _ := middleware.GetUserID(c)
+	role, _ := middleware.GetUserRole(c)
+	return redact.Viewer{UserID: userID, Role: role}
+}
+
+// renderUser 按字段可见性策略输出用户
+func renderUser(c *gin.Context, code int, user *service.User) {
+	body, err := redact.UserPolicy.JSON(viewerOf(c), user.ID, user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "序列化用户失败",
+		})
+		return
+	}
+	c.JSON(code, body)
+}
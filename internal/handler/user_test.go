@@ -0,0 +1,121 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/handler"
+	"github.com/zhang/microservice/internal/jsonschema"
+	"github.com/zhang/microservice/internal/module"
+	"github.com/zhang/microservice/internal/testutil"
+	"github.com/zhang/microservice/internal/usersettings"
+)
+
+// TestUserRoutesRejectBeforeDatabase 校验在访问数据库之前即被拒绝的请求
+func TestUserRoutesRejectBeforeDatabase(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		handler.RegisterUserRoutes(r, module.Deps{Config: &config.Config{}})
+	})
+
+	adminToken := minter.MustMint(t, 1, "admin", time.Hour)
+	userToken := minter.MustMint(t, 2, "user", time.Hour)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		token    string
+		expected int
+	}{
+		{"未登录", http.MethodGet, "/api/v1/users", "", "", http.StatusUnauthorized},
+		{"普通用户创建", http.MethodPost, "/api/v1/users", `{"name":"a","email":"a@example.com"}`, userToken, http.StatusForbidden},
+		{"普通用户删除", http.MethodDelete, "/api/v1/users/1", "", userToken, http.StatusForbidden},
+		{"普通用户恢复", http.MethodPost, "/api/v1/users/1/restore", "", userToken, http.StatusForbidden},
+		{"普通用户永久删除", http.MethodDelete, "/api/v1/users/1/purge", "", userToken, http.StatusForbidden},
+		{"恢复非法 ID", http.MethodPost, "/api/v1/users/0/restore", "", adminToken, http.StatusBadRequest},
+		{"非法 ID", http.MethodGet, "/api/v1/users/abc", "", userToken, http.StatusBadRequest},
+		{"不支持的排序", http.MethodGet, "/api/v1/users?sort=name", "", userToken, http.StatusBadRequest},
+		{"创建时间格式错误", http.MethodGet, "/api/v1/users?created_after=2024-01-01", "", userToken, http.StatusBadRequest},
+		{"无效游标", http.MethodGet, "/api/v1/users?cursor=abc", "", userToken, http.StatusBadRequest},
+		{"缺少邮箱", http.MethodPost, "/api/v1/users", `{"name":"a"}`, adminToken, http.StatusBadRequest},
+		{"邮箱格式错误", http.MethodPut, "/api/v1/users/1", `{"email":"bad"}`, adminToken, http.StatusBadRequest},
+		{"手机号格式错误", http.MethodPost, "/api/v1/users", `{"name":"a","email":"a@example.com","phone":"abc"}`, adminToken, http.StatusBadRequest},
+		{"名称为空", http.MethodPut, "/api/v1/users/1", `{"name":"  "}`, adminToken, http.StatusBadRequest},
+		{"无效时区", http.MethodPut, "/api/v1/users/1", `{"timezone":"Mars/Olympus"}`, adminToken, http.StatusBadRequest},
+		{"普通用户修改角色", http.MethodPut, "/api/v1/users/1/role", `{"role":"admin"}`, userToken, http.StatusForbidden},
+		{"未知角色", http.MethodPut, "/api/v1/users/1/role", `{"role":"root"}`, adminToken, http.StatusBadRequest},
+		{"缺少角色", http.MethodPut, "/api/v1/users/1/role", `{}`, adminToken, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", testutil.BearerHeader(tt.token))
+			}
+			if code := testutil.Do(router, req).Code; code != tt.expected {
+				t.Errorf("期望状态码 %d, 实际 %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+// TestUserSettingsRejectBeforeDatabase 校验无效的设置变更在访问数据库之前被拒绝
+func TestUserSettingsRejectBeforeDatabase(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	err := usersettings.Init(config.UserSettingsConfig{Keys: []config.UserSettingKey{
+		{Name: "theme", Schema: jsonschema.Schema{Type: "string", Enum: []interface{}{"light", "dark"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = usersettings.Init(config.UserSettingsConfig{}) })
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		handler.RegisterMeRoutes(r, module.Deps{Config: &config.Config{}})
+	})
+	token := minter.MustMint(t, 2, "user", time.Hour)
+
+	tests := []struct {
+		name     string
+		body     string
+		token    string
+		expected int
+	}{
+		{"未登录", `{"theme":"dark"}`, "", http.StatusUnauthorized},
+		{"空对象", `{}`, token, http.StatusBadRequest},
+		{"不是对象", `["theme"]`, token, http.StatusBadRequest},
+		{"未知设置项", `{"font":"serif"}`, token, http.StatusBadRequest},
+		{"值无效", `{"theme":"blue"}`, token, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/me/settings", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set("Authorization", testutil.BearerHeader(tt.token))
+			}
+			if code := testutil.Do(router, req).Code; code != tt.expected {
+				t.Errorf("期望状态码 %d, 实际 %d", tt.expected, code)
+			}
+		})
+	}
+}
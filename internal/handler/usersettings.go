@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/usersettings"
+	"go.uber.org/zap"
+)
+
+// GetMySettings 获取当前用户设置处理器
+// 返回所有设置项，未设置的项为默认值
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func GetMySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+
+		values, err := usersettings.Get(c.Request.Context(), userID)
+		if err != nil {
+			logger.FromContext(c).Error("查询用户设置失败",
+				zap.Int64("user_id", userID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "查询设置失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, values)
+	}
+}
+
+// UpdateMySettings 更新当前用户设置处理器
+// 请求体为设置项到值的 JSON 对象，只修改出现的项，值为 null 恢复默认值
+// 返回:
+//
+//	gin.HandlerFunc: Gin 处理器函数
+func UpdateMySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := middleware.GetUserID(c)
+
+		var changes map[string]json.RawMessage
+		if err := c.ShouldBindJSON(&changes); err != nil || len(changes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求体必须是非空的 JSON 对象",
+			})
+			return
+		}
+		// 先校验再访问数据库
+		if err := usersettings.Validate(changes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		values, err := usersettings.Update(c.Request.Context(), userID, changes)
+		if errors.Is(err, usersettings.ErrUnknownKey) || errors.Is(err, usersettings.ErrInvalidValue) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			logger.FromContext(c).Error("更新用户设置失败",
+				zap.Int64("user_id", userID),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "保存设置失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, values)
+	}
+}
@@ -0,0 +1,101 @@
+package jobrun
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// 执行状态
+const (
+	// StatusSuccess 执行成功
+	StatusSuccess = "success"
+	// StatusFailed 执行失败（返回错误或 panic）
+	StatusFailed = "failed"
+)
+
+// Run 定时任务执行记录
+type Run struct {
+	ID         int64     `gorm:"primaryKey" json:"id"`
+	Job        string    `gorm:"type:varchar(100);not null;index:idx_job_runs_job_started,priority:1" json:"job"`
+	StartedAt  time.Time `gorm:"not null;index:idx_job_runs_job_started,priority:2;index" json:"started_at"`
+	FinishedAt time.Time `gorm:"not null" json:"finished_at"`
+	// DurationMs 执行耗时（毫秒）
+	DurationMs int64  `gorm:"not null" json:"duration_ms"`
+	Status     string `gorm:"type:varchar(20);not null" json:"status"`
+	Error      string `gorm:"type:text" json:"error,omitempty"`
+	// Host 执行任务的主机名
+	Host string `gorm:"type:varchar(255)" json:"host"`
+}
+
+// TableName 指定表名
+func (Run) TableName() string {
+	return "job_runs"
+}
+
+// hostname 当前主机名，启动时获取一次
+var hostname, _ = os.Hostname()
+
+// Record 保存一次执行记录，写入失败只记录日志，不影响任务本身
+// 参数:
+//
+//	ctx: 上下文
+//	job: 任务名称
+//	started: 开始时间
+//	finished: 结束时间
+//	runErr: 任务返回的错误，nil 表示成功
+func Record(ctx context.Context, job string, started, finished time.Time, runErr error) {
+	run := &Run{
+		Job:        job,
+		StartedAt:  started,
+		FinishedAt: finished,
+		DurationMs: finished.Sub(started).Milliseconds(),
+		Status:     StatusSuccess,
+		Host:       hostname,
+	}
+	if runErr != nil {
+		run.Status = StatusFailed
+		run.Error = runErr.Error()
+	}
+
+	if err := database.DB.WithContext(ctx).Create(run).Error; err != nil {
+		logger.Error("保存任务执行记录失败", zap.String("任务", job), zap.Error(err))
+	}
+}
+
+// Filter 执行记录查询条件，空字段表示不过滤
+type Filter struct {
+	Job    string
+	Status string
+}
+
+// List 按开始时间倒序查询最近的执行记录
+// 参数:
+//
+//	ctx: 上下文
+//	filter: 查询条件
+//	limit: 最大条数
+//
+// 返回:
+//
+//	[]Run: 执行记录
+//	error: 错误信息
+func List(ctx context.Context, filter Filter, limit int) ([]Run, error) {
+	db := database.DB.WithContext(ctx)
+	if filter.Job != "" {
+		db = db.Where("job = ?", filter.Job)
+	}
+	if filter.Status != "" {
+		db = db.Where("status = ?", filter.Status)
+	}
+
+	var runs []Run
+	if err := db.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,245 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Schema JSON Schema 的子集，用于在配置文件中声明值的结构
+// 支持 type、enum、minimum/maximum、max_length、max_items、items、properties、required；
+// 声明了 properties 的对象不允许出现未声明的属性
+type Schema struct {
+	// Type string、number、integer、boolean、array、object，为空表示任意类型
+	Type string `mapstructure:"type"`
+	// Enum 允许的取值
+	Enum []interface{} `mapstructure:"enum"`
+	// Minimum 数值下限（含）
+	Minimum *float64 `mapstructure:"minimum"`
+	// Maximum 数值上限（含）
+	Maximum *float64 `mapstructure:"maximum"`
+	// MaxLength 字符串最大长度（字符数），0 表示不限制
+	MaxLength int `mapstructure:"max_length"`
+	// MaxItems 数组最大元素数，0 表示不限制
+	MaxItems int `mapstructure:"max_items"`
+	// Items 数组元素的结构
+	Items *Schema `mapstructure:"items"`
+	// Properties 对象各属性的结构
+	Properties map[string]*Schema `mapstructure:"properties"`
+	// Required 对象必填的属性
+	Required []string `mapstructure:"required"`
+}
+
+// types 支持的类型
+var types = map[string]bool{
+	"": true, "string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true,
+}
+
+// Check 检查结构声明本身是否有效（类型名称、嵌套结构）
+// 返回:
+//
+//	error: 错误信息
+func (s *Schema) Check() error {
+	if !types[s.Type] {
+		return fmt.Errorf("不支持的类型 %q", s.Type)
+	}
+	if s.Items != nil {
+		if err := s.Items.Check(); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		if s.Properties[name] == nil {
+			continue
+		}
+		if err := s.Properties[name].Check(); err != nil {
+			return fmt.Errorf("properties.%s: %w", name, err)
+		}
+	}
+	for _, v := range s.Enum {
+		if err := s.Validate(Normalize(v)); err != nil {
+			return fmt.Errorf("enum 取值 %v 不符合类型: %w", v, err)
+		}
+	}
+	return nil
+}
+
+// ValidateJSON 校验 JSON 值
+// 参数:
+//
+//	raw: JSON 值
+//
+// 返回:
+//
+//	error: 不是合法 JSON 或不符合结构时返回错误
+func (s *Schema) ValidateJSON(raw []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return fmt.Errorf("不是合法的 JSON: %w", err)
+	}
+	return s.Validate(v)
+}
+
+// Validate 校验 json.Unmarshal 解码得到的值
+// 参数:
+//
+//	v: 值（数字为 float64，对象为 map[string]interface{}）
+//
+// 返回:
+//
+//	error: 不符合结构时返回错误，错误信息带有属性路径
+func (s *Schema) Validate(v interface{}) error {
+	return s.validate("", v)
+}
+
+// validate 按路径递归校验
+func (s *Schema) validate(path string, v interface{}) error {
+	if !s.matchesType(v) {
+		return pathError(path, "应为 %s 类型", s.Type)
+	}
+	if len(s.Enum) > 0 && !s.inEnum(v) {
+		return pathError(path, "取值不在 %v 中", s.Enum)
+	}
+
+	switch val := v.(type) {
+	case float64:
+		if s.Minimum != nil && val < *s.Minimum {
+			return pathError(path, "不能小于 %v", *s.Minimum)
+		}
+		if s.Maximum != nil && val > *s.Maximum {
+			return pathError(path, "不能大于 %v", *s.Maximum)
+		}
+	case string:
+		if s.MaxLength > 0 && utf8.RuneCountInString(val) > s.MaxLength {
+			return pathError(path, "长度不能超过 %d", s.MaxLength)
+		}
+	case []interface{}:
+		if s.MaxItems > 0 && len(val) > s.MaxItems {
+			return pathError(path, "元素不能超过 %d 个", s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range val {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := val[name]; !ok {
+				return pathError(join(path, name), "缺少必填属性")
+			}
+		}
+		if len(s.Properties) == 0 {
+			return nil
+		}
+		for _, name := range sortedKeys(val) {
+			prop, ok := s.Properties[name]
+			if !ok {
+				return pathError(join(path, name), "未声明的属性")
+			}
+			if prop == nil {
+				continue
+			}
+			if err := prop.validate(join(path, name), val[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType 值是否符合声明的类型
+func (s *Schema) matchesType(v interface{}) bool {
+	switch s.Type {
+	case "":
+		return true
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+// inEnum 值是否在枚举中，按 JSON 编码比较
+func (s *Schema) inEnum(v interface{}) bool {
+	encoded, _ := json.Marshal(v)
+	for _, e := range s.Enum {
+		if candidate, err := json.Marshal(Normalize(e)); err == nil && string(candidate) == string(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize 把配置文件解析出的值（int、map[interface{}]interface{} 等）转换为 json.Unmarshal 的表示
+// 参数:
+//
+//	v: 任意值
+//
+// 返回:
+//
+//	interface{}: 转换后的值，无法转换时原样返回
+func Normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = item
+		}
+		v = m
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+// pathError 带属性路径的错误
+func pathError(path, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if path == "" {
+		return errors.New(msg)
+	}
+	return fmt.Errorf("%s: %s", path, msg)
+}
+
+// join 拼接属性路径
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sortedKeys 返回排序后的键，使错误信息稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,67 @@
+package jsonschema
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	one, ten := 1.0, 10.0
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"theme":  {Type: "string", Enum: []interface{}{"light", "dark"}},
+			"size":   {Type: "integer", Minimum: &one, Maximum: &ten},
+			"name":   {Type: "string", MaxLength: 3},
+			"tags":   {Type: "array", MaxItems: 2, Items: &Schema{Type: "string"}},
+			"extras": {Type: "object"},
+		},
+		Required: []string{"theme"},
+	}
+
+	tests := []struct {
+		raw   string
+		valid bool
+	}{
+		{`{"theme":"dark"}`, true},
+		{`{"theme":"dark","size":10,"name":"张三丰","tags":["a"],"extras":{"any":1}}`, true},
+		{`{"size":1}`, false},
+		{`{"theme":"blue"}`, false},
+		{`{"theme":"dark","size":1.5}`, false},
+		{`{"theme":"dark","size":11}`, false},
+		{`{"theme":"dark","name":"abcd"}`, false},
+		{`{"theme":"dark","tags":["a","b","c"]}`, false},
+		{`{"theme":"dark","tags":[1]}`, false},
+		{`{"theme":"dark","unknown":true}`, false},
+		{`[]`, false},
+		{`{`, false},
+	}
+	for _, tt := range tests {
+		if err := schema.ValidateJSON([]byte(tt.raw)); (err == nil) != tt.valid {
+			t.Errorf("ValidateJSON(%s) error = %v, 期望有效 = %v", tt.raw, err, tt.valid)
+		}
+	}
+
+	if err := schema.ValidateJSON([]byte(`{"theme":"dark","tags":[1]}`)); err == nil || err.Error() != "tags[0]: 应为 string 类型" {
+		t.Errorf("错误信息应包含属性路径, 实际 %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// 配置文件中的枚举值为 int，需按 JSON 数字比较
+	valid := &Schema{Type: "integer", Enum: []interface{}{10, 20}}
+	if err := valid.Check(); err != nil {
+		t.Fatalf("Check() = %v", err)
+	}
+	if err := valid.ValidateJSON([]byte(`20`)); err != nil {
+		t.Errorf("ValidateJSON(20) = %v", err)
+	}
+
+	invalid := []*Schema{
+		{Type: "date"},
+		{Type: "array", Items: &Schema{Type: "map"}},
+		{Type: "string", Enum: []interface{}{1}},
+	}
+	for _, s := range invalid {
+		if err := s.Check(); err == nil {
+			t.Errorf("Check(%+v) 应返回错误", s)
+		}
+	}
+}
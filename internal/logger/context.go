@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"go.uber.org/zap"
+)
+
+// contextKey 上下文中日志记录器的键
+type contextKey struct{}
+
+// WithContext 返回带有日志记录器的上下文，之后经由该上下文调用的服务、仓库、消费者用 FromContext 取出
+// 请求 ID 中间件放入带 request_id、trace_id 的记录器，认证通过后追加 user_id
+// 参数:
+//
+//	ctx: 上下文
+//	l: 日志记录器
+//
+// 返回:
+//
+//	context.Context: 新上下文
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// SetContext 把日志记录器放入 Gin 请求的上下文
+// 参数:
+//
+//	c: Gin 上下文
+//	l: 日志记录器
+func SetContext(c *gin.Context, l *zap.Logger) {
+	c.Request = c.Request.WithContext(WithContext(c.Request.Context(), l))
+}
+
+// FromContext 返回上下文中的日志记录器；没有时返回全局日志记录器，上下文中有请求 ID 时带上 request_id
+// 参数:
+//
+//	ctx: 上下文（*gin.Context、请求上下文、gRPC 上下文或消息处理上下文）
+//
+// 返回:
+//
+//	*zap.Logger: 日志记录器
+func FromContext(ctx context.Context) *zap.Logger {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return Logger
+		}
+		ctx = c.Request.Context()
+	}
+	if ctx == nil {
+		return Logger
+	}
+	if l, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return l
+	}
+	if id := ctxkeys.RequestID(ctx); id != "" {
+		return WithRequestID(id)
+	}
+	return Logger
+}
+
+// With 返回日志记录器追加了字段的上下文
+// 参数:
+//
+//	ctx: 上下文
+//	fields: 追加的字段
+//
+// 返回:
+//
+//	context.Context: 新上下文
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(fields...))
+}
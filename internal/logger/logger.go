@@ -3,6 +3,7 @@ package logger
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/zhang/microservice/internal/config"
 	"go.uber.org/zap"
@@ -15,6 +16,57 @@ var (
 	Sugar  *zap.SugaredLogger
 )
 
+// level 普通日志输出的级别，支持配置热加载时动态调整
+var level = zap.NewAtomicLevel()
+
+// watchOnce 保证只注册一次配置热加载回调
+var watchOnce sync.Once
+
+// parseLevel 解析日志级别，未知值按 info 处理
+func parseLevel(s string) zapcore.Level {
+	switch s {
+	case "debug":
+		return zapcore.DebugLevel
+	case "warn":
+		return zapcore.WarnLevel
+	case "error":
+		return zapcore.ErrorLevel
+	}
+	return zapcore.InfoLevel
+}
+
+// SetLevel 调整普通日志输出的级别（错误日志输出始终为 error 级别）
+// 参数:
+//
+//	s: 日志级别（debug、info、warn、error）
+func SetLevel(s string) {
+	level.SetLevel(parseLevel(s))
+}
+
+// ValidLevel 判断日志级别是否有效
+// 参数:
+//
+//	s: 日志级别
+//
+// 返回:
+//
+//	bool: 是否为 debug、info、warn、error 之一
+func ValidLevel(s string) bool {
+	switch s {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
+}
+
+// Level 返回普通日志输出的当前级别
+// 返回:
+//
+//	string: 日志级别
+func Level() string {
+	return level.String()
+}
+
 // Init 初始化日志系统
 // 参数:
 //
@@ -24,18 +76,18 @@ var (
 //
 //	error: 错误信息
 func Init(cfg config.LoggerConfig) error {
-	// 设置日志级别
-	level := zapcore.InfoLevel
-	switch cfg.Level {
-	case "debug":
-		level = zapcore.DebugLevel
-	case "info":
-		level = zapcore.InfoLevel
-	case "warn":
-		level = zapcore.WarnLevel
-	case "error":
-		level = zapcore.ErrorLevel
-	}
+	// 设置日志级别，配置文件修改后自动调整
+	SetLevel(cfg.Level)
+	configured.Store(cfg.Level)
+	watchOnce.Do(func() {
+		config.OnReload(func(old, next *config.Config) {
+			if old.Logger.Level != next.Logger.Level {
+				SetLevel(next.Logger.Level)
+				configured.Store(next.Logger.Level)
+				Info("日志级别已更新", zap.String("级别", level.String()))
+			}
+		})
+	})
 
 	// 创建编码器配置
 	encoderConfig := zapcore.EncoderConfig{
@@ -62,10 +114,11 @@ func Init(cfg config.LoggerConfig) error {
 
 	// 设置输出路径
 	var cores []zapcore.Core
+	files := make(map[string]zapcore.WriteSyncer)
 
 	// 普通日志输出
 	for _, path := range cfg.OutputPaths {
-		writer, err := getWriter(path)
+		writer, err := getWriter(cfg, path, files)
 		if err != nil {
 			return fmt.Errorf("创建日志输出失败: %w", err)
 		}
@@ -79,7 +132,7 @@ func Init(cfg config.LoggerConfig) error {
 
 	// 错误日志输出
 	for _, path := range cfg.ErrorOutputPaths {
-		writer, err := getWriter(path)
+		writer, err := getWriter(cfg, path, files)
 		if err != nil {
 			return fmt.Errorf("创建错误日志输出失败: %w", err)
 		}
@@ -107,34 +160,34 @@ func Init(cfg config.LoggerConfig) error {
 }
 
 // getWriter 获取日志输出 Writer
+// 文件输出按 cfg.RotationFor(path) 切割和保留；同一路径同时出现在普通和错误日志输出中时共用一个 Writer
 // 参数:
 //
+//	cfg: 日志配置
 //	path: 输出路径
+//	files: 已打开的日志文件
 //
 // 返回:
 //
 //	zapcore.WriteSyncer: 日志写入器
 //	error: 错误信息
-func getWriter(path string) (zapcore.WriteSyncer, error) {
+func getWriter(cfg config.LoggerConfig, path string, files map[string]zapcore.WriteSyncer) (zapcore.WriteSyncer, error) {
 	if path == "stdout" {
 		return zapcore.AddSync(os.Stdout), nil
 	}
 	if path == "stderr" {
 		return zapcore.AddSync(os.Stderr), nil
 	}
-
-	// 确保日志目录存在
-	if err := os.MkdirAll("logs", 0755); err != nil {
-		return nil, err
+	if w, ok := files[path]; ok {
+		return w, nil
 	}
 
-	// 打开文件
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	file, err := newRotatingFile(path, cfg.RotationFor(path))
 	if err != nil {
 		return nil, err
 	}
-
-	return zapcore.AddSync(file), nil
+	files[path] = file
+	return file, nil
 }
 
 // Sync 刷新日志缓冲区
@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// backupTimeFormat 旧日志文件名中的切割时间（UTC），如 app-2024-01-02T15-04-05.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile 按大小切割的日志文件
+// 写入超过 max_size 时把当前文件改名为带切割时间的旧文件并重新创建，
+// 之后在后台按 max_backups、max_age 删除旧文件，按 compress 压缩旧文件
+type rotatingFile struct {
+	path string
+	cfg  config.LogRotationConfig
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	// millCh 通知后台清理旧文件，容量为 1，切割频繁时合并为一次
+	millCh   chan struct{}
+	millOnce sync.Once
+}
+
+// newRotatingFile 打开（必要时创建）日志文件
+// 参数:
+//
+//	path: 文件路径
+//	cfg: 切割策略
+//
+// 返回:
+//
+//	*rotatingFile: 日志文件
+//	error: 错误信息
+func newRotatingFile(path string, cfg config.LogRotationConfig) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, cfg: cfg}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write 写入日志，超过大小上限时先切割
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.cfg.GetMaxSize() {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Sync 把缓冲写入磁盘
+func (r *rotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Sync()
+}
+
+// open 以追加方式打开日志文件，记录已有大小
+func (r *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+// rotate 把当前文件改名为旧文件并重新创建，调用方需持有 mu
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(r.path, r.backupName(time.Now())); err != nil {
+		return fmt.Errorf("切割日志文件失败: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.millOnce.Do(func() {
+		r.millCh = make(chan struct{}, 1)
+		go func() {
+			for range r.millCh {
+				_ = r.mill()
+			}
+		}()
+	})
+	select {
+	case r.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backupName 返回切割时间为 t 的旧文件名
+func (r *rotatingFile) backupName(t time.Time) string {
+	prefix, ext := r.nameParts()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// nameParts 返回旧文件名的前缀（含目录和连字符）和扩展名
+func (r *rotatingFile) nameParts() (prefix, ext string) {
+	ext = filepath.Ext(r.path)
+	return strings.TrimSuffix(r.path, ext) + "-", ext
+}
+
+// backup 切割出的旧文件
+type backup struct {
+	path string
+	time time.Time
+}
+
+// backups 返回旧文件，最新的在前
+func (r *rotatingFile) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := r.nameParts()
+	prefix = filepath.Base(prefix)
+
+	var list []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext), prefix)
+		t, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		list = append(list, backup{path: filepath.Join(filepath.Dir(r.path), name), time: t})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].time.After(list[j].time) })
+	return list, nil
+}
+
+// mill 删除超过个数或保留天数的旧文件，压缩其余未压缩的旧文件
+func (r *rotatingFile) mill() error {
+	list, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	maxAge := r.cfg.GetMaxAge()
+	var errs []error
+	for i, b := range list {
+		expired := r.cfg.MaxBackups > 0 && i >= r.cfg.MaxBackups ||
+			maxAge > 0 && time.Since(b.time) > maxAge
+		switch {
+		case expired:
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		case r.cfg.Compress && !strings.HasSuffix(b.path, ".gz"):
+			if err := compressFile(b.path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("清理旧日志文件失败: %v", errs)
+	}
+	return nil
+}
+
+// compressFile 把文件压缩为同名的 .gz 文件并删除原文件
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
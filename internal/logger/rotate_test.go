@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestRotatingFileRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "app.log")
+	r, err := newRotatingFile(path, config.LogRotationConfig{MaxSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.file.Close()
+
+	chunk := bytes.Repeat([]byte("x"), 600<<10)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := r.backups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("超过 1MB 后应切割出 1 个旧文件, 得到 %d", len(list))
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(chunk)) {
+		t.Errorf("切割后当前文件应只有最后一次写入, 大小为 %d", info.Size())
+	}
+}
+
+func TestRotatingFileMill(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	r := &rotatingFile{path: path, cfg: config.LogRotationConfig{MaxAge: 7, MaxBackups: 2, Compress: true}}
+
+	now := time.Now()
+	for _, age := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 10 * 24 * time.Hour} {
+		if err := os.WriteFile(r.backupName(now.Add(-age)), []byte("old"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 其他文件不受影响
+	for _, name := range []string{"app.log", "app-error.log", "other-2024-01-02T15-04-05.000.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.mill(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	want := []string{
+		filepath.Base(r.backupName(now.Add(-2*time.Hour))) + ".gz",
+		filepath.Base(r.backupName(now.Add(-time.Hour))) + ".gz",
+		"app-error.log",
+		"app.log",
+		"other-2024-01-02T15-04-05.000.log",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("清理后的文件 %v, 期望 %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("清理后的文件 %v, 期望 %v", names, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// configured 配置文件中的日志级别，从 debug 切回时恢复为该级别
+var configured atomic.Value
+
+// ToggleDebug 在 debug 与配置文件中的日志级别之间切换
+// 返回:
+//
+//	string: 切换后的日志级别
+func ToggleDebug() string {
+	if level.Level() == zapcore.DebugLevel {
+		s, _ := configured.Load().(string)
+		SetLevel(s)
+	} else {
+		level.SetLevel(zapcore.DebugLevel)
+	}
+	return Level()
+}
+
+// WatchSignal 收到 SIGHUP 时调用 ToggleDebug，运维人员可临时打开线上实例的 debug 日志而无需重启
+// （kill -HUP <pid>，再发送一次恢复）；需在 Init 之后调用
+func WatchSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			Info("收到 SIGHUP，日志级别已切换", zap.String("级别", ToggleDebug()))
+		}
+	}()
+}
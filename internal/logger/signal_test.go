@@ -0,0 +1,29 @@
+package logger
+
+import "testing"
+
+func TestToggleDebug(t *testing.T) {
+	defer SetLevel("info")
+	SetLevel("warn")
+	configured.Store("warn")
+
+	if got := ToggleDebug(); got != "debug" {
+		t.Errorf("第一次切换应为 debug, 得到 %q", got)
+	}
+	if got := ToggleDebug(); got != "warn" {
+		t.Errorf("第二次切换应恢复为配置的 warn, 得到 %q", got)
+	}
+}
+
+func TestValidLevel(t *testing.T) {
+	for _, s := range []string{"debug", "info", "warn", "error"} {
+		if !ValidLevel(s) {
+			t.Errorf("%q 应为有效级别", s)
+		}
+	}
+	for _, s := range []string{"", "trace", "DEBUG", "fatal"} {
+		if ValidLevel(s) {
+			t.Errorf("%q 应为无效级别", s)
+		}
+	}
+}
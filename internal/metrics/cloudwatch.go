@@ -0,0 +1,128 @@
+//go:build !noaws
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/zhang/microservice/internal/config"
+)
+
+const (
+	// cloudWatchBatchSize 单次 PutMetricData 的最大数据点数
+	cloudWatchBatchSize = 1000
+	// cloudWatchMaxDimensions 单个数据点的最大维度数
+	cloudWatchMaxDimensions = 30
+)
+
+// cloudWatchExporter 通过 PutMetricData 推送到 CloudWatch
+type cloudWatchExporter struct {
+	client     *cloudwatch.CloudWatch
+	namespace  string
+	dimensions []*cloudwatch.Dimension
+}
+
+// newCloudWatchExporter 创建 CloudWatch 推送器
+func newCloudWatchExporter(cfg config.CloudWatchConfig, awsCfg config.AWSConfig) (*cloudWatchExporter, error) {
+	if cfg.Namespace == "" {
+		return nil, fmt.Errorf("未配置 CloudWatch 命名空间")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(awsCfg.Region),
+		Credentials: credentials.NewStaticCredentials(
+			awsCfg.AccessKey,
+			awsCfg.SecretKey,
+			"",
+		),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建 AWS 会话失败: %w", err)
+	}
+
+	// 固定维度按名称排序，保证同一指标的维度组合稳定
+	names := make([]string, 0, len(cfg.Dimensions))
+	for name := range cfg.Dimensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	dims := make([]*cloudwatch.Dimension, 0, len(names))
+	for _, name := range names {
+		dims = append(dims, &cloudwatch.Dimension{
+			Name:  aws.String(name),
+			Value: aws.String(cfg.Dimensions[name]),
+		})
+	}
+
+	return &cloudWatchExporter{
+		client:     cloudwatch.New(sess),
+		namespace:  cfg.Namespace,
+		dimensions: dims,
+	}, nil
+}
+
+// Export 实现 Exporter，按批次调用 PutMetricData
+func (e *cloudWatchExporter) Export(ctx context.Context, samples []Sample) error {
+	now := time.Now()
+	data := make([]*cloudwatch.MetricDatum, 0, len(samples))
+	for _, s := range samples {
+		data = append(data, e.datum(s, now))
+	}
+
+	for start := 0; start < len(data); start += cloudWatchBatchSize {
+		end := start + cloudWatchBatchSize
+		if end > len(data) {
+			end = len(data)
+		}
+		_, err := e.client.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(e.namespace),
+			MetricData: data[start:end],
+		})
+		if err != nil {
+			return fmt.Errorf("推送 CloudWatch 指标失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// datum 转换为 CloudWatch 数据点，标签作为维度（空值标签跳过，CloudWatch 不接受空维度值）
+func (e *cloudWatchExporter) datum(s Sample, now time.Time) *cloudwatch.MetricDatum {
+	dims := append([]*cloudwatch.Dimension(nil), e.dimensions...)
+	for _, l := range s.Labels {
+		if l.Value == "" || len(dims) >= cloudWatchMaxDimensions {
+			continue
+		}
+		dims = append(dims, &cloudwatch.Dimension{
+			Name:  aws.String(l.Name),
+			Value: aws.String(l.Value),
+		})
+	}
+
+	unit := cloudwatch.StandardUnitNone
+	switch s.Kind {
+	case KindCount:
+		unit = cloudwatch.StandardUnitCount
+	case KindSeconds:
+		unit = cloudwatch.StandardUnitSeconds
+	}
+
+	return &cloudwatch.MetricDatum{
+		MetricName: aws.String(s.Name),
+		Dimensions: dims,
+		Value:      aws.Float64(s.Value),
+		Unit:       aws.String(unit),
+		Timestamp:  aws.Time(now),
+	}
+}
+
+// Close 实现 Exporter
+func (e *cloudWatchExporter) Close() error {
+	return nil
+}
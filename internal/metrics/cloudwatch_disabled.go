@@ -0,0 +1,14 @@
+//go:build noaws
+
+package metrics
+
+import (
+	"fmt"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// newCloudWatchExporter 以 noaws 构建标签编译时不包含 CloudWatch 支持
+func newCloudWatchExporter(cfg config.CloudWatchConfig, awsCfg config.AWSConfig) (Exporter, error) {
+	return nil, fmt.Errorf("当前构建未包含 CloudWatch 支持（noaws），请使用 %s 推送", ExportStatsD)
+}
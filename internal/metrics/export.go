@@ -0,0 +1,311 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// ExportStatsD 推送到 StatsD
+	ExportStatsD = "statsd"
+	// ExportCloudWatch 推送到 CloudWatch
+	ExportCloudWatch = "cloudwatch"
+)
+
+// DefaultExportMetrics 默认推送的关键指标（不含 microservice_ 前缀）
+var DefaultExportMetrics = []string{
+	"http_request_duration_seconds",
+	"grpc_request_duration_seconds",
+	"mq_published_total",
+	"mq_consumed_total",
+	"mq_queue_depth",
+	"cron_job_duration_seconds",
+	"cron_job_runs_total",
+	"component_up",
+	"slo_burn_rate",
+	"slo_error_budget_remaining",
+}
+
+// SampleKind 推送值的类型
+type SampleKind int
+
+const (
+	// KindCount 本周期内的增量
+	KindCount SampleKind = iota
+	// KindGauge 当前值
+	KindGauge
+	// KindSeconds 本周期内的平均耗时（秒）
+	KindSeconds
+)
+
+// Label 指标标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample 一次推送中的单个指标值
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+	Kind   SampleKind
+}
+
+// Exporter 指标推送目标
+type Exporter interface {
+	// Export 推送一批指标
+	Export(ctx context.Context, samples []Sample) error
+	// Close 释放连接
+	Close() error
+}
+
+// Pusher 定期从 Registry 采集关键指标并推送到外部监控
+// Counter 与 Histogram 按两次推送之间的增量上报，便于在 CloudWatch / StatsD 中直接计算速率
+type Pusher struct {
+	gatherer prometheus.Gatherer
+	exporter Exporter
+	interval time.Duration
+	include  map[string]bool
+	// last 上一次推送时的累计值，用于计算增量
+	last map[string]float64
+}
+
+// NewPusher 根据配置创建指标推送器
+// 参数:
+//
+//	cfg: 推送配置
+//	awsCfg: AWS 配置（CloudWatch 使用）
+//
+// 返回:
+//
+//	*Pusher: 推送器，未配置推送目标时为 nil
+//	error: 错误信息
+func NewPusher(cfg config.MetricsExportConfig, awsCfg config.AWSConfig) (*Pusher, error) {
+	var (
+		exporter Exporter
+		err      error
+	)
+
+	switch cfg.Driver {
+	case "":
+		return nil, nil
+	case ExportStatsD:
+		exporter, err = newStatsDExporter(cfg.StatsD)
+	case ExportCloudWatch:
+		exporter, err = newCloudWatchExporter(cfg.CloudWatch, awsCfg)
+	default:
+		return nil, fmt.Errorf("不支持的指标推送目标: %s", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPusher(Registry, exporter, cfg.Include)
+	p.interval = cfg.GetInterval()
+
+	logger.Info("指标推送已启用",
+		zap.String("目标", cfg.Driver),
+		zap.Duration("间隔", p.interval),
+	)
+	return p, nil
+}
+
+// StartPusher 按配置在后台启动指标推送，未配置推送目标时不启动
+// 参数:
+//
+//	ctx: 上下文，取消后推送最后一次增量并停止
+//	cfg: 推送配置
+//	awsCfg: AWS 配置（CloudWatch 使用）
+//
+// 返回:
+//
+//	func(): 等待最后一次推送完成，应在取消 ctx 后调用
+//	error: 错误信息
+func StartPusher(ctx context.Context, cfg config.MetricsExportConfig, awsCfg config.AWSConfig) (func(), error) {
+	p, err := NewPusher(cfg, awsCfg)
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	if p == nil {
+		close(done)
+	} else {
+		go func() {
+			p.Run(ctx)
+			close(done)
+		}()
+	}
+	return func() { <-done }, nil
+}
+
+// newPusher 创建推送器
+func newPusher(gatherer prometheus.Gatherer, exporter Exporter, include []string) *Pusher {
+	if len(include) == 0 {
+		include = DefaultExportMetrics
+	}
+	p := &Pusher{
+		gatherer: gatherer,
+		exporter: exporter,
+		interval: time.Minute,
+		include:  make(map[string]bool, len(include)),
+		last:     make(map[string]float64),
+	}
+	for _, name := range include {
+		p.include[name] = true
+	}
+	return p
+}
+
+// Run 按间隔推送，ctx 取消时再推送一次剩余的增量并关闭连接
+// 参数:
+//
+//	ctx: 上下文
+func (p *Pusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	defer p.exporter.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			p.push(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			p.push(ctx)
+		}
+	}
+}
+
+// push 采集并推送一次
+func (p *Pusher) push(ctx context.Context) {
+	samples, err := p.collect()
+	if err != nil {
+		logger.Error("采集指标失败", zap.Error(err))
+		return
+	}
+	if len(samples) == 0 {
+		return
+	}
+	if err := p.exporter.Export(ctx, samples); err != nil {
+		logger.Error("推送指标失败", zap.Int("数量", len(samples)), zap.Error(err))
+	}
+}
+
+// collect 采集配置的指标并转换为推送值
+// Counter 推送增量；Histogram 推送次数增量（<name>_count）与平均耗时（<name>_avg）；Gauge 推送当前值。
+// HTTP 请求另外汇总出 http_requests 与 http_errors（5xx），便于直接配置错误率告警
+func (p *Pusher) collect() ([]Sample, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []Sample
+	for _, mf := range families {
+		name := strings.TrimPrefix(mf.GetName(), namespace+"_")
+		if !p.include[name] {
+			continue
+		}
+
+		var requests, errors float64
+		for _, m := range mf.GetMetric() {
+			labels := toLabels(m.GetLabel())
+			key := seriesKey(name, labels)
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				samples = append(samples, Sample{
+					Name: name, Labels: labels, Kind: KindCount,
+					Value: p.delta(key, m.GetCounter().GetValue()),
+				})
+			case dto.MetricType_GAUGE:
+				samples = append(samples, Sample{
+					Name: name, Labels: labels, Kind: KindGauge,
+					Value: m.GetGauge().GetValue(),
+				})
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				count := p.delta(key+"#count", float64(h.GetSampleCount()))
+				sum := p.delta(key+"#sum", h.GetSampleSum())
+				samples = append(samples, Sample{
+					Name: strings.TrimSuffix(name, "_seconds") + "_count", Labels: labels, Kind: KindCount,
+					Value: count,
+				})
+				if count > 0 {
+					samples = append(samples, Sample{
+						Name: strings.TrimSuffix(name, "_seconds") + "_avg", Labels: labels, Kind: KindSeconds,
+						Value: sum / count,
+					})
+				}
+				if name == "http_request_duration_seconds" {
+					requests += count
+					if isServerError(labels) {
+						errors += count
+					}
+				}
+			}
+		}
+
+		if name == "http_request_duration_seconds" {
+			samples = append(samples,
+				Sample{Name: "http_requests", Kind: KindCount, Value: requests},
+				Sample{Name: "http_errors", Kind: KindCount, Value: errors},
+			)
+		}
+	}
+	return samples, nil
+}
+
+// delta 计算累计值相对上一次推送的增量（进程重启或计数器重置时取当前值）
+func (p *Pusher) delta(key string, value float64) float64 {
+	prev, ok := p.last[key]
+	p.last[key] = value
+	if !ok || value < prev {
+		return value
+	}
+	return value - prev
+}
+
+// toLabels 转换并按名称排序标签
+func toLabels(pairs []*dto.LabelPair) []Label {
+	labels := make([]Label, 0, len(pairs))
+	for _, lp := range pairs {
+		labels = append(labels, Label{Name: lp.GetName(), Value: lp.GetValue()})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+// seriesKey 时间序列的唯一键
+func seriesKey(name string, labels []Label) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteString("|" + l.Name + "=" + l.Value)
+	}
+	return b.String()
+}
+
+// isServerError 标签中的状态码是否为 5xx
+func isServerError(labels []Label) bool {
+	for _, l := range labels {
+		if l.Name == "status" {
+			code, err := strconv.Atoi(l.Value)
+			return err == nil && code >= 500
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPusherCollectDeltas(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	requests := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+	}, []string{"route", "status"})
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mq_queue_depth",
+	}, []string{"queue"})
+	ignored := prometheus.NewCounter(prometheus.CounterOpts{Namespace: namespace, Name: "ignored_total"})
+	reg.MustRegister(requests, depth, ignored)
+
+	p := newPusher(reg, nil, nil)
+
+	requests.WithLabelValues("/users", "200").Observe(0.2)
+	requests.WithLabelValues("/users", "200").Observe(0.4)
+	requests.WithLabelValues("/users", "500").Observe(1)
+	depth.WithLabelValues("orders").Set(7)
+	ignored.Inc()
+
+	got := index(t, p)
+	want := map[string]float64{
+		"http_request_duration_count|route=/users|status=200": 2,
+		"http_request_duration_avg|route=/users|status=200":   0.3,
+		"http_request_duration_count|route=/users|status=500": 1,
+		"http_request_duration_avg|route=/users|status=500":   1,
+		"http_requests":               3,
+		"http_errors":                 1,
+		"mq_queue_depth|queue=orders": 7,
+	}
+	assertSamples(t, got, want)
+
+	// 第二次推送只包含增量，无新请求时不推送平均耗时
+	requests.WithLabelValues("/users", "200").Observe(0.1)
+	depth.WithLabelValues("orders").Set(2)
+
+	got = index(t, p)
+	want = map[string]float64{
+		"http_request_duration_count|route=/users|status=200": 1,
+		"http_request_duration_avg|route=/users|status=200":   0.1,
+		"http_request_duration_count|route=/users|status=500": 0,
+		"http_requests":               1,
+		"http_errors":                 0,
+		"mq_queue_depth|queue=orders": 2,
+	}
+	assertSamples(t, got, want)
+}
+
+func TestStatsDFormat(t *testing.T) {
+	sample := Sample{
+		Name:   "http_request_duration_avg",
+		Labels: []Label{{Name: "route", Value: "/users/:id"}, {Name: "status", Value: "200"}},
+		Value:  0.25,
+		Kind:   KindSeconds,
+	}
+
+	plain := (&statsdExporter{prefix: "svc"}).format(sample)
+	if want := "svc.http_request_duration_avg._users_id.200:250|ms"; plain != want {
+		t.Errorf("format = %q, 期望 %q", plain, want)
+	}
+
+	tagged := (&statsdExporter{prefix: "svc", tags: true}).format(sample)
+	if want := "svc.http_request_duration_avg:250|ms|#route:/users/:id,status:200"; tagged != want {
+		t.Errorf("format = %q, 期望 %q", tagged, want)
+	}
+
+	count := (&statsdExporter{}).format(Sample{Name: "http_errors", Value: 3, Kind: KindCount})
+	if want := "http_errors:3|c"; count != want {
+		t.Errorf("format = %q, 期望 %q", count, want)
+	}
+}
+
+// index 采集一次并按序列键索引
+func index(t *testing.T, p *Pusher) map[string]float64 {
+	t.Helper()
+	samples, err := p.collect()
+	if err != nil {
+		t.Fatalf("采集失败: %v", err)
+	}
+	m := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		m[seriesKey(s.Name, s.Labels)] = s.Value
+	}
+	return m
+}
+
+// assertSamples 比较采集结果
+func assertSamples(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("采集结果 = %v, 期望 %v", got, want)
+	}
+	for key, value := range want {
+		if v, ok := got[key]; !ok || v < value-1e-9 || v > value+1e-9 {
+			t.Errorf("%s = %v, 期望 %v", key, got[key], value)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinMiddleware HTTP 请求耗时中间件
+// 未匹配路由的请求统一记为 unmatched；已匹配路由的请求同时通知 AddRequestObserver 注册的回调
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		elapsed := time.Since(start)
+		status := c.Writer.Status()
+
+		route := c.FullPath()
+		if route == "" {
+			HTTPRequestDuration.
+				WithLabelValues(c.Request.Method, "unmatched", strconv.Itoa(status)).
+				Observe(elapsed.Seconds())
+			return
+		}
+		HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).
+			Observe(elapsed.Seconds())
+
+		notifyObservers(Request{
+			Kind:        KindHTTP,
+			Target:      c.Request.Method + " " + route,
+			ServerError: status >= 500,
+			Duration:    elapsed,
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// startTimeKey 记录查询开始时间的实例键
+const startTimeKey = "metrics:start_time"
+
+// RegisterGormCallbacks 注册 GORM 回调，统计各类数据库操作的次数、耗时和慢查询次数
+// 参数:
+//
+//	db: GORM 实例
+//	slowThreshold: 慢查询阈值，耗时不低于该值的操作计入 DBSlowQueries
+//
+// 返回:
+//
+//	error: 错误信息
+func RegisterGormCallbacks(db *gorm.DB, slowThreshold time.Duration) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(startTimeKey, time.Now())
+	}
+	after := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			v, ok := tx.InstanceGet(startTimeKey)
+			if !ok {
+				return
+			}
+			start, ok := v.(time.Time)
+			if !ok {
+				return
+			}
+			elapsed := time.Since(start)
+			table := tx.Statement.Table
+			DBQueryDuration.WithLabelValues(operation, table).Observe(elapsed.Seconds())
+			DBQueries.WithLabelValues(operation, table, queryResult(tx.Error)).Inc()
+			if elapsed >= slowThreshold {
+				DBSlowQueries.WithLabelValues(operation, table).Inc()
+			}
+		}
+	}
+
+	cb := db.Callback()
+	registrations := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, r := range registrations {
+		if err := r.before("metrics:before_"+r.operation, before); err != nil {
+			return err
+		}
+		if err := r.after("metrics:after_"+r.operation, after(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryResult 数据库操作结果标签：ok 或 error，记录不存在视为 ok
+func queryResult(err error) string {
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		return "ok"
+	}
+	return "error"
+}
+
+// RegisterDBStats 注册数据库连接池指标（go_sql_in_use_connections、go_sql_max_open_connections、go_sql_wait_count_total 等）
+// 参数:
+//
+//	db: 底层数据库连接
+//	name: 数据库名称，作为 db_name 标签
+func RegisterDBStats(db *sql.DB, name string) {
+	Register(collectors.NewDBStatsCollector(db, name))
+}
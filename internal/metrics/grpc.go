@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor gRPC 一元方法耗时拦截器
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGRPC(info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor gRPC 流式方法耗时拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPC(info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+// observeGRPC 记录方法耗时并通知请求回调
+func observeGRPC(method string, err error, elapsed time.Duration) {
+	code := status.Code(err)
+	GRPCRequestDuration.WithLabelValues(method, code.String()).Observe(elapsed.Seconds())
+	notifyObservers(Request{
+		Kind:        KindGRPC,
+		Target:      method,
+		ServerError: isGRPCServerError(code),
+		Duration:    elapsed,
+	})
+}
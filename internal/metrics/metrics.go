@@ -0,0 +1,277 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// namespace 指标名前缀
+const namespace = "microservice"
+
+// Registry 指标注册表，包含 Go 运行时与进程指标
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestDuration HTTP 请求耗时（按路由模板统计，避免路径参数导致标签爆炸）
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP 请求耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// GRPCRequestDuration gRPC 方法耗时
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC 方法耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+
+	// DBQueryDuration 数据库查询耗时
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "数据库查询耗时",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// DBQueries 数据库操作次数（记录不存在不计为错误）
+	DBQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_queries_total",
+		Help:      "数据库操作次数",
+	}, []string{"operation", "table", "result"})
+
+	// DBSlowQueries 超过慢查询阈值（database.slow_query_threshold）的数据库操作次数
+	DBSlowQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_slow_queries_total",
+		Help:      "数据库慢查询次数",
+	}, []string{"operation", "table"})
+
+	// CacheRequests Redis 读缓存命中/未命中次数
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Redis 读缓存次数",
+	}, []string{"result"})
+
+	// CacheClassRequests 按类别统计的读缓存结果（cache.Refresher.Fetch）：
+	// hit 命中、miss 未命中、shared 未命中但与并发请求共享了同一次加载（同时计入 miss）、bypass Redis 不可用直接回源
+	CacheClassRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_class_requests_total",
+		Help:      "按类别统计的读缓存次数",
+	}, []string{"class", "result"})
+
+	// MQPublished 消息发布次数
+	MQPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_published_total",
+		Help:      "消息发布次数",
+	}, []string{"routing_key", "result"})
+
+	// MQConsumed 消息消费次数
+	MQConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mq_consumed_total",
+		Help:      "消息消费次数",
+	}, []string{"queue", "result"})
+
+	// CronJobDuration 定时任务执行耗时
+	CronJobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cron_job_duration_seconds",
+		Help:      "定时任务执行耗时",
+		Buckets:   []float64{.1, .5, 1, 5, 15, 30, 60, 120, 300},
+	}, []string{"job"})
+
+	// CronJobRuns 定时任务执行次数
+	CronJobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cron_job_runs_total",
+		Help:      "定时任务执行次数",
+	}, []string{"job", "result"})
+
+	// CronJobConsecutiveFailures 定时任务连续失败次数
+	CronJobConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cron_job_consecutive_failures",
+		Help:      "定时任务连续失败次数",
+	}, []string{"job"})
+
+	// DeprecatedCalls 已弃用接口的调用次数（按调用方统计，便于在下线前找到仍在使用的客户端）
+	DeprecatedCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deprecated_calls_total",
+		Help:      "已弃用接口的调用次数",
+	}, []string{"endpoint", "client"})
+
+	// BreakerState 外部依赖熔断器状态（0 关闭，1 半开，2 打开）
+	BreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "外部依赖熔断器状态（0 关闭，1 半开，2 打开）",
+	}, []string{"dependency"})
+
+	// BreakerTransitions 熔断器状态变化次数
+	BreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "熔断器状态变化次数",
+	}, []string{"dependency", "state"})
+
+	// BreakerRejected 熔断期间被直接拒绝的调用次数
+	BreakerRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_rejected_total",
+		Help:      "熔断期间被直接拒绝的调用次数",
+	}, []string{"dependency"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		GRPCRequestDuration,
+		DBQueryDuration,
+		DBQueries,
+		DBSlowQueries,
+		CacheRequests,
+		CacheClassRequests,
+		MQPublished,
+		MQConsumed,
+		CronJobDuration,
+		CronJobRuns,
+		CronJobConsecutiveFailures,
+		DeprecatedCalls,
+		BreakerState,
+		BreakerTransitions,
+		BreakerRejected,
+	)
+}
+
+// Handler 返回 /metrics 处理器
+// 返回:
+//
+//	http.Handler: Prometheus 指标处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve 在独立端口上暴露 Prometheus 指标（没有 HTTP 服务的 gRPC、定时任务服务使用），阻塞直到服务退出
+// 参数:
+//
+//	port: 指标端口
+//	path: 指标路径，为空时使用 /metrics
+func Serve(port int, path string) {
+	if path == "" {
+		path = "/metrics"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, Handler())
+
+	addr := fmt.Sprintf(":%d", port)
+	logger.Info("指标服务启动成功", zap.String("地址", addr))
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logger.Error("指标服务异常退出", zap.Error(err))
+	}
+}
+
+// RegisterHealthCheck 注册依赖的健康状态指标 microservice_component_up（1 健康，0 异常）
+// 每次采集（Prometheus 抓取或推送）时执行一次检查
+// 参数:
+//
+//	component: 依赖名称（如 database、redis）
+//	check: 健康检查函数
+func RegisterHealthCheck(component string, check func() error) {
+	Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "component_up",
+		Help:        "依赖健康状态（1 健康，0 异常）",
+		ConstLabels: prometheus.Labels{"component": component},
+	}, func() float64 {
+		if check() != nil {
+			return 0
+		}
+		return 1
+	}))
+}
+
+// Register 向 Registry 注册采集器，重复注册时忽略，其他错误只记录日志
+// 参数:
+//
+//	c: 采集器
+func Register(c prometheus.Collector) {
+	if err := Registry.Register(c); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return
+		}
+		logger.Warn("注册指标失败", zap.Error(err))
+	}
+}
+
+// result 将错误转换为结果标签
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
+
+// ObservePublish 记录消息发布结果
+// 参数:
+//
+//	routingKey: 路由键
+//	err: 发布错误
+func ObservePublish(routingKey string, err error) {
+	MQPublished.WithLabelValues(routingKey, result(err)).Inc()
+}
+
+// ObserveConsume 记录消息消费结果
+// 参数:
+//
+//	queue: 队列名称
+//	outcome: 结果（success、error、timeout 等）
+func ObserveConsume(queue, outcome string) {
+	MQConsumed.WithLabelValues(queue, outcome).Inc()
+}
+
+// ObserveCronJob 记录定时任务的执行耗时和结果
+// 参数:
+//
+//	job: 任务名称
+//	duration: 执行耗时
+//	err: 执行错误
+func ObserveCronJob(job string, duration time.Duration, err error) {
+	CronJobDuration.WithLabelValues(job).Observe(duration.Seconds())
+	CronJobRuns.WithLabelValues(job, result(err)).Inc()
+}
+
+// SetCronJobFailures 记录定时任务当前的连续失败次数
+// 参数:
+//
+//	job: 任务名称
+//	failures: 连续失败次数
+func SetCronJobFailures(job string, failures int64) {
+	CronJobConsecutiveFailures.WithLabelValues(job).Set(float64(failures))
+}
+
+// ObserveDeprecatedCall 记录一次对已弃用接口的调用
+// 参数:
+//
+//	endpoint: 接口名称（HTTP 方法 + 路由模板，或 gRPC 完整方法名）
+//	client: 调用方
+func ObserveDeprecatedCall(endpoint, client string) {
+	DeprecatedCalls.WithLabelValues(endpoint, client).Inc()
+}
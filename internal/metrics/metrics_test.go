@@ -0,0 +1,56 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestGinMiddlewareUsesRouteTemplate(t *testing.T) {
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "metrics"},
+	}}, func(r *gin.RouterGroup) {
+		r.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	})
+
+	for _, id := range []string{"1", "2", "3"} {
+		testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/items/"+id, nil))
+	}
+	testutil.Do(router, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	w := testutil.Do(metrics.Handler(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	if !strings.Contains(body, `route="/api/v1/items/:id",status="200"} 3`) {
+		t.Errorf("未按路由模板统计请求:\n%s", grep(body, "http_request_duration_seconds_count"))
+	}
+	if !strings.Contains(body, `route="unmatched",status="404"} 1`) {
+		t.Errorf("未匹配路由应记为 unmatched:\n%s", grep(body, "http_request_duration_seconds_count"))
+	}
+}
+
+func TestObserveConsume(t *testing.T) {
+	before := promtest.ToFloat64(metrics.MQConsumed.WithLabelValues("orders", "timeout"))
+	metrics.ObserveConsume("orders", "timeout")
+	if got := promtest.ToFloat64(metrics.MQConsumed.WithLabelValues("orders", "timeout")); got != before+1 {
+		t.Errorf("消费计数 = %v, 期望 %v", got, before+1)
+	}
+}
+
+// grep 返回包含指定子串的行，便于输出失败信息
+func grep(body, substr string) string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(line, substr) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
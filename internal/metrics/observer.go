@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// 请求类型
+const (
+	// KindHTTP HTTP 请求，Target 为 "方法 路由模板"
+	KindHTTP = "http"
+	// KindGRPC gRPC 请求，Target 为完整方法名
+	KindGRPC = "grpc"
+)
+
+// Request 已完成的请求，供 SLO 等统计使用
+type Request struct {
+	Kind   string
+	Target string
+	// ServerError 是否为服务端错误（HTTP 5xx，gRPC Internal/Unavailable 等）
+	ServerError bool
+	Duration    time.Duration
+}
+
+var (
+	observersMu sync.RWMutex
+	observers   []func(Request)
+)
+
+// AddRequestObserver 注册请求完成回调，在 HTTP 中间件和 gRPC 拦截器记录耗时后调用
+// 回调在请求处理的 goroutine 中同步执行，应尽快返回
+// 参数:
+//
+//	fn: 回调函数
+func AddRequestObserver(fn func(Request)) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+	observers = append(observers, fn)
+}
+
+// notifyObservers 通知所有回调
+func notifyObservers(r Request) {
+	observersMu.RLock()
+	defer observersMu.RUnlock()
+	for _, fn := range observers {
+		fn(r)
+	}
+}
+
+// isGRPCServerError gRPC 状态码是否属于服务端错误
+func isGRPCServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook 统计读命令（GET、HGET）命中情况的 go-redis 钩子
+type RedisHook struct{}
+
+// DialHook 不做处理
+func (RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook 记录单条读命令的命中/未命中
+func (RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		observeCacheCmd(cmd)
+		return err
+	}
+}
+
+// ProcessPipelineHook 记录管道中读命令的命中/未命中
+func (RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			observeCacheCmd(cmd)
+		}
+		return err
+	}
+}
+
+// observeCacheCmd 按命令结果记录命中情况，其他命令忽略
+func observeCacheCmd(cmd redis.Cmder) {
+	switch cmd.Name() {
+	case "get", "hget":
+	default:
+		return
+	}
+
+	err := cmd.Err()
+	switch {
+	case err == nil:
+		CacheRequests.WithLabelValues("hit").Inc()
+	case errors.Is(err, redis.Nil):
+		CacheRequests.WithLabelValues("miss").Inc()
+	default:
+		CacheRequests.WithLabelValues("error").Inc()
+	}
+}
+
+// RegisterRedisPool 注册 Redis 连接池指标：
+// microservice_redis_pool_connections（state: total、idle）和 microservice_redis_pool_timeouts_total（等待空闲连接超时次数）
+// 参数:
+//
+//	client: Redis 客户端
+func RegisterRedisPool(client *redis.Client) {
+	for _, state := range []string{"total", "idle"} {
+		state := state
+		Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        "redis_pool_connections",
+			Help:        "Redis 连接池连接数",
+			ConstLabels: prometheus.Labels{"state": state},
+		}, func() float64 {
+			stats := client.PoolStats()
+			if state == "idle" {
+				return float64(stats.IdleConns)
+			}
+			return float64(stats.TotalConns)
+		}))
+	}
+	Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redis_pool_timeouts_total",
+		Help:      "等待 Redis 空闲连接超时次数",
+	}, func() float64 {
+		return float64(client.PoolStats().Timeouts)
+	}))
+}
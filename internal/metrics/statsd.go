@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// statsdMaxPacket 单个 UDP 包的最大字节数（避免超过常见 MTU 被分片丢弃）
+const statsdMaxPacket = 1400
+
+// statsdInvalidChars StatsD 指标名中需要替换的字符
+var statsdInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]+`)
+
+// statsdExporter 通过 UDP 推送 StatsD 行协议
+type statsdExporter struct {
+	conn   net.Conn
+	prefix string
+	tags   bool
+}
+
+// newStatsDExporter 创建 StatsD 推送器
+func newStatsDExporter(cfg config.StatsDConfig) (*statsdExporter, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("未配置 StatsD 地址")
+	}
+	conn, err := net.Dial("udp", cfg.Address)
+	if err != nil {
+		return nil, fmt.Errorf("连接 StatsD 失败: %w", err)
+	}
+	return &statsdExporter{conn: conn, prefix: cfg.Prefix, tags: cfg.Tags}, nil
+}
+
+// Export 实现 Exporter，多行合并到一个 UDP 包中发送
+func (e *statsdExporter) Export(_ context.Context, samples []Sample) error {
+	var packet bytes.Buffer
+	for _, s := range samples {
+		line := e.format(s)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+			if _, err := e.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		_, err := e.conn.Write(packet.Bytes())
+		return err
+	}
+	return nil
+}
+
+// format 生成单行 StatsD 数据
+// 增量为计数（|c），当前值为 gauge（|g），平均耗时以毫秒计时（|ms）；
+// 未启用标签时，标签值按名称顺序拼接到指标名中
+func (e *statsdExporter) format(s Sample) string {
+	name := s.Name
+	if e.prefix != "" {
+		name = e.prefix + "." + name
+	}
+	if !e.tags {
+		for _, l := range s.Labels {
+			name += "." + statsdInvalidChars.ReplaceAllString(l.Value, "_")
+		}
+	}
+
+	value, kind := s.Value, "g"
+	switch s.Kind {
+	case KindCount:
+		kind = "c"
+	case KindSeconds:
+		value, kind = s.Value*1000, "ms"
+	}
+
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|" + kind
+	if e.tags && len(s.Labels) > 0 {
+		line += "|#"
+		for i, l := range s.Labels {
+			if i > 0 {
+				line += ","
+			}
+			line += l.Name + ":" + l.Value
+		}
+	}
+	return line
+}
+
+// Close 实现 Exporter
+func (e *statsdExporter) Close() error {
+	return e.conn.Close()
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/activity"
+)
+
+// TrackActivity 用户活跃度追踪中间件
+// 在请求处理完成后读取认证信息，因此可放在 auth 中间件之前；
+// 只写入内存，由 activity.Tracker 定期批量落库
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func TrackActivity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if userID, ok := GetUserID(c); ok {
+			activity.DefaultTracker.Touch(userID)
+		}
+	}
+}
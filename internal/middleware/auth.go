@@ -1,33 +1,238 @@
 package middleware
 
 import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
 	"github.com/zhang/microservice/internal/logger"
 	"go.uber.org/zap"
 )
 
+// 令牌类型
+const (
+	// TokenTypeAccess 访问令牌，用于请求认证
+	TokenTypeAccess = "access"
+	// TokenTypeRefresh 刷新令牌，只能用于换取新的令牌对
+	TokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrTokenRevoked 令牌已被吊销
+	ErrTokenRevoked = errors.New("令牌已被吊销")
+	// ErrTokenType 令牌类型不符（如用刷新令牌访问接口）
+	ErrTokenType = errors.New("令牌类型错误")
+	// ErrRefreshTokenReused 刷新令牌被重复使用，可能已泄露，该用户的全部令牌已被吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌已被使用")
+	// ErrUserInactive 令牌对应的用户不存在或已被删除
+	ErrUserInactive = errors.New("用户不存在或已删除")
+	// ErrNoSigningKey 非对称算法未配置私钥，不能签发令牌
+	ErrNoSigningKey = errors.New("未配置 JWT 签名私钥")
+	// ErrRevocationUnavailable Redis 不可用，按 redis.degradation.revocation=closed 拒绝认证
+	ErrRevocationUnavailable = errors.New("无法检查令牌吊销状态")
+)
+
 // Claims JWT 声明
 type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	// TokenType 令牌类型，为空的旧令牌视为访问令牌
+	TokenType string `json:"token_type,omitempty"`
+	// PreferredUsername 外部身份提供方（OIDC）的用户名声明，只在解析时使用
+	PreferredUsername string `json:"preferred_username,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// identity 声明对应的调用方身份
+func (c *Claims) identity() ctxkeys.Identity {
+	return ctxkeys.Identity{UserID: c.UserID, Username: c.Username, Role: c.Role}
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn 访问令牌有效期（秒）
+	ExpiresIn int64 `json:"expires_in"`
+	// RefreshExpiresIn 刷新令牌有效期（秒）
+	RefreshExpiresIn int64 `json:"refresh_expires_in"`
+}
+
+// revocationWarned 上次提示吊销检查失败的时间（Unix 秒），避免每个请求都打印日志
+var revocationWarned atomic.Int64
+
 // JWTConfig JWT 配置
 type JWTConfig struct {
 	Secret     []byte
 	ExpireTime time.Duration
+	// Issuer 签发者，非空时校验 token 的 iss 声明
+	Issuer string
+	// RefreshExpireTime 刷新令牌有效期，为 0 时使用 ExpireTime
+	RefreshExpireTime time.Duration
+	// Method 签名算法，为空时使用 HS256
+	Method jwt.SigningMethod
+	// PrivateKey RS/ES 算法签发令牌的私钥，为空时不能签发（只校验外部身份提供方签发的令牌）
+	PrivateKey crypto.Signer
+	// PublicKey RS/ES 算法校验令牌的公钥，令牌头不带 kid 或未配置 JWKS 时使用
+	PublicKey crypto.PublicKey
+	// JWKS 远程公钥集，令牌头带 kid 时按 kid 查找公钥
+	JWKS *JWKS
+	// Now 时间来源，为空时使用 time.Now（测试中可注入固定时钟）
+	Now func() time.Time
+}
+
+// now 返回当前时间
+func (c *JWTConfig) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// method 返回签名算法
+func (c *JWTConfig) method() jwt.SigningMethod {
+	if c.Method != nil {
+		return c.Method
+	}
+	return jwt.SigningMethodHS256
+}
+
+// asymmetric 是否为非对称签名算法
+func (c *JWTConfig) asymmetric() bool {
+	_, ok := c.method().(*jwt.SigningMethodHMAC)
+	return !ok
+}
+
+// signingKey 返回签发令牌的密钥
+func (c *JWTConfig) signingKey() (interface{}, error) {
+	if !c.asymmetric() {
+		return c.Secret, nil
+	}
+	if c.PrivateKey == nil {
+		return nil, ErrNoSigningKey
+	}
+	return c.PrivateKey, nil
+}
+
+// verifyKey 返回校验令牌的密钥：共享密钥；或按令牌头的 kid 从 JWKS 查找；或配置的公钥
+func (c *JWTConfig) verifyKey(token *jwt.Token) (interface{}, error) {
+	if !c.asymmetric() {
+		return c.Secret, nil
+	}
+	if kid, _ := token.Header["kid"].(string); kid != "" && c.JWKS != nil {
+		return c.JWKS.Key(kid)
+	}
+	if c.PublicKey != nil {
+		return c.PublicKey, nil
+	}
+	return nil, ErrUnknownKeyID
 }
 
-var defaultJWTConfig = &JWTConfig{
-	Secret:     []byte("your-secret-key-change-in-production"),
-	ExpireTime: 24 * time.Hour,
+// refreshExpire 返回刷新令牌有效期
+func (c *JWTConfig) refreshExpire() time.Duration {
+	if c.RefreshExpireTime > 0 {
+		return c.RefreshExpireTime
+	}
+	return c.ExpireTime
+}
+
+// issuer 返回签发 token 时使用的签发者
+func (c *JWTConfig) issuer() string {
+	if c.Issuer != "" {
+		return c.Issuer
+	}
+	return "microservice"
+}
+
+// defaultJWTConfig 未调用 SetJWTConfig 时使用占位密钥，仅供测试与本地开发
+var defaultJWTConfig = NewJWTConfig(config.JWTConfig{})
+
+// NewJWTConfig 根据配置文件中的 jwt 配置创建 JWT 配置
+// 参数:
+//
+//	cfg: jwt 配置（算法已在配置加载时校验）
+//
+// 返回:
+//
+//	*JWTConfig: JWT 配置
+func NewJWTConfig(cfg config.JWTConfig) *JWTConfig {
+	return &JWTConfig{
+		Secret:            cfg.GetSecret(),
+		ExpireTime:        cfg.GetAccessExpire(),
+		Issuer:            cfg.GetIssuer(),
+		RefreshExpireTime: cfg.GetRefreshExpire(),
+		Method:            jwt.GetSigningMethod(cfg.GetAlgorithm()),
+	}
+}
+
+// LoadJWTConfig 根据 jwt 配置创建 JWT 配置，并加载 RS/ES 算法的 PEM 密钥和远程公钥集
+// 公钥集首次拉取失败只记录日志，之后在校验令牌时重试
+// 参数:
+//
+//	cfg: jwt 配置
+//
+// 返回:
+//
+//	*JWTConfig: JWT 配置
+//	error: 密钥文件无法读取或解析时返回错误
+func LoadJWTConfig(cfg config.JWTConfig) (*JWTConfig, error) {
+	c := NewJWTConfig(cfg)
+	if !cfg.Asymmetric() {
+		return c, nil
+	}
+
+	isRSA := strings.HasPrefix(cfg.GetAlgorithm(), "RS")
+	if cfg.PrivateKeyFile != "" {
+		data, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 JWT 私钥失败: %w", err)
+		}
+		if isRSA {
+			c.PrivateKey, err = jwt.ParseRSAPrivateKeyFromPEM(data)
+		} else {
+			c.PrivateKey, err = jwt.ParseECPrivateKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 JWT 私钥失败: %w", err)
+		}
+		c.PublicKey = c.PrivateKey.Public()
+	}
+	if cfg.PublicKeyFile != "" {
+		data, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 JWT 公钥失败: %w", err)
+		}
+		if isRSA {
+			c.PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(data)
+		} else {
+			c.PublicKey, err = jwt.ParseECPublicKeyFromPEM(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 JWT 公钥失败: %w", err)
+		}
+	}
+	if cfg.JWKSURL != "" {
+		c.JWKS = NewJWKS(cfg.JWKSURL, cfg.GetJWKSRefresh())
+		ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+		defer cancel()
+		_ = c.JWKS.Refresh(ctx)
+	}
+	return c, nil
 }
 
 // SetJWTConfig 设置 JWT 配置
@@ -35,6 +240,11 @@ func SetJWTConfig(config *JWTConfig) {
 	defaultJWTConfig = config
 }
 
+// GetJWTConfig 获取当前 JWT 配置
+func GetJWTConfig() *JWTConfig {
+	return defaultJWTConfig
+}
+
 // JWTAuth JWT 认证中间件
 // 用途: 验证请求中的 JWT token，并将用户信息存入上下文
 // 返回:
@@ -72,15 +282,20 @@ func JWTAuth() gin.HandlerFunc {
 
 		// 解析 token
 		tokenString := parts[1]
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return defaultJWTConfig.Secret, nil
-		})
-
-		if err != nil || !token.Valid {
+		claims, err := authenticate(c.Request.Context(), tokenString)
+		if errors.Is(err, ErrRevocationUnavailable) {
+			c.Header("Retry-After", strconv.Itoa(int(cache.ProbeInterval()/time.Second)))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "认证服务暂时不可用",
+				"code":  "AUTH_UNAVAILABLE",
+			})
+			c.Abort()
+			return
+		}
+		if err != nil {
 			logger.Warn("认证令牌无效",
 				zap.Error(err),
-				zap.String("token", tokenString[:10]+"..."),
+				zap.String("token", maskToken(tokenString)),
 			)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "认证令牌无效或已过期",
@@ -91,9 +306,7 @@ func JWTAuth() gin.HandlerFunc {
 		}
 
 		// 将用户信息存入上下文
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		setIdentity(c, claims)
 
 		logger.Debug("用户认证成功",
 			zap.Int64("user_id", claims.UserID),
@@ -105,6 +318,12 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// setIdentity 把调用方身份存入上下文，之后的请求日志带上 user_id
+func setIdentity(c *gin.Context, claims *Claims) {
+	ctxkeys.SetIdentity(c, claims.identity())
+	logger.SetContext(c, logger.FromContext(c).With(zap.Int64("user_id", claims.UserID)))
+}
+
 // OptionalJWTAuth 可选的 JWT 认证
 // 用途: 如果提供了 token 则验证，未提供则继续处理
 // 返回:
@@ -120,15 +339,8 @@ func OptionalJWTAuth() gin.HandlerFunc {
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) == 2 && parts[0] == "Bearer" {
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
-				return defaultJWTConfig.Secret, nil
-			})
-
-			if err == nil && token.Valid {
-				c.Set("user_id", claims.UserID)
-				c.Set("username", claims.Username)
-				c.Set("role", claims.Role)
+			if claims, err := authenticate(c.Request.Context(), parts[1]); err == nil {
+				setIdentity(c, claims)
 			}
 		}
 
@@ -147,7 +359,7 @@ func OptionalJWTAuth() gin.HandlerFunc {
 //	gin.HandlerFunc: Gin 中间件函数
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
+		roleStr, exists := GetUserRole(c)
 		if !exists {
 			logger.Warn("未找到用户角色信息",
 				zap.String("path", c.Request.URL.Path),
@@ -160,7 +372,6 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			return
 		}
 
-		roleStr := userRole.(string)
 		for _, role := range roles {
 			if roleStr == role {
 				c.Next()
@@ -182,7 +393,7 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 }
 
 // GenerateToken 生成 JWT token
-// 用途: 为用户生成认证令牌
+// 用途: 为用户生成访问令牌
 // 参数:
 //
 //	userID: 用户ID
@@ -194,21 +405,164 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 //	string: JWT token
 //	error: 错误信息
 func GenerateToken(userID int64, username, role string) (string, error) {
-	now := time.Now()
+	return signToken(userID, username, role, TokenTypeAccess, defaultJWTConfig.ExpireTime)
+}
+
+// GenerateTokenPair 生成访问令牌与刷新令牌
+// 用途: 登录成功后签发，访问令牌过期后用刷新令牌换取新的令牌对（见 RefreshToken）
+// 参数:
+//
+//	userID: 用户ID
+//	username: 用户名
+//	role: 角色
+//
+// 返回:
+//
+//	*TokenPair: 令牌对
+//	error: 错误信息
+func GenerateTokenPair(userID int64, username, role string) (*TokenPair, error) {
+	access, err := signToken(userID, username, role, TokenTypeAccess, defaultJWTConfig.ExpireTime)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := signToken(userID, username, role, TokenTypeRefresh, defaultJWTConfig.refreshExpire())
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		ExpiresIn:        int64(defaultJWTConfig.ExpireTime / time.Second),
+		RefreshExpiresIn: int64(defaultJWTConfig.refreshExpire() / time.Second),
+	}, nil
+}
+
+// UserLoader 按 ID 查询用户当前的用户名和角色，刷新令牌时据此签发新令牌
+// 用户不存在（含已删除）时返回 ErrUserInactive
+type UserLoader func(ctx context.Context, userID int64) (username, role string, err error)
+
+// RefreshToken 刷新 token
+// 用途: 用刷新令牌换取新的令牌对，旧的刷新令牌随即吊销（轮换）；
+// 已吊销的刷新令牌再次出现说明可能已泄露，吊销该用户的全部令牌。
+// 新令牌的用户名和角色取自 load 查询到的当前值，而不是旧令牌中的声明
+// 参数:
+//
+//	ctx: 上下文
+//	refreshToken: 刷新令牌
+//	load: 用户查询函数
+//
+// 返回:
+//
+//	*TokenPair: 新的令牌对
+//	error: 令牌无效、类型错误、已吊销、被重复使用或用户已删除时返回错误
+func RefreshToken(ctx context.Context, refreshToken string, load UserLoader) (*TokenPair, error) {
+	claims, err := parseToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != TokenTypeRefresh || claims.ID == "" || claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, ErrTokenType
+	}
+
+	revoked, err := cache.TokenRevoked(ctx, "", claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	// 先查询用户再轮换，查询失败时刷新令牌仍可重试
+	username, role, err := load(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := cache.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Sub(defaultJWTConfig.now()))
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		logger.Warn("刷新令牌被重复使用，吊销该用户的全部令牌",
+			zap.Int64("user_id", claims.UserID),
+			zap.String("jti", claims.ID),
+		)
+		if err := cache.RevokeUserTokens(ctx, claims.UserID, defaultJWTConfig.now(), defaultJWTConfig.refreshExpire()); err != nil {
+			logger.Error("吊销用户令牌失败", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	return GenerateTokenPair(claims.UserID, username, role)
+}
+
+// RevokeToken 吊销令牌直到其原本的过期时间（退出登录时吊销访问令牌和刷新令牌）
+// 参数:
+//
+//	ctx: 上下文
+//	tokenString: 访问令牌或刷新令牌
+//
+// 返回:
+//
+//	error: 令牌无效时返回错误；已过期的令牌无需吊销
+func RevokeToken(ctx context.Context, tokenString string) error {
+	claims, err := parseToken(tokenString)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		// 不含 ID 的旧令牌无法单独吊销，只能整体吊销用户令牌
+		return ErrTokenType
+	}
+	_, err = cache.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Sub(defaultJWTConfig.now()))
+	return err
+}
+
+// RevokeUserTokens 吊销用户当前已签发的全部令牌（退出所有设备、令牌泄露时使用）
+// 参数:
+//
+//	ctx: 上下文
+//	userID: 用户 ID
+//
+// 返回:
+//
+//	error: 错误信息
+func RevokeUserTokens(ctx context.Context, userID int64) error {
+	ttl := max(defaultJWTConfig.ExpireTime, defaultJWTConfig.refreshExpire())
+	return cache.RevokeUserTokens(ctx, userID, defaultJWTConfig.now(), ttl)
+}
+
+// signToken 签发指定类型的令牌，每个令牌带有随机 ID（jti）以便单独吊销
+func signToken(userID int64, username, role, tokenType string, ttl time.Duration) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	now := defaultJWTConfig.now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		TokenType: tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(defaultJWTConfig.ExpireTime)),
+			ID:        hex.EncodeToString(b),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "microservice",
+			Issuer:    defaultJWTConfig.issuer(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(defaultJWTConfig.Secret)
+	key, err := defaultJWTConfig.signingKey()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(defaultJWTConfig.method(), claims)
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		logger.Error("生成token失败",
 			zap.Error(err),
@@ -220,28 +574,107 @@ func GenerateToken(userID int64, username, role string) (string, error) {
 	return tokenString, nil
 }
 
-// RefreshToken 刷新 token
-// 用途: 基于旧 token 生成新 token
+// authenticate 校验访问令牌：签名和有效期有效、不是刷新令牌、未被吊销
+// Redis 不可用时按 redis.degradation.revocation 处理：closed 返回 ErrRevocationUnavailable，
+// open 跳过吊销检查（访问令牌有效期较短）；未配置 Redis 时不检查
 // 参数:
 //
-//	oldToken: 旧的 JWT token
+//	ctx: 上下文
+//	tokenString: JWT token
 //
 // 返回:
 //
-//	string: 新的 JWT token
+//	*Claims: token 声明
 //	error: 错误信息
-func RefreshToken(oldToken string) (string, error) {
+func authenticate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" && claims.TokenType != TokenTypeAccess {
+		return nil, ErrTokenType
+	}
+
+	if cache.RedisClient == nil {
+		return claims, nil
+	}
+	if !cache.Available() {
+		return revocationDegraded(claims, cache.ErrDegraded)
+	}
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := cache.TokenRevoked(ctx, claims.ID, claims.UserID, issuedAt)
+	if err != nil {
+		return revocationDegraded(claims, err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// revocationDegraded 吊销状态无法检查时按降级策略拒绝或放行
+func revocationDegraded(claims *Claims, err error) (*Claims, error) {
+	closed := cache.RevocationPolicy() == cache.PolicyClosed
+	now := time.Now().Unix()
+	if last := revocationWarned.Load(); now-last >= 60 && revocationWarned.CompareAndSwap(last, now) {
+		if closed {
+			logger.Error("检查令牌吊销状态失败，拒绝认证", zap.Error(err))
+		} else {
+			logger.Warn("检查令牌吊销状态失败，跳过检查", zap.Error(err))
+		}
+	}
+	if closed {
+		return nil, fmt.Errorf("%w: %v", ErrRevocationUnavailable, err)
+	}
+	return claims, nil
+}
+
+// parseToken 解析并校验 token
+// 参数:
+//
+//	tokenString: JWT token
+//
+// 返回:
+//
+//	*Claims: token 声明
+//	error: token 无效或已过期时返回错误
+func parseToken(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithTimeFunc(defaultJWTConfig.now),
+		jwt.WithValidMethods([]string{defaultJWTConfig.method().Alg()}),
+	}
+	if defaultJWTConfig.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(defaultJWTConfig.Issuer))
+	}
+
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(oldToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return defaultJWTConfig.Secret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, defaultJWTConfig.verifyKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
 
-	if err != nil || !token.Valid {
-		return "", err
+	// 外部身份提供方签发的令牌没有 user_id、username 声明时，使用数字形式的 sub 和 preferred_username
+	if claims.UserID == 0 {
+		claims.UserID, _ = strconv.ParseInt(claims.Subject, 10, 64)
+	}
+	if claims.Username == "" {
+		claims.Username = claims.PreferredUsername
 	}
+	return claims, nil
+}
 
-	// 生成新 token
-	return GenerateToken(claims.UserID, claims.Username, claims.Role)
+// maskToken 截取 token 前缀用于日志，避免泄露完整 token
+func maskToken(tokenString string) string {
+	if len(tokenString) <= 10 {
+		return "***"
+	}
+	return tokenString[:10] + "..."
 }
 
 // GetUserID 从上下文获取用户ID
@@ -255,11 +688,8 @@ func RefreshToken(oldToken string) (string, error) {
 //	int64: 用户ID
 //	bool: 是否存在
 func GetUserID(c *gin.Context) (int64, bool) {
-	val, exists := c.Get("user_id")
-	if !exists {
-		return 0, false
-	}
-	return val.(int64), true
+	identity, ok := ctxkeys.IdentityFrom(c)
+	return identity.UserID, ok
 }
 
 // GetUsername 从上下文获取用户名
@@ -273,11 +703,8 @@ func GetUserID(c *gin.Context) (int64, bool) {
 //	string: 用户名
 //	bool: 是否存在
 func GetUsername(c *gin.Context) (string, bool) {
-	val, exists := c.Get("username")
-	if !exists {
-		return "", false
-	}
-	return val.(string), true
+	identity, ok := ctxkeys.IdentityFrom(c)
+	return identity.Username, ok
 }
 
 // GetUserRole 从上下文获取用户角色
@@ -291,9 +718,6 @@ func GetUsername(c *gin.Context) (string, bool) {
 //	string: 角色
 //	bool: 是否存在
 func GetUserRole(c *gin.Context) (string, bool) {
-	val, exists := c.Get("role")
-	if !exists {
-		return "", false
-	}
-	return val.(string), true
+	identity, ok := ctxkeys.IdentityFrom(c)
+	return identity.Role, ok
 }
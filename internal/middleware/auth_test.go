@@ -0,0 +1,217 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestJWTAuthWithClock(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+		"api":    {"auth"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/admin", middleware.RequireRole("admin"), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	})
+
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin", nil)
+		if token != "" {
+			req.Header.Set("Authorization", testutil.BearerHeader(token))
+		}
+		return testutil.Do(router, req).Code
+	}
+
+	adminToken := minter.MustMint(t, 1, "admin", time.Hour)
+	userToken := minter.MustMint(t, 2, "user", time.Hour)
+
+	tests := []struct {
+		name     string
+		token    string
+		advance  time.Duration
+		expected int
+	}{
+		{"管理员", adminToken, 0, http.StatusOK},
+		{"普通用户", userToken, 0, http.StatusForbidden},
+		{"未提供令牌", "", 0, http.StatusUnauthorized},
+		{"格式错误", "bad", 0, http.StatusUnauthorized},
+		{"令牌过期", adminToken, 2 * time.Hour, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			if code := request(tt.token); code != tt.expected {
+				t.Errorf("期望状态码 %d, 实际 %d", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestTokenPairTypes(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+		"api":    {"auth"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/ping", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	})
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+		req.Header.Set("Authorization", testutil.BearerHeader(token))
+		return testutil.Do(router, req).Code
+	}
+
+	pair, err := middleware.GenerateTokenPair(1, "alice", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair.AccessToken == pair.RefreshToken || pair.ExpiresIn != 3600 {
+		t.Errorf("令牌对 = %+v", pair)
+	}
+
+	if code := request(pair.AccessToken); code != http.StatusOK {
+		t.Errorf("访问令牌: 状态码 %d, 期望 200", code)
+	}
+	if code := request(pair.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("刷新令牌不能用于访问接口: 状态码 %d, 期望 401", code)
+	}
+
+	if _, err := middleware.RefreshToken(context.Background(), pair.AccessToken, loadUser); !errors.Is(err, middleware.ErrTokenType) {
+		t.Errorf("用访问令牌刷新: err = %v, 期望 ErrTokenType", err)
+	}
+}
+
+// loadUser 测试用的用户查询：用户 1 当前为 admin，其他用户不存在
+func loadUser(_ context.Context, userID int64) (string, string, error) {
+	if userID != 1 {
+		return "", "", middleware.ErrUserInactive
+	}
+	return "alice@example.com", "admin", nil
+}
+
+func TestRefreshToken(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	testutil.UseJWT(t, testutil.DefaultSecret, clock)
+	testutil.UseMiniredis(t)
+	ctx := context.Background()
+
+	// 角色取自当前的用户数据而不是旧令牌
+	pair, err := middleware.GenerateTokenPair(1, "alice", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := middleware.RefreshToken(ctx, pair.RefreshToken, loadUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims middleware.Claims
+	if _, _, err := jwt.NewParser().ParseUnverified(refreshed.AccessToken, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Role != "admin" || claims.Username != "alice@example.com" {
+		t.Errorf("新令牌声明 = %+v", claims)
+	}
+
+	// 已删除的用户不能刷新
+	pair, err = middleware.GenerateTokenPair(2, "bob", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := middleware.RefreshToken(ctx, pair.RefreshToken, loadUser); !errors.Is(err, middleware.ErrUserInactive) {
+		t.Errorf("已删除的用户: err = %v, 期望 ErrUserInactive", err)
+	}
+
+	// 缺少签发时间的刷新令牌
+	noIAT := middleware.Claims{UserID: 1, Role: "user", TokenType: middleware.TokenTypeRefresh}
+	noIAT.ID = "no-iat"
+	noIAT.ExpiresAt = jwt.NewNumericDate(clock.Now().Add(time.Hour))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, noIAT).SignedString([]byte(testutil.DefaultSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := middleware.RefreshToken(ctx, token, loadUser); !errors.Is(err, middleware.ErrTokenType) {
+		t.Errorf("缺少签发时间: err = %v, 期望 ErrTokenType", err)
+	}
+}
+
+func TestParseTokenRejectsOtherAlgorithm(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	claims := middleware.Claims{UserID: 1, Role: "user"}
+	claims.ExpiresAt = jwt.NewNumericDate(clock.Now().Add(time.Hour))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString([]byte(testutil.DefaultSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := middleware.RefreshToken(context.Background(), token, loadUser); err == nil {
+		t.Error("配置为 HS256 时应拒绝 HS512 签名的 token")
+	}
+}
+
+func TestJWTAuthRedisDegraded(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	// 没有服务监听的端口，Init 失败后 Redis 处于降级状态
+	degradation := config.RedisDegradationConfig{Revocation: cache.PolicyClosed, ProbeInterval: 60}
+	if err := cache.Init(config.RedisConfig{Host: "127.0.0.1", Port: 1, Degradation: degradation}); err == nil {
+		t.Fatal("连接不存在的 Redis 应返回错误")
+	}
+	t.Cleanup(func() {
+		_ = cache.Close()
+		cache.RedisClient = nil
+		cache.SetDegradationPolicies(config.RedisDegradationConfig{})
+	})
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+		"api":    {"auth"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/ping", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	})
+	token := minter.MustMint(t, 1, "user", time.Hour)
+	request := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+		req.Header.Set("Authorization", testutil.BearerHeader(token))
+		return testutil.Do(router, req)
+	}
+
+	// closed: 无法检查吊销状态时拒绝
+	w := request()
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("closed: 状态码 %d, Retry-After %q, 期望 503 和 60", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// open: 跳过吊销检查
+	degradation.Revocation = cache.PolicyOpen
+	cache.SetDegradationPolicies(degradation)
+	if w := request(); w.Code != http.StatusOK {
+		t.Errorf("open: 状态码 %d, 期望 200", w.Code)
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// BodyLimit 请求体大小限制中间件
+// 按路由（middleware.body_limit.routes，未配置时为 max_size）限制请求体大小：声明的 Content-Length
+// 超过上限时不读取请求体直接返回 413；未声明（分块传输）或声明不实时，读取超过上限后返回错误（*http.MaxBytesError），
+// 上传接口据此返回 413，其他接口按请求体解析失败处理
+// 参数:
+//
+//	cfg: 请求体大小限制配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func BodyLimit(cfg config.BodyLimitConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := cfg.GetMaxSize(c.Request.Method, c.FullPath())
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			logger.FromContext(c).Warn("请求体过大",
+				zap.String("path", c.Request.URL.Path),
+				zap.Int64("content_length", c.Request.ContentLength),
+				zap.Int64("limit", limit),
+			)
+			// 不读取剩余请求体，响应后关闭连接
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "请求体过大",
+				"limit": limit,
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+
+	r := gin.New()
+	r.Use(BodyLimit(config.BodyLimitConfig{
+		MaxSize: 8,
+		Routes:  []config.RouteBodyLimitConfig{{Method: "POST", Path: "/upload", MaxSize: 32}},
+	}))
+	read := func(c *gin.Context) {
+		_, err := io.ReadAll(c.Request.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.String(http.StatusRequestEntityTooLarge, "read")
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.POST("/data", read)
+	r.POST("/upload", read)
+
+	post := func(path, body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/data", "12345678", false); w.Code != http.StatusOK {
+		t.Errorf("未超过上限: 状态码 = %d", w.Code)
+	}
+	// 声明的 Content-Length 超过上限时不进入处理器
+	if w := post("/data", "123456789", false); w.Code != http.StatusRequestEntityTooLarge || w.Body.String() == "read" {
+		t.Errorf("Content-Length 超过上限: %d %s", w.Code, w.Body.String())
+	}
+	// 未声明长度时读取超过上限失败
+	if w := post("/data", "123456789", true); w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != "read" {
+		t.Errorf("分块请求超过上限: %d %s", w.Code, w.Body.String())
+	}
+	// 路由覆盖默认上限
+	if w := post("/upload", strings.Repeat("x", 32), false); w.Code != http.StatusOK {
+		t.Errorf("路由上限: 状态码 = %d", w.Code)
+	}
+	if w := post("/upload", strings.Repeat("x", 33), true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("超过路由上限: 状态码 = %d", w.Code)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+)
+
+// Factory 中间件构造函数
+// 根据中间件配置创建 Gin 中间件
+type Factory func(cfg config.MiddlewareConfig) gin.HandlerFunc
+
+// factories 按名称注册的中间件
+var factories = map[string]Factory{
+	"recovery":         func(config.MiddlewareConfig) gin.HandlerFunc { return Recovery() },
+	"request_id":       func(config.MiddlewareConfig) gin.HandlerFunc { return RequestID() },
+	"metrics":          func(config.MiddlewareConfig) gin.HandlerFunc { return metrics.GinMiddleware() },
+	"logger":           func(cfg config.MiddlewareConfig) gin.HandlerFunc { return Logger(cfg.RequestLog) },
+	"cors":             func(cfg config.MiddlewareConfig) gin.HandlerFunc { return CORS(cfg.CORS) },
+	"auth":             func(config.MiddlewareConfig) gin.HandlerFunc { return JWTAuth() },
+	"optional_auth":    func(config.MiddlewareConfig) gin.HandlerFunc { return OptionalJWTAuth() },
+	"ratelimit":        func(cfg config.MiddlewareConfig) gin.HandlerFunc { return RateLimit(cfg.RateLimit) },
+	"fields":           func(config.MiddlewareConfig) gin.HandlerFunc { return FieldFilter() },
+	"activity":         func(config.MiddlewareConfig) gin.HandlerFunc { return TrackActivity() },
+	"quota":            func(config.MiddlewareConfig) gin.HandlerFunc { return QuotaUsage() },
+	"timezone":         func(config.MiddlewareConfig) gin.HandlerFunc { return Localize() },
+	"failures":         func(config.MiddlewareConfig) gin.HandlerFunc { return CaptureFailures() },
+	"deprecation":      func(config.MiddlewareConfig) gin.HandlerFunc { return Deprecation() },
+	"idempotency":      func(cfg config.MiddlewareConfig) gin.HandlerFunc { return Idempotency(cfg.Idempotency) },
+	"timeout":          func(cfg config.MiddlewareConfig) gin.HandlerFunc { return Timeout(cfg.Timeout) },
+	"security_headers": func(cfg config.MiddlewareConfig) gin.HandlerFunc { return SecurityHeaders(cfg.SecurityHeaders) },
+	"csrf":             func(cfg config.MiddlewareConfig) gin.HandlerFunc { return CSRF(cfg.CSRF) },
+	"compression":      func(cfg config.MiddlewareConfig) gin.HandlerFunc { return Compression(cfg.Compression) },
+	"body_limit":       func(cfg config.MiddlewareConfig) gin.HandlerFunc { return BodyLimit(cfg.BodyLimit) },
+}
+
+// defaultChains 未在配置中指定时使用的默认中间件链
+var defaultChains = map[string][]string{
+	"global": {"recovery", "request_id", "security_headers", "compression", "metrics", "logger", "body_limit", "deprecation", "cors", "csrf", "ratelimit"},
+}
+
+// RegisterFactory 注册自定义中间件，之后即可在 chains 配置中按名称引用
+// 参数:
+//
+//	name: 中间件名称
+//	factory: 中间件构造函数
+func RegisterFactory(name string, factory Factory) {
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("中间件 %s 重复注册", name))
+	}
+	factories[name] = factory
+}
+
+// ValidateChains 校验所有路由组的中间件链
+// 参数:
+//
+//	cfg: 中间件配置
+//
+// 返回:
+//
+//	error: 存在未知或重复的中间件名称时返回错误
+func ValidateChains(cfg config.MiddlewareConfig) error {
+	var problems []string
+	for group, names := range cfg.Chains {
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if _, ok := factories[name]; !ok {
+				problems = append(problems, fmt.Sprintf("路由组 %s: 未知的中间件 %q", group, name))
+			}
+			if seen[name] {
+				problems = append(problems, fmt.Sprintf("路由组 %s: 中间件 %q 重复", group, name))
+			}
+			seen[name] = true
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("中间件链配置错误: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Chain 按配置顺序构建路由组的中间件链
+// 参数:
+//
+//	cfg: 中间件配置
+//	group: 路由组名称（如 global、api）
+//
+// 返回:
+//
+//	[]gin.HandlerFunc: 中间件列表
+//	error: 错误信息
+func Chain(cfg config.MiddlewareConfig, group string) ([]gin.HandlerFunc, error) {
+	names, ok := cfg.Chains[group]
+	if !ok {
+		names = defaultChains[group]
+	}
+
+	handlers := make([]gin.HandlerFunc, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("路由组 %s: 未知的中间件 %q", group, name)
+		}
+		handlers = append(handlers, factory(cfg))
+	}
+
+	return handlers, nil
+}
@@ -0,0 +1,292 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// compressor gzip、deflate 写入器的公共方法
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+	Flush() error
+}
+
+// Compression 响应压缩中间件
+// 按 Accept-Encoding 选择 gzip 或 deflate（同等权重时优先 gzip），响应体达到 min_size、
+// 内容类型在 content_types 中且处理器未设置 Content-Encoding 时压缩；HEAD、协议升级和 exclude_paths 中的请求不处理。
+// 需放在 failures、logger、idempotency 之前（外层），这些中间件记录的是压缩前的响应体
+// 参数:
+//
+//	cfg: 响应压缩配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func Compression(cfg config.CompressionConfig) gin.HandlerFunc {
+	level := cfg.GetLevel()
+	minSize := cfg.GetMinSize()
+	contentTypes := cfg.GetContentTypes()
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" ||
+			hasPathPrefix(cfg.ExcludePaths, c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		// 是否压缩取决于 Accept-Encoding，缓存需按其区分
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			pool:           pools[encoding],
+			minSize:        minSize,
+			contentTypes:   contentTypes,
+		}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+		c.Next()
+
+		if err := writer.finish(); err != nil {
+			logger.FromContext(c).Debug("写出压缩响应失败", zap.Error(err))
+		}
+	}
+}
+
+// compressWriter 压缩响应的 Writer
+// 响应体达到 minSize 前先缓冲，据此和响应头决定是否压缩，之后再写出状态码
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding     string
+	pool         *sync.Pool
+	minSize      int
+	contentTypes []string
+
+	// status 决定前处理器设置的状态码，0 表示未设置
+	status int
+	// headerNow 决定前处理器要求立即写出状态码（如 AbortWithStatus）
+	headerNow bool
+	buf       []byte
+	decided   bool
+	// comp 决定压缩后的写入器，不压缩时为 nil
+	comp compressor
+	// size 处理器写出的（压缩前）字节数
+	size int
+}
+
+// WriteHeader 决定前暂存状态码
+func (w *compressWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.status = code
+}
+
+// WriteHeaderNow 决定前只做记录，决定后一并写出
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.headerNow = true
+}
+
+// Status 返回处理器设置的状态码
+func (w *compressWriter) Status() int {
+	if w.decided {
+		return w.ResponseWriter.Status()
+	}
+	if w.status != 0 {
+		return w.status
+	}
+	return http.StatusOK
+}
+
+// Written 处理器是否已开始写出响应（包括尚在缓冲中的）
+func (w *compressWriter) Written() bool {
+	return w.headerNow || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Size 返回压缩前的响应体大小，未写出时为 -1
+func (w *compressWriter) Size() int {
+	if !w.Written() {
+		return -1
+	}
+	return w.size
+}
+
+// Write 缓冲到 minSize 后决定是否压缩
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+	if w.decided {
+		return w.write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString 同 Write
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 流式响应立即决定是否压缩（不再等待 minSize），并把已压缩的数据写出
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(w.compressible()); err != nil {
+			return
+		}
+	}
+	if w.comp != nil {
+		_ = w.comp.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// write 写出决定后的数据
+func (w *compressWriter) write(data []byte) (int, error) {
+	if w.comp != nil {
+		return w.comp.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// compressible 按状态码和响应头判断是否压缩
+func (w *compressWriter) compressible() bool {
+	switch status := w.Status(); {
+	case status < http.StatusOK, status == http.StatusNoContent,
+		status == http.StatusPartialContent, status == http.StatusNotModified:
+		return false
+	}
+	header := w.Header()
+	return header.Get("Content-Encoding") == "" && contentTypeAllowed(w.contentTypes, header.Get("Content-Type"))
+}
+
+// decide 决定是否压缩，写出状态码和缓冲的响应体
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		w.comp = w.pool.Get().(compressor)
+		w.comp.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) > 0 {
+		_, err := w.write(buf)
+		return err
+	}
+	if w.headerNow {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	return nil
+}
+
+// finish 处理器返回后写出剩余的响应，结束压缩并归还写入器
+func (w *compressWriter) finish() error {
+	if !w.decided {
+		// 未达到 minSize，不压缩
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.comp == nil {
+		return nil
+	}
+	err := w.comp.Close()
+	w.comp.Reset(io.Discard)
+	w.pool.Put(w.comp)
+	w.comp = nil
+	return err
+}
+
+// negotiateEncoding 按 Accept-Encoding 选择 gzip 或 deflate，都不接受时返回空
+func negotiateEncoding(accept string) string {
+	gzipQ, deflateQ, anyQ := -1.0, -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			} else {
+				q = 0
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			gzipQ = q
+		case "deflate":
+			deflateQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ < 0 {
+		gzipQ = anyQ
+	}
+	if deflateQ < 0 {
+		deflateQ = anyQ
+	}
+	switch {
+	case gzipQ > 0 && gzipQ >= deflateQ:
+		return "gzip"
+	case deflateQ > 0:
+		return "deflate"
+	}
+	return ""
+}
+
+// contentTypeAllowed 内容类型是否在列表中，列表项可以是 text/* 形式
+func contentTypeAllowed(allowed []string, contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" {
+		return false
+	}
+	for _, t := range allowed {
+		t = strings.ToLower(t)
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+
+	large := strings.Repeat("x", 2048)
+	r := gin.New()
+	r.Use(Recovery(), Compression(config.CompressionConfig{ExcludePaths: []string{"/files/"}}))
+	r.GET("/json", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"data": large}) })
+	r.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/png", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	r.GET("/files/export", func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(large)) })
+	r.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	r.GET("/empty", func(c *gin.Context) { c.AbortWithStatus(http.StatusNoContent) })
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// gzip 压缩大于 min_size 的 JSON，保留状态码
+	w := get("/json", "gzip, deflate")
+	if w.Code != http.StatusCreated || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("应以 gzip 压缩: %d %v", w.Code, w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(gz); string(body) != `{"data":"`+large+`"}` {
+		t.Errorf("解压后的响应体不正确: %.40s", body)
+	}
+
+	// 按权重选择 deflate（zlib 格式）
+	w = get("/json", "gzip;q=0.5, deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("应以 deflate 压缩: %v", w.Header())
+	}
+	zr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); !strings.Contains(string(body), large) {
+		t.Error("deflate 解压后的响应体不正确")
+	}
+
+	// 不压缩的情况
+	for _, tt := range []struct{ name, path, accept string }{
+		{"不接受压缩", "/json", ""},
+		{"不接受 gzip 和 deflate", "/json", "br, gzip;q=0"},
+		{"小于 min_size", "/small", "gzip"},
+		{"内容类型不在列表中", "/png", "gzip"},
+		{"排除的路径", "/files/export", "gzip"},
+		{"已设置 Content-Encoding", "/encoded", "gzip"},
+	} {
+		w := get(tt.path, tt.accept)
+		if w.Code >= http.StatusMultipleChoices || w.Header().Get("Content-Encoding") == "gzip" || w.Body.Len() == 0 {
+			t.Errorf("%s: 不应压缩, %d %v", tt.name, w.Code, w.Header())
+		}
+	}
+
+	if w := get("/empty", "gzip"); w.Code != http.StatusNoContent || w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("204 响应: %d %v", w.Code, w.Header())
+	}
+	// panic 时缓冲的响应丢弃，recovery 正常写出 500
+	if w := get("/panic", "gzip"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "Internal") {
+		t.Errorf("panic 响应: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate, gzip":         "gzip",
+		"deflate":               "deflate",
+		"gzip;q=0.2, deflate":   "deflate",
+		"GZIP":                  "gzip",
+		"*":                     "gzip",
+		"*;q=0":                 "",
+		"br, *;q=0.1, gzip;q=0": "deflate",
+		"identity":              "",
+		"gzip;q=bad, deflate":   "deflate",
+	}
+	for accept, want := range tests {
+		if got := negotiateEncoding(accept); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, 期望 %q", accept, got, want)
+		}
+	}
+}
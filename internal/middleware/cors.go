@@ -1,91 +1,130 @@
 package middleware
 
 import (
-	"time"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 	"github.com/zhang/microservice/internal/config"
 )
 
+// corsConfig 当前生效的 CORS 配置，支持运行时替换
+var corsConfig atomic.Pointer[config.CORSConfig]
+
+// UpdateCORSConfig 运行时替换 CORS 配置
+// 参数:
+//
+//	cfg: 新的 CORS 配置
+func UpdateCORSConfig(cfg config.CORSConfig) {
+	corsConfig.Store(&cfg)
+}
+
+// CurrentCORSConfig 获取当前生效的 CORS 配置
+func CurrentCORSConfig() config.CORSConfig {
+	if cfg := corsConfig.Load(); cfg != nil {
+		return *cfg
+	}
+	return config.CORSConfig{}
+}
+
 // CORS 跨域中间件
+// 只为匹配 allow_origins 的来源返回 CORS 头：匹配 * 时返回 *（不带凭证），
+// 明确列出或子域名通配（https://*.example.com）匹配时回显请求的 Origin，开启 allow_credentials 时允许携带凭证。
+// 预检请求（OPTIONS 且带 Access-Control-Request-Method）返回允许的方法、请求头和缓存时间后以 204 结束
 // 参数:
 //
-//	cfg: CORS 配置
+//	cfg: 初始 CORS 配置，之后可通过 UpdateCORSConfig 替换
 //
 // 返回:
 //
 //	gin.HandlerFunc: Gin 中间件函数
 func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	UpdateCORSConfig(cfg)
+
 	return func(c *gin.Context) {
+		cfg := CurrentCORSConfig()
 		if !cfg.Enable {
 			c.Next()
 			return
 		}
 
-		// 设置允许的源
-		if len(cfg.AllowOrigins) > 0 {
-			origin := c.Request.Header.Get("Origin")
-			for _, allowOrigin := range cfg.AllowOrigins {
-				if allowOrigin == "*" || allowOrigin == origin {
-					c.Header("Access-Control-Allow-Origin", allowOrigin)
-					break
-				}
-			}
-		}
+		header := c.Writer.Header()
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
 
-		// 设置允许的方法
-		if len(cfg.AllowMethods) > 0 {
-			methods := ""
-			for i, method := range cfg.AllowMethods {
-				if i > 0 {
-					methods += ", "
-				}
-				methods += method
-			}
-			c.Header("Access-Control-Allow-Methods", methods)
+		// 响应随 Origin 变化，共享缓存需按 Origin 区分
+		header.Add("Vary", "Origin")
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
 		}
 
-		// 设置允许的头
-		if len(cfg.AllowHeaders) > 0 {
-			headers := ""
-			for i, header := range cfg.AllowHeaders {
-				if i > 0 {
-					headers += ", "
-				}
-				headers += header
+		origin := c.GetHeader("Origin")
+		if allowOrigin := matchOrigin(cfg.AllowOrigins, origin); origin != "" && allowOrigin != "" {
+			header.Set("Access-Control-Allow-Origin", allowOrigin)
+			// 浏览器拒绝 * 与凭证同时出现的响应
+			if cfg.AllowCredentials && allowOrigin != "*" {
+				header.Set("Access-Control-Allow-Credentials", "true")
 			}
-			c.Header("Access-Control-Allow-Headers", headers)
-		}
-
-		// 设置暴露的头
-		if len(cfg.ExposeHeaders) > 0 {
-			headers := ""
-			for i, header := range cfg.ExposeHeaders {
-				if i > 0 {
-					headers += ", "
+			if preflight {
+				if len(cfg.AllowMethods) > 0 {
+					header.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowMethods, ", "))
+				}
+				if len(cfg.AllowHeaders) > 0 {
+					header.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowHeaders, ", "))
 				}
-				headers += header
+				// max_age 单位为小时，响应头单位为秒
+				if cfg.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge*3600))
+				}
+			} else if len(cfg.ExposeHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposeHeaders, ", "))
 			}
-			c.Header("Access-Control-Expose-Headers", headers)
 		}
 
-		// 设置是否允许凭证
-		if cfg.AllowCredentials {
-			c.Header("Access-Control-Allow-Credentials", "true")
+		// 处理预检请求（来源不允许时不带 CORS 头，浏览器据此拒绝实际请求）
+		if preflight {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
 		}
 
-		// 设置预检请求缓存时间
-		if cfg.MaxAge > 0 {
-			maxAge := time.Duration(cfg.MaxAge) * time.Hour
-			c.Header("Access-Control-Max-Age", string(rune(maxAge.Seconds())))
-		}
+		c.Next()
+	}
+}
 
-		// 处理预检请求
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+// matchOrigin 返回请求来源对应的 Access-Control-Allow-Origin 值
+// 明确列出或通配匹配时为请求来源本身，只匹配 * 时为 *，都不匹配时为空
+func matchOrigin(allowed []string, origin string) string {
+	wildcard := false
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			wildcard = true
+			continue
+		}
+		if originMatches(pattern, origin) {
+			return origin
 		}
+	}
+	if wildcard {
+		return "*"
+	}
+	return ""
+}
 
-		c.Next()
+// originMatches 来源是否匹配配置项：完全相同，或配置项为 scheme://*.host 且来源是 host 的子域名
+func originMatches(pattern, origin string) bool {
+	if pattern == origin {
+		return true
+	}
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	rest, ok := strings.CutPrefix(origin, scheme+"://")
+	if !ok {
+		return false
 	}
+	sub, ok := strings.CutSuffix(rest, "."+host)
+	return ok && sub != "" && !strings.ContainsAny(sub, ":/@")
 }
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestCORSPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { UpdateCORSConfig(config.CORSConfig{}) })
+
+	newRouter := func(origins ...string) *gin.Engine {
+		r := gin.New()
+		r.Use(CORS(config.CORSConfig{
+			Enable:           true,
+			AllowOrigins:     origins,
+			AllowMethods:     []string{"GET", "POST"},
+			AllowHeaders:     []string{"Content-Type", "Authorization"},
+			ExposeHeaders:    []string{"X-Request-ID"},
+			AllowCredentials: true,
+			MaxAge:           12,
+		}))
+		r.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	preflight := func(r *gin.Engine, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/users", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name        string
+		origins     []string
+		origin      string
+		allow       string
+		credentials string
+	}{
+		{"完整来源", []string{"https://app.example.com"}, "https://app.example.com", "https://app.example.com", "true"},
+		{"子域名通配", []string{"https://*.example.com"}, "https://a.b.example.com", "https://a.b.example.com", "true"},
+		{"通配不匹配主域名", []string{"https://*.example.com"}, "https://example.com", "", ""},
+		{"通配不匹配其他协议", []string{"https://*.example.com"}, "http://app.example.com", "", ""},
+		{"通配不匹配相似域名", []string{"https://*.example.com"}, "https://app.badexample.com", "", ""},
+		{"* 不带凭证", []string{"*"}, "https://any.test", "*", ""},
+		{"明确列出优先于 *", []string{"*", "https://app.example.com"}, "https://app.example.com", "https://app.example.com", "true"},
+		{"未允许的来源", []string{"https://app.example.com"}, "https://evil.test", "", ""},
+	}
+	for _, tt := range tests {
+		w := preflight(newRouter(tt.origins...), tt.origin)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: 预检状态码 = %d, 期望 204", tt.name, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+			t.Errorf("%s: Allow-Origin = %q, 期望 %q", tt.name, got, tt.allow)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+			t.Errorf("%s: Allow-Credentials = %q, 期望 %q", tt.name, got, tt.credentials)
+		}
+		if tt.allow == "" && w.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("%s: 未允许的来源不应返回 Allow-Methods", tt.name)
+		}
+	}
+
+	// 预检响应的方法、请求头和缓存时间（max_age 12 小时）
+	w := preflight(newRouter("https://app.example.com"), "https://app.example.com")
+	want := map[string]string{
+		"Access-Control-Allow-Methods":  "GET, POST",
+		"Access-Control-Allow-Headers":  "Content-Type, Authorization",
+		"Access-Control-Max-Age":        "43200",
+		"Access-Control-Expose-Headers": "",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("预检 %s = %q, 期望 %q", name, got, value)
+		}
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 3 || vary[0] != "Origin" {
+		t.Errorf("预检 Vary = %v", vary)
+	}
+
+	// 实际请求只返回 Expose-Headers，正常进入处理器
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	newRouter("https://app.example.com").ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" ||
+		w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("实际请求: %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/deprecation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Deprecation 弃用接口中间件
+// 路由在 deprecation 中声明弃用后，响应带上 Deprecation、Sunset、Link 头，
+// 请求处理完成后（此时已完成认证）按调用方记录调用，供 GET /api/v1/admin/deprecations 查看
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func Deprecation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoint := deprecation.HTTPEndpoint(c.Request.Method, c.FullPath())
+		notice, ok := deprecation.Lookup(endpoint)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		for name, value := range notice.Headers() {
+			c.Header(name, value)
+		}
+		c.Next()
+		deprecation.Record(c.Request.Context(), endpoint, deprecatedCaller(c))
+	}
+}
+
+// GRPCDeprecation 弃用方法拦截器
+// 需放在 GRPCAuth 之后：方法声明弃用后，响应 header metadata 带上 deprecation、sunset、link，并按调用方记录调用
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCDeprecation() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if notice, ok := deprecation.Lookup(info.FullMethod); ok {
+			_ = grpc.SetHeader(ctx, deprecationMetadata(notice))
+			deprecation.Record(ctx, info.FullMethod, deprecatedCaller(ctx))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamDeprecation 弃用方法流拦截器，见 GRPCDeprecation
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamDeprecation() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if notice, ok := deprecation.Lookup(info.FullMethod); ok {
+			_ = ss.SetHeader(deprecationMetadata(notice))
+			deprecation.Record(ss.Context(), info.FullMethod, deprecatedCaller(ss.Context()))
+		}
+		return handler(srv, ss)
+	}
+}
+
+// deprecationMetadata 把弃用响应头转换为 gRPC metadata（键为小写）
+func deprecationMetadata(notice deprecation.Notice) metadata.MD {
+	md := metadata.MD{}
+	for name, value := range notice.Headers() {
+		md.Set(strings.ToLower(name), value)
+	}
+	return md
+}
+
+// deprecatedCaller 弃用接口统计使用的调用方名称
+func deprecatedCaller(ctx context.Context) string {
+	if identity, ok := ctxkeys.IdentityFrom(ctx); ok && identity.Username != "" {
+		return identity.Username
+	}
+	return deprecation.Anonymous
+}
@@ -0,0 +1,71 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/deprecation"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+var legacyNotice = deprecation.Register(deprecation.Notice{
+	Endpoint:    "GET /api/v1/legacy/:id",
+	Since:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	Sunset:      time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+	Replacement: "GET /api/v1/items/:id",
+	Link:        "https://example.com/migrate",
+})
+
+func TestDeprecation(t *testing.T) {
+	testutil.UseMiniredis(t)
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id", "deprecation"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/legacy/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+		r.GET("/items/:id", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"id": c.Param("id")}) })
+	})
+
+	w := testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/legacy/1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d", w.Code)
+	}
+	if got := w.Header().Get("Deprecation"); got != "@1704067200" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Tue, 31 Dec 2024 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Link = %q", got)
+	}
+	testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/legacy/2", nil))
+
+	// 未弃用的路由不带弃用头
+	if w := testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/items/1", nil)); w.Header().Get("Deprecation") != "" {
+		t.Error("未弃用的路由不应带 Deprecation 头")
+	}
+
+	report, err := deprecation.Report(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var usage *deprecation.Usage
+	for i := range report {
+		if report[i].Endpoint == legacyNotice.Endpoint {
+			usage = &report[i]
+		}
+	}
+	if usage == nil {
+		t.Fatalf("报告中缺少 %s: %+v", legacyNotice.Endpoint, report)
+	}
+	if usage.Calls != 2 || len(usage.Clients) != 1 || usage.Clients[0].Client != deprecation.Anonymous {
+		t.Errorf("调用统计 = %+v", usage)
+	}
+}
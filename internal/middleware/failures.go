@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/failures"
+)
+
+// limitedBuffer 最多保留 limit 字节的缓冲区，超出部分丢弃并标记截断
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 写入数据，始终返回完整长度以免影响原始读写
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+// WriteString 经过 Write 以便按上限截断
+func (b *limitedBuffer) WriteString(s string) (int, error) {
+	return b.Write([]byte(s))
+}
+
+// teeBody 读取请求体时同时保留副本
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter 写出响应的同时保留响应体副本
+type captureWriter struct {
+	gin.ResponseWriter
+	body *limitedBuffer
+}
+
+// Write 写出并保留副本
+func (w *captureWriter) Write(data []byte) (int, error) {
+	_, _ = w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写出并保留副本
+func (w *captureWriter) WriteString(s string) (int, error) {
+	_, _ = w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// CaptureFailures 失败请求记录中间件
+// 请求体（处理器读取过的部分）和响应体边读写边保留副本（各不超过 failure_capture.max_body_size），
+// 状态码 ≥500 时脱敏后写入 failures.Default，供 GET /api/v1/admin/failures 查看；
+// 需放在 recovery 之前，panic 转成的 500 响应才会被记录
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func CaptureFailures() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		recorder := failures.Default
+		start := time.Now()
+
+		reqBody := &limitedBuffer{limit: recorder.MaxBodySize()}
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			c.Request.Body = teeBody{Reader: io.TeeReader(c.Request.Body, reqBody), Closer: c.Request.Body}
+		}
+		writer := &captureWriter{ResponseWriter: c.Writer, body: &limitedBuffer{limit: recorder.MaxBodySize()}}
+		c.Writer = writer
+
+		c.Next()
+
+		if c.Writer.Status() < http.StatusInternalServerError {
+			return
+		}
+
+		userID, _ := GetUserID(c)
+		entry := failures.Entry{
+			Time:         start,
+			RequestID:    ctxkeys.RequestID(c),
+			Method:       c.Request.Method,
+			Path:         c.Request.URL.Path,
+			Query:        c.Request.URL.RawQuery,
+			Status:       c.Writer.Status(),
+			Latency:      time.Since(start).String(),
+			ClientIP:     c.ClientIP(),
+			UserID:       userID,
+			Headers:      c.Request.Header,
+			RequestBody:  reqBody.String(),
+			ResponseBody: writer.body.String(),
+			Truncated:    reqBody.truncated || writer.body.truncated,
+		}
+		for _, err := range c.Errors {
+			entry.Errors = append(entry.Errors, err.Error())
+		}
+		recorder.Add(entry, c.ContentType(), c.Writer.Header().Get("Content-Type"))
+	}
+}
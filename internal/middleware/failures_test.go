@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/failures"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestCaptureFailures(t *testing.T) {
+	previous := failures.Default
+	failures.Init(config.FailureCaptureConfig{Size: 10, MaxBodySize: 64})
+	t.Cleanup(func() { failures.Default = previous })
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"failures", "recovery", "request_id"},
+	}}
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.POST("/boom", func(c *gin.Context) {
+			_, _ = io.ReadAll(c.Request.Body)
+			panic("boom")
+		})
+		r.GET("/unavailable", func(c *gin.Context) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "依赖不可用", "detail": strings.Repeat("x", 100)})
+		})
+		r.GET("/ok", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/boom?token=t", strings.NewReader(`{"name":"a","password":"p"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	if w := testutil.Do(router, req); w.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码 = %d", w.Code)
+	}
+	testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/ok", nil))
+	testutil.Do(router, httptest.NewRequest(http.MethodGet, "/api/v1/unavailable", nil))
+
+	list, total := failures.Default.List(0)
+	if total != 2 || len(list) != 2 {
+		t.Fatalf("记录数 = %d（总数 %d）, 期望 2", len(list), total)
+	}
+
+	unavailable, boom := list[0], list[1]
+	if unavailable.Status != http.StatusServiceUnavailable || !unavailable.Truncated {
+		t.Errorf("超长响应应被截断: %+v", unavailable)
+	}
+	if boom.Status != http.StatusInternalServerError || boom.RequestID == "" {
+		t.Errorf("panic 请求: %+v", boom)
+	}
+	if boom.RequestBody != `{"name":"a","password":"[REDACTED]"}` || boom.Query != "token=%5BREDACTED%5D" {
+		t.Errorf("请求体 = %s, 查询参数 = %s", boom.RequestBody, boom.Query)
+	}
+	if boom.Headers.Get("Authorization") != failures.Redacted {
+		t.Errorf("Authorization 未脱敏: %v", boom.Headers)
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/fieldmask"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// bufferedWriter 缓存响应体的 ResponseWriter
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write 写入缓存而不是直接输出
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString 写入缓存而不是直接输出
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// FieldFilter 稀疏字段集中间件
+// 请求带有 ?fields=id,name 时，只返回 JSON 响应中的指定字段
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func FieldFilter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paths := fieldmask.Parse(c.Query("fields"))
+		if len(paths) == 0 {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		body := writer.body.Bytes()
+
+		// 仅裁剪成功的 JSON 响应
+		status := original.Status()
+		contentType := original.Header().Get("Content-Type")
+		if status >= http.StatusOK && status < http.StatusMultipleChoices &&
+			strings.HasPrefix(contentType, "application/json") {
+			pruned, err := fieldmask.PruneJSON(body, paths)
+			if err != nil {
+				logger.FromContext(c).Warn("裁剪响应字段失败",
+					zap.Error(err),
+				)
+			} else {
+				body = pruned
+			}
+		}
+
+		if _, err := original.Write(body); err != nil {
+			logger.Error("写入响应失败", zap.Error(err))
+		}
+	}
+}
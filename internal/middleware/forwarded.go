@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/config"
+)
+
+// TrustProxies 配置 gin 采信转发头的代理范围
+// 只有 TCP 对端（启用 PROXY 协议时为协议头中的地址）属于受信任代理时，才按转发头取客户端地址；
+// client_ip_header 为 forwarded 时只采信由 ClientIP 从 Forwarded 转换出的 X-Forwarded-For
+// 参数:
+//
+//	engine: Gin 路由引擎
+//	cfg: 代理配置
+//
+// 返回:
+//
+//	error: 地址格式错误
+func TrustProxies(engine *gin.Engine, cfg config.ProxyConfig) error {
+	engine.ForwardedByClientIP = true
+	engine.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	if cfg.GetClientIPHeader() == config.ClientIPHeaderForwarded {
+		engine.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	}
+	return engine.SetTrustedProxies(cfg.TrustedProxies)
+}
+
+// ClientIP 客户端地址中间件，应在所有读取客户端地址的中间件之前注册
+// client_ip_header 为 forwarded 时，以 RFC 7239 Forwarded 头替换请求中的 X-Forwarded-For / X-Forwarded-Proto，
+// 并删除 X-Real-IP：代理只设置 Forwarded 时，客户端自带的 X-Forwarded-* 头不能被采信；
+// 为 x-forwarded-for 时不处理 Forwarded 头。解析出的地址写入请求上下文，供审计记录使用
+// 参数:
+//
+//	cfg: 代理配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func ClientIP(cfg config.ProxyConfig) gin.HandlerFunc {
+	useForwarded := cfg.GetClientIPHeader() == config.ClientIPHeaderForwarded
+
+	return func(c *gin.Context) {
+		if useForwarded {
+			header := c.Request.Header
+			forList, proto := parseForwarded(header.Get("Forwarded"))
+			header.Del("X-Real-IP")
+			header.Del("X-Forwarded-For")
+			header.Del("X-Forwarded-Proto")
+			if len(forList) > 0 {
+				header.Set("X-Forwarded-For", strings.Join(forList, ", "))
+			}
+			if proto != "" {
+				header.Set("X-Forwarded-Proto", proto)
+			}
+		}
+
+		c.Request = c.Request.WithContext(audit.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// parseForwarded 解析 Forwarded 头，返回按跳数排列的 for 地址（去掉端口）与第一跳的 proto
+// 例如 `for=192.0.2.60;proto=https, for="[2001:db8:cafe::17]:4711"`
+func parseForwarded(value string) ([]string, string) {
+	var (
+		forList []string
+		proto   string
+	)
+	for i, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			v = strings.Trim(v, `"`)
+			switch strings.ToLower(k) {
+			case "for":
+				forList = append(forList, stripPort(v))
+			case "proto":
+				if i == 0 {
+					proto = strings.ToLower(v)
+				}
+			}
+		}
+	}
+	return forList, proto
+}
+
+// stripPort 去掉地址中的端口与 IPv6 方括号，非 IP 的标识（unknown、_hidden）原样返回
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
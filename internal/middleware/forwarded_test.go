@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestClientIPForwardedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(clientIPHeader string) *gin.Engine {
+		cfg := config.ProxyConfig{TrustedProxies: []string{"10.0.0.0/8"}, ClientIPHeader: clientIPHeader}
+		r := gin.New()
+		if err := TrustProxies(r, cfg); err != nil {
+			t.Fatal(err)
+		}
+		r.Use(ClientIP(cfg))
+		r.GET("/", func(c *gin.Context) {
+			c.String(http.StatusOK, c.ClientIP()+" "+c.GetHeader("X-Forwarded-Proto"))
+		})
+		return r
+	}
+	routers := map[string]*gin.Engine{
+		config.ClientIPHeaderXForwardedFor: newRouter(""),
+		config.ClientIPHeaderForwarded:     newRouter(config.ClientIPHeaderForwarded),
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:   "无转发头",
+			mode:   config.ClientIPHeaderXForwardedFor,
+			remote: "10.0.0.5",
+			want:   "10.0.0.5 ",
+		},
+		{
+			name:    "X-Forwarded-For",
+			mode:    config.ClientIPHeaderXForwardedFor,
+			remote:  "10.0.0.5",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.9"},
+			want:    "203.0.113.7 ",
+		},
+		{
+			name:    "x-forwarded-for 模式忽略 Forwarded",
+			mode:    config.ClientIPHeaderXForwardedFor,
+			remote:  "10.0.0.5",
+			headers: map[string]string{"Forwarded": "for=198.51.100.1"},
+			want:    "10.0.0.5 ",
+		},
+		{
+			name:    "Forwarded",
+			mode:    config.ClientIPHeaderForwarded,
+			remote:  "10.0.0.5",
+			headers: map[string]string{"Forwarded": `for=203.0.113.7;proto=HTTPS, for="10.0.0.9:8080"`},
+			want:    "203.0.113.7 https",
+		},
+		{
+			name:    "Forwarded IPv6",
+			mode:    config.ClientIPHeaderForwarded,
+			remote:  "10.0.0.5",
+			headers: map[string]string{"Forwarded": `for="[2001:db8:cafe::17]:4711"`},
+			want:    "2001:db8:cafe::17 ",
+		},
+		{
+			name:   "代理只设置 Forwarded 时客户端伪造的 X-Forwarded-* 不生效",
+			mode:   config.ClientIPHeaderForwarded,
+			remote: "10.0.0.5",
+			headers: map[string]string{
+				"Forwarded":         "for=203.0.113.7",
+				"X-Forwarded-For":   "198.51.100.66",
+				"X-Real-IP":         "198.51.100.67",
+				"X-Forwarded-Proto": "https",
+			},
+			want: "203.0.113.7 ",
+		},
+		{
+			name:    "forwarded 模式没有 Forwarded 时使用对端地址",
+			mode:    config.ClientIPHeaderForwarded,
+			remote:  "10.0.0.5",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.66", "X-Real-IP": "198.51.100.67"},
+			want:    "10.0.0.5 ",
+		},
+		{
+			name:    "不受信任的对端",
+			mode:    config.ClientIPHeaderForwarded,
+			remote:  "198.51.100.1",
+			headers: map[string]string{"Forwarded": "for=203.0.113.7", "X-Forwarded-For": "203.0.113.7"},
+			want:    "198.51.100.1 ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote + ":12345"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			routers[tt.mode].ServeHTTP(w, req)
+
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("响应 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
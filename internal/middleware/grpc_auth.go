@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCAPIKeyMetadata 服务间调用传递静态密钥的 metadata 键
+const GRPCAPIKeyMetadata = "x-api-key"
+
+// errNoCredentials 调用方未提供任何凭证
+var errNoCredentials = errors.New("未提供认证凭证")
+
+// claimsKey gRPC 上下文中保存 JWT 声明的键
+type claimsKey struct{}
+
+// OptionalGRPCAuth 可选的 gRPC JWT 认证拦截器
+// 用途: metadata 中带有有效的 "authorization: Bearer <token>" 时将声明存入上下文，否则按匿名请求继续处理
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func OptionalGRPCAuth() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := ClaimsFromContext(ctx); ok {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			parts := strings.SplitN(value, " ", 2)
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				continue
+			}
+			if claims, err := authenticate(ctx, parts[1]); err == nil {
+				ctx = withClaims(ctx, claims)
+				break
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// withClaims 把声明和对应的调用方身份（见 ctxkeys.IdentityFrom）存入上下文，之后的日志带上 user_id
+func withClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = logger.With(ctx, zap.Int64("user_id", claims.UserID))
+	return ctxkeys.WithIdentity(context.WithValue(ctx, claimsKey{}, claims), claims.identity())
+}
+
+// ClaimsFromContext 从 gRPC 上下文获取 JWT 声明
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	*Claims: JWT 声明
+//	bool: 是否存在
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// grpcAuthenticator 按配置识别 gRPC 调用方
+type grpcAuthenticator struct {
+	required bool
+	public   map[string]bool
+	apiKeys  []config.GRPCAPIKey
+	certs    map[string]string
+}
+
+// newGRPCAuthenticator 根据配置创建调用方识别器
+func newGRPCAuthenticator(cfg config.GRPCAuthConfig) *grpcAuthenticator {
+	a := &grpcAuthenticator{
+		required: cfg.Required,
+		public:   make(map[string]bool, len(cfg.PublicMethods)),
+		apiKeys:  cfg.APIKeys,
+		certs:    make(map[string]string, len(cfg.ClientCerts)),
+	}
+	for _, m := range cfg.PublicMethods {
+		a.public[m] = true
+	}
+	for _, c := range cfg.ClientCerts {
+		a.certs[c.CommonName] = c.Role
+	}
+	return a
+}
+
+// identify 依次按 Bearer JWT、x-api-key、客户端证书识别调用方
+// 提供了凭证但校验失败时返回错误，不会回退到后续方式
+func (a *grpcAuthenticator) identify(ctx context.Context) (*Claims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		parts := strings.SplitN(values[0], " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			return nil, errors.New("认证令牌格式错误")
+		}
+		return authenticate(ctx, parts[1])
+	}
+
+	if values := md.Get(GRPCAPIKeyMetadata); len(values) > 0 {
+		for _, k := range a.apiKeys {
+			if subtle.ConstantTimeCompare([]byte(values[0]), []byte(k.Key)) == 1 {
+				return &Claims{Username: k.Name, Role: k.Role}, nil
+			}
+		}
+		return nil, errors.New("无效的 API 密钥")
+	}
+
+	// 只有 TLS 握手中已通过 CA 校验的证书才作为身份
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			cn := info.State.VerifiedChains[0][0].Subject.CommonName
+			if role, ok := a.certs[cn]; ok {
+				return &Claims{Username: cn, Role: role}, nil
+			}
+		}
+	}
+
+	return nil, errNoCredentials
+}
+
+// authorize 识别调用方并写入上下文
+func (a *grpcAuthenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+	claims, err := a.identify(ctx)
+	if err == nil {
+		return withClaims(ctx, claims), nil
+	}
+	if errors.Is(err, errNoCredentials) && (!a.required || a.public[method]) {
+		return ctx, nil
+	}
+	if errors.Is(err, ErrRevocationUnavailable) {
+		return nil, status.Error(codes.Unavailable, "认证服务暂时不可用")
+	}
+
+	logger.Warn("gRPC 认证失败", zap.String("method", method), zap.Error(err))
+	return nil, status.Error(codes.Unauthenticated, "认证失败")
+}
+
+// contextStream 替换上下文的服务端流
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 返回拦截器写入了调用方身份、请求 ID 等信息的上下文
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// GRPCAuth gRPC 认证一元拦截器
+// 从 metadata authorization（Bearer JWT）、x-api-key 或 mTLS 客户端证书识别调用方，
+// 身份以 *Claims 存入上下文（见 ClaimsFromContext）；凭证无效时返回 Unauthenticated，
+// 未提供凭证时按 cfg.Required 决定拒绝或按匿名调用继续
+// 参数:
+//
+//	cfg: gRPC 认证配置
+//
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCAuth(cfg config.GRPCAuthConfig) grpc.UnaryServerInterceptor {
+	a := newGRPCAuthenticator(cfg)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamAuth gRPC 认证流拦截器，规则同 GRPCAuth
+// 参数:
+//
+//	cfg: gRPC 认证配置
+//
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamAuth(cfg config.GRPCAuthConfig) grpc.StreamServerInterceptor {
+	a := newGRPCAuthenticator(cfg)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// MethodRoles 方法名（不含服务名，如 DeleteUser）到允许角色的映射
+// 角色列表为空表示只要求已认证
+type MethodRoles map[string][]string
+
+// check 校验调用方角色，未列出的方法不限制
+func (m MethodRoles) check(ctx context.Context, fullMethod string) error {
+	roles, ok := m[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+	if !ok {
+		return nil
+	}
+
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "未提供认证凭证")
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	for _, role := range roles {
+		if claims.Role == role {
+			return nil
+		}
+	}
+
+	logger.Warn("调用方角色不匹配",
+		zap.String("method", fullMethod),
+		zap.String("user_role", claims.Role),
+		zap.Strings("required_roles", roles),
+	)
+	return status.Error(codes.PermissionDenied, "权限不足")
+}
+
+// GRPCRequireRole gRPC 方法级角色检查一元拦截器，与 RequireRole 对应
+// 需放在 GRPCAuth 之后；未认证返回 Unauthenticated，角色不匹配返回 PermissionDenied
+// 参数:
+//
+//	roles: 各方法允许的角色
+//
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCRequireRole(roles MethodRoles) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := roles.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamRequireRole gRPC 方法级角色检查流拦截器
+// 参数:
+//
+//	roles: 各方法允许的角色
+//
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamRequireRole(roles MethodRoles) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := roles.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
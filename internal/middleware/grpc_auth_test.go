@@ -0,0 +1,100 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAuth(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	cfg := config.GRPCAuthConfig{
+		APIKeys: []config.GRPCAPIKey{{Name: "billing", Key: "secret-key", Role: "admin"}},
+	}
+	// Check 需要 admin，Watch（流）需要登录
+	roles := middleware.MethodRoles{"Check": {"admin"}, "Watch": {}}
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	},
+		grpc.ChainUnaryInterceptor(middleware.GRPCAuth(cfg), middleware.GRPCRequireRole(roles)),
+		grpc.ChainStreamInterceptor(middleware.GRPCStreamAuth(cfg), middleware.GRPCStreamRequireRole(roles)),
+	)
+	client := healthpb.NewHealthClient(conn)
+
+	withMD := func(kv ...string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), kv...)
+	}
+	bearer := func(role string) context.Context {
+		return withMD("authorization", testutil.BearerHeader(minter.MustMint(t, 1, role, time.Hour)))
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected codes.Code
+	}{
+		{"匿名", context.Background(), codes.Unauthenticated},
+		{"管理员令牌", bearer("admin"), codes.OK},
+		{"普通用户令牌", bearer("user"), codes.PermissionDenied},
+		{"无效令牌", withMD("authorization", "Bearer bad"), codes.Unauthenticated},
+		{"API 密钥", withMD("x-api-key", "secret-key"), codes.OK},
+		{"错误的 API 密钥", withMD("x-api-key", "guess"), codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Check(tt.ctx, &healthpb.HealthCheckRequest{})
+			if code := status.Code(err); code != tt.expected {
+				t.Errorf("状态码 = %s, 期望 %s", code, tt.expected)
+			}
+		})
+	}
+
+	watch := func(ctx context.Context) codes.Code {
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return status.Code(err)
+		}
+		_, err = stream.Recv()
+		return status.Code(err)
+	}
+	if code := watch(context.Background()); code != codes.Unauthenticated {
+		t.Errorf("匿名订阅: 状态码 = %s, 期望 Unauthenticated", code)
+	}
+	if code := watch(bearer("user")); code != codes.OK {
+		t.Errorf("登录后订阅: 状态码 = %s, 期望 OK", code)
+	}
+}
+
+func TestGRPCAuthRequired(t *testing.T) {
+	cfg := config.GRPCAuthConfig{
+		Required:      true,
+		PublicMethods: []string{"/grpc.health.v1.Health/Check"},
+	}
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, health.NewServer())
+	}, grpc.StreamInterceptor(middleware.GRPCStreamAuth(cfg)), grpc.UnaryInterceptor(middleware.GRPCAuth(cfg)))
+	client := healthpb.NewHealthClient(conn)
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("公开方法: %v", err)
+	}
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("未公开的方法: 状态码 = %s, 期望 Unauthenticated", code)
+	}
+}
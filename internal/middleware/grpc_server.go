@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCRequestIDMetadata 传递请求 ID 的 metadata 键，与 HTTP 的 X-Request-ID 对应（网关转码时转发）
+const GRPCRequestIDMetadata = ctxkeys.RequestIDMetadata
+
+// grpcInterceptor 按名称注册的 gRPC 拦截器
+type grpcInterceptor struct {
+	unary  grpc.UnaryServerInterceptor
+	stream grpc.StreamServerInterceptor
+}
+
+// grpcInterceptors 可在 grpc.interceptors 中引用的拦截器
+var grpcInterceptors = map[string]func() grpcInterceptor{
+	"recovery":   func() grpcInterceptor { return grpcInterceptor{GRPCRecovery(), GRPCStreamRecovery()} },
+	"request_id": func() grpcInterceptor { return grpcInterceptor{GRPCRequestID(), GRPCStreamRequestID()} },
+	"logger":     func() grpcInterceptor { return grpcInterceptor{GRPCLogger(), GRPCStreamLogger()} },
+	"metrics": func() grpcInterceptor {
+		return grpcInterceptor{metrics.UnaryServerInterceptor(), metrics.StreamServerInterceptor()}
+	},
+}
+
+// defaultGRPCInterceptors 未配置 grpc.interceptors 时使用的拦截器链
+// recovery 放在最内层，panic 转为 Internal 后仍会被访问日志和指标记录
+var defaultGRPCInterceptors = []string{"request_id", "logger", "metrics", "recovery"}
+
+// GRPCInterceptors 按配置顺序构建 gRPC 服务端的通用拦截器链（认证和服务专属拦截器由调用方追加在后面）
+// 错误映射拦截器（GRPCErrors）总是追加在配置的拦截器之后，未分类的错误不会原样返回给调用方
+// 参数:
+//
+//	cfg: gRPC 配置
+//
+// 返回:
+//
+//	[]grpc.UnaryServerInterceptor: 一元拦截器
+//	[]grpc.StreamServerInterceptor: 流拦截器
+//	error: 存在未知的拦截器名称时返回错误
+func GRPCInterceptors(cfg config.GRPCConfig) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor, error) {
+	names := cfg.Interceptors
+	if names == nil {
+		names = defaultGRPCInterceptors
+	}
+
+	unary := make([]grpc.UnaryServerInterceptor, 0, len(names))
+	stream := make([]grpc.StreamServerInterceptor, 0, len(names))
+	for _, name := range names {
+		build, ok := grpcInterceptors[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("未知的 gRPC 拦截器 %q", name)
+		}
+		i := build()
+		unary = append(unary, i.unary)
+		stream = append(stream, i.stream)
+	}
+	unary = append(unary, GRPCErrors())
+	stream = append(stream, GRPCStreamErrors())
+	return unary, stream, nil
+}
+
+// GRPCErrors gRPC 错误映射一元拦截器
+// 方法返回的错误经 errs.Status 转为 gRPC 状态：gorm 等未分类的错误记录错误日志后映射为 Internal，
+// 调用方只看到通用说明
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCErrors() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, mapGRPCError(ctx, info.FullMethod, err)
+	}
+}
+
+// GRPCStreamErrors gRPC 错误映射流拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamErrors() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return mapGRPCError(ss.Context(), info.FullMethod, handler(srv, ss))
+	}
+}
+
+// mapGRPCError 把方法返回的错误转为 gRPC 状态错误，服务端错误记录原始错误
+func mapGRPCError(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	st := errs.Status(err)
+	if errs.ServerError(st.Code()) {
+		logger.FromContext(ctx).Error("gRPC 方法返回内部错误",
+			zap.String("method", method),
+			zap.Error(err),
+		)
+	}
+	return st.Err()
+}
+
+// GRPCRecovery gRPC panic 恢复一元拦截器
+// 捕获 panic 并记录带堆栈的错误日志，向调用方返回通用的 Internal（不含 panic 内容），
+// 调用方可按响应 header 中的 x-request-id 在日志中查找
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverGRPC(ctx, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamRecovery gRPC panic 恢复流拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverGRPC(ss.Context(), info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+// recoverGRPC 捕获 panic 并改写返回的错误
+func recoverGRPC(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		logger.FromContext(ctx).Error("gRPC 方法发生 panic",
+			zap.String("method", method),
+			zap.Any("error", r),
+			zap.Stack("stacktrace"),
+		)
+		*err = errs.ErrInternal.GRPCStatus().Err()
+	}
+}
+
+// GRPCRequestID gRPC 请求 ID 一元拦截器
+// 优先使用 metadata x-request-id（网关转码或上游服务传入，格式不合法时忽略），没有时生成 UUIDv7；
+// 请求 ID 存入上下文（见 GRPCRequestIDFromContext）并通过响应 header 返回给调用方
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, id := withRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(GRPCRequestIDMetadata, id))
+		return handler(ctx, req)
+	}
+}
+
+// GRPCStreamRequestID gRPC 请求 ID 流拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, id := withRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(GRPCRequestIDMetadata, id))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// withRequestID 从 metadata 取出或生成请求 ID 并存入上下文
+func withRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := ""
+	if values := md.Get(GRPCRequestIDMetadata); len(values) > 0 {
+		id = values[0]
+	}
+	if !ctxkeys.ValidRequestID(id) {
+		id = ctxkeys.NewRequestID()
+	}
+	traceparent := ""
+	if values := md.Get(traceparentHeader); len(values) > 0 {
+		traceparent = values[0]
+	}
+	ctx = logger.WithContext(ctx, requestLogger(id, traceparent))
+	return ctxkeys.WithRequestID(ctx, id), id
+}
+
+// GRPCRequestIDFromContext 从 gRPC 上下文获取请求 ID
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	string: 请求 ID，未经过 GRPCRequestID 拦截器时为空
+func GRPCRequestIDFromContext(ctx context.Context) string {
+	return ctxkeys.RequestID(ctx)
+}
+
+// GRPCLogger gRPC 访问日志一元拦截器
+// 每次调用结束后记录方法、状态码、耗时和对端地址，服务端错误记为 Error，其余失败记为 Warn
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 拦截器
+func GRPCLogger() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logGRPCCall(ctx, info.FullMethod, err, time.Since(start))
+		return resp, err
+	}
+}
+
+// GRPCStreamLogger gRPC 访问日志流拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 拦截器
+func GRPCStreamLogger() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logGRPCCall(ss.Context(), info.FullMethod, err, time.Since(start))
+		return err
+	}
+}
+
+// logGRPCCall 记录一次 gRPC 调用
+func logGRPCCall(ctx context.Context, method string, err error, latency time.Duration) {
+	code := status.Code(err)
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Duration("latency", latency),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, zap.String("ip", p.Addr.String()))
+	}
+
+	log := logger.FromContext(ctx)
+	switch code {
+	case codes.OK:
+		log.Info("gRPC 请求完成", fields...)
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded, codes.Unimplemented:
+		log.Error("gRPC 请求失败", append(fields, zap.Error(err))...)
+	default:
+		log.Warn("gRPC 请求失败", append(fields, zap.Error(err))...)
+	}
+}
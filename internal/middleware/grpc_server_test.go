@@ -0,0 +1,95 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// panicHealth 按服务名触发 panic 或返回错误的健康检查服务，并记录处理时看到的请求 ID
+type panicHealth struct {
+	healthpb.UnimplementedHealthServer
+	requestID string
+}
+
+func (h *panicHealth) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.requestID = middleware.GRPCRequestIDFromContext(ctx)
+	if req.Service == "panic" {
+		panic("boom")
+	}
+	switch req.Service {
+	case "db":
+		return nil, errors.New("pq: connection refused to 10.0.0.5")
+	case "missing":
+		return nil, errs.FromDB(gorm.ErrRecordNotFound)
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	testutil.InitLogger()
+
+	unary, stream, err := middleware.GRPCInterceptors(config.GRPCConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &panicHealth{}
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, svc)
+	}, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	client := healthpb.NewHealthClient(conn)
+
+	// 沿用调用方传入的请求 ID，并在响应 header 中返回
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-1" || svc.requestID != "req-1" {
+		t.Errorf("请求 ID: header = %v, 服务端 = %q", got, svc.requestID)
+	}
+
+	// 未传入时生成
+	header = nil
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] == "" || got[0] != svc.requestID {
+		t.Errorf("生成的请求 ID: header = %v, 服务端 = %q", got, svc.requestID)
+	}
+
+	// panic 转为 Internal，服务继续可用
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "panic"})
+	if st := status.Convert(err); st.Code() != codes.Internal || strings.Contains(st.Message(), "boom") {
+		t.Errorf("panic: %v, 期望不含 panic 内容的 Internal", err)
+	}
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Errorf("panic 之后调用失败: %v", err)
+	}
+
+	// 未分类的错误映射为 Internal，原始信息不返回给调用方；带类别的错误使用对应状态码
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "db"})
+	if st := status.Convert(err); st.Code() != codes.Internal || strings.Contains(st.Message(), "10.0.0.5") {
+		t.Errorf("未分类的错误: %v", err)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("记录不存在: 状态码 = %s, 期望 NotFound", code)
+	}
+
+	if _, _, err := middleware.GRPCInterceptors(config.GRPCConfig{Interceptors: []string{"tracing"}}); err == nil {
+		t.Error("未知的拦截器应返回错误")
+	}
+}
@@ -0,0 +1,318 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端生成的幂等键请求头（如 UUID）
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader 重放的响应带有此响应头
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// idempotencyKeyPrefix 幂等记录的 Redis 键前缀
+	idempotencyKeyPrefix = "idempotency:"
+	// maxIdempotencyKeyLength 幂等键的最大长度
+	maxIdempotencyKeyLength = 255
+	// idempotencyHandled 已由幂等中间件处理的标记，中间件同时出现在中间件链和路由上时只生效一次
+	idempotencyHandled = "idempotency_handled"
+)
+
+// idempotencyWarned 上次提示幂等记录读写失败的时间（Unix 秒），避免每个请求都打印日志
+var idempotencyWarned atomic.Int64
+
+// idempotentRecord 幂等键对应的记录，处理中时只有 Done=false
+type idempotentRecord struct {
+	Done bool `json:"done"`
+	// Fingerprint 请求体指纹（见 newBodyFingerprint），重试的请求体不同时拒绝
+	Fingerprint string      `json:"fingerprint,omitempty"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// processingRecord 处理中的记录
+var processingRecord, _ = json.Marshal(idempotentRecord{})
+
+// Idempotency 幂等请求中间件
+// POST/PUT 请求带 Idempotency-Key 头时，同一调用方（已登录为用户，未登录为客户端 IP）在同一路由上的首次请求正常处理，
+// 状态码 <500 的响应保存在 Redis 中，TTL 内以相同键重试直接重放（带 Idempotent-Replayed: true 头），不再执行处理器；
+// 首次请求处理中时重试返回 409，相同键的请求体不同时返回 422（multipart 请求体按字段和文件内容比较，与 boundary 无关）。5xx 响应和超过 max_body_size 的响应不保存，重试会再次执行。
+// 应放在认证中间件之后；Redis 不可用时不做幂等处理
+// 参数:
+//
+//	cfg: 幂等配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func Idempotency(cfg config.IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		method := c.Request.Method
+		if !cfg.Enable || key == "" || (method != http.MethodPost && method != http.MethodPut) ||
+			c.GetBool(idempotencyHandled) || cache.RedisClient == nil {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key 过长"})
+			return
+		}
+		c.Set(idempotencyHandled, true)
+
+		ctx := c.Request.Context()
+		redisKey := idempotencyRedisKey(c, key)
+		acquired, err := cache.RedisClient.SetNX(ctx, redisKey, processingRecord, cfg.GetLockTTL()).Result()
+		if err != nil {
+			warnIdempotency("读写幂等记录失败，不做幂等处理", err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			replayIdempotent(c, redisKey)
+			return
+		}
+
+		serveIdempotent(c, cfg, redisKey)
+	}
+}
+
+// idempotencyRedisKey 幂等记录的 Redis 键，按调用方、方法、路由和幂等键隔离
+func idempotencyRedisKey(c *gin.Context, key string) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	sum := sha256.Sum256([]byte(callerSubject(c) + "\n" + c.Request.Method + "\n" + route + "\n" + key))
+	return idempotencyKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// serveIdempotent 执行首次请求并保存响应
+func serveIdempotent(c *gin.Context, cfg config.IdempotencyConfig, redisKey string) {
+	// 请求可能已取消，使用独立的上下文写入或释放记录
+	ctx := context.WithoutCancel(c.Request.Context())
+	release := func() {
+		if err := cache.RedisClient.Del(ctx, redisKey).Err(); err != nil {
+			warnIdempotency("释放幂等键失败", err)
+		}
+	}
+
+	fingerprint := newBodyFingerprint(c.Request)
+	body := c.Request.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	c.Request.Body = teeBody{Reader: io.TeeReader(body, fingerprint), Closer: body}
+	before := c.Writer.Header().Clone()
+	writer := &captureWriter{ResponseWriter: c.Writer, body: &limitedBuffer{limit: cfg.GetMaxBodySize()}}
+	c.Writer = writer
+
+	completed := false
+	defer func() {
+		// 处理器 panic 时释放幂等键，重试可以再次执行
+		if !completed {
+			fingerprint.Sum()
+			release()
+		}
+	}()
+	c.Next()
+	completed = true
+
+	// 处理器未读完的请求体也计入指纹，与重试时的完整请求体比较
+	_, _ = io.Copy(io.Discard, c.Request.Body)
+
+	sum := fingerprint.Sum()
+
+	status := writer.Status()
+	if status >= http.StatusInternalServerError || writer.body.truncated {
+		if writer.body.truncated {
+			logger.Warn("响应体超过幂等记录上限，不保存", zap.String("path", c.Request.URL.Path), zap.Int("status", status))
+		}
+		release()
+		return
+	}
+
+	record := idempotentRecord{
+		Done:        true,
+		Fingerprint: sum,
+		Status:      status,
+		Header:      handlerHeaders(before, writer.Header()),
+		Body:        writer.body.Bytes(),
+	}
+	data, err := json.Marshal(record)
+	if err == nil {
+		err = cache.RedisClient.Set(ctx, redisKey, data, cfg.GetTTL()).Err()
+	}
+	if err != nil {
+		warnIdempotency("保存幂等响应失败", err)
+		release()
+	}
+}
+
+// handlerHeaders 返回处理器设置的响应头（执行前已存在且未改变的响应头由前面的中间件设置，重放时由其重新设置）
+func handlerHeaders(before, after http.Header) http.Header {
+	header := make(http.Header)
+	for name, values := range after {
+		if name == "Content-Length" || slices.Equal(before[name], values) {
+			continue
+		}
+		header[name] = values
+	}
+	return header
+}
+
+// replayIdempotent 幂等键已被占用：首次请求已完成时重放响应，处理中时返回 409
+func replayIdempotent(c *gin.Context, redisKey string) {
+	data, err := cache.RedisClient.Get(c.Request.Context(), redisKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// 首次请求刚刚失败并释放了键
+		err = nil
+		data = processingRecord
+	}
+	var record idempotentRecord
+	if err == nil {
+		err = json.Unmarshal(data, &record)
+	}
+	if err != nil {
+		warnIdempotency("读取幂等记录失败", err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "暂时无法确认请求是否已处理，请稍后重试"})
+		return
+	}
+	if !record.Done {
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "相同 Idempotency-Key 的请求正在处理，请稍后重试"})
+		return
+	}
+
+	fingerprint := newBodyFingerprint(c.Request)
+	if c.Request.Body != nil {
+		if _, err := io.Copy(fingerprint, c.Request.Body); err != nil {
+			fingerprint.Sum()
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+			return
+		}
+	}
+	if fingerprint.Sum() != record.Fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key 已用于请求体不同的请求"})
+		return
+	}
+
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// warnIdempotency 记录幂等记录读写失败，每分钟最多一条
+func warnIdempotency(msg string, err error) {
+	now := time.Now().Unix()
+	if last := idempotencyWarned.Load(); now-last >= 60 && idempotencyWarned.CompareAndSwap(last, now) {
+		logger.Warn(msg, zap.Error(err))
+	}
+}
+
+// bodyFingerprint 写入请求体后计算指纹，Sum 只能调用一次
+type bodyFingerprint interface {
+	io.Writer
+	Sum() string
+}
+
+// newBodyFingerprint 按请求的内容类型创建指纹：multipart/form-data 按各部分的字段名、文件名、类型和内容摘要计算，
+// 客户端重建请求时随机生成的 boundary 不影响指纹；其他请求体为原始字节的 SHA-256
+func newBodyFingerprint(r *http.Request) bodyFingerprint {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && mediaType == "multipart/form-data" && params["boundary"] != "" {
+		return newMultipartFingerprint(params["boundary"])
+	}
+	return rawFingerprint{Hash: sha256.New()}
+}
+
+// rawFingerprint 原始字节的 SHA-256
+type rawFingerprint struct {
+	hash.Hash
+}
+
+// Sum 返回十六进制摘要
+func (f rawFingerprint) Sum() string {
+	return hex.EncodeToString(f.Hash.Sum(nil))
+}
+
+// multipartFingerprint 边写入边解析 multipart 请求体，不缓存文件内容
+type multipartFingerprint struct {
+	pw     *io.PipeWriter
+	raw    hash.Hash
+	result chan string
+}
+
+// newMultipartFingerprint 创建 multipart 指纹，在后台 goroutine 中解析写入的内容
+func newMultipartFingerprint(boundary string) *multipartFingerprint {
+	pr, pw := io.Pipe()
+	f := &multipartFingerprint{pw: pw, raw: sha256.New(), result: make(chan string, 1)}
+	go func() {
+		sum, err := multipartDigest(multipart.NewReader(pr, boundary))
+		// 解析失败或结束后读完剩余内容，写入方不被阻塞
+		_, _ = io.Copy(io.Discard, pr)
+		if err != nil {
+			sum = ""
+		}
+		f.result <- sum
+	}()
+	return f
+}
+
+// Write 写入请求体
+func (f *multipartFingerprint) Write(p []byte) (int, error) {
+	f.raw.Write(p)
+	return f.pw.Write(p)
+}
+
+// Sum 结束写入并返回指纹，请求体不是合法的 multipart 时按原始字节计算
+func (f *multipartFingerprint) Sum() string {
+	f.pw.Close()
+	if sum := <-f.result; sum != "" {
+		return sum
+	}
+	return hex.EncodeToString(f.raw.Sum(nil))
+}
+
+// multipartDigest 按顺序摘要各部分的字段名、文件名、内容类型和内容的 SHA-256
+func multipartDigest(mr *multipart.Reader) (string, error) {
+	h := sha256.New()
+	for {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return "multipart:" + hex.EncodeToString(h.Sum(nil)), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		content := sha256.New()
+		if _, err := io.Copy(content, part); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%q %q %q %x\n", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), content.Sum(nil))
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// useIdempotencyRedis 替换 cache.RedisClient 为 miniredis
+func useIdempotencyRedis(t *testing.T) *miniredis.Miniredis {
+	logger.Logger = zap.NewNop()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	prev := cache.RedisClient
+	cache.RedisClient = client
+	t.Cleanup(func() {
+		cache.RedisClient = prev
+		client.Close()
+	})
+	return mr
+}
+
+func TestIdempotency(t *testing.T) {
+	mr := useIdempotencyRedis(t)
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int64
+	status := http.StatusCreated
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		// 前面的中间件设置的响应头不保存
+		c.Header("X-Request-ID", "req")
+		c.Next()
+	})
+	r.POST("/messages", Idempotency(config.IdempotencyConfig{Enable: true}), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		n := calls.Add(1)
+		c.Header("Location", "/messages/1")
+		c.JSON(status, gin.H{"call": n, "body": string(body)})
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/messages", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("k1", "hello")
+	replay := send("k1", "hello")
+	if first.Code != http.StatusCreated || replay.Code != http.StatusCreated || calls.Load() != 1 {
+		t.Fatalf("状态码 %d, %d, 执行 %d 次", first.Code, replay.Code, calls.Load())
+	}
+	if replay.Body.String() != first.Body.String() || replay.Header().Get("Location") != "/messages/1" {
+		t.Errorf("重放响应 = %q, %v", replay.Body.String(), replay.Header())
+	}
+	if replay.Header().Get(IdempotentReplayedHeader) != "true" || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("只有重放的响应应带 Idempotent-Replayed 头")
+	}
+	if keys := mr.Keys(); len(keys) != 1 || mr.TTL(keys[0]) != 24*time.Hour {
+		t.Errorf("幂等记录 = %v", keys)
+	} else if v, _ := mr.Get(keys[0]); strings.Contains(v, "X-Request-Id") {
+		t.Errorf("保存了前面中间件设置的响应头: %s", v)
+	}
+
+	// 相同键、不同请求体
+	if w := send("k1", "other"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("请求体不同 = %d", w.Code)
+	}
+	// 没有幂等键时每次都执行
+	send("", "a")
+	send("", "a")
+	if calls.Load() != 3 {
+		t.Errorf("无幂等键执行 %d 次, 期望 3", calls.Load())
+	}
+
+	// 5xx 不保存，重试再次执行
+	status = http.StatusBadGateway
+	send("k2", "x")
+	status = http.StatusOK
+	if w := send("k2", "x"); w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("5xx 后重试 = %d", w.Code)
+	}
+
+	// 处理中的请求
+	for _, key := range mr.Keys() {
+		mr.Del(key)
+	}
+	ctx := httptest.NewRequest(http.MethodPost, "/messages", nil)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = ctx
+	mr.Set(idempotencyRedisKey(c, "k3"), string(processingRecord))
+	if w := send("k3", ""); w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("处理中 = %d", w.Code)
+	}
+
+	if w := send(strings.Repeat("k", maxIdempotencyKeyLength+1), ""); w.Code != http.StatusBadRequest {
+		t.Errorf("过长的键 = %d", w.Code)
+	}
+}
+
+func TestIdempotencyMultipartBoundary(t *testing.T) {
+	useIdempotencyRedis(t)
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int64
+	r := gin.New()
+	r.POST("/upload", Idempotency(config.IdempotencyConfig{Enable: true}), func(c *gin.Context) {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"call": calls.Add(1), "size": file.Size})
+	})
+
+	// 每次以新的 multipart.Writer（随机 boundary）构造请求体，与客户端重试时一致
+	upload := func(key, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("name", "a")
+		part, _ := mw.CreateFormFile("file", "a.txt")
+		part.Write([]byte(content))
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := upload("u1", "hello")
+	retry := upload("u1", "hello")
+	if first.Code != http.StatusOK || retry.Code != http.StatusOK || calls.Load() != 1 {
+		t.Fatalf("状态码 %d, %d, 执行 %d 次", first.Code, retry.Code, calls.Load())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("重试未重放: %v %s", retry.Header(), retry.Body.String())
+	}
+	if w := upload("u1", "other"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("文件内容不同 = %d", w.Code)
+	}
+}
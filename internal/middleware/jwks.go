@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownKeyID 公钥集中没有令牌头指定的 kid
+var ErrUnknownKeyID = errors.New("未知的签名公钥 kid")
+
+const (
+	// jwksFetchTimeout 拉取公钥集的超时时间
+	jwksFetchTimeout = 5 * time.Second
+	// jwksMinRefresh 两次拉取公钥集的最小间隔，避免伪造 kid 的请求打满身份提供方
+	jwksMinRefresh = 10 * time.Second
+)
+
+// JWKS 远程公钥集（RFC 7517），按 kid 缓存 RSA / EC 公钥
+// 缓存过期或遇到未知 kid 时重新拉取（身份提供方轮换密钥时先发布新公钥再启用）；
+// 拉取失败时继续使用已缓存的公钥
+type JWKS struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+
+	fetchMu     sync.Mutex
+	lastAttempt time.Time
+}
+
+// NewJWKS 创建远程公钥集
+// 参数:
+//
+//	url: 公钥集地址，如 https://<keycloak>/realms/<realm>/protocol/openid-connect/certs
+//	ttl: 缓存时间
+//
+// 返回:
+//
+//	*JWKS: 公钥集
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		keys:   make(map[string]crypto.PublicKey),
+	}
+}
+
+// Key 按 kid 查找公钥
+// 参数:
+//
+//	kid: 令牌头中的密钥 ID
+//
+// 返回:
+//
+//	crypto.PublicKey: 公钥
+//	error: 找不到时返回 ErrUnknownKeyID，从未拉取成功时返回拉取错误
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	fresh := time.Since(j.fetchedAt) < j.ttl
+	j.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+
+	if err := j.refresh(context.Background(), false); err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKeyID, kid)
+}
+
+// Refresh 立即拉取公钥集（启动时预热）
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	error: 错误信息
+func (j *JWKS) Refresh(ctx context.Context) error {
+	return j.refresh(ctx, true)
+}
+
+// refresh 拉取公钥集，并发请求只拉取一次；非强制时距上次拉取不足 jwksMinRefresh 则跳过
+func (j *JWKS) refresh(ctx context.Context, force bool) error {
+	j.fetchMu.Lock()
+	defer j.fetchMu.Unlock()
+
+	if !force && time.Since(j.lastAttempt) < jwksMinRefresh {
+		return nil
+	}
+	j.lastAttempt = time.Now()
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		logger.Warn("拉取 JWKS 失败", zap.String("url", j.url), zap.Error(err))
+		return err
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.mu.Unlock()
+	return nil
+}
+
+// jwk 公钥集中的单个公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch 下载并解析公钥集，跳过加密用途和不支持的密钥类型
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS 返回状态码 %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			logger.Warn("跳过无法解析的 JWK", zap.String("kid", k.Kid), zap.Error(err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS 中没有可用的签名公钥")
+	}
+	return keys, nil
+}
+
+// publicKey 把 JWK 转换为 RSA / ECDSA 公钥
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA 指数过大")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线 %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型 %s", k.Kty)
+	}
+}
+
+// decodeBigInt 解码 base64url（无填充）编码的大整数
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("无效的 JWK 参数")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,151 @@
+package middleware_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+// useJWTConfig 测试期间替换 JWT 配置
+func useJWTConfig(t *testing.T, cfg config.JWTConfig) {
+	t.Helper()
+	c, err := middleware.LoadJWTConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := middleware.GetJWTConfig()
+	middleware.SetJWTConfig(c)
+	t.Cleanup(func() { middleware.SetJWTConfig(previous) })
+}
+
+// newAuthRouter 返回认证后输出用户信息的路由
+func newAuthRouter(t *testing.T) *gin.Engine {
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+		"api":    {"auth"},
+	}}
+	return testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/me", func(c *gin.Context) {
+			id, _ := middleware.GetUserID(c)
+			name, _ := middleware.GetUsername(c)
+			c.JSON(http.StatusOK, gin.H{"id": id, "name": name})
+		})
+	})
+}
+
+func TestJWTAuthWithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(gin.H{"keys": []gin.H{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+
+	useJWTConfig(t, config.JWTConfig{Algorithm: "RS256", Issuer: "https://idp.example.com/realms/main", JWKSURL: srv.URL})
+	router := newAuthRouter(t)
+
+	// 外部身份提供方的令牌：用户 ID 在 sub 中，用户名在 preferred_username 中
+	sign := func(method jwt.SigningMethod, kid string, signKey interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub":                "42",
+			"preferred_username": "alice",
+			"iss":                "https://idp.example.com/realms/main",
+			"exp":                time.Now().Add(time.Hour).Unix(),
+		})
+		token.Header["kid"] = kid
+		s, err := token.SignedString(signKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	request := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+		req.Header.Set("Authorization", testutil.BearerHeader(token))
+		return testutil.Do(router, req)
+	}
+
+	w := request(sign(jwt.SigningMethodRS256, "k1", key))
+	if w.Code != http.StatusOK || w.Body.String() != `{"id":42,"name":"alice"}` {
+		t.Fatalf("JWKS 公钥校验: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := request(sign(jwt.SigningMethodRS256, "unknown", key)); w.Code != http.StatusUnauthorized {
+		t.Errorf("未知的 kid: 状态码 %d, 期望 401", w.Code)
+	}
+
+	// 用公钥作为 HMAC 密钥伪造的令牌（算法混淆）必须拒绝
+	pub := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+	if w := request(sign(jwt.SigningMethodHS256, "k1", pub)); w.Code != http.StatusUnauthorized {
+		t.Errorf("HS256 伪造令牌: 状态码 %d, 期望 401", w.Code)
+	}
+
+	// 只能校验时不能签发
+	if _, err := middleware.GenerateToken(1, "bob", "user"); !errors.Is(err, middleware.ErrNoSigningKey) {
+		t.Errorf("未配置私钥时签发: err = %v, 期望 ErrNoSigningKey", err)
+	}
+}
+
+func TestLoadJWTConfigPEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	privateFile := filepath.Join(dir, "jwt.key")
+	publicFile := filepath.Join(dir, "jwt.pub")
+	os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)
+	os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+
+	// 签发方持有私钥
+	useJWTConfig(t, config.JWTConfig{Algorithm: "ES256", PrivateKeyFile: privateFile})
+	token, err := middleware.GenerateToken(7, "carol", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他服务只配置公钥即可校验
+	useJWTConfig(t, config.JWTConfig{Algorithm: "ES256", PublicKeyFile: publicFile})
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.Header.Set("Authorization", testutil.BearerHeader(token))
+	if w := testutil.Do(newAuthRouter(t), req); w.Code != http.StatusOK || w.Body.String() != `{"id":7,"name":"carol"}` {
+		t.Errorf("公钥校验: %d %s", w.Code, w.Body.String())
+	}
+
+	if _, err := middleware.LoadJWTConfig(config.JWTConfig{Algorithm: "RS256", PublicKeyFile: publicFile}); err == nil {
+		t.Error("RS256 配置 EC 公钥应返回错误")
+	}
+}
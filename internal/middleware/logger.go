@@ -1,57 +1,96 @@
 package middleware
 
 import (
-	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/errs"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/redact"
 	"go.uber.org/zap"
 )
 
 // Logger 日志中间件
-// 记录每个 HTTP 请求的详细信息
+// 记录每个 HTTP 请求的详细信息，查询参数中的凭证已隐藏；
+// 开启 request_log 时按 sample_rate 采样，在请求完成日志中附带请求体（处理器读取过的部分）和响应体，
+// 各不超过 max_body_size，凭证类字段隐藏、邮箱手机号等部分遮盖（见 redact.Payload）
+// 参数:
+//
+//	cfg: 请求日志配置
+//
 // 返回:
 //
 //	gin.HandlerFunc: Gin 中间件函数
-func Logger() gin.HandlerFunc {
+func Logger(cfg config.RequestLogConfig) gin.HandlerFunc {
+	payload := redact.NewPayload(cfg.RedactFields, true)
+	logRequest := cfg.Enable && cfg.LogRequestBody
+	logResponse := cfg.Enable && cfg.LogResponseBody
+
 	return func(c *gin.Context) {
-		// 生成请求 ID（使用时间戳+随机数）
-		requestID := generateRequestID()
-		c.Set("request_id", requestID)
+		// 复用 RequestID 中间件设置的请求 ID，未配置时在此设置
+		if ctxkeys.RequestID(c) == "" {
+			setRequestID(c)
+		}
 
 		// 记录请求开始时间
 		startTime := time.Now()
 
 		// 记录请求信息
-		logger.Info("HTTP 请求开始",
-			zap.String("request_id", requestID),
+		logger.FromContext(c).Info("HTTP 请求开始",
 			zap.String("method", c.Request.Method),
 			zap.String("path", c.Request.URL.Path),
-			zap.String("query", c.Request.URL.RawQuery),
+			zap.String("query", payload.Query(c.Request.URL.RawQuery)),
 			zap.String("ip", c.ClientIP()),
 			zap.String("user_agent", c.Request.UserAgent()),
 		)
 
+		// 采样到的请求边读写边保留请求体、响应体副本
+		var reqBody, respBody *limitedBuffer
+		if (logRequest || logResponse) && rand.Float64() < cfg.GetSampleRate() {
+			if logRequest && c.Request.Body != nil && c.Request.Body != http.NoBody {
+				reqBody = &limitedBuffer{limit: cfg.GetMaxBodySize()}
+				c.Request.Body = teeBody{Reader: io.TeeReader(c.Request.Body, reqBody), Closer: c.Request.Body}
+			}
+			if logResponse {
+				respBody = &limitedBuffer{limit: cfg.GetMaxBodySize()}
+				c.Writer = &captureWriter{ResponseWriter: c.Writer, body: respBody}
+			}
+		}
+
 		// 处理请求
 		c.Next()
 
 		// 计算请求耗时
 		latency := time.Since(startTime)
 
-		// 记录响应信息
-		logger.Info("HTTP 请求完成",
-			zap.String("request_id", requestID),
+		// 记录响应信息（认证通过的请求带有 user_id）
+		fields := []zap.Field{
 			zap.Int("status", c.Writer.Status()),
 			zap.Duration("latency", latency),
 			zap.Int("body_size", c.Writer.Size()),
-		)
+		}
+		if reqBody != nil {
+			fields = append(fields, zap.String("request_body", payload.Body(reqBody.String(), c.ContentType())))
+		}
+		if respBody != nil {
+			fields = append(fields, zap.String("response_body", payload.Body(respBody.String(), c.Writer.Header().Get("Content-Type"))))
+		}
+		if reqBody != nil && reqBody.truncated || respBody != nil && respBody.truncated {
+			fields = append(fields, zap.Bool("body_truncated", true))
+		}
+		log := logger.FromContext(c)
+		log.Info("HTTP 请求完成", fields...)
 
 		// 如果有错误，记录错误日志
 		if len(c.Errors) > 0 {
 			for _, err := range c.Errors {
-				logger.Error("请求处理错误",
-					zap.String("request_id", requestID),
+				log.Error("请求处理错误",
 					zap.Error(err),
 				)
 			}
@@ -59,36 +98,103 @@ func Logger() gin.HandlerFunc {
 	}
 }
 
+// RequestID 请求 ID 中间件
+// 沿用请求头 X-Request-ID（上游代理或客户端传入，格式不合法时忽略），没有时生成 UUIDv7；
+// 请求 ID 存入上下文（任意位置可用 ctxkeys.RequestID 读取）并写入 X-Request-ID 响应头
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setRequestID(c)
+		c.Next()
+	}
+}
+
+// setRequestID 取出或生成请求 ID，存入上下文并写入响应头，
+// 同时放入带 request_id（以及 traceparent 中的 trace_id）的日志记录器（见 logger.FromContext）
+func setRequestID(c *gin.Context) string {
+	requestID := c.GetHeader(ctxkeys.RequestIDHeader)
+	if !ctxkeys.ValidRequestID(requestID) {
+		requestID = ctxkeys.NewRequestID()
+	}
+	ctxkeys.SetRequestID(c, requestID)
+	c.Header(ctxkeys.RequestIDHeader, requestID)
+	logger.SetContext(c, requestLogger(requestID, c.GetHeader(traceparentHeader)))
+	return requestID
+}
+
+// traceparentHeader W3C Trace Context 请求头（gRPC metadata 键相同）
+const traceparentHeader = "traceparent"
+
+// requestLogger 创建请求的日志记录器
+// 参数:
+//
+//	requestID: 请求 ID
+//	traceparent: W3C traceparent 请求头，合法时取出 trace_id
+//
+// 返回:
+//
+//	*zap.Logger: 日志记录器
+func requestLogger(requestID, traceparent string) *zap.Logger {
+	fields := []zap.Field{zap.String("request_id", requestID)}
+	if traceID := parseTraceID(traceparent); traceID != "" {
+		fields = append(fields, zap.String("trace_id", traceID))
+	}
+	return logger.Logger.With(fields...)
+}
+
+// parseTraceID 从 traceparent（版本-trace_id-parent_id-标志，如 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01）
+// 取出 32 位十六进制的 trace_id，格式不合法或全为 0 时返回空
+func parseTraceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return ""
+		}
+	}
+	return parts[1]
+}
+
 // Recovery 恢复中间件
-// 捕获 panic 并记录错误日志
+// 捕获 panic 并记录带堆栈的错误日志，向客户端返回通用的 500 错误响应（带 request_id，不含 panic 内容和堆栈）；
+// 请求 ID 中间件尚未执行时在此生成。响应已开始写出时只能中止；
+// http.ErrAbortHandler 按 net/http 约定继续抛出，由服务器中断连接
 // 返回:
 //
 //	gin.HandlerFunc: Gin 中间件函数
 func Recovery() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
-			if err := recover(); err != nil {
-				requestID, _ := c.Get("request_id")
-				logger.Error("发生 panic",
-					zap.String("request_id", requestID.(string)),
-					zap.Any("error", err),
-					zap.Stack("stacktrace"),
-				)
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			if ctxkeys.RequestID(c) == "" {
+				setRequestID(c)
+			}
+			logger.FromContext(c).Error("发生 panic",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Any("error", r),
+				zap.Stack("stacktrace"),
+			)
 
-				c.JSON(500, gin.H{
-					"error": "内ductservererror",
-				})
+			if c.Writer.Written() {
 				c.Abort()
+				return
 			}
+			p := errs.NewProblem(errs.ErrInternal.GRPCStatus())
+			p.Instance = c.Request.URL.Path
+			p.RequestID = ctxkeys.RequestID(c)
+			errs.WriteProblem(c, p)
 		}()
 		c.Next()
 	}
 }
-
-// generateRequestID 生成请求 ID
-// 返回:
-//
-//	string: 请求 ID
-func generateRequestID() string {
-	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), time.Now().Unix())
-}
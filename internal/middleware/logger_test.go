@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+
+	r := gin.New()
+	r.Use(RequestID())
+	var seen string
+	r.GET("/", func(c *gin.Context) {
+		seen = ctxkeys.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(inbound string) string {
+		req := httptest.NewRequest("GET", "/", nil)
+		if inbound != "" {
+			req.Header.Set("X-Request-ID", inbound)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("X-Request-ID"); got != seen {
+			t.Errorf("响应头 %q 与上下文中的请求 ID %q 不一致", got, seen)
+		}
+		return seen
+	}
+
+	// 沿用上游传入的请求 ID
+	if id := serve("upstream-1"); id != "upstream-1" {
+		t.Errorf("应沿用传入的请求 ID, 得到 %q", id)
+	}
+	// 没有或格式不合法时生成 UUIDv7
+	for _, inbound := range []string{"", "bad id\r\nX-Injected: 1"} {
+		if id := serve(inbound); len(id) != 36 || id[14] != '7' {
+			t.Errorf("传入 %q 时应生成 UUIDv7, 得到 %q", inbound, id)
+		}
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	saved := logger.Logger
+	logger.Logger = zap.New(core)
+	defer func() { logger.Logger = saved }()
+
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		logger.FromContext(c).Info("处理请求")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("应记录 1 条日志, 得到 %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != "req-1" || fields["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("日志字段不正确: %v", fields)
+	}
+}
+
+func TestLoggerBodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	saved := logger.Logger
+	logger.Logger = zap.New(core)
+	defer func() { logger.Logger = saved }()
+
+	serve := func(cfg config.RequestLogConfig, body string) map[string]interface{} {
+		logs.TakeAll()
+		r := gin.New()
+		r.Use(Logger(cfg))
+		r.POST("/", func(c *gin.Context) {
+			io.ReadAll(c.Request.Body)
+			c.JSON(http.StatusOK, gin.H{"token": "t-1", "phone": "13812345678", "name": "alice"})
+		})
+		req := httptest.NewRequest("POST", "/?access_token=abc&page=1", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		entries := logs.FilterMessage("HTTP 请求完成").All()
+		if len(entries) != 1 {
+			t.Fatalf("应记录 1 条请求完成日志, 得到 %d", len(entries))
+		}
+		if q := logs.FilterMessage("HTTP 请求开始").All()[0].ContextMap()["query"]; q != "access_token=%5BREDACTED%5D&page=1" {
+			t.Errorf("查询参数中的凭证应隐藏, 得到 %v", q)
+		}
+		return entries[0].ContextMap()
+	}
+
+	cfg := config.RequestLogConfig{Enable: true, LogRequestBody: true, LogResponseBody: true}
+	fields := serve(cfg, `{"password":"p","email":"alice@example.com","card":"visa"}`)
+	if got := fields["request_body"]; got != `{"card":"visa","email":"a***@example.com","password":"[REDACTED]"}` {
+		t.Errorf("请求体脱敏不正确: %v", got)
+	}
+	if got := fields["response_body"]; got != `{"name":"alice","phone":"138****5678","token":"[REDACTED]"}` {
+		t.Errorf("响应体脱敏不正确: %v", got)
+	}
+	if _, ok := fields["body_truncated"]; ok {
+		t.Error("未超过上限时不应标记截断")
+	}
+
+	// 超过上限时截断，截断的 JSON 整体隐藏
+	cfg.MaxBodySize = 8
+	cfg.RedactFields = []string{"card"}
+	fields = serve(cfg, `{"card":"visa"}`)
+	if fields["body_truncated"] != true || fields["request_body"] != "[JSON 无法解析，已隐藏]" {
+		t.Errorf("超过上限时应截断并隐藏: %v", fields)
+	}
+
+	// 未采样或未开启时不记录请求体、响应体
+	for _, cfg := range []config.RequestLogConfig{
+		{Enable: true, LogRequestBody: true, LogResponseBody: true, SampleRate: 0.000001},
+		{LogRequestBody: true, LogResponseBody: true},
+	} {
+		fields = serve(cfg, `{"name":"bob"}`)
+		if _, ok := fields["request_body"]; ok {
+			t.Errorf("配置 %+v 不应记录请求体", cfg)
+		}
+		if _, ok := fields["response_body"]; ok {
+			t.Errorf("配置 %+v 不应记录响应体", cfg)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	saved := logger.Logger
+	logger.Logger = zap.New(core)
+	defer func() { logger.Logger = saved }()
+
+	// 未配置请求 ID 中间件时由 recovery 生成
+	r := gin.New()
+	r.Use(Recovery())
+	r.GET("/boom", func(c *gin.Context) {
+		panic("dsn=postgres://admin:secret@db")
+	})
+	r.GET("/partial", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	r.GET("/abort", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/boom", nil))
+	var p errs.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || p.Code != "Internal" || p.Instance != "/boom" {
+		t.Errorf("响应不正确: %d %s", w.Code, w.Body.String())
+	}
+	if p.RequestID == "" || p.RequestID != w.Header().Get(ctxkeys.RequestIDHeader) {
+		t.Errorf("响应中的请求 ID %q 与响应头 %q 不一致", p.RequestID, w.Header().Get(ctxkeys.RequestIDHeader))
+	}
+	if strings.Contains(w.Body.String(), "secret") || strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("响应泄露了 panic 内容: %s", w.Body.String())
+	}
+	entries := logs.FilterMessage("发生 panic").All()
+	if len(entries) != 1 || entries[0].ContextMap()["request_id"] != p.RequestID {
+		t.Errorf("应记录带请求 ID 的 panic 日志: %v", entries)
+	}
+
+	// 响应已写出时保持原状态码
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/partial", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Errorf("已写出的响应不应改写: %d %s", w.Code, w.Body.String())
+	}
+
+	// http.ErrAbortHandler 继续抛出
+	func() {
+		defer func() {
+			if rec := recover(); rec != http.ErrAbortHandler {
+				t.Errorf("应继续抛出 http.ErrAbortHandler, 得到 %v", rec)
+			}
+		}()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	}()
+}
+
+func TestParseTraceID(t *testing.T) {
+	tests := map[string]string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01": "",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01": "",
+		"00-4bf92f35-00f067aa0ba902b7-01":                         "",
+		"":                                                        "",
+	}
+	for in, want := range tests {
+		if got := parseTraceID(in); got != want {
+			t.Errorf("parseTraceID(%q) = %q, 期望 %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/quota"
+	"go.uber.org/zap"
+)
+
+// QuotaUsage API 配额统计中间件
+// 与 TrackActivity 相同，在请求处理完成后读取认证信息；只统计不拒绝，超过阈值时发送提醒
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func QuotaUsage() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		userID, ok := GetUserID(c)
+		if !ok {
+			return
+		}
+		if err := quota.DefaultEngine.Add(c.Request.Context(), quota.UserSubject(userID), quota.API, 1); err != nil {
+			logger.Warn("记录 API 配额失败", zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}
+}
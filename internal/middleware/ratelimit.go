@@ -1,28 +1,342 @@
 package middleware
 
 import (
+	"context"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/cache"
 	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitState 当前生效的限流配置及解析后的白名单，支持运行时替换
+var rateLimitState atomic.Pointer[rateLimitPolicy]
+
+// rateLimitPolicy 限流配置及按识别方式建立索引的白名单
+type rateLimitPolicy struct {
+	cfg      config.RateLimitConfig
+	apiKeys  map[string]*config.RateLimitClient
+	subjects map[int64]*config.RateLimitClient
+	networks []clientNetwork
+}
+
+// clientNetwork 白名单客户端的一个网段
+type clientNetwork struct {
+	prefix netip.Prefix
+	client *config.RateLimitClient
+}
+
+// newRateLimitPolicy 解析限流配置中的白名单
+func newRateLimitPolicy(cfg config.RateLimitConfig) *rateLimitPolicy {
+	p := &rateLimitPolicy{
+		cfg:      cfg,
+		apiKeys:  make(map[string]*config.RateLimitClient),
+		subjects: make(map[int64]*config.RateLimitClient),
+	}
+	for i := range cfg.Allowlist {
+		client := &cfg.Allowlist[i]
+		for _, key := range client.APIKeys {
+			p.apiKeys[key] = client
+		}
+		for _, subject := range client.Subjects {
+			p.subjects[subject] = client
+		}
+		prefixes, err := client.Prefixes()
+		if err != nil {
+			logger.Warn("忽略限流白名单中无效的 IP", zap.String("client", client.Name), zap.Error(err))
+		}
+		for _, prefix := range prefixes {
+			p.networks = append(p.networks, clientNetwork{prefix: prefix, client: client})
+		}
+	}
+	return p
+}
+
+// match 识别请求所属的白名单客户端，依次按 X-API-Key、JWT 用户 ID、客户端 IP 匹配
+// JWT 只校验签名和有效期，不检查吊销（避免每个请求访问 Redis），吊销的令牌仍会在认证时被拒绝
+func (p *rateLimitPolicy) match(c *gin.Context) *config.RateLimitClient {
+	if len(p.apiKeys) > 0 {
+		if client, ok := p.apiKeys[c.GetHeader(APIKeyHeader)]; ok {
+			return client
+		}
+	}
+
+	if len(p.subjects) > 0 {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			if claims, err := parseToken(token); err == nil && claims.TokenType != TokenTypeRefresh {
+				if client, ok := p.subjects[claims.UserID]; ok {
+					return client
+				}
+			}
+		}
+	}
+
+	if len(p.networks) > 0 {
+		if addr, err := netip.ParseAddr(c.ClientIP()); err == nil {
+			addr = addr.Unmap()
+			for _, n := range p.networks {
+				if n.prefix.Contains(addr) {
+					return n.client
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateRateLimitConfig 运行时替换限流配置
+// 参数:
+//
+//	cfg: 新的限流配置
+func UpdateRateLimitConfig(cfg config.RateLimitConfig) {
+	rateLimitState.Store(newRateLimitPolicy(cfg))
+}
+
+// CurrentRateLimitConfig 获取当前生效的限流配置
+func CurrentRateLimitConfig() config.RateLimitConfig {
+	return currentRateLimitPolicy().cfg
+}
+
+// currentRateLimitPolicy 获取当前生效的限流配置及白名单
+func currentRateLimitPolicy() *rateLimitPolicy {
+	if p := rateLimitState.Load(); p != nil {
+		return p
+	}
+	return &rateLimitPolicy{}
+}
+
+// 限流模式
+const (
+	// RateLimitModeLocal 单实例内存令牌桶
+	RateLimitModeLocal = "local"
+	// RateLimitModeRedis 基于 Redis 的 GCRA，所有网关实例共享限额
+	RateLimitModeRedis = "redis"
 )
 
+// rateLimitKeyPrefix Redis 限流键前缀
+const rateLimitKeyPrefix = "ratelimit:"
+
+// APIKeyHeader 限流白名单识别 API Key 的请求头
+const APIKeyHeader = "X-API-Key"
+
+// limiterIdleTTL 令牌桶闲置多久后被回收
+const limiterIdleTTL = 10 * time.Minute
+
+// limiterEntry 单个客户端/路由的令牌桶
+type limiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter 按键（客户端 IP + 路由）维护的令牌桶集合
+// 单实例内存限流，多实例部署时每个实例独立计数（需要共享限额时使用 redis 模式）
+type rateLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*limiterEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newRateLimiter 创建令牌桶集合
+func newRateLimiter(now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		entries:   make(map[string]*limiterEntry),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+// allow 消耗一个令牌，令牌不足时返回需要等待的时间
+// 配置变化（运行时调整）时更新已有令牌桶的速率和容量
+func (l *rateLimiter) allow(key string, cfg config.RateLimitConfig) (bool, time.Duration) {
+	now := l.now()
+	limit := rate.Limit(cfg.RequestsPerSecond)
+	burst := rateLimitBurst(cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &limiterEntry{limiter: rate.NewLimiter(limit, burst)}
+		l.entries[key] = entry
+	} else {
+		if entry.limiter.Limit() != limit {
+			entry.limiter.SetLimitAt(now, limit)
+		}
+		if entry.limiter.Burst() != burst {
+			entry.limiter.SetBurstAt(now, burst)
+		}
+	}
+	entry.lastSeen = now
+
+	r := entry.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		// 不排队等待，归还令牌并拒绝
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// rateLimitBurst 突发容量，未配置时等于每秒请求数
+func rateLimitBurst(cfg config.RateLimitConfig) int {
+	if cfg.Burst <= 0 {
+		return cfg.RequestsPerSecond
+	}
+	return cfg.Burst
+}
+
+// sweep 回收闲置的令牌桶，每分钟最多执行一次
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if now.Sub(entry.lastSeen) > limiterIdleTTL {
+			delete(l.entries, key)
+		}
+	}
+}
+
 // RateLimit 限流中间件
-// 使用简单的计数器限流（生产环境建议使用更复杂的限流算法）
+// 按客户端 IP 和路由分别限流，超限时返回 429 并设置 Retry-After。
+// 白名单（allowlist）中的客户端按其等级（tiers）限流或免于限流，超限后可消耗管理接口授予的突发额度。
+// local 模式使用实例内存中的令牌桶（golang.org/x/time/rate）；
+// redis 模式使用 Redis 中的 GCRA 状态，多个网关实例共享限额，Redis 不可用时按 redis.degradation.rate_limit
+// 退化为本地令牌桶（local）、放行（open）或拒绝（closed）
 // 参数:
 //
-//	cfg: 限流配置
+//	cfg: 初始限流配置，之后可通过 UpdateRateLimitConfig 替换
 //
 // 返回:
 //
 //	gin.HandlerFunc: Gin 中间件函数
 func RateLimit(cfg config.RateLimitConfig) gin.HandlerFunc {
+	if cfg.Mode != "" && cfg.Mode != RateLimitModeLocal && cfg.Mode != RateLimitModeRedis {
+		logger.Warn("未知的限流模式，使用本地限流", zap.String("mode", cfg.Mode))
+	}
+	return rateLimitWith(cfg, newRateLimiter(time.Now))
+}
+
+// rateLimitWith 使用指定的令牌桶集合创建限流中间件
+func rateLimitWith(cfg config.RateLimitConfig, limiter *rateLimiter) gin.HandlerFunc {
+	UpdateRateLimitConfig(cfg)
+
 	return func(c *gin.Context) {
-		if !cfg.Enable {
+		policy := currentRateLimitPolicy()
+		cfg := policy.cfg
+		if !cfg.Enable || cfg.RequestsPerSecond <= 0 {
 			c.Next()
 			return
 		}
 
-		// 这里简化处理，实际生产环境应该使用 Redis 或其他方式实现分布式限流
-		// 可以集成 golang.org/x/time/rate 包或使用 Redis 实现
+		// 白名单客户端按等级限流，同一客户端的所有 IP 共享限额
+		key := c.ClientIP()
+		client := policy.match(c)
+		if client != nil {
+			tier := cfg.Tiers[client.Tier]
+			if tier.Exempt {
+				c.Next()
+				return
+			}
+			cfg.RequestsPerSecond = tier.RequestsPerSecond
+			cfg.Burst = tier.Burst
+			key = "client:" + client.Name
+		}
+
+		// 未匹配路由的请求共用一个限额，避免随机路径为每个客户端创建无限多的限流器
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		key += "|" + c.Request.Method + " " + route
+
+		ok, wait := allowRequest(c.Request.Context(), limiter, key, cfg)
+		if !ok && client != nil {
+			ok = consumeBurstCredit(c, client.Name)
+		}
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "请求过于频繁，请稍后重试",
+			})
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// unmatchedRoute 未匹配任何路由的请求使用的限流路由键
+const unmatchedRoute = "unmatched"
+
+// creditWarned 上次提示突发额度不可用的时间（Unix 秒），避免每个请求都打印日志
+var creditWarned atomic.Int64
+
+// consumeBurstCredit 超出限流时扣减白名单客户端的突发额度（由管理接口临时授予），
+// 扣减成功则放行并通过 X-RateLimit-Credits-Remaining 返回剩余额度；Redis 不可用时按没有额度处理
+func consumeBurstCredit(c *gin.Context, client string) bool {
+	if !cache.Available() {
+		return false
+	}
+	remaining, ok, err := cache.ConsumeRateCredit(c.Request.Context(), client)
+	if err != nil {
+		now := time.Now().Unix()
+		if last := creditWarned.Load(); now-last >= 60 && creditWarned.CompareAndSwap(last, now) {
+			logger.Warn("限流突发额度不可用", zap.Error(err))
+		}
+		return false
+	}
+	if ok {
+		c.Header("X-RateLimit-Credits-Remaining", strconv.FormatInt(remaining, 10))
+	}
+	return ok
+}
+
+// redisFallbackWarned 上次提示 Redis 限流失败的时间（Unix 秒），避免每个请求都打印日志
+var redisFallbackWarned atomic.Int64
+
+// allowRequest 按配置的模式判断请求是否允许通过
+func allowRequest(ctx context.Context, local *rateLimiter, key string, cfg config.RateLimitConfig) (bool, time.Duration) {
+	if cfg.Mode == RateLimitModeRedis {
+		err := cache.ErrDegraded
+		if cache.Available() {
+			var ok bool
+			var wait time.Duration
+			ok, wait, err = cache.AllowRate(ctx, rateLimitKeyPrefix+key, float64(cfg.RequestsPerSecond), rateLimitBurst(cfg))
+			if err == nil {
+				return ok, wait
+			}
+		}
+
+		policy := cache.RateLimitPolicy()
+		now := time.Now().Unix()
+		if last := redisFallbackWarned.Load(); now-last >= 60 && redisFallbackWarned.CompareAndSwap(last, now) {
+			logger.Warn("Redis 限流失败，按降级策略处理", zap.String("policy", policy), zap.Error(err))
+		}
+		switch policy {
+		case cache.PolicyOpen:
+			return true, 0
+		case cache.PolicyClosed:
+			return false, cache.ProbeInterval()
+		}
+	}
+	return local.allow(key, cfg)
+}
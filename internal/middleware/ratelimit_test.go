@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// fakeClock 测试用可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newRateLimitRouter(cfg config.RateLimitConfig, clock *fakeClock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(rateLimitWith(cfg, newRateLimiter(clock.now)))
+	r.GET("/a", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/b", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func doRequest(r http.Handler, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitBurstAndRetryAfter(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newRateLimitRouter(config.RateLimitConfig{Enable: true, RequestsPerSecond: 1, Burst: 2}, clock)
+
+	for i := 0; i < 2; i++ {
+		if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("第 %d 个请求应在突发额度内, 得到 %d", i+1, w.Code)
+		}
+	}
+
+	w := doRequest(r, "/a", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("超出突发额度应返回 429, 得到 %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, 期望 1", got)
+	}
+
+	// 令牌按速率恢复
+	clock.t = clock.t.Add(time.Second)
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("等待后应恢复, 得到 %d", w.Code)
+	}
+}
+
+func TestRateLimitKeyedByIPAndRoute(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newRateLimitRouter(config.RateLimitConfig{Enable: true, RequestsPerSecond: 1, Burst: 1}, clock)
+
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("首个请求应通过, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("同一 IP 同一路由应被限流, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "/b", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("不同路由应独立计数, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "/a", "10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("不同 IP 应独立计数, 得到 %d", w.Code)
+	}
+
+	// 未匹配的路径共用一个限额
+	if w := doRequest(r, "/missing-1", "10.0.0.1"); w.Code != http.StatusNotFound {
+		t.Fatalf("首个未匹配请求应返回 404, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "/missing-2", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("不同的未匹配路径应共用限额, 得到 %d", w.Code)
+	}
+}
+
+func TestRateLimitDisabledAndRuntimeUpdate(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newRateLimitRouter(config.RateLimitConfig{Enable: false, RequestsPerSecond: 1, Burst: 1}, clock)
+
+	for i := 0; i < 5; i++ {
+		if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("未启用限流时不应拒绝, 得到 %d", w.Code)
+		}
+	}
+
+	UpdateRateLimitConfig(config.RateLimitConfig{Enable: true, RequestsPerSecond: 1, Burst: 1})
+	doRequest(r, "/a", "10.0.0.1")
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("运行时启用后应限流, 得到 %d", w.Code)
+	}
+
+	// 运行时调大突发额度，已有令牌桶在下次请求时扩容，之后按新容量积累令牌
+	UpdateRateLimitConfig(config.RateLimitConfig{Enable: true, RequestsPerSecond: 1, Burst: 5})
+	clock.t = clock.t.Add(time.Second)
+	doRequest(r, "/a", "10.0.0.1")
+	clock.t = clock.t.Add(5 * time.Second)
+	for i := 0; i < 5; i++ {
+		if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("调大额度后第 %d 个请求应通过, 得到 %d", i+1, w.Code)
+		}
+	}
+}
+
+func TestRateLimiterSweepsIdleEntries(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := newRateLimiter(clock.now)
+	cfg := config.RateLimitConfig{Enable: true, RequestsPerSecond: 1, Burst: 1}
+
+	l.allow("old", cfg)
+	clock.t = clock.t.Add(limiterIdleTTL + time.Minute)
+	l.allow("new", cfg)
+
+	if _, ok := l.entries["old"]; ok {
+		t.Error("闲置的令牌桶应被回收")
+	}
+	if _, ok := l.entries["new"]; !ok {
+		t.Error("活跃的令牌桶不应被回收")
+	}
+}
+
+func TestRateLimitRedisModeFallsBackToLocal(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	// Redis 未初始化，redis 模式应退化为本地令牌桶，而不是放行或拒绝所有请求
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newRateLimitRouter(config.RateLimitConfig{
+		Enable: true, RequestsPerSecond: 1, Burst: 1, Mode: RateLimitModeRedis,
+	}, clock)
+
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("首个请求应通过, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "/a", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("超出额度应返回 429, 得到 %d", w.Code)
+	}
+}
+
+func TestRateLimitAllowlistTiers(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	SetJWTConfig(NewJWTConfig(config.JWTConfig{}))
+
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	r := newRateLimitRouter(config.RateLimitConfig{
+		Enable: true, RequestsPerSecond: 1, Burst: 1,
+		Tiers: map[string]config.RateLimitTier{
+			"partner":  {RequestsPerSecond: 1, Burst: 3},
+			"internal": {Exempt: true},
+		},
+		Allowlist: []config.RateLimitClient{
+			{Name: "acme", Tier: "partner", APIKeys: []string{"acme-key"}, IPs: []string{"203.0.113.0/24"}},
+			{Name: "ops", Tier: "internal", Subjects: []int64{7}},
+		},
+	}, clock)
+
+	send := func(ip string, header, value string) int {
+		req := httptest.NewRequest(http.MethodGet, "/a", nil)
+		req.RemoteAddr = ip + ":12345"
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 网段内不同 IP 属于同一客户端，共享等级额度
+	for i, ip := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		if code := send(ip, "", ""); code != http.StatusOK {
+			t.Fatalf("第 %d 个请求应在等级额度内, 得到 %d", i+1, code)
+		}
+	}
+	if code := send("203.0.113.4", "", ""); code != http.StatusTooManyRequests {
+		t.Errorf("客户端超出等级额度应返回 429, 得到 %d", code)
+	}
+	if code := send("198.51.100.1", APIKeyHeader, "acme-key"); code != http.StatusTooManyRequests {
+		t.Errorf("API Key 应识别为同一客户端, 得到 %d", code)
+	}
+
+	// 未命中白名单仍按默认额度
+	send("198.51.100.1", APIKeyHeader, "wrong")
+	if code := send("198.51.100.1", APIKeyHeader, "wrong"); code != http.StatusTooManyRequests {
+		t.Errorf("未知的 API Key 应按默认额度限流, 得到 %d", code)
+	}
+
+	// 免限流等级按 JWT 用户 ID 识别
+	token, err := GenerateToken(7, "ops", "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if code := send("198.51.100.9", "Authorization", "Bearer "+token); code != http.StatusOK {
+			t.Fatalf("免限流客户端第 %d 个请求被拒绝: %d", i+1, code)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(SecurityHeaders(config.SecurityHeadersConfig{
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'none'",
+		CSPReportOnly:         true,
+	}))
+	r.GET("/", func(c *gin.Context) { c.AbortWithStatus(http.StatusNotFound) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	want := map[string]string{
+		"X-Content-Type-Options":              "nosniff",
+		"X-Frame-Options":                     "DENY",
+		"Referrer-Policy":                     "strict-origin-when-cross-origin",
+		"Strict-Transport-Security":           "max-age=31536000; includeSubDomains",
+		"Content-Security-Policy-Report-Only": "default-src 'none'",
+		"Content-Security-Policy":             "",
+	}
+	for name, value := range want {
+		if got := w.Header().Get(name); got != value {
+			t.Errorf("%s = %q, 期望 %q", name, got, value)
+		}
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Logger = zap.NewNop()
+
+	r := gin.New()
+	r.Use(CSRF(config.CSRFConfig{Enable: true, ExemptPaths: []string{"/hooks/"}}))
+	r.GET("/form", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/submit", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/hooks/pay", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// 安全方法下发令牌
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != "csrf_token" || !validCSRFToken(cookies[0].Value) {
+		t.Fatalf("应下发令牌 Cookie: %d %v", w.Code, cookies)
+	}
+	token := cookies[0]
+
+	post := func(path, header string, withCookie bool, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.AddCookie(&http.Cookie{Name: "session", Value: "s-1"})
+		if withCookie {
+			req.AddCookie(token)
+		}
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		header        string
+		withCookie    bool
+		authorization string
+		want          int
+	}{
+		{"令牌一致", "/submit", token.Value, true, "", http.StatusOK},
+		{"缺少请求头", "/submit", "", true, "", http.StatusForbidden},
+		{"令牌不一致", "/submit", newCSRFToken(), true, "", http.StatusForbidden},
+		{"缺少令牌 Cookie", "/submit", token.Value, false, "", http.StatusForbidden},
+		{"Bearer 认证不校验", "/submit", "", false, "Bearer t", http.StatusOK},
+		{"豁免路径", "/hooks/pay", "", false, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := post(tt.path, tt.header, tt.withCookie, tt.authorization); w.Code != tt.want {
+			t.Errorf("%s: 状态码 = %d, 期望 %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	// 没有 Cookie 的请求不依赖 Cookie 会话，不校验
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/submit", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("无 Cookie 请求: 状态码 = %d", w.Code)
+	}
+}
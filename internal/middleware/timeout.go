@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// timeoutWriter 超时后丢弃处理器写出的响应，由 Timeout 改为返回 504
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+// expired 截止时间已过且响应尚未开始写出时返回 true，此后处理器的写出全部丢弃
+func (w *timeoutWriter) expired() bool {
+	if !w.timedOut && !w.ResponseWriter.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+// WriteHeaderNow 超时后不写出状态码
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.expired() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Write 超时后丢弃
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 超时后丢弃
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.expired() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Timeout 请求超时中间件
+// 按路由（middleware.timeout.routes，未配置时为 default）为请求上下文设置截止时间，处理器把 c.Request.Context()
+// 传给数据库、Redis、S3 和 gRPC 调用时，超时后这些调用随之取消；截止时间已过而响应尚未开始写出时，
+// 丢弃处理器的响应并返回 504（application/problem+json）。已开始写出的响应（如下载）不受影响。
+// 处理器在同一个 goroutine 中执行，不感知上下文的处理器会一直执行到返回
+// 参数:
+//
+//	cfg: 超时配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func Timeout(cfg config.TimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := cfg.GetTimeout(c.Request.Method, c.FullPath())
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = writer
+		// 处理器 panic 时同样恢复原始 Writer，recovery 的 500 响应才能写出
+		defer func() { c.Writer = writer.ResponseWriter }()
+		c.Next()
+
+		if writer.expired() {
+			logger.FromContext(c).Warn("请求超时",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.Duration("timeout", timeout),
+			)
+			c.Writer = writer.ResponseWriter
+			errs.Write(c, context.DeadlineExceeded)
+		}
+	}
+}
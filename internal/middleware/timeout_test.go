@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+func TestTimeout(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Timeout(config.TimeoutConfig{
+		Default: 1,
+		Routes: []config.RouteTimeoutConfig{
+			{Path: "/slow", Timeout: 0},
+			{Method: "GET", Path: "/ctx", Timeout: 0},
+		},
+	}))
+	r.GET("/ctx", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("GET /ctx 覆盖为不限制")
+		}
+		c.Status(http.StatusOK)
+	})
+	r.POST("/ctx", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); !ok {
+			t.Error("POST /ctx 应使用默认超时")
+		}
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/slow", func(c *gin.Context) {
+		if _, ok := c.Request.Context().Deadline(); ok {
+			t.Error("timeout 为 0 的路由不应设置截止时间")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	// 路由覆盖为 0，不设置截止时间
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /slow = %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ctx", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /ctx = %d", w.Code)
+	}
+	// 按方法匹配，POST 使用默认值
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ctx", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("POST /ctx = %d", w.Code)
+	}
+}
+
+func TestTimeoutExceeded(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Timeout(config.TimeoutConfig{Routes: []config.RouteTimeoutConfig{{Path: "/wait", Timeout: 1}}}))
+	r.GET("/wait", func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "late"})
+	})
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/wait", nil))
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("耗时 %v, 上下文未在超时后取消", elapsed)
+	}
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("状态码 = %d, 期望 504", w.Code)
+	}
+	var p errs.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Code != "DeadlineExceeded" || p.Status != http.StatusGatewayTimeout {
+		t.Errorf("响应体 = %s, %v", w.Body.String(), err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != errs.ProblemContentType+"; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
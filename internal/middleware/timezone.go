@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/timezone"
+	"go.uber.org/zap"
+)
+
+// TimezoneLookup 查询用户的时区偏好，返回空字符串表示未设置
+type TimezoneLookup func(ctx context.Context, userID int64) (string, error)
+
+// timezoneLookup 用户时区偏好查询函数，由用户模块在注册路由时设置
+var timezoneLookup TimezoneLookup
+
+// SetTimezoneLookup 设置用户时区偏好查询函数（启动时调用）
+// 参数:
+//
+//	lookup: 查询函数
+func SetTimezoneLookup(lookup TimezoneLookup) {
+	timezoneLookup = lookup
+}
+
+// localizeWriter 只缓存 JSON 响应体，文件下载、流式输出等其他响应直接写出
+type localizeWriter struct {
+	gin.ResponseWriter
+	body    *bytes.Buffer
+	decided bool
+	capture bool
+}
+
+// Write 首次写入时按 Content-Type 决定是否缓存
+func (w *localizeWriter) Write(data []byte) (int, error) {
+	if w.capturing() {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 首次写入时按 Content-Type 决定是否缓存
+func (w *localizeWriter) WriteString(s string) (int, error) {
+	if w.capturing() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capturing 返回当前响应是否需要缓存
+func (w *localizeWriter) capturing() bool {
+	if !w.decided {
+		w.decided = true
+		w.capture = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	return w.capture
+}
+
+// Localize 响应时间戳本地化中间件
+// 时间统一以 UTC 存储，JSON 响应中的时间戳（*_at、timestamp 字段）按请求头 X-Timezone、
+// 当前用户的时区偏好、默认时区的顺序选择时区输出，并在响应头 X-Timezone 中回显；
+// 在请求处理完成后读取认证信息，因此可放在 auth 中间件之前
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &localizeWriter{ResponseWriter: original, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = original
+		if !writer.capture {
+			return
+		}
+
+		body := writer.body.Bytes()
+		loc := requestLocation(c)
+		localized, err := timezone.LocalizeJSON(body, loc)
+		if err != nil {
+			logger.FromContext(c).Warn("转换响应时区失败",
+				zap.Error(err),
+			)
+		} else {
+			body = localized
+			original.Header().Set(timezone.Header, loc.String())
+		}
+
+		if _, err := original.Write(body); err != nil {
+			logger.Error("写入响应失败", zap.Error(err))
+		}
+	}
+}
+
+// requestLocation 选择请求的响应时区：请求头 > 用户偏好 > 默认时区
+func requestLocation(c *gin.Context) *time.Location {
+	if loc, err := timezone.Load(c.GetHeader(timezone.Header)); err == nil {
+		return loc
+	}
+
+	userID, ok := GetUserID(c)
+	if !ok || timezoneLookup == nil {
+		return timezone.Default
+	}
+	name, err := timezoneLookup(c.Request.Context(), userID)
+	if err != nil {
+		logger.FromContext(c).Warn("查询用户时区偏好失败",
+			zap.Int64("user_id", userID),
+			zap.Error(err),
+		)
+	}
+	return timezone.Resolve(name)
+}
@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/middleware"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func TestLocalize(t *testing.T) {
+	clock := testutil.NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	minter := testutil.UseJWT(t, testutil.DefaultSecret, clock)
+
+	middleware.SetTimezoneLookup(func(ctx context.Context, userID int64) (string, error) {
+		if userID == 1 {
+			return "Asia/Tokyo", nil
+		}
+		return "", nil
+	})
+	t.Cleanup(func() { middleware.SetTimezoneLookup(nil) })
+
+	cfg := config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+		"api":    {"timezone"},
+	}}
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	router := testutil.NewGinEngine(t, cfg, func(r *gin.RouterGroup) {
+		r.GET("/item", middleware.OptionalJWTAuth(), func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"id": 1, "created_at": createdAt})
+		})
+		r.GET("/raw", func(c *gin.Context) {
+			c.String(http.StatusOK, createdAt.Format(time.RFC3339))
+		})
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		userID   int64
+		expected string
+		zone     string
+	}{
+		{"默认 UTC", "", 0, "2024-01-02T03:04:05Z", "UTC"},
+		{"请求头", "Europe/Berlin", 1, "2024-01-02T04:04:05+01:00", "Europe/Berlin"},
+		{"用户偏好", "", 1, "2024-01-02T12:04:05+09:00", "Asia/Tokyo"},
+		{"无效请求头回退到用户偏好", "GMT+8", 1, "2024-01-02T12:04:05+09:00", "Asia/Tokyo"},
+		{"未设置偏好", "", 2, "2024-01-02T03:04:05Z", "UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/item", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Timezone", tt.header)
+			}
+			if tt.userID != 0 {
+				req.Header.Set("Authorization", testutil.BearerHeader(minter.MustMint(t, tt.userID, "user", time.Hour)))
+			}
+			w := testutil.Do(router, req)
+			if body := `{"created_at":"` + tt.expected + `","id":1}`; w.Body.String() != body {
+				t.Errorf("响应 = %s, 期望 %s", w.Body.String(), body)
+			}
+			if zone := w.Header().Get("X-Timezone"); zone != tt.zone {
+				t.Errorf("X-Timezone = %q, 期望 %q", zone, tt.zone)
+			}
+		})
+	}
+
+	// 非 JSON 响应原样输出
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/raw", nil)
+	req.Header.Set("X-Timezone", "Asia/Tokyo")
+	if w := testutil.Do(router, req); w.Body.String() != "2024-01-02T03:04:05Z" || w.Header().Get("X-Timezone") != "" {
+		t.Errorf("非 JSON 响应被修改: %s", w.Body.String())
+	}
+}
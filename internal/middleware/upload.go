@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/quota"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// uploadSlotKeyPrefix 上传并发槽位的 Redis 键前缀
+const uploadSlotKeyPrefix = "upload:slots:"
+
+// throttleChunk 限速读取时单次读取的最大字节数，同时作为令牌桶容量
+const throttleChunk = 32 * 1024
+
+// uploadSlotWarned 上次提示获取上传槽位失败的时间（Unix 秒），避免每个请求都打印日志
+var uploadSlotWarned atomic.Int64
+
+// UploadLimit 上传限制中间件
+// 按用户（未登录按客户端 IP）限制同时进行的上传数，并限制读取请求体的速率；
+// 角色可在 tiers 中覆盖默认限制。需放在认证中间件之后，Redis 不可用时不限制并发
+// 参数:
+//
+//	cfg: 上传限制配置
+//
+// 返回:
+//
+//	gin.HandlerFunc: Gin 中间件函数
+func UploadLimit(cfg config.UploadLimitsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enable {
+			c.Next()
+			return
+		}
+
+		role, _ := GetUserRole(c)
+		maxConcurrent, bytesPerSecond := cfg.ForRole(role)
+
+		if maxConcurrent > 0 {
+			key := uploadSlotKeyPrefix + callerSubject(c)
+			token, ok, err := cache.AcquireSemaphore(c.Request.Context(), key, maxConcurrent, cfg.GetSlotTTL())
+			switch {
+			case err != nil:
+				now := time.Now().Unix()
+				if last := uploadSlotWarned.Load(); now-last >= 60 && uploadSlotWarned.CompareAndSwap(last, now) {
+					logger.Warn("获取上传槽位失败，不限制并发上传", zap.Error(err))
+				}
+			case !ok:
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error": "同时进行的上传过多，请等待其他上传完成后重试",
+				})
+				return
+			default:
+				defer func() {
+					// 请求可能已取消，使用独立的上下文释放槽位
+					if err := cache.ReleaseSemaphore(context.Background(), key, token); err != nil {
+						logger.Warn("释放上传槽位失败", zap.String("key", key), zap.Error(err))
+					}
+				}()
+			}
+		}
+
+		if bytesPerSecond > 0 {
+			c.Request.Body = newThrottledReader(c.Request.Context(), c.Request.Body, bytesPerSecond)
+		}
+
+		c.Next()
+	}
+}
+
+// callerSubject 按调用方限制或隔离的主体（上传并发、幂等键）：已登录为用户，未登录为客户端 IP
+func callerSubject(c *gin.Context) string {
+	if userID, ok := GetUserID(c); ok {
+		return quota.UserSubject(userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// throttledReader 按令牌桶限制读取速率的 Reader
+type throttledReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+	chunk   int
+}
+
+// newThrottledReader 创建限速 Reader
+// 参数:
+//
+//	ctx: 上下文，取消后等待中的读取立即返回错误
+//	r: 原始 Reader
+//	bytesPerSecond: 每秒字节数
+//
+// 返回:
+//
+//	*throttledReader: 限速 Reader
+func newThrottledReader(ctx context.Context, r io.ReadCloser, bytesPerSecond int64) *throttledReader {
+	chunk := int(min(bytesPerSecond, throttleChunk))
+	return &throttledReader{
+		ReadCloser: r,
+		ctx:        ctx,
+		limiter:    rate.NewLimiter(rate.Limit(bytesPerSecond), chunk),
+		chunk:      chunk,
+	}
+}
+
+// Read 每次最多读取一个令牌桶容量，读到的字节数从令牌桶中扣除，不足时等待
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > r.chunk {
+		p = p[:r.chunk]
+	}
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
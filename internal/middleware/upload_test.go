@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestThrottledReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 30000)
+	r := newThrottledReader(context.Background(), io.NopCloser(bytes.NewReader(data)), 20000)
+
+	start := time.Now()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if len(got) != len(data) {
+		t.Fatalf("读取 %d 字节, 期望 %d", len(got), len(data))
+	}
+	// 令牌桶初始容量 20000 字节，其余 10000 字节按 20000 字节/秒需约 0.5 秒
+	if elapsed < 400*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("耗时 %v, 期望约 500ms", elapsed)
+	}
+}
+
+func TestThrottledReaderCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newThrottledReader(ctx, io.NopCloser(bytes.NewReader(make([]byte, 10000))), 1000)
+
+	buf := make([]byte, 1000)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := r.Read(buf); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, 期望 context.Canceled", err)
+	}
+}
+
+func TestUploadLimitsForRole(t *testing.T) {
+	cfg := config.UploadLimitsConfig{
+		MaxConcurrent:  3,
+		BytesPerSecond: 1000,
+		Tiers: map[string]config.UploadTierConfig{
+			"admin": {MaxConcurrent: 10, BytesPerSecond: -1},
+			"vip":   {BytesPerSecond: 5000},
+		},
+	}
+	tests := []struct {
+		role          string
+		maxConcurrent int
+		bps           int64
+	}{
+		{"", 3, 1000},
+		{"user", 3, 1000},
+		{"admin", 10, -1},
+		{"vip", 3, 5000},
+	}
+	for _, tt := range tests {
+		n, bps := cfg.ForRole(tt.role)
+		if n != tt.maxConcurrent || bps != tt.bps {
+			t.Errorf("ForRole(%q) = %d, %d, 期望 %d, %d", tt.role, n, bps, tt.maxConcurrent, tt.bps)
+		}
+	}
+}
+
+func TestUploadLimitThrottlesBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/upload", UploadLimit(config.UploadLimitsConfig{Enable: true, BytesPerSecond: 1000}), func(c *gin.Context) {
+		if _, ok := c.Request.Body.(*throttledReader); !ok {
+			t.Error("请求体未被限速")
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", bytes.NewReader([]byte("a"))))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("状态码 = %d", w.Code)
+	}
+}
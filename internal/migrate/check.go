@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/plugin/dbresolver"
+)
+
+var (
+	// ErrSchemaNotInitialized 数据库中没有表结构版本记录（尚未执行迁移）
+	ErrSchemaNotInitialized = errors.New("表结构尚未初始化")
+	// ErrSchemaDirty 上次迁移中途失败
+	ErrSchemaDirty = errors.New("表结构迁移未完成")
+	// ErrSchemaOutdated 表结构版本低于代码要求
+	ErrSchemaOutdated = errors.New("表结构版本过旧")
+	// ErrSchemaTooNew 表结构已被更新的代码迁移，且不兼容当前代码
+	ErrSchemaTooNew = errors.New("表结构版本过新")
+)
+
+// applied 数据库中的表结构版本
+type applied struct {
+	Version    uint
+	Dirty      bool
+	MinVersion uint
+}
+
+// checkCompatible 判断当前代码（版本 Version）能否在已应用的表结构上运行
+func checkCompatible(a *applied) error {
+	switch {
+	case a == nil:
+		return fmt.Errorf("%w: 请先执行 migrate up（代码要求版本 %d）", ErrSchemaNotInitialized, Version)
+	case a.Dirty:
+		return fmt.Errorf("%w: 版本 %d 的迁移中途失败，修复表结构后执行 migrate force 确认版本", ErrSchemaDirty, a.Version)
+	case a.Version < Version:
+		return fmt.Errorf("%w: 数据库为版本 %d，代码要求版本 %d，请先执行 migrate up",
+			ErrSchemaOutdated, a.Version, Version)
+	case a.MinVersion > Version:
+		return fmt.Errorf("%w: 数据库为版本 %d，要求代码版本不低于 %d，当前代码为版本 %d，请部署新版本",
+			ErrSchemaTooNew, a.Version, a.MinVersion, Version)
+	}
+	return nil
+}
+
+// readApplied 从主库读取已应用的版本，版本表不存在或为空时返回 nil
+func readApplied(ctx context.Context) (*applied, error) {
+	db := database.DB.WithContext(ctx).Clauses(dbresolver.Write)
+
+	if !db.Migrator().HasTable(VersionTable) {
+		return nil, nil
+	}
+
+	var rows []applied
+	if err := db.Raw("SELECT version, dirty FROM " + VersionTable + " LIMIT 1").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询表结构版本失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	a := &rows[0]
+
+	// 初始迁移执行失败时 schema_compat 可能不存在，此时 dirty 已使检查失败
+	if !a.Dirty {
+		if err := db.Raw("SELECT min_version FROM schema_compat").Scan(&a.MinVersion).Error; err != nil {
+			return nil, fmt.Errorf("查询表结构兼容版本失败: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// CheckSchema 确认数据库的表结构版本与当前代码兼容，各服务启动时调用（迁移由 cmd/migrate 执行）
+// 表结构版本低于 Version（尚未迁移）、迁移未完成，或高于且不兼容当前代码（部分部署）时返回错误，服务应拒绝启动
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	error: 不兼容时返回 ErrSchemaNotInitialized、ErrSchemaDirty、ErrSchemaOutdated 或 ErrSchemaTooNew
+func CheckSchema(ctx context.Context) error {
+	a, err := readApplied(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkCompatible(a); err != nil {
+		return err
+	}
+	if a.Version > Version {
+		logger.Warn("表结构已由新版本迁移，当前代码兼容该版本",
+			zap.Uint("数据库版本", a.Version),
+			zap.Uint("代码版本", Version),
+		)
+	}
+	return nil
+}
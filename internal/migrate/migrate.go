@@ -0,0 +1,257 @@
+package migrate
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// migrations 内嵌的 SQL 迁移文件，按驱动分目录（migrations/postgres、mysql、sqlite），
+// 命名为 {版本}_{描述}.up.sql / .down.sql（版本为 6 位数字，依次加一），各驱动的版本一一对应
+//
+//go:embed migrations
+var migrations embed.FS
+
+// openSource 打开驱动对应的迁移目录
+func openSource(driver string) (source.Driver, error) {
+	return iofs.New(migrations, "migrations/"+driver)
+}
+
+// VersionTable 记录已应用版本的表（golang-migrate 格式：version、dirty）
+const VersionTable = "schema_versions"
+
+// Version 当前代码内嵌的最新迁移版本，也是代码要求的表结构版本
+var Version = mustLatestVersion()
+
+// mustLatestVersion 读取内嵌迁移（以 postgres 为准）的最新版本，文件命名错误时 panic
+func mustLatestVersion() uint {
+	src, err := openSource(database.DriverPostgres)
+	if err != nil {
+		panic(fmt.Sprintf("读取内嵌迁移失败: %v", err))
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		panic(fmt.Sprintf("读取内嵌迁移失败: %v", err))
+	}
+	for {
+		next, err := src.Next(version)
+		if err != nil {
+			return version
+		}
+		version = next
+	}
+}
+
+// Migrator 表结构迁移执行器，由 cmd/migrate 使用
+type Migrator struct {
+	m      *gomigrate.Migrate
+	driver string
+}
+
+// New 连接主库并创建迁移执行器，按 database.driver 执行对应目录中的迁移
+// 多个执行器同时运行时通过数据库锁（Postgres advisory lock、MySQL GET_LOCK）互斥，
+// 等待超过 database.migration.lock_timeout 时返回错误
+// 参数:
+//
+//	cfg: 数据库配置
+//
+// 返回:
+//
+//	*Migrator: 迁移执行器
+//	error: 错误信息
+func New(cfg config.DatabaseConfig) (*Migrator, error) {
+	name := cfg.GetDriver()
+	driver, err := openDriver(name, cfg.GetDatabaseDSN())
+	if err != nil {
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+	src, err := openSource(name)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("读取内嵌迁移失败: %w", err)
+	}
+	m, err := gomigrate.NewWithInstance("iofs", src, name, driver)
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m.Log = migrateLogger{}
+	m.LockTimeout = cfg.Migration.GetLockTimeout()
+	return &Migrator{m: m, driver: name}, nil
+}
+
+// openDriver 按驱动连接数据库并创建 golang-migrate 的数据库驱动
+func openDriver(name, dsn string) (migratedb.Driver, error) {
+	switch name {
+	case database.DriverMySQL:
+		// 迁移文件包含多条语句
+		mc, err := gomysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		mc.MultiStatements = true
+		conn, err := sql.Open("mysql", mc.FormatDSN())
+		if err != nil {
+			return nil, err
+		}
+		return closeOnError(conn)(mysql.WithInstance(conn, &mysql.Config{MigrationsTable: VersionTable}))
+	case database.DriverSQLite:
+		conn, err := sql.Open("sqlite3", dsn)
+		if err != nil {
+			return nil, err
+		}
+		return closeOnError(conn)(sqlite3.WithInstance(conn, &sqlite3.Config{MigrationsTable: VersionTable}))
+	}
+	conn, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return closeOnError(conn)(pgx.WithInstance(conn, &pgx.Config{MigrationsTable: VersionTable}))
+}
+
+// closeOnError 创建驱动失败时关闭连接
+func closeOnError(conn *sql.DB) func(migratedb.Driver, error) (migratedb.Driver, error) {
+	return func(driver migratedb.Driver, err error) (migratedb.Driver, error) {
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return driver, nil
+	}
+}
+
+// Up 执行未应用的迁移
+// 参数:
+//
+//	steps: 最多执行的迁移数，0 表示全部
+//
+// 返回:
+//
+//	error: 错误信息，没有待执行的迁移时返回 nil
+func (m *Migrator) Up(steps int) error {
+	var err error
+	if steps > 0 {
+		err = m.m.Steps(steps)
+	} else {
+		err = m.m.Up()
+	}
+	return ignoreNoChange(err)
+}
+
+// Down 回滚已应用的迁移
+// 参数:
+//
+//	steps: 回滚的迁移数，必须大于 0（回滚全部迁移会删除所有表）
+//
+// 返回:
+//
+//	error: 错误信息
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return errors.New("回滚步数必须大于 0")
+	}
+	return ignoreNoChange(m.m.Steps(-steps))
+}
+
+// Force 把版本记录设为指定版本并清除 dirty 标记，不执行任何迁移
+// 迁移中途失败（dirty）时，手工修复表结构后用于确认实际所处的版本
+// 参数:
+//
+//	version: 版本，-1 表示没有应用任何迁移
+//
+// 返回:
+//
+//	error: 错误信息
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Status 迁移状态
+type Status struct {
+	// Version 已应用的版本，0 表示尚未迁移
+	Version uint `json:"version"`
+	// Dirty 上次迁移中途失败，需要修复后执行 force
+	Dirty bool `json:"dirty"`
+	// Latest 代码内嵌的最新版本
+	Latest uint `json:"latest"`
+	// Pending 待执行的迁移（版本_描述）
+	Pending []string `json:"pending"`
+}
+
+// Status 查询迁移状态
+// 返回:
+//
+//	Status: 迁移状态
+//	error: 错误信息
+func (m *Migrator) Status() (Status, error) {
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, gomigrate.ErrNilVersion) {
+		return Status{}, err
+	}
+	status := Status{Version: version, Dirty: dirty, Latest: Version, Pending: []string{}}
+
+	src, err := openSource(m.driver)
+	if err != nil {
+		return Status{}, err
+	}
+	defer src.Close()
+	for v, err := src.First(); err == nil; v, err = src.Next(v) {
+		if v <= version {
+			continue
+		}
+		r, identifier, err := src.ReadUp(v)
+		if err != nil {
+			return Status{}, err
+		}
+		r.Close()
+		status.Pending = append(status.Pending, fmt.Sprintf("%06d_%s", v, identifier))
+	}
+	return status, nil
+}
+
+// Close 关闭数据库连接
+// 返回:
+//
+//	error: 错误信息
+func (m *Migrator) Close() error {
+	_, err := m.m.Close()
+	return err
+}
+
+// ignoreNoChange 没有需要执行的迁移不视为错误
+func ignoreNoChange(err error) error {
+	if errors.Is(err, gomigrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrateLogger 把 golang-migrate 的日志写入 zap
+type migrateLogger struct{}
+
+// Printf 记录迁移进度
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	logger.Info("数据库迁移", zap.String("进度", strings.TrimSpace(fmt.Sprintf(format, v...))))
+}
+
+// Verbose 不输出详细日志
+func (migrateLogger) Verbose() bool {
+	return false
+}
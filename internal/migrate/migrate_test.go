@@ -0,0 +1,79 @@
+package migrate
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/zhang/microservice/internal/database"
+)
+
+func TestMigrationsArePaired(t *testing.T) {
+	pattern := regexp.MustCompile(`^(\d{6})_[a-z0-9_]+\.(up|down)\.sql$`)
+	for _, driver := range []string{database.DriverPostgres, database.DriverMySQL, database.DriverSQLite} {
+		names, err := fs.Glob(migrations, "migrations/"+driver+"/*.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ups := map[string]bool{}
+		downs := map[string]bool{}
+		for _, path := range names {
+			name := strings.TrimPrefix(path, "migrations/"+driver+"/")
+			m := pattern.FindStringSubmatch(name)
+			if m == nil {
+				t.Fatalf("迁移文件 %s 命名不符合 {版本}_{描述}.up|down.sql", path)
+			}
+			key := strings.TrimSuffix(strings.TrimSuffix(name, ".up.sql"), ".down.sql")
+			if m[2] == "up" {
+				ups[key] = true
+			} else {
+				downs[key] = true
+			}
+		}
+		for key := range ups {
+			if !downs[key] {
+				t.Errorf("%s 迁移 %s 缺少 down 文件", driver, key)
+			}
+		}
+		for key := range downs {
+			if !ups[key] {
+				t.Errorf("%s 迁移 %s 缺少 up 文件", driver, key)
+			}
+		}
+		// 各驱动的版本一一对应，Version 从 1 起依次加一
+		if Version != uint(len(ups)) {
+			t.Errorf("Version = %d，%s 迁移数为 %d", Version, driver, len(ups))
+		}
+	}
+}
+
+func TestCheckCompatible(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied *applied
+		want    error
+	}{
+		{"未迁移", nil, ErrSchemaNotInitialized},
+		{"迁移未完成", &applied{Version: Version, Dirty: true}, ErrSchemaDirty},
+		{"版本过旧", &applied{Version: Version - 1}, ErrSchemaOutdated},
+		{"当前版本", &applied{Version: Version, MinVersion: 1}, nil},
+		{"更新且兼容", &applied{Version: Version + 1, MinVersion: Version}, nil},
+		{"更新且不兼容", &applied{Version: Version + 1, MinVersion: Version + 1}, ErrSchemaTooNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCompatible(tt.applied)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("期望兼容，得到 %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("期望 %v，得到 %v", tt.want, err)
+			}
+		})
+	}
+}
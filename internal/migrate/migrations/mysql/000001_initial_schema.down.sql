@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS schema_compat;
+DROP TABLE IF EXISTS xact_locks;
+DROP TABLE IF EXISTS user_settings;
+DROP TABLE IF EXISTS file_refs;
+DROP TABLE IF EXISTS file_objects;
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS runtime_settings;
+DROP TABLE IF EXISTS users;
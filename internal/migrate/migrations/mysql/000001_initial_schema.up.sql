@@ -0,0 +1,94 @@
+-- 初始表结构（MySQL 8.0+），与 postgres/000001_initial_schema.up.sql 对应
+-- key 是保留字，需加反引号；对象 Key 使用 varbinary（按字节比较，utf8mb4 下 varchar(1024) 超出索引长度上限）
+CREATE TABLE users (
+    id             bigint AUTO_INCREMENT PRIMARY KEY,
+    name           varchar(100) NOT NULL,
+    email          varchar(100) NOT NULL,
+    phone          varchar(20),
+    created_at     datetime(6),
+    updated_at     datetime(6),
+    email_verified boolean NOT NULL DEFAULT false,
+    phone_verified boolean NOT NULL DEFAULT false,
+    last_seen_at   datetime(6),
+    password_hash  varchar(255),
+    role           varchar(20) NOT NULL DEFAULT 'user',
+    timezone       varchar(64),
+    UNIQUE INDEX idx_users_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE runtime_settings (
+    `key`      varchar(100) PRIMARY KEY,
+    value      text NOT NULL,
+    updated_by varchar(100),
+    updated_at datetime(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE audit_logs (
+    id         bigint AUTO_INCREMENT PRIMARY KEY,
+    actor      varchar(100),
+    action     varchar(100),
+    resource   varchar(200),
+    detail     text,
+    client_ip  varchar(45),
+    created_at datetime(6),
+    prev_hash  char(64) NOT NULL,
+    hash       char(64) NOT NULL,
+    INDEX idx_audit_logs_actor (actor),
+    INDEX idx_audit_logs_action (action),
+    INDEX idx_audit_logs_created_at (created_at),
+    UNIQUE INDEX idx_audit_logs_hash (hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE job_runs (
+    id          bigint AUTO_INCREMENT PRIMARY KEY,
+    job         varchar(100) NOT NULL,
+    started_at  datetime(6) NOT NULL,
+    finished_at datetime(6) NOT NULL,
+    duration_ms bigint NOT NULL,
+    status      varchar(20) NOT NULL,
+    error       text,
+    host        varchar(255),
+    INDEX idx_job_runs_job_started (job, started_at),
+    INDEX idx_job_runs_started_at (started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE file_objects (
+    `key`        varbinary(1024) PRIMARY KEY,
+    owner_id     bigint NOT NULL,
+    size         bigint NOT NULL,
+    content_type varchar(255),
+    filename     varchar(255),
+    sha256       char(64),
+    ref_count    bigint NOT NULL DEFAULT 1,
+    created_at   datetime(6),
+    INDEX idx_file_objects_owner_id (owner_id),
+    INDEX idx_file_objects_sha256 (sha256)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE file_refs (
+    id         bigint AUTO_INCREMENT PRIMARY KEY,
+    `key`      varbinary(1024) NOT NULL,
+    owner_id   bigint NOT NULL,
+    created_at datetime(6),
+    INDEX idx_file_refs_key_owner (`key`, owner_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE user_settings (
+    user_id    bigint NOT NULL,
+    `key`      varchar(64) NOT NULL,
+    value      text NOT NULL,
+    updated_at datetime(6),
+    PRIMARY KEY (user_id, `key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- database.XactLock 的锁表：事务内对名称哈希对应的行加排他锁，代替 Postgres 的 advisory lock
+CREATE TABLE xact_locks (
+    name char(64) PRIMARY KEY
+) ENGINE=InnoDB DEFAULT CHARSET=ascii;
+
+-- 能在当前表结构上运行的最老代码版本，见 postgres/000001_initial_schema.up.sql
+CREATE TABLE schema_compat (
+    singleton   boolean PRIMARY KEY DEFAULT true CHECK (singleton),
+    min_version bigint NOT NULL
+) ENGINE=InnoDB;
+INSERT INTO schema_compat (min_version) VALUES (1);
@@ -0,0 +1,2 @@
+-- 用户软删除，与 postgres/000002_users_soft_delete.up.sql 对应
+ALTER TABLE users ADD COLUMN deleted_at datetime(6), ADD INDEX idx_users_deleted_at (deleted_at);
@@ -0,0 +1,2 @@
+-- 用户乐观锁版本，与 postgres/000003_users_version.up.sql 对应
+ALTER TABLE users ADD COLUMN version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- 审计记录的请求 ID，与 postgres/000005_audit_request_id.up.sql 对应
+ALTER TABLE audit_logs ADD COLUMN request_id varchar(128);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
@@ -0,0 +1,93 @@
+-- 初始表结构，与此前 AutoMigrate 创建的表一致
+-- 使用 IF NOT EXISTS，已由 AutoMigrate 建表的数据库可直接执行
+CREATE TABLE IF NOT EXISTS users (
+    id             bigserial PRIMARY KEY,
+    name           varchar(100) NOT NULL,
+    email          varchar(100) NOT NULL,
+    phone          varchar(20),
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    email_verified boolean NOT NULL DEFAULT false,
+    phone_verified boolean NOT NULL DEFAULT false,
+    last_seen_at   timestamptz,
+    password_hash  varchar(255),
+    role           varchar(20) NOT NULL DEFAULT 'user',
+    timezone       varchar(64)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS runtime_settings (
+    key        varchar(100) PRIMARY KEY,
+    value      text NOT NULL,
+    updated_by varchar(100),
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id         bigserial PRIMARY KEY,
+    actor      varchar(100),
+    action     varchar(100),
+    resource   varchar(200),
+    detail     text,
+    client_ip  varchar(45),
+    created_at timestamptz,
+    prev_hash  char(64) NOT NULL,
+    hash       char(64) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_logs_hash ON audit_logs (hash);
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id          bigserial PRIMARY KEY,
+    job         varchar(100) NOT NULL,
+    started_at  timestamptz NOT NULL,
+    finished_at timestamptz NOT NULL,
+    duration_ms bigint NOT NULL,
+    status      varchar(20) NOT NULL,
+    error       text,
+    host        varchar(255)
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_started ON job_runs (job, started_at);
+CREATE INDEX IF NOT EXISTS idx_job_runs_started_at ON job_runs (started_at);
+
+CREATE TABLE IF NOT EXISTS file_objects (
+    key          varchar(1024) PRIMARY KEY,
+    owner_id     bigint NOT NULL,
+    size         bigint NOT NULL,
+    content_type varchar(255),
+    filename     varchar(255),
+    sha256       char(64),
+    ref_count    bigint NOT NULL DEFAULT 1,
+    created_at   timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_file_objects_owner_id ON file_objects (owner_id);
+CREATE INDEX IF NOT EXISTS idx_file_objects_sha256 ON file_objects (sha256);
+
+CREATE TABLE IF NOT EXISTS file_refs (
+    id         bigserial PRIMARY KEY,
+    key        varchar(1024) NOT NULL,
+    owner_id   bigint NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_file_refs_key_owner ON file_refs (key, owner_id);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id    bigint NOT NULL,
+    key        varchar(64) NOT NULL,
+    value      text NOT NULL,
+    updated_at timestamptz,
+    PRIMARY KEY (user_id, key)
+);
+
+-- 能在当前表结构上运行的最老代码版本（代码版本为其内嵌的最新迁移版本），
+-- 使旧代码无法运行的迁移（删除、重命名列，新增非空且无默认值的列）需同时更新 min_version
+CREATE TABLE IF NOT EXISTS schema_compat (
+    singleton   boolean PRIMARY KEY DEFAULT true CHECK (singleton),
+    min_version bigint NOT NULL
+);
+INSERT INTO schema_compat (min_version) VALUES (1) ON CONFLICT (singleton) DO NOTHING;
+
+-- AutoMigrate 时期的版本表，版本改由 schema_versions 记录
+DROP TABLE IF EXISTS schema_migrations;
@@ -0,0 +1,3 @@
+-- 回滚前已软删除的用户恢复为正常用户；需要时先执行 DELETE FROM users WHERE deleted_at IS NOT NULL
+DROP INDEX IF EXISTS idx_users_deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
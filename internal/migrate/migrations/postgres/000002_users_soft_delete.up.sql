@@ -0,0 +1,4 @@
+-- 用户软删除：deleted_at 非空的用户不出现在查询结果中，超过 users.purge_after_days 后由定时任务永久删除
+-- 新增可空列，旧代码仍可运行（把软删除的用户视为正常用户），不更新 schema_compat.min_version
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
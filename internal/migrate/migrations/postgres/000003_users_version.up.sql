@@ -0,0 +1,3 @@
+-- 用户乐观锁版本，UpdateUser 提交的版本与当前版本不一致时返回冲突
+-- 新增带默认值的列，旧代码仍可运行（更新时不递增版本），不更新 schema_compat.min_version
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- 按创建时间排序的游标分页（ListUsers sort=created_at / -created_at）使用 (created_at, id) 索引
+-- 只新增索引，不更新 schema_compat.min_version
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
@@ -0,0 +1,4 @@
+-- 审计记录的请求 ID，用于关联请求日志；旧记录为空，不影响哈希链校验
+-- 新增可为空的列，旧代码仍可运行，不更新 schema_compat.min_version
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS request_id varchar(128);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
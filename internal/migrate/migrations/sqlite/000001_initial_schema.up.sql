@@ -0,0 +1,88 @@
+-- 初始表结构（SQLite），与 postgres/000001_initial_schema.up.sql 对应
+CREATE TABLE users (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    name           varchar(100) NOT NULL,
+    email          varchar(100) NOT NULL,
+    phone          varchar(20),
+    created_at     datetime,
+    updated_at     datetime,
+    email_verified boolean NOT NULL DEFAULT false,
+    phone_verified boolean NOT NULL DEFAULT false,
+    last_seen_at   datetime,
+    password_hash  varchar(255),
+    role           varchar(20) NOT NULL DEFAULT 'user',
+    timezone       varchar(64)
+);
+CREATE UNIQUE INDEX idx_users_email ON users (email);
+
+CREATE TABLE runtime_settings (
+    key        varchar(100) PRIMARY KEY,
+    value      text NOT NULL,
+    updated_by varchar(100),
+    updated_at datetime
+);
+
+CREATE TABLE audit_logs (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    actor      varchar(100),
+    action     varchar(100),
+    resource   varchar(200),
+    detail     text,
+    client_ip  varchar(45),
+    created_at datetime,
+    prev_hash  char(64) NOT NULL,
+    hash       char(64) NOT NULL
+);
+CREATE INDEX idx_audit_logs_actor ON audit_logs (actor);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE UNIQUE INDEX idx_audit_logs_hash ON audit_logs (hash);
+
+CREATE TABLE job_runs (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    job         varchar(100) NOT NULL,
+    started_at  datetime NOT NULL,
+    finished_at datetime NOT NULL,
+    duration_ms bigint NOT NULL,
+    status      varchar(20) NOT NULL,
+    error       text,
+    host        varchar(255)
+);
+CREATE INDEX idx_job_runs_job_started ON job_runs (job, started_at);
+CREATE INDEX idx_job_runs_started_at ON job_runs (started_at);
+
+CREATE TABLE file_objects (
+    key          varchar(1024) PRIMARY KEY,
+    owner_id     bigint NOT NULL,
+    size         bigint NOT NULL,
+    content_type varchar(255),
+    filename     varchar(255),
+    sha256       char(64),
+    ref_count    bigint NOT NULL DEFAULT 1,
+    created_at   datetime
+);
+CREATE INDEX idx_file_objects_owner_id ON file_objects (owner_id);
+CREATE INDEX idx_file_objects_sha256 ON file_objects (sha256);
+
+CREATE TABLE file_refs (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    key        varchar(1024) NOT NULL,
+    owner_id   bigint NOT NULL,
+    created_at datetime
+);
+CREATE INDEX idx_file_refs_key_owner ON file_refs (key, owner_id);
+
+CREATE TABLE user_settings (
+    user_id    bigint NOT NULL,
+    key        varchar(64) NOT NULL,
+    value      text NOT NULL,
+    updated_at datetime,
+    PRIMARY KEY (user_id, key)
+);
+
+-- 能在当前表结构上运行的最老代码版本，见 postgres/000001_initial_schema.up.sql
+CREATE TABLE schema_compat (
+    singleton   boolean PRIMARY KEY DEFAULT true CHECK (singleton),
+    min_version bigint NOT NULL
+);
+INSERT INTO schema_compat (min_version) VALUES (1);
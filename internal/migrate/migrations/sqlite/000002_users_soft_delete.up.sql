@@ -0,0 +1,3 @@
+-- 用户软删除，与 postgres/000002_users_soft_delete.up.sql 对应
+ALTER TABLE users ADD COLUMN deleted_at datetime;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
@@ -0,0 +1,2 @@
+-- 按创建时间排序的游标分页索引，与 postgres/000004_users_created_at_index.up.sql 对应
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
@@ -0,0 +1,135 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// GRPCService gRPC 服务模块
+// 以声明方式描述服务及其专属拦截器，新增服务无需修改 cmd/grpc-server/main.go
+type GRPCService struct {
+	// Name 模块名称，对应 features 配置中的开关
+	Name string
+	// Desc 生成代码中的服务描述，如 pb.UserService_ServiceDesc
+	Desc *grpc.ServiceDesc
+	// New 创建服务实现
+	New func(deps Deps) interface{}
+	// UnaryInterceptors 仅作用于本服务的一元拦截器（按顺序执行）
+	UnaryInterceptors []grpc.UnaryServerInterceptor
+	// StreamInterceptors 仅作用于本服务的流拦截器（按顺序执行）
+	StreamInterceptors []grpc.StreamServerInterceptor
+}
+
+// grpcServices 按注册顺序保存的 gRPC 服务模块
+var grpcServices []GRPCService
+
+// RegisterGRPC 注册 gRPC 服务模块
+// 参数:
+//
+//	svc: 服务模块描述
+func RegisterGRPC(svc GRPCService) {
+	for _, s := range grpcServices {
+		if s.Desc.ServiceName == svc.Desc.ServiceName {
+			panic(fmt.Sprintf("gRPC 服务 %s 重复注册", svc.Desc.ServiceName))
+		}
+	}
+	grpcServices = append(grpcServices, svc)
+}
+
+// SetupGRPC 把所有已启用的服务注册到 gRPC 服务器
+// 参数:
+//
+//	s: gRPC 服务器
+//	deps: 模块依赖
+//
+// 返回:
+//
+//	[]string: 已注册的服务全名
+func SetupGRPC(s *grpc.Server, deps Deps) []string {
+	var enabled []string
+	for _, svc := range grpcServices {
+		if !deps.Config.FeatureEnabled(svc.Name) {
+			logger.Info("跳过未启用的 gRPC 服务", zap.String("模块", svc.Name))
+			continue
+		}
+
+		s.RegisterService(svc.Desc, svc.New(deps))
+		enabled = append(enabled, svc.Desc.ServiceName)
+	}
+
+	logger.Info("gRPC 服务加载完成", zap.Strings("服务", enabled))
+	return enabled
+}
+
+// UnaryInterceptor 按服务分发的一元拦截器
+// 根据调用的方法名找到所属服务，依次执行该服务声明的拦截器
+// 返回:
+//
+//	grpc.UnaryServerInterceptor: 一元拦截器
+func UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		svc := lookupService(info.FullMethod)
+		if svc == nil || len(svc.UnaryInterceptors) == 0 {
+			return handler(ctx, req)
+		}
+		return chainUnary(svc.UnaryInterceptors, info, handler)(ctx, req)
+	}
+}
+
+// StreamInterceptor 按服务分发的流拦截器
+// 返回:
+//
+//	grpc.StreamServerInterceptor: 流拦截器
+func StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		svc := lookupService(info.FullMethod)
+		if svc == nil || len(svc.StreamInterceptors) == 0 {
+			return handler(srv, ss)
+		}
+		return chainStream(svc.StreamInterceptors, info, handler)(srv, ss)
+	}
+}
+
+// lookupService 根据完整方法名（/package.Service/Method）查找服务模块
+func lookupService(fullMethod string) *GRPCService {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[:i]
+	}
+
+	for i := range grpcServices {
+		if grpcServices[i].Desc.ServiceName == name {
+			return &grpcServices[i]
+		}
+	}
+	return nil
+}
+
+// chainUnary 把多个一元拦截器串成一个处理函数
+func chainUnary(interceptors []grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, final grpc.UnaryHandler) grpc.UnaryHandler {
+	handler := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler
+}
+
+// chainStream 把多个流拦截器串成一个处理函数
+func chainStream(interceptors []grpc.StreamServerInterceptor, info *grpc.StreamServerInfo, final grpc.StreamHandler) grpc.StreamHandler {
+	handler := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(srv interface{}, ss grpc.ServerStream) error {
+			return interceptor(srv, ss, info, next)
+		}
+	}
+	return handler
+}
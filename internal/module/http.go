@@ -0,0 +1,69 @@
+package module
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Deps 模块依赖
+// 注册路由时传入，避免各功能包直接读取全局配置
+type Deps struct {
+	Config *config.Config
+}
+
+// RouteRegistrar 路由注册函数
+// 每个功能模块实现此签名，把自己的路由挂到给定的路由组上
+type RouteRegistrar func(r *gin.RouterGroup, deps Deps)
+
+// routeModule 已注册的路由模块
+type routeModule struct {
+	name     string
+	register RouteRegistrar
+}
+
+// routeModules 按注册顺序保存的路由模块
+var routeModules []routeModule
+
+// RegisterRoutes 注册 HTTP 路由模块
+// 通常在 cmd 包的 init 中调用，删除注册语句即可把模块从二进制中移除
+// 参数:
+//
+//	name: 模块名称，对应 features 配置中的开关
+//	fn: 路由注册函数
+func RegisterRoutes(name string, fn RouteRegistrar) {
+	for _, m := range routeModules {
+		if m.name == name {
+			panic(fmt.Sprintf("路由模块 %s 重复注册", name))
+		}
+	}
+	routeModules = append(routeModules, routeModule{name: name, register: fn})
+}
+
+// SetupRoutes 挂载所有已启用的路由模块
+// 参数:
+//
+//	r: 路由组
+//	deps: 模块依赖
+//
+// 返回:
+//
+//	[]string: 已挂载的模块名称
+func SetupRoutes(r *gin.RouterGroup, deps Deps) []string {
+	var enabled []string
+	for _, m := range routeModules {
+		if !deps.Config.FeatureEnabled(m.name) {
+			logger.Info("跳过未启用的路由模块", zap.String("模块", m.name))
+			continue
+		}
+
+		m.register(r, deps)
+		enabled = append(enabled, m.name)
+	}
+
+	logger.Info("路由模块加载完成", zap.Strings("模块", enabled))
+	return enabled
+}
@@ -0,0 +1,180 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Message 通知消息
+type Message struct {
+	// Event 事件类型（如 quota.threshold）
+	Event string `json:"event"`
+	// Title 标题（邮件主题）
+	Title string `json:"title"`
+	// Text 正文
+	Text string `json:"text"`
+	// Data 附加数据，原样放入 Webhook 请求体
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Default 全局通知渠道，未初始化时只记录日志
+var Default Notifier = logNotifier{}
+
+// Init 按配置初始化全局通知渠道
+// 参数:
+//
+//	cfg: 通知配置
+func Init(cfg config.NotifyConfig) {
+	var list multiNotifier
+	if cfg.Webhook.URL != "" {
+		list = append(list, NewWebhookNotifier(cfg.Webhook))
+	}
+	if cfg.Email.Host != "" && len(cfg.Email.To) > 0 {
+		list = append(list, NewEmailNotifier(cfg.Email))
+	}
+
+	if len(list) == 0 {
+		Default = logNotifier{}
+		return
+	}
+	Default = list
+}
+
+// Send 通过全局通知渠道发送消息
+// 参数:
+//
+//	ctx: 上下文
+//	msg: 通知消息
+//
+// 返回:
+//
+//	error: 错误信息
+func Send(ctx context.Context, msg Message) error {
+	return Default.Notify(ctx, msg)
+}
+
+// logNotifier 只记录日志的通知渠道
+type logNotifier struct{}
+
+// Notify 记录通知日志
+func (logNotifier) Notify(ctx context.Context, msg Message) error {
+	logger.Info("通知", zap.String("event", msg.Event), zap.String("title", msg.Title))
+	return nil
+}
+
+// multiNotifier 依次发送到多个渠道
+type multiNotifier []Notifier
+
+// Notify 发送到所有渠道，合并错误
+func (m multiNotifier) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookNotifier 以 JSON POST 发送通知
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier 创建 Webhook 通知渠道
+// 参数:
+//
+//	cfg: Webhook 配置
+//
+// 返回:
+//
+//	*WebhookNotifier: 通知渠道
+func NewWebhookNotifier(cfg config.WebhookConfig) *WebhookNotifier {
+	timeout := cfg.GetTimeout()
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &WebhookNotifier{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify 发送 Webhook 请求
+func (w *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送 Webhook 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// EmailNotifier 通过 SMTP 发送通知邮件
+type EmailNotifier struct {
+	cfg config.EmailConfig
+}
+
+// NewEmailNotifier 创建邮件通知渠道
+// 参数:
+//
+//	cfg: 邮件配置
+//
+// 返回:
+//
+//	*EmailNotifier: 通知渠道
+func NewEmailNotifier(cfg config.EmailConfig) *EmailNotifier {
+	return &EmailNotifier{cfg: cfg}
+}
+
+// Notify 发送通知邮件
+func (e *EmailNotifier) Notify(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if e.cfg.Username != "" {
+		auth = smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, e.cfg.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Title)
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Text)
+
+	if err := smtp.SendMail(e.cfg.GetAddr(), auth, e.cfg.From, e.cfg.To, []byte(b.String())); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package pgnotify
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 触发器通知中的操作类型（TG_OP）
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+)
+
+// Change InstallTrigger 安装的触发器发送的行变更通知
+type Change struct {
+	Op    string `json:"op"`
+	Table string `json:"table"`
+	ID    int64  `json:"id"`
+}
+
+// ParseChange 解析触发器发送的通知内容
+// 参数:
+//
+//	payload: 通知内容
+//
+// 返回:
+//
+//	Change: 行变更
+//	error: 内容不是有效的变更通知时返回错误
+func ParseChange(payload string) (Change, error) {
+	var c Change
+	if err := json.Unmarshal([]byte(payload), &c); err != nil {
+		return Change{}, err
+	}
+	if c.Table == "" || (c.Op != OpInsert && c.Op != OpUpdate && c.Op != OpDelete) {
+		return Change{}, fmt.Errorf("无效的变更通知: %s", payload)
+	}
+	return c, nil
+}
@@ -0,0 +1,112 @@
+package pgnotify
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Handler 通知处理函数，payload 为 pg_notify 的第二个参数
+type Handler func(ctx context.Context, payload string)
+
+// Listener Postgres LISTEN 监听器
+// 使用独立连接（不占用 GORM 连接池），连接断开后按指数退避重连并重新 LISTEN
+type Listener struct {
+	dsn string
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewListener 创建监听器
+// 参数:
+//
+//	dsn: 数据库连接串
+//
+// 返回:
+//
+//	*Listener: 监听器
+func NewListener(dsn string) *Listener {
+	return &Listener{
+		dsn:      dsn,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Handle 注册频道的处理函数，需在 Run 之前调用
+// 参数:
+//
+//	channel: 频道名称
+//	handler: 处理函数
+func (l *Listener) Handle(channel string, handler Handler) {
+	l.mu.Lock()
+	l.handlers[channel] = append(l.handlers[channel], handler)
+	l.mu.Unlock()
+}
+
+// Run 持续监听直到 ctx 取消
+// 参数:
+//
+//	ctx: 上下文
+func (l *Listener) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logger.Error("数据库变更监听中断，准备重连", zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// listen 建立连接并处理通知，连接出错时返回
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	l.mu.RLock()
+	channels := make([]string, 0, len(l.handlers))
+	for channel := range l.handlers {
+		channels = append(channels, channel)
+	}
+	l.mu.RUnlock()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("LISTEN %s 失败: %w", channel, err)
+		}
+	}
+	logger.Info("开始监听数据库变更通知", zap.Strings("channels", channels))
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		l.mu.RLock()
+		handlers := l.handlers[n.Channel]
+		l.mu.RUnlock()
+
+		for _, h := range handlers {
+			h(ctx, n.Payload)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package pgnotify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// identifierPattern 允许安装触发器的表名、频道名和列名（小写的 SQL 标识符，最长 63 字节）
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// InstallTrigger 在表上安装行变更通知触发器（幂等）
+// 每次 INSERT/UPDATE/DELETE 后发送 {"op": "INSERT", "table": "users", "id": 1} 到指定频道，
+// 因此手工 SQL 或共享数据库的其他服务的写入也能被感知；只修改了 ignoreColumns 中的列的 UPDATE 不发送通知
+// （如定期批量写入的 last_seen_at），避免无实际变更的通知造成缓存清理和事件推送风暴
+// 参数:
+//
+//	db: 数据库实例
+//	table: 表名（需有 id 主键列）
+//	channel: 通知频道
+//	ignoreColumns: 单独修改时不发送通知的列
+//
+// 返回:
+//
+//	error: 名称不是合法标识符或安装失败时返回错误
+func InstallTrigger(db *gorm.DB, table, channel string, ignoreColumns ...string) error {
+	// 名称直接拼接进 DDL 和 pg_notify 的字符串字面量，只接受白名单字符
+	for _, name := range append([]string{table, channel}, ignoreColumns...) {
+		if !identifierPattern.MatchString(name) {
+			return fmt.Errorf("安装变更触发器失败: 非法的标识符 %q", name)
+		}
+	}
+
+	function := quoteIdent(table + "_notify_change")
+	changeTrigger := quoteIdent(table + "_notify_change_trigger")
+	updateTrigger := quoteIdent(table + "_notify_update_trigger")
+	quotedTable := quoteIdent(table)
+
+	// UPDATE 触发器的 WHEN 条件：去掉忽略的列后行内容有变化
+	when := "OLD.* IS DISTINCT FROM NEW.*"
+	if len(ignoreColumns) > 0 {
+		ignored := "ARRAY['" + strings.Join(ignoreColumns, "','") + "']"
+		when = fmt.Sprintf("(to_jsonb(OLD) - %s) IS DISTINCT FROM (to_jsonb(NEW) - %s)", ignored, ignored)
+	}
+
+	statements := []string{
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('%s', json_build_object(
+		'op', TG_OP,
+		'table', TG_TABLE_NAME,
+		'id', COALESCE(NEW.id, OLD.id)
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`, function, channel),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, changeTrigger, quotedTable),
+		fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, updateTrigger, quotedTable),
+		// WHEN 条件不能在 INSERT/DELETE 触发器中引用 OLD/NEW，UPDATE 单独安装
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR DELETE ON %s
+FOR EACH ROW EXECUTE FUNCTION %s()`, changeTrigger, quotedTable, function),
+		fmt.Sprintf(`CREATE TRIGGER %s AFTER UPDATE ON %s
+FOR EACH ROW WHEN (%s) EXECUTE FUNCTION %s()`, updateTrigger, quotedTable, when, function),
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("安装 %s 变更触发器失败: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// quoteIdent 以双引号引用 SQL 标识符
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
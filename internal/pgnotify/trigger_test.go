@@ -0,0 +1,23 @@
+package pgnotify
+
+import "testing"
+
+func TestInstallTriggerRejectsIdentifiers(t *testing.T) {
+	tests := []struct {
+		name, table, channel string
+		ignore               []string
+	}{
+		{"表名含分号", "users; DROP TABLE users", "table_changes", nil},
+		{"表名含引号", `users"`, "table_changes", nil},
+		{"频道含单引号", "users", "changes', 'x", nil},
+		{"频道为空", "users", "", nil},
+		{"忽略的列含单引号", "users", "table_changes", []string{"last_seen_at'"}},
+		{"大写字母", "Users", "table_changes", nil},
+	}
+	for _, tt := range tests {
+		// 校验在访问数据库之前完成
+		if err := InstallTrigger(nil, tt.table, tt.channel, tt.ignore...); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// v2Signature PROXY 协议 v2 的固定前缀
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// v1Prefix PROXY 协议 v1 的前缀
+var v1Prefix = []byte("PROXY ")
+
+// v1MaxLength v1 头的最大长度（含 CRLF）
+const v1MaxLength = 107
+
+// ErrInvalidHeader PROXY 协议头格式错误
+var ErrInvalidHeader = errors.New("PROXY 协议头格式错误")
+
+// Listener 解析 PROXY 协议头（v1/v2）的监听器
+// 仅信任来自负载均衡地址的协议头；连接的 RemoteAddr 返回协议头中的客户端地址
+type Listener struct {
+	net.Listener
+	trusted []*net.IPNet
+	timeout time.Duration
+}
+
+// NewListener 包装监听器
+// 参数:
+//
+//	inner: 原始 TCP 监听器
+//	trusted: 允许发送 PROXY 协议头的负载均衡地址（CIDR 或 IP），不能为空
+//	timeout: 读取协议头的超时
+//
+// 返回:
+//
+//	*Listener: 监听器
+//	error: 地址为空或格式错误
+func NewListener(inner net.Listener, trusted []string, timeout time.Duration) (*Listener, error) {
+	if len(trusted) == 0 {
+		return nil, errors.New("启用 PROXY 协议需要配置受信任的代理地址")
+	}
+	nets, err := ParseCIDRs(trusted)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{Listener: inner, trusted: nets, timeout: timeout}, nil
+}
+
+// Listen 创建 TCP 监听，按配置解析 PROXY 协议头
+// 参数:
+//
+//	addr: 监听地址
+//	cfg: 代理配置
+//
+// 返回:
+//
+//	net.Listener: 监听器
+//	error: 监听失败或配置错误
+func Listen(addr string, cfg config.ProxyConfig) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil || !cfg.ProxyProtocol {
+		return l, err
+	}
+	pl, err := NewListener(l, cfg.TrustedProxies, cfg.GetHeaderTimeout())
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	return pl, nil
+}
+
+// ParseCIDRs 解析 CIDR 列表，单个 IP 视为 /32（IPv6 为 /128）
+// 参数:
+//
+//	values: CIDR 或 IP 列表
+//
+// 返回:
+//
+//	[]*net.IPNet: 网段
+//	error: 格式错误
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的地址: %q", v)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段: %q", v)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Accept 接受连接，协议头在首次读取或获取 RemoteAddr 时解析，不阻塞 Accept 循环
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.isTrusted(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return &Conn{Conn: conn, reader: bufio.NewReader(conn), timeout: l.timeout}, nil
+}
+
+// isTrusted 连接来源是否允许发送协议头
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range l.trusted {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn 带 PROXY 协议头的连接
+type Conn struct {
+	net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	source net.Addr
+	err    error
+}
+
+// init 读取协议头，没有协议头（如负载均衡的健康检查）时保留原始地址
+func (c *Conn) init() {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer c.Conn.SetReadDeadline(time.Time{})
+		}
+		c.source, c.err = readHeader(c.reader)
+		// 健康检查等连接建立后立即关闭，不记录
+		if c.err != nil && !errors.Is(c.err, io.EOF) {
+			logger.Warn("解析 PROXY 协议头失败",
+				zap.String("remote", c.Conn.RemoteAddr().String()),
+				zap.Error(c.err),
+			)
+		}
+	})
+}
+
+// Read 读取协议头之后的数据
+func (c *Conn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr 协议头中的客户端地址，无协议头时为 TCP 对端地址
+func (c *Conn) RemoteAddr() net.Addr {
+	c.init()
+	if c.source != nil {
+		return c.source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readHeader 读取并解析协议头
+// 返回:
+//
+//	net.Addr: 客户端地址，无协议头或为 LOCAL/UNKNOWN 时为 nil
+//	error: 格式错误或读取失败
+func readHeader(r *bufio.Reader) (net.Addr, error) {
+	// 先看第一个字节，避免对不发送协议头的短连接等待更多数据
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch first[0] {
+	case v2Signature[0]:
+		sig, err := r.Peek(len(v2Signature))
+		if err != nil || !bytes.Equal(sig, v2Signature) {
+			return nil, nil
+		}
+		return readV2(r)
+	case v1Prefix[0]:
+		prefix, err := r.Peek(len(v1Prefix))
+		if err != nil || !bytes.Equal(prefix, v1Prefix) {
+			return nil, nil
+		}
+		return readV1(r)
+	}
+	return nil, nil
+}
+
+// readV1 解析文本格式：PROXY TCP4 源地址 目标地址 源端口 目标端口\r\n
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidHeader
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidHeader
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil || port < 0 || port > 65535 {
+		return nil, ErrInvalidHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readV2 解析二进制格式，忽略 TLV 扩展
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[12]>>4 != 2 {
+		return nil, ErrInvalidHeader
+	}
+	command := header[12] & 0x0f
+	family := header[13] >> 4
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	// LOCAL 命令（负载均衡自身的健康检查）使用原始地址
+	if command == 0 {
+		return nil, nil
+	}
+	if command != 1 {
+		return nil, ErrInvalidHeader
+	}
+
+	switch family {
+	case 1: // IPv4
+		if length < 12 {
+			return nil, ErrInvalidHeader
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:4]),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+	case 2: // IPv6
+		if length < 36 {
+			return nil, ErrInvalidHeader
+		}
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	}
+	// AF_UNSPEC / AF_UNIX
+	return nil, nil
+}
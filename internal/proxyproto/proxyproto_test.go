@@ -0,0 +1,119 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// v2Header 构造 v2 协议头
+func v2Header(command, family byte, addrs []byte) []byte {
+	var b bytes.Buffer
+	b.Write(v2Signature)
+	b.WriteByte(0x20 | command)
+	b.WriteByte(family<<4 | 1)
+	binary.Write(&b, binary.BigEndian, uint16(len(addrs)))
+	b.Write(addrs)
+	return b.Bytes()
+}
+
+func TestReadHeader(t *testing.T) {
+	ipv4 := append(append(net.ParseIP("203.0.113.7").To4(), net.ParseIP("10.0.0.1").To4()...), 0x30, 0x39, 0x01, 0xbb)
+	ipv6 := append(append(net.ParseIP("2001:db8::1").To16(), net.ParseIP("2001:db8::2").To16()...), 0x30, 0x39, 0x01, 0xbb)
+	// 附带一个 TLV（AWS VPC 终端节点 ID），应被忽略
+	withTLV := append(append([]byte{}, ipv4...), 0xea, 0x00, 0x03, 'v', 'p', 'c')
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string
+		wantErr bool
+	}{
+		{name: "v1 TCP4", input: []byte("PROXY TCP4 203.0.113.7 10.0.0.1 12345 443\r\n"), want: "203.0.113.7:12345"},
+		{name: "v1 TCP6", input: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 12345 443\r\n"), want: "[2001:db8::1]:12345"},
+		{name: "v1 UNKNOWN", input: []byte("PROXY UNKNOWN\r\n")},
+		{name: "v1 格式错误", input: []byte("PROXY TCP4 bad 10.0.0.1 1 2\r\n"), wantErr: true},
+		{name: "v2 IPv4", input: v2Header(1, 1, ipv4), want: "203.0.113.7:12345"},
+		{name: "v2 IPv6", input: v2Header(1, 2, ipv6), want: "[2001:db8::1]:12345"},
+		{name: "v2 TLV", input: v2Header(1, 1, withTLV), want: "203.0.113.7:12345"},
+		{name: "v2 LOCAL", input: v2Header(0, 0, nil)},
+		{name: "无协议头", input: []byte("GET / HTTP/1.1\r\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(io.MultiReader(bytes.NewReader(tt.input), strings.NewReader("payload")))
+			addr, err := readHeader(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望错误, 实际地址 %v", addr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if got != tt.want {
+				t.Errorf("地址 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	logger.Logger = zap.NewNop()
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewListener(inner, []string{"127.0.0.1"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("PROXY TCP4 198.51.100.9 10.0.0.1 4000 80\r\nhello"))
+	}()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if got := conn.RemoteAddr().String(); got != "198.51.100.9:4000" {
+		t.Errorf("RemoteAddr = %s, 期望 198.51.100.9:4000", got)
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("读取 = %q, %v, 期望 hello", data, err)
+	}
+}
+
+func TestNewListenerRequiresTrustedProxies(t *testing.T) {
+	if _, err := NewListener(nil, nil, time.Second); err == nil {
+		t.Error("未配置受信任地址时期望错误")
+	}
+	if _, err := NewListener(nil, []string{"not-an-ip"}, time.Second); err == nil {
+		t.Error("地址格式错误时期望错误")
+	}
+}
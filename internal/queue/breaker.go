@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/zhang/microservice/internal/resilience"
+)
+
+// breakerBroker 为消息代理的发布加上熔断，消息代理持续不可用时直接返回“依赖不可用”错误
+// 消费为长连接，由各实现自行重连，不经过熔断器
+type breakerBroker struct {
+	MessageBroker
+	breaker *resilience.Breaker
+}
+
+// withBreaker 为消息代理加上熔断
+// 参数:
+//
+//	broker: 消息代理
+//	breaker: 熔断器，为 nil 时原样返回
+//
+// 返回:
+//
+//	MessageBroker: 消息代理
+func withBreaker(broker MessageBroker, breaker *resilience.Breaker) MessageBroker {
+	if breaker == nil {
+		return broker
+	}
+	return &breakerBroker{MessageBroker: broker, breaker: breaker}
+}
+
+// Publish 在熔断器保护下发布消息
+func (b *breakerBroker) Publish(routingKey string, body []byte) error {
+	return b.breaker.Execute(func() error {
+		return b.MessageBroker.Publish(routingKey, body)
+	})
+}
+
+// PublishContext 在熔断器保护下发布消息，并传递请求 ID
+func (b *breakerBroker) PublishContext(ctx context.Context, routingKey string, body []byte) error {
+	return b.breaker.Execute(func() error {
+		return publishContext(b.MessageBroker, ctx, routingKey, body)
+	})
+}
+
+// isBrokerUnavailable 是否为消息代理不可用类错误（计入熔断），编码、转存等错误不算
+func isBrokerUnavailable(err error) bool {
+	return resilience.IsConnectionError(err) || isAMQPError(err)
+}
+
+// unwrapBroker 返回被装饰的消息代理（用于查询队列深度等实现相关的功能）
+func unwrapBroker(broker MessageBroker) MessageBroker {
+	if b, ok := broker.(*breakerBroker); ok {
+		return b.MessageBroker
+	}
+	return broker
+}
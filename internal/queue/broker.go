@@ -0,0 +1,147 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/resilience"
+)
+
+const (
+	// DriverRabbitMQ RabbitMQ 消息代理
+	DriverRabbitMQ = "rabbitmq"
+	// DriverRedisStreams Redis Streams 消息代理
+	DriverRedisStreams = "redis_streams"
+)
+
+// MessageBroker 消息代理接口
+// RabbitMQ 与 Redis Streams 实现相同的发布/消费语义：
+// 按路由键投递到绑定的队列，处理成功确认，失败重试，超时转存
+type MessageBroker interface {
+	// Publish 按路由键发布消息
+	Publish(routingKey string, body []byte) error
+	// Consume 消费队列（处理函数无法感知取消，不应用 process_timeout）
+	Consume(queueName string, handler func([]byte) error) error
+	// ConsumeContext 消费队列（支持处理超时）
+	ConsumeContext(queueName string, handler ContextHandler) error
+	// DeclareQueues 运行时声明额外的队列（已存在的队列忽略）
+	DeclareQueues(queues []config.QueueConfig) error
+	// Close 关闭连接
+	Close() error
+}
+
+// MQClient 全局消息代理实例
+var MQClient MessageBroker
+
+// contextPublisher 支持随消息传递请求 ID 的消息代理
+type contextPublisher interface {
+	PublishContext(ctx context.Context, routingKey string, body []byte) error
+}
+
+// PublishContext 通过 MQClient 发布消息，上下文中的请求 ID（见 ctxkeys.RequestID）写入消息头 x-request-id，
+// 消费者处理函数的上下文中可读取同一个请求 ID
+// 参数:
+//
+//	ctx: 上下文
+//	routingKey: 路由键
+//	body: 消息内容
+//
+// 返回:
+//
+//	error: 错误信息
+func PublishContext(ctx context.Context, routingKey string, body []byte) error {
+	return publishContext(MQClient, ctx, routingKey, body)
+}
+
+// publishContext 代理支持时传递上下文，否则退化为 Publish
+func publishContext(broker MessageBroker, ctx context.Context, routingKey string, body []byte) error {
+	if p, ok := broker.(contextPublisher); ok {
+		return p.PublishContext(ctx, routingKey, body)
+	}
+	return broker.Publish(routingKey, body)
+}
+
+// Init 根据配置初始化消息代理
+// 参数:
+//
+//	cfg: 消息队列配置，driver 为空时使用 RabbitMQ
+//
+// 返回:
+//
+//	error: 错误信息
+func Init(cfg config.RabbitMQConfig) error {
+	var (
+		broker MessageBroker
+		err    error
+	)
+
+	switch cfg.Driver {
+	case "", DriverRabbitMQ:
+		broker, err = newRabbitMQ(cfg)
+	case DriverRedisStreams:
+		broker, err = newRedisStreams(cfg)
+	default:
+		return fmt.Errorf("不支持的消息队列驱动: %s", cfg.Driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	MQClient = withBreaker(broker, resilience.For("mq", isBrokerUnavailable))
+	return nil
+}
+
+// Close 关闭消息代理连接
+func Close() error {
+	if MQClient != nil {
+		return MQClient.Close()
+	}
+	return nil
+}
+
+// containsQueue 检查队列是否已存在
+func containsQueue(queues []config.QueueConfig, name string) bool {
+	for _, q := range queues {
+		if q.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic 按 AMQP topic 规则匹配路由键
+// * 匹配一个单词，# 匹配零个或多个单词
+// 参数:
+//
+//	pattern: 绑定键，如 task.*
+//	routingKey: 路由键，如 task.created
+//
+// 返回:
+//
+//	bool: 是否匹配
+func MatchTopic(pattern, routingKey string) bool {
+	return matchWords(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+// matchWords 逐个单词匹配
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+	}
+}
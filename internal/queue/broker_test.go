@@ -0,0 +1,26 @@
+package queue
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		routingKey string
+		expected   bool
+	}{
+		{"task.*", "task.created", true},
+		{"task.*", "task", false},
+		{"task.*", "task.created.v2", false},
+		{"task.#", "task", true},
+		{"task.#", "task.created.v2", true},
+		{"#.created", "user.created", true},
+		{"email.*", "task.created", false},
+		{"user.created", "user.created", true},
+	}
+
+	for _, tt := range tests {
+		if result := MatchTopic(tt.pattern, tt.routingKey); result != tt.expected {
+			t.Errorf("MatchTopic(%q, %q) 期望 %v, 实际 %v", tt.pattern, tt.routingKey, tt.expected, result)
+		}
+	}
+}
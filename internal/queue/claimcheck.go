@@ -0,0 +1,114 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/storage"
+	"go.uber.org/zap"
+)
+
+// headerClaimCheck 转存对象 Key 消息头
+const headerClaimCheck = "x-claim-check"
+
+// claimCheck 发布到队列中的转存引用
+type claimCheck struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// claimCheckCodec 超大消息转存
+// 消息体超过阈值时存入 S3，队列中只传递引用；消费成功后删除对象，
+// 消费失败遗留的对象由定时任务按保留时间清理
+type claimCheckCodec struct {
+	cfg   config.ClaimCheckConfig
+	store storage.ObjectStore
+}
+
+// newClaimCheckCodec 创建超大消息转存编解码器
+func newClaimCheckCodec(cfg config.ClaimCheckConfig, store storage.ObjectStore) (*claimCheckCodec, error) {
+	if store == nil {
+		return nil, fmt.Errorf("启用超大消息转存需要先初始化 S3 存储")
+	}
+	return &claimCheckCodec{cfg: cfg, store: store}, nil
+}
+
+// encode 把超大消息体存入 S3
+func (c *claimCheckCodec) encode(routingKey string, msg *message) error {
+	if len(msg.Body) <= c.cfg.Threshold {
+		return nil
+	}
+
+	key, err := c.newKey()
+	if err != nil {
+		return err
+	}
+	if err := c.store.PutObject(key, msg.Body, "application/octet-stream"); err != nil {
+		return fmt.Errorf("转存超大消息失败: %w", err)
+	}
+
+	ref, err := json.Marshal(claimCheck{Key: key, Size: len(msg.Body)})
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("超大消息已转存",
+		zap.String("routing_key", routingKey),
+		zap.String("key", key),
+		zap.Int("size", len(msg.Body)),
+	)
+
+	msg.Body = ref
+	msg.Headers[headerClaimCheck] = key
+	return nil
+}
+
+// decode 从 S3 取回消息体
+func (c *claimCheckCodec) decode(msg *message) error {
+	key := headerString(msg.Headers, headerClaimCheck)
+	if key == "" {
+		return nil
+	}
+
+	reader, err := c.store.Download(key)
+	if err != nil {
+		return fmt.Errorf("取回转存消息失败: %w", err)
+	}
+	defer reader.Close()
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("读取转存消息失败: %w", err)
+	}
+
+	msg.Body = body
+	return nil
+}
+
+// release 消息处理成功后删除转存对象
+func (c *claimCheckCodec) release(msg message) {
+	key := headerString(msg.Headers, headerClaimCheck)
+	if key == "" {
+		return
+	}
+	if err := c.store.Delete(key); err != nil {
+		logger.Warn("删除转存对象失败，等待过期清理",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+	}
+}
+
+// newKey 生成转存对象 Key
+func (c *claimCheckCodec) newKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成转存 Key 失败: %w", err)
+	}
+	return c.cfg.GetPrefix() + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,102 @@
+package queue
+
+import (
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/security"
+	"github.com/zhang/microservice/internal/storage"
+)
+
+// payloadCodec 消息体编解码器
+// 发布时按注册顺序编码，消费时按相反顺序解码
+type payloadCodec interface {
+	// encode 发布前处理消息
+	encode(routingKey string, msg *message) error
+	// decode 消费前还原消息
+	decode(msg *message) error
+}
+
+// codecChain 编解码器链
+// RabbitMQ 与 Redis Streams 共用，保证两种代理上的消息格式一致
+type codecChain struct {
+	codecs []payloadCodec
+	claim  *claimCheckCodec
+}
+
+// newCodecChain 根据配置创建编解码器链
+// 参数:
+//
+//	cfg: 消息队列配置
+//
+// 返回:
+//
+//	*codecChain: 编解码器链
+//	error: 错误信息
+func newCodecChain(cfg config.RabbitMQConfig) (*codecChain, error) {
+	chain := &codecChain{}
+
+	// 消息体压缩（先压缩后加密，解码时顺序相反）
+	compression, err := newCompressionCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	chain.codecs = append(chain.codecs, compression)
+
+	// 消息体加密
+	if cfg.Encryption.Enable {
+		codec, err := newEncryptionCodec(cfg.Encryption, security.Keys)
+		if err != nil {
+			return nil, err
+		}
+		chain.codecs = append(chain.codecs, codec)
+	}
+
+	// 超大消息转存（最后执行，转存的是压缩、加密后的消息体）
+	if cfg.ClaimCheck.Enable {
+		codec, err := newClaimCheckCodec(cfg.ClaimCheck, storage.S3Storage)
+		if err != nil {
+			return nil, err
+		}
+		chain.codecs = append(chain.codecs, codec)
+		chain.claim = codec
+	}
+
+	return chain, nil
+}
+
+// encode 依次执行所有编码器
+func (c *codecChain) encode(routingKey string, msg *message) error {
+	if msg.Headers == nil {
+		msg.Headers = map[string]interface{}{}
+	}
+	for _, codec := range c.codecs {
+		if err := codec.encode(routingKey, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode 按相反顺序执行所有解码器
+func (c *codecChain) decode(msg *message) error {
+	for i := len(c.codecs) - 1; i >= 0; i-- {
+		if err := c.codecs[i].decode(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// release 消息处理成功后释放资源（删除转存对象）
+func (c *codecChain) release(msg message) {
+	if c.claim != nil {
+		c.claim.release(msg)
+	}
+}
+
+// headerString 读取字符串类型的消息头
+func headerString(headers map[string]interface{}, key string) string {
+	if v, ok := headers[key].(string); ok {
+		return v
+	}
+	return ""
+}
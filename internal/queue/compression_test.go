@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestCompressionCodec(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"sku":"A-1","qty":1},`), 100)
+
+	for _, algorithm := range []string{encodingGzip, encodingZstd} {
+		t.Run(algorithm, func(t *testing.T) {
+			codec, err := newCompressionCodec(config.MessageCompressionConfig{
+				Enable:    true,
+				Algorithm: algorithm,
+				Threshold: 1024,
+			})
+			if err != nil {
+				t.Fatalf("创建编解码器失败: %v", err)
+			}
+
+			msg := message{Body: body}
+			if err := codec.encode("task.import", &msg); err != nil {
+				t.Fatalf("压缩失败: %v", err)
+			}
+			if msg.ContentEncoding != algorithm || len(msg.Body) >= len(body) {
+				t.Fatalf("消息未被压缩: encoding=%q size=%d", msg.ContentEncoding, len(msg.Body))
+			}
+
+			delivery := message{Body: msg.Body, ContentEncoding: msg.ContentEncoding}
+			if err := codec.decode(&delivery); err != nil {
+				t.Fatalf("解压失败: %v", err)
+			}
+			if !bytes.Equal(delivery.Body, body) {
+				t.Error("解压后数据不匹配")
+			}
+		})
+	}
+
+	// 低于阈值的消息不压缩
+	codec, _ := newCompressionCodec(config.MessageCompressionConfig{Enable: true, Algorithm: encodingGzip, Threshold: 1024})
+	small := message{Body: []byte(`{}`)}
+	if err := codec.encode("task.run", &small); err != nil || small.ContentEncoding != "" {
+		t.Error("低于阈值的消息不应压缩")
+	}
+}
@@ -0,0 +1,113 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+)
+
+// ContextHandler 支持上下文的消息处理函数
+// 处理超时时 ctx 会被取消，处理函数应及时返回
+type ContextHandler func(ctx context.Context, body []byte) error
+
+// ErrProcessTimeout 消息处理超时
+var ErrProcessTimeout = errors.New("消息处理超时")
+
+// slowTracker 慢消费统计
+// 在告警窗口内记录各队列的超时次数，达到阈值时告警（每个窗口最多告警一次）
+type slowTracker struct {
+	mu        sync.Mutex
+	cfg       config.SlowConsumerConfig
+	events    map[string][]time.Time
+	lastAlert map[string]time.Time
+}
+
+// newSlowTracker 创建慢消费统计
+func newSlowTracker(cfg config.SlowConsumerConfig) *slowTracker {
+	return &slowTracker{
+		cfg:       cfg,
+		events:    make(map[string][]time.Time),
+		lastAlert: make(map[string]time.Time),
+	}
+}
+
+// record 记录一次超时
+// 返回:
+//
+//	int: 窗口内的超时次数
+//	bool: 是否需要告警
+func (t *slowTracker) record(queueName string, now time.Time) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	window := t.cfg.GetAlertWindow()
+	events := append(t.events[queueName], now)
+
+	// 丢弃窗口外的记录
+	cutoff := now.Add(-window)
+	for len(events) > 0 && events[0].Before(cutoff) {
+		events = events[1:]
+	}
+	t.events[queueName] = events
+
+	if t.cfg.AlertThreshold <= 0 || len(events) < t.cfg.AlertThreshold {
+		return len(events), false
+	}
+	if last, ok := t.lastAlert[queueName]; ok && now.Sub(last) < window {
+		return len(events), false
+	}
+
+	t.lastAlert[queueName] = now
+	return len(events), true
+}
+
+// findQueueConfig 查找队列配置
+func findQueueConfig(queues []config.QueueConfig, queueName string) config.QueueConfig {
+	for _, q := range queues {
+		if q.Name == queueName {
+			return q
+		}
+	}
+	return config.QueueConfig{Name: queueName}
+}
+
+// runHandler 在超时控制下执行处理函数，并记录消费结果
+// ctx 带有发布方传递的请求 ID（见 message.handlerContext）
+func runHandler(ctx context.Context, queueName string, body []byte, timeout time.Duration, handler ContextHandler) error {
+	err := callHandler(ctx, body, timeout, handler)
+	switch {
+	case errors.Is(err, ErrProcessTimeout):
+		metrics.ObserveConsume(queueName, "timeout")
+	case err != nil:
+		metrics.ObserveConsume(queueName, "error")
+	default:
+		metrics.ObserveConsume(queueName, "success")
+	}
+	return err
+}
+
+// callHandler 在超时控制下执行处理函数
+func callHandler(ctx context.Context, body []byte, timeout time.Duration, handler ContextHandler) error {
+	if timeout <= 0 {
+		return handler(ctx, body)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(ctx, body)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ErrProcessTimeout
+	}
+}
@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestSlowTrackerAlert(t *testing.T) {
+	tracker := newSlowTracker(config.SlowConsumerConfig{
+		AlertThreshold: 3,
+		AlertWindow:    60,
+	})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 窗口内第三次超时触发告警
+	for i := 0; i < 2; i++ {
+		if _, alert := tracker.record("task_queue", start.Add(time.Duration(i)*time.Second)); alert {
+			t.Fatalf("第 %d 次超时不应告警", i+1)
+		}
+	}
+	if count, alert := tracker.record("task_queue", start.Add(2*time.Second)); !alert || count != 3 {
+		t.Fatalf("期望告警且计数为 3, 实际 alert=%v count=%d", alert, count)
+	}
+
+	// 同一窗口内不重复告警
+	if _, alert := tracker.record("task_queue", start.Add(3*time.Second)); alert {
+		t.Error("同一窗口内不应重复告警")
+	}
+
+	// 其他队列独立计数
+	if count, _ := tracker.record("email_queue", start); count != 1 {
+		t.Errorf("期望 email_queue 计数为 1, 实际 %d", count)
+	}
+
+	// 窗口外的记录被丢弃
+	if count, _ := tracker.record("task_queue", start.Add(2*time.Minute)); count != 1 {
+		t.Errorf("期望窗口外计数重置为 1, 实际 %d", count)
+	}
+}
+
+func TestCallHandlerTimeout(t *testing.T) {
+	block := func(ctx context.Context, _ []byte) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := callHandler(context.Background(), nil, 10*time.Millisecond, block); !errors.Is(err, ErrProcessTimeout) {
+		t.Errorf("超时: err = %v, 期望 ErrProcessTimeout", err)
+	}
+
+	// 不限制处理时间时等待处理函数返回
+	slow := func(context.Context, []byte) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+	if err := callHandler(context.Background(), nil, 0, slow); err != nil {
+		t.Errorf("不限制处理时间: err = %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// depthTimeout 单次查询队列深度的超时时间
+const depthTimeout = 3 * time.Second
+
+// queueDepthDesc 队列积压消息数指标
+var queueDepthDesc = prometheus.NewDesc(
+	"microservice_mq_queue_depth",
+	"队列积压消息数（未投递 + 已投递未确认）",
+	[]string{"queue"}, nil,
+)
+
+// depthInspector 支持查询队列深度的消息代理
+type depthInspector interface {
+	MessageBroker
+	queueDepth(ctx context.Context, queueName string) (int64, error)
+	queueNames() []string
+}
+
+// depthCollector 在每次采集时查询当前消息代理的队列深度
+type depthCollector struct{}
+
+// Describe 实现 prometheus.Collector
+func (depthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+// Collect 实现 prometheus.Collector，查询失败的队列不输出
+func (depthCollector) Collect(ch chan<- prometheus.Metric) {
+	broker, ok := unwrapBroker(MQClient).(depthInspector)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), depthTimeout)
+	defer cancel()
+
+	for _, name := range broker.queueNames() {
+		depth, err := broker.queueDepth(ctx, name)
+		if err != nil {
+			logger.Warn("查询队列深度失败", zap.String("queue", name), zap.Error(err))
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth), name)
+	}
+}
+
+func init() {
+	metrics.Register(depthCollector{})
+}
+
+// queueNames 返回需要统计深度的队列
+func (rs *RedisStreams) queueNames() []string {
+	return names(rs.queueList())
+}
+
+// queueDepth 查询 stream 中消费组未读取（lag）与未确认（pending）的消息数
+func (rs *RedisStreams) queueDepth(ctx context.Context, queueName string) (int64, error) {
+	groups, err := rs.client.XInfoGroups(ctx, rs.streamKey(queueName)).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, g := range groups {
+		if g.Name == rs.config.Streams.Group {
+			return g.Lag + g.Pending, nil
+		}
+	}
+	return 0, fmt.Errorf("消费组 %s 不存在", rs.config.Streams.Group)
+}
+
+// names 提取队列名称
+func names(queues []config.QueueConfig) []string {
+	result := make([]string, 0, len(queues))
+	for _, q := range queues {
+		result = append(result, q.Name)
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/security"
+)
+
+const (
+	// headerEncryption 加密算法消息头
+	headerEncryption = "x-encryption"
+	// headerKeyVersion 密钥版本消息头
+	headerKeyVersion = "x-key-version"
+	// encryptionAlgorithm 加密算法
+	encryptionAlgorithm = "aes-256-gcm"
+)
+
+// encryptionCodec 消息体加密
+// 只加密匹配路由键前缀的消息，密钥版本写入消息头以支持轮换
+type encryptionCodec struct {
+	keys     security.KeyProvider
+	prefixes []string
+}
+
+// newEncryptionCodec 创建消息体加密编解码器
+func newEncryptionCodec(cfg config.MessageEncryptionConfig, keys security.KeyProvider) (*encryptionCodec, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("启用消息加密需要先初始化密钥")
+	}
+	return &encryptionCodec{keys: keys, prefixes: cfg.RoutingKeyPrefixes}, nil
+}
+
+// matches 检查路由键是否需要加密
+func (e *encryptionCodec) matches(routingKey string) bool {
+	for _, prefix := range e.prefixes {
+		if strings.HasPrefix(routingKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// encode 加密消息体
+func (e *encryptionCodec) encode(routingKey string, msg *message) error {
+	if !e.matches(routingKey) {
+		return nil
+	}
+
+	version, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("获取加密密钥失败: %w", err)
+	}
+	encryptor, err := security.NewEncryptor(string(key))
+	if err != nil {
+		return err
+	}
+
+	sealed, err := encryptor.Seal(msg.Body)
+	if err != nil {
+		return fmt.Errorf("加密消息失败: %w", err)
+	}
+
+	msg.Body = sealed
+	msg.Headers[headerEncryption] = encryptionAlgorithm
+	msg.Headers[headerKeyVersion] = version
+	return nil
+}
+
+// decode 解密消息体
+func (e *encryptionCodec) decode(msg *message) error {
+	algorithm := headerString(msg.Headers, headerEncryption)
+	if algorithm == "" {
+		return nil
+	}
+	if algorithm != encryptionAlgorithm {
+		return fmt.Errorf("不支持的加密算法: %s", algorithm)
+	}
+
+	key, err := e.keys.Key(headerString(msg.Headers, headerKeyVersion))
+	if err != nil {
+		return fmt.Errorf("获取解密密钥失败: %w", err)
+	}
+	encryptor, err := security.NewEncryptor(string(key))
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := encryptor.Open(msg.Body)
+	if err != nil {
+		return fmt.Errorf("解密消息失败: %w", err)
+	}
+
+	msg.Body = plaintext
+	delete(msg.Headers, headerEncryption)
+	delete(msg.Headers, headerKeyVersion)
+	return nil
+}
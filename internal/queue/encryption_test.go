@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/security"
+)
+
+func TestEncryptionCodec(t *testing.T) {
+	keys, err := security.NewStaticKeyProvider("v2", map[string]string{
+		"v1": "11111111111111111111111111111111",
+		"v2": "22222222222222222222222222222222",
+	})
+	if err != nil {
+		t.Fatalf("创建密钥提供者失败: %v", err)
+	}
+	codec, err := newEncryptionCodec(config.MessageEncryptionConfig{
+		Enable:             true,
+		RoutingKeyPrefixes: []string{"user."},
+	}, keys)
+	if err != nil {
+		t.Fatalf("创建编解码器失败: %v", err)
+	}
+
+	body := []byte(`{"email":"user@example.com"}`)
+
+	// 匹配前缀的消息被加密并记录密钥版本
+	msg := message{Body: body, Headers: map[string]interface{}{}}
+	if err := codec.encode("user.created", &msg); err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if bytes.Equal(msg.Body, body) {
+		t.Fatal("消息体未被加密")
+	}
+	if msg.Headers[headerKeyVersion] != "v2" {
+		t.Errorf("期望密钥版本 v2, 实际 %v", msg.Headers[headerKeyVersion])
+	}
+
+	delivery := message{Body: msg.Body, Headers: msg.Headers}
+	if err := codec.decode(&delivery); err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if !bytes.Equal(delivery.Body, body) {
+		t.Errorf("解密后数据不匹配: %s", delivery.Body)
+	}
+
+	// 不匹配前缀的消息保持明文
+	plain := message{Body: body, Headers: map[string]interface{}{}}
+	if err := codec.encode("task.run", &plain); err != nil {
+		t.Fatalf("编码失败: %v", err)
+	}
+	if !bytes.Equal(plain.Body, body) || len(plain.Headers) != 0 {
+		t.Error("未匹配前缀的消息不应加密")
+	}
+}
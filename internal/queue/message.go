@@ -0,0 +1,39 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/zhang/microservice/internal/ctxkeys"
+)
+
+// message 与具体代理无关的消息结构，编解码器在其上工作
+// RabbitMQ 与 Redis Streams 在发布/消费时与各自的消息格式互相转换
+type message struct {
+	RoutingKey      string
+	ContentType     string
+	ContentEncoding string
+	Headers         map[string]interface{}
+	Body            []byte
+}
+
+// newMessage 创建待发布的消息，上下文中的请求 ID 写入消息头
+func newMessage(ctx context.Context, routingKey string, body []byte) message {
+	msg := message{
+		RoutingKey:  routingKey,
+		ContentType: "application/json",
+		Body:        body,
+	}
+	if id := ctxkeys.RequestID(ctx); id != "" {
+		msg.Headers = map[string]interface{}{ctxkeys.RequestIDMetadata: id}
+	}
+	return msg
+}
+
+// handlerContext 返回处理消息的上下文，带有发布方传递的请求 ID（logger.FromContext 据此带上 request_id）
+func (m message) handlerContext() context.Context {
+	ctx := context.Background()
+	if id := headerString(m.Headers, ctxkeys.RequestIDMetadata); ctxkeys.ValidRequestID(id) {
+		ctx = ctxkeys.WithRequestID(ctx, id)
+	}
+	return ctx
+}
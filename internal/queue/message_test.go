@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zhang/microservice/internal/ctxkeys"
+)
+
+func TestMessageRequestID(t *testing.T) {
+	// 没有请求 ID 时不写消息头
+	if msg := newMessage(context.Background(), "task.run", []byte(`{}`)); msg.Headers != nil {
+		t.Errorf("消息头 = %v", msg.Headers)
+	}
+
+	ctx := ctxkeys.WithRequestID(context.Background(), "req-1")
+	msg := newMessage(ctx, "task.run", []byte(`{}`))
+	if msg.Headers[ctxkeys.RequestIDMetadata] != "req-1" {
+		t.Fatalf("消息头 = %v", msg.Headers)
+	}
+
+	// 编码后的消息头经过代理原样传回，处理函数的上下文带有同一个请求 ID
+	var got string
+	err := runHandler(msg.handlerContext(), "tasks", msg.Body, 0, func(ctx context.Context, body []byte) error {
+		got = ctxkeys.RequestID(ctx)
+		return nil
+	})
+	if err != nil || got != "req-1" {
+		t.Errorf("处理函数中的请求 ID = %q, err = %v", got, err)
+	}
+
+	// 格式不合法的请求 ID 不沿用
+	forged := message{Headers: map[string]interface{}{ctxkeys.RequestIDMetadata: "a\nb"}}
+	if id := ctxkeys.RequestID(forged.handlerContext()); id != "" {
+		t.Errorf("不应沿用不合法的请求 ID, 得到 %q", id)
+	}
+}
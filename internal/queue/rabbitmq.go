@@ -1,12 +1,19 @@
+//go:build !noamqp
+
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"github.com/zhang/microservice/internal/retry"
 	"go.uber.org/zap"
 )
 
@@ -16,37 +23,48 @@ type RabbitMQ struct {
 	channel   *amqp.Channel
 	config    config.RabbitMQConfig
 	reconnect chan bool
+	slow      *slowTracker
+	codecs    *codecChain
+	// mu 保护 config.Queues（运行时可追加队列）
+	mu sync.RWMutex
 }
 
-// MQClient 全局 RabbitMQ 客户端实例
-var MQClient *RabbitMQ
-
-// Init 初始化 RabbitMQ 连接
+// newRabbitMQ 创建 RabbitMQ 客户端并建立连接
 // 参数:
 //
 //	cfg: RabbitMQ 配置
 //
 // 返回:
 //
+//	*RabbitMQ: RabbitMQ 客户端
 //	error: 错误信息
-func Init(cfg config.RabbitMQConfig) error {
+func newRabbitMQ(cfg config.RabbitMQConfig) (*RabbitMQ, error) {
 	mq := &RabbitMQ{
 		config:    cfg,
 		reconnect: make(chan bool),
+		slow:      newSlowTracker(cfg.SlowConsumer),
 	}
 
-	// 建立连接
-	if err := mq.connect(); err != nil {
-		return err
+	codecs, err := newCodecChain(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mq.codecs = codecs
+
+	// 建立连接，RabbitMQ 尚未就绪时按 retry.startup_timeout 等待，认证失败立即返回
+	policy := retry.Startup("RabbitMQ")
+	policy.Retryable = isReconnectable
+	if err := retry.Do(context.Background(), policy, func(context.Context) error {
+		return mq.connect()
+	}); err != nil {
+		return nil, err
 	}
 
 	// 声明交换机和队列
 	if err := mq.setup(); err != nil {
-		return err
+		return nil, err
 	}
 
-	MQClient = mq
-
 	// 启动重连监听
 	go mq.handleReconnect()
 
@@ -55,7 +73,7 @@ func Init(cfg config.RabbitMQConfig) error {
 		zap.Int("port", cfg.Port),
 	)
 
-	return nil
+	return mq, nil
 }
 
 // connect 建立连接
@@ -71,6 +89,7 @@ func (mq *RabbitMQ) connect() error {
 	// 创建通道
 	mq.channel, err = mq.conn.Channel()
 	if err != nil {
+		mq.conn.Close()
 		return fmt.Errorf("创建 RabbitMQ 通道失败: %w", err)
 	}
 
@@ -94,35 +113,107 @@ func (mq *RabbitMQ) setup() error {
 	}
 
 	// 声明队列并绑定
-	for _, queueCfg := range mq.config.Queues {
-		_, err := mq.channel.QueueDeclare(
-			queueCfg.Name,
-			queueCfg.Durable,
-			false, // auto-delete
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return fmt.Errorf("声明队列 %s 失败: %w", queueCfg.Name, err)
+	for _, queueCfg := range mq.queueList() {
+		if err := mq.declareQueue(queueCfg); err != nil {
+			return err
 		}
+	}
 
-		// 绑定队列到交换机
-		err = mq.channel.QueueBind(
-			queueCfg.Name,
-			queueCfg.RoutingKey,
-			mq.config.Exchange.Name,
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return fmt.Errorf("绑定队列 %s 失败: %w", queueCfg.Name, err)
+	// 声明慢消费转存队列和死信队列（不绑定交换机，由消费者直接投递）
+	for _, name := range []string{mq.config.SlowConsumer.ParkQueue, mq.config.DeadLetterQueue} {
+		if name == "" {
+			continue
+		}
+		if _, err := mq.channel.QueueDeclare(name, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("声明队列 %s 失败: %w", name, err)
 		}
 	}
 
 	return nil
 }
 
+// queueArguments 按队列配置生成 QueueDeclare 参数（消息 TTL、最大长度、溢出行为），未配置时返回 nil
+func queueArguments(queueCfg config.QueueConfig) amqp.Table {
+	args := amqp.Table{}
+	if ttl := queueCfg.GetMessageTTL(); ttl > 0 {
+		args["x-message-ttl"] = ttl.Milliseconds()
+	}
+	if queueCfg.MaxLength > 0 {
+		args["x-max-length"] = int64(queueCfg.MaxLength)
+		if queueCfg.Overflow != "" {
+			args["x-overflow"] = queueCfg.Overflow
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// declareQueue 声明队列并绑定到交换机
+func (mq *RabbitMQ) declareQueue(queueCfg config.QueueConfig) error {
+	_, err := mq.channel.QueueDeclare(
+		queueCfg.Name,
+		queueCfg.Durable,
+		false, // auto-delete
+		false, // exclusive
+		false, // no-wait
+		queueArguments(queueCfg),
+	)
+	if err != nil {
+		// 参数与已存在的队列不一致时 RabbitMQ 返回 PRECONDITION_FAILED，需删除队列后重新声明
+		return fmt.Errorf("声明队列 %s 失败: %w", queueCfg.Name, err)
+	}
+
+	// 绑定队列到交换机
+	err = mq.channel.QueueBind(
+		queueCfg.Name,
+		queueCfg.RoutingKey,
+		mq.config.Exchange.Name,
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("绑定队列 %s 失败: %w", queueCfg.Name, err)
+	}
+
+	return nil
+}
+
+// queueList 获取当前的队列配置
+func (mq *RabbitMQ) queueList() []config.QueueConfig {
+	mq.mu.RLock()
+	defer mq.mu.RUnlock()
+	return append([]config.QueueConfig(nil), mq.config.Queues...)
+}
+
+// DeclareQueues 运行时声明额外的队列
+// 新队列会记入配置，断线重连后自动重新声明
+// 参数:
+//
+//	queues: 队列配置
+//
+// 返回:
+//
+//	error: 错误信息
+func (mq *RabbitMQ) DeclareQueues(queues []config.QueueConfig) error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	for _, q := range queues {
+		if containsQueue(mq.config.Queues, q.Name) {
+			continue
+		}
+		if err := mq.declareQueue(q); err != nil {
+			return err
+		}
+		mq.config.Queues = append(mq.config.Queues, q)
+		logger.Info("声明新队列", zap.String("queue", q.Name), zap.String("routing_key", q.RoutingKey))
+	}
+
+	return nil
+}
+
 // handleReconnect 处理自动重连
 func (mq *RabbitMQ) handleReconnect() {
 	for {
@@ -136,22 +227,16 @@ func (mq *RabbitMQ) handleReconnect() {
 			zap.Error(reason),
 		)
 
-		// 尝试重连
-		for {
-			time.Sleep(5 * time.Second)
+		// 按 1 秒起、最长 30 秒的退避时间重连，直到成功
+		policy := retry.Policy{Name: "重连 RabbitMQ", Initial: time.Second, Max: 30 * time.Second}
+		_ = retry.Do(context.Background(), policy, func(context.Context) error {
 			if err := mq.connect(); err != nil {
-				logger.Error("RabbitMQ 重连失败", zap.Error(err))
-				continue
+				return err
 			}
+			return mq.setup()
+		})
 
-			if err := mq.setup(); err != nil {
-				logger.Error("RabbitMQ 设置失败", zap.Error(err))
-				continue
-			}
-
-			logger.Info("RabbitMQ 重连成功")
-			break
-		}
+		logger.Info("RabbitMQ 重连成功")
 	}
 }
 
@@ -165,20 +250,49 @@ func (mq *RabbitMQ) handleReconnect() {
 //
 //	error: 错误信息
 func (mq *RabbitMQ) Publish(routingKey string, body []byte) error {
-	return mq.channel.Publish(
-		mq.config.Exchange.Name,
-		routingKey,
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-			Timestamp:   time.Now(),
-		},
-	)
+	return mq.PublishContext(context.Background(), routingKey, body)
+}
+
+// PublishContext 发布消息，上下文中的请求 ID 写入消息头 x-request-id
+// 参数:
+//
+//	ctx: 上下文
+//	routingKey: 路由键
+//	body: 消息内容
+//
+// 返回:
+//
+//	error: 错误信息
+func (mq *RabbitMQ) PublishContext(ctx context.Context, routingKey string, body []byte) error {
+	msg := newMessage(ctx, routingKey, body)
+
+	// 按配置压缩、加密、转存
+	if err := mq.codecs.encode(routingKey, &msg); err != nil {
+		return err
+	}
+
+	// 连接正在重建时通道已关闭，按 retry.attempts 重试
+	err := retry.Do(context.Background(), retry.Transient("发布消息", isAMQPError), func(context.Context) error {
+		return mq.channel.Publish(
+			mq.config.Exchange.Name,
+			routingKey,
+			false, // mandatory
+			false, // immediate
+			amqp.Publishing{
+				Headers:         amqp.Table(msg.Headers),
+				ContentType:     msg.ContentType,
+				ContentEncoding: msg.ContentEncoding,
+				Body:            msg.Body,
+				Timestamp:       time.Now(),
+			},
+		)
+	})
+	metrics.ObservePublish(routingKey, err)
+	return err
 }
 
 // Consume 消费消息
+// 处理函数无法感知取消，不应用 process_timeout（超时后处理函数仍在运行，转存会导致重复处理），需要超时控制时使用 ConsumeContext
 // 参数:
 //
 //	queueName: 队列名称
@@ -188,6 +302,42 @@ func (mq *RabbitMQ) Publish(routingKey string, body []byte) error {
 //
 //	error: 错误信息
 func (mq *RabbitMQ) Consume(queueName string, handler func([]byte) error) error {
+	return mq.consume(queueName, func(_ context.Context, body []byte) error {
+		return handler(body)
+	}, 0)
+}
+
+// Close 关闭连接
+func (mq *RabbitMQ) Close() error {
+	if mq.channel != nil {
+		if err := mq.channel.Close(); err != nil {
+			return err
+		}
+	}
+	if mq.conn != nil {
+		return mq.conn.Close()
+	}
+	return nil
+}
+
+// ConsumeContext 消费消息（支持处理超时）
+// 处理时间超过队列配置的 process_timeout 时取消 ctx，
+// 并把消息转存到慢消费队列（nack-and-park），避免单条异常消息拖垮整个队列
+// 参数:
+//
+//	queueName: 队列名称
+//	handler: 消息处理函数
+//
+// 返回:
+//
+//	error: 错误信息
+func (mq *RabbitMQ) ConsumeContext(queueName string, handler ContextHandler) error {
+	queueCfg := findQueueConfig(mq.queueList(), queueName)
+	return mq.consume(queueName, handler, queueCfg.GetProcessTimeout())
+}
+
+// consume 开始消费队列，timeout 为 0 时不限制处理时间
+func (mq *RabbitMQ) consume(queueName string, handler ContextHandler, timeout time.Duration) error {
 	msgs, err := mq.channel.Consume(
 		queueName,
 		"",    // consumer
@@ -203,48 +353,189 @@ func (mq *RabbitMQ) Consume(queueName string, handler func([]byte) error) error
 
 	// 处理消息
 	go func() {
-		for msg := range msgs {
+		for d := range msgs {
 			logger.Debug("收到消息",
 				zap.String("queue", queueName),
-				zap.String("routing_key", msg.RoutingKey),
+				zap.String("routing_key", d.RoutingKey),
 			)
 
-			// 处理消息
-			if err := handler(msg.Body); err != nil {
+			// 还原消息体（取回转存、解密、解压），失败的消息无法处理，转存到死信队列
+			msg := fromDelivery(d)
+			if err := mq.codecs.decode(&msg); err != nil {
+				mq.deadLetter(queueName, d, err)
+				continue
+			}
+
+			err := runHandler(msg.handlerContext(), queueName, msg.Body, timeout, handler)
+			switch {
+			case errors.Is(err, ErrProcessTimeout):
+				mq.park(queueName, d, timeout)
+			case err != nil:
 				logger.Error("处理消息失败",
 					zap.String("queue", queueName),
 					zap.Error(err),
 				)
 				// 消息处理失败，拒绝并重新入队
-				msg.Nack(false, true)
-			} else {
-				// 消息处理成功，确认
-				msg.Ack(false)
+				d.Nack(false, true)
+			default:
+				// 消息处理成功，确认并删除转存对象
+				d.Ack(false)
+				mq.codecs.release(msg)
 			}
 		}
 	}()
 
-	logger.Info("开始消费队列", zap.String("queue", queueName))
+	logger.Info("开始消费队列",
+		zap.String("queue", queueName),
+		zap.Duration("process_timeout", timeout),
+	)
 	return nil
 }
 
-// Close 关闭连接
-func (mq *RabbitMQ) Close() error {
-	if mq.channel != nil {
-		if err := mq.channel.Close(); err != nil {
-			return err
+// park 把处理超时的消息转存到慢消费队列并拒绝原消息
+// 转存原始投递的消息体和头（仍为加密、压缩或转存引用的形式），重新投递后按相同流程还原
+func (mq *RabbitMQ) park(queueName string, d amqp.Delivery, timeout time.Duration) {
+	parkQueue := mq.config.SlowConsumer.ParkQueue
+
+	logger.Warn("消息处理超时",
+		zap.String("queue", queueName),
+		zap.String("routing_key", d.RoutingKey),
+		zap.Duration("timeout", timeout),
+		zap.String("park_queue", parkQueue),
+	)
+
+	if parkQueue != "" {
+		err := mq.forward(parkQueue, d, amqp.Table{
+			"x-original-queue":       queueName,
+			"x-original-routing-key": d.RoutingKey,
+			"x-process-timeout-ms":   timeout.Milliseconds(),
+			"x-parked-at":            time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			logger.Error("转存慢消息失败，重新入队",
+				zap.String("queue", queueName),
+				zap.Error(err),
+			)
+			d.Nack(false, true)
+			return
 		}
 	}
-	if mq.conn != nil {
-		return mq.conn.Close()
+
+	d.Nack(false, false)
+
+	count, alert := mq.slow.record(queueName, time.Now())
+	if alert {
+		logger.Error("队列慢消费告警",
+			zap.String("queue", queueName),
+			zap.Int("timeouts", count),
+			zap.Duration("window", mq.config.SlowConsumer.GetAlertWindow()),
+		)
 	}
-	return nil
 }
 
-// Close 关闭 RabbitMQ 连接
-func Close() error {
-	if MQClient != nil {
-		return MQClient.Close()
+// deadLetter 把无法解码的消息原样转存到死信队列并拒绝原消息，未配置死信队列时直接丢弃
+func (mq *RabbitMQ) deadLetter(queueName string, d amqp.Delivery, cause error) {
+	deadLetterQueue := mq.config.DeadLetterQueue
+
+	logger.Error("解码消息失败",
+		zap.String("queue", queueName),
+		zap.String("routing_key", d.RoutingKey),
+		zap.String("dead_letter_queue", deadLetterQueue),
+		zap.Error(cause),
+	)
+	metrics.ObserveConsume(queueName, "decode_error")
+
+	if deadLetterQueue != "" {
+		err := mq.forward(deadLetterQueue, d, amqp.Table{
+			"x-original-queue":       queueName,
+			"x-original-routing-key": d.RoutingKey,
+			"x-decode-error":         cause.Error(),
+			"x-dead-lettered-at":     time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			logger.Error("转存死信消息失败，重新入队",
+				zap.String("queue", queueName),
+				zap.Error(err),
+			)
+			d.Nack(false, true)
+			return
+		}
 	}
-	return nil
+
+	d.Nack(false, false)
+}
+
+// forward 通过默认交换机把原始投递（消息体和头保持不变）投递到指定队列
+// 参数:
+//
+//	queueName: 目标队列
+//	d: 原始投递
+//	extra: 追加的消息头
+//
+// 返回:
+//
+//	error: 错误信息
+func (mq *RabbitMQ) forward(queueName string, d amqp.Delivery, extra amqp.Table) error {
+	headers := make(amqp.Table, len(d.Headers)+len(extra))
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	for k, v := range extra {
+		headers[k] = v
+	}
+	return mq.channel.Publish("", queueName, false, false, amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		Body:            d.Body,
+		Timestamp:       d.Timestamp,
+		DeliveryMode:    amqp.Persistent,
+	})
+}
+
+// queueNames 返回需要统计深度的队列（含慢消费转存队列和死信队列，用于告警）
+func (mq *RabbitMQ) queueNames() []string {
+	result := names(mq.queueList())
+	for _, name := range []string{mq.config.SlowConsumer.ParkQueue, mq.config.DeadLetterQueue} {
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// queueDepth 查询 RabbitMQ 队列中待投递与未确认的消息数
+func (mq *RabbitMQ) queueDepth(_ context.Context, queueName string) (int64, error) {
+	q, err := mq.channel.QueueInspect(queueName)
+	if err != nil {
+		return 0, err
+	}
+	return int64(q.Messages), nil
+}
+
+// fromDelivery 把 RabbitMQ 投递转换为统一的消息结构
+// 复制消息头，解码时删除的编码头不影响原始投递（转存时需要原样保留）
+func fromDelivery(d amqp.Delivery) message {
+	headers := make(map[string]interface{}, len(d.Headers))
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	return message{
+		RoutingKey:      d.RoutingKey,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		Headers:         headers,
+		Body:            d.Body,
+	}
+}
+
+// isAMQPError 是否为 RabbitMQ 连接或通道错误（如连接已断开、通道已关闭）
+func isAMQPError(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr)
+}
+
+// isReconnectable 连接失败后是否应重试，认证失败不重试
+func isReconnectable(err error) bool {
+	return isBrokerUnavailable(err) && !errors.Is(err, amqp.ErrCredentials)
 }
@@ -0,0 +1,19 @@
+//go:build noamqp
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// newRabbitMQ 以 noamqp 构建标签编译时不包含 RabbitMQ 客户端，只能使用 redis_streams 驱动
+func newRabbitMQ(cfg config.RabbitMQConfig) (MessageBroker, error) {
+	return nil, fmt.Errorf("当前构建未包含 RabbitMQ 支持（noamqp），请将 rabbitmq.driver 设置为 %s", DriverRedisStreams)
+}
+
+// isAMQPError 以 noamqp 构建标签编译时不存在 RabbitMQ 错误
+func isAMQPError(err error) bool {
+	return false
+}
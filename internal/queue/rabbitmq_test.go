@@ -0,0 +1,44 @@
+//go:build !noamqp
+
+package queue
+
+import (
+	"testing"
+
+	"github.com/streadway/amqp"
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestQueueArguments(t *testing.T) {
+	if args := queueArguments(config.QueueConfig{Name: "task_queue"}); args != nil {
+		t.Errorf("未配置时参数应为 nil, 实际 %v", args)
+	}
+
+	args := queueArguments(config.QueueConfig{Name: "email_queue", MessageTTL: 3600, MaxLength: 1000, Overflow: "reject-publish"})
+	want := amqp.Table{"x-message-ttl": int64(3600000), "x-max-length": int64(1000), "x-overflow": "reject-publish"}
+	if len(args) != len(want) {
+		t.Fatalf("参数 = %v, 期望 %v", args, want)
+	}
+	for k, v := range want {
+		if args[k] != v {
+			t.Errorf("%s = %v, 期望 %v", k, args[k], v)
+		}
+	}
+
+	// 未限制长度时溢出行为无意义
+	args = queueArguments(config.QueueConfig{Name: "task_queue", Overflow: "drop-head"})
+	if args != nil {
+		t.Errorf("参数应为 nil, 实际 %v", args)
+	}
+}
+
+func TestFromDeliveryCopiesHeaders(t *testing.T) {
+	d := amqp.Delivery{Headers: amqp.Table{headerEncryption: encryptionAlgorithm}, Body: []byte("x")}
+	msg := fromDelivery(d)
+	delete(msg.Headers, headerEncryption)
+
+	// 转存到慢消费队列或死信队列时需要原始投递的消息头
+	if d.Headers[headerEncryption] != encryptionAlgorithm {
+		t.Errorf("解码不应修改原始投递的消息头: %v", d.Headers)
+	}
+}
@@ -0,0 +1,443 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// RedisStreams 基于 Redis Streams 的消息代理
+// 每个队列对应一个 stream，通过消费组实现至少一次投递；
+// 未确认的消息超过 claim_idle 后被重新认领处理，适合不部署 RabbitMQ 的单节点环境
+type RedisStreams struct {
+	client   *redis.Client
+	config   config.RabbitMQConfig
+	codecs   *codecChain
+	slow     *slowTracker
+	consumer string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	// mu 保护 config.Queues（运行时可追加队列）
+	mu sync.RWMutex
+}
+
+// newRedisStreams 创建 Redis Streams 消息代理
+// 依赖 cache.Init 已完成
+// 参数:
+//
+//	cfg: 消息队列配置
+//
+// 返回:
+//
+//	*RedisStreams: 消息代理
+//	error: 错误信息
+func newRedisStreams(cfg config.RabbitMQConfig) (*RedisStreams, error) {
+	if cache.RedisClient == nil {
+		return nil, fmt.Errorf("Redis Streams 需要先初始化 Redis")
+	}
+
+	codecs, err := newCodecChain(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	rs := &RedisStreams{
+		client:   cache.RedisClient,
+		config:   cfg,
+		codecs:   codecs,
+		slow:     newSlowTracker(cfg.SlowConsumer),
+		consumer: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+
+	// 为每个队列创建消费组
+	for _, q := range cfg.Queues {
+		if err := rs.ensureGroup(q.Name); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	logger.Info("Redis Streams 消息代理初始化成功",
+		zap.String("group", cfg.Streams.Group),
+		zap.String("consumer", rs.consumer),
+	)
+
+	return rs, nil
+}
+
+// streamKey 队列对应的 stream 键名
+func (rs *RedisStreams) streamKey(queueName string) string {
+	return rs.config.Streams.KeyPrefix + queueName
+}
+
+// ensureGroup 创建消费组（已存在时忽略）
+func (rs *RedisStreams) ensureGroup(queueName string) error {
+	err := rs.client.XGroupCreateMkStream(rs.ctx, rs.streamKey(queueName), rs.config.Streams.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("创建消费组 %s 失败: %w", queueName, err)
+	}
+	return nil
+}
+
+// Publish 发布消息
+// 按队列绑定的路由键（topic 规则）投递到所有匹配的 stream
+// 参数:
+//
+//	routingKey: 路由键
+//	body: 消息内容
+//
+// 返回:
+//
+//	error: 错误信息
+func (rs *RedisStreams) Publish(routingKey string, body []byte) error {
+	return rs.PublishContext(context.Background(), routingKey, body)
+}
+
+// PublishContext 发布消息，上下文中的请求 ID 写入消息头 x-request-id
+// 参数:
+//
+//	ctx: 上下文
+//	routingKey: 路由键
+//	body: 消息内容
+//
+// 返回:
+//
+//	error: 错误信息
+func (rs *RedisStreams) PublishContext(ctx context.Context, routingKey string, body []byte) error {
+	msg := newMessage(ctx, routingKey, body)
+
+	// 按配置压缩、加密、转存
+	if err := rs.codecs.encode(routingKey, &msg); err != nil {
+		return err
+	}
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return fmt.Errorf("序列化消息头失败: %w", err)
+	}
+	values := map[string]interface{}{
+		"routing_key":      routingKey,
+		"content_type":     msg.ContentType,
+		"content_encoding": msg.ContentEncoding,
+		"headers":          string(headers),
+		"body":             msg.Body,
+	}
+
+	for _, q := range rs.queueList() {
+		if !MatchTopic(q.RoutingKey, routingKey) {
+			continue
+		}
+		if err := rs.add(rs.streamKey(q.Name), q.MaxLength, values); err != nil {
+			metrics.ObservePublish(routingKey, err)
+			return fmt.Errorf("发布消息到 %s 失败: %w", q.Name, err)
+		}
+	}
+
+	metrics.ObservePublish(routingKey, nil)
+	return nil
+}
+
+// queueList 获取当前的队列配置
+func (rs *RedisStreams) queueList() []config.QueueConfig {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return append([]config.QueueConfig(nil), rs.config.Queues...)
+}
+
+// DeclareQueues 运行时声明额外的队列
+// 参数:
+//
+//	queues: 队列配置
+//
+// 返回:
+//
+//	error: 错误信息
+func (rs *RedisStreams) DeclareQueues(queues []config.QueueConfig) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, q := range queues {
+		if containsQueue(rs.config.Queues, q.Name) {
+			continue
+		}
+		if err := rs.ensureGroup(q.Name); err != nil {
+			return err
+		}
+		rs.config.Queues = append(rs.config.Queues, q)
+		logger.Info("声明新队列", zap.String("queue", q.Name), zap.String("routing_key", q.RoutingKey))
+	}
+
+	return nil
+}
+
+// add 追加消息到 stream
+// maxLen 为队列的 max_length，0 时使用 streams.max_len；裁剪总是丢弃最老的消息（相当于 drop-head）
+func (rs *RedisStreams) add(stream string, maxLen int, values map[string]interface{}) error {
+	limit := rs.config.Streams.MaxLen
+	if maxLen > 0 {
+		limit = int64(maxLen)
+	}
+	return rs.client.XAdd(rs.ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: limit,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// Consume 消费消息
+// 处理函数无法感知取消，不应用 process_timeout，需要超时控制时使用 ConsumeContext
+// 参数:
+//
+//	queueName: 队列名称
+//	handler: 消息处理函数
+//
+// 返回:
+//
+//	error: 错误信息
+func (rs *RedisStreams) Consume(queueName string, handler func([]byte) error) error {
+	return rs.consume(queueName, func(_ context.Context, body []byte) error {
+		return handler(body)
+	}, 0)
+}
+
+// ConsumeContext 消费消息（支持处理超时）
+// 处理失败的消息不确认，留在待处理列表中，空闲超过 claim_idle 后被重新认领
+// 参数:
+//
+//	queueName: 队列名称
+//	handler: 消息处理函数
+//
+// 返回:
+//
+//	error: 错误信息
+func (rs *RedisStreams) ConsumeContext(queueName string, handler ContextHandler) error {
+	queueCfg := findQueueConfig(rs.queueList(), queueName)
+	return rs.consume(queueName, handler, queueCfg.GetProcessTimeout())
+}
+
+// consume 开始消费队列，timeout 为 0 时不限制处理时间
+func (rs *RedisStreams) consume(queueName string, handler ContextHandler, timeout time.Duration) error {
+	if err := rs.ensureGroup(queueName); err != nil {
+		return err
+	}
+
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		rs.consumeLoop(queueName, timeout, handler)
+	}()
+
+	logger.Info("开始消费队列",
+		zap.String("queue", queueName),
+		zap.String("driver", DriverRedisStreams),
+		zap.Duration("process_timeout", timeout),
+	)
+	return nil
+}
+
+// consumeLoop 消费循环
+func (rs *RedisStreams) consumeLoop(queueName string, timeout time.Duration, handler ContextHandler) {
+	stream := rs.streamKey(queueName)
+	claimIdle := rs.config.Streams.GetClaimIdle()
+	lastClaim := time.Time{}
+
+	for rs.ctx.Err() == nil {
+		// 定期认领其他消费者（或本消费者）长时间未确认的消息
+		if claimIdle > 0 && time.Since(lastClaim) >= claimIdle {
+			lastClaim = time.Now()
+			claimed, _, err := rs.client.XAutoClaim(rs.ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    rs.config.Streams.Group,
+				Consumer: rs.consumer,
+				MinIdle:  claimIdle,
+				Start:    "0-0",
+				Count:    10,
+			}).Result()
+			if err != nil && rs.ctx.Err() == nil {
+				logger.Error("认领待处理消息失败", zap.String("queue", queueName), zap.Error(err))
+			}
+			for _, m := range claimed {
+				rs.handle(queueName, m, timeout, handler)
+			}
+		}
+
+		streams, err := rs.client.XReadGroup(rs.ctx, &redis.XReadGroupArgs{
+			Group:    rs.config.Streams.Group,
+			Consumer: rs.consumer,
+			Streams:  []string{stream, ">"},
+			Count:    10,
+			Block:    5 * time.Second,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) || rs.ctx.Err() != nil {
+				continue
+			}
+			logger.Error("读取 stream 失败", zap.String("queue", queueName), zap.Error(err))
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, m := range s.Messages {
+				rs.handle(queueName, m, timeout, handler)
+			}
+		}
+	}
+}
+
+// handle 处理单条消息
+func (rs *RedisStreams) handle(queueName string, m redis.XMessage, timeout time.Duration, handler ContextHandler) {
+	stream := rs.streamKey(queueName)
+	delivery := fromStream(m)
+
+	logger.Debug("收到消息",
+		zap.String("queue", queueName),
+		zap.String("routing_key", delivery.RoutingKey),
+		zap.String("id", m.ID),
+	)
+
+	// 还原消息体，失败的消息无法处理，转存到死信 stream
+	if err := rs.codecs.decode(&delivery); err != nil {
+		rs.deadLetter(queueName, m, err)
+		return
+	}
+
+	err := runHandler(delivery.handlerContext(), queueName, delivery.Body, timeout, handler)
+	switch {
+	case errors.Is(err, ErrProcessTimeout):
+		rs.park(queueName, m, timeout)
+	case err != nil:
+		// 不确认，等待重新认领
+		logger.Error("处理消息失败",
+			zap.String("queue", queueName),
+			zap.String("id", m.ID),
+			zap.Error(err),
+		)
+	default:
+		rs.ack(stream, m.ID)
+		rs.codecs.release(delivery)
+	}
+}
+
+// park 把处理超时的消息转存到慢消费 stream 并确认原消息
+func (rs *RedisStreams) park(queueName string, m redis.XMessage, timeout time.Duration) {
+	parkQueue := rs.config.SlowConsumer.ParkQueue
+
+	logger.Warn("消息处理超时",
+		zap.String("queue", queueName),
+		zap.String("id", m.ID),
+		zap.Duration("timeout", timeout),
+		zap.String("park_queue", parkQueue),
+	)
+
+	if parkQueue != "" {
+		values := make(map[string]interface{}, len(m.Values)+2)
+		for k, v := range m.Values {
+			values[k] = v
+		}
+		values["original_queue"] = queueName
+		values["parked_at"] = time.Now().Format(time.RFC3339)
+
+		if err := rs.add(rs.streamKey(parkQueue), 0, values); err != nil {
+			// 不确认，等待重新认领
+			logger.Error("转存慢消息失败", zap.String("queue", queueName), zap.Error(err))
+			return
+		}
+	}
+
+	rs.ack(rs.streamKey(queueName), m.ID)
+
+	count, alert := rs.slow.record(queueName, time.Now())
+	if alert {
+		logger.Error("队列慢消费告警",
+			zap.String("queue", queueName),
+			zap.Int("timeouts", count),
+			zap.Duration("window", rs.config.SlowConsumer.GetAlertWindow()),
+		)
+	}
+}
+
+// deadLetter 把无法解码的消息原样转存到死信 stream 并确认原消息，未配置死信队列时直接丢弃
+func (rs *RedisStreams) deadLetter(queueName string, m redis.XMessage, cause error) {
+	deadLetterQueue := rs.config.DeadLetterQueue
+
+	logger.Error("解码消息失败",
+		zap.String("queue", queueName),
+		zap.String("id", m.ID),
+		zap.String("dead_letter_queue", deadLetterQueue),
+		zap.Error(cause),
+	)
+	metrics.ObserveConsume(queueName, "decode_error")
+
+	if deadLetterQueue != "" {
+		values := make(map[string]interface{}, len(m.Values)+3)
+		for k, v := range m.Values {
+			values[k] = v
+		}
+		values["original_queue"] = queueName
+		values["decode_error"] = cause.Error()
+		values["dead_lettered_at"] = time.Now().Format(time.RFC3339)
+
+		if err := rs.add(rs.streamKey(deadLetterQueue), 0, values); err != nil {
+			// 不确认，等待重新认领
+			logger.Error("转存死信消息失败", zap.String("queue", queueName), zap.Error(err))
+			return
+		}
+	}
+
+	rs.ack(rs.streamKey(queueName), m.ID)
+}
+
+// ack 确认消息
+func (rs *RedisStreams) ack(stream, id string) {
+	if err := rs.client.XAck(rs.ctx, stream, rs.config.Streams.Group, id).Err(); err != nil {
+		logger.Error("确认消息失败", zap.String("stream", stream), zap.String("id", id), zap.Error(err))
+	}
+}
+
+// fromStream 把 stream 消息转换为统一的消息结构，以复用编解码器
+func fromStream(m redis.XMessage) message {
+	str := func(key string) string {
+		v, _ := m.Values[key].(string)
+		return v
+	}
+
+	headers := map[string]interface{}{}
+	if raw := str("headers"); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &headers)
+	}
+
+	return message{
+		RoutingKey:      str("routing_key"),
+		ContentType:     str("content_type"),
+		ContentEncoding: str("content_encoding"),
+		Headers:         headers,
+		Body:            []byte(str("body")),
+	}
+}
+
+// Close 停止所有消费循环
+// Redis 连接由 cache 包管理，这里不关闭
+func (rs *RedisStreams) Close() error {
+	rs.cancel()
+	rs.wg.Wait()
+	return nil
+}
@@ -0,0 +1,246 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/notify"
+	"go.uber.org/zap"
+)
+
+// 内置配额名称
+const (
+	// Storage 存储空间（字节）
+	Storage = "storage"
+	// API API 请求次数
+	API = "api"
+)
+
+// Status 配额使用情况
+type Status struct {
+	Name    string     `json:"name"`
+	Used    int64      `json:"used"`
+	Limit   int64      `json:"limit"`
+	Percent float64    `json:"percent"`
+	ResetAt *time.Time `json:"reset_at,omitempty"`
+}
+
+// limit 单项配额定义
+type limit struct {
+	max    int64
+	window time.Duration
+}
+
+// Engine 配额统计与提醒
+// 使用量保存在 Redis 中，超过阈值时通过 notify 发送提醒（按 cooldown 限频），不拒绝请求
+type Engine struct {
+	limits     map[string]limit
+	thresholds []int
+	cooldown   time.Duration
+	now        func() time.Time
+}
+
+// DefaultEngine 全局配额引擎
+var DefaultEngine = NewEngine(config.QuotaConfig{})
+
+// NewEngine 创建配额引擎
+// 参数:
+//
+//	cfg: 配额配置
+//
+// 返回:
+//
+//	*Engine: 配额引擎
+func NewEngine(cfg config.QuotaConfig) *Engine {
+	limits := make(map[string]limit, len(cfg.Limits))
+	for name, l := range cfg.Limits {
+		if l.Limit <= 0 {
+			continue
+		}
+		limits[name] = limit{max: l.Limit, window: l.GetWindow()}
+	}
+
+	thresholds := append([]int(nil), cfg.Thresholds...)
+	if len(thresholds) == 0 {
+		thresholds = []int{80, 95}
+	}
+	sort.Ints(thresholds)
+
+	cooldown := cfg.GetAlertCooldown()
+	if cooldown <= 0 {
+		cooldown = 24 * time.Hour
+	}
+
+	return &Engine{
+		limits:     limits,
+		thresholds: thresholds,
+		cooldown:   cooldown,
+		now:        time.Now,
+	}
+}
+
+// Init 按配置初始化全局配额引擎
+// 参数:
+//
+//	cfg: 配额配置
+func Init(cfg config.QuotaConfig) {
+	DefaultEngine = NewEngine(cfg)
+}
+
+// usageKey 返回当前统计周期的使用量键及周期结束时间
+func (e *Engine) usageKey(subject, name string, l limit) (string, *time.Time) {
+	if l.window <= 0 {
+		return fmt.Sprintf("quota:usage:%s:%s", name, subject), nil
+	}
+	now := e.now()
+	bucket := now.Unix() / int64(l.window.Seconds())
+	reset := time.Unix((bucket+1)*int64(l.window.Seconds()), 0)
+	return fmt.Sprintf("quota:usage:%s:%s:%d", name, subject, bucket), &reset
+}
+
+// Add 增加使用量，跨过提醒阈值时发送通知
+// 参数:
+//
+//	ctx: 上下文
+//	subject: 配额主体（如 user:1、tenant:acme）
+//	name: 配额名称
+//	delta: 增加量
+//
+// 返回:
+//
+//	error: 错误信息（未配置的配额直接忽略）
+func (e *Engine) Add(ctx context.Context, subject, name string, delta int64) error {
+	l, ok := e.limits[name]
+	if !ok || delta == 0 {
+		return nil
+	}
+
+	key, reset := e.usageKey(subject, name, l)
+	used, err := cache.RedisClient.IncrBy(ctx, key, delta).Result()
+	if err != nil {
+		return err
+	}
+	if reset != nil && used == delta {
+		// 周期内首次写入，设置过期时间
+		cache.RedisClient.ExpireAt(ctx, key, *reset)
+	}
+
+	before := used - delta
+	for _, t := range crossed(before, used, l.max, e.thresholds) {
+		e.alert(ctx, subject, name, t, used, l.max)
+	}
+	return nil
+}
+
+// crossed 返回从 before 增加到 after 时跨过的阈值
+func crossed(before, after, max int64, thresholds []int) []int {
+	var result []int
+	for _, t := range thresholds {
+		mark := max * int64(t) / 100
+		if before < mark && after >= mark {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// alert 发送阈值提醒，cooldown 内同一主体同一阈值只发送一次
+func (e *Engine) alert(ctx context.Context, subject, name string, threshold int, used, max int64) {
+	key := fmt.Sprintf("quota:alerted:%s:%s:%d", name, subject, threshold)
+	ok, err := cache.RedisClient.SetNX(ctx, key, 1, e.cooldown).Result()
+	if err != nil {
+		logger.Error("记录配额提醒失败", zap.String("subject", subject), zap.Error(err))
+		return
+	}
+	if !ok {
+		return
+	}
+
+	logger.Warn("配额使用超过阈值",
+		zap.String("subject", subject),
+		zap.String("quota", name),
+		zap.Int("threshold", threshold),
+		zap.Int64("used", used),
+		zap.Int64("limit", max),
+	)
+
+	msg := notify.Message{
+		Event: "quota.threshold",
+		Title: fmt.Sprintf("配额提醒: %s 的 %s 已使用 %d%%", subject, name, threshold),
+		Text:  fmt.Sprintf("%s 的 %s 配额已使用 %d / %d。", subject, name, used, max),
+		Data: map[string]interface{}{
+			"subject":   subject,
+			"quota":     name,
+			"threshold": threshold,
+			"used":      used,
+			"limit":     max,
+		},
+	}
+
+	// 异步发送，避免阻塞请求
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := notify.Send(sendCtx, msg); err != nil {
+			logger.Error("发送配额提醒失败", zap.String("subject", subject), zap.Error(err))
+		}
+	}()
+}
+
+// Status 查询主体的所有配额使用情况，按名称排序
+// 参数:
+//
+//	ctx: 上下文
+//	subject: 配额主体
+//
+// 返回:
+//
+//	[]Status: 配额使用情况
+//	error: 错误信息
+func (e *Engine) Status(ctx context.Context, subject string) ([]Status, error) {
+	names := make([]string, 0, len(e.limits))
+	for name := range e.limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]Status, 0, len(names))
+	for _, name := range names {
+		l := e.limits[name]
+		key, reset := e.usageKey(subject, name, l)
+
+		used, err := cache.RedisClient.Get(ctx, key).Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+
+		result = append(result, Status{
+			Name:    name,
+			Used:    used,
+			Limit:   l.max,
+			Percent: float64(used) * 100 / float64(l.max),
+			ResetAt: reset,
+		})
+	}
+	return result, nil
+}
+
+// UserSubject 返回用户的配额主体标识
+// 参数:
+//
+//	userID: 用户 ID
+//
+// 返回:
+//
+//	string: 配额主体
+func UserSubject(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
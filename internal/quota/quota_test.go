@@ -0,0 +1,31 @@
+package quota
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrossed(t *testing.T) {
+	thresholds := []int{80, 95}
+
+	tests := []struct {
+		name          string
+		before, after int64
+		want          []int
+	}{
+		{"未达到", 10, 79, nil},
+		{"刚好达到 80%", 79, 80, []int{80}},
+		{"一次跨过两个阈值", 50, 100, []int{80, 95}},
+		{"已超过后继续增长", 81, 90, nil},
+		{"跨过 95%", 90, 96, []int{95}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crossed(tt.before, tt.after, 100, thresholds)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("crossed(%d, %d) = %v, 期望 %v", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
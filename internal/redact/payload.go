@@ -0,0 +1,190 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/zhang/microservice/internal/security"
+)
+
+// Redacted 脱敏后替换的值
+const Redacted = "[REDACTED]"
+
+// defaultFields 默认隐藏的字段（小写，按包含匹配，如 access_token、client_secret）
+var defaultFields = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "credential", "private_key"}
+
+// sensitiveHeaders 隐藏的请求头（规范化名称）
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"X-Api-Key":     true,
+}
+
+// piiFields 部分遮盖的个人信息字段（小写，完全匹配）及 security.MaskSensitiveData 的类型
+var piiFields = map[string]string{
+	"email":       "email",
+	"phone":       "phone",
+	"mobile":      "phone",
+	"idcard":      "idcard",
+	"id_card":     "idcard",
+	"bankcard":    "bankcard",
+	"bank_card":   "bankcard",
+	"card_number": "bankcard",
+}
+
+// Payload 请求、响应内容的脱敏规则：凭证类字段整体替换为 [REDACTED]，
+// 开启 PII 时邮箱、手机号、证件号、银行卡号按 security.MaskSensitiveData 部分遮盖
+type Payload struct {
+	fields []string
+	pii    bool
+}
+
+// NewPayload 创建脱敏规则
+// 参数:
+//
+//	extra: 默认字段之外需要隐藏的字段（不区分大小写，按包含匹配）
+//	pii: 是否部分遮盖个人信息字段
+//
+// 返回:
+//
+//	*Payload: 脱敏规则
+func NewPayload(extra []string, pii bool) *Payload {
+	fields := append([]string(nil), defaultFields...)
+	for _, f := range extra {
+		fields = append(fields, strings.ToLower(f))
+	}
+	return &Payload{fields: fields, pii: pii}
+}
+
+// sensitive 字段名是否需要隐藏
+func (p *Payload) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, f := range p.fields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Headers 复制请求头并隐藏凭证
+// 参数:
+//
+//	h: 请求头
+//
+// 返回:
+//
+//	http.Header: 脱敏后的副本
+func (p *Payload) Headers(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] || p.sensitive(name) {
+			out[name] = []string{Redacted}
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// Query 隐藏查询参数（或表单）中的敏感值
+// 参数:
+//
+//	raw: 原始查询字符串
+//
+// 返回:
+//
+//	string: 脱敏后的查询字符串，无法解析时为 [REDACTED]
+func (p *Payload) Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return Redacted
+	}
+	for name, vs := range values {
+		if p.sensitive(name) {
+			values[name] = []string{Redacted}
+			continue
+		}
+		for i, v := range vs {
+			vs[i] = p.maskPII(name, v)
+		}
+	}
+	return values.Encode()
+}
+
+// Body 按内容类型脱敏请求体或响应体：JSON 和表单按字段脱敏，其他文本原样保留，二进制只记录类型
+// 参数:
+//
+//	body: 内容
+//	contentType: Content-Type
+//
+// 返回:
+//
+//	string: 脱敏后的内容
+func (p *Payload) Body(body, contentType string) string {
+	if body == "" {
+		return ""
+	}
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case strings.HasSuffix(mediaType, "json"):
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			// 截断或格式错误的 JSON 无法按字段脱敏，整体隐藏
+			return "[JSON 无法解析，已隐藏]"
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(p.value(v)); err != nil {
+			return Redacted
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	case mediaType == "application/x-www-form-urlencoded":
+		return p.Query(body)
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "":
+		return body
+	default:
+		return "[" + mediaType + " 内容已省略]"
+	}
+}
+
+// value 递归脱敏 JSON 中的字段
+func (p *Payload) value(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if p.sensitive(k) {
+				val[k] = Redacted
+			} else if s, ok := item.(string); ok {
+				val[k] = p.maskPII(k, s)
+			} else {
+				val[k] = p.value(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = p.value(item)
+		}
+	}
+	return v
+}
+
+// maskPII 开启 PII 时部分遮盖个人信息字段的值
+func (p *Payload) maskPII(name, value string) string {
+	if !p.pii {
+		return value
+	}
+	if dataType, ok := piiFields[strings.ToLower(name)]; ok {
+		return security.MaskSensitiveData(value, dataType)
+	}
+	return value
+}
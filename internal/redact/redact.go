@@ -0,0 +1,114 @@
+package redact
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Viewer 请求方
+type Viewer struct {
+	// UserID 请求方用户 ID，未登录时为 0
+	UserID int64
+	// Role 请求方角色
+	Role string
+}
+
+// Rule 字段可见性规则，返回 true 表示请求方可以看到该字段
+// ownerID 为数据所属用户的 ID
+type Rule func(v Viewer, ownerID int64) bool
+
+// SelfOrAdmin 仅本人或管理员可见
+func SelfOrAdmin(v Viewer, ownerID int64) bool {
+	if v.Role == "admin" {
+		return true
+	}
+	return v.UserID != 0 && v.UserID == ownerID
+}
+
+// Policy 序列化策略，键为字段名（proto 字段名，与 REST JSON 字段名一致），未列出的字段始终可见
+type Policy map[string]Rule
+
+// UserPolicy 用户资料的字段可见性策略
+var UserPolicy = Policy{
+	"email":          SelfOrAdmin,
+	"phone":          SelfOrAdmin,
+	"email_verified": SelfOrAdmin,
+	"phone_verified": SelfOrAdmin,
+	"timezone":       SelfOrAdmin,
+}
+
+// Hidden 返回请求方不可见的字段
+// 参数:
+//
+//	v: 请求方
+//	ownerID: 数据所属用户 ID
+//
+// 返回:
+//
+//	map[string]bool: 不可见字段集合
+func (p Policy) Hidden(v Viewer, ownerID int64) map[string]bool {
+	hidden := make(map[string]bool)
+	for field, rule := range p {
+		if !rule(v, ownerID) {
+			hidden[field] = true
+		}
+	}
+	return hidden
+}
+
+// JSON 按策略序列化 REST 响应对象，不可见字段从输出中删除
+// 参数:
+//
+//	v: 请求方
+//	ownerID: 数据所属用户 ID
+//	obj: 响应对象
+//
+// 返回:
+//
+//	interface{}: 可直接交给 c.JSON 的对象
+//	error: 序列化错误
+func (p Policy) JSON(v Viewer, ownerID int64, obj interface{}) (interface{}, error) {
+	hidden := p.Hidden(v, ownerID)
+	if len(hidden) == 0 {
+		return obj, nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for field := range hidden {
+		delete(m, field)
+	}
+	return m, nil
+}
+
+// Message 按策略清空 gRPC 响应消息中不可见的字段
+// 参数:
+//
+//	v: 请求方
+//	ownerID: 数据所属用户 ID
+//	m: protobuf 消息
+func (p Policy) Message(v Viewer, ownerID int64, m proto.Message) {
+	if m == nil {
+		return
+	}
+	hidden := p.Hidden(v, ownerID)
+	if len(hidden) == 0 {
+		return
+	}
+
+	msg := m.ProtoReflect()
+	fields := msg.Descriptor().Fields()
+	for field := range hidden {
+		if fd := fields.ByName(protoreflect.Name(field)); fd != nil {
+			msg.Clear(fd)
+		}
+	}
+}
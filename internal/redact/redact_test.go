@@ -0,0 +1,55 @@
+package redact
+
+import (
+	"testing"
+
+	pb "github.com/zhang/microservice/proto"
+)
+
+type sample struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+func TestUserPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		viewer  Viewer
+		visible bool
+	}{
+		{"本人", Viewer{UserID: 7, Role: "user"}, true},
+		{"管理员", Viewer{UserID: 1, Role: "admin"}, true},
+		{"其他用户", Viewer{UserID: 8, Role: "user"}, false},
+		{"未登录", Viewer{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := UserPolicy.JSON(tt.viewer, 7, sample{ID: 7, Name: "n", Email: "e@example.com", Phone: "1"})
+			if err != nil {
+				t.Fatalf("序列化失败: %v", err)
+			}
+			if m, ok := out.(map[string]interface{}); ok {
+				if _, has := m["email"]; has || tt.visible {
+					t.Errorf("REST email 可见性错误: %v", m)
+				}
+				if m["name"] != "n" {
+					t.Errorf("未受限字段不应被删除: %v", m)
+				}
+			} else if !tt.visible {
+				t.Errorf("应删除受限字段, 得到 %#v", out)
+			}
+
+			msg := &pb.User{Id: 7, Name: "n", Email: "e@example.com", Phone: "1", EmailVerified: true}
+			UserPolicy.Message(tt.viewer, 7, msg)
+			if (msg.Email != "") != tt.visible || (msg.Phone != "") != tt.visible || msg.EmailVerified != tt.visible {
+				t.Errorf("gRPC 字段可见性错误: %v", msg)
+			}
+			if msg.Name != "n" {
+				t.Errorf("未受限字段不应被清空: %v", msg)
+			}
+		})
+	}
+}
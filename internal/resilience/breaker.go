@@ -0,0 +1,339 @@
+// Package resilience 外部依赖（数据库、Redis、消息队列、S3）的熔断器
+// 依赖连续失败达到阈值后熔断，熔断期间调用直接返回“依赖不可用”错误而不再等待超时，
+// 超过 open_timeout 后进入半开状态放行少量探测请求，全部成功则恢复，任一失败则重新熔断
+package resilience
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrOpen 熔断器处于打开状态（或半开状态的探测名额已用完），调用被直接拒绝
+// 拒绝时返回的 *errs.Error 以它为原因，可用 errors.Is(err, resilience.ErrOpen) 判断
+var ErrOpen = errors.New("熔断器已打开")
+
+// State 熔断器状态
+type State int
+
+// 熔断器状态，取值即 circuit_breaker_state 指标的值
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+// String 返回状态名称
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// Settings 熔断器参数
+type Settings struct {
+	// Name 依赖名称，用于日志、指标和返回的错误说明
+	Name string
+	// FailureThreshold 连续失败多少次后熔断，为 0 时为 5
+	FailureThreshold int
+	// OpenTimeout 熔断持续时间，之后进入半开状态，为 0 时为 30 秒
+	OpenTimeout time.Duration
+	// HalfOpenRequests 半开状态下放行的探测请求数，全部成功后恢复，为 0 时为 1
+	HalfOpenRequests int
+	// IsFailure 判断错误是否计为依赖故障，为 nil 时使用 IsConnectionError；
+	// 记录不存在、参数错误等依赖正常返回的错误不应计入
+	IsFailure func(err error) bool
+}
+
+// Breaker 熔断器，并发安全；nil 表示未启用熔断，所有调用直接放行
+type Breaker struct {
+	settings Settings
+	now      func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	failures   int
+	openedAt   time.Time
+	// halfOpen 半开状态下已放行的探测数，successes 为其中已成功的数量
+	halfOpen  int
+	successes int
+}
+
+// NewBreaker 创建熔断器
+// 参数:
+//
+//	s: 熔断器参数
+//
+// 返回:
+//
+//	*Breaker: 熔断器，初始为关闭状态
+func NewBreaker(s Settings) *Breaker {
+	if s.FailureThreshold <= 0 {
+		s.FailureThreshold = 5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 30 * time.Second
+	}
+	if s.HalfOpenRequests <= 0 {
+		s.HalfOpenRequests = 1
+	}
+	if s.IsFailure == nil {
+		s.IsFailure = IsConnectionError
+	}
+	b := &Breaker{settings: s, now: time.Now}
+	metrics.BreakerState.WithLabelValues(s.Name).Set(float64(StateClosed))
+	return b
+}
+
+// Name 返回依赖名称
+func (b *Breaker) Name() string {
+	if b == nil {
+		return ""
+	}
+	return b.settings.Name
+}
+
+// State 返回当前状态（打开状态超过 open_timeout 时返回半开）
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	return b.state
+}
+
+// Allow 申请一次调用，调用结束后必须以调用结果执行返回的 done
+// 用于无法用 Execute 包装的场景（如 GORM 回调、go-redis 钩子）
+// 返回:
+//
+//	func(error): 报告调用结果
+//	error: 熔断时返回 KindUnavailable 类别的 *errs.Error（原因为 ErrOpen），此时不应发起调用
+func (b *Breaker) Allow() (func(error), error) {
+	if b == nil {
+		return func(error) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+
+	switch b.state {
+	case StateOpen:
+		return nil, b.reject()
+	case StateHalfOpen:
+		if b.halfOpen >= b.settings.HalfOpenRequests {
+			return nil, b.reject()
+		}
+		b.halfOpen++
+	}
+
+	generation := b.generation
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() { b.done(generation, err) })
+	}, nil
+}
+
+// Execute 在熔断器保护下执行调用
+// 参数:
+//
+//	fn: 对依赖的调用
+//
+// 返回:
+//
+//	error: fn 的错误；熔断时返回 KindUnavailable 类别的 *errs.Error 且不执行 fn
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err)
+	return err
+}
+
+// reject 记录并返回拒绝错误，调用方需持有锁
+func (b *Breaker) reject() error {
+	metrics.BreakerRejected.WithLabelValues(b.settings.Name).Inc()
+	return &errs.Error{
+		Kind:    errs.KindUnavailable,
+		Message: fmt.Sprintf("依赖 %s 暂时不可用", b.settings.Name),
+		Err:     ErrOpen,
+	}
+}
+
+// done 处理调用结果；状态已变化（generation 不同）时忽略过期的结果
+func (b *Breaker) done(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance()
+	if generation != b.generation {
+		return
+	}
+
+	failed := err != nil && b.settings.IsFailure(err)
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.transition(StateOpen, err)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen, err)
+			return
+		}
+		b.successes++
+		if b.successes >= b.settings.HalfOpenRequests {
+			b.transition(StateClosed, nil)
+		}
+	}
+}
+
+// advance 打开状态超过 open_timeout 后进入半开状态，调用方需持有锁
+func (b *Breaker) advance() {
+	if b.state == StateOpen && !b.now().Before(b.openedAt.Add(b.settings.OpenTimeout)) {
+		b.transition(StateHalfOpen, nil)
+	}
+}
+
+// transition 切换状态并记录日志和指标，调用方需持有锁
+func (b *Breaker) transition(to State, cause error) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures = 0
+	b.halfOpen = 0
+	b.successes = 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+
+	metrics.BreakerState.WithLabelValues(b.settings.Name).Set(float64(to))
+	metrics.BreakerTransitions.WithLabelValues(b.settings.Name, to.String()).Inc()
+
+	fields := []zap.Field{
+		zap.String("dependency", b.settings.Name),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()),
+	}
+	switch to {
+	case StateOpen:
+		logger.Error("外部依赖熔断", append(fields, zap.Error(cause), zap.Duration("open_timeout", b.settings.OpenTimeout))...)
+	case StateHalfOpen:
+		logger.Warn("熔断器进入半开状态，开始探测", fields...)
+	default:
+		logger.Info("外部依赖已恢复，熔断器关闭", fields...)
+	}
+}
+
+// IsConnectionError 是否为连接类错误（网络错误、连接断开、超时），默认的故障判断
+// 调用方取消不算
+// 参数:
+//
+//	err: 错误
+//
+// 返回:
+//
+//	bool: 是否计为依赖故障
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, driver.ErrBadConn)
+}
+
+// registry 按依赖名称注册的熔断器
+var registry = struct {
+	mu       sync.Mutex
+	cfg      config.CircuitBreakerConfig
+	breakers map[string]*Breaker
+}{breakers: map[string]*Breaker{}}
+
+// Init 设置熔断配置，需在初始化数据库、Redis、消息队列和 S3 之前调用
+// 参数:
+//
+//	cfg: 熔断配置
+func Init(cfg config.CircuitBreakerConfig) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.cfg = cfg
+	registry.breakers = map[string]*Breaker{}
+}
+
+// For 返回依赖的熔断器，同名依赖共用一个熔断器
+// 参数:
+//
+//	name: 依赖名称（如 database、redis、mq、s3）
+//	isFailure: 故障判断，为 nil 时使用 IsConnectionError；仅在首次创建时生效
+//
+// 返回:
+//
+//	*Breaker: 熔断器，未启用熔断时为 nil（nil 熔断器直接放行）
+func For(name string, isFailure func(err error) bool) *Breaker {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if !registry.cfg.Enable {
+		return nil
+	}
+	if b, ok := registry.breakers[name]; ok {
+		return b
+	}
+	b := NewBreaker(Settings{
+		Name:             name,
+		FailureThreshold: registry.cfg.GetFailureThreshold(),
+		OpenTimeout:      registry.cfg.GetOpenTimeout(),
+		HalfOpenRequests: registry.cfg.GetHalfOpenRequests(),
+		IsFailure:        isFailure,
+	})
+	registry.breakers[name] = b
+	return b
+}
+
+// States 返回已创建的熔断器的状态（用于健康检查）
+// 返回:
+//
+//	map[string]string: 依赖名称到状态名称，未启用熔断时为空
+func States() map[string]string {
+	registry.mu.Lock()
+	names := make([]string, 0, len(registry.breakers))
+	breakers := make([]*Breaker, 0, len(registry.breakers))
+	for name, b := range registry.breakers {
+		names = append(names, name)
+		breakers = append(breakers, b)
+	}
+	registry.mu.Unlock()
+
+	states := make(map[string]string, len(names))
+	for i, b := range breakers {
+		states[names[i]] = b.State().String()
+	}
+	return states
+}
@@ -0,0 +1,109 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+var errDown = errors.New("down")
+
+func TestBreaker(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+
+	now := time.Unix(0, 0)
+	b := NewBreaker(Settings{
+		Name:             "test",
+		FailureThreshold: 3,
+		OpenTimeout:      10 * time.Second,
+		HalfOpenRequests: 2,
+		IsFailure:        func(err error) bool { return errors.Is(err, errDown) },
+	})
+	b.now = func() time.Time { return now }
+
+	fail := func() error { return errDown }
+	ok := func() error { return nil }
+
+	// 非故障错误和成功会清零连续失败次数
+	_ = b.Execute(fail)
+	_ = b.Execute(fail)
+	_ = b.Execute(func() error { return errors.New("not found") })
+	_ = b.Execute(fail)
+	_ = b.Execute(fail)
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("未达到连续失败阈值, 状态 = %s", s)
+	}
+	_ = b.Execute(fail)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("连续失败 3 次后应熔断, 状态 = %s", s)
+	}
+
+	// 熔断期间不执行调用，返回依赖不可用错误
+	called := false
+	err := b.Execute(func() error { called = true; return nil })
+	if called || !errors.Is(err, ErrOpen) || !errors.Is(err, errs.ErrUnavailable) {
+		t.Fatalf("熔断期间 called = %v, err = %v", called, err)
+	}
+
+	// 超过 open_timeout 后半开，只放行 HalfOpenRequests 个探测
+	now = now.Add(10 * time.Second)
+	done1, err1 := b.Allow()
+	done2, err2 := b.Allow()
+	_, err3 := b.Allow()
+	if err1 != nil || err2 != nil || !errors.Is(err3, ErrOpen) {
+		t.Fatalf("半开状态放行 = %v, %v, %v", err1, err2, err3)
+	}
+
+	// 探测失败重新熔断，之前放行的调用结果被忽略
+	done1(errDown)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("探测失败后应重新熔断, 状态 = %s", s)
+	}
+	done2(nil)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("过期的结果不应改变状态, 状态 = %s", s)
+	}
+
+	// 探测全部成功后恢复
+	now = now.Add(10 * time.Second)
+	if err := b.Execute(ok); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("探测未全部完成, 状态 = %s", s)
+	}
+	if err := b.Execute(ok); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("探测全部成功后应恢复, 状态 = %s", s)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Init(config.CircuitBreakerConfig{})
+	b := For("redis", nil)
+	if b != nil {
+		t.Fatal("未启用熔断时应返回 nil")
+	}
+	// nil 熔断器直接放行
+	if err := b.Execute(func() error { return errDown }); !errors.Is(err, errDown) {
+		t.Fatalf("err = %v", err)
+	}
+
+	Init(config.CircuitBreakerConfig{Enable: true, FailureThreshold: 2})
+	b = For("redis", nil)
+	if b == nil || For("redis", nil) != b {
+		t.Fatal("同名依赖应共用一个熔断器")
+	}
+	if got := States(); got["redis"] != "closed" {
+		t.Errorf("States = %v", got)
+	}
+	t.Cleanup(func() { Init(config.CircuitBreakerConfig{}) })
+}
@@ -0,0 +1,220 @@
+// Package retry 带指数退避和随机抖动的重试
+// 用于启动时等待数据库、Redis、RabbitMQ 就绪（编排系统中依赖与服务同时重启），以及出站调用的暂时性失败
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Policy 重试策略
+type Policy struct {
+	// Name 重试的操作名称，非空时每次重试记录一条警告日志
+	Name string
+	// MaxAttempts 最多尝试次数（含首次），0 表示不限（由 MaxElapsed 或 ctx 结束）
+	MaxAttempts int
+	// MaxElapsed 总耗时上限，0 表示不限
+	MaxElapsed time.Duration
+	// Initial 首次重试前的等待时间，为 0 时为 200 毫秒
+	Initial time.Duration
+	// Max 等待时间上限，为 0 时为 10 秒
+	Max time.Duration
+	// Multiplier 每次重试等待时间的倍数，为 0 时为 2
+	Multiplier float64
+	// Jitter 随机抖动比例（0~1），等待时间在 ±Jitter 范围内随机，避免多个实例同时重试；为 0 时为 0.2
+	Jitter float64
+	// Retryable 判断错误是否可以重试，为 nil 时除 Permanent 包装的错误和 ctx 结束外都重试
+	Retryable func(err error) bool
+}
+
+// permanentError 不再重试的错误
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 包装不应重试的错误（如配置错误、认证失败），Do 立即返回原错误
+// 参数:
+//
+//	err: 错误
+//
+// 返回:
+//
+//	error: 包装后的错误，err 为 nil 时返回 nil
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Backoff 返回第 attempt 次重试（从 1 开始）前的等待时间，已包含随机抖动
+// 参数:
+//
+//	attempt: 重试序号
+//
+// 返回:
+//
+//	time.Duration: 等待时间
+func (p Policy) Backoff(attempt int) time.Duration {
+	initial, max := p.Initial, p.Max
+	if initial <= 0 {
+		initial = 200 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	jitter := p.Jitter
+	if jitter <= 0 || jitter > 1 {
+		jitter = 0.2
+	}
+
+	d := float64(initial)
+	for i := 1; i < attempt && d < float64(max); i++ {
+		d *= multiplier
+	}
+	d = min(d, float64(max))
+	return time.Duration(d * (1 - jitter + 2*jitter*rand.Float64()))
+}
+
+// Do 按策略执行 fn，失败后退避重试，直到成功、错误不可重试、达到次数或耗时上限、ctx 结束
+// 参数:
+//
+//	ctx: 上下文，取消时停止重试；设置了 MaxElapsed 时传给 fn 的上下文带相应的截止时间
+//	p: 重试策略
+//	fn: 操作
+//
+// 返回:
+//
+//	error: 最后一次的错误（Permanent 包装的错误返回原错误）；未执行过 fn 时为 ctx 的错误
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	if p.MaxElapsed > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.MaxElapsed)
+		defer cancel()
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err == nil {
+				err = ctxErr
+			}
+			return err
+		}
+
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if p.Retryable != nil && !p.Retryable(err) {
+			return err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return err
+		}
+
+		wait := p.Backoff(attempt)
+		if p.Name != "" {
+			logger.Warn("操作失败，稍后重试",
+				zap.String("operation", p.Name),
+				zap.Int("attempt", attempt),
+				zap.Duration("retry_in", wait),
+				zap.Error(err),
+			)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// defaults 由 Init 设置的默认重试参数
+var defaults = struct {
+	mu  sync.RWMutex
+	cfg config.RetryConfig
+}{}
+
+// Init 设置默认重试参数，需在初始化数据库、Redis、消息队列之前调用
+// 未调用时启动不等待依赖就绪（命令行工具即如此）
+// 参数:
+//
+//	cfg: 重试配置
+func Init(cfg config.RetryConfig) {
+	defaults.mu.Lock()
+	defer defaults.mu.Unlock()
+	defaults.cfg = cfg
+}
+
+// current 返回当前的重试配置
+func current() config.RetryConfig {
+	defaults.mu.RLock()
+	defer defaults.mu.RUnlock()
+	return defaults.cfg
+}
+
+// Startup 返回启动时等待依赖就绪的策略：不限次数，最长 retry.startup_timeout；
+// startup_timeout 为 0 时只尝试一次
+// 参数:
+//
+//	name: 依赖名称，用于日志
+//
+// 返回:
+//
+//	Policy: 重试策略
+func Startup(name string) Policy {
+	cfg := current()
+	p := Policy{
+		Name:    "连接" + name,
+		Initial: cfg.GetInitialInterval(),
+		Max:     cfg.GetMaxInterval(),
+	}
+	if timeout := cfg.GetStartupTimeout(); timeout > 0 {
+		p.MaxElapsed = timeout
+	} else {
+		p.MaxAttempts = 1
+	}
+	return p
+}
+
+// Transient 返回暂时性失败的重试策略：最多 retry.attempts 次
+// 参数:
+//
+//	name: 操作名称，用于日志
+//	retryable: 可以重试的错误，为 nil 时都重试
+//
+// 返回:
+//
+//	Policy: 重试策略
+func Transient(name string, retryable func(err error) bool) Policy {
+	cfg := current()
+	return Policy{
+		Name:        name,
+		MaxAttempts: cfg.GetAttempts(),
+		Initial:     cfg.GetInitialInterval(),
+		Max:         cfg.GetMaxInterval(),
+		Retryable:   retryable,
+	}
+}
@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+var errTemporary = errors.New("temporary")
+
+func TestDo(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	logger.Sugar = logger.Logger.Sugar()
+	ctx := context.Background()
+	fast := Policy{Name: "test", Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+	// 失败后重试直到成功
+	calls := 0
+	err := Do(ctx, fast, func(context.Context) error {
+		if calls++; calls < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+
+	// 达到次数上限返回最后一次的错误
+	calls = 0
+	p := fast
+	p.MaxAttempts = 2
+	if err := Do(ctx, p, func(context.Context) error { calls++; return errTemporary }); !errors.Is(err, errTemporary) || calls != 2 {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+
+	// 不可重试和 Permanent 包装的错误立即返回
+	calls = 0
+	p = fast
+	p.Retryable = func(err error) bool { return !errors.Is(err, errTemporary) }
+	if err := Do(ctx, p, func(context.Context) error { calls++; return errTemporary }); calls != 1 || !errors.Is(err, errTemporary) {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+	calls = 0
+	err = Do(ctx, fast, func(context.Context) error { calls++; return Permanent(errTemporary) })
+	if calls != 1 || err != errTemporary {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+
+	// 超过总耗时上限后停止
+	p = fast
+	p.MaxElapsed = 20 * time.Millisecond
+	start := time.Now()
+	if err := Do(ctx, p, func(context.Context) error { return errTemporary }); !errors.Is(err, errTemporary) {
+		t.Fatalf("err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("MaxElapsed 未生效, 耗时 %v", elapsed)
+	}
+
+	// ctx 已取消时不执行
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	if err := Do(cancelled, fast, func(context.Context) error { calls++; return nil }); calls != 0 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("calls = %d, err = %v", calls, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Max: time.Second, Jitter: 0.5}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for i := 0; i < 20; i++ {
+			got := p.Backoff(attempt)
+			if got < want/2 || got > want*3/2 {
+				t.Fatalf("Backoff(%d) = %v, 应在 %v ±50%% 范围内", attempt, got, want)
+			}
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	t.Cleanup(func() { Init(config.RetryConfig{}) })
+
+	// 未设置等待时间时启动只尝试一次
+	Init(config.RetryConfig{})
+	if p := Startup("Redis"); p.MaxAttempts != 1 || p.MaxElapsed != 0 {
+		t.Errorf("Startup = %+v", p)
+	}
+
+	Init(config.RetryConfig{StartupTimeout: 60, Attempts: 5})
+	if p := Startup("Redis"); p.MaxAttempts != 0 || p.MaxElapsed != time.Minute {
+		t.Errorf("Startup = %+v", p)
+	}
+	if p := Transient("publish", nil); p.MaxAttempts != 5 || p.Initial != 200*time.Millisecond || p.Max != 10*time.Second {
+		t.Errorf("Transient = %+v", p)
+	}
+}
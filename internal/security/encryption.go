@@ -47,27 +47,11 @@ func (e *Encryptor) Encrypt(plaintext string) (string, error) {
 		return "", nil
 	}
 
-	// 创建 AES cipher
-	block, err := aes.NewCipher(e.key)
-	if err != nil {
-		return "", fmt.Errorf("创建cipher失败: %w", err)
-	}
-
-	// 创建 GCM mode
-	gcm, err := cipher.NewGCM(block)
+	ciphertext, err := e.Seal([]byte(plaintext))
 	if err != nil {
-		return "", fmt.Errorf("创建GCM失败: %w", err)
+		return "", err
 	}
 
-	// 生成随机 nonce
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("生成nonce失败: %w", err)
-	}
-
-	// 加密数据 (nonce + ciphertext + tag)
-	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-
 	// Base64 编码
 	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
@@ -93,33 +77,87 @@ func (e *Encryptor) Decrypt(ciphertext string) (string, error) {
 		return "", fmt.Errorf("Base64解码失败: %w", err)
 	}
 
-	// 创建 AES cipher
-	block, err := aes.NewCipher(e.key)
+	plaintext, err := e.Open(data)
 	if err != nil {
-		return "", fmt.Errorf("创建cipher失败: %w", err)
+		return "", err
 	}
 
-	// 创建 GCM mode
-	gcm, err := cipher.NewGCM(block)
+	return string(plaintext), nil
+}
+
+// Seal 加密二进制数据
+// 用途: 使用 AES-256-GCM 算法加密，输出格式为 nonce + ciphertext + tag
+// 参数:
+//
+//	plaintext: 明文数据
+//
+// 返回:
+//
+//	[]byte: 密文
+//	error: 错误信息
+func (e *Encryptor) Seal(plaintext []byte) ([]byte, error) {
+	gcm, err := e.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	// 生成随机 nonce
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("生成nonce失败: %w", err)
+	}
+
+	// 加密数据 (nonce + ciphertext + tag)
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Open 解密二进制数据
+// 参数:
+//
+//	data: Seal 输出的密文
+//
+// 返回:
+//
+//	[]byte: 明文数据
+//	error: 错误信息
+func (e *Encryptor) Open(data []byte) ([]byte, error) {
+	gcm, err := e.gcm()
 	if err != nil {
-		return "", fmt.Errorf("创建GCM失败: %w", err)
+		return nil, err
 	}
 
 	// 提取 nonce
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return "", fmt.Errorf("密文长度不足")
+		return nil, fmt.Errorf("密文长度不足")
 	}
 
-	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
+	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
 
 	// 解密数据
-	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		return "", fmt.Errorf("解密失败: %w", err)
+		return nil, fmt.Errorf("解密失败: %w", err)
 	}
 
-	return string(plaintext), nil
+	return plaintext, nil
+}
+
+// gcm 创建 AES-GCM 实例
+func (e *Encryptor) gcm() (cipher.AEAD, error) {
+	// 创建 AES cipher
+	block, err := aes.NewCipher(e.key)
+	if err != nil {
+		return nil, fmt.Errorf("创建cipher失败: %w", err)
+	}
+
+	// 创建 GCM mode
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建GCM失败: %w", err)
+	}
+
+	return gcm, nil
 }
 
 // EncryptFields 批量加密字段
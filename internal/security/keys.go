@@ -0,0 +1,87 @@
+package security
+
+import (
+	"fmt"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// KeyProvider 密钥提供者
+// 按版本管理加密密钥，支持密钥轮换：新数据使用当前版本加密，旧数据按记录的版本解密
+type KeyProvider interface {
+	// CurrentKey 获取当前用于加密的密钥
+	CurrentKey() (version string, key []byte, err error)
+	// Key 获取指定版本的密钥
+	Key(version string) ([]byte, error)
+}
+
+// StaticKeyProvider 基于配置文件的密钥提供者
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// Keys 全局密钥提供者实例
+var Keys KeyProvider
+
+// NewStaticKeyProvider 创建基于配置的密钥提供者
+// 参数:
+//
+//	current: 当前密钥版本
+//	keys: 版本到密钥的映射（每个密钥必须为32字节）
+//
+// 返回:
+//
+//	*StaticKeyProvider: 密钥提供者
+//	error: 错误信息
+func NewStaticKeyProvider(current string, keys map[string]string) (*StaticKeyProvider, error) {
+	p := &StaticKeyProvider{
+		current: current,
+		keys:    make(map[string][]byte, len(keys)),
+	}
+	for version, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("密钥 %s 长度必须为32字节，当前为%d字节", version, len(key))
+		}
+		p.keys[version] = []byte(key)
+	}
+	if _, ok := p.keys[current]; !ok {
+		return nil, fmt.Errorf("当前密钥版本 %s 不存在", current)
+	}
+	return p, nil
+}
+
+// InitKeyProvider 根据配置初始化全局密钥提供者
+// 参数:
+//
+//	cfg: 安全配置
+//
+// 返回:
+//
+//	error: 错误信息
+func InitKeyProvider(cfg config.SecurityConfig) error {
+	if len(cfg.EncryptionKeys) == 0 {
+		return nil
+	}
+
+	p, err := NewStaticKeyProvider(cfg.CurrentKeyVersion, cfg.EncryptionKeys)
+	if err != nil {
+		return fmt.Errorf("初始化密钥失败: %w", err)
+	}
+	Keys = p
+	return nil
+}
+
+// CurrentKey 获取当前用于加密的密钥
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return p.current, p.keys[p.current], nil
+}
+
+// Key 获取指定版本的密钥
+func (p *StaticKeyProvider) Key(version string) ([]byte, error) {
+	key, ok := p.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("密钥版本 %s 不存在", version)
+	}
+	return key, nil
+}
@@ -0,0 +1,178 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zhang/microservice/internal/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 密码哈希算法
+const (
+	// PasswordArgon2id argon2id（默认）
+	PasswordArgon2id = "argon2id"
+	// PasswordBcrypt bcrypt
+	PasswordBcrypt = "bcrypt"
+)
+
+// argon2id 参数（RFC 9106 推荐的第二组参数）
+const (
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 2
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// ErrInvalidHash 密码哈希格式无法识别
+var ErrInvalidHash = errors.New("无法识别的密码哈希格式")
+
+// PasswordHasher 密码哈希器
+// 新密码按配置的算法哈希；校验时按哈希本身的格式识别算法，切换算法后旧密码仍可登录，登录成功后再按新算法重新哈希
+type PasswordHasher struct {
+	algorithm  string
+	bcryptCost int
+}
+
+// Passwords 全局密码哈希器，默认使用 argon2id
+var Passwords = NewPasswordHasher(config.PasswordConfig{})
+
+// NewPasswordHasher 创建密码哈希器
+// 参数:
+//
+//	cfg: 密码哈希配置
+//
+// 返回:
+//
+//	*PasswordHasher: 密码哈希器
+func NewPasswordHasher(cfg config.PasswordConfig) *PasswordHasher {
+	return &PasswordHasher{
+		algorithm:  cfg.GetAlgorithm(),
+		bcryptCost: cfg.GetBcryptCost(),
+	}
+}
+
+// InitPasswordHasher 根据配置初始化全局密码哈希器
+// 参数:
+//
+//	cfg: 密码哈希配置
+func InitPasswordHasher(cfg config.PasswordConfig) {
+	Passwords = NewPasswordHasher(cfg)
+}
+
+// Hash 哈希密码
+// 参数:
+//
+//	password: 明文密码
+//
+// 返回:
+//
+//	string: 带算法和参数的哈希字符串（argon2id 为 PHC 格式，bcrypt 为 $2a$ 格式）
+//	error: 错误信息
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify 校验密码
+// 参数:
+//
+//	hash: 保存的哈希
+//	password: 明文密码
+//
+// 返回:
+//
+//	bool: 密码是否正确
+//	error: 哈希格式无法识别时返回 ErrInvalidHash
+func (h *PasswordHasher) Verify(hash, password string) (bool, error) {
+	if strings.HasPrefix(hash, "$2") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	params, err := parseArgon2(hash)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+// NeedsRehash 哈希是否需要按当前配置重新生成（算法或参数已变更）
+// 参数:
+//
+//	hash: 保存的哈希
+//
+// 返回:
+//
+//	bool: 是否需要重新哈希
+func (h *PasswordHasher) NeedsRehash(hash string) bool {
+	if h.algorithm == PasswordBcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.bcryptCost
+	}
+
+	params, err := parseArgon2(hash)
+	return err != nil || params.memory != argon2Memory || params.time != argon2Time || params.threads != argon2Threads
+}
+
+// argon2Params 从哈希中解析出的 argon2id 参数
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2 解析 PHC 格式的 argon2id 哈希：$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func parseArgon2(hash string) (*argon2Params, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != PasswordArgon2id {
+		return nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidHash
+	}
+
+	p := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(p.key) == 0 {
+		return nil, ErrInvalidHash
+	}
+	return p, nil
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+func TestPasswordHashAndVerify(t *testing.T) {
+	for _, cfg := range []config.PasswordConfig{
+		{Algorithm: PasswordArgon2id},
+		{Algorithm: PasswordBcrypt, BcryptCost: 4},
+	} {
+		t.Run(cfg.GetAlgorithm(), func(t *testing.T) {
+			h := NewPasswordHasher(cfg)
+			hash, err := h.Hash("correct horse battery")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(hash, "correct horse") {
+				t.Fatal("哈希中不应包含明文")
+			}
+
+			if ok, err := h.Verify(hash, "correct horse battery"); !ok || err != nil {
+				t.Errorf("正确密码: ok = %v, err = %v", ok, err)
+			}
+			if ok, err := h.Verify(hash, "wrong password"); ok || err != nil {
+				t.Errorf("错误密码: ok = %v, err = %v", ok, err)
+			}
+			if h.NeedsRehash(hash) {
+				t.Error("当前配置生成的哈希不需要重新哈希")
+			}
+
+			again, _ := h.Hash("correct horse battery")
+			if again == hash {
+				t.Error("相同密码两次哈希应使用不同的盐")
+			}
+		})
+	}
+}
+
+func TestPasswordAlgorithmSwitch(t *testing.T) {
+	bcryptHasher := NewPasswordHasher(config.PasswordConfig{Algorithm: PasswordBcrypt, BcryptCost: 4})
+	argonHasher := NewPasswordHasher(config.PasswordConfig{})
+
+	old, err := bcryptHasher.Hash("secret-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 切换到 argon2id 后旧的 bcrypt 哈希仍可校验，并提示重新哈希
+	if ok, err := argonHasher.Verify(old, "secret-password"); !ok || err != nil {
+		t.Errorf("ok = %v, err = %v", ok, err)
+	}
+	if !argonHasher.NeedsRehash(old) {
+		t.Error("bcrypt 哈希在 argon2id 配置下应需要重新哈希")
+	}
+}
+
+func TestPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "plain", "$argon2id$v=19$m=1,t=1,p=1$bad$", "$argon2i$v=19$m=1,t=1,p=1$c2FsdA$a2V5"} {
+		if _, err := Passwords.Verify(hash, "x"); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Verify(%q) err = %v, 期望 ErrInvalidHash", hash, err)
+		}
+	}
+}
@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 
-	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/errs"
 	"github.com/zhang/microservice/internal/logger"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -18,22 +19,60 @@ type User struct {
 	Phone     string    `gorm:"type:varchar(20)" json:"phone"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// EmailVerified 邮箱是否已验证
+	EmailVerified bool `gorm:"not null;default:false" json:"email_verified"`
+	// PhoneVerified 手机号是否已验证
+	PhoneVerified bool `gorm:"not null;default:false" json:"phone_verified"`
+	// LastSeenAt 最近活跃时间，由 activity 包批量更新
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	// PasswordHash 密码哈希（见 security.PasswordHasher），为空表示未设置密码，不能登录
+	PasswordHash string `gorm:"type:varchar(255)" json:"-"`
+	// Role 角色，登录时写入 JWT
+	Role string `gorm:"type:varchar(20);not null;default:user" json:"-"`
+	// Timezone 时区偏好（IANA 名称），API 响应中的时间戳按此时区输出，为空时使用默认时区
+	Timezone string `gorm:"type:varchar(64)" json:"timezone,omitempty"`
+	// DeletedAt 软删除时间，已删除的用户不出现在查询结果中，可恢复；
+	// 删除超过 users.purge_after_days 天后由定时任务永久删除。软删除的用户仍占用邮箱
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// Version 乐观锁版本，创建时为 1，每次 UpdateUser 成功后加一；
+	// 更新时提交读取到的版本，与当前版本不一致说明已被其他请求修改
+	Version int64 `gorm:"not null;default:1" json:"version"`
 }
 
+// credentialColumns 只能通过专用方法修改的列，UpdateUser 不写入，避免按部分字段构造的用户覆盖密码和角色
+var credentialColumns = []string{"password_hash", "role"}
+
+// UserCacheClass 用户资料缓存类别（见 cache.Refresher），键为用户 ID
+const UserCacheClass = "user"
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
 
+// BeforeCreate 新用户的版本从 1 开始（MySQL 不支持 RETURNING，不能依赖列默认值回填）
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.Version == 0 {
+		u.Version = 1
+	}
+	return nil
+}
+
 // UserService 用户服务
-type UserService struct{}
+type UserService struct {
+	repo UserRepository
+}
 
 // NewUserService 创建用户服务实例
+// 参数:
+//
+//	repo: 用户仓库，生产环境为 NewGormUserRepository(database.DB)，单元测试可用 NewMemoryUserRepository()
+//
 // 返回:
 //
 //	*UserService: 用户服务实例
-func NewUserService() *UserService {
-	return &UserService{}
+func NewUserService(repo UserRepository) *UserService {
+	return &UserService{repo: repo}
 }
 
 // GetUser 获取用户
@@ -47,17 +86,69 @@ func NewUserService() *UserService {
 //	*User: 用户信息
 //	error: 错误信息
 func (s *UserService) GetUser(ctx context.Context, id int64) (*User, error) {
-	var user User
-
-	if err := database.DB.WithContext(ctx).First(&user, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, nil
-		}
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
 		logger.Error("查询用户失败", zap.Int64("id", id), zap.Error(err))
-		return nil, err
+		return nil, errs.FromDB(err)
+	}
+
+	return user, nil
+}
+
+// GetUserByEmail 按邮箱获取用户（登录时使用，包含密码哈希）
+// 参数:
+//
+//	ctx: 上下文
+//	email: 邮箱
+//
+// 返回:
+//
+//	*User: 用户信息，不存在时为 nil
+//	error: 错误信息
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
+	if err != nil {
+		logger.Error("按邮箱查询用户失败", zap.Error(err))
+		return nil, errs.FromDB(err)
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// SetPasswordHash 更新用户的密码哈希
+// 参数:
+//
+//	ctx: 上下文
+//	id: 用户 ID
+//	hash: 密码哈希
+//
+// 返回:
+//
+//	error: 错误信息
+func (s *UserService) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	if err := s.repo.SetPasswordHash(ctx, id, hash); err != nil {
+		logger.Error("更新密码失败", zap.Int64("id", id), zap.Error(err))
+		return errs.FromDB(err)
+	}
+	return nil
+}
+
+// SetRole 更新用户的角色，新角色在用户下次登录时写入 JWT
+// 参数:
+//
+//	ctx: 上下文
+//	id: 用户 ID
+//	role: 角色
+//
+// 返回:
+//
+//	error: 错误信息
+func (s *UserService) SetRole(ctx context.Context, id int64, role string) error {
+	if err := s.repo.SetRole(ctx, id, role); err != nil {
+		logger.FromContext(ctx).Error("更新角色失败", zap.Int64("id", id), zap.Error(err))
+		return errs.FromDB(err)
+	}
+	return nil
 }
 
 // CreateUser 创建用户
@@ -71,36 +162,109 @@ func (s *UserService) GetUser(ctx context.Context, id int64) (*User, error) {
 //	*User: 创建的用户
 //	error: 错误信息
 func (s *UserService) CreateUser(ctx context.Context, user *User) (*User, error) {
-	if err := database.DB.WithContext(ctx).Create(user).Error; err != nil {
+	if err := s.repo.Create(ctx, user); err != nil {
 		logger.Error("创建用户失败", zap.Error(err))
-		return nil, err
+		return nil, errs.FromDB(err)
 	}
 
 	logger.Info("用户创建成功", zap.Int64("id", user.ID), zap.String("name", user.Name))
 	return user, nil
 }
 
-// UpdateUser 更新用户
+// CreateUsers 在一个事务中批量创建用户（一条 INSERT），任一用户失败（如邮箱重复）则全部回滚
 // 参数:
 //
 //	ctx: 上下文
-//	user: 用户信息
+//	users: 用户列表，成功后回填 ID
 //
 // 返回:
 //
-//	*User: 更新后的用户
 //	error: 错误信息
-func (s *UserService) UpdateUser(ctx context.Context, user *User) (*User, error) {
-	if err := database.DB.WithContext(ctx).Save(user).Error; err != nil {
-		logger.Error("更新用户失败", zap.Int64("id", user.ID), zap.Error(err))
+func (s *UserService) CreateUsers(ctx context.Context, users []*User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	if err := s.repo.CreateBatch(ctx, users); err != nil {
+		logger.Error("批量创建用户失败", zap.Int("count", len(users)), zap.Error(err))
+		return errs.FromDB(err)
+	}
+
+	logger.Info("批量创建用户成功", zap.Int("count", len(users)))
+	return nil
+}
+
+// UpdateUser 更新用户（不修改密码和角色）
+// fields 为空时保存 user 的全部列；否则只更新 fields 列出的列（同时更新 updated_at），
+// 其余列保持数据库中的值，邮箱、手机号因此变化时重置对应的验证状态。
+// user.Version 不为 0 时作为期望的当前版本（乐观锁），与数据库中的版本不一致时返回 errs.ErrVersionConflict 类别的错误；
+// 检查和写入在同一事务中进行（当前行被锁定），更新成功后版本加一
+// 参数:
+//
+//	ctx: 上下文
+//	user: 用户信息，Version 为 0 表示不检查版本
+//	fields: 需要更新的列名（如 name、email），为空表示全部列
+//
+// 返回:
+//
+//	*User: 更新后的用户，用户不存在时返回 nil
+//	error: 错误信息
+func (s *UserService) UpdateUser(ctx context.Context, user *User, fields ...string) (*User, error) {
+	expected := user.Version
+
+	var updated *User
+	err := s.repo.Transaction(ctx, func(tx UserRepository) error {
+		current, err := tx.GetForUpdate(ctx, user.ID)
+		if err != nil || current == nil {
+			return err
+		}
+		if expected != 0 && expected != current.Version {
+			return errs.New(errs.KindVersionConflict, "用户已被修改（当前版本 %d），请重新获取后再更新", current.Version)
+		}
+		user.Version = current.Version + 1
+
+		if len(fields) == 0 {
+			if err := tx.Save(ctx, user); err != nil {
+				return err
+			}
+			updated = user
+			return nil
+		}
+
+		columns := append(append([]string(nil), fields...), "updated_at", "version")
+		for _, field := range fields {
+			switch {
+			case field == "email" && user.Email != current.Email:
+				user.EmailVerified = false
+				columns = append(columns, "email_verified")
+			case field == "phone" && user.Phone != current.Phone:
+				user.PhoneVerified = false
+				columns = append(columns, "phone_verified")
+			}
+		}
+
+		if err := tx.UpdateColumns(ctx, user, columns); err != nil {
+			return err
+		}
+		updated, err = tx.Get(ctx, user.ID)
+		return err
+	})
+	if errors.Is(err, errs.ErrVersionConflict) {
+		logger.Info("更新用户版本冲突", zap.Int64("id", user.ID), zap.Int64("version", expected))
 		return nil, err
 	}
+	if err != nil {
+		logger.Error("更新用户失败", zap.Int64("id", user.ID), zap.Strings("fields", fields), zap.Error(err))
+		return nil, errs.FromDB(err)
+	}
+	if updated == nil {
+		return nil, nil
+	}
 
-	logger.Info("用户更新成功", zap.Int64("id", user.ID))
-	return user, nil
+	logger.Info("用户更新成功", zap.Int64("id", user.ID), zap.Strings("fields", fields))
+	return updated, nil
 }
 
-// DeleteUser 删除用户
+// DeleteUser 软删除用户，可通过 RestoreUser 恢复
 // 参数:
 //
 //	ctx: 上下文
@@ -110,19 +274,146 @@ func (s *UserService) UpdateUser(ctx context.Context, user *User) (*User, error)
 //
 //	error: 错误信息
 func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
-	if err := database.DB.WithContext(ctx).Delete(&User{}, id).Error; err != nil {
+	if err := s.repo.Delete(ctx, id); err != nil {
 		logger.Error("删除用户失败", zap.Int64("id", id), zap.Error(err))
-		return err
+		return errs.FromDB(err)
 	}
 
 	logger.Info("用户删除成功", zap.Int64("id", id))
 	return nil
 }
 
-// ListUsers 获取用户列表
+// RestoreUser 恢复已软删除的用户
+// 参数:
+//
+//	ctx: 上下文
+//	id: 用户 ID
+//
+// 返回:
+//
+//	*User: 恢复后的用户，用户不存在或未被删除时为 nil
+//	error: 错误信息
+func (s *UserService) RestoreUser(ctx context.Context, id int64) (*User, error) {
+	restored, err := s.repo.Restore(ctx, id)
+	if err != nil {
+		logger.Error("恢复用户失败", zap.Int64("id", id), zap.Error(err))
+		return nil, errs.FromDB(err)
+	}
+	if !restored {
+		return nil, nil
+	}
+
+	logger.Info("用户恢复成功", zap.Int64("id", id))
+	return s.GetUser(ctx, id)
+}
+
+// PurgeUser 永久删除用户（包括已软删除的），不可恢复
 // 参数:
 //
 //	ctx: 上下文
+//	id: 用户 ID
+//
+// 返回:
+//
+//	bool: 是否删除，用户不存在时为 false
+//	error: 错误信息
+func (s *UserService) PurgeUser(ctx context.Context, id int64) (bool, error) {
+	purged, err := s.repo.Purge(ctx, id)
+	if err != nil {
+		logger.Error("永久删除用户失败", zap.Int64("id", id), zap.Error(err))
+		return false, errs.FromDB(err)
+	}
+
+	if purged {
+		logger.Info("用户已永久删除", zap.Int64("id", id))
+	}
+	return purged, nil
+}
+
+// purgeBatchSize PurgeDeletedUsers 每批删除的用户数，避免长时间持有大量行锁
+const purgeBatchSize = 500
+
+// PurgeDeletedUsers 分批永久删除在 before 之前软删除的用户（定时任务 purge_deleted_users 调用）
+// 参数:
+//
+//	ctx: 上下文，取消时在当前批次完成后停止
+//	before: 软删除时间早于该时间的用户被删除
+//
+// 返回:
+//
+//	int64: 删除的用户数
+//	error: 错误信息
+func (s *UserService) PurgeDeletedUsers(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		n, err := s.repo.PurgeDeleted(ctx, before, purgeBatchSize)
+		total += n
+		if err != nil {
+			logger.Error("清理已删除用户失败", zap.Int64("已删除", total), zap.Error(err))
+			return total, errs.FromDB(err)
+		}
+		if n < purgeBatchSize {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// 分页默认值
+const (
+	// DefaultPageSize 默认每页条数
+	DefaultPageSize = 20
+	// MaxPageSize 每页最大条数
+	MaxPageSize = 100
+)
+
+// NormalizePage 规范化分页参数
+// 参数:
+//
+//	page: 页码（从 1 开始），<=0 时取 1
+//	pageSize: 每页条数，<=0 时取默认值，超过上限时取上限
+//
+// 返回:
+//
+//	int: 页码
+//	int: 每页条数
+func NormalizePage(page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	return page, pageSize
+}
+
+// UserFilter 用户列表过滤条件和排序，空字段表示不过滤
+type UserFilter struct {
+	// Name 按名称模糊匹配
+	Name string
+	// NamePrefix 按名称前缀匹配
+	NamePrefix string
+	// Email 按邮箱精确匹配
+	Email string
+	// CreatedAfter 创建时间不早于该时间
+	CreatedAfter time.Time
+	// CreatedBefore 创建时间早于该时间
+	CreatedBefore time.Time
+	// Sort 排序方式（UserSortID 等），为空时按 ID 升序
+	Sort string
+}
+
+// ListUsers 按偏移量分页获取用户列表
+// 深度翻页时数据库需要扫描并丢弃 offset 行，大表上应改用 ListUsersAfter
+// 参数:
+//
+//	ctx: 上下文
+//	filter: 过滤条件
 //	offset: 偏移量
 //	limit: 限制数量
 //
@@ -131,23 +422,90 @@ func (s *UserService) DeleteUser(ctx context.Context, id int64) error {
 //	[]*User: 用户列表
 //	int64: 总数
 //	error: 错误信息
-func (s *UserService) ListUsers(ctx context.Context, offset, limit int) ([]*User, int64, error) {
-	var users []*User
-	var total int64
+func (s *UserService) ListUsers(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, int64, error) {
+	if err := checkUserSort(filter.Sort); err != nil {
+		return nil, 0, err
+	}
+	users, total, err := s.repo.List(ctx, filter, offset, limit)
+	if err != nil {
+		logger.Error("查询用户列表失败", zap.Error(err))
+		return nil, 0, errs.FromDB(err)
+	}
 
-	db := database.DB.WithContext(ctx).Model(&User{})
+	return users, total, nil
+}
 
-	// 获取总数
-	if err := db.Count(&total).Error; err != nil {
-		logger.Error("查询用户总数失败", zap.Error(err))
-		return nil, 0, err
+// ListUsersAfter 按游标（键集）分页获取用户列表，翻页开销与页码无关；不统计总数
+// 游标由上一页返回，翻页时需使用相同的过滤条件和排序方式
+// 参数:
+//
+//	ctx: 上下文
+//	filter: 过滤条件和排序
+//	cursor: 上一页返回的游标，为空时从第一条开始
+//	limit: 每页条数
+//
+// 返回:
+//
+//	[]*User: 用户列表
+//	string: 下一页的游标，没有更多数据时为空
+//	error: 游标无效或与排序方式不匹配时返回 errs.ErrValidation 类别的错误
+func (s *UserService) ListUsersAfter(ctx context.Context, filter UserFilter, cursor string, limit int) ([]*User, string, error) {
+	if err := checkUserSort(filter.Sort); err != nil {
+		return nil, "", err
+	}
+	var after *UserCursor
+	if cursor != "" {
+		c, err := DecodeUserCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if c.Sort != userSortOrDefault(filter.Sort) {
+			return nil, "", errs.Invalid("游标与排序方式 %q 不匹配", filter.Sort)
+		}
+		after = c
 	}
 
-	// 获取列表
-	if err := db.Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	// 多取一条判断是否还有下一页
+	users, err := s.repo.ListAfter(ctx, filter, after, limit+1)
+	if err != nil {
 		logger.Error("查询用户列表失败", zap.Error(err))
-		return nil, 0, err
+		return nil, "", errs.FromDB(err)
+	}
+	next := ""
+	if len(users) > limit {
+		users = users[:limit]
+		next = EncodeUserCursor(filter.Sort, users[limit-1])
 	}
+	return users, next, nil
+}
 
-	return users, total, nil
+// ScanUsers 按 ID 升序分批读取用户（键集分页，深度翻页不变慢），用于缓存重建等后台任务
+// 参数:
+//
+//	ctx: 上下文
+//	afterID: 从大于该 ID 的用户开始，0 表示从头开始
+//	batchSize: 每批数量
+//	fn: 处理一批用户，返回错误时停止
+//
+// 返回:
+//
+//	error: 查询错误或 fn 返回的错误
+func (s *UserService) ScanUsers(ctx context.Context, afterID int64, batchSize int, fn func(batch []*User) error) error {
+	for {
+		batch, err := s.repo.ScanAfter(ctx, afterID, batchSize)
+		if err != nil {
+			logger.Error("分批查询用户失败", zap.Int64("after_id", afterID), zap.Error(err))
+			return errs.FromDB(err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		afterID = batch[len(batch)-1].ID
+	}
 }
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/zhang/microservice/internal/errs"
+)
+
+// 用户列表的排序方式，- 前缀表示降序；相同创建时间的用户再按 ID 排序，保证顺序稳定
+const (
+	UserSortID            = "id"
+	UserSortIDDesc        = "-id"
+	UserSortCreatedAt     = "created_at"
+	UserSortCreatedAtDesc = "-created_at"
+)
+
+// userSorts 支持的排序方式
+var userSorts = map[string]bool{
+	UserSortID:            true,
+	UserSortIDDesc:        true,
+	UserSortCreatedAt:     true,
+	UserSortCreatedAtDesc: true,
+}
+
+// userSortOrDefault 返回排序方式，为空时为 UserSortID
+func userSortOrDefault(sort string) string {
+	if sort == "" {
+		return UserSortID
+	}
+	return sort
+}
+
+// checkUserSort 校验排序方式
+func checkUserSort(sort string) error {
+	if sort != "" && !userSorts[sort] {
+		return errs.Invalid("不支持的排序方式 %q，可选 id、-id、created_at、-created_at", sort)
+	}
+	return nil
+}
+
+// UserCursor 用户列表的游标：上一页最后一个用户在排序中的位置
+type UserCursor struct {
+	// Sort 生成游标时的排序方式
+	Sort string `json:"s"`
+	// ID 用户 ID
+	ID int64 `json:"id"`
+	// CreatedAt 创建时间（按创建时间排序时使用）
+	CreatedAt time.Time `json:"t"`
+}
+
+// EncodeUserCursor 生成位于 user 之后的游标（base64url 编码的 JSON，对调用方不透明）
+// 参数:
+//
+//	sort: 排序方式
+//	user: 当前页的最后一个用户
+//
+// 返回:
+//
+//	string: 游标
+func EncodeUserCursor(sort string, user *User) string {
+	data, _ := json.Marshal(UserCursor{Sort: userSortOrDefault(sort), ID: user.ID, CreatedAt: user.CreatedAt.UTC()})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeUserCursor 解析游标
+// 参数:
+//
+//	s: EncodeUserCursor 生成的游标
+//
+// 返回:
+//
+//	*UserCursor: 游标
+//	error: 游标无效时返回 errs.ErrValidation 类别的错误
+func DecodeUserCursor(s string) (*UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errs.Invalid("无效的游标")
+	}
+	var c UserCursor
+	if err := json.Unmarshal(data, &c); err != nil || !userSorts[c.Sort] {
+		return nil, errs.Invalid("无效的游标")
+	}
+	return &c, nil
+}
+
+// userLess 返回排序方式下 a 是否排在 b 之前（内存仓库使用，与 GormUserRepository 的 ORDER BY 一致）
+func userLess(sort string) func(a, b *User) bool {
+	switch sort {
+	case UserSortIDDesc:
+		return func(a, b *User) bool { return a.ID > b.ID }
+	case UserSortCreatedAt:
+		return func(a, b *User) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			return a.ID < b.ID
+		}
+	case UserSortCreatedAtDesc:
+		return func(a, b *User) bool {
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+			return a.ID > b.ID
+		}
+	default:
+		return func(a, b *User) bool { return a.ID < b.ID }
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// 用户变更类型
+const (
+	UserCreated = "created"
+	UserUpdated = "updated"
+	UserDeleted = "deleted"
+)
+
+// UserChange 用户变更通知
+type UserChange struct {
+	// Op 变更类型：created、updated、deleted
+	Op string
+	// ID 用户 ID
+	ID int64
+	// At 收到变更的时间
+	At time.Time
+}
+
+// UserFeed 把用户变更广播给订阅者（gRPC WatchUsers）
+// 每个订阅者有固定大小的缓冲区，缓冲区满（消费过慢）时关闭其通道并取消订阅，不阻塞其他订阅者
+type UserFeed struct {
+	mu     sync.Mutex
+	subs   map[chan UserChange]struct{}
+	buffer int
+	closed bool
+}
+
+// DefaultUserFeed 全局用户变更广播，由数据库变更通知驱动
+var DefaultUserFeed = NewUserFeed(64)
+
+// NewUserFeed 创建用户变更广播
+// 参数:
+//
+//	buffer: 每个订阅者的缓冲区大小
+//
+// 返回:
+//
+//	*UserFeed: 用户变更广播
+func NewUserFeed(buffer int) *UserFeed {
+	return &UserFeed{
+		subs:   make(map[chan UserChange]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe 订阅用户变更
+// 返回:
+//
+//	<-chan UserChange: 变更通道，因消费过慢被取消订阅或广播关闭（见 Closed）时关闭
+//	func(): 取消订阅
+func (f *UserFeed) Subscribe() (<-chan UserChange, func()) {
+	ch := make(chan UserChange, f.buffer)
+	f.mu.Lock()
+	if f.closed {
+		close(ch)
+	} else {
+		f.subs[ch] = struct{}{}
+	}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish 向所有订阅者发送变更
+// 参数:
+//
+//	change: 用户变更
+func (f *UserFeed) Publish(change UserChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- change:
+		default:
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close 关闭广播及所有订阅者的通道，服务关闭时调用，使订阅流结束
+func (f *UserFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// Closed 广播是否已关闭
+func (f *UserFeed) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// Subscribers 当前订阅者数量
+func (f *UserFeed) Subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
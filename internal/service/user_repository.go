@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/zhang/microservice/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRepository 用户数据访问接口
+// UserService 只通过该接口读写用户，生产环境使用 GormUserRepository，单元测试使用 MemoryUserRepository。
+// 实现返回存储层的原始错误（唯一约束冲突返回 gorm.ErrDuplicatedKey 或 PostgreSQL 错误），由 UserService 转换为 errs 错误
+type UserRepository interface {
+	// Get 按 ID 查询用户，不存在时返回 nil, nil
+	Get(ctx context.Context, id int64) (*User, error)
+	// GetForUpdate 在事务中按 ID 查询并锁定用户（其他事务的更新需等待），不存在时返回 nil, nil
+	GetForUpdate(ctx context.Context, id int64) (*User, error)
+	// GetByEmail 按邮箱查询用户，不存在时返回 nil, nil
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	// List 按过滤条件和排序方式分页查询用户及总数
+	List(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, int64, error)
+	// ListAfter 按过滤条件和排序方式查询排在游标之后的至多 limit 个用户，after 为 nil 时从第一条开始
+	ListAfter(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]*User, error)
+	// ScanAfter 按 ID 升序查询 ID 大于 afterID 的至多 limit 个用户
+	ScanAfter(ctx context.Context, afterID int64, limit int) ([]*User, error)
+	// Create 创建用户，成功后回填 ID 和创建时间
+	Create(ctx context.Context, user *User) error
+	// CreateBatch 在一条语句中批量创建用户，任一失败则全部失败
+	CreateBatch(ctx context.Context, users []*User) error
+	// Save 保存用户的全部列（密码哈希和角色除外）
+	Save(ctx context.Context, user *User) error
+	// UpdateColumns 只更新 columns 列出的列（密码哈希和角色除外），其余列保持不变
+	UpdateColumns(ctx context.Context, user *User, columns []string) error
+	// SetPasswordHash 更新密码哈希
+	SetPasswordHash(ctx context.Context, id int64, hash string) error
+	// SetRole 更新角色
+	SetRole(ctx context.Context, id int64, role string) error
+	// Delete 软删除用户（查询不再返回，可通过 Restore 恢复），不存在时不返回错误
+	Delete(ctx context.Context, id int64) error
+	// Restore 恢复已软删除的用户，返回是否恢复（用户不存在或未被删除时为 false）
+	Restore(ctx context.Context, id int64) (bool, error)
+	// Purge 永久删除用户（包括已软删除的），返回是否删除
+	Purge(ctx context.Context, id int64) (bool, error)
+	// PurgeDeleted 永久删除在 before 之前软删除的至多 limit 个用户，返回删除的数量
+	PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error)
+	// Transaction 在一个事务中执行 fn，fn 返回错误时回滚；fn 应只通过参数中的仓库访问数据
+	Transaction(ctx context.Context, fn func(repo UserRepository) error) error
+}
+
+// GormUserRepository 基于 GORM 的用户仓库
+// 上下文中有事务（database.RunInTx）时所有方法都加入该事务
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUserRepository 创建基于 GORM 的用户仓库
+// 参数:
+//
+//	db: 数据库连接（通常为 database.DB）
+//
+// 返回:
+//
+//	*GormUserRepository: 用户仓库
+func NewGormUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+// first 查询单个用户，不存在时返回 nil, nil
+func first(db *gorm.DB, conds ...interface{}) (*User, error) {
+	var user User
+	if err := db.First(&user, conds...).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Get 按 ID 查询用户
+func (r *GormUserRepository) Get(ctx context.Context, id int64) (*User, error) {
+	return first(database.Conn(ctx, r.db), id)
+}
+
+// GetForUpdate 按 ID 查询并锁定用户（SELECT ... FOR UPDATE）
+func (r *GormUserRepository) GetForUpdate(ctx context.Context, id int64) (*User, error) {
+	return first(database.Conn(ctx, r.db).Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+// GetByEmail 按邮箱查询用户
+func (r *GormUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return first(database.Conn(ctx, r.db).Where("email = ?", email))
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符，配合 ESCAPE '!' 使用（各驱动的默认转义字符不同）
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// filterUsers 添加过滤条件
+func filterUsers(db *gorm.DB, filter UserFilter) *gorm.DB {
+	if filter.Name != "" {
+		db = db.Where("name LIKE ?", "%"+filter.Name+"%")
+	}
+	if filter.NamePrefix != "" {
+		db = db.Where("name LIKE ? ESCAPE '!'", likeEscaper.Replace(filter.NamePrefix)+"%")
+	}
+	if filter.Email != "" {
+		db = db.Where("email = ?", filter.Email)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		db = db.Where("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	return db
+}
+
+// sortUsers 按排序方式添加 ORDER BY，与 userLess 一致
+func sortUsers(db *gorm.DB, sort string) *gorm.DB {
+	switch sort {
+	case UserSortIDDesc:
+		return db.Order("id DESC")
+	case UserSortCreatedAt:
+		return db.Order("created_at, id")
+	case UserSortCreatedAtDesc:
+		return db.Order("created_at DESC, id DESC")
+	default:
+		return db.Order("id")
+	}
+}
+
+// List 分页查询用户及总数
+func (r *GormUserRepository) List(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, int64, error) {
+	db := filterUsers(database.Conn(ctx, r.db).Model(&User{}), filter)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var users []*User
+	if err := sortUsers(db, filter.Sort).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// ListAfter 键集分页：按排序列与游标比较，走 (created_at, id) 或主键索引，不扫描前面的行
+func (r *GormUserRepository) ListAfter(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]*User, error) {
+	db := filterUsers(database.Conn(ctx, r.db), filter)
+	if after != nil {
+		switch after.Sort {
+		case UserSortIDDesc:
+			db = db.Where("id < ?", after.ID)
+		case UserSortCreatedAt:
+			db = db.Where("created_at > ? OR (created_at = ? AND id > ?)", after.CreatedAt, after.CreatedAt, after.ID)
+		case UserSortCreatedAtDesc:
+			db = db.Where("created_at < ? OR (created_at = ? AND id < ?)", after.CreatedAt, after.CreatedAt, after.ID)
+		default:
+			db = db.Where("id > ?", after.ID)
+		}
+	}
+
+	var users []*User
+	err := sortUsers(db, filter.Sort).Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// ScanAfter 按 ID 升序查询一批用户
+func (r *GormUserRepository) ScanAfter(ctx context.Context, afterID int64, limit int) ([]*User, error) {
+	var users []*User
+	err := database.Conn(ctx, r.db).Where("id > ?", afterID).Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+// Create 创建用户
+func (r *GormUserRepository) Create(ctx context.Context, user *User) error {
+	return database.Conn(ctx, r.db).Create(user).Error
+}
+
+// CreateBatch 批量创建用户（一条 INSERT）
+func (r *GormUserRepository) CreateBatch(ctx context.Context, users []*User) error {
+	return database.Conn(ctx, r.db).Create(users).Error
+}
+
+// Save 保存用户的全部列
+func (r *GormUserRepository) Save(ctx context.Context, user *User) error {
+	return database.Conn(ctx, r.db).Omit(credentialColumns...).Save(user).Error
+}
+
+// UpdateColumns 只更新指定的列
+// 受限的 Select 下 GORM 不会自动写入 updated_at，需要时由调用方列出
+func (r *GormUserRepository) UpdateColumns(ctx context.Context, user *User, columns []string) error {
+	return database.Conn(ctx, r.db).Model(&User{ID: user.ID}).Select(columns).Omit(credentialColumns...).Updates(user).Error
+}
+
+// SetPasswordHash 更新密码哈希
+func (r *GormUserRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	return database.Conn(ctx, r.db).Model(&User{}).Where("id = ?", id).Update("password_hash", hash).Error
+}
+
+// SetRole 更新角色
+func (r *GormUserRepository) SetRole(ctx context.Context, id int64, role string) error {
+	return database.Conn(ctx, r.db).Model(&User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// Delete 软删除用户（写入 deleted_at）
+func (r *GormUserRepository) Delete(ctx context.Context, id int64) error {
+	return database.Conn(ctx, r.db).Delete(&User{}, id).Error
+}
+
+// Restore 清除 deleted_at 恢复用户
+func (r *GormUserRepository) Restore(ctx context.Context, id int64) (bool, error) {
+	result := database.Conn(ctx, r.db).Unscoped().Model(&User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": time.Now().UTC()})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Purge 永久删除用户
+func (r *GormUserRepository) Purge(ctx context.Context, id int64) (bool, error) {
+	result := database.Conn(ctx, r.db).Unscoped().Delete(&User{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// PurgeDeleted 先查出一批过期的 ID 再按 ID 删除（MySQL 不支持 IN 子查询中的 LIMIT）
+func (r *GormUserRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	var ids []int64
+	err := database.Conn(ctx, r.db).Unscoped().Model(&User{}).
+		Where("deleted_at < ?", before).Order("id").Limit(limit).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	result := database.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&User{}, ids)
+	return result.RowsAffected, result.Error
+}
+
+// Transaction 在数据库事务中执行 fn，已在上下文事务中时嵌套执行（保存点）
+func (r *GormUserRepository) Transaction(ctx context.Context, fn func(repo UserRepository) error) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		return fn(&GormUserRepository{db: tx})
+	})
+}
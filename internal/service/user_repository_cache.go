@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// CachedUserRepository 带读缓存的用户仓库，包装另一个 UserRepository
+// Get 经 cache.Refresher 读取 Redis 中的用户（类别 UserCacheClass，JSON 编码，TTL 由 redis.refresh.classes.user 配置并带随机抖动），
+// 未命中时回源并写入缓存，同一个用户的并发未命中只回源一次；修改用户的方法成功后删除对应缓存。
+// 缓存中的用户不含密码哈希和角色（json:"-"），需要它们的调用方应使用 GetByEmail 或 GetForUpdate；
+// 上下文中有事务时 Get 直接读底层仓库，避免读到事务外的缓存
+type CachedUserRepository struct {
+	UserRepository
+	refresher *cache.Refresher
+
+	// pending 在 Transaction 中修改的用户 ID，提交后再删除缓存；为 nil 时修改后立即删除
+	pending *[]int64
+}
+
+// NewCachedUserRepository 创建带读缓存的用户仓库，并把用户缓存类别注册到 refresher（预刷新时从 repo 加载）
+// 参数:
+//
+//	repo: 底层用户仓库
+//	refresher: 缓存刷新器（通常为 cache.DefaultRefresher）
+//
+// 返回:
+//
+//	*CachedUserRepository: 用户仓库
+func NewCachedUserRepository(repo UserRepository, refresher *cache.Refresher) *CachedUserRepository {
+	r := &CachedUserRepository{UserRepository: repo, refresher: refresher}
+	refresher.Register(UserCacheClass, r.load)
+	return r
+}
+
+// load 缓存未命中或预刷新时从底层仓库加载用户
+func (r *CachedUserRepository) load(ctx context.Context, key string) (interface{}, error) {
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.UserRepository.Get(ctx, id)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// changed 删除修改过的用户的缓存，失败时只记录日志（缓存会在 TTL 后过期）
+func (r *CachedUserRepository) changed(ctx context.Context, ids ...int64) {
+	if r.pending != nil {
+		*r.pending = append(*r.pending, ids...)
+		return
+	}
+	for _, id := range ids {
+		if err := r.refresher.Invalidate(ctx, UserCacheClass, strconv.FormatInt(id, 10)); err != nil {
+			logger.Warn("删除用户缓存失败", zap.Int64("id", id), zap.Error(err))
+		}
+	}
+}
+
+// Get 按 ID 查询用户（经缓存）
+func (r *CachedUserRepository) Get(ctx context.Context, id int64) (*User, error) {
+	if r.pending != nil || database.TxFrom(ctx) != nil {
+		return r.UserRepository.Get(ctx, id)
+	}
+	var user User
+	found, err := r.refresher.Fetch(ctx, UserCacheClass, strconv.FormatInt(id, 10), &user)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Save 保存用户并删除缓存
+func (r *CachedUserRepository) Save(ctx context.Context, user *User) error {
+	if err := r.UserRepository.Save(ctx, user); err != nil {
+		return err
+	}
+	r.changed(ctx, user.ID)
+	return nil
+}
+
+// UpdateColumns 更新指定的列并删除缓存
+func (r *CachedUserRepository) UpdateColumns(ctx context.Context, user *User, columns []string) error {
+	if err := r.UserRepository.UpdateColumns(ctx, user, columns); err != nil {
+		return err
+	}
+	r.changed(ctx, user.ID)
+	return nil
+}
+
+// SetPasswordHash 更新密码哈希并删除缓存（更新时间随之变化）
+func (r *CachedUserRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	if err := r.UserRepository.SetPasswordHash(ctx, id, hash); err != nil {
+		return err
+	}
+	r.changed(ctx, id)
+	return nil
+}
+
+// SetRole 更新角色并删除缓存
+func (r *CachedUserRepository) SetRole(ctx context.Context, id int64, role string) error {
+	if err := r.UserRepository.SetRole(ctx, id, role); err != nil {
+		return err
+	}
+	r.changed(ctx, id)
+	return nil
+}
+
+// Delete 软删除用户并删除缓存
+func (r *CachedUserRepository) Delete(ctx context.Context, id int64) error {
+	if err := r.UserRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	r.changed(ctx, id)
+	return nil
+}
+
+// Restore 恢复用户并删除缓存
+func (r *CachedUserRepository) Restore(ctx context.Context, id int64) (bool, error) {
+	restored, err := r.UserRepository.Restore(ctx, id)
+	if err != nil || !restored {
+		return restored, err
+	}
+	r.changed(ctx, id)
+	return true, nil
+}
+
+// Purge 永久删除用户并删除缓存
+func (r *CachedUserRepository) Purge(ctx context.Context, id int64) (bool, error) {
+	purged, err := r.UserRepository.Purge(ctx, id)
+	if err != nil || !purged {
+		return purged, err
+	}
+	r.changed(ctx, id)
+	return true, nil
+}
+
+// Transaction 在事务中执行 fn，提交成功后才删除 fn 中修改过的用户的缓存，
+// 避免并发请求在提交前把旧数据重新写入缓存
+func (r *CachedUserRepository) Transaction(ctx context.Context, fn func(repo UserRepository) error) error {
+	var pending []int64
+	err := r.UserRepository.Transaction(ctx, func(repo UserRepository) error {
+		return fn(&CachedUserRepository{UserRepository: repo, refresher: r.refresher, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+	r.changed(ctx, pending...)
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+)
+
+// countingUserRepository 统计 Get 次数，gate 非空时 Get 等待 gate 关闭
+type countingUserRepository struct {
+	*MemoryUserRepository
+	gets atomic.Int64
+	gate chan struct{}
+}
+
+func (r *countingUserRepository) Get(ctx context.Context, id int64) (*User, error) {
+	r.gets.Add(1)
+	if r.gate != nil {
+		<-r.gate
+	}
+	return r.MemoryUserRepository.Get(ctx, id)
+}
+
+// useTestRedis 替换 cache.RedisClient 为 miniredis（testutil 依赖本包，不能引用 testutil.UseMiniredis）
+func useTestRedis(t *testing.T) *miniredis.Miniredis {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	prev := cache.RedisClient
+	cache.RedisClient = client
+	t.Cleanup(func() {
+		cache.RedisClient = prev
+		client.Close()
+	})
+	return mr
+}
+
+// TestCachedUserRepository 测试读缓存、写后删除缓存和并发未命中合并
+func TestCachedUserRepository(t *testing.T) {
+	useNopLogger()
+	mr := useTestRedis(t)
+	ctx := context.Background()
+
+	base := &countingUserRepository{MemoryUserRepository: NewMemoryUserRepository()}
+	refresher := cache.NewRefresher(config.CacheRefreshConfig{Classes: map[string]config.CacheClassConfig{
+		UserCacheClass: {TTL: 60},
+	}})
+	users := NewUserService(NewCachedUserRepository(base, refresher))
+
+	user := &User{Name: "张三", Email: "a@example.com"}
+	if _, err := users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	hits := metrics.CacheClassRequests.WithLabelValues(UserCacheClass, "hit")
+	hitsBefore := promtest.ToFloat64(hits)
+	for i := 0; i < 3; i++ {
+		got, err := users.GetUser(ctx, user.ID)
+		if err != nil || got == nil || got.Name != "张三" {
+			t.Fatalf("GetUser = %+v, %v", got, err)
+		}
+	}
+	if n := base.gets.Load(); n != 1 {
+		t.Errorf("回源 %d 次, 期望 1", n)
+	}
+	if got := promtest.ToFloat64(hits) - hitsBefore; got != 2 {
+		t.Errorf("命中 %v 次, 期望 2", got)
+	}
+	// TTL 按配置（加最多 10% 抖动）
+	if ttl := mr.TTL("cache:user:1"); ttl < time.Minute || ttl > 66*time.Second {
+		t.Errorf("TTL = %v", ttl)
+	}
+
+	// 事务中的更新提交后删除缓存
+	if _, err := users.UpdateUser(ctx, &User{ID: user.ID, Name: "张三丰"}, "name"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("cache:user:1") {
+		t.Error("更新后缓存未删除")
+	}
+	if got, err := users.GetUser(ctx, user.ID); err != nil || got.Name != "张三丰" {
+		t.Fatalf("更新后 GetUser = %+v, %v", got, err)
+	}
+
+	// 删除后不再返回，不存在的用户不写入缓存
+	if err := users.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := users.GetUser(ctx, user.ID); err != nil || got != nil {
+		t.Fatalf("删除后 GetUser = %+v, %v", got, err)
+	}
+	if mr.Exists("cache:user:1") {
+		t.Error("不存在的用户被写入缓存")
+	}
+	if _, err := users.RestoreUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 并发未命中只回源一次：第一次加载阻塞到所有请求都读过 Redis
+	mr.Del("cache:user:1")
+	base.gets.Store(0)
+	base.gate = make(chan struct{})
+	const n = 8
+	commands := mr.CommandCount()
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got, err := users.GetUser(ctx, user.ID); err != nil || got == nil {
+				t.Errorf("并发 GetUser = %+v, %v", got, err)
+			}
+		}()
+	}
+	// 每次读取执行 ZADD、GET 两条命令
+	for mr.CommandCount() < commands+2*n {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(base.gate)
+	wg.Wait()
+	if got := base.gets.Load(); got != 1 {
+		t.Errorf("并发未命中回源 %d 次, 期望 1", got)
+	}
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryUserRepository 内存用户仓库，用于单元测试
+// 与 users 表的约束一致：ID 自增、邮箱唯一（包括已软删除的用户，冲突时返回 gorm.ErrDuplicatedKey）、角色默认为 user、版本从 1 开始；
+// 查询和更新与 gorm 一样跳过已软删除的用户；
+// 读写均复制 User，调用方修改返回值不影响仓库中的数据
+type MemoryUserRepository struct {
+	mu     sync.Mutex
+	users  map[int64]*User
+	nextID int64
+
+	// txMu 串行执行事务，失败时整体回滚
+	txMu sync.Mutex
+}
+
+// NewMemoryUserRepository 创建内存用户仓库
+// 返回:
+//
+//	*MemoryUserRepository: 用户仓库
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[int64]*User)}
+}
+
+// get 按 ID 复制用户（包括已软删除的），调用方需持有锁
+func (r *MemoryUserRepository) get(id int64) *User {
+	user, ok := r.users[id]
+	if !ok {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+// live 按 ID 查询未删除的用户（不复制），调用方需持有锁
+func (r *MemoryUserRepository) live(id int64) (*User, bool) {
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, false
+	}
+	return user, true
+}
+
+// emailTaken 邮箱是否已被其他用户使用，调用方需持有锁
+func (r *MemoryUserRepository) emailTaken(email string, exceptID int64) bool {
+	for id, user := range r.users {
+		if id != exceptID && user.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// insert 插入用户并回填 ID、时间、默认角色和初始版本，调用方需持有锁
+func (r *MemoryUserRepository) insert(user *User) {
+	r.nextID++
+	user.ID = r.nextID
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	user.UpdatedAt = now
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.Version == 0 {
+		user.Version = 1
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+}
+
+// Get 按 ID 查询用户
+func (r *MemoryUserRepository) Get(ctx context.Context, id int64) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.live(id); !ok {
+		return nil, nil
+	}
+	return r.get(id), nil
+}
+
+// GetForUpdate 按 ID 查询用户（事务已串行执行，无需额外加锁）
+func (r *MemoryUserRepository) GetForUpdate(ctx context.Context, id int64) (*User, error) {
+	return r.Get(ctx, id)
+}
+
+// GetByEmail 按邮箱查询用户
+func (r *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return r.get(id), nil
+		}
+	}
+	return nil, nil
+}
+
+// match 按过滤条件和排序方式查询未删除的用户，调用方需持有锁
+func (r *MemoryUserRepository) match(filter UserFilter) []*User {
+	var matched []*User
+	for id, user := range r.users {
+		switch {
+		case user.DeletedAt.Valid,
+			filter.Name != "" && !strings.Contains(user.Name, filter.Name),
+			filter.NamePrefix != "" && !strings.HasPrefix(user.Name, filter.NamePrefix),
+			filter.Email != "" && user.Email != filter.Email,
+			!filter.CreatedAfter.IsZero() && user.CreatedAt.Before(filter.CreatedAfter),
+			!filter.CreatedBefore.IsZero() && !user.CreatedAt.Before(filter.CreatedBefore):
+			continue
+		}
+		matched = append(matched, r.get(id))
+	}
+	less := userLess(filter.Sort)
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+	return matched
+}
+
+// List 分页查询用户及总数
+func (r *MemoryUserRepository) List(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := r.match(filter)
+	total := int64(len(matched))
+	if offset > len(matched) {
+		offset = len(matched)
+	}
+	matched = matched[offset:]
+	if limit >= 0 && limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, total, nil
+}
+
+// ListAfter 查询排在游标之后的一批用户
+func (r *MemoryUserRepository) ListAfter(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := r.match(filter)
+	if after != nil {
+		less := userLess(filter.Sort)
+		pos := &User{ID: after.ID, CreatedAt: after.CreatedAt}
+		i := sort.Search(len(matched), func(i int) bool { return less(pos, matched[i]) })
+		matched = matched[i:]
+	}
+	if limit < len(matched) {
+		matched = matched[:limit]
+	}
+	return matched, nil
+}
+
+// ScanAfter 按 ID 升序查询一批用户
+func (r *MemoryUserRepository) ScanAfter(ctx context.Context, afterID int64, limit int) ([]*User, error) {
+	users, _, err := r.List(ctx, UserFilter{}, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(users), func(i int) bool { return users[i].ID > afterID })
+	users = users[i:]
+	if limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Create 创建用户
+func (r *MemoryUserRepository) Create(ctx context.Context, user *User) error {
+	return r.CreateBatch(ctx, []*User{user})
+}
+
+// CreateBatch 批量创建用户，任一邮箱重复时全部不创建
+func (r *MemoryUserRepository) CreateBatch(ctx context.Context, users []*User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		if seen[user.Email] || r.emailTaken(user.Email, 0) {
+			return gorm.ErrDuplicatedKey
+		}
+		seen[user.Email] = true
+	}
+	for _, user := range users {
+		r.insert(user)
+	}
+	return nil
+}
+
+// Save 保存用户的全部列（密码哈希和角色保持不变），用户不存在时创建
+func (r *MemoryUserRepository) Save(ctx context.Context, user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(user.Email, user.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	current, ok := r.users[user.ID]
+	if !ok {
+		r.insert(user)
+		return nil
+	}
+
+	user.UpdatedAt = time.Now()
+	copied := *user
+	copied.PasswordHash, copied.Role = current.PasswordHash, current.Role
+	r.users[user.ID] = &copied
+	return nil
+}
+
+// UpdateColumns 只更新指定的列
+func (r *MemoryUserRepository) UpdateColumns(ctx context.Context, user *User, columns []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.live(user.ID)
+	if !ok {
+		return nil
+	}
+	updated := *current
+	for _, column := range columns {
+		switch column {
+		case "name":
+			updated.Name = user.Name
+		case "email":
+			if r.emailTaken(user.Email, user.ID) {
+				return gorm.ErrDuplicatedKey
+			}
+			updated.Email = user.Email
+		case "phone":
+			updated.Phone = user.Phone
+		case "timezone":
+			updated.Timezone = user.Timezone
+		case "email_verified":
+			updated.EmailVerified = user.EmailVerified
+		case "phone_verified":
+			updated.PhoneVerified = user.PhoneVerified
+		case "last_seen_at":
+			updated.LastSeenAt = user.LastSeenAt
+		case "updated_at":
+			updated.UpdatedAt = time.Now()
+		case "version":
+			updated.Version = user.Version
+		case "password_hash", "role":
+		default:
+			return fmt.Errorf("未知的列 %q", column)
+		}
+	}
+	r.users[user.ID] = &updated
+	return nil
+}
+
+// SetPasswordHash 更新密码哈希
+func (r *MemoryUserRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.live(id); ok {
+		user.PasswordHash = hash
+	}
+	return nil
+}
+
+// SetRole 更新角色
+func (r *MemoryUserRepository) SetRole(ctx context.Context, id int64, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.live(id); ok {
+		user.Role = role
+	}
+	return nil
+}
+
+// Delete 软删除用户
+func (r *MemoryUserRepository) Delete(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user, ok := r.live(id); ok {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+// Restore 恢复已软删除的用户
+func (r *MemoryUserRepository) Restore(ctx context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return false, nil
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.UpdatedAt = time.Now()
+	return true, nil
+}
+
+// Purge 永久删除用户
+func (r *MemoryUserRepository) Purge(ctx context.Context, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.users[id]
+	delete(r.users, id)
+	return ok, nil
+}
+
+// PurgeDeleted 按 ID 升序永久删除在 before 之前软删除的至多 limit 个用户
+func (r *MemoryUserRepository) PurgeDeleted(ctx context.Context, before time.Time, limit int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []int64
+	for id, user := range r.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if limit < len(ids) {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		delete(r.users, id)
+	}
+	return int64(len(ids)), nil
+}
+
+// Transaction 串行执行 fn，fn 返回错误时恢复执行前的数据
+// 只与其他事务互斥，事务外的并发写入可能被回滚覆盖，测试中不应混用
+func (r *MemoryUserRepository) Transaction(ctx context.Context, fn func(repo UserRepository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	r.mu.Lock()
+	snapshot := make(map[int64]*User, len(r.users))
+	for id := range r.users {
+		snapshot[id] = r.get(id)
+	}
+	nextID := r.nextID
+	r.mu.Unlock()
+
+	if err := fn(r); err != nil {
+		r.mu.Lock()
+		r.users, r.nextID = snapshot, nextID
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
@@ -2,10 +2,25 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
 )
 
+// useNopLogger 未初始化日志时使用空日志（testutil 依赖本包，不能在包内测试中引用）
+func useNopLogger() {
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+		logger.Sugar = logger.Logger.Sugar()
+	}
+}
+
 // TestUserModel 测试用户模型
 func TestUserModel(t *testing.T) {
 	user := User{
@@ -24,21 +39,17 @@ func TestUserModel(t *testing.T) {
 
 // TestNewUserService 测试创建用户服务
 func TestNewUserService(t *testing.T) {
-	service := NewUserService()
+	service := NewUserService(NewMemoryUserRepository())
 	if service == nil {
 		t.Error("用户服务创建失败")
 	}
 }
 
-// 注意：以下测试需要数据库连接，实际测试时需要先初始化数据库
-// 这里仅作为示例展示如何编写测试
-
-// TestUserService_CRUD 测试用户 CRUD 操作（需要数据库）
+// TestUserService_CRUD 测试用户 CRUD 操作
 func TestUserService_CRUD(t *testing.T) {
-	t.Skip("跳过需要数据库的测试")
-
+	useNopLogger()
 	ctx := context.Background()
-	service := NewUserService()
+	service := NewUserService(NewMemoryUserRepository())
 
 	// 测试创建用户
 	user := &User{
@@ -46,37 +57,431 @@ func TestUserService_CRUD(t *testing.T) {
 		Email: "test@example.com",
 		Phone: "13800138000",
 	}
+	createdUser, err := service.CreateUser(ctx, user)
+	if err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if createdUser.ID == 0 || createdUser.CreatedAt.IsZero() {
+		t.Errorf("创建的用户未回填 ID 和创建时间: %+v", createdUser)
+	}
 
-	// createdUser, err := service.CreateUser(ctx, user)
-	// if err != nil {
-	// 	t.Fatalf("创建用户失败: %v", err)
-	// }
+	// 邮箱重复
+	_, err = service.CreateUser(ctx, &User{Name: "重复", Email: "test@example.com"})
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("邮箱重复: err = %v, 期望 ErrConflict", err)
+	}
 
 	// 测试获取用户
-	// gotUser, err := service.GetUser(ctx, createdUser.ID)
-	// if err != nil {
-	// 	t.Fatalf("获取用户失败: %v", err)
-	// }
-	// if gotUser.Email != user.Email {
-	// 	t.Errorf("期望邮箱为 %s, 实际为 %s", user.Email, gotUser.Email)
-	// }
-
-	// 测试更新用户
-	// gotUser.Name = "更新后的用户"
-	// updatedUser, err := service.UpdateUser(ctx, gotUser)
-	// if err != nil {
-	// 	t.Fatalf("更新用户失败: %v", err)
-	// }
-	// if updatedUser.Name != "更新后的用户" {
-	// 	t.Errorf("期望名称为 '更新后的用户', 实际为 %s", updatedUser.Name)
-	// }
+	gotUser, err := service.GetUser(ctx, createdUser.ID)
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	if gotUser.Email != user.Email {
+		t.Errorf("期望邮箱为 %s, 实际为 %s", user.Email, gotUser.Email)
+	}
+	if byEmail, err := service.GetUserByEmail(ctx, user.Email); err != nil || byEmail == nil || byEmail.ID != createdUser.ID {
+		t.Errorf("按邮箱获取用户 = %+v, %v", byEmail, err)
+	}
+	if missing, err := service.GetUser(ctx, 999); missing != nil || err != nil {
+		t.Errorf("不存在的用户 = %+v, %v", missing, err)
+	}
+
+	// 测试更新用户（全部列，不修改密码）
+	if err := service.SetPasswordHash(ctx, createdUser.ID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	gotUser.Name = "更新后的用户"
+	gotUser.PasswordHash = ""
+	updatedUser, err := service.UpdateUser(ctx, gotUser)
+	if err != nil {
+		t.Fatalf("更新用户失败: %v", err)
+	}
+	if updatedUser.Name != "更新后的用户" {
+		t.Errorf("期望名称为 '更新后的用户', 实际为 %s", updatedUser.Name)
+	}
+	if stored, _ := service.GetUser(ctx, createdUser.ID); stored.PasswordHash != "hash" {
+		t.Error("UpdateUser 不应覆盖密码哈希")
+	}
 
 	// 测试删除用户
-	// err = service.DeleteUser(ctx, createdUser.ID)
-	// if err != nil {
-	// 	t.Fatalf("删除用户失败: %v", err)
-	// }
+	if err := service.DeleteUser(ctx, createdUser.ID); err != nil {
+		t.Fatalf("删除用户失败: %v", err)
+	}
+	if deleted, _ := service.GetUser(ctx, createdUser.ID); deleted != nil {
+		t.Error("删除后仍能获取用户")
+	}
+}
+
+// TestUserService_VersionConflict 测试乐观锁：提交过期的版本时不覆盖其他请求的修改
+func TestUserService_VersionConflict(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	service := NewUserService(NewMemoryUserRepository())
+
+	created, err := service.CreateUser(ctx, &User{Name: "张三", Email: "zhang@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("新用户版本 = %d", created.Version)
+	}
+
+	// 两个客户端读取到同一版本，先提交的成功
+	first := &User{ID: created.ID, Name: "张三丰", Version: created.Version}
+	updated, err := service.UpdateUser(ctx, first, "name")
+	if err != nil || updated.Version != 2 {
+		t.Fatalf("第一次更新 = %+v, %v", updated, err)
+	}
+	second := &User{ID: created.ID, Phone: "13800138000", Version: created.Version}
+	if _, err := service.UpdateUser(ctx, second, "phone"); !errors.Is(err, errs.ErrVersionConflict) {
+		t.Fatalf("过期版本更新: err = %v，期望版本冲突", err)
+	}
+	current, _ := service.GetUser(ctx, created.ID)
+	if current.Name != "张三丰" || current.Phone != "" || current.Version != 2 {
+		t.Errorf("冲突后用户 = %+v", current)
+	}
+
+	// 全部列更新同样检查版本；版本为 0 时不检查
+	current.Name = "王五"
+	current.Version = 1
+	if _, err := service.UpdateUser(ctx, current); !errors.Is(err, errs.ErrVersionConflict) {
+		t.Errorf("全部列更新过期版本: err = %v", err)
+	}
+	current.Version = 0
+	if updated, err := service.UpdateUser(ctx, current); err != nil || updated.Version != 3 {
+		t.Errorf("不检查版本更新 = %+v, %v", updated, err)
+	}
+}
+
+// TestUserService_SoftDelete 测试软删除、恢复和永久删除
+func TestUserService_SoftDelete(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	service := NewUserService(NewMemoryUserRepository())
+
+	created, err := service.CreateUser(ctx, &User{Name: "张三", Email: "zhang@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteUser(ctx, created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := service.GetUserByEmail(ctx, created.Email); got != nil {
+		t.Error("软删除后仍能按邮箱查询到用户")
+	}
+	if _, total, _ := service.ListUsers(ctx, UserFilter{}, 0, 10); total != 0 {
+		t.Errorf("软删除后列表总数 = %d", total)
+	}
+	// 软删除的用户仍占用邮箱
+	if _, err := service.CreateUser(ctx, &User{Name: "李四", Email: created.Email}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("使用已删除用户的邮箱创建: err = %v", err)
+	}
+
+	restored, err := service.RestoreUser(ctx, created.ID)
+	if err != nil || restored == nil || restored.Email != created.Email {
+		t.Fatalf("RestoreUser = %+v, %v", restored, err)
+	}
+	if again, err := service.RestoreUser(ctx, created.ID); err != nil || again != nil {
+		t.Errorf("恢复未删除的用户 = %+v, %v", again, err)
+	}
+
+	if purged, err := service.PurgeUser(ctx, created.ID); err != nil || !purged {
+		t.Fatalf("PurgeUser = %v, %v", purged, err)
+	}
+	if restored, _ := service.RestoreUser(ctx, created.ID); restored != nil {
+		t.Error("永久删除后仍能恢复")
+	}
+	if purged, _ := service.PurgeUser(ctx, created.ID); purged {
+		t.Error("重复永久删除返回 true")
+	}
+}
+
+// TestUserService_PurgeDeletedUsers 测试按软删除时间分批清理
+func TestUserService_PurgeDeletedUsers(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	service := NewUserService(NewMemoryUserRepository())
+
+	users := make([]*User, purgeBatchSize+2)
+	for i := range users {
+		users[i] = &User{Name: "u", Email: fmt.Sprintf("u%d@example.com", i)}
+	}
+	if err := service.CreateUsers(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	// 最后一个用户保持正常，其余软删除
+	for _, user := range users[:len(users)-1] {
+		if err := service.DeleteUser(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := service.PurgeDeletedUsers(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("未到期时清理 = %d, %v", n, err)
+	}
+	n, err := service.PurgeDeletedUsers(ctx, time.Now().Add(time.Second))
+	if err != nil || n != int64(len(users)-1) {
+		t.Fatalf("清理 = %d, %v，期望 %d", n, err, len(users)-1)
+	}
+	if restored, _ := service.RestoreUser(ctx, users[0].ID); restored != nil {
+		t.Error("清理后仍能恢复")
+	}
+	if _, total, _ := service.ListUsers(ctx, UserFilter{}, 0, 10); total != 1 {
+		t.Errorf("清理后剩余 %d 个用户", total)
+	}
+}
+
+// TestUserService_PartialUpdate 测试按字段更新
+func TestUserService_PartialUpdate(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	service := NewUserService(NewMemoryUserRepository())
+
+	created, err := service.CreateUser(ctx, &User{
+		Name: "张三", Email: "zhang@example.com", Phone: "13800138000",
+		EmailVerified: true, PhoneVerified: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只更新 email：名称保持数据库中的值，邮箱变化时重置验证状态，手机号验证状态不变
+	updated, err := service.UpdateUser(ctx, &User{ID: created.ID, Name: "忽略", Email: "new@example.com"}, "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "张三" || updated.Email != "new@example.com" || updated.EmailVerified || !updated.PhoneVerified {
+		t.Errorf("更新后的用户 = %+v", updated)
+	}
+
+	// 邮箱未变化时不重置验证状态
+	if _, err := service.UpdateUser(ctx, &User{ID: created.ID, Phone: "13800138000"}, "phone"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := service.GetUser(ctx, created.ID); !got.PhoneVerified {
+		t.Error("手机号未变化时不应重置验证状态")
+	}
+
+	// 邮箱冲突时整体回滚
+	other, err := service.CreateUser(ctx, &User{Name: "李四", Email: "li@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.UpdateUser(ctx, &User{ID: other.ID, Name: "李四四", Email: "new@example.com"}, "name", "email")
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("邮箱冲突: err = %v", err)
+	}
+	if got, _ := service.GetUser(ctx, other.ID); got.Name != "李四" {
+		t.Errorf("冲突后名称 = %s, 期望回滚为 李四", got.Name)
+	}
+
+	// 用户不存在
+	if missing, err := service.UpdateUser(ctx, &User{ID: 999, Name: "x"}, "name"); missing != nil || err != nil {
+		t.Errorf("不存在的用户 = %+v, %v", missing, err)
+	}
+}
 
-	_ = ctx
-	_ = service
+// TestUserService_List 测试列表过滤、分页和分批读取
+func TestUserService_List(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	service := NewUserService(NewMemoryUserRepository())
+
+	users := []*User{
+		{Name: "张三", Email: "a@example.com"},
+		{Name: "张三丰", Email: "b@example.com"},
+		{Name: "李四", Email: "c@example.com"},
+	}
+	if err := service.CreateUsers(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.CreateUsers(ctx, []*User{{Name: "x", Email: "d@example.com"}, {Name: "y", Email: "a@example.com"}}); !errors.Is(err, errs.ErrConflict) {
+		t.Errorf("批量创建邮箱重复: err = %v", err)
+	}
+
+	list, total, err := service.ListUsers(ctx, UserFilter{Name: "张三"}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(list) != 1 || list[0].Name != "张三丰" {
+		t.Errorf("列表 = %+v, 总数 = %d", list, total)
+	}
+
+	var scanned []int64
+	err = service.ScanUsers(ctx, 0, 2, func(batch []*User) error {
+		for _, user := range batch {
+			scanned = append(scanned, user.ID)
+		}
+		return nil
+	})
+	if err != nil || len(scanned) != 3 || scanned[0] != users[0].ID || scanned[2] != users[2].ID {
+		t.Errorf("分批读取 = %v, %v", scanned, err)
+	}
+}
+
+// TestUserService_ListCursor 测试游标翻页、排序和过滤条件
+func TestUserService_ListCursor(t *testing.T) {
+	useNopLogger()
+	ctx := context.Background()
+	repo := NewMemoryUserRepository()
+	service := NewUserService(repo)
+
+	// 创建时间与 ID 顺序相反，且 2、3 的创建时间相同
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := []*User{
+		{Name: "a1", Email: "a1@example.com", CreatedAt: base.Add(3 * time.Hour)},
+		{Name: "a2", Email: "a2@example.com", CreatedAt: base.Add(time.Hour)},
+		{Name: "a3", Email: "a3@example.com", CreatedAt: base.Add(time.Hour)},
+		{Name: "b4", Email: "b4@example.com", CreatedAt: base},
+		{Name: "a_5", Email: "a5@example.com", CreatedAt: base.Add(4 * time.Hour)},
+	}
+	if err := service.CreateUsers(ctx, users); err != nil {
+		t.Fatal(err)
+	}
+
+	// collect 按游标翻完所有页，返回名称顺序
+	collect := func(filter UserFilter, limit int) []string {
+		t.Helper()
+		var names []string
+		cursor := ""
+		for pages := 0; ; pages++ {
+			list, next, err := service.ListUsersAfter(ctx, filter, cursor, limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range list {
+				names = append(names, user.Name)
+			}
+			if next == "" || pages > 10 {
+				return names
+			}
+			cursor = next
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter UserFilter
+		want   string
+	}{
+		{"默认按 ID", UserFilter{}, "a1 a2 a3 b4 a_5"},
+		{"ID 降序", UserFilter{Sort: UserSortIDDesc}, "a_5 b4 a3 a2 a1"},
+		{"创建时间", UserFilter{Sort: UserSortCreatedAt}, "b4 a2 a3 a1 a_5"},
+		{"创建时间降序", UserFilter{Sort: UserSortCreatedAtDesc}, "a_5 a1 a3 a2 b4"},
+		{"名称前缀", UserFilter{NamePrefix: "a"}, "a1 a2 a3 a_5"},
+		{"创建时间范围", UserFilter{CreatedAfter: base.Add(time.Hour), CreatedBefore: base.Add(4 * time.Hour)}, "a1 a2 a3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(collect(tt.filter, 2), " "); got != tt.want {
+				t.Errorf("游标翻页 = %q, 期望 %q", got, tt.want)
+			}
+			list, _, err := service.ListUsers(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, user := range list {
+				names = append(names, user.Name)
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("偏移量分页 = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+
+	// 最后一页恰好取满时没有下一页
+	if _, next, err := service.ListUsersAfter(ctx, UserFilter{}, "", 5); err != nil || next != "" {
+		t.Errorf("next = %q, err = %v", next, err)
+	}
+
+	_, next, err := service.ListUsersAfter(ctx, UserFilter{}, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := []struct {
+		name   string
+		filter UserFilter
+		cursor string
+	}{
+		{"不支持的排序", UserFilter{Sort: "name"}, ""},
+		{"游标格式错误", UserFilter{}, "not-a-cursor"},
+		{"排序方式与游标不一致", UserFilter{Sort: UserSortCreatedAt}, next},
+	}
+	for _, tt := range invalid {
+		if _, _, err := service.ListUsersAfter(ctx, tt.filter, tt.cursor, 2); !errors.Is(err, errs.ErrValidation) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+// TestNormalizePage 测试分页参数规范化
+func TestNormalizePage(t *testing.T) {
+	tests := []struct {
+		page, pageSize         int
+		wantPage, wantPageSize int
+	}{
+		{0, 0, 1, DefaultPageSize},
+		{-1, 10, 1, 10},
+		{3, 50, 3, 50},
+		{2, MaxPageSize + 1, 2, MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		page, pageSize := NormalizePage(tt.page, tt.pageSize)
+		if page != tt.wantPage || pageSize != tt.wantPageSize {
+			t.Errorf("NormalizePage(%d, %d) = (%d, %d), 期望 (%d, %d)",
+				tt.page, tt.pageSize, page, pageSize, tt.wantPage, tt.wantPageSize)
+		}
+	}
+}
+
+// TestUserFeed 测试用户变更广播
+func TestUserFeed(t *testing.T) {
+	feed := NewUserFeed(1)
+	fast, cancelFast := feed.Subscribe()
+	slow, cancelSlow := feed.Subscribe()
+	defer cancelSlow()
+
+	feed.Publish(UserChange{Op: UserCreated, ID: 1})
+	if got := <-fast; got.Op != UserCreated || got.ID != 1 {
+		t.Errorf("收到 %+v", got)
+	}
+
+	// slow 的缓冲区已满，再次发布时被取消订阅
+	feed.Publish(UserChange{Op: UserUpdated, ID: 1})
+	if feed.Subscribers() != 1 {
+		t.Errorf("订阅者数量 = %d, 期望 1", feed.Subscribers())
+	}
+	<-slow
+	if _, ok := <-slow; ok {
+		t.Error("消费过慢的订阅者通道应被关闭")
+	}
+	if got := <-fast; got.Op != UserUpdated {
+		t.Errorf("收到 %+v", got)
+	}
+
+	cancelFast()
+	cancelFast()
+	if feed.Subscribers() != 0 {
+		t.Errorf("取消后订阅者数量 = %d", feed.Subscribers())
+	}
+}
+
+// TestUserFeedClose 测试关闭广播
+func TestUserFeedClose(t *testing.T) {
+	feed := NewUserFeed(1)
+	ch, cancel := feed.Subscribe()
+	defer cancel()
+
+	feed.Close()
+	if _, ok := <-ch; ok || !feed.Closed() {
+		t.Error("关闭后订阅者通道应被关闭")
+	}
+	if late, _ := feed.Subscribe(); late != nil {
+		if _, ok := <-late; ok {
+			t.Error("关闭后订阅应立即得到已关闭的通道")
+		}
+	}
+	feed.Publish(UserChange{Op: UserCreated, ID: 1})
 }
@@ -0,0 +1,130 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+)
+
+// ApplyFunc 配置项变更回调
+// value 为 nil 表示配置项被删除，应恢复为配置文件中的值
+type ApplyFunc func(value []byte) error
+
+// Reconciler 运行时配置同步器
+// 周期性读取配置项，值发生变化时调用对应的回调，使各组件与数据库中的配置保持一致
+type Reconciler struct {
+	interval time.Duration
+	handlers map[string]ApplyFunc
+	applied  map[string][]byte
+	// reapply 请求立即重新应用全部配置项
+	reapply chan struct{}
+}
+
+// NewReconciler 创建配置同步器
+// 参数:
+//
+//	interval: 同步间隔
+//
+// 返回:
+//
+//	*Reconciler: 配置同步器
+func NewReconciler(interval time.Duration) *Reconciler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Reconciler{
+		interval: interval,
+		handlers: make(map[string]ApplyFunc),
+		applied:  make(map[string][]byte),
+		reapply:  make(chan struct{}, 1),
+	}
+}
+
+// Watch 注册配置项变更回调
+// 参数:
+//
+//	key: 配置项
+//	fn: 变更回调
+func (r *Reconciler) Watch(key string, fn ApplyFunc) {
+	r.handlers[key] = fn
+}
+
+// Run 启动同步循环，直到 ctx 取消
+// 参数:
+//
+//	ctx: 上下文
+func (r *Reconciler) Run(ctx context.Context) {
+	r.reconcile(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.reconcile(ctx)
+		case <-r.reapply:
+			r.applied = make(map[string][]byte)
+			r.reconcileAll(ctx)
+		}
+	}
+}
+
+// Reapply 请求立即重新应用全部配置项（包括未设置的，回调收到 nil）
+// 配置文件热加载后调用，使回调基于新的文件配置重新计算，数据库中的配置项仍然优先
+func (r *Reconciler) Reapply() {
+	select {
+	case r.reapply <- struct{}{}:
+	default:
+	}
+}
+
+// reconcileAll 无条件调用全部回调
+func (r *Reconciler) reconcileAll(ctx context.Context) {
+	for key, fn := range r.handlers {
+		value, err := Get(ctx, key)
+		if err != nil {
+			logger.Error("读取运行时配置失败", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if err := fn(value); err != nil {
+			logger.Error("应用运行时配置失败", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		r.applied[key] = value
+	}
+}
+
+// reconcile 执行一次同步
+func (r *Reconciler) reconcile(ctx context.Context) {
+	for key, fn := range r.handlers {
+		value, err := Get(ctx, key)
+		if err != nil {
+			logger.Error("读取运行时配置失败", zap.String("key", key), zap.Error(err))
+			continue
+		}
+
+		previous, seen := r.applied[key]
+		if seen && bytes.Equal(previous, value) {
+			continue
+		}
+		// 首次同步且未设置时无需处理，保持配置文件中的值
+		if !seen && value == nil {
+			r.applied[key] = nil
+			continue
+		}
+
+		if err := fn(value); err != nil {
+			logger.Error("应用运行时配置失败", zap.String("key", key), zap.Error(err))
+			continue
+		}
+
+		r.applied[key] = value
+		logger.Info("运行时配置已生效", zap.String("key", key))
+	}
+}
@@ -0,0 +1,262 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/audit"
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 可在运行时调整的配置项
+const (
+	// KeyCORSAllowOrigins 允许的跨域来源，值为 []string
+	KeyCORSAllowOrigins = "cors.allow_origins"
+	// KeyRateLimit 限流参数，值为 RateLimitValue
+	KeyRateLimit = "rate_limit"
+	// KeyQueues 额外的队列，值为 []QueueValue
+	KeyQueues = "queues"
+)
+
+// RateLimitValue 限流参数
+type RateLimitValue struct {
+	RequestsPerSecond int `json:"requests_per_second"`
+	Burst             int `json:"burst"`
+}
+
+// QueueValue 额外的队列
+type QueueValue struct {
+	Name           string `json:"name"`
+	RoutingKey     string `json:"routing_key"`
+	Durable        bool   `json:"durable"`
+	ProcessTimeout int    `json:"process_timeout"`
+}
+
+// validators 各配置项的值校验
+var validators = map[string]func(raw []byte) error{
+	KeyCORSAllowOrigins: func(raw []byte) error {
+		var v []string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		for _, origin := range v {
+			if !config.ValidCORSOrigin(origin) {
+				return fmt.Errorf("来源 %q 格式不正确，应为 *、https://app.example.com 或 https://*.example.com", origin)
+			}
+		}
+		return nil
+	},
+	KeyRateLimit: func(raw []byte) error {
+		var v RateLimitValue
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if v.RequestsPerSecond <= 0 || v.Burst <= 0 {
+			return fmt.Errorf("requests_per_second 和 burst 必须大于 0")
+		}
+		return nil
+	},
+	KeyQueues: func(raw []byte) error {
+		var v []QueueValue
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		for _, q := range v {
+			if q.Name == "" || q.RoutingKey == "" {
+				return fmt.Errorf("队列 name 和 routing_key 不能为空")
+			}
+		}
+		return nil
+	},
+}
+
+// ErrUnknownKey 未知的配置项
+var ErrUnknownKey = errors.New("未知的配置项")
+
+// Setting 运行时配置项
+type Setting struct {
+	Key       string    `gorm:"primaryKey;type:varchar(100)" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"`
+	UpdatedBy string    `gorm:"type:varchar(100)" json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Setting) TableName() string {
+	return "runtime_settings"
+}
+
+// cacheTTL 配置项缓存时间
+var cacheTTL = time.Minute
+
+// Init 设置配置项缓存时间
+// 参数:
+//
+//	cfg: 运行时配置选项
+func Init(cfg config.RuntimeSettingsConfig) {
+	if ttl := cfg.GetCacheTTL(); ttl > 0 {
+		cacheTTL = ttl
+	}
+}
+
+// Keys 返回所有可调整的配置项
+func Keys() []string {
+	keys := make([]string, 0, len(validators))
+	for k := range validators {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cacheKey 配置项缓存键名
+func cacheKey(key string) string {
+	return "settings:" + key
+}
+
+// Get 获取配置项原始值
+// 先读 Redis 缓存，未命中时读数据库并回填缓存
+// 参数:
+//
+//	ctx: 上下文
+//	key: 配置项
+//
+// 返回:
+//
+//	[]byte: JSON 值，未设置时为 nil
+//	error: 错误信息
+func Get(ctx context.Context, key string) ([]byte, error) {
+	if _, ok := validators[key]; !ok {
+		return nil, ErrUnknownKey
+	}
+
+	// Redis 降级期间按 redis.degradation.cache 直接读数据库或返回错误
+	useCache, err := cache.CacheReadable()
+	if err != nil {
+		return nil, err
+	}
+	if useCache {
+		cached, err := cache.Get(ctx, cacheKey(key))
+		if err == nil {
+			if cached == "" {
+				return nil, nil
+			}
+			return []byte(cached), nil
+		}
+		if !errors.Is(err, redis.Nil) {
+			logger.Warn("读取配置缓存失败", zap.String("key", key), zap.Error(err))
+		}
+	}
+
+	var setting Setting
+	value := ""
+	err = database.DB.WithContext(ctx).First(&setting, map[string]interface{}{"key": key}).Error
+	switch {
+	case err == nil:
+		value = setting.Value
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, fmt.Errorf("查询配置项失败: %w", err)
+	}
+
+	// 未设置的配置项也缓存空值，避免反复查询数据库
+	if useCache {
+		if err := cache.Set(ctx, cacheKey(key), value, cacheTTL); err != nil {
+			logger.Warn("写入配置缓存失败", zap.String("key", key), zap.Error(err))
+		}
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+	return []byte(value), nil
+}
+
+// Set 保存配置项
+// 参数:
+//
+//	ctx: 上下文
+//	key: 配置项
+//	value: JSON 值
+//	actor: 操作人
+//
+// 返回:
+//
+//	error: 校验失败或保存失败时返回错误
+func Set(ctx context.Context, key string, value []byte, actor string) error {
+	validate, ok := validators[key]
+	if !ok {
+		return ErrUnknownKey
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("配置项 %s 的值无效: %w", key, err)
+	}
+
+	setting := Setting{Key: key, Value: string(value), UpdatedBy: actor}
+	if err := database.DB.WithContext(ctx).Save(&setting).Error; err != nil {
+		return fmt.Errorf("保存配置项失败: %w", err)
+	}
+
+	if err := cache.Delete(ctx, cacheKey(key)); err != nil {
+		logger.Warn("清除配置缓存失败", zap.String("key", key), zap.Error(err))
+	}
+
+	logger.Info("运行时配置已更新", zap.String("key", key), zap.String("操作人", actor))
+	_ = audit.Record(ctx, actor, "settings.update", "settings/"+key, json.RawMessage(value))
+	return nil
+}
+
+// Delete 删除配置项，恢复为配置文件中的值
+// 参数:
+//
+//	ctx: 上下文
+//	key: 配置项
+//	actor: 操作人
+//
+// 返回:
+//
+//	error: 错误信息
+func Delete(ctx context.Context, key string, actor string) error {
+	if _, ok := validators[key]; !ok {
+		return ErrUnknownKey
+	}
+
+	if err := database.DB.WithContext(ctx).Delete(&Setting{Key: key}).Error; err != nil {
+		return fmt.Errorf("删除配置项失败: %w", err)
+	}
+	if err := cache.Delete(ctx, cacheKey(key)); err != nil {
+		logger.Warn("清除配置缓存失败", zap.String("key", key), zap.Error(err))
+	}
+
+	logger.Info("运行时配置已删除", zap.String("key", key), zap.String("操作人", actor))
+	_ = audit.Record(ctx, actor, "settings.delete", "settings/"+key, nil)
+	return nil
+}
+
+// List 列出所有已设置的配置项
+// 参数:
+//
+//	ctx: 上下文
+//
+// 返回:
+//
+//	[]Setting: 配置项列表
+//	error: 错误信息
+func List(ctx context.Context) ([]Setting, error) {
+	var list []Setting
+	// key 在 MySQL 中是保留字，按列排序由 gorm 按方言为列名加引号
+	if err := database.DB.WithContext(ctx).Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("查询配置项失败: %w", err)
+	}
+	return list, nil
+}
@@ -0,0 +1,337 @@
+package slo
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// SLI 类型
+const (
+	// SLIAvailability 可用性：非服务端错误的请求比例
+	SLIAvailability = "availability"
+	// SLILatency 延迟：成功且低于延迟阈值的请求比例
+	SLILatency = "latency"
+)
+
+// BurnWindows 计算错误预算消耗速率的时间窗口（多窗口告警常用组合）
+var BurnWindows = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+// bucketSize 统计桶粒度
+const bucketSize = time.Minute
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "microservice",
+		Name:      "slo_requests_total",
+		Help:      "SLO 统计的请求数（result: good、error 服务端错误、slow 超过延迟阈值）",
+	}, []string{"slo", "result"})
+
+	burnRateDesc = prometheus.NewDesc(
+		"microservice_slo_burn_rate",
+		"错误预算消耗速率（1 表示恰好在统计周期末耗尽预算）",
+		[]string{"slo", "sli", "window"}, nil,
+	)
+	budgetRemainingDesc = prometheus.NewDesc(
+		"microservice_slo_error_budget_remaining",
+		"统计周期内剩余的错误预算比例（小于 0 表示已超支）",
+		[]string{"slo", "sli"}, nil,
+	)
+	objectiveDesc = prometheus.NewDesc(
+		"microservice_slo_objective",
+		"配置的 SLO 目标",
+		[]string{"slo", "sli"}, nil,
+	)
+)
+
+func init() {
+	metrics.Register(requestsTotal)
+}
+
+// bucket 一分钟内的请求计数
+type bucket struct {
+	minute int64
+	total  int64
+	errors int64
+	slow   int64
+}
+
+// objective 单个目标的滑动窗口统计
+type objective struct {
+	cfg       config.SLOObjectiveConfig
+	threshold time.Duration
+
+	mu      sync.Mutex
+	buckets []bucket
+}
+
+// record 记录一次请求
+func (o *objective) record(now time.Time, serverError bool, d time.Duration) {
+	result := "good"
+	switch {
+	case serverError:
+		result = "error"
+	case o.threshold > 0 && d > o.threshold:
+		result = "slow"
+	}
+	requestsTotal.WithLabelValues(o.cfg.Name, result).Inc()
+
+	minute := now.Unix() / int64(bucketSize/time.Second)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	b := &o.buckets[minute%int64(len(o.buckets))]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	switch result {
+	case "error":
+		b.errors++
+	case "slow":
+		b.slow++
+	}
+}
+
+// sum 统计最近 window 内的请求
+func (o *objective) sum(now time.Time, window time.Duration) bucket {
+	current := now.Unix() / int64(bucketSize/time.Second)
+	oldest := current - int64(window/bucketSize) + 1
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var s bucket
+	for _, b := range o.buckets {
+		if b.minute >= oldest && b.minute <= current {
+			s.total += b.total
+			s.errors += b.errors
+			s.slow += b.slow
+		}
+	}
+	return s
+}
+
+// Tracker 按配置的目标统计 SLI 并计算错误预算
+type Tracker struct {
+	window     time.Duration
+	objectives []*objective
+	// byTarget 按 "类型|目标" 索引，同一路由可以配置多个目标
+	byTarget map[string][]*objective
+	now      func() time.Time
+}
+
+// DefaultTracker 全局 SLO 统计实例
+var DefaultTracker *Tracker
+
+// New 创建 SLO 统计
+// 参数:
+//
+//	cfg: SLO 配置
+//
+// 返回:
+//
+//	*Tracker: SLO 统计
+//	error: 配置错误
+func New(cfg config.SLOConfig) (*Tracker, error) {
+	window := cfg.GetWindow()
+	if last := BurnWindows[len(BurnWindows)-1].Duration; window < last {
+		return nil, fmt.Errorf("SLO 统计周期不能小于 %s", last)
+	}
+
+	t := &Tracker{
+		window:   window,
+		byTarget: make(map[string][]*objective),
+		now:      time.Now,
+	}
+	names := make(map[string]bool)
+	for _, oc := range cfg.Objectives {
+		if oc.Name == "" || names[oc.Name] {
+			return nil, fmt.Errorf("SLO 名称为空或重复: %q", oc.Name)
+		}
+		names[oc.Name] = true
+		if oc.Kind != metrics.KindHTTP && oc.Kind != metrics.KindGRPC {
+			return nil, fmt.Errorf("SLO %s 的类型必须为 http 或 grpc", oc.Name)
+		}
+		if !validTarget(oc.Availability) || !validTarget(oc.LatencyTarget) {
+			return nil, fmt.Errorf("SLO %s 的目标必须在 0 到 1 之间", oc.Name)
+		}
+		if oc.LatencyTarget > 0 && oc.LatencyThreshold <= 0 {
+			return nil, fmt.Errorf("SLO %s 配置了延迟目标但未配置延迟阈值", oc.Name)
+		}
+
+		o := &objective{
+			cfg:       oc,
+			threshold: oc.GetLatencyThreshold(),
+			buckets:   make([]bucket, window/bucketSize),
+		}
+		t.objectives = append(t.objectives, o)
+		key := oc.Kind + "|" + oc.Target
+		t.byTarget[key] = append(t.byTarget[key], o)
+	}
+	return t, nil
+}
+
+// validTarget 目标比例是否合法（0 表示不统计）
+func validTarget(v float64) bool {
+	return v >= 0 && v < 1
+}
+
+// Init 初始化全局 SLO 统计，并接入请求指标与 Prometheus 注册表
+// 参数:
+//
+//	cfg: SLO 配置
+//
+// 返回:
+//
+//	error: 配置错误
+func Init(cfg config.SLOConfig) error {
+	t, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	DefaultTracker = t
+	if len(t.objectives) == 0 {
+		return nil
+	}
+
+	metrics.AddRequestObserver(t.Observe)
+	metrics.Register(t)
+
+	logger.Info("SLO 统计已启用",
+		zap.Int("目标数", len(t.objectives)),
+		zap.Duration("统计周期", t.window),
+	)
+	return nil
+}
+
+// Observe 记录一次已完成的请求（metrics.AddRequestObserver 回调）
+// 参数:
+//
+//	r: 请求信息
+func (t *Tracker) Observe(r metrics.Request) {
+	objectives := t.byTarget[r.Kind+"|"+r.Target]
+	if len(objectives) == 0 {
+		return
+	}
+	now := t.now()
+	for _, o := range objectives {
+		o.record(now, r.ServerError, r.Duration)
+	}
+}
+
+// SLIStatus 单个 SLI 的预算状态
+type SLIStatus struct {
+	SLI string `json:"sli"`
+	// Objective 目标比例
+	Objective float64 `json:"objective"`
+	// Current 统计周期内的实际达标比例，无请求时为 1
+	Current float64 `json:"current"`
+	// BudgetRemaining 剩余错误预算比例，小于 0 表示已超支
+	BudgetRemaining float64 `json:"budget_remaining"`
+	// BurnRates 各窗口的预算消耗速率
+	BurnRates map[string]float64 `json:"burn_rates"`
+}
+
+// Status 单个目标的状态
+type Status struct {
+	Name   string      `json:"name"`
+	Kind   string      `json:"kind"`
+	Target string      `json:"target"`
+	Total  int64       `json:"total"`
+	SLIs   []SLIStatus `json:"slis"`
+}
+
+// Status 汇总所有目标的当前状态
+// 返回:
+//
+//	[]Status: 各目标状态
+func (t *Tracker) Status() []Status {
+	now := t.now()
+	result := make([]Status, 0, len(t.objectives))
+	for _, o := range t.objectives {
+		total := o.sum(now, t.window)
+		status := Status{
+			Name:   o.cfg.Name,
+			Kind:   o.cfg.Kind,
+			Target: o.cfg.Target,
+			Total:  total.total,
+		}
+		for _, sli := range o.slis() {
+			s := SLIStatus{
+				SLI:             sli.name,
+				Objective:       sli.target,
+				Current:         1 - badRatio(total, sli.bad),
+				BudgetRemaining: 1 - badRatio(total, sli.bad)/(1-sli.target),
+				BurnRates:       make(map[string]float64, len(BurnWindows)),
+			}
+			for _, w := range BurnWindows {
+				s.BurnRates[w.Name] = badRatio(o.sum(now, w.Duration), sli.bad) / (1 - sli.target)
+			}
+			status.SLIs = append(status.SLIs, s)
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// sliDef 目标上配置的 SLI
+type sliDef struct {
+	name   string
+	target float64
+	bad    func(b bucket) int64
+}
+
+// slis 返回目标上配置的 SLI
+// 延迟 SLI 把服务端错误也计为不达标（失败的请求不能算作“足够快”）
+func (o *objective) slis() []sliDef {
+	var defs []sliDef
+	if o.cfg.Availability > 0 {
+		defs = append(defs, sliDef{SLIAvailability, o.cfg.Availability, func(b bucket) int64 { return b.errors }})
+	}
+	if o.cfg.LatencyTarget > 0 {
+		defs = append(defs, sliDef{SLILatency, o.cfg.LatencyTarget, func(b bucket) int64 { return b.errors + b.slow }})
+	}
+	return defs
+}
+
+// badRatio 不达标请求比例，无请求时为 0
+func badRatio(b bucket, bad func(bucket) int64) float64 {
+	if b.total == 0 {
+		return 0
+	}
+	return float64(bad(b)) / float64(b.total)
+}
+
+// Describe 实现 prometheus.Collector
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- burnRateDesc
+	ch <- budgetRemainingDesc
+	ch <- objectiveDesc
+}
+
+// Collect 实现 prometheus.Collector，采集时按当前窗口计算
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range t.Status() {
+		for _, sli := range s.SLIs {
+			ch <- prometheus.MustNewConstMetric(objectiveDesc, prometheus.GaugeValue, sli.Objective, s.Name, sli.SLI)
+			ch <- prometheus.MustNewConstMetric(budgetRemainingDesc, prometheus.GaugeValue, sli.BudgetRemaining, s.Name, sli.SLI)
+			for _, w := range BurnWindows {
+				ch <- prometheus.MustNewConstMetric(burnRateDesc, prometheus.GaugeValue, sli.BurnRates[w.Name], s.Name, sli.SLI, w.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package slo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/metrics"
+)
+
+func newTestTracker(t *testing.T, now *time.Time) *Tracker {
+	t.Helper()
+	tracker, err := New(config.SLOConfig{
+		Window: 24,
+		Objectives: []config.SLOObjectiveConfig{{
+			Name:             "get_user",
+			Kind:             metrics.KindHTTP,
+			Target:           "GET /api/v1/users/:id",
+			Availability:     0.99,
+			LatencyThreshold: 100,
+			LatencyTarget:    0.9,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("创建 SLO 统计失败: %v", err)
+	}
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestTrackerStatus(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(t, &now)
+
+	observe := func(n int, serverError bool, d time.Duration) {
+		for i := 0; i < n; i++ {
+			tracker.Observe(metrics.Request{
+				Kind: metrics.KindHTTP, Target: "GET /api/v1/users/:id",
+				ServerError: serverError, Duration: d,
+			})
+		}
+	}
+
+	// 2 小时前：100 个正常请求
+	now = now.Add(-2 * time.Hour)
+	observe(100, false, 10*time.Millisecond)
+
+	// 最近 5 分钟内：100 个请求，其中 2 个服务端错误、8 个超过延迟阈值
+	now = now.Add(2 * time.Hour)
+	observe(90, false, 10*time.Millisecond)
+	observe(8, false, 200*time.Millisecond)
+	observe(2, true, 10*time.Millisecond)
+
+	// 其他路由不计入
+	tracker.Observe(metrics.Request{Kind: metrics.KindHTTP, Target: "GET /health", ServerError: true})
+
+	status := tracker.Status()
+	if len(status) != 1 || status[0].Total != 200 {
+		t.Fatalf("状态 = %+v, 期望 1 个目标共 200 个请求", status)
+	}
+
+	availability, latency := status[0].SLIs[0], status[0].SLIs[1]
+	assertClose(t, "可用性达标比例", availability.Current, 0.99)
+	assertClose(t, "可用性剩余预算", availability.BudgetRemaining, 0)
+	assertClose(t, "可用性 5m 消耗速率", availability.BurnRates["5m"], 2)
+	assertClose(t, "可用性 6h 消耗速率", availability.BurnRates["6h"], 1)
+
+	assertClose(t, "延迟达标比例", latency.Current, 0.95)
+	assertClose(t, "延迟剩余预算", latency.BudgetRemaining, 0.5)
+	assertClose(t, "延迟 5m 消耗速率", latency.BurnRates["5m"], 1)
+
+	// 超过统计周期后旧数据不再计入
+	now = now.Add(25 * time.Hour)
+	status = tracker.Status()
+	if status[0].Total != 0 || status[0].SLIs[0].BudgetRemaining != 1 {
+		t.Errorf("超出统计周期后应清零, 得到 %+v", status[0])
+	}
+}
+
+func TestNewValidatesObjectives(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.SLOConfig
+	}{
+		{"统计周期过短", config.SLOConfig{Window: 1}},
+		{"类型错误", config.SLOConfig{Objectives: []config.SLOObjectiveConfig{{Name: "a", Kind: "tcp"}}}},
+		{"目标超出范围", config.SLOConfig{Objectives: []config.SLOObjectiveConfig{{Name: "a", Kind: "http", Availability: 1}}}},
+		{"缺少延迟阈值", config.SLOConfig{Objectives: []config.SLOObjectiveConfig{{Name: "a", Kind: "http", LatencyTarget: 0.9}}}},
+		{"名称重复", config.SLOConfig{Objectives: []config.SLOObjectiveConfig{
+			{Name: "a", Kind: "http"}, {Name: "a", Kind: "grpc"},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("期望返回配置错误")
+			}
+		})
+	}
+}
+
+func assertClose(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("%s = %v, 期望 %v", name, got, want)
+	}
+}
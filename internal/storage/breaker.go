@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"io"
+	"time"
+
+	"github.com/zhang/microservice/internal/resilience"
+)
+
+// breakerStore 为对象存储的请求加上熔断，对象存储持续不可用时直接返回“依赖不可用”错误
+// ObjectURL、GetPresignedURL 只在本地拼接或签名，不经过熔断器
+type breakerStore struct {
+	ObjectStore
+	breaker *resilience.Breaker
+}
+
+// withBreaker 为对象存储加上熔断
+// 参数:
+//
+//	store: 对象存储
+//	breaker: 熔断器，为 nil 时原样返回
+//
+// 返回:
+//
+//	ObjectStore: 对象存储
+func withBreaker(store ObjectStore, breaker *resilience.Breaker) ObjectStore {
+	if breaker == nil {
+		return store
+	}
+	return &breakerStore{ObjectStore: store, breaker: breaker}
+}
+
+// Upload 上传文件
+func (s *breakerStore) Upload(filename string, content io.Reader, contentType string) (url, key string, err error) {
+	err = s.breaker.Execute(func() error {
+		url, key, err = s.ObjectStore.Upload(filename, content, contentType)
+		return err
+	})
+	return url, key, err
+}
+
+// Download 下载对象
+func (s *breakerStore) Download(key string) (body io.ReadCloser, err error) {
+	err = s.breaker.Execute(func() error {
+		body, err = s.ObjectStore.Download(key)
+		return err
+	})
+	return body, err
+}
+
+// Delete 删除对象
+func (s *breakerStore) Delete(key string) error {
+	return s.breaker.Execute(func() error {
+		return s.ObjectStore.Delete(key)
+	})
+}
+
+// PutObject 按指定 Key 写入对象
+func (s *breakerStore) PutObject(key string, body []byte, contentType string) error {
+	return s.breaker.Execute(func() error {
+		return s.ObjectStore.PutObject(key, body, contentType)
+	})
+}
+
+// PutObjectStream 按指定 Key 流式写入对象
+func (s *breakerStore) PutObjectStream(key string, body io.ReadSeeker, size int64, contentType string) error {
+	return s.breaker.Execute(func() error {
+		return s.ObjectStore.PutObjectStream(key, body, size, contentType)
+	})
+}
+
+// PutObjectLocked 写入对象并设置保留期
+func (s *breakerStore) PutObjectLocked(key string, body []byte, contentType string, retainUntil time.Time) error {
+	return s.breaker.Execute(func() error {
+		return s.ObjectStore.PutObjectLocked(key, body, contentType, retainUntil)
+	})
+}
+
+// DeleteExpired 删除前缀下早于指定时间的对象
+func (s *breakerStore) DeleteExpired(prefix string, before time.Time) (deleted int, err error) {
+	err = s.breaker.Execute(func() error {
+		deleted, err = s.ObjectStore.DeleteExpired(prefix, before)
+		return err
+	})
+	return deleted, err
+}
+
+// ListFiles 列出前缀下的对象 Key
+func (s *breakerStore) ListFiles(prefix string) (keys []string, err error) {
+	err = s.breaker.Execute(func() error {
+		keys, err = s.ObjectStore.ListFiles(prefix)
+		return err
+	})
+	return keys, err
+}
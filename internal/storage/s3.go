@@ -1,18 +1,26 @@
+//go:build !noaws
+
 package storage
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"path/filepath"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/zhang/microservice/internal/config"
 	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/resilience"
+	"github.com/zhang/microservice/internal/retry"
 	"go.uber.org/zap"
 )
 
@@ -24,9 +32,6 @@ type S3Client struct {
 	expire time.Duration
 }
 
-// S3Storage 全局 S3 存储实例
-var S3Storage *S3Client
-
 // Init 初始化 S3 客户端
 // 参数:
 //
@@ -36,9 +41,15 @@ var S3Storage *S3Client
 //
 //	error: 错误信息
 func Init(cfg config.AWSConfig) error {
-	// 创建 AWS 会话
+	// 创建 AWS 会话；请求的暂时性失败（连接错误、限流、5xx）由 SDK 按 retry 配置的次数和间隔重试
+	policy := retry.Transient("S3", nil)
 	sess, err := session.NewSession(&aws.Config{
 		Region: aws.String(cfg.Region),
+		Retryer: client.DefaultRetryer{
+			NumMaxRetries: policy.MaxAttempts - 1,
+			MinRetryDelay: policy.Initial,
+			MaxRetryDelay: policy.Max,
+		},
 		Credentials: credentials.NewStaticCredentials(
 			cfg.AccessKey,
 			cfg.SecretKey,
@@ -50,12 +61,14 @@ func Init(cfg config.AWSConfig) error {
 	}
 
 	// 创建 S3 客户端
-	S3Storage = &S3Client{
+	client := &S3Client{
 		client: s3.New(sess),
 		bucket: cfg.S3.Bucket,
 		prefix: cfg.S3.UploadPrefix,
 		expire: cfg.S3.GetPresignedExpire(),
 	}
+	// S3 持续不可用时熔断
+	S3Storage = withBreaker(client, resilience.For("s3", isS3Unavailable))
 
 	logger.Info("S3 客户端初始化成功",
 		zap.String("region", cfg.Region),
@@ -101,7 +114,7 @@ func (s *S3Client) Upload(filename string, content io.Reader, contentType string
 	}
 
 	// 生成文件 URL
-	url := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.bucket, key)
+	url := s.ObjectURL(key)
 
 	logger.Info("文件上传成功",
 		zap.String("key", key),
@@ -154,6 +167,123 @@ func (s *S3Client) Delete(key string) error {
 	return nil
 }
 
+// PutObject 以指定 key 上传对象
+// 参数:
+//
+//	key: 对象 Key
+//	body: 对象内容
+//	contentType: 内容类型
+//
+// 返回:
+//
+//	error: 错误信息
+func (s *S3Client) PutObject(key string, body []byte, contentType string) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(body))),
+	})
+	if err != nil {
+		return fmt.Errorf("上传对象到 S3 失败: %w", err)
+	}
+	return nil
+}
+
+// PutObjectStream 以指定 key 流式上传对象
+// 参数:
+//
+//	key: 对象 Key
+//	body: 对象内容（SDK 签名和重试需要可回退读取）
+//	size: 对象大小
+//	contentType: 内容类型
+//
+// 返回:
+//
+//	error: 错误信息
+func (s *S3Client) PutObjectStream(key string, body io.ReadSeeker, size int64, contentType string) error {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          body,
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return fmt.Errorf("上传对象到 S3 失败: %w", err)
+	}
+	return nil
+}
+
+// PutObjectLocked 以指定 key 上传对象，并设置 Object Lock 合规保留期
+// 保留期内对象无法被删除或覆盖（包括 root 账号），桶需开启 Object Lock
+// 参数:
+//
+//	key: 对象 Key
+//	body: 对象内容
+//	contentType: 内容类型
+//	retainUntil: 保留截止时间，零值表示不设置保留期
+//
+// 返回:
+//
+//	error: 错误信息
+func (s *S3Client) PutObjectLocked(key string, body []byte, contentType string, retainUntil time.Time) error {
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(body),
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(int64(len(body))),
+	}
+	if !retainUntil.IsZero() {
+		input.ObjectLockMode = aws.String(s3.ObjectLockModeCompliance)
+		input.ObjectLockRetainUntilDate = aws.Time(retainUntil)
+	}
+
+	if _, err := s.client.PutObject(input); err != nil {
+		return fmt.Errorf("上传对象到 S3 失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired 删除前缀下早于指定时间的对象
+// 参数:
+//
+//	prefix: 对象前缀
+//	before: 截止时间，最后修改时间早于该时间的对象会被删除
+//
+// 返回:
+//
+//	int: 删除的对象数量
+//	error: 错误信息
+func (s *S3Client) DeleteExpired(prefix string, before time.Time) (int, error) {
+	deleted := 0
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	err := s.client.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range page.Contents {
+			if item.LastModified == nil || !item.LastModified.Before(before) {
+				continue
+			}
+			if err := s.Delete(*item.Key); err != nil {
+				logger.Error("删除过期对象失败", zap.String("key", *item.Key), zap.Error(err))
+				continue
+			}
+			deleted++
+		}
+		return true
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("列出 S3 文件失败: %w", err)
+	}
+
+	return deleted, nil
+}
+
 // GetPresignedURL 生成预签名 URL
 // 参数:
 //
@@ -177,6 +307,18 @@ func (s *S3Client) GetPresignedURL(key string) (string, error) {
 	return url, nil
 }
 
+// ObjectURL 返回对象的访问 URL（不签名，桶需允许公开读取才能直接访问）
+// 参数:
+//
+//	key: 对象 Key
+//
+// 返回:
+//
+//	string: 访问 URL
+func (s *S3Client) ObjectURL(key string) string {
+	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.bucket, key)
+}
+
 // ListFiles 列出文件
 // 参数:
 //
@@ -219,3 +361,20 @@ func (s *S3Client) generateKey(filename string) string {
 
 	return fmt.Sprintf("%s%s_%s%s", s.prefix, name, timestamp, ext)
 }
+
+// isS3Unavailable 是否为 S3 不可用类错误（计入熔断）：连接失败、超时和 5xx 响应
+// 对象不存在、权限不足等 4xx 响应不算
+func isS3Unavailable(err error) bool {
+	if resilience.IsConnectionError(err) {
+		return true
+	}
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode() >= 500
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return awsErr.Code() == request.ErrCodeRequestError || awsErr.Code() == request.ErrCodeResponseTimeout
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+//go:build noaws
+
+package storage
+
+import (
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/logger"
+)
+
+// Init 以 noaws 构建标签编译时不包含 S3 支持，S3Storage 保持为 nil
+// 依赖对象存储的功能（文件上传、超大消息转存、审计锚点）返回 ErrUnavailable
+// 参数:
+//
+//	cfg: AWS 配置（忽略）
+//
+// 返回:
+//
+//	error: 总是 nil
+func Init(cfg config.AWSConfig) error {
+	logger.Warn("当前构建未包含 S3 支持（noaws），对象存储相关功能不可用")
+	return nil
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrUnavailable 对象存储不可用（未初始化，或以 noaws 构建标签编译）
+var ErrUnavailable = errors.New("对象存储不可用")
+
+// ObjectStore 对象存储接口
+// 默认实现为 S3（s3.go）；以 noaws 构建标签编译时不包含 aws-sdk，S3Storage 保持为 nil
+type ObjectStore interface {
+	// Upload 上传文件，返回访问 URL 和对象 Key
+	Upload(filename string, content io.Reader, contentType string) (string, string, error)
+	// Download 下载对象
+	Download(key string) (io.ReadCloser, error)
+	// Delete 删除对象
+	Delete(key string) error
+	// PutObject 按指定 Key 写入对象
+	PutObject(key string, body []byte, contentType string) error
+	// PutObjectStream 按指定 Key 流式写入对象，用于不便整体读入内存的大文件
+	PutObjectStream(key string, body io.ReadSeeker, size int64, contentType string) error
+	// PutObjectLocked 写入对象并设置保留期（期间不可删除、覆盖）
+	PutObjectLocked(key string, body []byte, contentType string, retainUntil time.Time) error
+	// DeleteExpired 删除前缀下早于指定时间的对象，返回删除数量
+	DeleteExpired(prefix string, before time.Time) (int, error)
+	// ObjectURL 返回对象的访问 URL（不签名）
+	ObjectURL(key string) string
+	// GetPresignedURL 获取预签名下载 URL
+	GetPresignedURL(key string) (string, error)
+	// ListFiles 列出前缀下的对象 Key
+	ListFiles(prefix string) ([]string, error)
+}
+
+// S3Storage 全局对象存储实例
+var S3Storage ObjectStore
@@ -0,0 +1,52 @@
+package testutil
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 可控时钟
+// 用于让 JWT 过期、限流窗口等时间相关逻辑在测试中可复现
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock 创建固定在指定时间的时钟
+// 参数:
+//
+//	t: 初始时间
+//
+// 返回:
+//
+//	*Clock: 时钟实例
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// Now 返回当前时间
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance 将时钟向前拨动
+// 参数:
+//
+//	d: 时长
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set 将时钟设置为指定时间
+// 参数:
+//
+//	t: 时间
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,154 @@
+package testutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/events"
+	"github.com/zhang/microservice/internal/queue"
+)
+
+// UpdateFixturesEnv 设置为 1 时，缺少的事件样例由本次记录的消息生成
+const UpdateFixturesEnv = "UPDATE_EVENT_FIXTURES"
+
+// RecordedEvent 测试中发布的一条消息
+type RecordedEvent struct {
+	RoutingKey string
+	Body       []byte
+}
+
+// EventRecorder 记录测试期间发布的消息
+type EventRecorder struct {
+	mu     sync.Mutex
+	events []RecordedEvent
+}
+
+// RecordEvents 在测试期间用记录器替换全局消息代理，测试结束时还原，
+// 并按契约注册表和样例文件校验记录到的每条消息（见 CheckEventContracts）
+// 参数:
+//
+//	t: 测试实例
+//
+// 返回:
+//
+//	*EventRecorder: 事件记录器
+func RecordEvents(t testing.TB) *EventRecorder {
+	t.Helper()
+
+	recorder := &EventRecorder{}
+	previous := queue.MQClient
+	queue.MQClient = &recordingBroker{recorder: recorder}
+	t.Cleanup(func() {
+		queue.MQClient = previous
+		CheckEventContracts(t, recorder.Events())
+	})
+	return recorder
+}
+
+// Events 返回已记录的消息
+func (r *EventRecorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+// CheckEventContracts 校验消息是否符合契约：
+// 路由键必须有注册的契约，消息体必须符合契约，且与该版本的样例文件兼容（不删除字段、不修改类型）
+// 参数:
+//
+//	t: 测试实例
+//	recorded: 发布的消息
+func CheckEventContracts(t testing.TB, recorded []RecordedEvent) {
+	t.Helper()
+
+	for _, event := range recorded {
+		contract, ok := events.Lookup(event.RoutingKey)
+		if !ok {
+			t.Errorf("路由键 %s 没有注册事件契约（internal/events/contracts.go）", event.RoutingKey)
+			continue
+		}
+		if err := contract.Validate(event.Body); err != nil {
+			t.Errorf("事件不符合契约:\n%v\n消息体: %s", err, event.Body)
+			continue
+		}
+
+		path := EventFixturePath(contract.Name, contract.Version)
+		fixture, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) && os.Getenv(UpdateFixturesEnv) == "1" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Errorf("创建事件样例目录失败: %v", err)
+				continue
+			}
+			if err := os.WriteFile(path, event.Body, 0o644); err != nil {
+				t.Errorf("写入事件样例 %s 失败: %v", path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("读取事件样例失败（设置 %s=1 可由本次消息生成）: %v", UpdateFixturesEnv, err)
+			continue
+		}
+
+		previous, err := events.Shape(fixture)
+		if err != nil {
+			t.Errorf("解析事件样例 %s 失败: %v", path, err)
+			continue
+		}
+		current, _ := events.Shape(event.Body)
+		if err := events.Compatible(previous, current); err != nil {
+			t.Errorf("%s v%d 与样例 %s 不兼容，不兼容的变更需要注册新版本:\n%v",
+				contract.Name, contract.Version, path, err)
+		}
+	}
+}
+
+// EventFixturePath 返回事件样例文件路径：internal/events/testdata/<名称>/v<版本>.json
+// 参数:
+//
+//	name: 事件名称
+//	version: 契约版本
+//
+// 返回:
+//
+//	string: 文件路径
+func EventFixturePath(name string, version int) string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "events", "testdata", name, "v"+strconv.Itoa(version)+".json")
+}
+
+// recordingBroker 只记录发布的消息，不支持消费
+type recordingBroker struct {
+	recorder *EventRecorder
+}
+
+func (b *recordingBroker) Publish(routingKey string, body []byte) error {
+	b.recorder.mu.Lock()
+	defer b.recorder.mu.Unlock()
+	b.recorder.events = append(b.recorder.events, RecordedEvent{
+		RoutingKey: routingKey,
+		Body:       append([]byte(nil), body...),
+	})
+	return nil
+}
+
+func (b *recordingBroker) Consume(queueName string, handler func([]byte) error) error {
+	return errors.New("记录器不支持消费")
+}
+
+func (b *recordingBroker) ConsumeContext(queueName string, handler queue.ContextHandler) error {
+	return errors.New("记录器不支持消费")
+}
+
+func (b *recordingBroker) DeclareQueues(queues []config.QueueConfig) error {
+	return nil
+}
+
+func (b *recordingBroker) Close() error {
+	return nil
+}
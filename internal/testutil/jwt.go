@@ -0,0 +1,99 @@
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/zhang/microservice/internal/middleware"
+)
+
+// DefaultSecret 测试用 JWT 密钥
+const DefaultSecret = "test-secret-key"
+
+// TokenMinter 测试 token 签发器
+// 使用注入的密钥和时钟签发任意声明、任意有效期的 token
+type TokenMinter struct {
+	Secret []byte
+	Clock  *Clock
+}
+
+// UseJWT 在测试期间替换中间件的 JWT 配置，测试结束后自动还原
+// 参数:
+//
+//	t: 测试实例
+//	secret: 签名密钥
+//	clock: 时钟
+//
+// 返回:
+//
+//	*TokenMinter: 与中间件使用相同密钥和时钟的签发器
+func UseJWT(t testing.TB, secret string, clock *Clock) *TokenMinter {
+	t.Helper()
+
+	previous := middleware.GetJWTConfig()
+	middleware.SetJWTConfig(&middleware.JWTConfig{
+		Secret:     []byte(secret),
+		ExpireTime: time.Hour,
+		Now:        clock.Now,
+	})
+	t.Cleanup(func() {
+		middleware.SetJWTConfig(previous)
+	})
+
+	return &TokenMinter{Secret: []byte(secret), Clock: clock}
+}
+
+// Mint 签发 token
+// 参数:
+//
+//	claims: 自定义声明，未设置的时间字段按时钟和 ttl 填充
+//	ttl: 有效期（负数表示已过期）
+//
+// 返回:
+//
+//	string: JWT token
+//	error: 错误信息
+func (m *TokenMinter) Mint(claims middleware.Claims, ttl time.Duration) (string, error) {
+	now := m.Clock.Now()
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.NotBefore == nil {
+		claims.NotBefore = jwt.NewNumericDate(now)
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	}
+	if claims.Issuer == "" {
+		claims.Issuer = "microservice"
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.Secret)
+}
+
+// MustMint 签发 token，失败时终止测试
+// 参数:
+//
+//	t: 测试实例
+//	userID: 用户 ID
+//	role: 角色
+//	ttl: 有效期
+//
+// 返回:
+//
+//	string: JWT token
+func (m *TokenMinter) MustMint(t testing.TB, userID int64, role string, ttl time.Duration) string {
+	t.Helper()
+
+	token, err := m.Mint(middleware.Claims{UserID: userID, Username: "test-user", Role: role}, ttl)
+	if err != nil {
+		t.Fatalf("签发测试 token 失败: %v", err)
+	}
+	return token
+}
+
+// BearerHeader 生成 Authorization 头的值
+func BearerHeader(token string) string {
+	return "Bearer " + token
+}
@@ -0,0 +1,32 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/zhang/microservice/internal/cache"
+)
+
+// UseMiniredis 启动内存 Redis（miniredis）并替换 cache.RedisClient，测试结束时关闭并恢复
+// 参数:
+//
+//	t: 测试对象
+//
+// 返回:
+//
+//	*miniredis.Miniredis: 内存 Redis，可用于快进时间（FastForward）或直接检查数据
+func UseMiniredis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	prev := cache.RedisClient
+	cache.RedisClient = client
+	t.Cleanup(func() {
+		cache.RedisClient = prev
+		client.Close()
+	})
+	return mr
+}
@@ -0,0 +1,162 @@
+package timezone
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// 内嵌时区数据库，精简镜像（distroless、alpine）中没有 /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/zhang/microservice/internal/config"
+)
+
+// Header 指定响应时区的请求头，值为 IANA 时区名称（如 Asia/Shanghai）；响应中回显实际使用的时区
+const Header = "X-Timezone"
+
+// Default 未指定时区时使用的时区
+var Default = time.UTC
+
+// locations 已加载的时区，避免每个请求都解析时区文件
+var locations sync.Map
+
+// Init 按配置设置默认时区
+// 参数:
+//
+//	cfg: 时区配置
+//
+// 返回:
+//
+//	error: 时区名称无效时返回错误
+func Init(cfg config.TimezoneConfig) error {
+	loc, err := Load(cfg.GetDefault())
+	if err != nil {
+		return err
+	}
+	Default = loc
+	return nil
+}
+
+// Load 按 IANA 名称加载时区
+// 参数:
+//
+//	name: 时区名称，不接受空字符串和 Local（取决于部署机器）
+//
+// 返回:
+//
+//	*time.Location: 时区
+//	error: 名称无效时返回错误
+func Load(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("无效的时区 %q", name)
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %q", name)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Valid 判断时区名称是否可用于用户偏好
+// 参数:
+//
+//	name: 时区名称，空字符串表示未设置偏好
+//
+// 返回:
+//
+//	bool: 是否有效
+func Valid(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, err := Load(name)
+	return err == nil
+}
+
+// Resolve 按顺序返回第一个有效的时区
+// 参数:
+//
+//	names: 候选时区名称（如请求头、用户偏好），无效的名称被忽略
+//
+// 返回:
+//
+//	*time.Location: 时区，全部无效时为 Default
+func Resolve(names ...string) *time.Location {
+	for _, name := range names {
+		if loc, err := Load(name); err == nil {
+			return loc
+		}
+	}
+	return Default
+}
+
+// isTimeKey 判断 JSON 字段是否为时间戳（created_at、expires_at、timestamp 等）
+func isTimeKey(key string) bool {
+	return strings.HasSuffix(key, "_at") || key == "timestamp"
+}
+
+// LocalizeJSON 把 JSON 响应中的时间戳转换到指定时区
+// 只处理名称以 _at 结尾或为 timestamp 的字段，且值须为 RFC 3339 字符串；表示的时间点不变，只改变时区偏移
+// 参数:
+//
+//	body: JSON 响应体
+//	loc: 目标时区
+//
+// 返回:
+//
+//	[]byte: 转换后的响应体，没有需要转换的字段时原样返回
+//	error: 错误信息
+func LocalizeJSON(body []byte, loc *time.Location) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	// 保留整数精度，避免 int64 ID 经 float64 往返后失真
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("响应体包含多个 JSON 值")
+	}
+
+	if !localizeValue(v, loc) {
+		return body, nil
+	}
+	return json.Marshal(v)
+}
+
+// localizeValue 递归转换时间戳字段，返回是否有字段被修改
+func localizeValue(v interface{}, loc *time.Location) bool {
+	changed := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, field := range val {
+			if s, ok := field.(string); ok && isTimeKey(key) {
+				if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					if local := t.In(loc).Format(time.RFC3339Nano); local != s {
+						val[key] = local
+						changed = true
+					}
+				}
+				continue
+			}
+			if localizeValue(field, loc) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if localizeValue(item, loc) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
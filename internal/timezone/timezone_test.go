@@ -0,0 +1,56 @@
+package timezone
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{[]string{"Asia/Shanghai", "Europe/Berlin"}, "Asia/Shanghai"},
+		{[]string{"", "Europe/Berlin"}, "Europe/Berlin"},
+		{[]string{"Mars/Olympus", "Local"}, "UTC"},
+		{nil, "UTC"},
+	}
+	for _, tt := range tests {
+		if loc := Resolve(tt.names...); loc.String() != tt.expected {
+			t.Errorf("Resolve(%q) = %s, 期望 %s", tt.names, loc, tt.expected)
+		}
+	}
+
+	if !Valid("") || !Valid("America/New_York") || Valid("Local") || Valid("UTC+8") {
+		t.Error("Valid 结果错误")
+	}
+}
+
+func TestLocalizeJSON(t *testing.T) {
+	shanghai, err := Load("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := []byte(`{"items":[{"id":9007199254740993,"created_at":"2024-01-02T03:04:05Z","note":"2024-01-02T03:04:05Z"}],` +
+		`"timestamp":"2024-01-02T03:04:05.5Z","expires_at":"never"}`)
+	got, err := LocalizeJSON(body, shanghai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只转换时间字段，非时间字段和无法解析的值保持不变，大整数不丢失精度
+	expected := `{"expires_at":"never","items":[{"created_at":"2024-01-02T11:04:05+08:00","id":9007199254740993,"note":"2024-01-02T03:04:05Z"}],"timestamp":"2024-01-02T11:04:05.5+08:00"}`
+	if string(got) != expected {
+		t.Errorf("LocalizeJSON() = %s\n期望 %s", got, expected)
+	}
+
+	// 已是目标时区时原样返回
+	same := []byte(`{"b":1, "updated_at":"2024-01-02T03:04:05Z"}`)
+	if got, _ := LocalizeJSON(same, time.UTC); string(got) != string(same) {
+		t.Errorf("无需转换时应原样返回, 实际 %s", got)
+	}
+
+	if _, err := LocalizeJSON([]byte(`{"a":1}{"b":2}`), shanghai); err == nil {
+		t.Error("多个 JSON 值应返回错误")
+	}
+}
@@ -0,0 +1,169 @@
+package transcode
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/ctxkeys"
+	"github.com/zhang/microservice/internal/errs"
+	"github.com/zhang/microservice/internal/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// forwardHeaders 转发到 gRPC metadata 的请求头
+var forwardHeaders = []string{"authorization", "x-request-id"}
+
+// marshalOptions 响应 JSON 格式：字段名与 proto 一致（与 REST 接口相同的 snake_case），输出零值字段；
+// google.protobuf.Timestamp 等 Well-Known Types 按 protojson 规范输出（RFC 3339）
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// unmarshalOptions 请求 JSON 格式：同时接受 proto 字段名和 lowerCamelCase，忽略未知字段
+var unmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Transcoder 将 HTTP JSON 请求转为 gRPC 一元调用
+// 路由由 proto 服务描述符生成：POST {prefix}/{package.Service}/{Method}，请求体为输入消息的 JSON
+type Transcoder struct {
+	conn    grpc.ClientConnInterface
+	methods map[string]protoreflect.MethodDescriptor
+}
+
+// New 创建转码器
+// 参数:
+//
+//	conn: gRPC 连接
+//	services: 需要暴露的服务描述符
+//
+// 返回:
+//
+//	*Transcoder: 转码器
+func New(conn grpc.ClientConnInterface, services ...protoreflect.ServiceDescriptor) *Transcoder {
+	methods := make(map[string]protoreflect.MethodDescriptor)
+	for _, sd := range services {
+		for i := 0; i < sd.Methods().Len(); i++ {
+			md := sd.Methods().Get(i)
+			// 流式方法不支持转码
+			if md.IsStreamingClient() || md.IsStreamingServer() {
+				continue
+			}
+			methods[string(sd.FullName())+"/"+string(md.Name())] = md
+		}
+	}
+	return &Transcoder{conn: conn, methods: methods}
+}
+
+// Register 在路由组上注册转码路由
+// 参数:
+//
+//	r: 路由组
+func (t *Transcoder) Register(r *gin.RouterGroup) {
+	r.POST("/:service/:method", t.handle)
+}
+
+// Methods 返回可转码的方法列表（service/method）
+// 返回:
+//
+//	[]string: 方法列表
+func (t *Transcoder) Methods() []string {
+	list := make([]string, 0, len(t.methods))
+	for name := range t.methods {
+		list = append(list, name)
+	}
+	return list
+}
+
+// handle 处理转码请求
+func (t *Transcoder) handle(c *gin.Context) {
+	md, ok := t.methods[c.Param("service")+"/"+c.Param("method")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "未知的方法",
+			"code":  codes.Unimplemented.String(),
+		})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "读取请求失败",
+			"code":  codes.InvalidArgument.String(),
+		})
+		return
+	}
+
+	req := newMessage(md.Input())
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := unmarshalOptions.Unmarshal(body, req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误: " + err.Error(),
+				"code":  codes.InvalidArgument.String(),
+			})
+			return
+		}
+	}
+
+	resp := newMessage(md.Output())
+	fullMethod := "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+	if err := t.conn.Invoke(outgoingContext(c), fullMethod, req, resp); err != nil {
+		writeError(c, fullMethod, err)
+		return
+	}
+
+	data, err := marshalOptions.Marshal(resp)
+	if err != nil {
+		writeError(c, fullMethod, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+// newMessage 创建消息实例，优先使用已注册的生成类型
+func newMessage(desc protoreflect.MessageDescriptor) proto.Message {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName()); err == nil {
+		return mt.New().Interface()
+	}
+	return dynamicpb.NewMessage(desc)
+}
+
+// outgoingContext 将认证及请求 ID 请求头转为 gRPC metadata
+func outgoingContext(c *gin.Context) context.Context {
+	md := metadata.MD{}
+	for _, h := range forwardHeaders {
+		if v := c.GetHeader(h); v != "" {
+			md.Set(h, v)
+		}
+	}
+	if id := ctxkeys.RequestID(c); id != "" {
+		md.Set("x-request-id", id)
+	}
+	return metadata.NewOutgoingContext(c.Request.Context(), md)
+}
+
+// writeError 将 gRPC 错误映射为 application/problem+json 响应（见 errs.Problem）
+// 字段级错误（google.rpc.BadRequest）放在 fields 中，与 REST 接口的格式一致
+func writeError(c *gin.Context, method string, err error) {
+	st := errs.Status(err)
+	if errs.HTTPStatus(st.Code()) >= http.StatusInternalServerError {
+		logger.FromContext(c).Error("gRPC 调用失败",
+			zap.String("method", method),
+			zap.Error(err),
+		)
+	}
+
+	p := errs.NewProblem(st)
+	p.Instance = c.Request.URL.Path
+	p.RequestID = ctxkeys.RequestID(c)
+	errs.WriteProblem(c, p)
+}
@@ -0,0 +1,128 @@
+package transcode_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/testutil"
+	"github.com/zhang/microservice/internal/transcode"
+	"github.com/zhang/microservice/internal/validate"
+	pb "github.com/zhang/microservice/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeUserService 测试用用户服务
+type fakeUserService struct {
+	pb.UnimplementedUserServiceServer
+	authorization string
+}
+
+func (s *fakeUserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.authorization = strings.Join(md.Get("authorization"), "")
+	}
+	if req.Id != 42 {
+		return nil, status.Error(codes.NotFound, "用户不存在")
+	}
+	return &pb.GetUserResponse{User: &pb.User{Id: 42, Name: "测试用户", CreatedAt: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))}}, nil
+}
+
+func (s *fakeUserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.CreateUserResponse, error) {
+	if err := validate.User(validate.UserInput{Name: &req.Name, Email: &req.Email}); err != nil {
+		return nil, err
+	}
+	return &pb.CreateUserResponse{User: &pb.User{Id: 1, Name: req.Name, Email: req.Email}}, nil
+}
+
+func TestTranscoder(t *testing.T) {
+	svc := &fakeUserService{}
+	conn := testutil.NewGRPCConn(t, func(s *grpc.Server) {
+		pb.RegisterUserServiceServer(s, svc)
+	})
+
+	tr := transcode.New(conn, pb.File_proto_service_proto.Services().ByName("UserService"))
+	router := testutil.NewGinEngine(t, config.MiddlewareConfig{Chains: map[string][]string{
+		"global": {"recovery", "request_id"},
+	}}, func(r *gin.RouterGroup) {
+		tr.Register(r.Group("/rpc"))
+	})
+
+	call := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/rpc/microservice.UserService/"+method, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer token")
+		return testutil.Do(router, req)
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		w := call("GetUser", `{"id": "42"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望状态码 200, 实际 %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			User map[string]interface{} `json:"user"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if resp.User["name"] != "测试用户" || resp.User["created_at"] != "2024-01-02T03:04:05Z" {
+			t.Errorf("响应字段错误: %v", resp.User)
+		}
+		if _, ok := resp.User["phone"]; !ok {
+			t.Error("零值字段应输出")
+		}
+		if svc.authorization != "Bearer token" {
+			t.Errorf("Authorization 未转发, 得到 %q", svc.authorization)
+		}
+	})
+
+	t.Run("字段校验错误", func(t *testing.T) {
+		w := call("CreateUser", `{"name": "测试用户", "email": "bad"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("期望状态码 400, 实际 %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp struct {
+			Fields []struct {
+				Field   string `json:"field"`
+				Message string `json:"message"`
+			} `json:"fields"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		if len(resp.Fields) != 1 || resp.Fields[0].Field != "email" {
+			t.Errorf("字段错误 = %+v, 期望 email", resp.Fields)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		expected int
+	}{
+		{"NotFound 映射为 404", "GetUser", `{"id": 1}`, http.StatusNotFound},
+		{"未实现映射为 501", "DeleteUser", `{"id": 1}`, http.StatusNotImplemented},
+		{"请求体错误", "GetUser", `{"id": "abc"}`, http.StatusBadRequest},
+		{"未知方法", "Missing", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := call(tt.method, tt.body); w.Code != tt.expected {
+				t.Errorf("期望状态码 %d, 实际 %d: %s", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package usersettings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zhang/microservice/internal/cache"
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/database"
+	"github.com/zhang/microservice/internal/events"
+	"github.com/zhang/microservice/internal/jsonschema"
+	"github.com/zhang/microservice/internal/logger"
+	"github.com/zhang/microservice/internal/queue"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownKey 未声明的设置项
+	ErrUnknownKey = errors.New("未知的设置项")
+	// ErrInvalidValue 设置值不符合声明的结构
+	ErrInvalidValue = errors.New("设置值无效")
+)
+
+// Setting 用户设置项，每个用户每个设置项一行
+type Setting struct {
+	UserID    int64     `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Key       string    `gorm:"primaryKey;type:varchar(64)" json:"key"`
+	Value     string    `gorm:"type:text;not null" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Setting) TableName() string {
+	return "user_settings"
+}
+
+// ChangedEvent 用户设置变更事件
+type ChangedEvent struct {
+	UserID  int64                      `json:"user_id"`
+	Changed map[string]json.RawMessage `json:"changed"`
+	Reset   []string                   `json:"reset"`
+	At      time.Time                  `json:"at"`
+}
+
+// definition 设置项定义
+type definition struct {
+	schema jsonschema.Schema
+	// def 默认值的 JSON 编码，未配置时为 nil
+	def json.RawMessage
+}
+
+var (
+	definitions  = map[string]definition{}
+	cacheTTL     = 5 * time.Minute
+	maxValueSize = 4096
+)
+
+// Init 根据配置注册设置项
+// 参数:
+//
+//	cfg: 用户设置配置
+//
+// 返回:
+//
+//	error: 默认值无法编码时返回错误
+func Init(cfg config.UserSettingsConfig) error {
+	defs := make(map[string]definition, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		d := definition{schema: k.Schema}
+		if k.Default != nil {
+			raw, err := json.Marshal(jsonschema.Normalize(k.Default))
+			if err != nil {
+				return fmt.Errorf("编码设置项 %s 的默认值失败: %w", k.Name, err)
+			}
+			d.def = raw
+		}
+		defs[k.Name] = d
+	}
+
+	definitions = defs
+	cacheTTL = cfg.GetCacheTTL()
+	maxValueSize = cfg.GetMaxValueSize()
+	return nil
+}
+
+// Keys 返回所有允许的设置项
+func Keys() []string {
+	keys := make([]string, 0, len(definitions))
+	for k := range definitions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate 校验设置变更，值为 null 表示恢复默认值
+// 参数:
+//
+//	changes: 设置项到 JSON 值的映射
+//
+// 返回:
+//
+//	error: 包含未知设置项时返回 ErrUnknownKey，值无效时返回 ErrInvalidValue
+func Validate(changes map[string]json.RawMessage) error {
+	for _, key := range sortedKeys(changes) {
+		def, ok := definitions[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+		}
+		raw := changes[key]
+		if isNull(raw) {
+			continue
+		}
+		if len(raw) > maxValueSize {
+			return fmt.Errorf("%w: %s 超过 %d 字节", ErrInvalidValue, key, maxValueSize)
+		}
+		if err := def.schema.ValidateJSON(raw); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidValue, key, err)
+		}
+	}
+	return nil
+}
+
+// cacheKey 用户设置缓存键名
+func cacheKey(userID int64) string {
+	return "user_settings:" + strconv.FormatInt(userID, 10)
+}
+
+// Get 获取用户设置，未设置的项返回默认值
+// 先读 Redis 缓存，未命中时读数据库并回填缓存；缓存中只保存用户设置过的值，默认值修改后立即生效
+// 参数:
+//
+//	ctx: 上下文
+//	userID: 用户 ID
+//
+// 返回:
+//
+//	map[string]json.RawMessage: 设置项到 JSON 值的映射
+//	error: 错误信息
+func Get(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	stored, err := load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]json.RawMessage, len(definitions))
+	for key, def := range definitions {
+		if raw, ok := stored[key]; ok {
+			result[key] = raw
+		} else if def.def != nil {
+			result[key] = def.def
+		}
+	}
+	return result, nil
+}
+
+// load 读取用户设置过的值（经缓存，降级期间按 redis.degradation.cache 直接读数据库或返回错误）
+// 没有设置过的用户也缓存空对象，避免反复查询数据库
+func load(ctx context.Context, userID int64) (map[string]json.RawMessage, error) {
+	return cache.Remember(ctx, cacheKey(userID), cacheTTL, func(ctx context.Context) (map[string]json.RawMessage, error) {
+		var rows []Setting
+		if err := database.DB.WithContext(ctx).Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("查询用户设置失败: %w", err)
+		}
+		stored := make(map[string]json.RawMessage, len(rows))
+		for _, row := range rows {
+			stored[row.Key] = json.RawMessage(row.Value)
+		}
+		return stored, nil
+	})
+}
+
+// Update 更新用户设置，值为 null 的项删除后恢复默认值
+// 所有变更先统一校验，任一无效则整体拒绝；保存后清除缓存并发布 user.settings.changed 事件
+// 参数:
+//
+//	ctx: 上下文
+//	userID: 用户 ID
+//	changes: 设置项到 JSON 值的映射
+//
+// 返回:
+//
+//	map[string]json.RawMessage: 更新后的全部设置
+//	error: 校验失败时返回 ErrUnknownKey 或 ErrInvalidValue
+func Update(ctx context.Context, userID int64, changes map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	if err := Validate(changes); err != nil {
+		return nil, err
+	}
+
+	event := ChangedEvent{UserID: userID, Changed: map[string]json.RawMessage{}, Reset: []string{}}
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range sortedKeys(changes) {
+			raw := changes[key]
+			if isNull(raw) {
+				if err := tx.Delete(&Setting{UserID: userID, Key: key}).Error; err != nil {
+					return err
+				}
+				event.Reset = append(event.Reset, key)
+				continue
+			}
+
+			var compact bytes.Buffer
+			if err := json.Compact(&compact, raw); err != nil {
+				return err
+			}
+			if err := tx.Save(&Setting{UserID: userID, Key: key, Value: compact.String()}).Error; err != nil {
+				return err
+			}
+			event.Changed[key] = compact.Bytes()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("保存用户设置失败: %w", err)
+	}
+
+	if err := cache.Delete(ctx, cacheKey(userID)); err != nil {
+		logger.Warn("清除用户设置缓存失败", zap.Int64("user_id", userID), zap.Error(err))
+	}
+
+	event.At = time.Now()
+	publish(ctx, event)
+
+	return Get(ctx, userID)
+}
+
+// publish 发布设置变更事件（携带请求 ID），失败只记录日志
+func publish(ctx context.Context, event ChangedEvent) {
+	if queue.MQClient == nil {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := queue.PublishContext(ctx, events.UserSettingsChanged.RoutingKey, body); err != nil {
+		logger.Error("发布用户设置变更事件失败", zap.Int64("user_id", event.UserID), zap.Error(err))
+	}
+}
+
+// isNull 值是否为 JSON null
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// sortedKeys 返回排序后的键，使校验和写入顺序稳定
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
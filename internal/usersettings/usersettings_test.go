@@ -0,0 +1,78 @@
+package usersettings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhang/microservice/internal/config"
+	"github.com/zhang/microservice/internal/jsonschema"
+	"github.com/zhang/microservice/internal/testutil"
+)
+
+func useKeys(t *testing.T) {
+	t.Helper()
+	err := Init(config.UserSettingsConfig{
+		MaxValueSize: 64,
+		Keys: []config.UserSettingKey{
+			{Name: "theme", Schema: jsonschema.Schema{Type: "string", Enum: []interface{}{"light", "dark"}}, Default: "light"},
+			{Name: "page_size", Schema: jsonschema.Schema{Type: "integer"}},
+			{Name: "tags", Schema: jsonschema.Schema{Type: "array"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Init(config.UserSettingsConfig{}) })
+}
+
+func TestValidate(t *testing.T) {
+	useKeys(t)
+
+	tests := []struct {
+		name     string
+		changes  string
+		expected error
+	}{
+		{"有效", `{"theme":"dark","page_size":50}`, nil},
+		{"恢复默认值", `{"theme":null}`, nil},
+		{"未知设置项", `{"font":"serif"}`, ErrUnknownKey},
+		{"枚举外的值", `{"theme":"blue"}`, ErrInvalidValue},
+		{"类型错误", `{"page_size":"50"}`, ErrInvalidValue},
+		{"超过大小限制", `{"tags":["aaaaaaaaaaaaaaaaaaaa","bbbbbbbbbbbbbbbbbbbb","cccccccccccccccccccc"]}`, ErrInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.changes), &changes); err != nil {
+				t.Fatal(err)
+			}
+			if err := Validate(changes); !errors.Is(err, tt.expected) {
+				t.Errorf("Validate() = %v, 期望 %v", err, tt.expected)
+			}
+		})
+	}
+
+	if keys := Keys(); len(keys) != 3 || keys[0] != "page_size" {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
+
+// TestPublishFollowsContract 变更事件按契约发布（契约校验在测试结束时进行）
+func TestPublishFollowsContract(t *testing.T) {
+	testutil.InitLogger()
+	recorder := testutil.RecordEvents(t)
+
+	publish(context.Background(), ChangedEvent{
+		UserID:  42,
+		Changed: map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)},
+		Reset:   []string{"page_size"},
+		At:      time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC),
+	})
+
+	if published := recorder.Events(); len(published) != 1 || published[0].RoutingKey != "user.settings.changed" {
+		t.Errorf("发布的事件 = %+v", published)
+	}
+}
@@ -0,0 +1,123 @@
+package validate
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zhang/microservice/internal/timezone"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 用户字段长度上限，与 users 表的列定义一致
+const (
+	// MaxNameLength 名称最大字符数
+	MaxNameLength = 100
+	// MaxEmailLength 邮箱最大字符数
+	MaxEmailLength = 100
+)
+
+// phonePattern 手机号格式：可选的 + 前缀和 6-15 位数字（E.164），不含空格和分隔符
+var phonePattern = regexp.MustCompile(`^\+?[1-9][0-9]{5,14}$`)
+
+// FieldViolation 单个字段的校验错误
+type FieldViolation struct {
+	// Field 字段名，与请求中的字段名一致
+	Field string `json:"field"`
+	// Message 错误说明
+	Message string `json:"message"`
+}
+
+// Error 校验错误，包含所有不合法的字段
+// 实现 GRPCStatus，gRPC 方法直接返回时映射为 INVALID_ARGUMENT，字段错误放在 BadRequest 详情中
+type Error struct {
+	Violations []FieldViolation
+}
+
+// Error 实现 error 接口
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+": "+v.Message)
+	}
+	return "请求参数错误: " + strings.Join(parts, "; ")
+}
+
+// GRPCStatus 转换为 gRPC 状态（INVALID_ARGUMENT 附带 google.rpc.BadRequest 详情）
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(codes.InvalidArgument, e.Error())
+	br := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Message,
+		})
+	}
+	if detailed, err := st.WithDetails(br); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// UserInput 需要校验的用户字段，nil 表示未提供（部分更新时不校验）
+type UserInput struct {
+	Name     *string
+	Email    *string
+	Phone    *string
+	Timezone *string
+}
+
+// User 校验用户输入：名称非空且不超过 MaxNameLength 个字符，邮箱格式有效且不超过 MaxEmailLength 个字符，
+// 手机号为空或符合 E.164 格式，时区为空或为有效的 IANA 名称
+// 参数:
+//
+//	in: 用户输入
+//
+// 返回:
+//
+//	error: 存在不合法的字段时返回 *Error
+func User(in UserInput) error {
+	var violations []FieldViolation
+	add := func(field, message string) {
+		violations = append(violations, FieldViolation{Field: field, Message: message})
+	}
+
+	if in.Name != nil {
+		switch n := utf8.RuneCountInString(*in.Name); {
+		case strings.TrimSpace(*in.Name) == "":
+			add("name", "不能为空")
+		case n > MaxNameLength:
+			add("name", "不能超过 100 个字符")
+		}
+	}
+	if in.Email != nil {
+		switch {
+		case *in.Email == "":
+			add("email", "不能为空")
+		case utf8.RuneCountInString(*in.Email) > MaxEmailLength:
+			add("email", "不能超过 100 个字符")
+		case !validEmail(*in.Email):
+			add("email", "邮箱格式无效")
+		}
+	}
+	if in.Phone != nil && *in.Phone != "" && !phonePattern.MatchString(*in.Phone) {
+		add("phone", "手机号格式无效，应为 6-15 位数字，可带 + 前缀")
+	}
+	if in.Timezone != nil && !timezone.Valid(*in.Timezone) {
+		add("timezone", "无效的时区，应为 IANA 时区名称（如 Asia/Shanghai）")
+	}
+
+	if len(violations) > 0 {
+		return &Error{Violations: violations}
+	}
+	return nil
+}
+
+// validEmail 判断是否为单个不带显示名称的邮箱地址
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
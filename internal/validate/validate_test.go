@@ -0,0 +1,75 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     UserInput
+		fields []string
+	}{
+		{"全部有效", UserInput{Name: ptr("张三"), Email: ptr("a@example.com"), Phone: ptr("+8613800138000"), Timezone: ptr("Asia/Shanghai")}, nil},
+		{"未提供的字段不校验", UserInput{}, nil},
+		{"手机号和时区可为空", UserInput{Phone: ptr(""), Timezone: ptr("")}, nil},
+		{"名称为空白", UserInput{Name: ptr("  ")}, []string{"name"}},
+		{"名称按字符计长度", UserInput{Name: ptr(strings.Repeat("名", 100))}, nil},
+		{"名称过长", UserInput{Name: ptr(strings.Repeat("a", 101))}, []string{"name"}},
+		{"邮箱为空", UserInput{Email: ptr("")}, []string{"email"}},
+		{"邮箱带显示名称", UserInput{Email: ptr("张三 <a@example.com>")}, []string{"email"}},
+		{"邮箱缺少域名后缀", UserInput{Email: ptr("a@localhost")}, []string{"email"}},
+		{"手机号含分隔符", UserInput{Phone: ptr("138-0013-8000")}, []string{"phone"}},
+		{"手机号过短", UserInput{Phone: ptr("12345")}, []string{"phone"}},
+		{"多个字段", UserInput{Name: ptr(""), Email: ptr("bad"), Timezone: ptr("Mars/Olympus")}, []string{"name", "email", "timezone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := User(tt.in)
+			if tt.fields == nil {
+				if err != nil {
+					t.Errorf("期望通过, 得到 %v", err)
+				}
+				return
+			}
+
+			var verr *Error
+			if !errors.As(err, &verr) {
+				t.Fatalf("期望 *Error, 得到 %v", err)
+			}
+			var got []string
+			for _, v := range verr.Violations {
+				got = append(got, v.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("字段 = %v, 期望 %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestErrorGRPCStatus(t *testing.T) {
+	err := User(UserInput{Email: ptr("bad")})
+
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		t.Fatalf("期望 InvalidArgument, 得到 %v", err)
+	}
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("期望 1 个详情, 得到 %d", len(details))
+	}
+	br, ok := details[0].(*errdetails.BadRequest)
+	if !ok || len(br.FieldViolations) != 1 || br.FieldViolations[0].Field != "email" {
+		t.Errorf("BadRequest 详情错误: %v", details[0])
+	}
+}